@@ -0,0 +1,157 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"strings"
+
+	"github.com/NickP005/Vindax-MCM-tools/pkg/mcmaddr"
+	"github.com/NickP005/Vindax-MCM-tools/pkg/meshclient"
+)
+
+// Outcomes of a balance lookup
+const (
+	StatusFound    = "found"
+	StatusNotFound = "not found"
+	StatusInvalid  = "invalid"
+	StatusFailed   = "failed"
+)
+
+/*
+ * BalanceEntry is one address of the input with its balance
+ *
+ * Fields:
+ * - Line: input line number
+ * - Input: the address as given, Label: the rest of the line, if any
+ * - Hex, Base58: the normalized tag, empty for an invalid input
+ * - Status: one of the Status constants, Error explains invalid and failed ones
+ * - Balance: in nanoMCM, set only when found, with the ResolvedAddress
+ */
+type BalanceEntry struct {
+	Line            int     `json:"line"`
+	Input           string  `json:"input"`
+	Label           string  `json:"label,omitempty"`
+	Hex             string  `json:"hex,omitempty"`
+	Base58          string  `json:"base58,omitempty"`
+	Status          string  `json:"status"`
+	Balance         *uint64 `json:"balance,omitempty"`
+	ResolvedAddress string  `json:"resolvedAddress,omitempty"`
+	Error           string  `json:"error,omitempty"`
+
+	tag [mcmaddr.TagLength]byte
+}
+
+/*
+ * ReadAddresses reads one address per line, hex or base58, optionally
+ * followed by a label (e.g. "hot wallet 2")
+ *
+ * Blank lines and lines starting with # are skipped. An address that does
+ * not normalize is kept with StatusInvalid, so it is reported in its place.
+ */
+func ReadAddresses(r io.Reader) ([]BalanceEntry, error) {
+	var entries []BalanceEntry
+	scanner := bufio.NewScanner(r)
+	line := 0
+	for scanner.Scan() {
+		line++
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+		fields := strings.Fields(text)
+		entry := BalanceEntry{
+			Line:  line,
+			Input: strings.TrimRight(fields[0], ",;"),
+			Label: strings.Join(fields[1:], " "),
+		}
+		tag, err := mcmaddr.Normalize(entry.Input)
+		if err != nil {
+			entry.Status = StatusInvalid
+			entry.Error = err.Error()
+		} else {
+			entry.tag = tag
+			entry.Hex = mcmaddr.ToHex(tag)
+			entry.Base58 = mcmaddr.To58(tag)
+		}
+		entries = append(entries, entry)
+	}
+	return entries, scanner.Err()
+}
+
+/*
+ * LookupBalances resolves the tag of every valid entry and fills in its
+ * status and balance
+ *
+ * Lookups go through BatchResolveTags: each distinct tag is looked up once,
+ * with the client's batch concurrency, and a failed lookup only marks its
+ * entries as failed. Returns ctx's error if it was canceled meanwhile.
+ */
+func LookupBalances(ctx context.Context, client *meshclient.MeshAPIClient, entries []BalanceEntry) error {
+	var tags [][mcmaddr.TagLength]byte
+	for _, entry := range entries {
+		if entry.Status != StatusInvalid {
+			tags = append(tags, entry.tag)
+		}
+	}
+	resolutions, err := client.BatchResolveTags(ctx, tags)
+
+	for i := range entries {
+		entry := &entries[i]
+		if entry.Status == StatusInvalid {
+			continue
+		}
+		resolution := resolutions[entry.tag]
+		switch {
+		case resolution.Err != nil:
+			entry.Status = StatusFailed
+			entry.Error = resolution.Err.Error()
+		case resolution.Found:
+			balance := resolution.Amount
+			entry.Status = StatusFound
+			entry.Balance = &balance
+			entry.ResolvedAddress = resolution.AddressHex
+		default:
+			entry.Status = StatusNotFound
+		}
+	}
+	return err
+}
+
+/*
+ * Summary totals a lookup
+ *
+ * Total adds up the balance of every distinct tag found: an address listed
+ * twice is counted once.
+ */
+type Summary struct {
+	Addresses int    `json:"addresses"`
+	Found     int    `json:"found"`
+	NotFound  int    `json:"notFound"`
+	Invalid   int    `json:"invalid"`
+	Failed    int    `json:"failed"`
+	Total     uint64 `json:"total"`
+}
+
+// Summarize counts the entries by status and totals their balances
+func Summarize(entries []BalanceEntry) Summary {
+	summary := Summary{Addresses: len(entries)}
+	counted := make(map[[mcmaddr.TagLength]byte]bool)
+	for _, entry := range entries {
+		switch entry.Status {
+		case StatusFound:
+			summary.Found++
+			if !counted[entry.tag] {
+				counted[entry.tag] = true
+				summary.Total += *entry.Balance
+			}
+		case StatusNotFound:
+			summary.NotFound++
+		case StatusInvalid:
+			summary.Invalid++
+		case StatusFailed:
+			summary.Failed++
+		}
+	}
+	return summary
+}
@@ -0,0 +1,129 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/NickP005/Vindax-MCM-tools/pkg/mcmaddr"
+	"github.com/NickP005/Vindax-MCM-tools/pkg/meshclient"
+	"github.com/NickP005/Vindax-MCM-tools/pkg/meshmock"
+)
+
+// testTag is the tag whose bytes are all b
+func testTag(b byte) [mcmaddr.TagLength]byte {
+	var tag [mcmaddr.TagLength]byte
+	for i := range tag {
+		tag[i] = b
+	}
+	return tag
+}
+
+func TestReadAddresses(t *testing.T) {
+	hot, cold := testTag(0x0a), testTag(0x0b)
+	input := "# hot wallets\n" +
+		mcmaddr.To58(hot) + " hot wallet 1\n" +
+		"\n" +
+		"0x" + strings.ToUpper(mcmaddr.ToHex(cold)) + ",\n" +
+		"not-an-address cold\n"
+	entries, err := ReadAddresses(strings.NewReader(input))
+	if err != nil || len(entries) != 3 {
+		t.Fatalf("%d entries, %v", len(entries), err)
+	}
+	if e := entries[0]; e.Line != 2 || e.Label != "hot wallet 1" || e.Hex != mcmaddr.ToHex(hot) || e.Status != "" {
+		t.Errorf("base58 entry %+v", e)
+	}
+	if e := entries[1]; e.Line != 4 || e.Base58 != mcmaddr.To58(cold) || e.Label != "" {
+		t.Errorf("hex entry %+v", e)
+	}
+	if e := entries[2]; e.Line != 5 || e.Status != StatusInvalid || e.Error == "" || e.Label != "cold" {
+		t.Errorf("invalid entry %+v", e)
+	}
+}
+
+// lookup reads input and looks its balances up on mock
+func lookup(t *testing.T, mock *meshmock.Server, input string) ([]BalanceEntry, Summary) {
+	t.Helper()
+	entries, err := ReadAddresses(strings.NewReader(input))
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := meshclient.NewMeshAPIClient(mock.URL(), nil)
+	client.SetBatchConcurrency(1)
+	if err := LookupBalances(context.Background(), client, entries); err != nil {
+		t.Fatal(err)
+	}
+	return entries, Summarize(entries)
+}
+
+// TestLookupBalances mixes a found address listed twice, an unknown one, an invalid line and a failing lookup
+func TestLookupBalances(t *testing.T) {
+	mock := meshmock.New()
+	defer mock.Close()
+	hot, unknown, failing := testTag(0x0a), testTag(0x0b), testTag(0x0c)
+	mock.SetAccount(hot[:], "0x"+mcmaddr.ToHex(hot)+strings.Repeat("ee", 20), 1500000000)
+	input := strings.Join([]string{mcmaddr.ToHex(failing), mcmaddr.To58(hot) + " hot", mcmaddr.ToHex(unknown), "bogus", mcmaddr.ToHex(hot) + " again"}, "\n")
+	// One lookup at a time: the first distinct tag, failing, gets the fault
+	mock.Fail("/call", meshmock.Fault{Status: 500, Body: `{"code":2,"message":"internal error","retriable":false}`})
+
+	entries, summary := lookup(t, mock, input)
+	want := []string{StatusFailed, StatusFound, StatusNotFound, StatusInvalid, StatusFound}
+	for i, entry := range entries {
+		if entry.Status != want[i] {
+			t.Errorf("line %d: %s, want %s", entry.Line, entry.Status, want[i])
+		}
+	}
+	if entries[1].Balance == nil || *entries[1].Balance != 1500000000 || entries[1].ResolvedAddress == "" || entries[0].Error == "" {
+		t.Errorf("entries %+v", entries)
+	}
+	// The address listed twice counts once
+	if summary != (Summary{Addresses: 5, Found: 2, NotFound: 1, Invalid: 1, Failed: 1, Total: 1500000000}) {
+		t.Errorf("summary %+v", summary)
+	}
+}
+
+func TestWriteOutputs(t *testing.T) {
+	mock := meshmock.New()
+	defer mock.Close()
+	hot := testTag(0x0a)
+	mock.SetAccount(hot[:], "0x"+mcmaddr.ToHex(hot)+strings.Repeat("ee", 20), 1500000000)
+	entries, summary := lookup(t, mock, mcmaddr.ToHex(hot)+" hot wallet\nbogus\n")
+
+	var table bytes.Buffer
+	if err := WriteTable(&table, entries, summary); err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{mcmaddr.To58(hot) + "  1500000000      1.5  hot wallet", "bogus", "over 1 addresses found (0 not found, 1 invalid, 0 failed)"} {
+		if !strings.Contains(table.String(), want) {
+			t.Errorf("table lacks %q:\n%s", want, table.String())
+		}
+	}
+
+	var rows bytes.Buffer
+	if err := WriteCSV(&rows, entries); err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(rows.String()), "\n")
+	if len(lines) != 3 || lines[0] != "line,input,hex,base58,label,status,balance,error" ||
+		lines[1] != "1,"+mcmaddr.ToHex(hot)+","+mcmaddr.ToHex(hot)+","+mcmaddr.To58(hot)+",hot wallet,found,1500000000," {
+		t.Errorf("csv:\n%s", rows.String())
+	}
+
+	var object bytes.Buffer
+	if err := WriteJSON(&object, entries, summary); err != nil {
+		t.Fatal(err)
+	}
+	var decoded struct {
+		Addresses []BalanceEntry `json:"addresses"`
+		Summary   Summary        `json:"summary"`
+	}
+	if err := json.Unmarshal(object.Bytes(), &decoded); err != nil || len(decoded.Addresses) != 2 || decoded.Summary != summary || *decoded.Addresses[0].Balance != 1500000000 {
+		t.Errorf("json %s, %v", object.String(), err)
+	}
+	object.Reset()
+	if WriteJSON(&object, nil, Summary{}); !strings.Contains(object.String(), `"addresses": []`) {
+		t.Errorf("empty json %s", object.String())
+	}
+}
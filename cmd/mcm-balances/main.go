@@ -0,0 +1,110 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/NickP005/Vindax-MCM-tools/pkg/amount"
+	"github.com/NickP005/Vindax-MCM-tools/pkg/cli"
+	"github.com/NickP005/Vindax-MCM-tools/pkg/config"
+	"github.com/NickP005/Vindax-MCM-tools/pkg/meshclient"
+	"github.com/NickP005/Vindax-MCM-tools/pkg/shutdown"
+)
+
+// newMeshClient returns a Mesh API client identifying mcm-balances in its User-Agent, retrying failed lookups
+func newMeshClient(cfg *config.Config, concurrency int) *meshclient.MeshAPIClient {
+	client := meshclient.NewMeshAPIClient(cfg.API, nil)
+	cfg.Apply(client)
+	client.SetUserAgent("mcm-balances")
+	client.SetRetryPolicy(meshclient.DefaultRetryPolicy())
+	client.SetBatchConcurrency(concurrency)
+	return client
+}
+
+// readInput reads the addresses from the arguments, from -file, or from stdin when neither is given
+func readInput(file string, args []string) ([]BalanceEntry, error) {
+	if len(args) > 0 {
+		return ReadAddresses(strings.NewReader(strings.Join(args, "\n")))
+	}
+	input := io.Reader(os.Stdin)
+	if file != "" && file != "-" {
+		f, err := os.Open(file)
+		if err != nil {
+			return nil, err
+		}
+		defer f.Close()
+		input = f
+	}
+	return ReadAddresses(input)
+}
+
+func main() {
+	cfg, err := config.Load(config.Defaults())
+	if err != nil {
+		cli.Usagef("%v", err)
+	}
+	file := flag.String("file", "", "File with one address per line, hex or base58, optionally followed by a label (default: stdin)")
+	cfg.APIFlags(flag.CommandLine, "Mesh API URL")
+	concurrency := flag.Int("concurrency", 8, "Maximum concurrent balance lookups")
+	asCSV := flag.Bool("csv", false, "Output CSV, one row per address; the total goes to stderr")
+	asJSON := flag.Bool("json", false, "Output a JSON object with every address and the summary")
+	cfg.TimeoutFlag(flag.CommandLine, "Give up the lookups not made after this long")
+	printConfig := cfg.PrintFlag(flag.CommandLine)
+	flag.Usage = func() {
+		fmt.Fprintln(flag.CommandLine.Output(), "Usage: mcm-balances [flags] [address...]")
+		flag.PrintDefaults()
+	}
+
+	cli.Parse(cfg)
+	if *printConfig {
+		cfg.Print(os.Stdout)
+		return
+	}
+	if *asCSV && *asJSON {
+		cli.Usagef("-csv and -json cannot be combined")
+	}
+	if flag.NArg() > 0 && *file != "" {
+		cli.Usagef("give the addresses either as arguments or with -file")
+	}
+
+	entries, err := readInput(*file, flag.Args())
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error reading addresses: %v\n", err)
+		os.Exit(cli.ExitUsage)
+	}
+
+	// Interrupting the tool cancels the lookups in flight; those not made are reported failed
+	sig := shutdown.Notify()
+	defer sig.Stop()
+	ctx := sig.Context()
+	ctx, cancel := context.WithTimeout(ctx, cfg.Timeout)
+	defer cancel()
+
+	if err := LookupBalances(ctx, newMeshClient(cfg, *concurrency), entries); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: lookups stopped early: %v\n", err)
+	}
+	summary := Summarize(entries)
+
+	switch {
+	case *asJSON:
+		err = WriteJSON(os.Stdout, entries, summary)
+	case *asCSV:
+		err = WriteCSV(os.Stdout, entries)
+		fmt.Fprintf(os.Stderr, "Total: %s over %d addresses found\n", amount.Describe(summary.Total), summary.Found)
+	default:
+		err = WriteTable(os.Stdout, entries, summary)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error writing output: %v\n", err)
+		os.Exit(cli.ExitUsage)
+	}
+
+	// An invalid address is reported in its row, only a balance left unknown fails the run
+	if summary.Failed > 0 {
+		os.Exit(cli.ExitFailure)
+	}
+}
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"text/tabwriter"
+
+	"github.com/NickP005/Vindax-MCM-tools/pkg/amount"
+)
+
+// Output formats
+const (
+	FormatTable = "table"
+	FormatCSV   = "csv"
+	FormatJSON  = "json"
+)
+
+// balanceText renders the balance of an entry in nanoMCM, or its status when it has none
+func balanceText(entry BalanceEntry) string {
+	if entry.Balance == nil {
+		return entry.Status
+	}
+	return strconv.FormatUint(*entry.Balance, 10)
+}
+
+// WriteTable writes the entries as an aligned table followed by the grand total
+func WriteTable(out io.Writer, entries []BalanceEntry, summary Summary) error {
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "ADDRESS\tBALANCE (nMCM)\tMCM\tLABEL\tERROR")
+	for _, entry := range entries {
+		address := entry.Base58
+		if address == "" {
+			address = entry.Input
+		}
+		mcm := ""
+		if entry.Balance != nil {
+			mcm = amount.FormatMCM(*entry.Balance)
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", address, balanceText(entry), mcm, entry.Label, entry.Error)
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+	_, err := fmt.Fprintf(out, "\nTotal: %s over %d addresses found (%d not found, %d invalid, %d failed)\n",
+		amount.Describe(summary.Total), summary.Found, summary.NotFound, summary.Invalid, summary.Failed)
+	return err
+}
+
+// WriteCSV writes one row per entry with a header; the grand total is left to the caller, so the file stays one row per address
+func WriteCSV(out io.Writer, entries []BalanceEntry) error {
+	w := csv.NewWriter(out)
+	w.Write([]string{"line", "input", "hex", "base58", "label", "status", "balance", "error"})
+	for _, entry := range entries {
+		balance := ""
+		if entry.Balance != nil {
+			balance = strconv.FormatUint(*entry.Balance, 10)
+		}
+		w.Write([]string{strconv.Itoa(entry.Line), entry.Input, entry.Hex, entry.Base58, entry.Label, entry.Status, balance, entry.Error})
+	}
+	w.Flush()
+	return w.Error()
+}
+
+// WriteJSON writes the entries and the summary as one JSON object
+func WriteJSON(out io.Writer, entries []BalanceEntry, summary Summary) error {
+	if entries == nil {
+		entries = []BalanceEntry{}
+	}
+	encoder := json.NewEncoder(out)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(struct {
+		Addresses []BalanceEntry `json:"addresses"`
+		Summary   Summary        `json:"summary"`
+	}{entries, summary})
+}
@@ -0,0 +1,106 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/NickP005/Vindax-MCM-tools/pkg/meshclient"
+)
+
+// HASH_HEX_LENGTH is the length in hex of a block or transaction hash
+const HASH_HEX_LENGTH = 64
+
+/*
+ * BlockView is a block with its transactions decoded
+ *
+ * Transactions holds every transaction of the block, those the server left
+ * out of the inline list (other_transactions) fetched one by one.
+ */
+type BlockView struct {
+	Index        uint64                `json:"index"`
+	Hash         string                `json:"hash"`
+	ParentIndex  uint64                `json:"parentIndex"`
+	ParentHash   string                `json:"parentHash"`
+	Time         time.Time             `json:"time"`
+	Transactions []meshclient.Transfer `json:"transactions"`
+}
+
+// isHash reports whether s is a 32 bytes hash in hex, with or without 0x
+func isHash(s string) bool {
+	s = strings.TrimPrefix(strings.TrimPrefix(s, "0x"), "0X")
+	if len(s) != HASH_HEX_LENGTH {
+		return false
+	}
+	for _, c := range s {
+		if !strings.ContainsRune("0123456789abcdefABCDEF", c) {
+			return false
+		}
+	}
+	return true
+}
+
+// checkRef validates a block reference without fetching anything
+func checkRef(ref string) error {
+	if ref == "latest" || isHash(ref) {
+		return nil
+	}
+	if _, err := strconv.ParseUint(ref, 10, 64); err != nil {
+		return fmt.Errorf("invalid block %q: expected a height, a hash or \"latest\"", ref)
+	}
+	return nil
+}
+
+// resolveHeight turns a height or "latest" into a height; a hash is not accepted
+func resolveHeight(ctx context.Context, client *meshclient.MeshAPIClient, ref string) (uint64, error) {
+	if ref == "latest" {
+		status, err := client.NetworkStatus(ctx)
+		if err != nil {
+			return 0, err
+		}
+		return status.CurrentBlockIdentifier.Index, nil
+	}
+	height, err := strconv.ParseUint(ref, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid block %q: expected a height or \"latest\"", ref)
+	}
+	return height, nil
+}
+
+// FetchBlock fetches a block given as a height, a hash (with or without 0x) or "latest"
+func FetchBlock(ctx context.Context, client *meshclient.MeshAPIClient, ref string) (*meshclient.Block, error) {
+	if isHash(ref) {
+		return client.BlockByHash(ctx, ref)
+	}
+	height, err := resolveHeight(ctx, client, ref)
+	if err != nil {
+		return nil, err
+	}
+	return client.Block(ctx, height)
+}
+
+/*
+ * ViewBlock decodes the transactions of a block
+ *
+ * The transactions listed in other_transactions only are fetched through
+ * /block/transaction, in order after the inline ones; a failed fetch fails
+ * the whole view rather than showing an incomplete block.
+ */
+func ViewBlock(ctx context.Context, client *meshclient.MeshAPIClient, block *meshclient.Block) (*BlockView, error) {
+	view := &BlockView{
+		Index:        block.Block.BlockIdentifier.Index,
+		Hash:         block.Block.BlockIdentifier.Hash,
+		ParentIndex:  block.Block.ParentBlockIdentifier.Index,
+		ParentHash:   block.Block.ParentBlockIdentifier.Hash,
+		Time:         time.UnixMilli(block.Block.Timestamp).UTC(),
+		Transactions: []meshclient.Transfer{},
+	}
+	transfers, err := client.BlockTransfers(ctx, block)
+	if err != nil {
+		return nil, err
+	}
+	view.Transactions = append(view.Transactions, transfers...)
+	return view, nil
+}
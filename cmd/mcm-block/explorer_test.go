@@ -0,0 +1,123 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/NickP005/Vindax-MCM-tools/pkg/mcmaddr"
+	"github.com/NickP005/Vindax-MCM-tools/pkg/meshclient"
+	"github.com/NickP005/Vindax-MCM-tools/pkg/meshmock"
+)
+
+// transferTx pays 300 nanoMCM with memo to the tag whose bytes are all to, from the tag 0x42..., with change and a fee of 10
+func transferTx(n byte, to byte, memo string) meshclient.Transaction {
+	amount := func(value string) *meshclient.Amount {
+		return &meshclient.Amount{Value: value, Currency: meshclient.MCM}
+	}
+	account := func(address string) *meshclient.AccountIdentifier {
+		return &meshclient.AccountIdentifier{Address: address}
+	}
+	return meshclient.Transaction{
+		TransactionIdentifier: meshclient.TransactionIdentifier{Hash: "0x" + strings.Repeat(fmt.Sprintf("%02x", n), 32)},
+		Operations: []meshclient.Operation{
+			{Type: meshclient.OpSourceTransfer, Account: account("0x" + strings.Repeat("42", 40)), Amount: amount("-1000")},
+			{Type: meshclient.OpDestinationTransfer, Account: account("0x" + strings.Repeat(fmt.Sprintf("%02x", to), 20)), Amount: amount("300"),
+				Metadata: map[string]interface{}{"memo": memo}},
+			{Type: meshclient.OpDestinationTransfer, Account: account("0x" + strings.Repeat("42", 20) + strings.Repeat("43", 20)), Amount: amount("690")},
+			{Type: meshclient.OpFee, Amount: amount("10")},
+		},
+	}
+}
+
+// explorerMock is a mock with two transactions in block 1 and none in block 2
+func explorerMock(t *testing.T) (*meshmock.Server, *meshclient.MeshAPIClient) {
+	mock := meshmock.New()
+	t.Cleanup(mock.Close)
+	mock.AddToMempool(transferTx(1, 0xa1, "INV-1"))
+	mock.AddToMempool(transferTx(2, 0xb0, ""))
+	mock.MineBlock()
+	mock.MineBlock()
+	return mock, meshclient.NewMeshAPIClient(mock.URL(), nil)
+}
+
+func TestFetchBlock(t *testing.T) {
+	_, client := explorerMock(t)
+	ctx := context.Background()
+	latest, err := FetchBlock(ctx, client, "latest")
+	if err != nil || latest.Block.BlockIdentifier.Index != 2 {
+		t.Fatalf("latest: %+v, %v", latest, err)
+	}
+	byHeight, err := FetchBlock(ctx, client, "1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	hash := byHeight.Block.BlockIdentifier.Hash
+	for _, ref := range []string{hash, strings.TrimPrefix(hash, "0x"), strings.ToUpper(strings.TrimPrefix(hash, "0x"))} {
+		if block, err := FetchBlock(ctx, client, ref); err != nil || block.Block.BlockIdentifier.Index != 1 {
+			t.Errorf("by hash %s: %v", ref, err)
+		}
+	}
+	if _, err := FetchBlock(ctx, client, "0x"+strings.Repeat("ee", 32)); err == nil {
+		t.Error("unknown hash found")
+	}
+
+	for ref, valid := range map[string]bool{"latest": true, "12": true, hash: true, "-1": false, "tip": false, "0x12": false} {
+		if err := checkRef(ref); (err == nil) != valid {
+			t.Errorf("checkRef(%q): %v", ref, err)
+		}
+	}
+}
+
+// TestViewBlock prints a block the server truncated: the transactions left out are fetched, in order
+func TestViewBlock(t *testing.T) {
+	mock, client := explorerMock(t)
+	mock.SetBlockLimit(1)
+	ctx := context.Background()
+	block, err := FetchBlock(ctx, client, "1")
+	if err != nil || len(block.OtherTransactions) != 1 {
+		t.Fatalf("truncated block: %+v, %v", block, err)
+	}
+	view, err := ViewBlock(ctx, client, block)
+	if err != nil || len(view.Transactions) != 2 || view.Transactions[1].TxID != strings.Repeat("02", 32) || view.ParentIndex != 0 {
+		t.Fatalf("view %+v, %v", view, err)
+	}
+
+	var out bytes.Buffer
+	if err := WriteBlock(&out, view); err != nil {
+		t.Fatal(err)
+	}
+	var alice, source [mcmaddr.TagLength]byte
+	copy(alice[:], bytes.Repeat([]byte{0xa1}, 20))
+	copy(source[:], bytes.Repeat([]byte{0x42}, 20))
+	for _, want := range []string{
+		fmt.Sprintf("Block 1  %s\n", view.Hash),
+		"  transactions  2\n",
+		"    from    " + mcmaddr.To58(source) + "  spent 0.000001 MCM\n",
+		"    to      " + mcmaddr.To58(alice) + "  0.0000003 MCM  memo \"INV-1\"\n",
+		"    change  " + mcmaddr.To58(source) + "  0.00000069 MCM\n",
+		"    fee     0.00000001 MCM\n",
+	} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("output lacks %q:\n%s", want, out.String())
+		}
+	}
+
+	out.Reset()
+	if err := WriteJSON(&out, view); err != nil {
+		t.Fatal(err)
+	}
+	var decoded BlockView
+	if err := json.Unmarshal(out.Bytes(), &decoded); err != nil || len(decoded.Transactions) != 2 || decoded.Transactions[0].Destinations[0].Memo != "INV-1" {
+		t.Errorf("json %s, %v", out.String(), err)
+	}
+
+	// An empty block has an empty list, not null
+	empty, _ := FetchBlock(ctx, client, "2")
+	if view, err := ViewBlock(ctx, client, empty); err != nil || view.Transactions == nil {
+		t.Errorf("empty block: %+v, %v", view, err)
+	}
+}
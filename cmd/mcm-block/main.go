@@ -0,0 +1,170 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/NickP005/Vindax-MCM-tools/pkg/cli"
+	"github.com/NickP005/Vindax-MCM-tools/pkg/config"
+	"github.com/NickP005/Vindax-MCM-tools/pkg/meshclient"
+	"github.com/NickP005/Vindax-MCM-tools/pkg/shutdown"
+)
+
+// newMeshClient returns a Mesh API client identifying mcm-block in its User-Agent, retrying failed reads
+func newMeshClient(cfg *config.Config) *meshclient.MeshAPIClient {
+	client := meshclient.NewMeshAPIClient(cfg.API, nil)
+	cfg.Apply(client)
+	client.SetUserAgent("mcm-block")
+	client.SetRetryPolicy(meshclient.DefaultRetryPolicy())
+	return client
+}
+
+// printBlock fetches, decodes and prints one block
+func printBlock(ctx context.Context, client *meshclient.MeshAPIClient, out io.Writer, block *meshclient.Block, asJSON bool) error {
+	view, err := ViewBlock(ctx, client, block)
+	if err != nil {
+		return fmt.Errorf("block %d: %v", block.Block.BlockIdentifier.Index, err)
+	}
+	if asJSON {
+		return WriteJSON(out, view)
+	}
+	return WriteBlock(out, view)
+}
+
+// runTx implements -tx: a transaction looked up through /block/transaction
+func runTx(ctx context.Context, client *meshclient.MeshAPIClient, txID string, asJSON bool) int {
+	tx, err := client.BlockTransaction(ctx, txID)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error fetching transaction: %v\n", err)
+		return cli.ExitFailure
+	}
+	transfer, err := meshclient.DecodeTransfer(tx.Transaction)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error decoding transaction: %v\n", err)
+		return cli.ExitFailure
+	}
+	if asJSON {
+		err = WriteJSON(os.Stdout, transfer)
+	} else {
+		err = WriteTransfer(os.Stdout, transfer, "")
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error writing output: %v\n", err)
+		return cli.ExitFailure
+	}
+	return cli.ExitOK
+}
+
+/*
+ * runRange implements -from/-to: the blocks of a range, printed one at a
+ * time in height order as they are fetched
+ *
+ * The first block that cannot be fetched stops the range, after the ones
+ * before it were printed.
+ */
+func runRange(ctx context.Context, client *meshclient.MeshAPIClient, from string, to string, asJSON bool) int {
+	first, err := resolveHeight(ctx, client, from)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: -from: %v\n", err)
+		return cli.ExitUsage
+	}
+	last, err := resolveHeight(ctx, client, to)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: -to: %v\n", err)
+		return cli.ExitUsage
+	}
+	if first > last {
+		fmt.Fprintf(os.Stderr, "Error: -from %d is after -to %d\n", first, last)
+		return cli.ExitUsage
+	}
+
+	for height := first; height <= last; height++ {
+		block, err := client.Block(ctx, height)
+		if err == nil {
+			err = printBlock(ctx, client, os.Stdout, block, asJSON)
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error at block %d: %v\n", height, err)
+			return cli.ExitFailure
+		}
+	}
+	return cli.ExitOK
+}
+
+func main() {
+	cfg, err := config.Load(config.Defaults())
+	if err != nil {
+		cli.Usagef("%v", err)
+	}
+	cfg.APIFlags(flag.CommandLine, "Mesh API URL")
+	asJSON := flag.Bool("json", false, "Output JSON, one object per block (NDJSON for a range) or for the transaction")
+	txID := flag.String("tx", "", "Print this transaction, looked up by hash, instead of a block")
+	from := flag.String("from", "", "First block of a range, height or \"latest\"")
+	to := flag.String("to", "latest", "Last block of a range, height or \"latest\"")
+	printConfig := cfg.PrintFlag(flag.CommandLine)
+	flag.Usage = func() {
+		out := flag.CommandLine.Output()
+		fmt.Fprintln(out, "Usage: mcm-block [flags] <height|hash|latest>")
+		fmt.Fprintln(out, "       mcm-block [flags] -from <height> [-to <height>]")
+		fmt.Fprintln(out, "       mcm-block [flags] -tx <hash>")
+		flag.PrintDefaults()
+	}
+
+	cli.Parse(cfg)
+	if *printConfig {
+		cfg.Print(os.Stdout)
+		return
+	}
+	toSet := false
+	flag.Visit(func(f *flag.Flag) {
+		toSet = toSet || f.Name == "to"
+	})
+	modes := 0
+	for _, set := range []bool{*txID != "", *from != "", flag.NArg() > 0} {
+		if set {
+			modes++
+		}
+	}
+	switch {
+	case modes > 1:
+		cli.Usagef("give either a block, a range with -from, or -tx")
+	case toSet && *from == "":
+		cli.Usagef("-to needs -from")
+	case flag.NArg() > 1:
+		cli.Usagef("give one block, or a range with -from and -to")
+	case *txID != "" && !isHash(*txID):
+		cli.Usagef("invalid transaction hash %q", *txID)
+	}
+
+	// Interrupting a range stops it after the blocks already printed
+	sig := shutdown.Notify()
+	defer sig.Stop()
+	ctx := sig.Context()
+	client := newMeshClient(cfg)
+
+	switch {
+	case *txID != "":
+		os.Exit(runTx(ctx, client, *txID, *asJSON))
+	case *from != "":
+		os.Exit(runRange(ctx, client, *from, *to, *asJSON))
+	}
+
+	ref := "latest"
+	if flag.NArg() == 1 {
+		ref = flag.Arg(0)
+	}
+	if err := checkRef(ref); err != nil {
+		cli.Usagef("%v", err)
+	}
+	block, err := FetchBlock(ctx, client, ref)
+	if err == nil {
+		err = printBlock(ctx, client, os.Stdout, block, *asJSON)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(cli.ExitFailure)
+	}
+}
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/NickP005/Vindax-MCM-tools/pkg/amount"
+	"github.com/NickP005/Vindax-MCM-tools/pkg/mcmaddr"
+	"github.com/NickP005/Vindax-MCM-tools/pkg/meshclient"
+)
+
+// displayAddress renders an operation account as the base58 address of its tag, or as given when it has none
+func displayAddress(address string) string {
+	raw, err := hex.DecodeString(strings.TrimPrefix(strings.TrimPrefix(address, "0x"), "0X"))
+	if err != nil || len(raw) < mcmaddr.TagLength {
+		return address
+	}
+	var tag [mcmaddr.TagLength]byte
+	copy(tag[:], raw)
+	return mcmaddr.To58(tag)
+}
+
+// mcm renders a nanoMCM amount in MCM with its unit
+func mcm(nano uint64) string {
+	return amount.Format(nano, amount.MCM)
+}
+
+// WriteBlock writes a block header followed by its transactions
+func WriteBlock(out io.Writer, view *BlockView) error {
+	fmt.Fprintf(out, "Block %d  %s\n", view.Index, view.Hash)
+	if view.Index > 0 {
+		fmt.Fprintf(out, "  parent        %d  %s\n", view.ParentIndex, view.ParentHash)
+	}
+	fmt.Fprintf(out, "  time          %s\n", view.Time.Format(time.RFC3339))
+	fmt.Fprintf(out, "  transactions  %d\n", len(view.Transactions))
+	for _, transfer := range view.Transactions {
+		fmt.Fprintln(out)
+		if err := WriteTransfer(out, transfer, "  "); err != nil {
+			return err
+		}
+	}
+	_, err := fmt.Fprintln(out)
+	return err
+}
+
+// WriteTransfer writes a decoded transaction, each line starting with indent
+func WriteTransfer(out io.Writer, transfer meshclient.Transfer, indent string) error {
+	fmt.Fprintf(out, "%stx %s\n", indent, transfer.TxID)
+	if transfer.Source != "" {
+		fmt.Fprintf(out, "%s  from    %s  spent %s\n", indent, displayAddress(transfer.Source), mcm(transfer.Spent))
+	}
+	for _, payment := range transfer.Destinations {
+		line := fmt.Sprintf("%s  to      %s  %s", indent, displayAddress(payment.Address), mcm(payment.Amount))
+		if payment.Memo != "" {
+			line += fmt.Sprintf("  memo %q", payment.Memo)
+		}
+		fmt.Fprintln(out, line)
+	}
+	for _, payment := range transfer.Change {
+		fmt.Fprintf(out, "%s  change  %s  %s\n", indent, displayAddress(payment.Address), mcm(payment.Amount))
+	}
+	for _, op := range transfer.Other {
+		fmt.Fprintf(out, "%s  other   operation %d of type %s\n", indent, op.OperationIdentifier.Index, op.Type)
+	}
+	_, err := fmt.Fprintf(out, "%s  fee     %s\n", indent, mcm(transfer.Fee))
+	return err
+}
+
+// WriteJSON writes v as one line of JSON, so a range of blocks is NDJSON
+func WriteJSON(out io.Writer, v interface{}) error {
+	return json.NewEncoder(out).Encode(v)
+}
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"fmt"
+	"io"
+)
+
+// Difference is a field whose value differs between two transactions; a side lacking the field is empty
+type Difference struct {
+	Field string `json:"field"`
+	A     string `json:"a"`
+	B     string `json:"b"`
+}
+
+// Compare lists the fields that differ between two decoded transactions, in print order
+func Compare(a Decoded, b Decoded) []Difference {
+	values := make(map[string]string)
+	var names []string
+	for _, field := range a.Fields() {
+		values[field.Name] = field.Value
+		names = append(names, field.Name)
+	}
+	differences := []Difference{}
+	seen := make(map[string]bool)
+	for _, field := range b.Fields() {
+		seen[field.Name] = true
+		if old, ok := values[field.Name]; !ok || old != field.Value {
+			differences = append(differences, Difference{Field: field.Name, A: old, B: field.Value})
+		}
+	}
+	for _, name := range names {
+		if !seen[name] {
+			differences = append(differences, Difference{Field: name, A: values[name]})
+		}
+	}
+	return differences
+}
+
+// WriteDifferences writes each difference as two lines, A then B
+func WriteDifferences(out io.Writer, differences []Difference) error {
+	if len(differences) == 0 {
+		_, err := fmt.Fprintln(out, "The transactions are identical")
+		return err
+	}
+	for _, difference := range differences {
+		fmt.Fprintf(out, "%s:\n  a: %s\n  b: %s\n", difference.Field, orNone(difference.A), orNone(difference.B))
+	}
+	_, err := fmt.Fprintf(out, "%d field(s) differ\n", len(differences))
+	return err
+}
+
+// orNone renders a missing field
+func orNone(value string) string {
+	if value == "" {
+		return "(none)"
+	}
+	return value
+}
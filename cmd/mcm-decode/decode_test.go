@@ -0,0 +1,201 @@
+package main
+
+import (
+	"encoding/hex"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/NickP005/Vindax-MCM-tools/pkg/cli"
+	"github.com/NickP005/Vindax-MCM-tools/pkg/txbuild"
+	"github.com/NickP005/Vindax-MCM-tools/pkg/txentry"
+	"github.com/NickP005/Vindax-MCM-tools/pkg/wotsp"
+)
+
+// signedHex is a signed transfer of amount to "INV-12" with a fee of fee, as the hex the wallet tools print
+func signedHex(t testing.TB, amount uint64, fee uint64) string {
+	t.Helper()
+	key, next := wotsp.Keygen([32]byte{1}), wotsp.Keygen([32]byte{2})
+	tag := key.AddrHash()
+	payment, err := txbuild.NewDestination([txentry.TagLength]byte{0x42}, "INV-12", amount)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tx, err := txbuild.NewTransfer(txbuild.Address(tag, key.PublicKey), txbuild.Address(tag, next.PublicKey), 10000, fee, []txentry.Destination{payment})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := txbuild.Sign(tx, &key); err != nil {
+		t.Fatal(err)
+	}
+	return hex.EncodeToString(tx.Bytes())
+}
+
+func TestDecodeHex(t *testing.T) {
+	if raw, err := decodeHex("0xA0ff"); err != nil || hex.EncodeToString(raw) != "a0ff" {
+		t.Errorf("valid hex: %x, %v", raw, err)
+	}
+	for text, position := range map[string]int{
+		"":       1,
+		"0x":     1,
+		"abc":    3,
+		"zz":     1,
+		"0xa0g0": 3,
+		"a0f ":   4,
+		"a0\n0":  3,
+	} {
+		_, err := decodeHex(text)
+		var hexErr *HexError
+		if !errors.As(err, &hexErr) || hexErr.Position != position {
+			t.Errorf("%q: %v, want position %d", text, err, position)
+		}
+	}
+}
+
+func TestReadInput(t *testing.T) {
+	signed := signedHex(t, 2500, 500)
+	dir := t.TempDir()
+	plain := filepath.Join(dir, "tx.hex")
+	os.WriteFile(plain, []byte("\n  0x"+signed+"\n"), 0600)
+	request := filepath.Join(dir, "submit.json")
+	os.WriteFile(request, []byte(`{"network_identifier":{},"signed_transaction":"`+signed+`"}`), 0600)
+	for _, input := range []string{signed, "0x" + signed, plain, request} {
+		text, err := readInput(input)
+		if err == nil {
+			var raw []byte
+			raw, err = decodeHex(text)
+			if err == nil && hex.EncodeToString(raw) != signed {
+				t.Errorf("%.20s: other bytes read", input)
+			}
+		}
+		if err != nil {
+			t.Errorf("%.20s: %v", input, err)
+		}
+	}
+
+	// A short argument is a file name, not hex
+	if isHexText("abcd") || !isHexText("0xab") || isHexText(strings.Repeat("g", 64)) {
+		t.Error("isHexText")
+	}
+	empty := filepath.Join(dir, "empty.json")
+	os.WriteFile(empty, []byte(`{"signed_transaction":""}`), 0600)
+	for input, message := range map[string]string{
+		filepath.Join(dir, "missing"): "no such file",
+		empty:                         "without a signed_transaction field",
+	} {
+		if _, err := readInput(input); err == nil || !strings.Contains(err.Error(), message) {
+			t.Errorf("%s: %v", filepath.Base(input), err)
+		}
+	}
+}
+
+func TestLoad(t *testing.T) {
+	signed := signedHex(t, 2500, 500)
+	d, code, err := load(signed)
+	if err != nil || code != cli.ExitOK {
+		t.Fatalf("exit %d, %v", code, err)
+	}
+	if !d.SignatureValid || len(d.Problems) != 0 || d.Total == nil || *d.Total != 10000 || d.Fee != 500 {
+		t.Errorf("decoded %+v", d)
+	}
+	if len(d.Destinations) != 1 || d.Destinations[0].Memo != "INV-12" || !d.Destinations[0].MemoValid {
+		t.Errorf("destinations %+v", d.Destinations)
+	}
+
+	for _, tc := range []struct {
+		name    string
+		input   string
+		message string
+	}{
+		{"not hex", "0x" + signed[:10] + "x" + signed[11:], "hex position 11"},
+		{"odd length", "0x" + signed[:len(signed)-1], "odd number of hex digits"},
+		// The change address starts at byte 44, hex position 89
+		{"truncated", "0x" + signed[:100], "offset 44 (change address): truncated: 6 bytes left, need 40 (hex position 89)"},
+		{"unknown scheme", "0x0001" + signed[4:], "offset 1 (options): unknown signature scheme 0x01 (hex position 3)"},
+	} {
+		_, code, err := load(tc.input)
+		if code != ExitMalformed || err == nil || !strings.Contains(err.Error(), tc.message) {
+			t.Errorf("%s: exit %d, %v", tc.name, code, err)
+		}
+	}
+}
+
+func TestDescribeProblems(t *testing.T) {
+	raw, _ := hex.DecodeString(signedHex(t, 2500, 500))
+	// A destination amount changed after signing: the totals no longer add up and the signature fails
+	raw[txentry.HeaderLength+txentry.TagLength+txentry.ReferenceLength]++
+	tx, err := txentry.Decode(raw)
+	if err != nil {
+		t.Fatal(err)
+	}
+	d := Describe(raw, tx)
+	if d.SignatureValid || d.SignatureError != "" || d.SignedBy == "" {
+		t.Errorf("signature valid %v, signed by %q, %s", d.SignatureValid, d.SignedBy, d.SignatureError)
+	}
+	if len(d.Problems) != 1 || d.Problems[0] != "the destinations add up to 2501, the send total is 2500" {
+		t.Errorf("problems %q", d.Problems)
+	}
+	var signature string
+	for _, field := range d.Fields() {
+		if field.Name == "signature" {
+			signature = field.Value
+		}
+	}
+	if !strings.HasPrefix(signature, "DOES NOT VERIFY") {
+		t.Errorf("signature printed as %q", signature)
+	}
+}
+
+func TestCompare(t *testing.T) {
+	a, _, _ := load(signedHex(t, 2500, 500))
+	b, _, _ := load(signedHex(t, 2500, 600))
+	if differences := Compare(a, a); len(differences) != 0 {
+		t.Errorf("same transaction: %+v", differences)
+	}
+	var names []string
+	for _, difference := range Compare(a, b) {
+		names = append(names, difference.Field)
+	}
+	// The fee moves the change total, and the signed message
+	if got := strings.Join(names, ", "); got != "change total, fee, message hash" {
+		t.Errorf("differences: %s", got)
+	}
+
+	// A field only one side has is listed with the other side empty
+	b.Problems = []string{"extra"}
+	differences := Compare(b, a)
+	last := differences[len(differences)-1]
+	if last.Field != "problem 1" || last.A != "extra" || last.B != "" {
+		t.Errorf("missing field: %+v", last)
+	}
+	var out strings.Builder
+	WriteDifferences(&out, differences)
+	if !strings.Contains(out.String(), "problem 1:\n  a: extra\n  b: (none)\n") || !strings.HasSuffix(out.String(), "4 field(s) differ\n") {
+		t.Errorf("printed as %q", out.String())
+	}
+}
+
+// FuzzDecodeHex feeds any text to the decoder: it never panics, and its errors carry a position inside the text
+func FuzzDecodeHex(f *testing.F) {
+	signed := signedHex(f, 2500, 500)
+	f.Add(signed)
+	f.Add("0x" + signed[:300])
+	f.Add("0x00ff")
+	f.Add("0xzz")
+	f.Add("")
+	f.Fuzz(func(t *testing.T, text string) {
+		raw, err := decodeHex(text)
+		if err != nil {
+			var hexErr *HexError
+			if !errors.As(err, &hexErr) || hexErr.Position < 1 || hexErr.Position > max(len(text), 1) {
+				t.Fatalf("unpositioned error for %q: %v", text, err)
+			}
+			return
+		}
+		if tx, err := txentry.Decode(raw); err == nil {
+			Describe(raw, tx).Fields()
+		}
+	})
+}
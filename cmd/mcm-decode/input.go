@@ -0,0 +1,111 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// HexError is returned for input that is not hex; Position counts the characters of the hex text from 1, after any 0x
+type HexError struct {
+	Position int
+	Reason   string
+}
+
+func (e *HexError) Error() string {
+	return fmt.Sprintf("hex position %d: %s", e.Position, e.Reason)
+}
+
+/*
+ * readInput loads a signed transaction given as hex, as a path to a file,
+ * or as "-" for stdin
+ *
+ * The content may be the hex itself, with or without 0x and surrounding
+ * whitespace, or a JSON object with a signed_transaction field such as the
+ * /construction/submit request printed by tool-3.
+ */
+func readInput(input string) (string, error) {
+	var data []byte
+	var err error
+	switch {
+	case input == "-":
+		data, err = io.ReadAll(os.Stdin)
+	case isHexText(input):
+		data = []byte(input)
+	default:
+		data, err = os.ReadFile(input)
+	}
+	if err != nil {
+		return "", err
+	}
+
+	text := strings.TrimSpace(string(data))
+	if strings.HasPrefix(text, "{") {
+		var request struct {
+			SignedTransaction string `json:"signed_transaction"`
+		}
+		if err := json.Unmarshal([]byte(text), &request); err != nil {
+			return "", fmt.Errorf("invalid JSON input: %v", err)
+		}
+		if request.SignedTransaction == "" {
+			return "", fmt.Errorf("JSON input without a signed_transaction field")
+		}
+		text = request.SignedTransaction
+	}
+	return text, nil
+}
+
+// isHexText reports whether an argument is hex rather than a file name: 0x prefixed, or long and made of hex digits only
+func isHexText(s string) bool {
+	if strings.HasPrefix(s, "0x") || strings.HasPrefix(s, "0X") {
+		return true
+	}
+	if len(s) < 64 {
+		return false
+	}
+	for _, c := range s {
+		if !strings.ContainsRune("0123456789abcdefABCDEF", c) {
+			return false
+		}
+	}
+	return true
+}
+
+// decodeHex decodes hex text, reporting the position of the first character that is not a hex digit
+func decodeHex(text string) ([]byte, error) {
+	text = strings.TrimPrefix(strings.TrimPrefix(text, "0x"), "0X")
+	if text == "" {
+		return nil, &HexError{Position: 1, Reason: "empty transaction"}
+	}
+	raw := make([]byte, 0, len(text)/2)
+	for i := 0; i < len(text); i += 2 {
+		hi, ok := hexDigit(text[i])
+		if !ok {
+			return nil, &HexError{Position: i + 1, Reason: fmt.Sprintf("%q is not a hex digit", text[i])}
+		}
+		if i+1 == len(text) {
+			return nil, &HexError{Position: i + 1, Reason: fmt.Sprintf("odd number of hex digits (%d): a digit is missing or extra", len(text))}
+		}
+		lo, ok := hexDigit(text[i+1])
+		if !ok {
+			return nil, &HexError{Position: i + 2, Reason: fmt.Sprintf("%q is not a hex digit", text[i+1])}
+		}
+		raw = append(raw, hi<<4|lo)
+	}
+	return raw, nil
+}
+
+// hexDigit returns the value of a hex digit
+func hexDigit(c byte) (byte, bool) {
+	switch {
+	case '0' <= c && c <= '9':
+		return c - '0', true
+	case 'a' <= c && c <= 'f':
+		return c - 'a' + 10, true
+	case 'A' <= c && c <= 'F':
+		return c - 'A' + 10, true
+	}
+	return 0, false
+}
@@ -0,0 +1,103 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/NickP005/Vindax-MCM-tools/pkg/cli"
+	"github.com/NickP005/Vindax-MCM-tools/pkg/txentry"
+)
+
+// Exit codes of mcm-decode beyond those of pkg/cli, stable for use from shell scripts
+const (
+	ExitMalformed = 3 // the input is not a transaction: not hex, or not the TXENTRY layout
+)
+
+/*
+ * load reads and decodes one transaction
+ *
+ * Errors are positioned: a hex error gives the character, a layout error
+ * the byte offset and field along with the matching hex position.
+ */
+func load(input string) (Decoded, int, error) {
+	text, err := readInput(input)
+	if err != nil {
+		return Decoded{}, cli.ExitUsage, err
+	}
+	raw, err := decodeHex(text)
+	if err != nil {
+		return Decoded{}, ExitMalformed, err
+	}
+	tx, err := txentry.Decode(raw)
+	var decodeErr *txentry.DecodeError
+	if errors.As(err, &decodeErr) {
+		return Decoded{}, ExitMalformed, fmt.Errorf("%v (hex position %d)", err, 2*decodeErr.Offset+1)
+	}
+	if err != nil {
+		return Decoded{}, ExitMalformed, err
+	}
+	return Describe(raw, tx), cli.ExitOK, nil
+}
+
+func main() {
+	asJSON := flag.Bool("json", false, "Output JSON")
+	compare := flag.String("compare", "", "Another transaction (hex, file or -) to diff against, field by field")
+	flag.Usage = func() {
+		out := flag.CommandLine.Output()
+		fmt.Fprintln(out, "Usage: mcm-decode [flags] <hex|file|->")
+		fmt.Fprintln(out, "       mcm-decode [flags] -compare <hex|file|-> <hex|file|->")
+		flag.PrintDefaults()
+	}
+
+	cli.Parse(nil)
+	if flag.NArg() != 1 {
+		flag.Usage()
+		os.Exit(cli.ExitUsage)
+	}
+	if flag.Arg(0) == "-" && *compare == "-" {
+		cli.Usagef("only one of the transactions can be read from stdin")
+	}
+
+	a, code, err := load(flag.Arg(0))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(code)
+	}
+
+	if *compare != "" {
+		b, code, err := load(*compare)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error in -compare: %v\n", err)
+			os.Exit(code)
+		}
+		differences := Compare(a, b)
+		if *asJSON {
+			err = WriteJSON(os.Stdout, differences)
+		} else {
+			err = WriteDifferences(os.Stdout, differences)
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error writing output: %v\n", err)
+			os.Exit(cli.ExitUsage)
+		}
+		if len(differences) > 0 {
+			os.Exit(cli.ExitFailure)
+		}
+		return
+	}
+
+	if *asJSON {
+		err = WriteJSON(os.Stdout, a)
+	} else {
+		err = WriteText(os.Stdout, a)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error writing output: %v\n", err)
+		os.Exit(cli.ExitUsage)
+	}
+	if !a.SignatureValid || len(a.Problems) > 0 {
+		os.Exit(cli.ExitFailure)
+	}
+}
@@ -0,0 +1,31 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"text/tabwriter"
+
+	"github.com/NickP005/Vindax-MCM-tools/pkg/amount"
+)
+
+// formatAmount renders a nanoMCM amount with its MCM value
+func formatAmount(nano uint64) string {
+	return amount.Describe(nano)
+}
+
+// WriteText writes the fields of a decoded transaction, one aligned line each
+func WriteText(out io.Writer, d Decoded) error {
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	for _, field := range d.Fields() {
+		fmt.Fprintf(w, "%s:\t%s\n", field.Name, field.Value)
+	}
+	return w.Flush()
+}
+
+// WriteJSON writes v as indented JSON
+func WriteJSON(out io.Writer, v interface{}) error {
+	encoder := json.NewEncoder(out)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(v)
+}
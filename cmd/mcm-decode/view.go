@@ -0,0 +1,171 @@
+package main
+
+import (
+	"encoding/hex"
+	"fmt"
+	"math"
+
+	"github.com/NickP005/Vindax-MCM-tools/pkg/mcmaddr"
+	"github.com/NickP005/Vindax-MCM-tools/pkg/txentry"
+)
+
+// AddressView is an address of the transaction in every representation
+type AddressView struct {
+	Hex    string `json:"hex"`
+	Tag    string `json:"tag"`
+	Base58 string `json:"base58"`
+}
+
+// DestinationView is a payment of the transaction
+type DestinationView struct {
+	Tag       string `json:"tag"`
+	Base58    string `json:"base58"`
+	Amount    uint64 `json:"amount"`
+	Memo      string `json:"memo,omitempty"`
+	MemoValid bool   `json:"memoValid"`
+}
+
+/*
+ * Decoded is every field of a signed transaction, with the checks made on it
+ *
+ * Problems lists the inconsistencies found (totals, memos); a transaction
+ * with none and a verified signature is well-formed, which does not mean
+ * the network accepts it (balance, fee, BTL).
+ */
+type Decoded struct {
+	Length          int               `json:"length"`
+	SignedLength    int               `json:"signedLength"`
+	Source          AddressView       `json:"source"`
+	Change          AddressView       `json:"change"`
+	Destinations    []DestinationView `json:"destinations"`
+	SendTotal       uint64            `json:"sendTotal"`
+	ChangeTotal     uint64            `json:"changeTotal"`
+	Fee             uint64            `json:"fee"`
+	Total           *uint64           `json:"total,omitempty"`
+	BlockToLive     uint64            `json:"blockToLive"`
+	SignatureScheme string            `json:"signatureScheme"`
+	MessageHash     string            `json:"messageHash"`
+	SignatureValid  bool              `json:"signatureValid"`
+	SignedBy        string            `json:"signedBy,omitempty"`
+	SignatureError  string            `json:"signatureError,omitempty"`
+	Nonce           *uint64           `json:"nonce,omitempty"`
+	TxID            string            `json:"txId,omitempty"`
+	Problems        []string          `json:"problems"`
+}
+
+// addressView renders a 40 bytes address
+func addressView(address [txentry.AddressLength]byte) AddressView {
+	var tag [mcmaddr.TagLength]byte
+	copy(tag[:], address[:mcmaddr.TagLength])
+	return AddressView{Hex: hex.EncodeToString(address[:]), Tag: mcmaddr.ToHex(tag), Base58: mcmaddr.To58(tag)}
+}
+
+// Describe lays out a decoded transaction and checks its totals, memos and signature
+func Describe(raw []byte, tx *txentry.Transaction) Decoded {
+	message := tx.MessageHash()
+	d := Decoded{
+		Length:          len(raw),
+		SignedLength:    tx.SignedLength(),
+		Source:          addressView(tx.Source),
+		Change:          addressView(tx.Change),
+		SendTotal:       tx.SendTotal,
+		ChangeTotal:     tx.ChangeTotal,
+		Fee:             tx.Fee,
+		BlockToLive:     tx.BlockToLive,
+		SignatureScheme: tx.SignatureScheme(),
+		MessageHash:     hex.EncodeToString(message[:]),
+		Problems:        []string{},
+	}
+	if tx.HasTrailer {
+		nonce := tx.Nonce
+		d.Nonce = &nonce
+		d.TxID = hex.EncodeToString(tx.ID[:])
+	}
+
+	sum, overflow := uint64(0), false
+	for i, dst := range tx.Destinations {
+		d.Destinations = append(d.Destinations, DestinationView{
+			Tag:       mcmaddr.ToHex(dst.Tag),
+			Base58:    mcmaddr.To58(dst.Tag),
+			Amount:    dst.Amount,
+			Memo:      dst.Memo(),
+			MemoValid: dst.ValidMemo(),
+		})
+		if !dst.ValidMemo() {
+			d.Problems = append(d.Problems, fmt.Sprintf("destination %d: memo %q does not follow the reference rules", i+1, dst.Memo()))
+		}
+		overflow = overflow || sum > math.MaxUint64-dst.Amount
+		sum += dst.Amount
+	}
+	switch {
+	case overflow:
+		d.Problems = append(d.Problems, "the destination amounts overflow")
+	case sum != tx.SendTotal:
+		d.Problems = append(d.Problems, fmt.Sprintf("the destinations add up to %d, the send total is %d", sum, tx.SendTotal))
+	}
+	if tx.SendTotal <= math.MaxUint64-tx.ChangeTotal && tx.SendTotal+tx.ChangeTotal <= math.MaxUint64-tx.Fee {
+		total := tx.SendTotal + tx.ChangeTotal + tx.Fee
+		d.Total = &total
+	} else {
+		d.Problems = append(d.Problems, "send + change + fee overflows")
+	}
+
+	valid, signedBy, err := tx.VerifySignature()
+	d.SignatureValid = valid
+	if err != nil {
+		d.SignatureError = err.Error()
+	} else {
+		d.SignedBy = hex.EncodeToString(signedBy[:])
+	}
+	return d
+}
+
+// Field is one line of a decoded transaction, as printed and compared
+type Field struct {
+	Name  string
+	Value string
+}
+
+// Fields flattens a decoded transaction into named lines, in print order
+func (d Decoded) Fields() []Field {
+	fields := []Field{
+		{"length", fmt.Sprintf("%d bytes (%d signed)", d.Length, d.SignedLength)},
+		{"source", fmt.Sprintf("%s (%s)", d.Source.Base58, d.Source.Hex)},
+		{"change", fmt.Sprintf("%s (%s)", d.Change.Base58, d.Change.Hex)},
+	}
+	for i, dst := range d.Destinations {
+		value := fmt.Sprintf("%s (%s) %s", dst.Base58, dst.Tag, formatAmount(dst.Amount))
+		if dst.Memo != "" {
+			value += fmt.Sprintf(" memo %q", dst.Memo)
+		}
+		fields = append(fields, Field{fmt.Sprintf("destination %d", i+1), value})
+	}
+	total := "overflows"
+	if d.Total != nil {
+		total = formatAmount(*d.Total)
+	}
+	fields = append(fields,
+		Field{"send total", formatAmount(d.SendTotal)},
+		Field{"change total", formatAmount(d.ChangeTotal)},
+		Field{"fee", formatAmount(d.Fee)},
+		Field{"source total", total},
+		Field{"block-to-live", fmt.Sprint(d.BlockToLive)},
+		Field{"signature scheme", d.SignatureScheme},
+		Field{"message hash", d.MessageHash},
+	)
+	signature := "verifies"
+	switch {
+	case d.SignatureError != "":
+		signature = "cannot be checked: " + d.SignatureError
+	case !d.SignatureValid:
+		signature = fmt.Sprintf("DOES NOT VERIFY: signed by address hash %s, not the source's", d.SignedBy)
+	}
+	fields = append(fields, Field{"signature", signature})
+	if d.Nonce != nil {
+		fields = append(fields, Field{"nonce", fmt.Sprint(*d.Nonce)}, Field{"transaction ID", d.TxID})
+	}
+	for i, problem := range d.Problems {
+		fields = append(fields, Field{fmt.Sprintf("problem %d", i+1), problem})
+	}
+	return fields
+}
@@ -0,0 +1,105 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/NickP005/Vindax-MCM-tools/pkg/fileutil"
+	"github.com/NickP005/Vindax-MCM-tools/pkg/meshclient"
+)
+
+// CachedBlock is the fees of a block with the time they were read
+type CachedBlock struct {
+	meshclient.BlockFees
+	ReadAt time.Time `json:"readAt"`
+}
+
+/*
+ * FeeCache is the cache file: the blocks read by the previous runs against
+ * one API
+ *
+ * Blocks do not change once mined, so the cache is only bounded in time to
+ * keep it small and to limit how long a block replaced by a reorg the
+ * check of RecentFees cannot see, e.g. below a failed block, is trusted.
+ */
+type FeeCache struct {
+	API    string        `json:"api"`
+	Blocks []CachedBlock `json:"blocks"`
+}
+
+// defaultCachePath returns the cache file in the user cache directory, or "" when there is none
+func defaultCachePath() string {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(dir, "vindax-mcm-tools", "mcm-feestat.json")
+}
+
+// Known returns the blocks read from api less than ttl ago by height, and the time each was read
+func (c *FeeCache) Known(api string, ttl time.Duration) (map[uint64]meshclient.BlockFees, map[uint64]time.Time) {
+	known := make(map[uint64]meshclient.BlockFees)
+	readAt := make(map[uint64]time.Time)
+	if c == nil || c.API != api {
+		return known, readAt
+	}
+	for _, block := range c.Blocks {
+		if time.Since(block.ReadAt) < ttl {
+			known[block.Height] = block.BlockFees
+			readAt[block.Height] = block.ReadAt
+		}
+	}
+	return known, readAt
+}
+
+// ReadFeeCache reads a cache file written by WriteFeeCache; a missing file returns nil and no error
+func ReadFeeCache(path string) (*FeeCache, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var cache FeeCache
+	if err := json.Unmarshal(data, &cache); err != nil {
+		return nil, fmt.Errorf("invalid cache file %s: %v", path, err)
+	}
+	return &cache, nil
+}
+
+// WriteFeeCache replaces the cache file atomically, see fileutil.WriteAtomic
+func WriteFeeCache(path string, cache FeeCache) error {
+	data, err := json.Marshal(cache)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	return fileutil.WriteAtomic(path, append(data, '\n'), false)
+}
+
+/*
+ * updateCache returns the cache to write after a run: the blocks of the
+ * sample, with the time they were read
+ *
+ * Blocks reused from the cache, listed in readAt, keep their time, so a
+ * block is read again ttl after it was first read however often the tool
+ * runs.
+ */
+func updateCache(api string, readAt map[uint64]time.Time, sample *meshclient.FeeSample, now time.Time) FeeCache {
+	cache := FeeCache{API: api, Blocks: []CachedBlock{}}
+	for _, block := range sample.Blocks {
+		read, ok := readAt[block.Height]
+		if !ok {
+			read = now
+		}
+		cache.Blocks = append(cache.Blocks, CachedBlock{BlockFees: block, ReadAt: read})
+	}
+	return cache
+}
@@ -0,0 +1,103 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/NickP005/Vindax-MCM-tools/pkg/meshclient"
+)
+
+func TestFeeCache(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cache", "mcm-feestat.json")
+	if cache, err := ReadFeeCache(path); cache != nil || err != nil {
+		t.Fatalf("missing file: %v, %v", cache, err)
+	}
+	// A run without cache knows nothing
+	var none *FeeCache
+	if known, readAt := none.Known("http://api", time.Minute); len(known) != 0 || len(readAt) != 0 {
+		t.Error("nil cache knows blocks")
+	}
+
+	now := time.Now()
+	old := now.Add(-10 * time.Minute)
+	sample := &meshclient.FeeSample{Blocks: []meshclient.BlockFees{
+		{Height: 7, Hash: "0x07", Fees: []uint64{500}},
+		{Height: 8, Hash: "0x08", Fees: []uint64{}},
+	}}
+	// Block 7 was reused from an earlier run and keeps its read time
+	cache := updateCache("http://api", map[uint64]time.Time{7: old}, sample, now)
+	if err := WriteFeeCache(path, cache); err != nil {
+		t.Fatal(err)
+	}
+	loaded, err := ReadFeeCache(path)
+	if err != nil || loaded.API != "http://api" || len(loaded.Blocks) != 2 || !loaded.Blocks[0].ReadAt.Equal(old) || !loaded.Blocks[1].ReadAt.Equal(now) {
+		t.Fatalf("loaded %+v, %v", loaded, err)
+	}
+
+	known, readAt := loaded.Known("http://api", 5*time.Minute)
+	if len(known) != 1 || known[8].Hash != "0x08" || !readAt[8].Equal(now) {
+		t.Errorf("within 5m: %v", known)
+	}
+	if known, _ := loaded.Known("http://api", time.Hour); len(known) != 2 || known[7].Fees[0] != 500 {
+		t.Errorf("within 1h: %v", known)
+	}
+	if known, _ := loaded.Known("http://other", time.Hour); len(known) != 0 {
+		t.Error("blocks of another API reused")
+	}
+
+	os.WriteFile(path, []byte("{"), 0600)
+	if _, err := ReadFeeCache(path); err == nil || !strings.Contains(err.Error(), "invalid cache file") {
+		t.Errorf("corrupt file: %v", err)
+	}
+}
+
+func TestWriteReport(t *testing.T) {
+	sample := &meshclient.FeeSample{From: 1, To: 4, Failed: []uint64{3}, Blocks: []meshclient.BlockFees{
+		{Height: 1, Fees: []uint64{500, 500, 500, 1000}},
+		{Height: 2, Fees: []uint64{}},
+		{Height: 4, Fees: []uint64{500}},
+	}}
+	report := newReport(sample, 2, 2)
+	if report.Blocks != 3 || report.EmptyBlocks != 1 || report.Stats.Transactions != 5 || len(report.Stats.Histogram) != 2 {
+		t.Fatalf("report %+v", report)
+	}
+
+	var out bytes.Buffer
+	if err := WriteReport(&out, report); err != nil {
+		t.Fatal(err)
+	}
+	text := out.String()
+	for _, want := range []string{
+		"Blocks 1 to 4: 3 read (2 from cache), 1 empty, 1 failed\n",
+		"Fees of 5 transactions:\n",
+		"median  500 nMCM",
+		"max     1000 nMCM",
+		"   500 - 750  4 " + strings.Repeat("#", BAR_WIDTH) + "\n",
+		"  751 - 1000  1 " + strings.Repeat("#", BAR_WIDTH/4) + "\n",
+	} {
+		if !strings.Contains(text, want) {
+			t.Errorf("output lacks %q:\n%s", want, text)
+		}
+	}
+
+	// No fee at all, and the JSON lists no failed block as []
+	empty := newReport(&meshclient.FeeSample{Blocks: []meshclient.BlockFees{{Fees: []uint64{}}}}, 0, 10)
+	out.Reset()
+	WriteReport(&out, empty)
+	if !strings.HasSuffix(out.String(), "No transaction paid a fee in these blocks\n") {
+		t.Errorf("empty output %q", out.String())
+	}
+	out.Reset()
+	if err := WriteJSON(&out, empty); err != nil {
+		t.Fatal(err)
+	}
+	var decoded map[string]interface{}
+	if err := json.Unmarshal(out.Bytes(), &decoded); err != nil || decoded["failedBlocks"] == nil || decoded["stats"].(map[string]interface{})["histogram"] == nil {
+		t.Errorf("JSON %s: %v", out.String(), err)
+	}
+}
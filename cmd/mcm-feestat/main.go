@@ -0,0 +1,121 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/NickP005/Vindax-MCM-tools/pkg/cli"
+	"github.com/NickP005/Vindax-MCM-tools/pkg/config"
+	"github.com/NickP005/Vindax-MCM-tools/pkg/meshclient"
+	"github.com/NickP005/Vindax-MCM-tools/pkg/shutdown"
+)
+
+// Exit codes of mcm-feestat beyond those of pkg/cli, stable for use from shell scripts
+const (
+	ExitIncomplete = 3 // the statistics leave out blocks that could not be read
+)
+
+// newMeshClient returns a Mesh API client identifying mcm-feestat in its User-Agent, retrying failed reads
+func newMeshClient(cfg *config.Config, concurrency int) *meshclient.MeshAPIClient {
+	client := meshclient.NewMeshAPIClient(cfg.API, nil)
+	cfg.Apply(client)
+	client.SetUserAgent("mcm-feestat")
+	client.SetRetryPolicy(meshclient.DefaultRetryPolicy())
+	client.SetBatchConcurrency(concurrency)
+	return client
+}
+
+func main() {
+	cfg, err := config.Load(config.Defaults())
+	if err != nil {
+		cli.Usagef("%v", err)
+	}
+	cfg.APIFlags(flag.CommandLine, "Mesh API URL")
+	blocks := flag.Int("blocks", 100, "Number of blocks to analyze, the tip included")
+	concurrency := flag.Int("concurrency", 8, "Maximum concurrent block reads")
+	buckets := flag.Int("buckets", meshclient.DefaultHistogramBuckets, "Number of histogram buckets")
+	asJSON := flag.Bool("json", false, "Output the statistics and the histogram as JSON")
+	cachePath := flag.String("cache", defaultCachePath(), "Cache file of the blocks read, \"\" to disable the cache")
+	cacheTTL := flag.Duration("cache-ttl", 5*time.Minute, "Read a cached block again after this long")
+	cfg.TimeoutFlag(flag.CommandLine, "Give up the reads not made after this long")
+	printConfig := cfg.PrintFlag(flag.CommandLine)
+	flag.Usage = func() {
+		fmt.Fprintln(flag.CommandLine.Output(), "Usage: mcm-feestat [flags]")
+		flag.PrintDefaults()
+	}
+
+	cli.Parse(cfg)
+	if *printConfig {
+		cfg.Print(os.Stdout)
+		return
+	}
+	switch {
+	case flag.NArg() > 0:
+		cli.Usagef("unexpected argument %q", flag.Arg(0))
+	case *blocks <= 0:
+		cli.Usagef("-blocks must be positive")
+	case *buckets <= 0:
+		cli.Usagef("-buckets must be positive")
+	}
+
+	// A cache that cannot be read only means reading every block again
+	var cache *FeeCache
+	if *cachePath != "" {
+		if cache, err = ReadFeeCache(*cachePath); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: ignoring the cache: %v\n", err)
+		}
+	}
+	known, readAt := cache.Known(cfg.API, *cacheTTL)
+
+	// Interrupting the tool stops the reads in flight, nothing is printed
+	sig := shutdown.Notify()
+	defer sig.Stop()
+	ctx := sig.Context()
+	ctx, cancel := context.WithTimeout(ctx, cfg.Timeout)
+	defer cancel()
+
+	sample, err := newMeshClient(cfg, *concurrency).RecentFees(ctx, *blocks, known, func(block meshclient.BlockFees) {
+		// A block read again, e.g. after a reorg, gets a new read time in the cache
+		delete(readAt, block.Height)
+		if block.Err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: block %d: %v\n", block.Height, block.Err)
+		}
+	})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(cli.ExitFailure)
+	}
+	cached := len(sample.Blocks)
+	for _, block := range sample.Blocks {
+		if _, ok := readAt[block.Height]; !ok {
+			cached--
+		}
+	}
+
+	if *cachePath != "" {
+		if err := WriteFeeCache(*cachePath, updateCache(cfg.API, readAt, sample, time.Now())); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: failed to write the cache: %v\n", err)
+		}
+	}
+
+	report := newReport(sample, cached, *buckets)
+	if *asJSON {
+		err = WriteJSON(os.Stdout, report)
+	} else {
+		err = WriteReport(os.Stdout, report)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error writing output: %v\n", err)
+		os.Exit(cli.ExitUsage)
+	}
+
+	switch {
+	case len(sample.Blocks) == 0:
+		os.Exit(cli.ExitFailure)
+	case len(sample.Failed) > 0:
+		os.Exit(ExitIncomplete)
+	}
+}
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/NickP005/Vindax-MCM-tools/pkg/amount"
+	"github.com/NickP005/Vindax-MCM-tools/pkg/meshclient"
+)
+
+// BAR_WIDTH is the length in characters of the largest histogram bar
+const BAR_WIDTH = 40
+
+// Report is the JSON output: the range analyzed, how many blocks it held, and the statistics
+type Report struct {
+	From         uint64              `json:"from"`
+	To           uint64              `json:"to"`
+	Blocks       int                 `json:"blocks"`
+	EmptyBlocks  int                 `json:"emptyBlocks"`
+	FailedBlocks []uint64            `json:"failedBlocks"`
+	Cached       int                 `json:"cached"`
+	Stats        meshclient.FeeStats `json:"stats"`
+}
+
+// newReport returns the report of a sample, its statistics computed with buckets histogram buckets
+func newReport(sample *meshclient.FeeSample, cached int, buckets int) Report {
+	report := Report{
+		From:         sample.From,
+		To:           sample.To,
+		Blocks:       len(sample.Blocks),
+		EmptyBlocks:  sample.Empty(),
+		FailedBlocks: sample.Failed,
+		Cached:       cached,
+		Stats:        meshclient.SummarizeFees(sample.Fees(), buckets),
+	}
+	if report.FailedBlocks == nil {
+		report.FailedBlocks = []uint64{}
+	}
+	return report
+}
+
+// WriteReport writes the statistics, then the histogram with a bar per bucket
+func WriteReport(out io.Writer, report Report) error {
+	fmt.Fprintf(out, "Blocks %d to %d: %d read (%d from cache), %d empty, %d failed\n",
+		report.From, report.To, report.Blocks, report.Cached, report.EmptyBlocks, len(report.FailedBlocks))
+	stats := report.Stats
+	if stats.Transactions == 0 {
+		_, err := fmt.Fprintln(out, "No transaction paid a fee in these blocks")
+		return err
+	}
+	fmt.Fprintf(out, "Fees of %d transactions:\n", stats.Transactions)
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	for _, line := range []struct {
+		name  string
+		value uint64
+	}{{"min", stats.Min}, {"median", stats.Median}, {"p90", stats.P90}, {"max", stats.Max}} {
+		fmt.Fprintf(w, "  %s\t%s\n", line.name, amount.Describe(line.value))
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+
+	largest := 0
+	for _, bucket := range stats.Histogram {
+		largest = max(largest, bucket.Count)
+	}
+	fmt.Fprintln(out, "Histogram (nMCM):")
+	w = tabwriter.NewWriter(out, 0, 0, 2, ' ', tabwriter.AlignRight)
+	for _, bucket := range stats.Histogram {
+		bar := strings.Repeat("#", (bucket.Count*BAR_WIDTH+largest-1)/largest)
+		rangeText := fmt.Sprint(bucket.From)
+		if bucket.To != bucket.From {
+			rangeText += fmt.Sprintf(" - %d", bucket.To)
+		}
+		fmt.Fprintf(w, "  %s\t%d\t %s\n", rangeText, bucket.Count, bar)
+	}
+	return w.Flush()
+}
+
+// WriteJSON writes v as indented JSON
+func WriteJSON(out io.Writer, v interface{}) error {
+	encoder := json.NewEncoder(out)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(v)
+}
@@ -0,0 +1,14 @@
+module github.com/NickP005/Vindax-MCM-tools/cmd/mcm-paperwallet
+
+go 1.23.5
+
+require github.com/NickP005/Vindax-MCM-tools/pkg v0.0.0-00010101000000-000000000000
+
+require (
+	github.com/btcsuite/btcutil v1.0.2 // indirect
+	github.com/sigurn/crc16 v0.0.0-20240131213347-83fcde1e29d1 // indirect
+	golang.org/x/crypto v0.33.0 // indirect
+	golang.org/x/sys v0.30.0 // indirect
+)
+
+replace github.com/NickP005/Vindax-MCM-tools/pkg => ../../pkg
@@ -0,0 +1,33 @@
+package main
+
+import (
+	"crypto/rand"
+	"fmt"
+
+	"github.com/NickP005/Vindax-MCM-tools/pkg/mcmaddr"
+	"github.com/NickP005/Vindax-MCM-tools/pkg/secure"
+	"github.com/NickP005/Vindax-MCM-tools/pkg/wotsp"
+)
+
+// NewSeed returns a random 32 bytes secret seed, as wallet-tool creates for a new wallet cache
+func NewSeed() ([secure.KeyLength]byte, error) {
+	var seed [secure.KeyLength]byte
+	if _, err := rand.Read(seed[:]); err != nil {
+		return seed, fmt.Errorf("failed to generate random seed: %v", err)
+	}
+	return seed, nil
+}
+
+/*
+ * RefillAddress returns the base58 refill address of a seed: the wallet
+ * tag, address hash of the keypair at index 0 of its keychain, as
+ * wallet-tool computes it
+ *
+ * The keypair is wiped as soon as its public key is hashed.
+ */
+func RefillAddress(seed [secure.KeyLength]byte) (string, error) {
+	defer secure.Wipe(seed[:])
+	keypair := wotsp.KeychainKeygen(seed, 0)
+	defer keypair.Wipe()
+	return mcmaddr.To58(keypair.AddrHash()), nil
+}
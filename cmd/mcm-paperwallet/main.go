@@ -0,0 +1,200 @@
+package main
+
+import (
+	"bufio"
+	"encoding/hex"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/NickP005/Vindax-MCM-tools/pkg/bip39"
+	"github.com/NickP005/Vindax-MCM-tools/pkg/cli"
+	"github.com/NickP005/Vindax-MCM-tools/pkg/secure"
+)
+
+// Exit codes of mcm-paperwallet beyond those of pkg/cli, stable for use from shell scripts
+const (
+	ExitMismatch = 3 // -verify: the sheet is incomplete, or its seed, mnemonic and address disagree
+)
+
+/*
+ * readSeed returns the seed of -seed, read from standard input for "-" so
+ * it stays out of the process list and the shell history
+ */
+func readSeed(value string) ([secure.KeyLength]byte, error) {
+	if value != "-" {
+		return secure.DecodeKey([]byte(value))
+	}
+	reader := bufio.NewReader(os.Stdin)
+	line, err := reader.ReadSlice('\n')
+	defer secure.Wipe(line)
+	if err != nil && len(line) == 0 {
+		return [secure.KeyLength]byte{}, fmt.Errorf("failed to read the seed from standard input: %v", err)
+	}
+	return secure.DecodeKey(line)
+}
+
+// writeNew writes data to a file that must not exist yet, readable by its owner only
+func writeNew(path string, data []byte) error {
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+	if errors.Is(err, os.ErrExist) {
+		return fmt.Errorf("%s already exists, refusing to overwrite it", path)
+	}
+	if err != nil {
+		return err
+	}
+	_, err = file.Write(data)
+	if err == nil {
+		err = file.Sync()
+	}
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(path)
+		return fmt.Errorf("failed to write %s: %v", path, err)
+	}
+	return nil
+}
+
+// NewSheet builds the sheet of a seed, wiping the seed; the caller wipes the sheet
+func NewSheet(seed [secure.KeyLength]byte, created time.Time) (*Sheet, error) {
+	defer secure.Wipe(seed[:])
+	address, err := RefillAddress(seed)
+	if err != nil {
+		return nil, err
+	}
+	mnemonic, err := bip39.Mnemonic(seed[:])
+	if err != nil {
+		return nil, err
+	}
+	seedHex := make([]byte, hex.EncodedLen(len(seed)))
+	hex.Encode(seedHex, seed[:])
+	return &Sheet{Address: address, Created: created, SeedHex: seedHex, Mnemonic: mnemonic}, nil
+}
+
+/*
+ * Verify checks that a sheet is consistent: its mnemonic encodes its seed,
+ * and its seed derives its refill address
+ *
+ * Returns the address on success.
+ */
+func Verify(data []byte) (string, error) {
+	sheet, err := ParseSheet(data)
+	if err != nil {
+		return "", err
+	}
+	defer sheet.Wipe()
+
+	seed, err := secure.DecodeKey(sheet.SeedHex)
+	if err != nil {
+		return "", fmt.Errorf("invalid seed: %v", err)
+	}
+	defer secure.Wipe(seed[:])
+	entropy, err := bip39.Entropy(sheet.Mnemonic)
+	if err != nil {
+		return "", fmt.Errorf("invalid mnemonic: %v", err)
+	}
+	defer secure.Wipe(entropy)
+	if !secure.Equal(entropy, seed[:]) {
+		return "", fmt.Errorf("the mnemonic does not encode the seed")
+	}
+
+	address, err := RefillAddress(seed)
+	if err != nil {
+		return "", err
+	}
+	if address != sheet.Address {
+		return "", fmt.Errorf("the seed derives refill address %s, the sheet prints %s", address, sheet.Address)
+	}
+	return address, nil
+}
+
+func main() {
+	seedFlag := flag.String("seed", "", "Hex secret seed of the wallet, or - to read it from standard input")
+	newSeed := flag.Bool("new", false, "Generate a new random seed")
+	out := flag.String("out", "", "Sheet file to create, readable by its owner only; never overwritten")
+	asHTML := flag.Bool("html", false, "Write the sheet as an HTML page for printing instead of text")
+	verify := flag.String("verify", "", "Sheet file to verify: its seed must derive its refill address")
+	flag.Usage = func() {
+		fmt.Fprintln(flag.CommandLine.Output(), "Usage: mcm-paperwallet [-seed <hex>|-new] [-html] -out sheet.txt")
+		fmt.Fprintln(flag.CommandLine.Output(), "       mcm-paperwallet -verify sheet.txt")
+		flag.PrintDefaults()
+	}
+
+	cli.Parse(nil)
+	if flag.NArg() > 0 {
+		cli.Usagef("unexpected argument %q", flag.Arg(0))
+	}
+
+	if *verify != "" {
+		if *seedFlag != "" || *newSeed || *out != "" || *asHTML {
+			cli.Usagef("-verify takes no other flag")
+		}
+		data, err := os.ReadFile(*verify)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(cli.ExitFailure)
+		}
+		address, err := Verify(data)
+		secure.Wipe(data)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %s: %v\n", *verify, err)
+			os.Exit(ExitMismatch)
+		}
+		fmt.Printf("%s: OK, the seed and the mnemonic derive refill address %s\n", *verify, address)
+		os.Exit(cli.ExitOK)
+	}
+
+	if (*seedFlag != "") == *newSeed {
+		cli.Usagef("expected one of -seed and -new")
+	}
+	if *out == "" {
+		cli.Usagef("-out is required")
+	}
+	// Checked before deriving anything; writeNew still refuses a file created in between
+	if _, err := os.Lstat(*out); err == nil {
+		cli.Usagef("%s already exists, refusing to overwrite it", *out)
+	}
+
+	var seed [secure.KeyLength]byte
+	var err error
+	if *newSeed {
+		seed, err = NewSeed()
+	} else {
+		seed, err = readSeed(*seedFlag)
+	}
+	if err != nil && *newSeed {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(cli.ExitFailure)
+	}
+	if err != nil {
+		cli.Usagef("-seed: %v", err)
+	}
+	sheet, err := NewSheet(seed, time.Now())
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(cli.ExitFailure)
+	}
+
+	var data []byte
+	if *asHTML {
+		data, err = RenderHTML(sheet)
+	} else {
+		data, err = RenderText(sheet)
+	}
+	if err == nil {
+		err = writeNew(*out, data)
+		secure.Wipe(data)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		sheet.Wipe()
+		os.Exit(cli.ExitFailure)
+	}
+	fmt.Printf("Wrote the paper wallet of refill address %s to %s\n", sheet.Address, *out)
+	fmt.Printf("Print it, check it with: mcm-paperwallet -verify %s, then delete the file\n", *out)
+	sheet.Wipe()
+}
@@ -0,0 +1,251 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"html"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/NickP005/Vindax-MCM-tools/pkg/qrcode"
+	"github.com/NickP005/Vindax-MCM-tools/pkg/secure"
+)
+
+// Labels of the sheet sections, which -verify looks for
+const (
+	LABEL_ADDRESS  = "Refill address:"
+	LABEL_CREATED  = "Created:"
+	LABEL_SEED     = "Seed (hex):"
+	LABEL_MNEMONIC = "Mnemonic (BIP39 English, 24 words):"
+)
+
+// CUT_LINE separates the public part of the sheet from the secret one
+const CUT_LINE = "- - - - - - - - - - - - - - - 8< - - CUT HERE - - 8< - - - - - - - - - - - - - - -"
+
+// SVG_SCALE is the size in pixels of a QR code module in HTML sheets
+const SVG_SCALE = 6
+
+// MNEMONIC_ROWS is the number of rows of the word table, the words being numbered down the columns
+const MNEMONIC_ROWS = 6
+
+/*
+ * Sheet is the content of a paper wallet
+ *
+ * SeedHex and Mnemonic are byte slices, wiped by Wipe once the sheet is
+ * written or verified.
+ */
+type Sheet struct {
+	Address  string
+	Created  time.Time
+	SeedHex  []byte
+	Mnemonic []byte
+}
+
+// Wipe overwrites the secret parts of the sheet
+func (s *Sheet) Wipe() {
+	secure.Wipe(s.SeedHex)
+	secure.Wipe(s.Mnemonic)
+}
+
+// writeSeed writes the seed in groups of 8 hex digits, easier to copy by hand
+func writeSeed(b *bytes.Buffer, seedHex []byte) {
+	b.WriteString("  ")
+	for i := 0; i < len(seedHex); i += 8 {
+		if i > 0 {
+			b.WriteByte(' ')
+		}
+		b.Write(seedHex[i:min(i+8, len(seedHex))])
+	}
+	b.WriteByte('\n')
+}
+
+// writeMnemonic writes the numbered words in MNEMONIC_ROWS rows, numbered down the columns
+func writeMnemonic(b *bytes.Buffer, mnemonic []byte) {
+	words := bytes.Fields(mnemonic)
+	columns := (len(words) + MNEMONIC_ROWS - 1) / MNEMONIC_ROWS
+	for row := 0; row < MNEMONIC_ROWS; row++ {
+		b.WriteString(" ")
+		for column := 0; column < columns; column++ {
+			n := column*MNEMONIC_ROWS + row
+			if n >= len(words) {
+				break
+			}
+			fmt.Fprintf(b, " %2d. ", n+1)
+			b.Write(words[n])
+			// BIP39 English words have at most 8 letters
+			b.WriteString(strings.Repeat(" ", max(0, 9-len(words[n]))))
+		}
+		// Drop the padding of the last column
+		trimmed := bytes.TrimRight(b.Bytes(), " ")
+		b.Truncate(len(trimmed))
+		b.WriteByte('\n')
+	}
+}
+
+// writePublic writes the part of the sheet meant to be shared: the address and its QR code
+func writePublic(b *bytes.Buffer, sheet *Sheet, withQR bool) error {
+	b.WriteString("MCM 3.0 PAPER WALLET\n\n")
+	fmt.Fprintf(b, "%s\n  %s\n\n", LABEL_ADDRESS, sheet.Address)
+	fmt.Fprintf(b, "%s\n  %s\n\n", LABEL_CREATED, sheet.Created.UTC().Format("2006-01-02"))
+	b.WriteString("Send funds to the refill address: it is the wallet tag, all later keys of the wallet spend from it.\n")
+	if !withQR {
+		return nil
+	}
+	code, err := qrcode.Encode(sheet.Address)
+	if err != nil {
+		return err
+	}
+	b.WriteString("\n")
+	b.WriteString(code.Text())
+	return nil
+}
+
+// writeSecret writes the part of the sheet to cut off and keep offline: the seed and its mnemonic
+func writeSecret(b *bytes.Buffer, sheet *Sheet) {
+	b.WriteString("SECRET - anyone reading the seed or the words can spend every fund of this wallet.\n")
+	b.WriteString("Keep this part offline, never photograph or type it into a website.\n\n")
+	fmt.Fprintf(b, "%s\n", LABEL_SEED)
+	writeSeed(b, sheet.SeedHex)
+	fmt.Fprintf(b, "\n%s\n", LABEL_MNEMONIC)
+	writeMnemonic(b, sheet.Mnemonic)
+}
+
+/*
+ * RenderText returns the sheet as text: the address, its QR code and the
+ * creation date, then a cut line, then the seed and its mnemonic
+ *
+ * The caller wipes the result, which holds the secret.
+ */
+func RenderText(sheet *Sheet) ([]byte, error) {
+	var b bytes.Buffer
+	// Grown once, so no copy of the secret is left behind by a reallocation
+	b.Grow(32 * 1024)
+	if err := writePublic(&b, sheet, true); err != nil {
+		return nil, err
+	}
+	fmt.Fprintf(&b, "\n%s\n\n", CUT_LINE)
+	writeSecret(&b, sheet)
+	return b.Bytes(), nil
+}
+
+/*
+ * RenderHTML returns the sheet as a standalone HTML page for printing, the
+ * QR code as inline SVG
+ *
+ * The text of both parts is the one of RenderText, in pre blocks, so
+ * -verify reads HTML sheets too. The caller wipes the result.
+ */
+func RenderHTML(sheet *Sheet) ([]byte, error) {
+	code, err := qrcode.Encode(sheet.Address)
+	if err != nil {
+		return nil, err
+	}
+	var text bytes.Buffer
+	text.Grow(4 * 1024)
+	defer func() { secure.Wipe(text.Bytes()) }()
+
+	var b bytes.Buffer
+	b.Grow(64 * 1024)
+	b.WriteString("<!DOCTYPE html>\n<html>\n<head>\n<meta charset=\"utf-8\">\n<title>MCM paper wallet</title>\n")
+	b.WriteString("<style>\nbody { font-family: monospace; margin: 2em; }\npre { font-size: 11pt; }\n")
+	b.WriteString(".cut { border-top: 2px dashed #000; margin: 2em 0; padding-top: 0.5em; text-align: center; }\n</style>\n</head>\n<body>\n")
+
+	if err := writePublic(&text, sheet, false); err != nil {
+		return nil, err
+	}
+	b.WriteString("<pre>\n")
+	b.WriteString(html.EscapeString(text.String()))
+	b.WriteString("</pre>\n")
+	b.WriteString(code.SVG(SVG_SCALE))
+	fmt.Fprintf(&b, "\n<div class=\"cut\">%s</div>\n", html.EscapeString(CUT_LINE))
+
+	secure.Wipe(text.Bytes())
+	text.Reset()
+	writeSecret(&text, sheet)
+	// The secret holds hex digits and lowercase words only, nothing to escape
+	b.WriteString("<pre>\n")
+	b.Write(text.Bytes())
+	b.WriteString("</pre>\n</body>\n</html>\n")
+	return b.Bytes(), nil
+}
+
+// tagPattern matches HTML tags, removed from HTML sheets before parsing them
+var tagPattern = regexp.MustCompile(`<[^>]*>`)
+
+// wordPattern matches a numbered word of the mnemonic table
+var wordPattern = regexp.MustCompile(`(\d+)\.\s+([A-Za-z]+)`)
+
+/*
+ * ParseSheet reads back the address, the seed and the mnemonic of a text
+ * or HTML sheet
+ *
+ * Each value is read from the lines after its label, up to a blank line;
+ * the creation date is not needed to verify a sheet. Returns an error
+ * naming the first section that is missing. The caller wipes the sheet.
+ */
+func ParseSheet(data []byte) (*Sheet, error) {
+	// Nothing the sheet holds is escaped in HTML, so removing the tags is enough; the secret stays out of strings
+	if bytes.Contains(data, []byte("<html")) {
+		data = tagPattern.ReplaceAll(data, nil)
+		defer secure.Wipe(data)
+	}
+	labels := []string{LABEL_ADDRESS, LABEL_SEED, LABEL_MNEMONIC}
+	sections := make(map[string][][]byte)
+	var current string
+lines:
+	for _, line := range bytes.Split(data, []byte("\n")) {
+		trimmed := bytes.TrimSpace(line)
+		for _, label := range labels {
+			if bytes.Equal(trimmed, []byte(label)) {
+				current = label
+				continue lines
+			}
+		}
+		if len(trimmed) == 0 {
+			current = ""
+		} else if current != "" {
+			sections[current] = append(sections[current], trimmed)
+		}
+	}
+
+	// Preallocated, so appending leaves no copies of the secret behind
+	sheet := &Sheet{SeedHex: make([]byte, 0, 2*secure.KeyLength), Mnemonic: make([]byte, 0, 24*9)}
+	for _, label := range labels {
+		if len(sections[label]) == 0 {
+			return nil, fmt.Errorf("no %q section", strings.TrimSuffix(label, ":"))
+		}
+	}
+	sheet.Address = string(sections[LABEL_ADDRESS][0])
+	for _, line := range sections[LABEL_SEED] {
+		for _, group := range bytes.Fields(line) {
+			sheet.SeedHex = append(sheet.SeedHex, group...)
+		}
+	}
+
+	// Words are numbered down the columns: put them back in their order
+	words := make(map[int][]byte)
+	for _, line := range sections[LABEL_MNEMONIC] {
+		for _, match := range wordPattern.FindAllSubmatch(line, -1) {
+			n, err := strconv.Atoi(string(match[1]))
+			if err != nil || n < 1 {
+				sheet.Wipe()
+				return nil, fmt.Errorf("invalid mnemonic word number %q", match[1])
+			}
+			words[n] = match[2]
+		}
+	}
+	for n := 1; n <= len(words); n++ {
+		word, ok := words[n]
+		if !ok {
+			sheet.Wipe()
+			return nil, fmt.Errorf("mnemonic word %d is missing", n)
+		}
+		if n > 1 {
+			sheet.Mnemonic = append(sheet.Mnemonic, ' ')
+		}
+		sheet.Mnemonic = append(sheet.Mnemonic, word...)
+	}
+	return sheet, nil
+}
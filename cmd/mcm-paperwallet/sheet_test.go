@@ -0,0 +1,172 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/NickP005/Vindax-MCM-tools/pkg/mcmaddr"
+	"github.com/NickP005/Vindax-MCM-tools/pkg/secure"
+	"github.com/NickP005/Vindax-MCM-tools/pkg/walletstore"
+)
+
+// testSeed is the secret of the wallet cache fixtures, 0x17 repeated
+const testSeed = "1717171717171717171717171717171717171717171717171717171717171717"
+
+// testSheet returns the sheet of testSeed
+func testSheet(t *testing.T) *Sheet {
+	t.Helper()
+	seed, err := secure.DecodeKey([]byte(testSeed))
+	if err != nil {
+		t.Fatal(err)
+	}
+	sheet, err := NewSheet(seed, time.Date(2026, 3, 4, 23, 0, 0, 0, time.FixedZone("", -5*3600)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return sheet
+}
+
+func TestNewSheet(t *testing.T) {
+	sheet := testSheet(t)
+	// The refill address is the tag wallet-tool's keychain gives the same secret
+	keychain, _ := walletstore.NewKeychain(testSeed)
+	defer keychain.Wipe()
+	if tag := keychain.Tag(); sheet.Address != mcmaddr.To58(tag) {
+		t.Errorf("address %s, wallet-tool tag %s", sheet.Address, mcmaddr.To58(tag))
+	}
+	if string(sheet.SeedHex) != testSeed || len(bytes.Fields(sheet.Mnemonic)) != 24 {
+		t.Errorf("seed %s, mnemonic %q", sheet.SeedHex, sheet.Mnemonic)
+	}
+	sheet.Wipe()
+	if bytes.Count(sheet.SeedHex, []byte{0}) != len(sheet.SeedHex) {
+		t.Error("seed not wiped")
+	}
+}
+
+func TestRenderText(t *testing.T) {
+	sheet := testSheet(t)
+	data, err := RenderText(sheet)
+	if err != nil {
+		t.Fatal(err)
+	}
+	text := string(data)
+	public, secret, found := strings.Cut(text, CUT_LINE)
+	if !found {
+		t.Fatalf("no cut line:\n%s", text)
+	}
+	// The public part holds the address, its QR code and the date, nothing secret
+	for _, want := range []string{LABEL_ADDRESS + "\n  " + sheet.Address + "\n", LABEL_CREATED + "\n  2026-03-05\n", "██"} {
+		if !strings.Contains(public, want) {
+			t.Errorf("public part lacks %q", want)
+		}
+	}
+	words := strings.Fields(string(sheet.Mnemonic))
+	if strings.Contains(public, "17171717") || strings.Contains(public, words[0]) {
+		t.Error("the public part holds the secret")
+	}
+	if !strings.Contains(secret, "  17171717 17171717 17171717 17171717 17171717 17171717 17171717 17171717\n") {
+		t.Errorf("seed not in groups of 8:\n%s", secret)
+	}
+	// Numbered down the columns: the first row holds words 1, 7, 13 and 19
+	if !strings.Contains(secret, "   1. "+words[0]) || !strings.Contains(secret, " 19. "+words[18]+"\n") {
+		t.Errorf("mnemonic table:\n%s", secret)
+	}
+}
+
+// TestVerify verifies the text and HTML sheets, then sheets changed or cut
+func TestVerify(t *testing.T) {
+	sheet := testSheet(t)
+	words := strings.Fields(string(sheet.Mnemonic))
+	for _, render := range []func(*Sheet) ([]byte, error){RenderText, RenderHTML} {
+		data, err := render(sheet)
+		if err != nil {
+			t.Fatal(err)
+		}
+		sheetText := string(data)
+		if address, err := Verify(data); err != nil || address != sheet.Address {
+			t.Fatalf("sheet: %s, %v", address, err)
+		}
+
+		other := "abandon"
+		if words[4] == other {
+			other = "zoo"
+		}
+		for _, tc := range []struct {
+			name    string
+			sheet   string
+			message string
+		}{
+			{"changed word", strings.Replace(sheetText, " 5. "+words[4], " 5. "+other, 1), "invalid mnemonic"},
+			{"unknown word", strings.Replace(sheetText, " 5. "+words[4], " 5. mochimo", 1), "is not a BIP39 English word"},
+			{"changed seed", strings.Replace(sheetText, "  17171717 ", "  17171718 ", 1), "the mnemonic does not encode the seed"},
+			{"changed address", strings.Replace(sheetText, "  "+sheet.Address, "  "+sheet.Address[:len(sheet.Address)-1]+"x", 1), "the sheet prints"},
+			{"cut off", sheetText[:strings.Index(sheetText, "SECRET")], `no "Seed (hex)" section`},
+			{"missing word", strings.Replace(sheetText, "13. ", "xx. ", 1), "mnemonic word 13 is missing"},
+		} {
+			if _, err := Verify([]byte(tc.sheet)); err == nil || !strings.Contains(err.Error(), tc.message) {
+				t.Errorf("%s: %v", tc.name, err)
+			}
+		}
+	}
+}
+
+func TestRenderHTML(t *testing.T) {
+	data, err := RenderHTML(testSheet(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	page := string(data)
+	for _, want := range []string{"<!DOCTYPE html>", "<svg xmlns=", `<div class="cut">`, "</html>\n"} {
+		if !strings.Contains(page, want) {
+			t.Errorf("page lacks %q", want)
+		}
+	}
+	if strings.Index(page, "<svg") > strings.Index(page, "17171717") {
+		t.Error("the seed comes before the QR code")
+	}
+}
+
+func TestWriteNew(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "sheet.txt")
+	if err := writeNew(path, []byte("first")); err != nil {
+		t.Fatal(err)
+	}
+	info, err := os.Stat(path)
+	if err != nil || info.Mode().Perm() != 0600 {
+		t.Fatalf("mode %v, %v", info.Mode(), err)
+	}
+	if err := writeNew(path, []byte("second")); err == nil || !strings.Contains(err.Error(), "refusing to overwrite") {
+		t.Errorf("existing file: %v", err)
+	}
+	if data, _ := os.ReadFile(path); string(data) != "first" {
+		t.Errorf("existing file overwritten: %q", data)
+	}
+}
+
+func TestReadSeed(t *testing.T) {
+	if seed, err := readSeed("0x" + testSeed); err != nil || seed[0] != 0x17 {
+		t.Errorf("flag: %x, %v", seed, err)
+	}
+	if _, err := readSeed("1717"); err == nil {
+		t.Error("short seed accepted")
+	}
+
+	// - reads the first line of standard input
+	input := filepath.Join(t.TempDir(), "stdin")
+	os.WriteFile(input, []byte(testSeed+"\nnext line\n"), 0600)
+	stdin, err := os.Open(input)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer stdin.Close()
+	saved := os.Stdin
+	os.Stdin = stdin
+	defer func() { os.Stdin = saved }()
+	if seed, err := readSeed("-"); err != nil || seed[31] != 0x17 {
+		t.Errorf("standard input: %x, %v", seed, err)
+	}
+}
@@ -0,0 +1,128 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/NickP005/Vindax-MCM-tools/pkg/cli"
+	"github.com/NickP005/Vindax-MCM-tools/pkg/config"
+	"github.com/NickP005/Vindax-MCM-tools/pkg/meshclient"
+	"github.com/NickP005/Vindax-MCM-tools/pkg/shutdown"
+)
+
+// Exit codes of mcm-resolve beyond those of pkg/cli, stable for use from shell scripts; with several outcomes
+// cli.ExitFailure wins, then the first listed
+const (
+	ExitUnresolved = 3 // a valid tag is unknown to the chain
+	ExitInvalid    = 4 // an input is not an address
+)
+
+// newMeshClient returns a Mesh API client identifying mcm-resolve in its User-Agent, retrying failed lookups
+func newMeshClient(cfg *config.Config, concurrency int) *meshclient.MeshAPIClient {
+	client := meshclient.NewMeshAPIClient(cfg.API, nil)
+	cfg.Apply(client)
+	client.SetUserAgent("mcm-resolve")
+	client.SetRetryPolicy(meshclient.DefaultRetryPolicy())
+	client.SetBatchConcurrency(concurrency)
+	return client
+}
+
+// readInput takes the addresses from the arguments, from -file, or from stdin when neither is given (or the only argument is -)
+func readInput(file string, args []string) ([]Resolution, error) {
+	if len(args) == 1 && args[0] == "-" {
+		args = nil
+	}
+	if len(args) > 0 {
+		resolutions := make([]Resolution, 0, len(args))
+		for _, arg := range args {
+			resolutions = append(resolutions, newResolution(arg))
+		}
+		return resolutions, nil
+	}
+	input := io.Reader(os.Stdin)
+	if file != "" && file != "-" {
+		f, err := os.Open(file)
+		if err != nil {
+			return nil, err
+		}
+		defer f.Close()
+		input = f
+	}
+	return ReadInputs(input)
+}
+
+// exitCode returns the exit code of a run, see the Exit constants
+func exitCode(resolutions []Resolution) int {
+	code := cli.ExitOK
+	for _, resolution := range resolutions {
+		switch {
+		case resolution.Status == StatusFailed:
+			return cli.ExitFailure
+		case resolution.Status == StatusUnresolved:
+			code = ExitUnresolved
+		case resolution.Status == StatusInvalid && code == cli.ExitOK:
+			code = ExitInvalid
+		}
+	}
+	return code
+}
+
+func main() {
+	cfg, err := config.Load(config.Defaults())
+	if err != nil {
+		cli.Usagef("%v", err)
+	}
+	file := flag.String("file", "", "File with one address per line, hex or base58 (default: stdin)")
+	cfg.APIFlags(flag.CommandLine, "Mesh API URL")
+	concurrency := flag.Int("concurrency", 8, "Maximum concurrent lookups")
+	asJSON := flag.Bool("json", false, "Output a JSON array, one object per input")
+	cfg.TimeoutFlag(flag.CommandLine, "Give up the lookups not made after this long")
+	printConfig := cfg.PrintFlag(flag.CommandLine)
+	flag.Usage = func() {
+		fmt.Fprintln(flag.CommandLine.Output(), "Usage: mcm-resolve [flags] [address...]")
+		flag.PrintDefaults()
+	}
+
+	cli.Parse(cfg)
+	if *printConfig {
+		cfg.Print(os.Stdout)
+		return
+	}
+	if flag.NArg() > 0 && *file != "" {
+		cli.Usagef("give the addresses either as arguments or with -file")
+	}
+
+	resolutions, err := readInput(*file, flag.Args())
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error reading addresses: %v\n", err)
+		os.Exit(cli.ExitUsage)
+	}
+	if len(resolutions) == 0 {
+		cli.Usagef("no address given")
+	}
+
+	// Interrupting the tool cancels the lookups in flight; those not made are reported failed
+	sig := shutdown.Notify()
+	defer sig.Stop()
+	ctx := sig.Context()
+	ctx, cancel := context.WithTimeout(ctx, cfg.Timeout)
+	defer cancel()
+
+	if err := ResolveAll(ctx, newMeshClient(cfg, *concurrency), resolutions); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: lookups stopped early: %v\n", err)
+	}
+
+	if *asJSON {
+		err = WriteJSON(os.Stdout, resolutions)
+	} else {
+		err = WriteTable(os.Stdout, resolutions)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error writing output: %v\n", err)
+		os.Exit(cli.ExitUsage)
+	}
+	os.Exit(exitCode(resolutions))
+}
@@ -0,0 +1,37 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"text/tabwriter"
+
+	"github.com/NickP005/Vindax-MCM-tools/pkg/amount"
+)
+
+// WriteTable writes one aligned row per input; the explanation of an invalid or failed one goes in its last column
+func WriteTable(out io.Writer, resolutions []Resolution) error {
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "INPUT\tSTATUS\tADDRESS\tBALANCE (MCM)\tDETAIL")
+	for _, resolution := range resolutions {
+		balance, detail := "", resolution.Error
+		if resolution.Balance != nil {
+			balance = amount.FormatMCM(*resolution.Balance)
+		}
+		if resolution.Status == StatusUnresolved {
+			detail = "the chain does not know this tag: never funded, or emptied"
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", resolution.Input, resolution.Status, resolution.Address, balance, detail)
+	}
+	return w.Flush()
+}
+
+// WriteJSON writes the resolutions as an indented JSON array
+func WriteJSON(out io.Writer, resolutions []Resolution) error {
+	if resolutions == nil {
+		resolutions = []Resolution{}
+	}
+	encoder := json.NewEncoder(out)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(resolutions)
+}
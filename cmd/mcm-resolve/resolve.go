@@ -0,0 +1,168 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/NickP005/Vindax-MCM-tools/pkg/mcmaddr"
+	"github.com/NickP005/Vindax-MCM-tools/pkg/meshclient"
+)
+
+// Outcomes of a resolution
+const (
+	StatusResolved   = "resolved"
+	StatusUnresolved = "unresolved"
+	StatusInvalid    = "invalid"
+	StatusFailed     = "failed"
+)
+
+/*
+ * Resolution is one input with the full address its tag resolves to
+ *
+ * Fields:
+ * - Input: the address as given
+ * - Hex, Base58: the normalized tag, empty for an invalid input
+ * - Status: one of the Status constants, Error explains invalid and failed ones
+ * - Address, Balance: the full address (0x prefixed hex) and its balance in nanoMCM, set only when resolved
+ */
+type Resolution struct {
+	Input   string  `json:"input"`
+	Hex     string  `json:"hex,omitempty"`
+	Base58  string  `json:"base58,omitempty"`
+	Status  string  `json:"status"`
+	Address string  `json:"address,omitempty"`
+	Balance *uint64 `json:"balance,omitempty"`
+	Error   string  `json:"error,omitempty"`
+
+	tag [mcmaddr.TagLength]byte
+}
+
+// looksHex reports whether an input is meant as hex: 0x prefixed, or hex digits only
+func looksHex(input string) bool {
+	if strings.HasPrefix(input, "0x") || strings.HasPrefix(input, "0X") {
+		return true
+	}
+	for _, c := range input {
+		if !strings.ContainsRune("0123456789abcdefABCDEF", c) {
+			return false
+		}
+	}
+	return len(input) > 0
+}
+
+/*
+ * friendlyError explains why an input is not an address, in words meant
+ * for whoever pasted it
+ *
+ * Positions are 1-based and count the characters of the input as given,
+ * 0x prefix included.
+ */
+func friendlyError(input string, err error) string {
+	var alphabetErr *mcmaddr.AlphabetError
+	var lengthErr *mcmaddr.LengthError
+	var oddErr *mcmaddr.OddLengthError
+	var checksumErr *mcmaddr.ChecksumError
+	hexInput := looksHex(input)
+	prefixed := strings.HasPrefix(input, "0x") || strings.HasPrefix(input, "0X")
+	digits := input
+	if prefixed {
+		digits = input[2:]
+	}
+
+	switch {
+	case errors.As(err, &alphabetErr) && prefixed:
+		return fmt.Sprintf("%q at position %d is not a hex digit", alphabetErr.Char, alphabetErr.Offset+1)
+	case errors.As(err, &alphabetErr):
+		if strings.ContainsRune("0OIl", alphabetErr.Char) {
+			return fmt.Sprintf("%q at position %d is not used in base58 addresses (0, O, I and l never appear): probably a mistyped character", alphabetErr.Char, alphabetErr.Offset+1)
+		}
+		return fmt.Sprintf("%q at position %d is neither a hex digit nor a base58 character", alphabetErr.Char, alphabetErr.Offset+1)
+	case errors.As(err, &lengthErr) && hexInput && len(digits) == meshclient.AddressLength*2:
+		return fmt.Sprintf("this is a full %d bytes address, not a tag: its tag is its first %d bytes, 0x%s", meshclient.AddressLength, mcmaddr.TagLength, digits[:mcmaddr.TagLength*2])
+	case errors.As(err, &oddErr), errors.As(err, &lengthErr) && hexInput:
+		return fmt.Sprintf("a hex address has %d digits, this one has %d: a digit may be missing or extra, or it was cut when copied", mcmaddr.TagLength*2, len(digits))
+	case errors.As(err, &lengthErr):
+		return fmt.Sprintf("decodes to %d bytes where a base58 address has %d (tag and checksum): a character may be missing or extra, or it was cut when copied", lengthErr.Length, mcmaddr.EncodedLength)
+	case errors.As(err, &checksumErr):
+		return "the checksum does not match: a character was probably mistyped"
+	}
+	return err.Error()
+}
+
+/*
+ * ReadInputs reads one address per line, hex or base58
+ *
+ * Blank lines and lines starting with # are skipped, as is anything after
+ * the address on its line (e.g. a label). An input that does not normalize
+ * is kept with StatusInvalid and a friendly error, so it is reported in its
+ * place.
+ */
+func ReadInputs(r io.Reader) ([]Resolution, error) {
+	var resolutions []Resolution
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 || strings.HasPrefix(fields[0], "#") {
+			continue
+		}
+		resolutions = append(resolutions, newResolution(strings.TrimRight(fields[0], ",;")))
+	}
+	return resolutions, scanner.Err()
+}
+
+// newResolution normalizes an input, recording why it is invalid if it is
+func newResolution(input string) Resolution {
+	resolution := Resolution{Input: input}
+	tag, err := mcmaddr.Normalize(input)
+	if err != nil {
+		resolution.Status = StatusInvalid
+		resolution.Error = friendlyError(input, err)
+		return resolution
+	}
+	resolution.tag = tag
+	resolution.Hex = mcmaddr.ToHex(tag)
+	resolution.Base58 = mcmaddr.To58(tag)
+	return resolution
+}
+
+/*
+ * ResolveAll resolves the tag of every valid input through BatchResolveTags
+ *
+ * Each distinct tag is looked up once, with the client's batch concurrency;
+ * a failed lookup only marks its inputs as failed. Returns ctx's error if it
+ * was canceled meanwhile.
+ */
+func ResolveAll(ctx context.Context, client *meshclient.MeshAPIClient, resolutions []Resolution) error {
+	var tags [][mcmaddr.TagLength]byte
+	for _, resolution := range resolutions {
+		if resolution.Status != StatusInvalid {
+			tags = append(tags, resolution.tag)
+		}
+	}
+	results, err := client.BatchResolveTags(ctx, tags)
+
+	for i := range resolutions {
+		resolution := &resolutions[i]
+		if resolution.Status == StatusInvalid {
+			continue
+		}
+		result := results[resolution.tag]
+		switch {
+		case result.Err != nil:
+			resolution.Status = StatusFailed
+			resolution.Error = result.Err.Error()
+		case result.Found:
+			balance := result.Amount
+			resolution.Status = StatusResolved
+			resolution.Address = result.AddressHex
+			resolution.Balance = &balance
+		default:
+			resolution.Status = StatusUnresolved
+		}
+	}
+	return err
+}
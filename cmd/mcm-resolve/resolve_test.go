@@ -0,0 +1,128 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/NickP005/Vindax-MCM-tools/pkg/cli"
+	"github.com/NickP005/Vindax-MCM-tools/pkg/mcmaddr"
+	"github.com/NickP005/Vindax-MCM-tools/pkg/meshclient"
+	"github.com/NickP005/Vindax-MCM-tools/pkg/meshmock"
+)
+
+// testTag is the tag whose bytes are all b
+func testTag(b byte) [mcmaddr.TagLength]byte {
+	var tag [mcmaddr.TagLength]byte
+	for i := range tag {
+		tag[i] = b
+	}
+	return tag
+}
+
+// replaceAt replaces the character of s at 0-based position i by c
+func replaceAt(s string, i int, c byte) string {
+	return s[:i] + string(c) + s[i+1:]
+}
+
+func TestFriendlyErrors(t *testing.T) {
+	tag := testTag(0x5a)
+	b58, digits := mcmaddr.To58(tag), mcmaddr.ToHex(tag)
+	// Another base58 character, so only the checksum can tell
+	other := byte('2')
+	if b58[3] == other {
+		other = '3'
+	}
+
+	for _, tc := range []struct {
+		name  string
+		input string
+		want  string
+	}{
+		{"base58 with a zero", replaceAt(b58, 4, '0'), `'0' at position 5 is not used in base58 addresses`},
+		{"base58 with a dash", replaceAt(b58, 2, '-'), `'-' at position 3 is neither a hex digit nor a base58 character`},
+		{"base58 cut", b58[:len(b58)-4], "where a base58 address has"},
+		{"base58 mistyped", replaceAt(b58, 3, other), "the checksum does not match"},
+		{"hex with a letter", "0x" + replaceAt(digits, 6, 'g'), `'g' at position 9 is not a hex digit`},
+		{"hex odd", "0x" + digits[:39], "a hex address has 40 digits, this one has 39"},
+		{"hex short", digits[:38], "a hex address has 40 digits, this one has 38"},
+		{"full address", "0x" + digits + strings.Repeat("ee", 20), "this is a full 40 bytes address, not a tag: its tag is its first 20 bytes, 0x" + digits},
+	} {
+		resolution := newResolution(tc.input)
+		if resolution.Status != StatusInvalid || !strings.Contains(resolution.Error, tc.want) {
+			t.Errorf("%s: %s %q, want %q", tc.name, resolution.Status, resolution.Error, tc.want)
+		}
+	}
+
+	for _, input := range []string{b58, digits, "0X" + strings.ToUpper(digits)} {
+		if resolution := newResolution(input); resolution.Status != "" || resolution.Hex != digits || resolution.Base58 != b58 {
+			t.Errorf("%s: %+v", input, resolution)
+		}
+	}
+}
+
+// TestResolveAll resolves a known tag twice, an unknown one, an invalid input and a failing lookup, read as a file is
+func TestResolveAll(t *testing.T) {
+	mock := meshmock.New()
+	defer mock.Close()
+	known, unknown, failing := testTag(0x0a), testTag(0x0b), testTag(0x0c)
+	full := "0x" + mcmaddr.ToHex(known) + strings.Repeat("ee", 20)
+	mock.SetAccount(known[:], full, 2500000000)
+	// One lookup at a time: the first distinct tag, failing, gets the fault
+	mock.Fail("/call", meshmock.Fault{Status: 500, Body: `{"code":2,"message":"internal error","retriable":false}`})
+	client := meshclient.NewMeshAPIClient(mock.URL(), nil)
+	client.SetBatchConcurrency(1)
+
+	input := "# support ticket 42\n" + mcmaddr.ToHex(failing) + "\n" + mcmaddr.To58(known) + " exchange,\n\n" +
+		mcmaddr.ToHex(unknown) + ";\nnot-an-address\n" + mcmaddr.ToHex(known) + "\n"
+	resolutions, err := ReadInputs(strings.NewReader(input))
+	if err != nil || len(resolutions) != 5 {
+		t.Fatalf("%d inputs, %v", len(resolutions), err)
+	}
+	if err := ResolveAll(context.Background(), client, resolutions); err != nil {
+		t.Fatal(err)
+	}
+	want := []string{StatusFailed, StatusResolved, StatusUnresolved, StatusInvalid, StatusResolved}
+	for i, resolution := range resolutions {
+		if resolution.Status != want[i] {
+			t.Errorf("input %d %s: %s, want %s", i, resolution.Input, resolution.Status, want[i])
+		}
+	}
+	if r := resolutions[1]; r.Address != full || r.Balance == nil || *r.Balance != 2500000000 {
+		t.Errorf("resolved %+v", r)
+	}
+
+	// The first listed outcome wins
+	if code := exitCode(resolutions); code != cli.ExitFailure {
+		t.Errorf("exit code %d", code)
+	}
+	if code := exitCode(resolutions[1:]); code != ExitUnresolved {
+		t.Errorf("without the failure: exit code %d", code)
+	}
+	if code := exitCode(append(resolutions[3:4:4], resolutions[1])); code != ExitInvalid {
+		t.Errorf("invalid and resolved: exit code %d", code)
+	}
+	if code := exitCode(resolutions[1:2]); code != cli.ExitOK {
+		t.Errorf("resolved: exit code %d", code)
+	}
+
+	var table bytes.Buffer
+	if err := WriteTable(&table, resolutions); err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{full + "  2.5", "the chain does not know this tag", "internal error"} {
+		if !strings.Contains(table.String(), want) {
+			t.Errorf("table lacks %q:\n%s", want, table.String())
+		}
+	}
+	var array bytes.Buffer
+	if err := WriteJSON(&array, resolutions); err != nil {
+		t.Fatal(err)
+	}
+	var decoded []Resolution
+	if err := json.Unmarshal(array.Bytes(), &decoded); err != nil || len(decoded) != 5 || decoded[3].Error == "" || decoded[1].Base58 != mcmaddr.To58(known) {
+		t.Errorf("json %s, %v", array.String(), err)
+	}
+}
@@ -0,0 +1,167 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/NickP005/Vindax-MCM-tools/pkg/amount"
+	"github.com/NickP005/Vindax-MCM-tools/pkg/cli"
+	"github.com/NickP005/Vindax-MCM-tools/pkg/config"
+	"github.com/NickP005/Vindax-MCM-tools/pkg/fileutil"
+	"github.com/NickP005/Vindax-MCM-tools/pkg/mcmaddr"
+	"github.com/NickP005/Vindax-MCM-tools/pkg/meshclient"
+	"github.com/NickP005/Vindax-MCM-tools/pkg/shutdown"
+	"github.com/NickP005/Vindax-MCM-tools/pkg/sweep"
+)
+
+// newMeshClient returns a Mesh API client identifying mcm-sweep in its User-Agent, retrying failed lookups
+func newMeshClient(cfg *config.Config, concurrency int) *meshclient.MeshAPIClient {
+	client := meshclient.NewMeshAPIClient(cfg.API, nil)
+	cfg.Apply(client)
+	client.SetUserAgent("mcm-sweep")
+	client.SetRetryPolicy(meshclient.DefaultRetryPolicy())
+	client.SetBatchConcurrency(concurrency)
+	return client
+}
+
+// readKeys reads the seeds of -keys, from stdin for "-"
+func readKeys(file string) ([]*sweep.Account, error) {
+	input := io.Reader(os.Stdin)
+	if file != "-" {
+		f, err := os.Open(file)
+		if err != nil {
+			return nil, err
+		}
+		defer f.Close()
+		input = f
+	}
+	return sweep.ReadSeeds(input)
+}
+
+func main() {
+	cfg, err := config.Load(config.Defaults())
+	if err != nil {
+		cli.Usagef("%v", err)
+	}
+	keysFile := flag.String("keys", "", "tool-2 output (JSON, NDJSON or CSV) or a file of hex seeds, one per line (\"-\" for stdin)")
+	to := flag.String("to", "", "Address receiving the funds, hex or base58")
+	cfg.APIFlags(flag.CommandLine, "Mesh API URL")
+	cfg.FeeFlag(flag.CommandLine, "Fee of each sweep transaction, in nanoMCM or with a unit suffix")
+	dust := flag.String("dust", "0", "Skip the accounts holding less than this amount, in nanoMCM or with a unit suffix")
+	btl := flag.Uint64("btl", 0, "Blocks after the tip each transaction can be mined in, 0 for no limit")
+	concurrency := flag.Int("concurrency", 8, "Maximum concurrent balance lookups")
+	submitConcurrency := flag.Int("submit-concurrency", 4, "Maximum transactions signed and submitted at once")
+	dryRun := flag.Bool("dry-run", false, "Report what would be swept, without signing or sending anything")
+	confirmations := flag.Int("confirmations", 1, "Blocks to wait for, the one holding a sweep included, 0 not to wait")
+	cfg.PollIntervalFlag(flag.CommandLine, "poll-interval", "Time between two polls for new blocks")
+	cfg.TimeoutFlag(flag.CommandLine, "Give up waiting for confirmations after this long")
+	journalFile := flag.String("journal", "sweep-journal.json", "File keeping every transaction signed, so no key signs twice across runs")
+	asJSON := flag.Bool("json", false, "Output a JSON object with every account and the summary")
+	printConfig := cfg.PrintFlag(flag.CommandLine)
+	flag.Usage = func() {
+		fmt.Fprintln(flag.CommandLine.Output(), "Usage: mcm-sweep -keys <file> -to <address> [flags]")
+		flag.PrintDefaults()
+	}
+
+	cli.Parse(cfg)
+	if *printConfig {
+		cfg.Print(os.Stdout)
+		return
+	}
+	if *keysFile == "" || *to == "" {
+		cli.Usagef("-keys and -to are required")
+	}
+	destination, err := mcmaddr.Normalize(*to)
+	if err != nil {
+		cli.Usagef("invalid -to: %v", err)
+	}
+	fee, err := amount.Parse(cfg.Fee, amount.NanoMCM)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error parsing -fee: %v\n", err)
+		os.Exit(cli.ExitUsage)
+	}
+	dustValue, err := amount.Parse(*dust, amount.NanoMCM)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error parsing -dust: %v\n", err)
+		os.Exit(cli.ExitUsage)
+	}
+
+	accounts, err := readKeys(*keysFile)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error reading keys: %v\n", err)
+		os.Exit(cli.ExitUsage)
+	}
+	defer sweep.Wipe(accounts)
+
+	// Interrupting the tool stops the submits not made; the report still lists every account
+	sig := shutdown.Notify()
+	defer sig.Stop()
+	sig.OnCleanup(func() { sweep.Wipe(accounts) })
+
+	s := &sweep.Sweeper{
+		Client:            newMeshClient(cfg, *concurrency),
+		Destination:       destination,
+		Fee:               fee,
+		Dust:              dustValue,
+		BlockToLive:       *btl,
+		SubmitConcurrency: *submitConcurrency,
+		DryRun:            *dryRun,
+		Confirmations:     *confirmations,
+		PollInterval:      cfg.PollInterval,
+		Log:               func(line string) { fmt.Fprintln(os.Stderr, line) },
+	}
+	if !*dryRun {
+		// Two runs on the same journal could sign with the same key twice; the lock goes with the process
+		lock, err := fileutil.Lock(*journalFile + ".lock")
+		if errors.Is(err, fileutil.ErrLocked) {
+			fmt.Fprintf(os.Stderr, "Error: journal %s is in use by another mcm-sweep run\n", *journalFile)
+			sig.Exit(cli.ExitUsage)
+		}
+		if err != nil && !errors.Is(err, errors.ErrUnsupported) {
+			fmt.Fprintf(os.Stderr, "Error locking journal: %v\n", err)
+			sig.Exit(cli.ExitUsage)
+		}
+		if lock != nil {
+			sig.OnCleanup(func() { lock.Unlock() })
+		}
+	}
+	if s.Journal, err = sweep.OpenJournal(*journalFile); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		sig.Exit(cli.ExitUsage)
+	}
+
+	ctx, cancel := context.WithTimeout(sig.Context(), cfg.Timeout)
+	defer cancel()
+	if err := s.Run(ctx, accounts); err != nil {
+		// Only the end of ctx stops a sweep already started; anything else means the chain could not be read at all
+		if ctx.Err() == nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			sig.Exit(cli.ExitFailure)
+		}
+		if !errors.Is(err, context.DeadlineExceeded) {
+			fmt.Fprintf(os.Stderr, "Warning: sweep stopped early: %v\n", err)
+		}
+	}
+
+	summary := sweep.Summarize(accounts)
+	if *asJSON {
+		err = WriteJSON(os.Stdout, accounts, summary)
+	} else {
+		err = WriteTable(os.Stdout, accounts, summary)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error writing output: %v\n", err)
+	}
+
+	switch {
+	case sig.Interrupted():
+		sig.Exit(shutdown.ExitInterrupted)
+	case summary.Failed > 0 || summary.Submitted > 0 && *confirmations > 0:
+		sig.Exit(cli.ExitFailure)
+	}
+	sig.Exit(cli.ExitOK)
+}
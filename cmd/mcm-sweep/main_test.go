@@ -0,0 +1,294 @@
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/NickP005/Vindax-MCM-tools/pkg/cli"
+	"github.com/NickP005/Vindax-MCM-tools/pkg/fileutil"
+	"github.com/NickP005/Vindax-MCM-tools/pkg/mcmaddr"
+	"github.com/NickP005/Vindax-MCM-tools/pkg/meshmock"
+	"github.com/NickP005/Vindax-MCM-tools/pkg/sweep"
+	"github.com/NickP005/Vindax-MCM-tools/pkg/wotsp"
+)
+
+// mcmSweep is the binary built by TestMain, run by the tests driving the command line
+var mcmSweep string
+
+func TestMain(m *testing.M) {
+	dir, err := os.MkdirTemp("", "mcm-sweep-test")
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	mcmSweep = filepath.Join(dir, "mcm-sweep")
+	if out, err := exec.Command("go", "build", "-o", mcmSweep, ".").CombinedOutput(); err != nil {
+		fmt.Fprintf(os.Stderr, "failed to build mcm-sweep: %v\n%s", err, out)
+		os.RemoveAll(dir)
+		os.Exit(1)
+	}
+	code := m.Run()
+	os.RemoveAll(dir)
+	os.Exit(code)
+}
+
+// testAccount is a generated account: its seed, and its tag, the address hash of its first key
+type testAccount struct {
+	seed [32]byte
+	tag  [20]byte
+}
+
+// newTestAccount derives the account of a label
+func newTestAccount(label string) testAccount {
+	seed := sha256.Sum256([]byte("mcm-sweep test " + label))
+	keypair := wotsp.Keygen(seed)
+	defer keypair.Wipe()
+	return testAccount{seed: seed, tag: keypair.AddrHash()}
+}
+
+// fund sets the balance of the account at its implicit address, as a first payment to its tag does
+func (a testAccount) fund(mock *meshmock.Server, balance uint64) {
+	mock.SetAccount(a.tag[:], "0x"+hex.EncodeToString(a.tag[:])+hex.EncodeToString(a.tag[:]), balance)
+}
+
+// tool2CSV renders accounts as the CSV output of tool-2
+func tool2CSV(accounts ...testAccount) string {
+	var b strings.Builder
+	b.WriteString("mcmAccountNumber,wotsPublicKey,wotsSecretKey\n")
+	for i, a := range accounts {
+		fmt.Fprintf(&b, "%020x,00,%x\n", i, a.seed[:])
+	}
+	return b.String()
+}
+
+// result is what a run of the binary printed and its exit code
+type result struct {
+	stdout string
+	stderr string
+	code   int
+}
+
+/*
+ * runSweep runs the binary with args and the keys on stdin, mining a block
+ * of mock every 20ms while it runs when mine is set
+ *
+ * The environment holds no MCM_* variable of the caller and a HOME of its
+ * own, so no config file of the machine leaks in.
+ */
+func runSweep(t *testing.T, mock *meshmock.Server, mine bool, keys string, args ...string) result {
+	t.Helper()
+	cmd := exec.Command(mcmSweep, append([]string{"-api", mock.URL(), "-keys", "-", "-poll-interval", "20ms"}, args...)...)
+	home := t.TempDir()
+	cmd.Env = []string{"HOME=" + home, "XDG_CONFIG_HOME=" + home}
+	for _, v := range os.Environ() {
+		if !strings.HasPrefix(v, "MCM_") && !strings.HasPrefix(v, "HOME=") && !strings.HasPrefix(v, "XDG_CONFIG_HOME=") {
+			cmd.Env = append(cmd.Env, v)
+		}
+	}
+	cmd.Stdin = strings.NewReader(keys)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout, cmd.Stderr = &stdout, &stderr
+	if err := cmd.Start(); err != nil {
+		t.Fatal(err)
+	}
+	done := make(chan error, 1)
+	go func() { done <- cmd.Wait() }()
+	ticker := time.NewTicker(20 * time.Millisecond)
+	defer ticker.Stop()
+	var err error
+wait:
+	for {
+		select {
+		case err = <-done:
+			break wait
+		case <-ticker.C:
+			if mine {
+				mock.MineBlock()
+			}
+		case <-time.After(30 * time.Second):
+			cmd.Process.Kill()
+			t.Fatalf("mcm-sweep did not end:\n%s", stderr.String())
+		}
+	}
+	r := result{stdout: stdout.String(), stderr: stderr.String()}
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		r.code = exitErr.ExitCode()
+	} else if err != nil {
+		t.Fatalf("running mcm-sweep: %v", err)
+	}
+	return r
+}
+
+// report is the -json output
+type report struct {
+	Accounts []sweep.Account `json:"accounts"`
+	Summary  sweep.Summary   `json:"summary"`
+}
+
+// readReport decodes the -json output of a run, and checks the status of every account in order
+func readReport(t *testing.T, r result, statuses ...string) report {
+	t.Helper()
+	var out report
+	if err := json.Unmarshal([]byte(r.stdout), &out); err != nil {
+		t.Fatalf("%v:\n%s\n%s", err, r.stdout, r.stderr)
+	}
+	if len(out.Accounts) != len(statuses) {
+		t.Fatalf("%d accounts, want %d", len(out.Accounts), len(statuses))
+	}
+	for i, account := range out.Accounts {
+		if account.Status != statuses[i] {
+			t.Errorf("line %d: %s (%s), want %s", account.Line, account.Status, account.Reason, statuses[i])
+		}
+	}
+	return out
+}
+
+// sweepChain funds two accounts and one holding dust next to one never funded, and returns them and the destination
+func sweepChain(t *testing.T) (*meshmock.Server, []testAccount, testAccount) {
+	t.Helper()
+	mock := meshmock.New()
+	t.Cleanup(mock.Close)
+	mock.MineBlock()
+	accounts := []testAccount{newTestAccount("a"), newTestAccount("b"), newTestAccount("dust"), newTestAccount("unknown")}
+	accounts[0].fund(mock, 100000)
+	accounts[1].fund(mock, 250000)
+	accounts[2].fund(mock, 800)
+	return mock, accounts, newTestAccount("destination")
+}
+
+func TestSweepDryRun(t *testing.T) {
+	mock, accounts, destination := sweepChain(t)
+	journal := filepath.Join(t.TempDir(), "journal.json")
+	r := runSweep(t, mock, false, tool2CSV(accounts...), "-to", mcmaddr.To58(destination.tag), "-fee", "500", "-dust", "0.000001mcm", "-dry-run", "-journal", journal)
+	if r.code != cli.ExitOK {
+		t.Fatalf("exit %d: %s", r.code, r.stderr)
+	}
+	for _, want := range []string{
+		mcmaddr.To58(accounts[0].tag) + "  100000",
+		"would-sweep",
+		"balance under the dust threshold of 0.000001 MCM",
+		"not on chain",
+		"4 accounts: 0 swept, 0 submitted, 2 would sweep, 2 skipped, 0 failed",
+		"Sent: 349000 nMCM (0.000349 MCM), fees 0.000001 MCM",
+	} {
+		if !strings.Contains(r.stdout, want) {
+			t.Errorf("no %q in:\n%s", want, r.stdout)
+		}
+	}
+	if len(mock.Submitted()) != 0 {
+		t.Errorf("%d transactions submitted", len(mock.Submitted()))
+	}
+	if _, err := os.Stat(journal); !os.IsNotExist(err) {
+		t.Errorf("journal written by a dry run: %v", err)
+	}
+}
+
+// TestSweepJSON sweeps into a hex destination and waits for two confirmations, reporting every account as JSON
+func TestSweepJSON(t *testing.T) {
+	mock, accounts, destination := sweepChain(t)
+	journal := filepath.Join(t.TempDir(), "journal.json")
+	r := runSweep(t, mock, true, tool2CSV(accounts...), "-to", mcmaddr.ToHex(destination.tag), "-fee", "500", "-dust", "1000",
+		"-btl", "50", "-confirmations", "2", "-submit-concurrency", "1", "-journal", journal, "-json")
+	if r.code != cli.ExitOK {
+		t.Fatalf("exit %d: %s", r.code, r.stderr)
+	}
+	out := readReport(t, r, sweep.StatusSwept, sweep.StatusSwept, sweep.StatusSkipped, sweep.StatusSkipped)
+	if out.Summary.Swept != 2 || out.Summary.Amount != 349000 || out.Summary.Fees != 1000 {
+		t.Errorf("summary %+v", out.Summary)
+	}
+	for _, account := range out.Accounts[:2] {
+		if account.TxID == "" || account.Block == 0 || account.BlockToLive < 51 || account.Fee != 500 {
+			t.Errorf("account %+v", account)
+		}
+	}
+	if balance, _ := mock.Balance(destination.tag[:]); balance != 349000 {
+		t.Errorf("destination balance %d", balance)
+	}
+	if !strings.Contains(r.stderr, out.Accounts[0].TxID+" submitted") {
+		t.Errorf("stderr:\n%s", r.stderr)
+	}
+
+	// Run again, nothing is left to sweep
+	r = runSweep(t, mock, true, tool2CSV(accounts...), "-to", mcmaddr.ToHex(destination.tag), "-fee", "500", "-dust", "1000", "-journal", journal, "-json")
+	readReport(t, r, sweep.StatusSkipped, sweep.StatusSkipped, sweep.StatusSkipped, sweep.StatusSkipped)
+	if r.code != cli.ExitOK || len(mock.Submitted()) != 2 {
+		t.Errorf("second run: exit %d, %d submitted", r.code, len(mock.Submitted()))
+	}
+}
+
+// TestSweepFailures exits with cli.ExitFailure when an account fails, and when a sweep is not confirmed in time
+func TestSweepFailures(t *testing.T) {
+	mock, accounts, destination := sweepChain(t)
+	journal := filepath.Join(t.TempDir(), "journal.json")
+	// A tag held by a key that is none of its seed's
+	foreign := newTestAccount("foreign")
+	mock.SetAccount(foreign.tag[:], "0x"+hex.EncodeToString(foreign.tag[:])+strings.Repeat("77", 20), 9000)
+	r := runSweep(t, mock, true, tool2CSV(accounts[0], foreign), "-to", mcmaddr.To58(destination.tag), "-fee", "500", "-journal", journal, "-json")
+	readReport(t, r, sweep.StatusSwept, sweep.StatusFailed)
+	if r.code != cli.ExitFailure {
+		t.Errorf("failed account: exit %d", r.code)
+	}
+
+	// No block is mined before the timeout
+	r = runSweep(t, mock, false, tool2CSV(accounts[1]), "-to", mcmaddr.To58(destination.tag), "-fee", "500", "-journal", journal, "-timeout", "300ms", "-json")
+	out := readReport(t, r, sweep.StatusSubmitted)
+	if r.code != cli.ExitFailure || out.Accounts[0].Reason != "not mined yet" {
+		t.Errorf("unconfirmed: exit %d, %+v", r.code, out.Accounts[0])
+	}
+	// Without waiting for confirmations, a submitted sweep is a success
+	quick := newTestAccount("quick")
+	quick.fund(mock, 7000)
+	r = runSweep(t, mock, false, tool2CSV(quick), "-to", mcmaddr.To58(destination.tag), "-fee", "500", "-journal", journal, "-confirmations", "0", "-json")
+	out = readReport(t, r, sweep.StatusSubmitted)
+	if r.code != cli.ExitOK || out.Accounts[0].Amount != 6500 {
+		t.Errorf("no confirmations: exit %d, %+v", r.code, out.Accounts[0])
+	}
+}
+
+func TestSweepUsage(t *testing.T) {
+	mock, accounts, destination := sweepChain(t)
+	keys := tool2CSV(accounts...)
+	to := mcmaddr.To58(destination.tag)
+	for _, tc := range []struct {
+		name    string
+		keys    string
+		args    []string
+		message string
+	}{
+		{"no destination", keys, nil, "-keys and -to are required"},
+		{"bad destination", keys, []string{"-to", "nowhere"}, "invalid -to"},
+		{"bad dust", keys, []string{"-to", to, "-dust", "a little"}, "Error parsing -dust"},
+		{"bad fee", keys, []string{"-to", to, "-fee", "-5"}, "Error parsing -fee"},
+		{"bad seed", keys + "02,00,zz\n", []string{"-to", to}, "Error reading keys: line 6"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			r := runSweep(t, mock, false, tc.keys, append(tc.args, "-journal", filepath.Join(t.TempDir(), "journal.json"))...)
+			if r.code != cli.ExitUsage || !strings.Contains(r.stderr, tc.message) {
+				t.Errorf("exit %d: %s", r.code, r.stderr)
+			}
+		})
+	}
+
+	// A journal locked by another run is refused before anything is signed
+	journal := filepath.Join(t.TempDir(), "journal.json")
+	lock, err := fileutil.Lock(journal + ".lock")
+	if err != nil {
+		t.Skipf("no file lock: %v", err)
+	}
+	defer lock.Unlock()
+	r := runSweep(t, mock, false, keys, "-to", to, "-journal", journal)
+	if r.code != cli.ExitUsage || !strings.Contains(r.stderr, "is in use by another mcm-sweep run") || len(mock.Submitted()) != 0 {
+		t.Errorf("locked journal: exit %d: %s", r.code, r.stderr)
+	}
+}
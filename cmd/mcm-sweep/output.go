@@ -0,0 +1,42 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"text/tabwriter"
+
+	"github.com/NickP005/Vindax-MCM-tools/pkg/amount"
+	"github.com/NickP005/Vindax-MCM-tools/pkg/sweep"
+)
+
+// WriteTable writes one row per account followed by the totals
+func WriteTable(out io.Writer, accounts []*sweep.Account, summary sweep.Summary) error {
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "LINE\tADDRESS\tBALANCE (nMCM)\tSTATUS\tTXID\tREASON")
+	for _, account := range accounts {
+		fmt.Fprintf(w, "%d\t%s\t%s\t%s\t%s\t%s\n", account.Line, account.Address,
+			strconv.FormatUint(account.Balance, 10), account.Status, account.TxID, account.Reason)
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+	_, err := fmt.Fprintf(out, "\n%d accounts: %d swept, %d submitted, %d would sweep, %d skipped, %d failed\nSent: %s, fees %s\n",
+		summary.Accounts, summary.Swept, summary.Submitted, summary.WouldSweep, summary.Skipped, summary.Failed,
+		amount.Describe(summary.Amount), amount.Format(summary.Fees, amount.MCM))
+	return err
+}
+
+// WriteJSON writes the accounts and the summary as one JSON object
+func WriteJSON(out io.Writer, accounts []*sweep.Account, summary sweep.Summary) error {
+	if accounts == nil {
+		accounts = []*sweep.Account{}
+	}
+	encoder := json.NewEncoder(out)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(struct {
+		Accounts []*sweep.Account `json:"accounts"`
+		Summary  sweep.Summary    `json:"summary"`
+	}{accounts, summary})
+}
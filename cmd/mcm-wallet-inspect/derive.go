@@ -0,0 +1,62 @@
+package main
+
+import (
+	"encoding/hex"
+	"fmt"
+
+	"github.com/NickP005/Vindax-MCM-tools/pkg/mcmaddr"
+	"github.com/NickP005/Vindax-MCM-tools/pkg/walletstore"
+	"github.com/NickP005/Vindax-MCM-tools/pkg/wotsp"
+)
+
+/*
+ * Deriver computes the address hash of wallet indices, with wallet-tool's
+ * keychain
+ *
+ * Only address hashes leave it: each keypair is wiped as soon as its public
+ * key is hashed. Hashes are remembered, so the on-chain search does not
+ * derive the indices of the range again.
+ */
+type Deriver struct {
+	keychain *walletstore.Keychain
+	hashes   map[uint64][wotsp.AddrHashLength]byte
+}
+
+// NewDeriver creates the keychain of a 32 bytes hex secret key
+func NewDeriver(secretKey string) (*Deriver, error) {
+	keychain, err := walletstore.NewKeychain(secretKey)
+	if err != nil {
+		return nil, fmt.Errorf("invalid secret key: %v", err)
+	}
+	return &Deriver{keychain: keychain, hashes: make(map[uint64][wotsp.AddrHashLength]byte)}, nil
+}
+
+// AddrHash returns the address hash of the key at index
+func (d *Deriver) AddrHash(index uint64) ([wotsp.AddrHashLength]byte, error) {
+	if hash, ok := d.hashes[index]; ok {
+		return hash, nil
+	}
+	hash := d.keychain.AddrHash(index)
+	d.keychain.Wipe()
+	d.hashes[index] = hash
+	return hash, nil
+}
+
+// Tag returns the wallet tag: the address hash of index 0, which every later key of the wallet spends from
+func (d *Deriver) Tag() ([mcmaddr.TagLength]byte, error) {
+	return d.AddrHash(0)
+}
+
+// IndexView is one derived index, as printed
+type IndexView struct {
+	Index    uint64 `json:"index"`
+	AddrHash string `json:"addrHash"`
+	Base58   string `json:"base58"`
+	// Flags name what the index is for the wallet: refill, current, pending, controlling
+	Flags []string `json:"flags,omitempty"`
+}
+
+// newIndexView returns the view of an index and its address hash, without flags
+func newIndexView(index uint64, hash [wotsp.AddrHashLength]byte) IndexView {
+	return IndexView{Index: index, AddrHash: hex.EncodeToString(hash[:]), Base58: mcmaddr.To58(hash)}
+}
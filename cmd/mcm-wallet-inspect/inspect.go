@@ -0,0 +1,247 @@
+package main
+
+import (
+	"context"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/NickP005/Vindax-MCM-tools/pkg/mcmaddr"
+	"github.com/NickP005/Vindax-MCM-tools/pkg/meshclient"
+	"github.com/NickP005/Vindax-MCM-tools/pkg/secure"
+)
+
+// MAX_RANGE bounds the indices of -range, each one being a full WOTS+ key derivation
+const MAX_RANGE = 10000
+
+// Flags of an index
+const (
+	FlagRefill      = "refill"      // index 0, whose address hash is the wallet tag
+	FlagCurrent     = "current"     // the index of the cache
+	FlagPending     = "pending"     // the index that signed the pending transaction of the cache
+	FlagControlling = "controlling" // the index of the key the tag belongs to on chain
+)
+
+// PendingView is the pending transaction of the cache, its signed bytes summed up by their length
+type PendingView struct {
+	Index         uint64    `json:"index"`
+	SignedAt      time.Time `json:"signedAt"`
+	Fee           uint64    `json:"fee"`
+	FeeRejected   bool      `json:"feeRejected,omitempty"`
+	SignedTxBytes int       `json:"signedTxBytes"`
+}
+
+/*
+ * OnChain is what the chain holds for the wallet tag
+ *
+ * Controlling is the index of the key the tag belongs to, nil when the tag
+ * is not on chain or no index below Searched derives its address hash.
+ */
+type OnChain struct {
+	Found       bool    `json:"found"`
+	Address     string  `json:"address,omitempty"`
+	Balance     uint64  `json:"balance"`
+	Controlling *uint64 `json:"controllingIndex,omitempty"`
+	Searched    uint64  `json:"searched"`
+}
+
+// Inspection is everything printed about a wallet cache
+type Inspection struct {
+	File    string   `json:"file"`
+	Schema  string   `json:"schema"`
+	Fields  []string `json:"fields"`
+	Unknown []string `json:"unknownFields,omitempty"`
+	// SecretKey is set only with -show-secret
+	SecretKey     string       `json:"secretKey,omitempty"`
+	Index         uint64       `json:"index"`
+	RefillAddress string       `json:"refillAddress"`
+	RefillMatches bool         `json:"refillMatches"`
+	TagHex        string       `json:"tag"`
+	TagBase58     string       `json:"tagBase58"`
+	Pending       *PendingView `json:"pendingTx,omitempty"`
+	From          uint64       `json:"from"`
+	To            uint64       `json:"to"`
+	Indices       []IndexView  `json:"indices"`
+	OnChain       *OnChain     `json:"onChain,omitempty"`
+}
+
+// Mismatch reports whether the cache disagrees with itself or with the chain; an unset refill address is not a mismatch
+func (i *Inspection) Mismatch() bool {
+	if i.RefillAddress != "" && !i.RefillMatches {
+		return true
+	}
+	if i.OnChain == nil || !i.OnChain.Found {
+		return false
+	}
+	return i.OnChain.Controlling == nil || *i.OnChain.Controlling != i.Index
+}
+
+/*
+ * parseRange parses -range as from:to, to excluded, or as a single index
+ *
+ * An empty range selects the indices around the current one: the 8 before
+ * it and the 2 after it, where the next keys of the wallet are.
+ */
+func parseRange(text string, current uint64) (uint64, uint64, error) {
+	if text == "" {
+		return current - min(current, 8), current + 3, nil
+	}
+	fromText, toText, isRange := strings.Cut(text, ":")
+	from, err := strconv.ParseUint(fromText, 10, 64)
+	if err != nil {
+		return 0, 0, fmt.Errorf("invalid range %q: expected from:to or an index", text)
+	}
+	to := from + 1
+	if isRange {
+		if to, err = strconv.ParseUint(toText, 10, 64); err != nil {
+			return 0, 0, fmt.Errorf("invalid range %q: expected from:to or an index", text)
+		}
+	}
+	switch {
+	case to <= from:
+		return 0, 0, fmt.Errorf("invalid range %q: empty, the end is excluded", text)
+	case to-from > MAX_RANGE:
+		return 0, 0, fmt.Errorf("invalid range %q: more than %d indices", text, MAX_RANGE)
+	}
+	return from, to, nil
+}
+
+// Inspect derives the indices of [from, to) and describes the cache; the secret key is included only with showSecret
+func Inspect(path string, cache *WalletCache, deriver *Deriver, from uint64, to uint64, showSecret bool) (*Inspection, error) {
+	tag, err := deriver.Tag()
+	if err != nil {
+		return nil, err
+	}
+	inspection := &Inspection{
+		File:      path,
+		Schema:    cache.Schema,
+		Fields:    cache.Fields,
+		Unknown:   cache.Unknown,
+		Index:     cache.Index,
+		TagHex:    hex.EncodeToString(tag[:]),
+		TagBase58: mcmaddr.To58(tag),
+		From:      from,
+		To:        to,
+	}
+	if inspection.Schema == "" {
+		inspection.Schema = "unversioned"
+	}
+	if showSecret {
+		inspection.SecretKey = cache.SecretKey
+	}
+	inspection.RefillAddress = cache.RefillAddress
+	refill, err := mcmaddr.Normalize(cache.RefillAddress)
+	inspection.RefillMatches = err == nil && secure.Equal(refill[:], tag[:])
+	if cache.Pending != nil {
+		inspection.Pending = &PendingView{
+			Index:         cache.Pending.Index,
+			SignedAt:      cache.Pending.SignedAt,
+			Fee:           cache.Pending.Fee,
+			FeeRejected:   cache.Pending.FeeRejected,
+			SignedTxBytes: len(cache.Pending.SignedTx) / 2,
+		}
+	}
+
+	for index := from; index < to; index++ {
+		if err := inspection.addIndex(deriver, index); err != nil {
+			return nil, err
+		}
+	}
+	return inspection, nil
+}
+
+// addIndex adds the row of an index if it is not there yet, keeping the rows in index order
+func (i *Inspection) addIndex(deriver *Deriver, index uint64) error {
+	at := sort.Search(len(i.Indices), func(n int) bool { return i.Indices[n].Index >= index })
+	if at < len(i.Indices) && i.Indices[at].Index == index {
+		return nil
+	}
+	hash, err := deriver.AddrHash(index)
+	if err != nil {
+		return err
+	}
+	i.Indices = append(i.Indices, IndexView{})
+	copy(i.Indices[at+1:], i.Indices[at:])
+	i.Indices[at] = newIndexView(index, hash)
+	return nil
+}
+
+/*
+ * CheckOnChain resolves the wallet tag and looks for the index of the key
+ * it belongs to
+ *
+ * The index of the cache is tried first, then every index below search, as
+ * wallet-tool's search does. Address hashes are compared in constant time.
+ */
+func (i *Inspection) CheckOnChain(ctx context.Context, client *meshclient.MeshAPIClient, deriver *Deriver, search uint64) error {
+	tag, err := deriver.Tag()
+	if err != nil {
+		return err
+	}
+	resolution, err := client.ResolveTag(ctx, tag[:])
+	if errors.Is(err, meshclient.ErrTagNotFound) {
+		i.OnChain = &OnChain{}
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to resolve wallet tag: %v", err)
+	}
+	onChain := &OnChain{Found: true, Address: resolution.AddressHex, Balance: resolution.Amount, Searched: search}
+	i.OnChain = onChain
+	wanted := resolution.AddrHash()
+	controls := func(index uint64) (bool, error) {
+		hash, err := deriver.AddrHash(index)
+		if err != nil || !secure.Equal(hash[:], wanted[:]) {
+			return false, err
+		}
+		onChain.Controlling = &index
+		return true, nil
+	}
+
+	if found, err := controls(i.Index); found || err != nil {
+		return err
+	}
+	for index := uint64(0); index < search; index++ {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if found, err := controls(index); found || err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// flagMark is a flag to set on the row of an index
+type flagMark struct {
+	index uint64
+	flag  string
+}
+
+// Flag marks the rows of the indices that matter to the wallet, adding the rows of those outside the range
+func (i *Inspection) Flag(deriver *Deriver) error {
+	marks := []flagMark{{0, FlagRefill}, {i.Index, FlagCurrent}}
+	if i.Pending != nil {
+		marks = append(marks, flagMark{i.Pending.Index, FlagPending})
+	}
+	if i.OnChain != nil && i.OnChain.Controlling != nil {
+		marks = append(marks, flagMark{*i.OnChain.Controlling, FlagControlling})
+	}
+	for _, mark := range marks {
+		if mark.flag != FlagRefill {
+			if err := i.addIndex(deriver, mark.index); err != nil {
+				return err
+			}
+		}
+		for n := range i.Indices {
+			if i.Indices[n].Index == mark.index {
+				i.Indices[n].Flags = append(i.Indices[n].Flags, mark.flag)
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,228 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/NickP005/Vindax-MCM-tools/pkg/meshclient"
+	"github.com/NickP005/Vindax-MCM-tools/pkg/meshmock"
+	"github.com/NickP005/Vindax-MCM-tools/pkg/walletstore"
+)
+
+// fixture is testdata/wallet-cache.json: index 5, the refill address its tag, a pending transaction signed at 5
+const fixture = "testdata/wallet-cache.json"
+
+// inspectFixture reads the fixture and inspects the indices of [from, to)
+func inspectFixture(t *testing.T, from uint64, to uint64, showSecret bool) (*WalletCache, *Deriver, *Inspection) {
+	t.Helper()
+	cache, err := ReadWalletCache(fixture)
+	if err != nil {
+		t.Fatal(err)
+	}
+	deriver, err := NewDeriver(cache.SecretKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	inspection, err := Inspect(fixture, cache, deriver, from, to, showSecret)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return cache, deriver, inspection
+}
+
+func TestReadWalletCache(t *testing.T) {
+	cache, err := ReadWalletCache(fixture)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cache.Index != 5 || cache.Pending == nil || cache.Pending.Fee != 500 || cache.Schema != "" {
+		t.Errorf("cache %+v", cache)
+	}
+	if !slices.Equal(cache.Fields, []string{"index", "note", "pendingTx", "refillAddress", "secretKey"}) || !slices.Equal(cache.Unknown, []string{"note"}) {
+		t.Errorf("fields %v, unknown %v", cache.Fields, cache.Unknown)
+	}
+
+	dir := t.TempDir()
+	versioned := filepath.Join(dir, "versioned.json")
+	os.WriteFile(versioned, []byte(`{"secretKey":"`+cache.SecretKey+`","index":0,"schemaVersion":2}`), 0600)
+	if cache, err := ReadWalletCache(versioned); err != nil || cache.Schema != "2" || !slices.Equal(cache.Unknown, []string{"schemaVersion"}) {
+		t.Errorf("versioned: %+v, %v", cache, err)
+	}
+
+	for content, message := range map[string]string{
+		`{"index":3}`:  "no secretKey",
+		`{"secretKey"`: "invalid wallet cache",
+		`[1, 2]`:       "invalid wallet cache",
+	} {
+		path := filepath.Join(dir, "bad.json")
+		os.WriteFile(path, []byte(content), 0600)
+		if _, err := ReadWalletCache(path); err == nil || !strings.Contains(err.Error(), message) {
+			t.Errorf("%s: %v", content, err)
+		}
+	}
+	// A missing cache is an error, never a new wallet
+	if _, err := ReadWalletCache(filepath.Join(dir, "missing.json")); !os.IsNotExist(err) {
+		t.Errorf("missing file: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "missing.json")); !os.IsNotExist(err) {
+		t.Error("missing cache created")
+	}
+}
+
+func TestParseRange(t *testing.T) {
+	for _, tc := range []struct {
+		text     string
+		current  uint64
+		from, to uint64
+		failed   bool
+	}{
+		{"", 20, 12, 23, false},
+		{"", 3, 0, 6, false},
+		{"0:50", 3, 0, 50, false},
+		{"7", 3, 7, 8, false},
+		{"5:5", 3, 0, 0, true},
+		{"9:2", 3, 0, 0, true},
+		{"0:10001", 3, 0, 0, true},
+		{"a:3", 3, 0, 0, true},
+		{"1:", 3, 0, 0, true},
+		{"-1:3", 3, 0, 0, true},
+	} {
+		from, to, err := parseRange(tc.text, tc.current)
+		if (err != nil) != tc.failed || from != tc.from || to != tc.to {
+			t.Errorf("%q: %d:%d, %v", tc.text, from, to, err)
+		}
+	}
+}
+
+func TestInspect(t *testing.T) {
+	cache, deriver, inspection := inspectFixture(t, 0, 8, false)
+	if inspection.SecretKey != "" || !inspection.RefillMatches || inspection.Schema != "unversioned" || inspection.Mismatch() {
+		t.Errorf("inspection %+v", inspection)
+	}
+	if inspection.Pending == nil || inspection.Pending.SignedTxBytes != 4 || !inspection.Pending.FeeRejected {
+		t.Errorf("pending %+v", inspection.Pending)
+	}
+
+	// The rows are the keychain of wallet-tool
+	keychain, _ := walletstore.NewKeychain(cache.SecretKey)
+	defer keychain.Wipe()
+	tag := keychain.Tag()
+	if inspection.TagHex != hex.EncodeToString(tag[:]) || inspection.TagBase58 != cache.RefillAddress {
+		t.Errorf("tag %s (%s)", inspection.TagBase58, inspection.TagHex)
+	}
+	if len(inspection.Indices) != 8 {
+		t.Fatalf("%d rows", len(inspection.Indices))
+	}
+	for _, row := range inspection.Indices {
+		hash := keychain.AddrHash(row.Index)
+		if row.AddrHash != hex.EncodeToString(hash[:]) {
+			t.Errorf("index %d: hash %s", row.Index, row.AddrHash)
+		}
+	}
+
+	if err := inspection.Flag(deriver); err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(inspection.Indices[0].Flags, []string{FlagRefill}) || !slices.Equal(inspection.Indices[5].Flags, []string{FlagCurrent, FlagPending}) || inspection.Indices[6].Flags != nil {
+		t.Errorf("flags %v, %v, %v", inspection.Indices[0].Flags, inspection.Indices[5].Flags, inspection.Indices[6].Flags)
+	}
+
+	// A refill address of another wallet is a mismatch
+	cache.RefillAddress = "0x" + strings.Repeat("ab", 20)
+	other, _ := Inspect(fixture, cache, deriver, 0, 1, false)
+	if other.RefillMatches || !other.Mismatch() {
+		t.Error("foreign refill address accepted")
+	}
+}
+
+// TestSecretHidden prints the fixture in text and JSON: the secret key shows only with -show-secret
+func TestSecretHidden(t *testing.T) {
+	for _, showSecret := range []bool{false, true} {
+		cache, _, inspection := inspectFixture(t, 4, 6, showSecret)
+		var text, json bytes.Buffer
+		if err := WriteText(&text, inspection); err != nil {
+			t.Fatal(err)
+		}
+		if err := WriteJSON(&json, inspection); err != nil {
+			t.Fatal(err)
+		}
+		for name, out := range map[string]string{"text": text.String(), "JSON": json.String()} {
+			if strings.Contains(out, cache.SecretKey) != showSecret {
+				t.Errorf("%s output, -show-secret %v:\n%s", name, showSecret, out)
+			}
+		}
+		if !showSecret && !strings.Contains(text.String(), "hidden, -show-secret prints it") {
+			t.Errorf("text output:\n%s", text.String())
+		}
+	}
+}
+
+/*
+ * TestCheckOnChain resolves the fixture's tag on meshmock: not on chain,
+ * controlled by the cache index, drifted to index 7 outside the range,
+ * controlled by no index, and the API down
+ */
+func TestCheckOnChain(t *testing.T) {
+	mock := meshmock.New()
+	defer mock.Close()
+	client := meshclient.NewMeshAPIClient(mock.URL(), nil)
+	ctx := context.Background()
+	cache, deriver, _ := inspectFixture(t, 0, 1, false)
+	tag, _ := deriver.Tag()
+	fund := func(index uint64) {
+		hash, _ := deriver.AddrHash(index)
+		mock.SetAccount(tag[:], "0x"+hex.EncodeToString(tag[:])+hex.EncodeToString(hash[:]), 4200)
+	}
+
+	check := func(search uint64) (*Inspection, string) {
+		t.Helper()
+		inspection, _ := Inspect(fixture, cache, deriver, 3, 6, false)
+		if err := inspection.CheckOnChain(ctx, client, deriver, search); err != nil {
+			t.Fatal(err)
+		}
+		inspection.Flag(deriver)
+		var out bytes.Buffer
+		WriteText(&out, inspection)
+		return inspection, out.String()
+	}
+
+	inspection, out := check(10)
+	if inspection.OnChain.Found || inspection.Mismatch() || !strings.Contains(out, "the wallet tag is not on chain") {
+		t.Errorf("not on chain: %+v\n%s", inspection.OnChain, out)
+	}
+
+	fund(5)
+	inspection, out = check(10)
+	if inspection.OnChain.Controlling == nil || *inspection.OnChain.Controlling != 5 || inspection.Mismatch() || !strings.Contains(out, "controlled by index 5, the cache index") {
+		t.Errorf("cache index: %+v\n%s", inspection.OnChain, out)
+	}
+
+	fund(7)
+	inspection, out = check(10)
+	if inspection.OnChain.Controlling == nil || *inspection.OnChain.Controlling != 7 || !inspection.Mismatch() || !strings.Contains(out, "NOT THE CACHE INDEX 5") {
+		t.Errorf("drifted: %+v\n%s", inspection.OnChain, out)
+	}
+	// The controlling row is added after the range, flagged
+	last := inspection.Indices[len(inspection.Indices)-1]
+	if last.Index != 7 || !slices.Equal(last.Flags, []string{FlagControlling}) || !strings.Contains(out, "and the flagged ones outside") {
+		t.Errorf("controlling row %+v", last)
+	}
+
+	fund(12)
+	inspection, out = check(10)
+	if inspection.OnChain.Controlling != nil || !inspection.Mismatch() || !strings.Contains(out, "controlled by no index below 10") {
+		t.Errorf("beyond the search: %+v\n%s", inspection.OnChain, out)
+	}
+
+	mock.Fail("/call", meshmock.Fault{Status: 500, Body: `{"code":2,"message":"internal error","retriable":false}`})
+	inspection, _ = Inspect(fixture, cache, deriver, 3, 6, false)
+	if err := inspection.CheckOnChain(ctx, client, deriver, 10); err == nil || !strings.Contains(err.Error(), "failed to resolve wallet tag") {
+		t.Errorf("API down: %v", err)
+	}
+}
@@ -0,0 +1,104 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/NickP005/Vindax-MCM-tools/pkg/cli"
+	"github.com/NickP005/Vindax-MCM-tools/pkg/config"
+	"github.com/NickP005/Vindax-MCM-tools/pkg/meshclient"
+	"github.com/NickP005/Vindax-MCM-tools/pkg/shutdown"
+)
+
+// Exit codes of mcm-wallet-inspect beyond those of pkg/cli, stable for use from shell scripts
+const (
+	ExitMismatch = 3 // the refill address is not the wallet tag, or the cache index does not control it on chain
+)
+
+// MAX_INDEX_SEARCH is how far the on-chain check looks for the controlling index by default, as wallet-tool does
+const MAX_INDEX_SEARCH = 10000
+
+// newMeshClient returns a Mesh API client identifying mcm-wallet-inspect in its User-Agent, retrying failed lookups
+func newMeshClient(api string, cfg *config.Config) *meshclient.MeshAPIClient {
+	client := meshclient.NewMeshAPIClient(api, nil)
+	cfg.Apply(client)
+	client.SetUserAgent("mcm-wallet-inspect")
+	client.SetRetryPolicy(meshclient.DefaultRetryPolicy())
+	return client
+}
+
+func main() {
+	// The on-chain check stays opt-in: the API of the configuration is not used, only its network and failover endpoints
+	cfg, err := config.Load(config.Defaults())
+	if err != nil {
+		cli.Usagef("%v", err)
+	}
+	walletFile := flag.String("wallet", "wallet-cache.json", "Wallet cache file")
+	indexRange := flag.String("range", "", "Indices to derive, from:to (to excluded) or one index (default: around the cache index)")
+	api := flag.String("api", "", "Mesh API URL; when set, look up which index controls the wallet tag on chain")
+	search := flag.Uint64("search", MAX_INDEX_SEARCH, "With -api, the indices below this one searched for the controlling key")
+	showSecret := flag.Bool("show-secret", false, "Print the secret key of the wallet")
+	asJSON := flag.Bool("json", false, "Output JSON")
+	printConfig := cfg.PrintFlag(flag.CommandLine)
+	flag.Usage = func() {
+		fmt.Fprintln(flag.CommandLine.Output(), "Usage: mcm-wallet-inspect [-wallet wallet-cache.json] [-range from:to] [-api URL] [-json]")
+		flag.PrintDefaults()
+	}
+
+	cli.Parse(nil)
+	if *printConfig {
+		cfg.Print(os.Stdout)
+		return
+	}
+	if flag.NArg() > 0 {
+		cli.Usagef("unexpected argument %q", flag.Arg(0))
+	}
+
+	cache, err := ReadWalletCache(*walletFile)
+	if err != nil {
+		cli.Usagef("%v", err)
+	}
+	from, to, err := parseRange(*indexRange, cache.Index)
+	if err != nil {
+		cli.Usagef("-range: %v", err)
+	}
+	deriver, err := NewDeriver(cache.SecretKey)
+	if err != nil {
+		cli.Usagef("%s: %v", *walletFile, err)
+	}
+	inspection, err := Inspect(*walletFile, cache, deriver, from, to, *showSecret)
+	if err != nil {
+		cli.Usagef("%v", err)
+	}
+
+	code := cli.ExitOK
+	if *api != "" {
+		// Interrupting the tool stops the search, the cache is printed without the on-chain state
+		sig := shutdown.Notify()
+		defer sig.Stop()
+		ctx := sig.Context()
+		if err := inspection.CheckOnChain(ctx, newMeshClient(*api, cfg), deriver, *search); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			inspection.OnChain = nil
+			code = cli.ExitFailure
+		}
+	}
+	if err := inspection.Flag(deriver); err != nil {
+		cli.Usagef("%v", err)
+	}
+
+	if *asJSON {
+		err = WriteJSON(os.Stdout, inspection)
+	} else {
+		err = WriteText(os.Stdout, inspection)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error writing output: %v\n", err)
+		os.Exit(cli.ExitUsage)
+	}
+	if code == cli.ExitOK && inspection.Mismatch() {
+		code = ExitMismatch
+	}
+	os.Exit(code)
+}
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/NickP005/Vindax-MCM-tools/pkg/amount"
+)
+
+// WriteText writes the cache metadata, what the chain holds when checked, then a row per index
+func WriteText(out io.Writer, inspection *Inspection) error {
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(w, "wallet cache:\t%s\n", inspection.File)
+	fmt.Fprintf(w, "schema:\t%s (fields: %s)\n", inspection.Schema, strings.Join(inspection.Fields, ", "))
+	if len(inspection.Unknown) > 0 {
+		fmt.Fprintf(w, "unknown fields:\t%s\n", strings.Join(inspection.Unknown, ", "))
+	}
+	if inspection.SecretKey != "" {
+		fmt.Fprintf(w, "secret key:\t%s\n", inspection.SecretKey)
+	} else {
+		fmt.Fprintf(w, "secret key:\thidden, -show-secret prints it\n")
+	}
+	fmt.Fprintf(w, "index:\t%d\n", inspection.Index)
+	fmt.Fprintf(w, "wallet tag:\t%s (%s)\n", inspection.TagBase58, inspection.TagHex)
+	switch {
+	case inspection.RefillAddress == "":
+		fmt.Fprintf(w, "refill address:\tnot set\n")
+	case inspection.RefillMatches:
+		fmt.Fprintf(w, "refill address:\t%s, the wallet tag\n", inspection.RefillAddress)
+	default:
+		fmt.Fprintf(w, "refill address:\t%s, NOT THE WALLET TAG: this cache does not belong to its secret key\n", inspection.RefillAddress)
+	}
+	if pending := inspection.Pending; pending != nil {
+		line := fmt.Sprintf("signed at index %d on %s, fee %s, %d bytes", pending.Index,
+			pending.SignedAt.Format(time.RFC3339), amount.Describe(pending.Fee), pending.SignedTxBytes)
+		if pending.FeeRejected {
+			line += ", rejected for its fee"
+		}
+		fmt.Fprintf(w, "pending tx:\t%s\n", line)
+	}
+	if inspection.OnChain != nil {
+		fmt.Fprintf(w, "on chain:\t%s\n", describeOnChain(inspection))
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+
+	header := fmt.Sprintf("Indices %d to %d", inspection.From, inspection.To-1)
+	if len(inspection.Indices) > int(inspection.To-inspection.From) {
+		header += ", and the flagged ones outside"
+	}
+	fmt.Fprintf(out, "\n%s:\n", header)
+	w = tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(w, "INDEX\tADDRESS HASH\tBASE58\tFLAGS\n")
+	for _, row := range inspection.Indices {
+		fmt.Fprintf(w, "%d\t%s\t%s\t%s\n", row.Index, row.AddrHash, row.Base58, strings.Join(row.Flags, ", "))
+	}
+	return w.Flush()
+}
+
+// describeOnChain renders what the chain holds for the wallet tag, and whether the cache index agrees
+func describeOnChain(inspection *Inspection) string {
+	onChain := inspection.OnChain
+	switch {
+	case !onChain.Found:
+		return "the wallet tag is not on chain: never funded, or emptied"
+	case onChain.Controlling == nil:
+		return fmt.Sprintf("%s, %s, controlled by no index below %d: is this the right wallet cache?",
+			onChain.Address, amount.Describe(onChain.Balance), onChain.Searched)
+	case *onChain.Controlling != inspection.Index:
+		return fmt.Sprintf("%s, %s, controlled by index %d, NOT THE CACHE INDEX %d",
+			onChain.Address, amount.Describe(onChain.Balance), *onChain.Controlling, inspection.Index)
+	}
+	return fmt.Sprintf("%s, %s, controlled by index %d, the cache index",
+		onChain.Address, amount.Describe(onChain.Balance), *onChain.Controlling)
+}
+
+// WriteJSON writes v as indented JSON
+func WriteJSON(out io.Writer, v interface{}) error {
+	encoder := json.NewEncoder(out)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(v)
+}
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"time"
+)
+
+// knownFields are the members of a wallet cache written by wallet-tool; any other is listed as unknown
+var knownFields = map[string]bool{
+	"secretKey":     true,
+	"index":         true,
+	"refillAddress": true,
+	"pendingTx":     true,
+}
+
+// versionFields are the members a versioned cache would use; wallet-tool writes none of them
+var versionFields = []string{"version", "schemaVersion", "schema"}
+
+// PendingTx is the pendingTx member of a wallet cache, the signed bytes left out
+type PendingTx struct {
+	Index       uint64    `json:"index"`
+	SignedAt    time.Time `json:"signedAt"`
+	Fee         uint64    `json:"fee"`
+	FeeRejected bool      `json:"feeRejected,omitempty"`
+	// SignedTx is the signed transaction in hex, only its length is shown
+	SignedTx string `json:"signedTx"`
+}
+
+/*
+ * WalletCache is a wallet cache file as wallet-tool writes it
+ *
+ * SecretKey is the 32 bytes seed of the keychain in hex: it is only ever
+ * printed with -show-secret. Schema is the version member of the file, or
+ * "" for the unversioned layout every wallet-tool release writes so far.
+ */
+type WalletCache struct {
+	SecretKey     string     `json:"secretKey"`
+	Index         uint64     `json:"index"`
+	RefillAddress string     `json:"refillAddress,omitempty"`
+	Pending       *PendingTx `json:"pendingTx,omitempty"`
+
+	Schema  string   `json:"-"`
+	Fields  []string `json:"-"`
+	Unknown []string `json:"-"`
+}
+
+// ReadWalletCache reads a wallet cache file, without ever creating one as wallet-tool does
+func ReadWalletCache(path string) (*WalletCache, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var cache WalletCache
+	if err := json.Unmarshal(data, &cache); err != nil {
+		return nil, fmt.Errorf("invalid wallet cache %s: %v", path, err)
+	}
+	var members map[string]json.RawMessage
+	if err := json.Unmarshal(data, &members); err != nil {
+		return nil, fmt.Errorf("invalid wallet cache %s: %v", path, err)
+	}
+	if _, ok := members["secretKey"]; !ok {
+		return nil, fmt.Errorf("invalid wallet cache %s: no secretKey", path)
+	}
+
+	for name := range members {
+		cache.Fields = append(cache.Fields, name)
+		if !knownFields[name] {
+			cache.Unknown = append(cache.Unknown, name)
+		}
+	}
+	sort.Strings(cache.Fields)
+	sort.Strings(cache.Unknown)
+	for _, name := range versionFields {
+		if raw, ok := members[name]; ok {
+			cache.Schema = string(raw)
+			break
+		}
+	}
+	return &cache, nil
+}
@@ -0,0 +1,201 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/NickP005/Vindax-MCM-tools/pkg/cli"
+	"github.com/NickP005/Vindax-MCM-tools/pkg/config"
+	"github.com/NickP005/Vindax-MCM-tools/pkg/mcmaddr"
+	"github.com/NickP005/Vindax-MCM-tools/pkg/meshclient"
+	"github.com/NickP005/Vindax-MCM-tools/pkg/shutdown"
+)
+
+// newMeshClient returns a Mesh API client identifying mempool-watch in its User-Agent
+func newMeshClient(cfg *config.Config) *meshclient.MeshAPIClient {
+	client := meshclient.NewMeshAPIClient(cfg.API, nil)
+	cfg.Apply(client)
+	client.SetUserAgent("mempool-watch")
+	client.SetRetryPolicy(meshclient.DefaultRetryPolicy())
+	// No StatusCache: a poll needs the tip as of its snapshot to tell a mined transaction from a dropped one
+	return client
+}
+
+/*
+ * runOnce implements -once: one poll diffed against the snapshot saved in
+ * stateFile by the previous run, which is then replaced
+ *
+ * Without a previous snapshot (first run, no -state, or a state file of
+ * another -address) every transaction in the mempool is printed as present.
+ */
+func runOnce(ctx context.Context, watcher *Watcher, out *EventWriter, stateFile string, address string) int {
+	if stateFile != "" {
+		state, err := ReadWatchState(stateFile)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error reading state file: %v\n", err)
+			return cli.ExitUsage
+		}
+		switch {
+		case state == nil:
+		case state.Address != address:
+			fmt.Fprintf(os.Stderr, "Warning: state file %s is for another -address, starting over\n", stateFile)
+		default:
+			watcher.Restore(*state)
+		}
+	}
+
+	events, err := watcher.Poll(ctx, true)
+	if err != nil && !errors.Is(err, ErrIncomplete) {
+		fmt.Fprintf(os.Stderr, "Error reading the mempool: %v\n", err)
+		return cli.ExitFailure
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+	}
+	if err := out.Write(events); err != nil {
+		fmt.Fprintf(os.Stderr, "Error writing output: %v\n", err)
+		return cli.ExitFailure
+	}
+	if stateFile != "" {
+		if err := WriteWatchState(stateFile, watcher.State(address)); err != nil {
+			fmt.Fprintf(os.Stderr, "Error writing state file: %v\n", err)
+			return cli.ExitFailure
+		}
+	}
+	return cli.ExitOK
+}
+
+/*
+ * runWatch polls the mempool every interval, and as soon as a block is
+ * mined, until ctx is done
+ *
+ * Blocks come from WatchBlocks and are recorded before the poll they
+ * trigger, so their transactions are reported mined. Failed polls are
+ * reported on stderr and retried on the next tick.
+ */
+func runWatch(ctx context.Context, client *meshclient.MeshAPIClient, watcher *Watcher, out *EventWriter, interval time.Duration) int {
+	blocks, err := client.WatchBlocks(ctx, interval)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error reading the network status: %v\n", err)
+		return cli.ExitFailure
+	}
+
+	poll := func() bool {
+		events, err := watcher.Poll(ctx, false)
+		if ctx.Err() != nil {
+			return true
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+		}
+		if err := out.Write(events); err != nil {
+			fmt.Fprintf(os.Stderr, "Error writing output: %v\n", err)
+			return false
+		}
+		return true
+	}
+
+	// The baseline is retried until the mempool can be read
+	for {
+		_, err := watcher.Poll(ctx, false)
+		if err == nil {
+			fmt.Fprintf(os.Stderr, "Watching %d transactions in the mempool\n", len(watcher.pending))
+			break
+		}
+		fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+		select {
+		case <-ctx.Done():
+			return cli.ExitOK
+		case <-time.After(interval):
+		}
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return cli.ExitOK
+		case event, ok := <-blocks:
+			if !ok {
+				return cli.ExitOK
+			}
+			if event.Err != nil {
+				continue
+			}
+			if tip, _ := watcher.Tip(); event.Reorg || event.Height > tip {
+				block, err := client.Block(ctx, event.Height)
+				if err != nil {
+					// The poll syncs the blocks it missed
+					fmt.Fprintf(os.Stderr, "Warning: block %d: %v\n", event.Height, err)
+				} else {
+					watcher.RecordBlock(block)
+				}
+			}
+			if !poll() {
+				return cli.ExitFailure
+			}
+		case <-ticker.C:
+			if !poll() {
+				return cli.ExitFailure
+			}
+		}
+	}
+}
+
+func main() {
+	cfg, err := config.Load(config.Defaults())
+	if err != nil {
+		cli.Usagef("%v", err)
+	}
+	cfg.APIFlags(flag.CommandLine, "Mesh API URL")
+	cfg.PollIntervalFlag(flag.CommandLine, "interval", "Time between two polls of the mempool")
+	address := flag.String("address", "", "Only report the transactions touching this address (hex or base58)")
+	asJSON := flag.Bool("json", false, "Output NDJSON, one event object per line")
+	once := flag.Bool("once", false, "Poll once and exit, diffing against the snapshot saved in -state (for cron)")
+	stateFile := flag.String("state", "", "File keeping the snapshot between -once runs")
+	printConfig := cfg.PrintFlag(flag.CommandLine)
+	flag.Usage = func() {
+		fmt.Fprintln(flag.CommandLine.Output(), "Usage: mempool-watch [flags]")
+		flag.PrintDefaults()
+	}
+
+	cli.Parse(cfg)
+	if *printConfig {
+		cfg.Print(os.Stdout)
+		return
+	}
+	if flag.NArg() > 0 {
+		cli.Usagef("unexpected argument %q", flag.Arg(0))
+	}
+	if *stateFile != "" && !*once {
+		cli.Usagef("-state is only used with -once")
+	}
+
+	var tag []byte
+	addressHex := ""
+	if *address != "" {
+		normalized, err := mcmaddr.Normalize(*address)
+		if err != nil {
+			cli.Usagef("invalid -address: %v", err)
+		}
+		tag = normalized[:]
+		addressHex = mcmaddr.ToHex(normalized)
+	}
+
+	sig := shutdown.Notify()
+	defer sig.Stop()
+	ctx := sig.Context()
+
+	client := newMeshClient(cfg)
+	watcher := NewWatcher(client, tag)
+	out := &EventWriter{Out: os.Stdout, JSON: *asJSON}
+	if *once {
+		os.Exit(runOnce(ctx, watcher, out, *stateFile, addressHex))
+	}
+	os.Exit(runWatch(ctx, client, watcher, out, cfg.PollInterval))
+}
@@ -0,0 +1,44 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+)
+
+// EventWriter prints events, as plain lines or as NDJSON (one JSON object per line)
+type EventWriter struct {
+	Out  io.Writer
+	JSON bool
+}
+
+// Write prints the events in order; the first write error is returned
+func (ew *EventWriter) Write(events []Event) error {
+	for _, event := range events {
+		var err error
+		if ew.JSON {
+			err = json.NewEncoder(ew.Out).Encode(event)
+		} else {
+			_, err = fmt.Fprintln(ew.Out, formatEvent(event))
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// formatEvent renders an event as one line, e.g. "2024-05-01T10:00:00Z departed <hash> mined in block 1234"
+func formatEvent(event Event) string {
+	line := event.Time.Format(time.RFC3339) + " " + event.Event
+	switch {
+	case event.Event == EventReset:
+		return fmt.Sprintf("%s: mempool replaced (%d transactions before, %d now)", line, event.Previous, event.Current)
+	case event.Outcome == OutcomeMined:
+		return fmt.Sprintf("%s %s mined in block %d", line, event.TxID, event.Block)
+	case event.Outcome != "":
+		return fmt.Sprintf("%s %s %s", line, event.TxID, event.Outcome)
+	}
+	return line + " " + event.TxID
+}
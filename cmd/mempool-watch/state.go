@@ -0,0 +1,65 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/NickP005/Vindax-MCM-tools/pkg/fileutil"
+)
+
+/*
+ * WatchState is what a -once run leaves for the next one: the snapshot to
+ * diff against and the block it was taken at
+ *
+ * Fields:
+ * - Address: the -address filter of the snapshot, hex, empty for the whole mempool
+ * - Tip: the highest block seen, the next run looks for mined transactions after it
+ * - Pending: the snapshot, by hash, mapped to whether the transaction is reported
+ */
+type WatchState struct {
+	Address string          `json:"address,omitempty"`
+	Tip     uint64          `json:"tip"`
+	Pending map[string]bool `json:"pending"`
+}
+
+// State returns the snapshot of the last poll, with the tip it was taken at
+func (w *Watcher) State(address string) WatchState {
+	return WatchState{Address: address, Tip: w.tip, Pending: w.pending}
+}
+
+// Restore makes state the previous snapshot, as if it had been polled by this watcher
+func (w *Watcher) Restore(state WatchState) {
+	w.pending = state.Pending
+	if w.pending == nil {
+		w.pending = make(map[string]bool)
+	}
+	w.baseline = true
+	w.SetTip(state.Tip)
+}
+
+// ReadWatchState reads a state file written by WriteWatchState; a missing file returns nil and no error
+func ReadWatchState(path string) (*WatchState, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var state WatchState
+	if err := json.Unmarshal(data, &state); err != nil {
+		return nil, fmt.Errorf("invalid state file %s: %v", path, err)
+	}
+	return &state, nil
+}
+
+// WriteWatchState replaces the state file atomically, see fileutil.WriteAtomic
+func WriteWatchState(path string, state WatchState) error {
+	data, err := json.MarshalIndent(state, "", "  ")
+	if err != nil {
+		return err
+	}
+	return fileutil.WriteAtomic(path, append(data, '\n'), false)
+}
@@ -0,0 +1,300 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/NickP005/Vindax-MCM-tools/pkg/meshclient"
+)
+
+// Events reported by the watcher
+const (
+	EventAppeared = "appeared"
+	EventDeparted = "departed"
+	EventPresent  = "present"
+	EventReset    = "reset"
+)
+
+// Outcomes of a departed transaction
+const (
+	OutcomeMined   = "mined"
+	OutcomeDropped = "dropped"
+)
+
+// RESET_MIN_SIZE is the smallest mempool whose complete replacement is reported as one reset rather than per transaction
+const RESET_MIN_SIZE = 20
+
+// MINED_KEEP_BLOCKS is how many blocks the transactions mined are remembered, to tell a mined departure from a dropped one
+const MINED_KEEP_BLOCKS = 20
+
+// DETAIL_CONCURRENCY bounds the /mempool/transaction lookups in flight
+const DETAIL_CONCURRENCY = 8
+
+// ErrIncomplete is returned by a poll that took its snapshot but could not check some transactions
+var ErrIncomplete = errors.New("some transactions could not be checked, they are looked at again on the next poll")
+
+/*
+ * Event is a change of the mempool
+ *
+ * Fields:
+ * - Event: one of the Event constants
+ * - TxID: the transaction hash, without 0x
+ * - Outcome, Block: for a departure, whether it was mined (and in which block) or dropped
+ * - Previous, Current: for a reset, the number of transactions before and after
+ */
+type Event struct {
+	Time     time.Time `json:"time"`
+	Event    string    `json:"event"`
+	TxID     string    `json:"txId,omitempty"`
+	Outcome  string    `json:"outcome,omitempty"`
+	Block    uint64    `json:"block,omitempty"`
+	Previous int       `json:"previous,omitempty"`
+	Current  int       `json:"current,omitempty"`
+}
+
+/*
+ * Watcher diffs successive snapshots of the mempool
+ *
+ * Two quirks of the Mesh API are taken care of:
+ * - servers may truncate the /mempool list of a large mempool, so a
+ *   transaction missing from the list is only reported departed once
+ *   /mempool/transaction confirms it is gone
+ * - a node that restarts or resyncs replaces its whole mempool at once,
+ *   which is reported as a single reset event rather than one departure per
+ *   transaction
+ *
+ * A departure is reported mined when a block holds the transaction, dropped
+ * otherwise: every poll first records the blocks mined since the previous
+ * one, up to MINED_KEEP_BLOCKS. With a tag, only the
+ * transactions with an operation on its account are reported: each new
+ * transaction is fetched once through /mempool/transaction.
+ */
+type Watcher struct {
+	client *meshclient.MeshAPIClient
+	tag    []byte
+
+	// pending is the last snapshot, by hash without 0x, mapped to whether the transaction is reported (touches the tag)
+	pending  map[string]bool
+	baseline bool
+	// mined maps the transactions of the recorded blocks to their height
+	mined map[string]uint64
+	// tip is the highest block recorded, valid once tipKnown
+	tip      uint64
+	tipKnown bool
+}
+
+// NewWatcher returns a watcher of the whole mempool, or of the transactions touching tag if not nil
+func NewWatcher(client *meshclient.MeshAPIClient, tag []byte) *Watcher {
+	return &Watcher{client: client, tag: tag, pending: make(map[string]bool), mined: make(map[string]uint64)}
+}
+
+// normalizeHash lowercases a hash and drops its 0x prefix, so hashes of different sources compare equal
+func normalizeHash(h string) string {
+	return strings.ToLower(strings.TrimPrefix(strings.TrimPrefix(h, "0x"), "0X"))
+}
+
+// RecordBlock remembers the transactions of a block, forgetting those of blocks MINED_KEEP_BLOCKS below it
+func (w *Watcher) RecordBlock(block *meshclient.Block) {
+	height := block.Block.BlockIdentifier.Index
+	for _, hash := range block.TransactionHashes() {
+		w.mined[normalizeHash(hash)] = height
+	}
+	for hash, minedAt := range w.mined {
+		if minedAt+MINED_KEEP_BLOCKS < height {
+			delete(w.mined, hash)
+		}
+	}
+	if !w.tipKnown || height > w.tip {
+		w.tip, w.tipKnown = height, true
+	}
+}
+
+// Tip returns the highest block recorded, see SetTip
+func (w *Watcher) Tip() (uint64, bool) {
+	return w.tip, w.tipKnown
+}
+
+// SetTip makes the next poll record the blocks after height, e.g. those mined since the previous -once run
+func (w *Watcher) SetTip(height uint64) {
+	w.tip, w.tipKnown = height, true
+}
+
+// syncBlocks records the blocks mined after the tip, at most the last MINED_KEEP_BLOCKS; the first call only reads the tip
+func (w *Watcher) syncBlocks(ctx context.Context) error {
+	status, err := w.client.NetworkStatus(ctx)
+	if err != nil {
+		return err
+	}
+	top := status.CurrentBlockIdentifier.Index
+	if !w.tipKnown {
+		w.SetTip(top)
+		return nil
+	}
+	from := w.tip + 1
+	if top >= MINED_KEEP_BLOCKS && from < top-MINED_KEEP_BLOCKS+1 {
+		from = top - MINED_KEEP_BLOCKS + 1
+	}
+	for height := from; height <= top; height++ {
+		block, err := w.client.Block(ctx, height)
+		if err != nil {
+			return err
+		}
+		w.RecordBlock(block)
+	}
+	return nil
+}
+
+/*
+ * reported tells for each new transaction whether it touches the tag,
+ * fetching the details of at most DETAIL_CONCURRENCY at once
+ *
+ * A transaction whose details cannot be fetched is left out of the result,
+ * to be looked at again on the next poll; one that already left the mempool
+ * is not reported.
+ */
+func (w *Watcher) reported(ctx context.Context, hashes []string) map[string]bool {
+	result := make(map[string]bool, len(hashes))
+	if w.tag == nil {
+		for _, hash := range hashes {
+			result[hash] = true
+		}
+		return result
+	}
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	slots := make(chan struct{}, DETAIL_CONCURRENCY)
+	for _, hash := range hashes {
+		wg.Add(1)
+		slots <- struct{}{}
+		go func(hash string) {
+			defer wg.Done()
+			defer func() { <-slots }()
+			tx, err := w.client.MempoolTransaction(ctx, hash)
+			mu.Lock()
+			defer mu.Unlock()
+			switch {
+			case errors.Is(err, meshclient.ErrNotInMempool):
+				result[hash] = false
+			case err == nil:
+				result[hash] = tx.Transaction.Touches(w.tag)
+			}
+		}(hash)
+	}
+	wg.Wait()
+	return result
+}
+
+// stillPending reports whether a transaction missing from the /mempool list is still in the mempool; false with an error when unknown
+func (w *Watcher) stillPending(ctx context.Context, hash string) (bool, error) {
+	_, err := w.client.MempoolTransaction(ctx, hash)
+	if errors.Is(err, meshclient.ErrNotInMempool) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+/*
+ * Poll takes a snapshot of the mempool and returns the changes since the
+ * previous one
+ *
+ * The blocks are synced after the snapshot is taken, so a transaction
+ * mined before it is found in them.
+ * The first snapshot is the baseline: it reports nothing, unless present is
+ * set, in which case every transaction in it is an EventPresent. A
+ * transaction whose status could not be checked is carried over to the
+ * next poll rather than reported, so a failed request never shows as a
+ * departure, and the error returned wraps ErrIncomplete along with the
+ * events; any other error means no snapshot was taken.
+ */
+func (w *Watcher) Poll(ctx context.Context, present bool) ([]Event, error) {
+	mempool, err := w.client.Mempool(ctx)
+	if err != nil {
+		return nil, err
+	}
+	syncErr := w.syncBlocks(ctx)
+	now := time.Now().UTC()
+	current := make(map[string]bool, len(mempool.TransactionIdentifiers))
+	var added []string
+	for _, tx := range mempool.TransactionIdentifiers {
+		hash := normalizeHash(tx.Hash)
+		if _, known := w.pending[hash]; known && w.baseline {
+			current[hash] = w.pending[hash]
+			continue
+		}
+		added = append(added, hash)
+	}
+
+	var events []Event
+	if !w.baseline || (syncErr == nil && w.isReset(current)) {
+		if w.baseline {
+			events = append(events, Event{Time: now, Event: EventReset, Previous: len(w.pending), Current: len(mempool.TransactionIdentifiers)})
+		}
+		w.pending = w.reported(ctx, added)
+		w.baseline = true
+		if present {
+			for _, hash := range added {
+				if w.pending[hash] {
+					events = append(events, Event{Time: now, Event: EventPresent, TxID: hash})
+				}
+			}
+		}
+		return events, nil
+	}
+
+	// Departures first: the listing may be truncated, only a failed lookup proves a transaction gone,
+	// and only once the blocks are known it was not mined
+	lookupErr := syncErr
+	for hash, report := range w.pending {
+		if _, listed := current[hash]; listed {
+			continue
+		}
+		if height, ok := w.mined[hash]; ok {
+			if report {
+				events = append(events, Event{Time: now, Event: EventDeparted, TxID: hash, Outcome: OutcomeMined, Block: height})
+			}
+			continue
+		}
+		if syncErr != nil {
+			current[hash] = report
+			continue
+		}
+		pending, err := w.stillPending(ctx, hash)
+		if err != nil || pending {
+			lookupErr = errors.Join(lookupErr, err)
+			current[hash] = report
+			continue
+		}
+		if report {
+			events = append(events, Event{Time: now, Event: EventDeparted, TxID: hash, Outcome: OutcomeDropped})
+		}
+	}
+
+	for hash, report := range w.reported(ctx, added) {
+		current[hash] = report
+		if report {
+			events = append(events, Event{Time: now, Event: EventAppeared, TxID: hash})
+		}
+	}
+	w.pending = current
+	if lookupErr != nil {
+		return events, errors.Join(ErrIncomplete, lookupErr)
+	}
+	return events, nil
+}
+
+// isReset reports whether a snapshot shares nothing with a large previous one, none of which was mined
+func (w *Watcher) isReset(carried map[string]bool) bool {
+	if len(w.pending) < RESET_MIN_SIZE || len(carried) > 0 {
+		return false
+	}
+	for hash := range w.pending {
+		if _, ok := w.mined[hash]; ok {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,276 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/NickP005/Vindax-MCM-tools/internal/clitest"
+	"github.com/NickP005/Vindax-MCM-tools/pkg/cli"
+	"github.com/NickP005/Vindax-MCM-tools/pkg/meshclient"
+	"github.com/NickP005/Vindax-MCM-tools/pkg/meshmock"
+)
+
+// testTx is a mempool transaction with hash n repeated, paying the tag whose bytes are all to
+func testTx(n byte, to byte) meshclient.Transaction {
+	return meshclient.Transaction{
+		TransactionIdentifier: meshclient.TransactionIdentifier{Hash: fmt.Sprintf("0x%s", strings.Repeat(fmt.Sprintf("%02x", n), 32))},
+		Operations: []meshclient.Operation{{Type: "DESTINATION_TRANSFER",
+			Account: &meshclient.AccountIdentifier{Address: "0x" + strings.Repeat(fmt.Sprintf("%02x", to), 20)}}},
+	}
+}
+
+// hashOf is the hash of testTx(n) as events report it
+func hashOf(n byte) string {
+	return strings.Repeat(fmt.Sprintf("%02x", n), 32)
+}
+
+// poll polls and returns the events as "event txid outcome block" strings
+func poll(t *testing.T, w *Watcher, present bool) []string {
+	t.Helper()
+	events, err := w.Poll(context.Background(), present)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return describe(events)
+}
+
+func describe(events []Event) []string {
+	var lines []string
+	for _, e := range events {
+		line := e.Event + " " + e.TxID
+		if e.Outcome != "" {
+			line += fmt.Sprintf(" %s %d", e.Outcome, e.Block)
+		}
+		lines = append(lines, line)
+	}
+	return lines
+}
+
+// sameEvents compares events regardless of their order
+func sameEvents(got []string, want ...string) bool {
+	if len(got) != len(want) {
+		return false
+	}
+	left := strings.Join(got, "\n") + "\n"
+	for _, w := range want {
+		if !strings.Contains(left, w+"\n") {
+			return false
+		}
+		left = strings.Replace(left, w+"\n", "", 1)
+	}
+	return true
+}
+
+func newWatch(t *testing.T, tag []byte) (*meshmock.Server, *Watcher) {
+	mock := meshmock.New()
+	t.Cleanup(mock.Close)
+	return mock, NewWatcher(meshclient.NewMeshAPIClient(mock.URL(), nil), tag)
+}
+
+// TestWatcherDiff reports transactions appearing, then leaving the mempool mined or dropped
+func TestWatcherDiff(t *testing.T) {
+	mock, w := newWatch(t, nil)
+	mock.AddToMempool(testTx(1, 0xa1))
+	if got := poll(t, w, true); !sameEvents(got, "present "+hashOf(1)) {
+		t.Errorf("baseline: %q", got)
+	}
+
+	mock.AddToMempool(testTx(2, 0xa1))
+	mock.AddToMempool(testTx(3, 0xa1))
+	if got := poll(t, w, false); !sameEvents(got, "appeared "+hashOf(2), "appeared "+hashOf(3)) {
+		t.Errorf("appeared: %q", got)
+	}
+	if got := poll(t, w, false); len(got) != 0 {
+		t.Errorf("no change: %q", got)
+	}
+
+	mock.DropFromMempool(hashOf(3))
+	height := mock.MineBlock()
+	if got := poll(t, w, false); !sameEvents(got, fmt.Sprintf("departed %s mined %d", hashOf(1), height),
+		fmt.Sprintf("departed %s mined %d", hashOf(2), height), "departed "+hashOf(3)+" dropped 0") {
+		t.Errorf("departed: %q", got)
+	}
+}
+
+// TestWatcherTruncated keeps a transaction missing from a truncated listing while it is still in the mempool
+func TestWatcherTruncated(t *testing.T) {
+	mock, w := newWatch(t, nil)
+	for n := byte(1); n <= 3; n++ {
+		mock.AddToMempool(testTx(n, 0xa1))
+	}
+	poll(t, w, false)
+	mock.SetMempoolLimit(1)
+	if got := poll(t, w, false); len(got) != 0 {
+		t.Errorf("truncated listing: %q", got)
+	}
+	mock.DropFromMempool(hashOf(3))
+	if got := poll(t, w, false); !sameEvents(got, "departed "+hashOf(3)+" dropped 0") {
+		t.Errorf("dropped while truncated: %q", got)
+	}
+}
+
+// TestWatcherReset reports a large mempool replaced at once as one reset
+func TestWatcherReset(t *testing.T) {
+	mock, w := newWatch(t, nil)
+	for n := byte(1); n <= RESET_MIN_SIZE; n++ {
+		mock.AddToMempool(testTx(n, 0xa1))
+	}
+	poll(t, w, false)
+	for n := byte(1); n <= RESET_MIN_SIZE; n++ {
+		mock.DropFromMempool(hashOf(n))
+	}
+	mock.AddToMempool(testTx(100, 0xa1))
+	events, err := w.Poll(context.Background(), false)
+	if err != nil || len(events) != 1 || events[0].Event != EventReset || events[0].Previous != RESET_MIN_SIZE || events[0].Current != 1 {
+		t.Fatalf("reset: %+v, %v", events, err)
+	}
+	// The new mempool is the baseline
+	mock.AddToMempool(testTx(101, 0xa1))
+	if got := poll(t, w, false); !sameEvents(got, "appeared "+hashOf(101)) {
+		t.Errorf("after the reset: %q", got)
+	}
+}
+
+// TestWatcherAddress only reports the transactions touching the tag, and carries over those that could not be fetched
+func TestWatcherAddress(t *testing.T) {
+	mock, w := newWatch(t, bytes.Repeat([]byte{0xa1}, 20))
+	poll(t, w, false)
+	mock.AddToMempool(testTx(1, 0xa1))
+	mock.AddToMempool(testTx(2, 0xb0))
+	if got := poll(t, w, false); !sameEvents(got, "appeared "+hashOf(1)) {
+		t.Errorf("filtered: %q", got)
+	}
+
+	// A failed lookup is neither reported nor forgotten
+	mock.AddToMempool(testTx(3, 0xa1))
+	mock.Fail("/mempool/transaction", meshmock.Fault{Status: 500})
+	if got := poll(t, w, false); len(got) != 0 {
+		t.Errorf("failed lookup: %q", got)
+	}
+	if got := poll(t, w, false); !sameEvents(got, "appeared "+hashOf(3)) {
+		t.Errorf("after the failed lookup: %q", got)
+	}
+	mock.MineBlock()
+	if got := poll(t, w, false); !sameEvents(got, "departed "+hashOf(1)+" mined 1", "departed "+hashOf(3)+" mined 1") {
+		t.Errorf("mined: %q", got)
+	}
+}
+
+// TestWatcherDepartureUnknown carries over a transaction missing from the listing whose lookup failed
+func TestWatcherDepartureUnknown(t *testing.T) {
+	mock, w := newWatch(t, nil)
+	mock.AddToMempool(testTx(1, 0xa1))
+	poll(t, w, false)
+	mock.DropFromMempool(hashOf(1))
+	mock.Fail("/mempool/transaction", meshmock.Fault{Status: 500})
+	events, err := w.Poll(context.Background(), false)
+	if !errors.Is(err, ErrIncomplete) || len(events) != 0 {
+		t.Errorf("failed lookup: %+v, %v", events, err)
+	}
+	if got := poll(t, w, false); !sameEvents(got, "departed "+hashOf(1)+" dropped 0") {
+		t.Errorf("after the failed lookup: %q", got)
+	}
+}
+
+// TestRunOnce diffs -once runs through their state file, as cron runs them
+func TestRunOnce(t *testing.T) {
+	mock := meshmock.New()
+	defer mock.Close()
+	client := meshclient.NewMeshAPIClient(mock.URL(), nil)
+	stateFile := filepath.Join(t.TempDir(), "mempool.json")
+	run := func(address string) []Event {
+		var out bytes.Buffer
+		if code := runOnce(context.Background(), NewWatcher(client, nil), &EventWriter{Out: &out, JSON: true}, stateFile, address); code != cli.ExitOK {
+			t.Fatalf("exit code %d", code)
+		}
+		var events []Event
+		for decoder := json.NewDecoder(&out); decoder.More(); {
+			var event Event
+			if err := decoder.Decode(&event); err != nil {
+				t.Fatal(err)
+			}
+			events = append(events, event)
+		}
+		return events
+	}
+
+	mock.AddToMempool(testTx(1, 0xa1))
+	if got := describe(run("")); !sameEvents(got, "present "+hashOf(1)) {
+		t.Errorf("first run: %q", got)
+	}
+	mock.MineBlock()
+	mock.AddToMempool(testTx(2, 0xa1))
+	if got := describe(run("")); !sameEvents(got, "departed "+hashOf(1)+" mined 1", "appeared "+hashOf(2)) {
+		t.Errorf("second run: %q", got)
+	}
+	// A state of another address starts over
+	if got := describe(run("a1")); !sameEvents(got, "present "+hashOf(2)) {
+		t.Errorf("other address: %q", got)
+	}
+}
+
+func TestEventWriter(t *testing.T) {
+	at := time.Date(2026, 5, 1, 10, 0, 0, 0, time.UTC)
+	events := []Event{
+		{Time: at, Event: EventAppeared, TxID: "01"},
+		{Time: at, Event: EventDeparted, TxID: "01", Outcome: OutcomeMined, Block: 1234},
+		{Time: at, Event: EventDeparted, TxID: "02", Outcome: OutcomeDropped},
+		{Time: at, Event: EventReset, Previous: 40, Current: 2},
+	}
+	var out bytes.Buffer
+	if err := (&EventWriter{Out: &out}).Write(events); err != nil {
+		t.Fatal(err)
+	}
+	want := "2026-05-01T10:00:00Z appeared 01\n" +
+		"2026-05-01T10:00:00Z departed 01 mined in block 1234\n" +
+		"2026-05-01T10:00:00Z departed 02 dropped\n" +
+		"2026-05-01T10:00:00Z reset: mempool replaced (40 transactions before, 2 now)\n"
+	if out.String() != want {
+		t.Errorf("got\n%s", out.String())
+	}
+
+	out.Reset()
+	(&EventWriter{Out: &out, JSON: true}).Write(events[1:2])
+	if out.String() != `{"time":"2026-05-01T10:00:00Z","event":"departed","txId":"01","outcome":"mined","block":1234}`+"\n" {
+		t.Errorf("ndjson %s", out.String())
+	}
+}
+
+// TestRunWatch streams a transaction appearing, then mined by a new block
+func TestRunWatch(t *testing.T) {
+	mock := meshmock.New()
+	defer mock.Close()
+	client := meshclient.NewMeshAPIClient(mock.URL(), nil)
+	ctx, cancel := context.WithCancel(context.Background())
+	var out clitest.SyncBuffer
+	done := make(chan int)
+	go func() {
+		done <- runWatch(ctx, client, NewWatcher(client, nil), &EventWriter{Out: &out}, 20*time.Millisecond)
+	}()
+
+	waitFor := func(want string) {
+		t.Helper()
+		for deadline := time.Now().Add(5 * time.Second); time.Now().Before(deadline); time.Sleep(5 * time.Millisecond) {
+			if strings.Contains(out.String(), want) {
+				return
+			}
+		}
+		t.Fatalf("output %q never had %q", out.String(), want)
+	}
+	time.Sleep(50 * time.Millisecond)
+	mock.AddToMempool(testTx(1, 0xa1))
+	waitFor("appeared " + hashOf(1))
+	height := mock.MineBlock()
+	waitFor(fmt.Sprintf("departed %s mined in block %d", hashOf(1), height))
+	cancel()
+	if code := <-done; code != cli.ExitOK {
+		t.Errorf("exit code %d", code)
+	}
+}
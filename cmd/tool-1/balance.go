@@ -0,0 +1,81 @@
+package main
+
+import (
+	"context"
+	"encoding/hex"
+	"sync"
+
+	"github.com/NickP005/Vindax-MCM-tools/pkg/meshclient"
+)
+
+// BalanceUnavailable is reported when the balance lookup fails; the conversion itself still succeeds
+const BalanceUnavailable = "unavailable"
+
+// LookupBalance resolves the converted address via the Mesh API and records its balance in result
+func LookupBalance(ctx context.Context, client *meshclient.MeshAPIClient, result *ConversionResult) {
+	tag, err := hex.DecodeString(result.AddressHex)
+	if err != nil {
+		result.BalanceError = BalanceUnavailable + ": " + err.Error()
+		return
+	}
+	resolution, err := client.ResolveTag(ctx, tag)
+	if err != nil {
+		result.BalanceError = BalanceUnavailable + ": " + err.Error()
+		return
+	}
+	result.Balance = &resolution.Amount
+}
+
+/*
+ * BalanceWriter looks up the balance of every successfully converted address
+ * before passing results on to the wrapped writer.
+ *
+ * Results are buffered in groups of Concurrency, looked up in parallel and
+ * then written in input order, so at most Concurrency requests are in flight.
+ * Lookups made once Ctx is done report the balance as unavailable.
+ */
+type BalanceWriter struct {
+	Ctx         context.Context
+	Next        ResultWriter
+	Client      *meshclient.MeshAPIClient
+	Concurrency int
+	pending     []ConversionResult
+}
+
+func (w *BalanceWriter) Write(result ConversionResult) error {
+	w.pending = append(w.pending, result)
+	if len(w.pending) >= max(w.Concurrency, 1) {
+		return w.flush()
+	}
+	return nil
+}
+
+func (w *BalanceWriter) flush() error {
+	var wg sync.WaitGroup
+	for i := range w.pending {
+		if w.pending[i].Error != "" {
+			continue
+		}
+		wg.Add(1)
+		go func(result *ConversionResult) {
+			defer wg.Done()
+			LookupBalance(w.Ctx, w.Client, result)
+		}(&w.pending[i])
+	}
+	wg.Wait()
+
+	for _, result := range w.pending {
+		if err := w.Next.Write(result); err != nil {
+			return err
+		}
+	}
+	w.pending = w.pending[:0]
+	return nil
+}
+
+func (w *BalanceWriter) Close() error {
+	if err := w.flush(); err != nil {
+		return err
+	}
+	return w.Next.Close()
+}
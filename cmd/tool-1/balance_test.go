@@ -0,0 +1,93 @@
+package main
+
+import (
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/NickP005/Vindax-MCM-tools/pkg/meshclient"
+	"github.com/NickP005/Vindax-MCM-tools/pkg/meshmock"
+)
+
+// fundAddress gives the converted address of w a balance on the mock
+func fundAddress(t *testing.T, mock *meshmock.Server, w testWots, balance uint64) {
+	t.Helper()
+	tag, err := hex.DecodeString(w.address)
+	if err != nil {
+		t.Fatal(err)
+	}
+	mock.SetAccount(tag, "0x"+w.address+w.address, balance)
+}
+
+func TestLookupBalance(t *testing.T) {
+	mock := meshmock.New()
+	defer mock.Close()
+	funded := newTestWots("funded", DefaultTag)
+	fundAddress(t, mock, funded, 42_000)
+	client := meshclient.NewMeshAPIClient(mock.URL(), nil)
+
+	result, err := Convert(funded.hexFull(), ConvertOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	LookupBalance(context.Background(), client, &result)
+	if result.Balance == nil || *result.Balance != 42_000 || result.BalanceError != "" {
+		t.Errorf("funded: balance %v, error %q", result.Balance, result.BalanceError)
+	}
+
+	mock.Fail("/call", meshmock.Fault{Status: 500, Body: "boom"})
+	result.Balance = nil
+	LookupBalance(context.Background(), client, &result)
+	if result.Balance != nil || !strings.HasPrefix(result.BalanceError, BalanceUnavailable+": ") {
+		t.Errorf("failed lookup: balance %v, error %q", result.Balance, result.BalanceError)
+	}
+	if balanceSuffix(result) != " balance: "+BalanceUnavailable {
+		t.Errorf("failed lookup renders %q", balanceSuffix(result))
+	}
+}
+
+func TestCheckBalanceBatch(t *testing.T) {
+	mock := meshmock.New()
+	defer mock.Close()
+	var lines []string
+	var want []string
+	for i, label := range []string{"a", "b", "c", "d", "e"} {
+		w := newTestWots(label, DefaultTag)
+		fundAddress(t, mock, w, uint64(i+1)*1000)
+		lines = append(lines, w.hexFull())
+		want = append(want, w.address+" balance: "+[]string{"1000", "2000", "3000", "4000", "5000"}[i])
+	}
+	lines = append(lines[:2], append([]string{"zz"}, lines[2:]...)...)
+
+	r := runTool1(t, "", "-file", writeLines(t, lines), "-check-balance", "-api", mock.URL(), "-concurrency", "2")
+	if r.Code != 0 {
+		t.Fatalf("exited %d: %s", r.Code, r.Stderr)
+	}
+	if r.Stdout != strings.Join(want, "\n")+"\n" {
+		t.Errorf("stdout:\n%s\nwant, in input order:\n%s", r.Stdout, strings.Join(want, "\n"))
+	}
+}
+
+func TestCheckBalanceUnavailable(t *testing.T) {
+	w := newTestWots("a", DefaultTag)
+	mock := meshmock.New()
+	url := mock.URL()
+	mock.Close()
+
+	// An unreachable API does not fail the conversion
+	r := runTool1(t, "", "-wots", w.hexFull(), "-check-balance", "-api", url)
+	if r.Code != 0 || r.Stdout != w.address+" balance: "+BalanceUnavailable+"\n" {
+		t.Errorf("exited %d with %q: %s", r.Code, r.Stdout, r.Stderr)
+	}
+
+	r = runTool1(t, "", "-wots", w.hexFull(), "-check-balance", "-api", url, "-json")
+	var result ConversionResult
+	if err := json.Unmarshal([]byte(r.Stdout), &result); err != nil {
+		t.Fatal(err)
+	}
+	if r.Code != 0 || result.AddressHex != w.address || result.Balance != nil || result.BalanceError == "" {
+		t.Errorf("exited %d with %+v", r.Code, result)
+	}
+}
@@ -0,0 +1,168 @@
+package main
+
+import (
+	"context"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/NickP005/Vindax-MCM-tools/pkg/csvfile"
+)
+
+// Column names of the MCM 2.X wallet CSV export
+const (
+	CSVNameColumn = "name"
+	CSVWotsColumn = "wots_hex"
+)
+
+// CSVRejection is an input row that could not be converted
+type CSVRejection struct {
+	Line   int
+	Record []string
+	Reason string
+}
+
+/*
+ * CSVReport summarizes a CSV conversion
+ *
+ * Fields:
+ * - Converted: number of rows written to the output
+ * - Rejected: rows that failed, in input order
+ * - Header: the input header, nil if the input has none
+ * - Delimiter: the delimiter detected from the input, used for every output
+ */
+type CSVReport struct {
+	Converted int
+	Rejected  []CSVRejection
+	Header    []string
+	Delimiter rune
+}
+
+/*
+ * ConvertCSV converts a MCM 2.X wallet CSV export (name, wots_hex columns)
+ *
+ * The delimiter and an optional header line are detected as in every other
+ * CSV read by the tools (see pkg/csvfile). With a header the columns are
+ * located by name, without one the first column is the name and the second
+ * the WOTS address. Each converted row is written to out as name, addressHex,
+ * addressBase58 followed by the remaining columns verbatim.
+ *
+ * Rows that fail do not stop the conversion, they are collected in the
+ * report so the caller can write them to a rejected file. Once ctx is done
+ * no further row is read and the rows written so far are flushed.
+ */
+func ConvertCSV(ctx context.Context, in io.Reader, out io.Writer, opts ConvertOptions) (CSVReport, error) {
+	reader, err := csvfile.NewReader(in)
+	if err != nil {
+		return CSVReport{}, err
+	}
+	report := CSVReport{Delimiter: reader.Delimiter}
+	writer := csvfile.NewWriter(out, reader.Delimiter)
+
+	nameIdx, wotsIdx := 0, 1
+	first := true
+	var extra []int
+	for ctx.Err() == nil {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		var parseErr *csv.ParseError
+		if errors.As(err, &parseErr) {
+			report.Rejected = append(report.Rejected, CSVRejection{Line: parseErr.Line, Reason: parseErr.Err.Error()})
+			continue
+		}
+		if err != nil {
+			return report, err
+		}
+		line, _ := reader.FieldPos(0)
+
+		if first {
+			first = false
+			if csvfile.HasHeader(record, CSVNameColumn, CSVWotsColumn) {
+				report.Header = record
+				nameIdx = csvfile.ColumnIndex(record, CSVNameColumn)
+				wotsIdx = csvfile.ColumnIndex(record, CSVWotsColumn)
+				if wotsIdx < 0 {
+					return report, fmt.Errorf("header has no %s column", CSVWotsColumn)
+				}
+				extra = extraColumns(len(record), nameIdx, wotsIdx)
+				header := append([]string{CSVNameColumn, "addressHex", "addressBase58"}, pick(record, extra)...)
+				if err := writer.Write(header); err != nil {
+					return report, err
+				}
+				continue
+			}
+		}
+		if report.Header == nil {
+			extra = extraColumns(len(record), nameIdx, wotsIdx)
+		}
+
+		if wotsIdx >= len(record) {
+			report.Rejected = append(report.Rejected, CSVRejection{Line: line, Record: record, Reason: fmt.Sprintf("missing %s column", CSVWotsColumn)})
+			continue
+		}
+		result, err := Convert(record[wotsIdx], opts)
+		if err != nil {
+			report.Rejected = append(report.Rejected, CSVRejection{Line: line, Record: record, Reason: err.Error()})
+			continue
+		}
+
+		name := ""
+		if nameIdx >= 0 && nameIdx < len(record) {
+			name = record[nameIdx]
+		}
+		row := append([]string{name, result.AddressHex, result.AddressBase58}, pick(record, extra)...)
+		if err := writer.Write(row); err != nil {
+			return report, err
+		}
+		report.Converted++
+	}
+
+	writer.Flush()
+	return report, writer.Error()
+}
+
+/*
+ * WriteRejected writes the rejected rows of a report as CSV: the original
+ * fields followed by an error column holding the line number and the reason
+ */
+func WriteRejected(w io.Writer, report CSVReport) error {
+	writer := csvfile.NewWriter(w, report.Delimiter)
+	if report.Header != nil {
+		if err := writer.Write(append(append([]string{}, report.Header...), "error")); err != nil {
+			return err
+		}
+	}
+	for _, rejection := range report.Rejected {
+		row := append(append([]string{}, rejection.Record...), fmt.Sprintf("line %d: %s", rejection.Line, rejection.Reason))
+		if err := writer.Write(row); err != nil {
+			return err
+		}
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+// extraColumns lists the column indexes of a row other than the name and WOTS columns
+func extraColumns(count int, nameIdx int, wotsIdx int) []int {
+	extra := []int{}
+	for i := 0; i < count; i++ {
+		if i != nameIdx && i != wotsIdx {
+			extra = append(extra, i)
+		}
+	}
+	return extra
+}
+
+// pick returns the fields of record at the given indexes, skipping those past its end
+func pick(record []string, indexes []int) []string {
+	fields := []string{}
+	for _, i := range indexes {
+		if i < len(record) {
+			fields = append(fields, record[i])
+		}
+	}
+	return fields
+}
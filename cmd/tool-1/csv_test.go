@@ -0,0 +1,96 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestConvertCSVWithHeader(t *testing.T) {
+	a, b := newTestWots("a", DefaultTag), newTestWots("b", DefaultTag)
+	input := "note;wots_hex;name;balance\n" +
+		"first;" + a.hexFull() + ";alice;10\n" +
+		"broken;zz;carol;0\n" +
+		"second;" + b.hexFull() + ";bob;20\n" +
+		"short\n"
+	resultA, _ := Convert(a.hexFull(), ConvertOptions{})
+	resultB, _ := Convert(b.hexFull(), ConvertOptions{})
+
+	var out bytes.Buffer
+	report, err := ConvertCSV(context.Background(), strings.NewReader(input), &out, ConvertOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Extra columns are kept verbatim, in their input order, with the input delimiter
+	want := "name;addressHex;addressBase58;note;balance\n" +
+		"alice;" + a.address + ";" + resultA.AddressBase58 + ";first;10\n" +
+		"bob;" + b.address + ";" + resultB.AddressBase58 + ";second;20\n"
+	if out.String() != want {
+		t.Errorf("output:\n%s\nwant:\n%s", out.String(), want)
+	}
+	if report.Converted != 2 || len(report.Rejected) != 2 {
+		t.Fatalf("report %+v", report)
+	}
+	if report.Rejected[0].Line != 3 || !strings.Contains(report.Rejected[0].Reason, "invalid hex character") {
+		t.Errorf("first rejection %+v", report.Rejected[0])
+	}
+	if report.Rejected[1].Line != 5 || report.Rejected[1].Reason != "missing wots_hex column" {
+		t.Errorf("second rejection %+v", report.Rejected[1])
+	}
+
+	var rejected bytes.Buffer
+	if err := WriteRejected(&rejected, report); err != nil {
+		t.Fatal(err)
+	}
+	if want := "note;wots_hex;name;balance;error\nbroken;zz;carol;0;line 3: invalid hex character 'z' at offset 0\nshort;line 5: missing wots_hex column\n"; rejected.String() != want {
+		t.Errorf("rejected:\n%s\nwant:\n%s", rejected.String(), want)
+	}
+}
+
+func TestConvertCSVWithoutHeader(t *testing.T) {
+	a := newTestWots("a", DefaultTag)
+	var out bytes.Buffer
+	report, err := ConvertCSV(context.Background(), strings.NewReader("alice,"+a.hexFull()+",x\n"), &out, ConvertOptions{})
+	if err != nil || report.Converted != 1 || report.Header != nil {
+		t.Fatalf("report %+v, %v", report, err)
+	}
+	if !strings.HasPrefix(out.String(), "alice,"+a.address+",") || !strings.HasSuffix(out.String(), ",x\n") {
+		t.Errorf("output %q", out.String())
+	}
+}
+
+func TestConvertCSVHeaderWithoutWotsColumn(t *testing.T) {
+	_, err := ConvertCSV(context.Background(), strings.NewReader("name,address\nalice,ab\n"), &bytes.Buffer{}, ConvertOptions{})
+	if err == nil || err.Error() != "header has no wots_hex column" {
+		t.Errorf("got %v", err)
+	}
+}
+
+func TestCSVFlags(t *testing.T) {
+	a := newTestWots("a", DefaultTag)
+	dir := t.TempDir()
+	in, out := filepath.Join(dir, "export.csv"), filepath.Join(dir, "converted.csv")
+	if err := os.WriteFile(in, []byte("name,wots_hex\nalice,"+a.hexFull()+"\nbob,abc\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	r := runTool1(t, "", "-csv-in", in, "-csv-out", out)
+	if r.Code != 0 || !strings.Contains(r.Stderr, "Converted 1 rows, 1 rejected (see "+out+".rejected)") {
+		t.Fatalf("exited %d: %s", r.Code, r.Stderr)
+	}
+	converted, _ := os.ReadFile(out)
+	if !strings.Contains(string(converted), "alice,"+a.address+",") {
+		t.Errorf("converted %q", converted)
+	}
+	rejected, _ := os.ReadFile(out + ".rejected")
+	if string(rejected) != "name,wots_hex,error\nbob,abc,line 3: hex string has odd length 3\n" {
+		t.Errorf("rejected %q", rejected)
+	}
+
+	if r := runTool1(t, "", "-csv-in", in); r.Code != 1 || !strings.Contains(r.Stdout, "-csv-out is required") {
+		t.Errorf("without -csv-out exited %d with %q", r.Code, r.Stdout)
+	}
+}
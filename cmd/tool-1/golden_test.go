@@ -0,0 +1,38 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/NickP005/Vindax-MCM-tools/internal/clitest"
+)
+
+func TestGolden(t *testing.T) {
+	a, b := newTestWots("a", DefaultTag), newTestWots("b", DefaultTag)
+	tagged := newTestWots("tagged", []byte{0xde, 0xad, 0xbe, 0xef, 1, 2, 3, 4, 5, 6, 7, 8})
+	file := writeLines(t, []string{"# exported addresses", a.hexFull(), "not hex", "", b.hexKey(), a.hexFull()[:100]})
+	for _, tc := range []struct {
+		name  string
+		stdin string
+		args  []string
+	}{
+		{"hex", "", []string{"-wots", a.hexFull()}},
+		{"base58", "", []string{"-wots", a.hexFull(), "-base58"}},
+		{"all", "", []string{"-wots", a.hexFull(), "-all"}},
+		{"json", "", []string{"-wots", a.hexFull(), "-json"}},
+		{"public-key", "", []string{"-wots", b.hexKey(), "-input-format", "pk"}},
+		{"tagged", "", []string{"-wots", tagged.hexFull(), "-all"}},
+		{"require-untagged", "", []string{"-wots", tagged.hexFull(), "-require-untagged"}},
+		{"invalid", "", []string{"-wots", "0xzz"}},
+		{"file", "", []string{"-file", file, "-all"}},
+		{"file-json", "", []string{"-file", file, "-json"}},
+		{"stdin", a.hexFull() + "\r\n" + b.hexFull() + "\n", []string{"-base58"}},
+		{"print-config", "", []string{"-print-config"}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			r := runTool1(t, tc.stdin, tc.args...)
+			r.Stderr = strings.ReplaceAll(r.Stderr, file, "addresses.txt")
+			clitest.CheckGolden(t, tc.name, r.Golden())
+		})
+	}
+}
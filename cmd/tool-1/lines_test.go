@@ -0,0 +1,142 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/NickP005/Vindax-MCM-tools/pkg/mcmaddr"
+)
+
+// collectWriter keeps every result written, and signals each one on written if set
+type collectWriter struct {
+	results []ConversionResult
+	written chan ConversionResult
+	closed  bool
+}
+
+func (w *collectWriter) Write(result ConversionResult) error {
+	w.results = append(w.results, result)
+	if w.written != nil {
+		w.written <- result
+	}
+	return nil
+}
+
+func (w *collectWriter) Close() error {
+	w.closed = true
+	return nil
+}
+
+func TestConvertLinesSkipsBlankAndComments(t *testing.T) {
+	a, b := newTestWots("a", DefaultTag), newTestWots("b", DefaultTag)
+	input := strings.Join([]string{
+		"# exported from the 2.x wallet",
+		"",
+		"  " + a.hexFull() + "  ",
+		"\t",
+		"#" + b.hexFull(),
+		"0x" + strings.ToUpper(b.hexFull()),
+		"",
+	}, "\n")
+
+	w := &collectWriter{}
+	converted, failed, err := ConvertLines(context.Background(), strings.NewReader(input), w, ConvertOptions{})
+	if err != nil || converted != 2 || failed != 0 {
+		t.Fatalf("got %d converted, %d failed, %v", converted, failed, err)
+	}
+	if !w.closed {
+		t.Error("writer not closed")
+	}
+	if len(w.results) != 2 || w.results[0].AddressHex != a.address || w.results[1].AddressHex != b.address {
+		t.Fatalf("results %+v", w.results)
+	}
+	if w.results[0].Line != 3 || w.results[1].Line != 6 {
+		t.Errorf("line numbers %d and %d, want 3 and 6 (comments and blank lines count)", w.results[0].Line, w.results[1].Line)
+	}
+}
+
+func TestConvertLinesCRLF(t *testing.T) {
+	a := newTestWots("a", DefaultTag)
+	w := &collectWriter{}
+	converted, failed, err := ConvertLines(context.Background(), strings.NewReader(a.hexFull()+"\r\n"+a.hexKey()+"\r\n"), w, ConvertOptions{})
+	if err != nil || converted != 2 || failed != 0 {
+		t.Fatalf("got %d converted, %d failed, %v: %+v", converted, failed, err, w.results)
+	}
+}
+
+func TestConvertLinesBase58(t *testing.T) {
+	a, b := newTestWots("a", DefaultTag), newTestWots("b", DefaultTag)
+	var out, errOut bytes.Buffer
+	w := &PlainWriter{Out: &out, ErrOut: &errOut, AsBase58: true}
+	if _, _, err := ConvertLines(context.Background(), strings.NewReader(a.hexFull()+"\nbad\n"+b.hexFull()+"\n"), w, ConvertOptions{}); err != nil {
+		t.Fatal(err)
+	}
+
+	var want []string
+	for _, w := range []testWots{a, b} {
+		tag, err := mcmaddr.Normalize(w.address)
+		if err != nil {
+			t.Fatal(err)
+		}
+		want = append(want, mcmaddr.To58(tag))
+	}
+	if out.String() != strings.Join(want, "\n")+"\n" {
+		t.Errorf("base58 output:\n%s\nwant:\n%s", out.String(), strings.Join(want, "\n"))
+	}
+	if !strings.Contains(errOut.String(), "line 2:") {
+		t.Errorf("error of line 2 not reported: %q", errOut.String())
+	}
+}
+
+func TestConvertLinesStreams(t *testing.T) {
+	a := newTestWots("a", DefaultTag)
+	reader, writer := io.Pipe()
+	w := &collectWriter{written: make(chan ConversionResult, 1)}
+	done := make(chan error, 1)
+	go func() {
+		_, _, err := ConvertLines(context.Background(), reader, w, ConvertOptions{})
+		done <- err
+	}()
+
+	// Each line is converted as soon as it is read, while the input is still open
+	for i := 0; i < 3; i++ {
+		if _, err := io.WriteString(writer, a.hexFull()+"\n"); err != nil {
+			t.Fatal(err)
+		}
+		select {
+		case result := <-w.written:
+			if result.AddressHex != a.address {
+				t.Errorf("line %d: %+v", i+1, result)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("line %d not converted before the input ended", i+1)
+		}
+	}
+	writer.Close()
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestConvertLinesStopsWhenCanceled(t *testing.T) {
+	a := newTestWots("a", DefaultTag)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	w := &collectWriter{}
+	converted, _, err := ConvertLines(ctx, strings.NewReader(a.hexFull()+"\n"), w, ConvertOptions{})
+	if err != nil || converted != 0 || !w.closed {
+		t.Errorf("canceled run converted %d (err %v, closed %v), want none and the writer closed", converted, err, w.closed)
+	}
+}
+
+func TestStdinPipe(t *testing.T) {
+	a, b := newTestWots("a", DefaultTag), newTestWots("b", DefaultTag)
+	r := runTool1(t, "# header\n"+a.hexFull()+"\n\n"+b.hexFull()+"\n")
+	if r.Code != 0 || r.Stdout != a.address+"\n"+b.address+"\n" {
+		t.Errorf("piped stdin: exited %d with %q (stderr %q)", r.Code, r.Stdout, r.Stderr)
+	}
+}
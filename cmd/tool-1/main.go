@@ -0,0 +1,324 @@
+package main
+
+/*
+ * MCM 2.X to MCM 3.0 Address Converter Tool
+ *
+ * This tool converts MCM 2.X WOTS addresses to MCM 3.0 format using the go_mcminterface library.
+ *
+ * Command line flags:
+ * -wots string: WOTS address in hex format (4416 characters)
+ *               Can be tagged or untagged MCM 2.X address, or the bare
+ *               2144 bytes WOTS public key (4288 characters)
+ * -input-format: auto (default, detect from length), pk or full
+ * -require-untagged: Fail on MCM 2.X addresses carrying a custom tag
+ *                    (the tag is otherwise reported alongside the converted address)
+ * -check-balance: Resolve the converted address via the Mesh API (-api) and print its balance
+ *                 An unreachable API prints "balance: unavailable" without failing the conversion
+ * -print-config: Print the effective shared configuration (-api, -failover, -network) and exit
+ * -all: Print both the hex and the base58 address, labeled on separate lines
+ *       (two aligned columns in batch mode)
+ * -json: Output {"wotsSha256", "addressHex", "addressBase58"} (an array in batch mode)
+ * -file string: File with one WOTS address in hex format per line
+ *               Each line is converted independently, errors are reported per line
+ *
+ * -csv-in string: MCM 2.X wallet CSV export (name, wots_hex columns) to convert
+ * -csv-out string: CSV written with name, addressHex, addressBase58 and any extra
+ *                  columns; rows that fail go to <csv-out>.rejected with the reason
+ *
+ * When neither -wots nor -file is given, addresses are read line by line from
+ * stdin and converted as they are read. Blank lines and # comments are skipped.
+ *
+ * Ctrl-C (or SIGTERM) during a batch or CSV conversion finishes the current
+ * line, closes the output so it stays well-formed (the JSON array included),
+ * writes the rejected file, reports how many lines were done and exits 130.
+ *
+ * Dependencies:
+ * - github.com/NickP005/go_mcminterface: Provides MCM address conversion functionality
+ * - pkg/mcmaddr: base58 address encoding with CRC16 checksum
+ * - pkg/meshclient: Mesh API client used by -check-balance
+ *
+ * Output:
+ * - MCM 3.0 address in hex format, padded to 2x20 bytes
+ *
+ * Example usage:
+ * ./tool-1 -wots <4416_char_hex_string>
+ * ./tool-1 -file addresses.txt -base58
+ * ./tool-1 -wots <4416_char_hex_string> -all
+ * cat addresses.txt | ./tool-1 -base58
+ * ./tool-1 -csv-in export.csv -csv-out converted.csv
+ */
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/NickP005/Vindax-MCM-tools/pkg/config"
+	"github.com/NickP005/Vindax-MCM-tools/pkg/meshclient"
+	"github.com/NickP005/Vindax-MCM-tools/pkg/shutdown"
+
+	"github.com/NickP005/go_mcminterface"
+)
+
+/*
+ * ConvertWotsAddress converts a MCM 2.X WOTS address to its MCM 3.0 address
+ *
+ * Parameters:
+ * - wotsHex: WOTS public key (4288 characters) or full address (4416
+ *            characters) as hex string, optional 0x prefix
+ *
+ * Returns:
+ * - []byte: 20 bytes MCM 3.0 address
+ * - error: if the input is not valid hex of an accepted length
+ */
+func ConvertWotsAddress(wotsHex string) ([]byte, error) {
+	wots, _, err := DecodeWotsInput(wotsHex, FormatAuto)
+	if err != nil {
+		return nil, err
+	}
+	return AddressFromWots(wots), nil
+}
+
+// AddressFromWots derives the 20 bytes MCM 3.0 address from a decoded WOTS input, using only its first 2144 bytes (the public key)
+func AddressFromWots(wots []byte) []byte {
+	mcmAddr := go_mcminterface.WotsAddressFromBytes(wots[:WotsPublicKeyLength])
+	return mcmAddr.GetAddress()
+}
+
+/*
+ * ConvertLines converts every line of r, passing one result per line to w
+ * in input order as soon as it is read. Blank lines and lines starting with #
+ * are skipped. Lines that fail produce a result carrying the error and the
+ * line number, and do not stop the conversion.
+ *
+ * Once ctx is done no further line is read, but w is still closed so the
+ * output written so far stays well-formed.
+ *
+ * Returns the number of converted and failed lines.
+ */
+func ConvertLines(ctx context.Context, r io.Reader, w ResultWriter, opts ConvertOptions) (int, int, error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 8192), 1024*1024)
+
+	converted, failed := 0, 0
+	lineNum := 0
+	for ctx.Err() == nil && scanner.Scan() {
+		lineNum++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		result, err := Convert(line, opts)
+		if err != nil {
+			result = ConversionResult{Error: err.Error()}
+			failed++
+		} else {
+			converted++
+		}
+		result.Line = lineNum
+		if err := w.Write(result); err != nil {
+			return converted, failed, err
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return converted, failed, err
+	}
+	return converted, failed, w.Close()
+}
+
+// runCSV implements the -csv-in mode and returns the process exit code
+func runCSV(ctx context.Context, inPath string, outPath string, opts ConvertOptions) int {
+	in, err := os.Open(inPath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error opening CSV: %v\n", err)
+		return 1
+	}
+	defer in.Close()
+
+	out, err := os.Create(outPath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error creating output CSV: %v\n", err)
+		return 1
+	}
+	defer out.Close()
+
+	report, err := ConvertCSV(ctx, in, out, opts)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error converting CSV: %v\n", err)
+		return 1
+	}
+
+	if len(report.Rejected) > 0 {
+		rejectedPath := outPath + ".rejected"
+		rejected, err := os.Create(rejectedPath)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error creating rejected file: %v\n", err)
+			return 1
+		}
+		defer rejected.Close()
+		if err := WriteRejected(rejected, report); err != nil {
+			fmt.Fprintf(os.Stderr, "Error writing rejected file: %v\n", err)
+			return 1
+		}
+		fmt.Fprintf(os.Stderr, "Converted %d rows, %d rejected (see %s)\n", report.Converted, len(report.Rejected), rejectedPath)
+		if ctx.Err() != nil {
+			return shutdown.ExitInterrupted
+		}
+		if report.Converted == 0 {
+			return 1
+		}
+		return 0
+	}
+	fmt.Fprintf(os.Stderr, "Converted %d rows\n", report.Converted)
+	if ctx.Err() != nil {
+		return shutdown.ExitInterrupted
+	}
+	return 0
+}
+
+// isTerminal reports whether f is an interactive terminal rather than a pipe or file
+func isTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	if err != nil {
+		return false
+	}
+	return info.Mode()&os.ModeCharDevice != 0
+}
+
+func main() {
+	cfg, err := config.Load(config.Defaults())
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	wotsAddr := flag.String("wots", "", "WOTS address as hex string (4416 characters)")
+	inputFile := flag.String("file", "", "File with one WOTS address as hex string per line")
+	base58Flag := flag.Bool("base58", false, "Output address in base58 format")
+	allFlag := flag.Bool("all", false, "Output both the hex and the base58 address")
+	inputFormat := flag.String("input-format", "auto", "Input format: auto (detect from length), pk (2144 bytes public key) or full (2208 bytes address)")
+	requireUntagged := flag.Bool("require-untagged", false, "Fail on MCM 2.X addresses carrying a custom (non-default) tag")
+	checkBalance := flag.Bool("check-balance", false, "Look up the balance of the converted address via the Mesh API")
+	cfg.APIFlags(flag.CommandLine, "Mesh API URL used by -check-balance")
+	concurrency := flag.Int("concurrency", 8, "Maximum concurrent balance lookups in batch mode")
+	csvIn := flag.String("csv-in", "", "MCM 2.X wallet CSV export (name, wots_hex) to convert")
+	csvOut := flag.String("csv-out", "", "Output CSV for -csv-in; failed rows are written to <csv-out>.rejected")
+	jsonFlag := flag.Bool("json", false, "Output JSON with the hex and base58 address and the input fingerprint (an array in batch mode)")
+	printConfig := cfg.PrintFlag(flag.CommandLine)
+	flag.Parse()
+	if err := cfg.Parsed(flag.CommandLine); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	if *printConfig {
+		cfg.Print(os.Stdout)
+		return
+	}
+
+	format, err := ParseInputFormat(*inputFormat)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+	opts := ConvertOptions{RequireUntagged: *requireUntagged, InputFormat: format}
+	client := meshclient.NewMeshAPIClient(cfg.API, nil)
+	cfg.Apply(client)
+	client.SetUserAgent("tool-1")
+
+	sig := shutdown.Notify()
+	defer sig.Stop()
+	ctx := sig.Context()
+
+	if *csvIn != "" {
+		if *csvOut == "" {
+			fmt.Println("Error: -csv-out is required with -csv-in")
+			os.Exit(1)
+		}
+		sig.Exit(runCSV(ctx, *csvIn, *csvOut, opts))
+	}
+
+	// Batch mode, from -file or from a piped stdin
+	if *inputFile != "" || (*wotsAddr == "" && !isTerminal(os.Stdin)) {
+		input := io.Reader(os.Stdin)
+		if *inputFile != "" {
+			file, err := os.Open(*inputFile)
+			if err != nil {
+				fmt.Printf("Error opening file: %v\n", err)
+				os.Exit(1)
+			}
+			defer file.Close()
+			input = file
+		}
+
+		var writer ResultWriter = &PlainWriter{Out: os.Stdout, ErrOut: os.Stderr, AsBase58: *base58Flag, All: *allFlag}
+		if *jsonFlag {
+			writer = &JSONArrayWriter{Out: os.Stdout}
+		}
+		if *checkBalance {
+			writer = &BalanceWriter{Ctx: ctx, Next: writer, Client: client, Concurrency: *concurrency}
+		}
+
+		converted, failed, err := ConvertLines(ctx, input, writer, opts)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error reading input: %v\n", err)
+			os.Exit(1)
+		}
+		if sig.Interrupted() {
+			fmt.Fprintf(os.Stderr, "Interrupted: converted %d addresses, %d failed\n", converted, failed)
+			sig.Exit(shutdown.ExitInterrupted)
+		}
+		if failed > 0 {
+			fmt.Fprintf(os.Stderr, "Converted %d addresses, %d failed\n", converted, failed)
+		}
+		// Fail only if nothing could be converted
+		if converted == 0 && failed > 0 {
+			os.Exit(1)
+		}
+		return
+	}
+
+	if *wotsAddr == "" {
+		fmt.Println("Error: WOTS address is required")
+		flag.Usage()
+		os.Exit(1)
+	}
+
+	result, err := Convert(*wotsAddr, opts)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+	if *checkBalance {
+		LookupBalance(ctx, client, &result)
+	}
+
+	if *jsonFlag {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(result); err != nil {
+			fmt.Fprintf(os.Stderr, "Error encoding JSON: %v\n", err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	if result.LegacyTag != "" {
+		fmt.Fprintf(os.Stderr, "Legacy tag: %s\n", result.LegacyTag)
+	}
+	if *allFlag {
+		fmt.Printf("hex:    %s\n", result.AddressHex)
+		fmt.Printf("base58: %s\n", result.AddressBase58)
+		if suffix := balanceSuffix(result); suffix != "" {
+			fmt.Println(strings.TrimSpace(suffix))
+		}
+	} else if *base58Flag {
+		fmt.Println(result.AddressBase58 + balanceSuffix(result))
+	} else {
+		fmt.Println(result.AddressHex + balanceSuffix(result))
+	}
+}
@@ -0,0 +1,173 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/NickP005/Vindax-MCM-tools/internal/clitest"
+	"github.com/NickP005/Vindax-MCM-tools/pkg/wotsp"
+)
+
+// tool1 is the binary built by TestMain, run by the tests driving the command line
+var tool1 string
+
+func TestMain(m *testing.M) {
+	dir, err := os.MkdirTemp("", "tool-1-test")
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	tool1 = filepath.Join(dir, "tool-1")
+	if out, err := exec.Command("go", "build", "-o", tool1, ".").CombinedOutput(); err != nil {
+		fmt.Fprintf(os.Stderr, "failed to build tool-1: %v\n%s", err, out)
+		os.RemoveAll(dir)
+		os.Exit(1)
+	}
+	code := m.Run()
+	os.RemoveAll(dir)
+	os.Exit(code)
+}
+
+// testWots is the full 2208 bytes WOTS address of the key of label, and the MCM 3.0 address it converts to
+type testWots struct {
+	full    []byte
+	address string
+}
+
+// hexFull is the 4416 characters hex form of the full address
+func (w testWots) hexFull() string { return hex.EncodeToString(w.full) }
+
+// hexKey is the 4288 characters hex form of the bare public key
+func (w testWots) hexKey() string { return hex.EncodeToString(w.full[:WotsPublicKeyLength]) }
+
+// newTestWots derives the key of label, its full address ending with tag (DefaultTag for an untagged address)
+func newTestWots(label string, tag []byte) testWots {
+	keypair := wotsp.Keygen(sha256.Sum256([]byte("tool-1 test " + label)))
+	defer keypair.Wipe()
+	full := append(keypair.PublicKey[:], keypair.Components.PublicSeed[:]...)
+	full = append(full, keypair.Components.AddrSeed[:20]...)
+	full = append(full, tag...)
+	hash := keypair.AddrHash()
+	return testWots{full: full, address: hex.EncodeToString(hash[:])}
+}
+
+// runTool1 runs the binary with args and stdin, in an environment without the caller's MCM_* variables or config file
+func runTool1(t testing.TB, stdin string, args ...string) clitest.Result {
+	t.Helper()
+	return clitest.Run(t, tool1, stdin, nil, args...)
+}
+
+// writeLines writes lines to a file of a temporary directory and returns its path
+func writeLines(t testing.TB, lines []string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "addresses.txt")
+	if err := os.WriteFile(path, []byte(strings.Join(lines, "\n")+"\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestFileKeepsOrderAndReportsPerLine(t *testing.T) {
+	a, b, c := newTestWots("a", DefaultTag), newTestWots("b", DefaultTag), newTestWots("c", DefaultTag)
+	file := writeLines(t, []string{a.hexFull(), "not hex", b.hexFull(), a.hexFull()[:100], c.hexFull()})
+
+	r := runTool1(t, "", "-file", file)
+	if r.Code != 0 {
+		t.Fatalf("exited %d with some lines converted: %s", r.Code, r.Stderr)
+	}
+	if want := a.address + "\n" + b.address + "\n" + c.address + "\n"; r.Stdout != want {
+		t.Errorf("stdout:\n%s\nwant, in input order:\n%s", r.Stdout, want)
+	}
+	for _, want := range []string{"line 2: invalid hex character", "line 4: WOTS input must be", "Converted 3 addresses, 2 failed"} {
+		if !strings.Contains(r.Stderr, want) {
+			t.Errorf("stderr %q does not report %q", r.Stderr, want)
+		}
+	}
+}
+
+func TestFileFailsOnlyIfEveryLineFails(t *testing.T) {
+	r := runTool1(t, "", "-file", writeLines(t, []string{"zz", "abc"}))
+	if r.Code != 1 {
+		t.Errorf("every line failing exited %d, want 1", r.Code)
+	}
+	if r.Stdout != "" {
+		t.Errorf("printed addresses: %q", r.Stdout)
+	}
+
+	r = runTool1(t, "", "-file", filepath.Join(t.TempDir(), "missing.txt"))
+	if r.Code != 1 || !strings.Contains(r.Stdout, "Error opening file") {
+		t.Errorf("missing file exited %d with %q", r.Code, r.Stdout)
+	}
+}
+
+func TestFileJSON(t *testing.T) {
+	a, b := newTestWots("a", DefaultTag), newTestWots("b", DefaultTag)
+	r := runTool1(t, "", "-file", writeLines(t, []string{a.hexFull(), "0x12", b.hexFull()}), "-json")
+	if r.Code != 0 {
+		t.Fatalf("exited %d: %s", r.Code, r.Stderr)
+	}
+	var results []ConversionResult
+	if err := json.Unmarshal([]byte(r.Stdout), &results); err != nil {
+		t.Fatalf("output is not a JSON array: %v\n%s", err, r.Stdout)
+	}
+	if len(results) != 3 {
+		t.Fatalf("%d results, want one per line", len(results))
+	}
+	if results[0].AddressHex != a.address || results[0].Line != 1 {
+		t.Errorf("first result %+v", results[0])
+	}
+	if results[1].Error == "" || results[1].AddressHex != "" || results[1].Line != 2 {
+		t.Errorf("second result %+v, want an error entry", results[1])
+	}
+	if results[2].AddressHex != b.address || results[2].Line != 3 {
+		t.Errorf("third result %+v", results[2])
+	}
+	fingerprint := sha256.Sum256(a.full)
+	if results[0].WotsSha256 != hex.EncodeToString(fingerprint[:]) {
+		t.Errorf("fingerprint %s is not the sha256 of the input", results[0].WotsSha256)
+	}
+}
+
+// benchmarkLines is the number of addresses converted per iteration of the batch benchmarks
+const benchmarkLines = 50
+
+// benchmarkFile writes benchmarkLines distinct addresses to a file
+func benchmarkFile(b *testing.B) (string, []string) {
+	b.Helper()
+	lines := make([]string, benchmarkLines)
+	for i := range lines {
+		lines[i] = newTestWots(fmt.Sprintf("bench %d", i), DefaultTag).hexFull()
+	}
+	return writeLines(b, lines), lines
+}
+
+// BenchmarkBatchFile converts benchmarkLines addresses with one -file run
+func BenchmarkBatchFile(b *testing.B) {
+	file, _ := benchmarkFile(b)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if r := runTool1(b, "", "-file", file); r.Code != 0 {
+			b.Fatalf("exited %d: %s", r.Code, r.Stderr)
+		}
+	}
+}
+
+// BenchmarkPerProcess converts the same addresses with one -wots run each, as before -file
+func BenchmarkPerProcess(b *testing.B) {
+	_, lines := benchmarkFile(b)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		for _, line := range lines {
+			if r := runTool1(b, "", "-wots", line); r.Code != 0 {
+				b.Fatalf("exited %d: %s", r.Code, r.Stderr)
+			}
+		}
+	}
+}
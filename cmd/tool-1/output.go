@@ -0,0 +1,157 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"github.com/NickP005/Vindax-MCM-tools/pkg/mcmaddr"
+)
+
+/*
+ * ConversionResult is the outcome of converting one WOTS address
+ *
+ * Fields:
+ * - WotsSha256: sha256 fingerprint of the decoded WOTS input, to join results back to the input
+ * - AddressHex: 20 bytes MCM 3.0 address in hex
+ * - AddressBase58: MCM 3.0 address in base58 with checksum
+ * - LegacyTag: 12 bytes MCM 2.X tag in hex, only for tagged input addresses
+ * - Balance: balance in nanoMCM (with -check-balance)
+ * - BalanceError: why the balance is unavailable (with -check-balance)
+ * - Line: input line number (batch mode only)
+ * - Error: conversion error, set instead of the address fields
+ */
+type ConversionResult struct {
+	WotsSha256    string  `json:"wotsSha256,omitempty"`
+	AddressHex    string  `json:"addressHex,omitempty"`
+	AddressBase58 string  `json:"addressBase58,omitempty"`
+	LegacyTag     string  `json:"legacyTag,omitempty"`
+	Balance       *uint64 `json:"balance,omitempty"`
+	BalanceError  string  `json:"balanceError,omitempty"`
+	Line          int     `json:"line,omitempty"`
+	Error         string  `json:"error,omitempty"`
+}
+
+// ConvertOptions tunes how input addresses are accepted
+type ConvertOptions struct {
+	// RequireUntagged rejects MCM 2.X addresses carrying a custom tag
+	RequireUntagged bool
+	// InputFormat forces the input format instead of detecting it from the length
+	InputFormat InputFormat
+}
+
+// Convert converts a WOTS hex string into a ConversionResult with every representation filled in
+func Convert(input string, opts ConvertOptions) (ConversionResult, error) {
+	wots, format, err := DecodeWotsInput(input, opts.InputFormat)
+	if err != nil {
+		return ConversionResult{}, err
+	}
+	addr := AddressFromWots(wots)
+
+	// Only a full address carries the legacy tag
+	var tag []byte
+	tagged := false
+	if format == FormatFullAddress {
+		tag, tagged = LegacyTag(wots)
+	}
+	if tagged && opts.RequireUntagged {
+		return ConversionResult{}, fmt.Errorf("address carries the legacy tag %x but -require-untagged is set", tag)
+	}
+	base58Addr, err := mcmaddr.EncodeTag(addr)
+	if err != nil {
+		return ConversionResult{}, err
+	}
+
+	fingerprint := sha256.Sum256(wots)
+
+	result := ConversionResult{
+		WotsSha256:    hex.EncodeToString(fingerprint[:]),
+		AddressHex:    hex.EncodeToString(addr),
+		AddressBase58: base58Addr,
+	}
+	if tagged {
+		result.LegacyTag = hex.EncodeToString(tag)
+	}
+	return result, nil
+}
+
+// ResultWriter receives conversion results in input order
+type ResultWriter interface {
+	Write(result ConversionResult) error
+	Close() error
+}
+
+/*
+ * PlainWriter prints one address per line, errors and legacy tags go to ErrOut
+ *
+ * With All set each line holds the hex address and the base58 address as two
+ * columns; the hex address has a fixed width so the columns stay aligned.
+ */
+type PlainWriter struct {
+	Out      io.Writer
+	ErrOut   io.Writer
+	AsBase58 bool
+	All      bool
+}
+
+func (w *PlainWriter) Write(result ConversionResult) error {
+	if result.Error != "" {
+		_, err := fmt.Fprintf(w.ErrOut, "line %d: %s\n", result.Line, result.Error)
+		return err
+	}
+	if result.LegacyTag != "" {
+		fmt.Fprintf(w.ErrOut, "line %d: legacy tag %s\n", result.Line, result.LegacyTag)
+	}
+	address := result.AddressHex
+	if w.All {
+		address = result.AddressHex + " " + result.AddressBase58
+	} else if w.AsBase58 {
+		address = result.AddressBase58
+	}
+	_, err := fmt.Fprintln(w.Out, address+balanceSuffix(result))
+	return err
+}
+
+// balanceSuffix renders the balance column of a result, empty if no lookup was made
+func balanceSuffix(result ConversionResult) string {
+	if result.Balance != nil {
+		return fmt.Sprintf(" balance: %d", *result.Balance)
+	}
+	if result.BalanceError != "" {
+		return " balance: " + BalanceUnavailable
+	}
+	return ""
+}
+
+func (w *PlainWriter) Close() error { return nil }
+
+// JSONArrayWriter streams results as a JSON array, one element per input line
+type JSONArrayWriter struct {
+	Out   io.Writer
+	count int
+}
+
+func (w *JSONArrayWriter) Write(result ConversionResult) error {
+	data, err := json.Marshal(result)
+	if err != nil {
+		return err
+	}
+	sep := ",\n  "
+	if w.count == 0 {
+		sep = "[\n  "
+	}
+	w.count++
+	_, err = fmt.Fprintf(w.Out, "%s%s", sep, data)
+	return err
+}
+
+func (w *JSONArrayWriter) Close() error {
+	if w.count == 0 {
+		_, err := fmt.Fprintln(w.Out, "[]")
+		return err
+	}
+	_, err := fmt.Fprintln(w.Out, "\n]")
+	return err
+}
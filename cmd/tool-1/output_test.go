@@ -0,0 +1,78 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestAllSingle(t *testing.T) {
+	w := newTestWots("a", DefaultTag)
+	result, err := Convert(w.hexFull(), ConvertOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := runTool1(t, "", "-wots", w.hexFull(), "-all")
+	if want := "hex:    " + w.address + "\nbase58: " + result.AddressBase58 + "\n"; r.Code != 0 || r.Stdout != want {
+		t.Errorf("exited %d with %q, want %q", r.Code, r.Stdout, want)
+	}
+
+	// The single format flags are unchanged
+	if r := runTool1(t, "", "-wots", w.hexFull()); r.Stdout != w.address+"\n" {
+		t.Errorf("hex output %q", r.Stdout)
+	}
+	if r := runTool1(t, "", "-wots", w.hexFull(), "-base58"); r.Stdout != result.AddressBase58+"\n" {
+		t.Errorf("base58 output %q", r.Stdout)
+	}
+}
+
+func TestAllBatchColumnsAlign(t *testing.T) {
+	var input []string
+	for _, label := range []string{"a", "b", "c", "d"} {
+		input = append(input, newTestWots(label, DefaultTag).hexFull())
+	}
+	r := runTool1(t, strings.Join(input, "\n")+"\n", "-all")
+	if r.Code != 0 {
+		t.Fatalf("exited %d: %s", r.Code, r.Stderr)
+	}
+	lines := strings.Split(strings.TrimSuffix(r.Stdout, "\n"), "\n")
+	if len(lines) != len(input) {
+		t.Fatalf("%d lines, want %d", len(lines), len(input))
+	}
+	for i, line := range lines {
+		// The base58 column starts after the 40 hex characters and one space on every line
+		if strings.Index(line, " ") != 40 || strings.Count(line, " ") != 1 {
+			t.Errorf("line %d %q is not two aligned columns", i+1, line)
+		}
+	}
+}
+
+func TestPlainWriterReportsErrorsAndTags(t *testing.T) {
+	var out, errOut bytes.Buffer
+	w := &PlainWriter{Out: &out, ErrOut: &errOut, All: true}
+	w.Write(ConversionResult{Line: 1, AddressHex: "aa", AddressBase58: "bb", LegacyTag: "cc"})
+	w.Write(ConversionResult{Line: 2, Error: "bad"})
+	if out.String() != "aa bb\n" {
+		t.Errorf("out %q", out.String())
+	}
+	if errOut.String() != "line 1: legacy tag cc\nline 2: bad\n" {
+		t.Errorf("errOut %q", errOut.String())
+	}
+}
+
+func TestJSONArrayWriter(t *testing.T) {
+	var out bytes.Buffer
+	w := &JSONArrayWriter{Out: &out}
+	w.Close()
+	if out.String() != "[]\n" {
+		t.Errorf("empty array %q", out.String())
+	}
+	out.Reset()
+	w = &JSONArrayWriter{Out: &out}
+	w.Write(ConversionResult{Line: 1, AddressHex: "aa"})
+	w.Write(ConversionResult{Line: 2, Error: "bad"})
+	w.Close()
+	if want := "[\n  {\"addressHex\":\"aa\",\"line\":1},\n  {\"line\":2,\"error\":\"bad\"}\n]\n"; out.String() != want {
+		t.Errorf("array %q, want %q", out.String(), want)
+	}
+}
@@ -0,0 +1,50 @@
+//go:build !windows
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/NickP005/Vindax-MCM-tools/internal/clitest"
+	"github.com/NickP005/Vindax-MCM-tools/pkg/shutdown"
+)
+
+// endlessLines is an input repeating a line forever, which only a signal stops reading
+type endlessLines struct {
+	line string
+	off  int
+}
+
+func (r *endlessLines) Read(p []byte) (int, error) {
+	n := 0
+	for n < len(p) {
+		c := copy(p[n:], r.line[r.off:])
+		n += c
+		r.off = (r.off + c) % len(r.line)
+	}
+	return n, nil
+}
+
+// TestInterruptBatch stops the JSON conversion of an endless stdin: the array written so far is closed, and counted
+func TestInterruptBatch(t *testing.T) {
+	a := newTestWots("a", DefaultTag)
+	r := clitest.InterruptWhen(t, tool1, &endlessLines{line: a.hexFull() + "\n"}, func(stdout string) bool { return strings.Count(stdout, "\n  {") >= 10 }, "-json")
+	if r.Code != shutdown.ExitInterrupted {
+		t.Fatalf("exit %d, want %d: %s", r.Code, shutdown.ExitInterrupted, r.Stderr)
+	}
+	var results []ConversionResult
+	if err := json.Unmarshal([]byte(r.Stdout), &results); err != nil {
+		t.Fatalf("partial output is not a JSON array: %v\n%.200s", err, r.Stdout)
+	}
+	for i, result := range results {
+		if result.AddressHex != a.address || result.Line != i+1 {
+			t.Fatalf("result %d: %+v", i, result)
+		}
+	}
+	if want := fmt.Sprintf("Interrupted: converted %d addresses, 0 failed\n", len(results)); r.Stderr != want {
+		t.Errorf("stderr %q, want %q", r.Stderr, want)
+	}
+}
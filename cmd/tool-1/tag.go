@@ -0,0 +1,26 @@
+package main
+
+import (
+	"github.com/NickP005/Vindax-MCM-tools/pkg/secure"
+)
+
+// DefaultTag is the 12 bytes trailer of an untagged MCM 2.X WOTS address
+var DefaultTag = []byte{0x42, 0x00, 0x00, 0x00, 0x0e, 0x00, 0x00, 0x00, 0x01, 0x00, 0x00, 0x00}
+
+// LegacyTagLength is the length in bytes of a MCM 2.X tag
+const LegacyTagLength = 12
+
+/*
+ * LegacyTag extracts the MCM 2.X tag from the final 12 bytes of a full WOTS address
+ *
+ * Parameters:
+ * - wotsAddr: 2208 bytes WOTS address
+ *
+ * Returns:
+ * - []byte: the 12 bytes tag
+ * - bool: true if the address carries a custom tag, false for the default pattern
+ */
+func LegacyTag(wotsAddr []byte) ([]byte, bool) {
+	tag := wotsAddr[len(wotsAddr)-LegacyTagLength:]
+	return tag, !secure.Equal(tag, DefaultTag)
+}
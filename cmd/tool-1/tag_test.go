@@ -0,0 +1,88 @@
+package main
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+// customTag is the tag of the tagged fixture addresses
+var customTag = []byte{0xde, 0xad, 0xbe, 0xef, 0x00, 0x11, 0x22, 0x33, 0x44, 0x55, 0x66, 0x77}
+
+func TestLegacyTag(t *testing.T) {
+	for _, tc := range []struct {
+		name   string
+		tag    []byte
+		tagged bool
+	}{
+		{"default", DefaultTag, false},
+		{"custom", customTag, true},
+		{"zero", make([]byte, LegacyTagLength), true},
+		// One byte off the default pattern is a custom tag
+		{"default but last byte", append(append([]byte{}, DefaultTag[:11]...), 0x02), true},
+		{"default but first byte", append([]byte{0x43}, DefaultTag[1:]...), true},
+	} {
+		full := newTestWots("tag", tc.tag).full
+		tag, tagged := LegacyTag(full)
+		if tagged != tc.tagged || hex.EncodeToString(tag) != hex.EncodeToString(tc.tag) {
+			t.Errorf("%s: got %x tagged %v, want %x tagged %v", tc.name, tag, tagged, tc.tag, tc.tagged)
+		}
+	}
+}
+
+func TestConvertReportsLegacyTag(t *testing.T) {
+	untagged, tagged := newTestWots("a", DefaultTag), newTestWots("a", customTag)
+
+	result, err := Convert(untagged.hexFull(), ConvertOptions{})
+	if err != nil || result.LegacyTag != "" {
+		t.Errorf("untagged: legacy tag %q, %v", result.LegacyTag, err)
+	}
+	result, err = Convert(tagged.hexFull(), ConvertOptions{})
+	if err != nil || result.LegacyTag != hex.EncodeToString(customTag) {
+		t.Errorf("tagged: legacy tag %q, %v", result.LegacyTag, err)
+	}
+	// The tag is not part of the key: both convert to the same address
+	if result.AddressHex != untagged.address {
+		t.Errorf("tagged address converts to %s, want %s", result.AddressHex, untagged.address)
+	}
+	// A bare public key has no tag to report
+	if result, err = Convert(tagged.hexKey(), ConvertOptions{RequireUntagged: true}); err != nil || result.LegacyTag != "" {
+		t.Errorf("public key: legacy tag %q, %v", result.LegacyTag, err)
+	}
+
+	if _, err := Convert(tagged.hexFull(), ConvertOptions{RequireUntagged: true}); err == nil || !strings.Contains(err.Error(), "-require-untagged") {
+		t.Errorf("tagged with -require-untagged: %v", err)
+	}
+	if _, err := Convert(untagged.hexFull(), ConvertOptions{RequireUntagged: true}); err != nil {
+		t.Errorf("untagged with -require-untagged: %v", err)
+	}
+}
+
+func TestLegacyTagOutput(t *testing.T) {
+	tagged := newTestWots("a", customTag)
+
+	r := runTool1(t, "", "-wots", tagged.hexFull())
+	if r.Code != 0 || r.Stdout != tagged.address+"\n" {
+		t.Fatalf("exited %d with %q: %s", r.Code, r.Stdout, r.Stderr)
+	}
+	if r.Stderr != "Legacy tag: "+hex.EncodeToString(customTag)+"\n" {
+		t.Errorf("stderr %q, want the legacy tag", r.Stderr)
+	}
+
+	r = runTool1(t, "", "-wots", tagged.hexFull(), "-json")
+	var result ConversionResult
+	if err := json.Unmarshal([]byte(r.Stdout), &result); err != nil || result.LegacyTag != hex.EncodeToString(customTag) {
+		t.Errorf("JSON legacy tag %q, %v", result.LegacyTag, err)
+	}
+
+	r = runTool1(t, "", "-wots", tagged.hexFull(), "-require-untagged")
+	if r.Code != 1 || r.Stdout != "Error: address carries the legacy tag "+hex.EncodeToString(customTag)+" but -require-untagged is set\n" {
+		t.Errorf("-require-untagged exited %d with %q", r.Code, r.Stdout)
+	}
+
+	r = runTool1(t, "", "-wots", newTestWots("a", DefaultTag).hexFull())
+	if r.Code != 0 || r.Stderr != "" {
+		t.Errorf("untagged address exited %d with stderr %q", r.Code, r.Stderr)
+	}
+}
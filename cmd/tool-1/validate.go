@@ -0,0 +1,114 @@
+package main
+
+import (
+	"encoding/hex"
+	"fmt"
+	"strings"
+)
+
+const (
+	// WotsPublicKeyLength is the length in bytes of the WOTS public key itself
+	WotsPublicKeyLength = 2144
+	// WotsSeedsLength is the length in bytes of the public seed and address seed trailer
+	WotsSeedsLength = 64
+	// WotsAddressLength is the length in bytes of a full MCM 2.X WOTS address
+	WotsAddressLength = WotsPublicKeyLength + WotsSeedsLength
+)
+
+// InputFormat tells which portion of a WOTS address an input holds
+type InputFormat int
+
+const (
+	// FormatAuto detects the format from the input length
+	FormatAuto InputFormat = iota
+	// FormatPublicKey is the bare 2144 bytes WOTS public key (4288 hex characters)
+	FormatPublicKey
+	// FormatFullAddress is the 2208 bytes WOTS address: public key, public seed and address seed with tag (4416 hex characters)
+	FormatFullAddress
+)
+
+// ParseInputFormat parses the -input-format flag value
+func ParseInputFormat(s string) (InputFormat, error) {
+	switch strings.ToLower(s) {
+	case "auto", "":
+		return FormatAuto, nil
+	case "pk", "2144":
+		return FormatPublicKey, nil
+	case "full", "2208":
+		return FormatFullAddress, nil
+	}
+	return FormatAuto, fmt.Errorf("unknown input format %q (expected auto, pk or full)", s)
+}
+
+func (f InputFormat) byteLength() int {
+	if f == FormatPublicKey {
+		return WotsPublicKeyLength
+	}
+	return WotsAddressLength
+}
+
+/*
+ * NormalizeWotsHex validates a WOTS hex string and returns it in canonical form
+ *
+ * Surrounding whitespace and an optional 0x prefix are removed. The input is
+ * rejected if it contains a non-hex character (reporting the offset of the
+ * first one) or has an odd length.
+ */
+func NormalizeWotsHex(input string) (string, error) {
+	s := strings.TrimSpace(input)
+	if strings.HasPrefix(s, "0x") || strings.HasPrefix(s, "0X") {
+		s = s[2:]
+	}
+
+	for i := 0; i < len(s); i++ {
+		if !isHexChar(s[i]) {
+			return "", fmt.Errorf("invalid hex character %q at offset %d", s[i], i)
+		}
+	}
+	if len(s)%2 != 0 {
+		return "", fmt.Errorf("hex string has odd length %d", len(s))
+	}
+	return strings.ToLower(s), nil
+}
+
+/*
+ * DecodeWotsInput validates and decodes a WOTS public key or full address
+ *
+ * Parameters:
+ * - input: hex string, 4288 characters (2144 bytes public key) or 4416
+ *          characters (2208 bytes: public key + 64 bytes of public and address seeds)
+ * - format: expected format, FormatAuto to detect it from the length
+ *
+ * Returns the decoded bytes and the format they were read as.
+ */
+func DecodeWotsInput(input string, format InputFormat) ([]byte, InputFormat, error) {
+	s, err := NormalizeWotsHex(input)
+	if err != nil {
+		return nil, format, err
+	}
+
+	if format == FormatAuto {
+		switch len(s) / 2 {
+		case WotsPublicKeyLength:
+			format = FormatPublicKey
+		case WotsAddressLength:
+			format = FormatFullAddress
+		default:
+			return nil, format, fmt.Errorf("WOTS input must be %d characters (%d bytes WOTS public key) or %d characters (%d bytes: %d bytes WOTS public key + %d bytes of public and address seeds), got %d",
+				WotsPublicKeyLength*2, WotsPublicKeyLength, WotsAddressLength*2, WotsAddressLength, WotsPublicKeyLength, WotsSeedsLength, len(s))
+		}
+	} else if len(s)/2 != format.byteLength() {
+		return nil, format, fmt.Errorf("WOTS input must be %d characters (%d bytes) for the selected input format, got %d",
+			format.byteLength()*2, format.byteLength(), len(s))
+	}
+
+	decoded, err := hex.DecodeString(s)
+	if err != nil {
+		return nil, format, err
+	}
+	return decoded, format, nil
+}
+
+func isHexChar(c byte) bool {
+	return (c >= '0' && c <= '9') || (c >= 'a' && c <= 'f') || (c >= 'A' && c <= 'F')
+}
@@ -0,0 +1,134 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestNormalizeWotsHexMalformed(t *testing.T) {
+	for _, tc := range []struct {
+		name  string
+		input string
+		want  string
+	}{
+		{"non-hex character", "abcg12", `invalid hex character 'g' at offset 3`},
+		{"inner space", "ab cd", `invalid hex character ' ' at offset 2`},
+		{"inner newline", "abcd\nef", `invalid hex character '\n' at offset 4`},
+		// The offset counts from after the prefix
+		{"after prefix", "0xab_d", `invalid hex character '_' at offset 2`},
+		{"double prefix", "0x0xab", `invalid hex character 'x' at offset 1`},
+		{"odd length", "abc", "hex string has odd length 3"},
+		{"odd length after prefix", "0xabcde", "hex string has odd length 5"},
+		// A bad character is reported before the odd length
+		{"odd with bad character", "abz", `invalid hex character 'z' at offset 2`},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := NormalizeWotsHex(tc.input)
+			if err == nil || err.Error() != tc.want {
+				t.Errorf("got %v, want %q", err, tc.want)
+			}
+		})
+	}
+}
+
+func TestNormalizeWotsHexAccepted(t *testing.T) {
+	for _, tc := range []struct {
+		input string
+		want  string
+	}{
+		{"abcd", "abcd"},
+		{"ABCD", "abcd"},
+		{"0xAbCd", "abcd"},
+		{"0XABCD", "abcd"},
+		{"  abcd\n", "abcd"},
+		{"\t0xabcd\r\n", "abcd"},
+		{"", ""},
+	} {
+		got, err := NormalizeWotsHex(tc.input)
+		if err != nil || got != tc.want {
+			t.Errorf("%q: got %q, %v; want %q", tc.input, got, err, tc.want)
+		}
+	}
+}
+
+func TestDecodeWotsInputLength(t *testing.T) {
+	full := newTestWots("a", DefaultTag).hexFull()
+	for _, tc := range []struct {
+		name   string
+		input  string
+		format InputFormat
+		want   string
+	}{
+		{"empty", "", FormatAuto, "got 0"},
+		{"short", full[:100], FormatAuto, "4416 characters (2208 bytes: 2144 bytes WOTS public key + 64 bytes of public and address seeds), got 100"},
+		{"between lengths", full[:4350], FormatAuto, "got 4350"},
+		{"long", full + "00", FormatAuto, "got 4418"},
+		{"full as pk", full, FormatPublicKey, "must be 4288 characters (2144 bytes) for the selected input format, got 4416"},
+		{"pk as full", full[:4288], FormatFullAddress, "must be 4416 characters (2208 bytes) for the selected input format, got 4288"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			_, _, err := DecodeWotsInput(tc.input, tc.format)
+			if err == nil || !strings.Contains(err.Error(), tc.want) {
+				t.Errorf("got %v, want it to contain %q", err, tc.want)
+			}
+		})
+	}
+}
+
+func TestMalformedInputOutput(t *testing.T) {
+	full := newTestWots("a", DefaultTag).hexFull()
+	r := runTool1(t, "", "-wots", full[:10]+"x"+full[11:])
+	if r.Code != 1 || r.Stdout != "Error: invalid hex character 'x' at offset 10\n" {
+		t.Errorf("exited %d with %q", r.Code, r.Stdout)
+	}
+	// A trailing newline, as pasted from a file, is not an error
+	if r := runTool1(t, "", "-wots", full+"\n"); r.Code != 0 {
+		t.Errorf("trailing newline exited %d with %q", r.Code, r.Stdout)
+	}
+}
+
+func TestInputLengthsGiveTheSameAddress(t *testing.T) {
+	w := newTestWots("a", DefaultTag)
+	tagged := newTestWots("a", customTag)
+	for _, tc := range []struct {
+		name   string
+		input  string
+		format InputFormat
+	}{
+		{"pk auto", w.hexKey(), FormatAuto},
+		{"pk explicit", w.hexKey(), FormatPublicKey},
+		{"full auto", w.hexFull(), FormatAuto},
+		{"full explicit", w.hexFull(), FormatFullAddress},
+		{"tagged full", tagged.hexFull(), FormatAuto},
+		{"prefixed upper case pk", "0x" + strings.ToUpper(w.hexKey()), FormatAuto},
+		{"prefixed upper case full", "0X" + strings.ToUpper(w.hexFull()), FormatFullAddress},
+	} {
+		result, err := Convert(tc.input, ConvertOptions{InputFormat: tc.format})
+		if err != nil || result.AddressHex != w.address {
+			t.Errorf("%s: got %s, %v; want %s", tc.name, result.AddressHex, err, w.address)
+		}
+	}
+
+	// Detection from the length
+	if _, format, _ := DecodeWotsInput(w.hexKey(), FormatAuto); format != FormatPublicKey {
+		t.Errorf("4288 characters detected as %v", format)
+	}
+	if _, format, _ := DecodeWotsInput(w.hexFull(), FormatAuto); format != FormatFullAddress {
+		t.Errorf("4416 characters detected as %v", format)
+	}
+}
+
+func TestParseInputFormat(t *testing.T) {
+	for input, want := range map[string]InputFormat{"": FormatAuto, "auto": FormatAuto, "pk": FormatPublicKey, "2144": FormatPublicKey, "FULL": FormatFullAddress, "2208": FormatFullAddress} {
+		if got, err := ParseInputFormat(input); err != nil || got != want {
+			t.Errorf("%q: got %v, %v; want %v", input, got, err, want)
+		}
+	}
+	if _, err := ParseInputFormat("4416"); err == nil {
+		t.Error("no error for an unknown format")
+	}
+	r := runTool1(t, "", "-wots", newTestWots("a", DefaultTag).hexKey(), "-input-format", "bytes")
+	if r.Code != 1 || !strings.Contains(r.Stdout, `unknown input format "bytes"`) {
+		t.Errorf("exited %d with %q", r.Code, r.Stdout)
+	}
+}
@@ -0,0 +1,17 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/NickP005/Vindax-MCM-tools/internal/clitest"
+)
+
+// TestGolden pins every output format, for no account and for two; the binary draws random seeds so the writers are run here
+func TestGolden(t *testing.T) {
+	for _, format := range []string{"json", "ndjson", "csv"} {
+		t.Run(format, func(t *testing.T) {
+			clitest.CheckGolden(t, format+"-empty", writeAll(t, format, nil))
+			clitest.CheckGolden(t, format, writeAll(t, format, testAccounts(t, 2)))
+		})
+	}
+}
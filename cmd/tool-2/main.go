@@ -0,0 +1,186 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/NickP005/Vindax-MCM-tools/pkg/config"
+	"github.com/NickP005/Vindax-MCM-tools/pkg/meshclient"
+	"github.com/NickP005/Vindax-MCM-tools/pkg/secure"
+	"github.com/NickP005/Vindax-MCM-tools/pkg/shutdown"
+	"github.com/NickP005/Vindax-MCM-tools/pkg/wotsp"
+)
+
+type Account struct {
+	MCMAccountNumber string `json:"mcmAccountNumber"`
+	WOTSPublicKey    string `json:"wotsPublicKey"`
+	WOTSSecretKey    string `json:"wotsSecretKey"`
+}
+
+/*
+ * GenerateAccount creates a new MCM 3.0 account using WOTS signatures
+ *
+ * Parameters:
+ * - seed: byte array of exactly 32 bytes used as the initial seed
+ * - index: uint64 used to generate unique addresses for multiple accounts
+ *
+ * Returns:
+ * - *Account: contains MCM account number (20 bytes hex), WOTS public key (2208 bytes hex),
+ *            and WOTS secret key (32 bytes hex)
+ * - error: if seed length is invalid or if generation fails
+ *
+ * The keypair is derived by wotsp.Keygen from three components of the seed:
+ * 1. Private seed - used for WOTS secret key
+ * 2. Public seed - used for WOTS public key generation
+ * 3. Address seed - used for MCM account number
+ */
+func generateAccount(seed []byte, index uint64) (*Account, error) {
+	if len(seed) != 32 {
+		return nil, fmt.Errorf("seed must be exactly 32 bytes, got %d", len(seed))
+	}
+	var privateKey [32]byte
+	copy(privateKey[:], seed)
+	defer secure.Wipe(privateKey[:])
+
+	keypair := wotsp.Keygen(privateKey)
+	defer keypair.Wipe()
+
+	// The public key is followed by the public seed and the signing address (address seed and default tag)
+	var public_key [2208]byte
+	copy(public_key[:], keypair.PublicKey[:])
+	copy(public_key[2144:], keypair.Components.PublicSeed[:])
+	copy(public_key[2144+32:], keypair.Address[:])
+
+	return &Account{
+		MCMAccountNumber: fmt.Sprintf("%020x", index),
+		WOTSPublicKey:    hex.EncodeToString(public_key[:]),
+		WOTSSecretKey:    hex.EncodeToString(seed),
+	}, nil
+}
+
+// checkDerivation compares the local address hash of the account's public key with the Mesh API's
+func checkDerivation(ctx context.Context, client *meshclient.MeshAPIClient, account *Account) error {
+	publicKey, err := hex.DecodeString(account.WOTSPublicKey)
+	if err != nil {
+		return fmt.Errorf("invalid public key: %v", err)
+	}
+	_, err = client.CheckDerivation(ctx, publicKey)
+	return err
+}
+
+/*
+ * Main function for the MCM 3.0 WOTS keypair generator tool
+ *
+ * Command line flags:
+ * -n uint: number of accounts to generate (default: 1)
+ * -format string: json (default), ndjson or csv
+ * -derive-check: cross-check each address hash with the Mesh API (-api)
+ * -print-config: print the effective shared configuration and exit
+ *
+ * For each account:
+ * 1. Generates a random 32-byte seed
+ * 2. Derives WOTS components (private, public, address seeds)
+ * 3. Generates WOTS keypair and MCM account number
+ *
+ * Each account is written as soon as it is generated, so memory use does not
+ * grow with -n. With -derive-check, the address hash of every public key is
+ * also derived by /construction/derive and any difference with the local
+ * derivation stops the tool before the account is written.
+ *
+ * Ctrl-C (or SIGTERM) stops the generation after the account in progress:
+ * the output is closed so it stays well-formed, the number of accounts
+ * written is reported and the tool exits 130.
+ *
+ * The default output is a JSON object holding the array of accounts with:
+ * - mcmAccountNumber: 20 bytes hex (index based)
+ * - wotsPublicKey: 2208 bytes hex
+ * - wotsSecretKey: 32 bytes hex
+ */
+func main() {
+	cfg, err := config.Load(config.Defaults())
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	numAccounts := flag.Uint64("n", 1, "number of accounts to generate")
+	format := flag.String("format", "json", "output format: json, ndjson or csv")
+	deriveCheck := flag.Bool("derive-check", false, "cross-check each address hash with /construction/derive of the Mesh API")
+	cfg.APIFlags(flag.CommandLine, "Mesh API URL used by -derive-check")
+	printConfig := cfg.PrintFlag(flag.CommandLine)
+	flag.Parse()
+	if err := cfg.Parsed(flag.CommandLine); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	if *printConfig {
+		cfg.Print(os.Stdout)
+		return
+	}
+
+	var client *meshclient.MeshAPIClient
+	if *deriveCheck {
+		client = meshclient.NewMeshAPIClient(cfg.API, nil)
+		cfg.Apply(client)
+		client.SetUserAgent("tool-2")
+	}
+
+	out := bufio.NewWriter(os.Stdout)
+	writer, err := NewAccountWriter(*format, out)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	sig := shutdown.Notify()
+	defer sig.Stop()
+	ctx := sig.Context()
+
+	// i ends as the number of accounts written, fewer than -n if interrupted
+	var i uint64
+	for i = 0; i < *numAccounts && ctx.Err() == nil; i++ {
+		// Generate random seed for each account
+		seed := make([]byte, 32)
+		if _, err := rand.Read(seed); err != nil {
+			fmt.Fprintf(os.Stderr, "Error generating random seed: %v\n", err)
+			os.Exit(1)
+		}
+
+		account, err := generateAccount(seed, i)
+		secure.Wipe(seed)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error generating account %d: %v\n", i, err)
+			os.Exit(1)
+		}
+		if client != nil {
+			if err := checkDerivation(ctx, client, account); err != nil {
+				if ctx.Err() != nil {
+					break
+				}
+				fmt.Fprintf(os.Stderr, "Error checking account %d: %v\n", i, err)
+				os.Exit(1)
+			}
+		}
+		if err := writer.Write(account); err != nil {
+			fmt.Fprintf(os.Stderr, "Error writing account %d: %v\n", i, err)
+			os.Exit(1)
+		}
+	}
+
+	if err := writer.Close(); err != nil {
+		fmt.Fprintf(os.Stderr, "Error writing output: %v\n", err)
+		os.Exit(1)
+	}
+	if err := out.Flush(); err != nil {
+		fmt.Fprintf(os.Stderr, "Error writing output: %v\n", err)
+		os.Exit(1)
+	}
+	if sig.Interrupted() {
+		fmt.Fprintf(os.Stderr, "Interrupted: %d of %d accounts written\n", i, *numAccounts)
+		sig.Exit(shutdown.ExitInterrupted)
+	}
+}
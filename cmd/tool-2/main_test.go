@@ -0,0 +1,120 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/NickP005/Vindax-MCM-tools/pkg/meshclient"
+	"github.com/NickP005/Vindax-MCM-tools/pkg/meshmock"
+)
+
+// tool2 is the binary built by TestMain, run by the tests driving the command line
+var tool2 string
+
+func TestMain(m *testing.M) {
+	dir, err := os.MkdirTemp("", "tool-2-test")
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	tool2 = filepath.Join(dir, "tool-2")
+	if out, err := exec.Command("go", "build", "-o", tool2, ".").CombinedOutput(); err != nil {
+		fmt.Fprintf(os.Stderr, "failed to build tool-2: %v\n%s", err, out)
+		os.RemoveAll(dir)
+		os.Exit(1)
+	}
+	code := m.Run()
+	os.RemoveAll(dir)
+	os.Exit(code)
+}
+
+/*
+ * accountVectors pin the accounts of known seeds
+ *
+ * The public key is pinned by the SHA-256 of its first 2144 bytes, the seeds
+ * and keys are those of the pkg/wotsp vectors, so tool-2 generates exactly
+ * the keys it did through WOTS-Go.
+ */
+var accountVectors = []struct {
+	seed      string
+	publicKey string // sha256 of the 2144 bytes WOTS public key
+	trailer   string // public seed, address seed and default tag
+}{
+	{
+		seed:      "0000000000000000000000000000000000000000000000000000000000000000",
+		publicKey: "53d94f08d54f3b7dc630a0aa534fbac6be30a9c1264d0b18fb152963351b0351",
+		trailer:   "e91fbaa1089e91c5b2e8c781e1602f97db2591423c11baffb70fa2118d204339" + "01dd935548226652b4f0f29e5bb6d62d900f7940420000000e00000001000000",
+	},
+	{
+		seed:      "9fd07681776e3f6b8dc56ce9707ed2b627ac445becfb526613d8306ae1d0d355",
+		publicKey: "63c8044450b4f99c660f49909d13b8689506d69d9a8cf7f1f3fa20712b892130",
+		trailer:   "6412d1838e96a1e0a08034948086160d24ce8ff03dfa8f2900034b6cc19f88ca" + "9fd952e01c37e554aa87ddaec64871d96863428f420000000e00000001000000",
+	},
+}
+
+func TestGenerateAccountVectors(t *testing.T) {
+	for i, v := range accountVectors {
+		seed, _ := hex.DecodeString(v.seed)
+		account, err := generateAccount(seed, uint64(i))
+		if err != nil {
+			t.Fatalf("vector %d: %v", i, err)
+		}
+		if account.MCMAccountNumber != strings.Repeat("0", 19)+string(rune('0'+i)) {
+			t.Errorf("vector %d: account number %s", i, account.MCMAccountNumber)
+		}
+		if account.WOTSSecretKey != v.seed {
+			t.Errorf("vector %d: secret key %s", i, account.WOTSSecretKey)
+		}
+		publicKey, err := hex.DecodeString(account.WOTSPublicKey)
+		if err != nil || len(publicKey) != 2208 {
+			t.Fatalf("vector %d: public key of %d bytes, %v", i, len(publicKey), err)
+		}
+		if sum := sha256.Sum256(publicKey[:2144]); hex.EncodeToString(sum[:]) != v.publicKey {
+			t.Errorf("vector %d: public key digest %x, want %s", i, sum, v.publicKey)
+		}
+		if got := hex.EncodeToString(publicKey[2144:]); got != v.trailer {
+			t.Errorf("vector %d: trailer %s, want %s", i, got, v.trailer)
+		}
+	}
+}
+
+func TestGenerateAccountSeedLength(t *testing.T) {
+	for _, length := range []int{0, 31, 33, 64} {
+		if _, err := generateAccount(make([]byte, length), 0); err == nil || !strings.Contains(err.Error(), "seed must be exactly 32 bytes") {
+			t.Errorf("%d bytes seed: %v", length, err)
+		}
+	}
+}
+
+// TestCheckDerivation runs -derive-check against a mock deriving as the tools do, and against a server that does not
+func TestCheckDerivation(t *testing.T) {
+	account, err := generateAccount(make([]byte, 32), 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	mock := meshmock.New()
+	defer mock.Close()
+	if err := checkDerivation(context.Background(), meshclient.NewMeshAPIClient(mock.URL(), nil), account); err != nil {
+		t.Errorf("matching server: %v", err)
+	}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, `{"account_identifier":{"address":"0x`+strings.Repeat("00", 20)+`"}}`)
+	}))
+	defer server.Close()
+	var derivationErr *meshclient.DerivationError
+	if err := checkDerivation(context.Background(), meshclient.NewMeshAPIClient(server.URL, nil), account); !errors.As(err, &derivationErr) {
+		t.Errorf("diverging server: %v", err)
+	}
+}
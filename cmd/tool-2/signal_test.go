@@ -0,0 +1,81 @@
+//go:build !windows
+
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/NickP005/Vindax-MCM-tools/internal/clitest"
+	"github.com/NickP005/Vindax-MCM-tools/pkg/shutdown"
+)
+
+// interrupted checks a run was interrupted after writing accounts, and returns how many it reported
+func interrupted(t *testing.T, r clitest.Result) int {
+	t.Helper()
+	if r.Code != shutdown.ExitInterrupted {
+		t.Fatalf("exit %d, want %d: %s", r.Code, shutdown.ExitInterrupted, r.Stderr)
+	}
+	var written int
+	if _, err := fmt.Sscanf(r.Stderr, "Interrupted: %d of 100000000 accounts written\n", &written); err != nil || written == 0 {
+		t.Fatalf("stderr %q", r.Stderr)
+	}
+	return written
+}
+
+// TestInterruptGeneration stops a generation far from -n in every format: the output holds whole accounts, as many as reported
+func TestInterruptGeneration(t *testing.T) {
+	// Each account is larger than the output buffer, so it reaches stdout as soon as it is written
+	enough := func(stdout string) bool { return strings.Count(stdout, "\n") >= 5 }
+
+	t.Run("json", func(t *testing.T) {
+		r := clitest.InterruptWhen(t, tool2, nil, enough, "-n", "100000000")
+		written := interrupted(t, r)
+		var out struct {
+			Accounts []Account `json:"accounts"`
+		}
+		if err := json.Unmarshal([]byte(r.Stdout), &out); err != nil {
+			t.Fatalf("partial output is not JSON: %v\n%.200s", err, r.Stdout)
+		}
+		if len(out.Accounts) != written {
+			t.Errorf("%d accounts, %d reported", len(out.Accounts), written)
+		}
+		for i, account := range out.Accounts {
+			if account.MCMAccountNumber != fmt.Sprintf("%020x", i) || len(account.WOTSPublicKey) != 2*2208 {
+				t.Fatalf("account %d: %.80v", i, account)
+			}
+		}
+	})
+
+	t.Run("ndjson", func(t *testing.T) {
+		r := clitest.InterruptWhen(t, tool2, nil, enough, "-n", "100000000", "-format", "ndjson")
+		written := interrupted(t, r)
+		lines := strings.SplitAfter(r.Stdout, "\n")
+		if lines[len(lines)-1] != "" || len(lines)-1 != written {
+			t.Fatalf("%d lines, %d reported, last %.80q", len(lines)-1, written, lines[len(lines)-1])
+		}
+		for i, line := range lines[:written] {
+			var account Account
+			if err := json.Unmarshal([]byte(line), &account); err != nil || account.MCMAccountNumber != fmt.Sprintf("%020x", i) {
+				t.Fatalf("line %d: %v", i, err)
+			}
+		}
+	})
+
+	t.Run("csv", func(t *testing.T) {
+		r := clitest.InterruptWhen(t, tool2, nil, enough, "-n", "100000000", "-format", "csv")
+		written := interrupted(t, r)
+		records, err := csv.NewReader(strings.NewReader(r.Stdout)).ReadAll()
+		if err != nil || len(records) != written+1 {
+			t.Fatalf("%d records, %d reported: %v", len(records), written, err)
+		}
+		for i, record := range records[1:] {
+			if record[0] != fmt.Sprintf("%020x", i) || len(record[1]) != 2*2208 {
+				t.Fatalf("record %d: %.80q", i, record)
+			}
+		}
+	})
+}
@@ -0,0 +1,94 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+)
+
+// AccountWriter receives generated accounts one at a time, so memory stays flat whatever -n is
+type AccountWriter interface {
+	Write(account *Account) error
+	Close() error
+}
+
+// NewAccountWriter returns the writer for an output format: json, ndjson or csv
+func NewAccountWriter(format string, out io.Writer) (AccountWriter, error) {
+	switch format {
+	case "json":
+		return &JSONWriter{Out: out}, nil
+	case "ndjson":
+		return &NDJSONWriter{Out: out}, nil
+	case "csv":
+		return &CSVWriter{Out: csv.NewWriter(out)}, nil
+	default:
+		return nil, fmt.Errorf("unknown format %q (use json, ndjson or csv)", format)
+	}
+}
+
+// JSONWriter streams the {"accounts": [...]} object, byte for byte as the indented encoder prints it
+type JSONWriter struct {
+	Out   io.Writer
+	count int
+}
+
+func (w *JSONWriter) Write(account *Account) error {
+	data, err := json.MarshalIndent(account, "    ", "  ")
+	if err != nil {
+		return err
+	}
+	sep := ",\n    "
+	if w.count == 0 {
+		sep = "{\n  \"accounts\": [\n    "
+	}
+	w.count++
+	_, err = fmt.Fprintf(w.Out, "%s%s", sep, data)
+	return err
+}
+
+func (w *JSONWriter) Close() error {
+	end := "\n  ]\n}"
+	if w.count == 0 {
+		end = "{\n  \"accounts\": []\n}"
+	}
+	_, err := fmt.Fprintln(w.Out, end)
+	return err
+}
+
+// NDJSONWriter prints one account object per line
+type NDJSONWriter struct {
+	Out io.Writer
+}
+
+func (w *NDJSONWriter) Write(account *Account) error {
+	data, err := json.Marshal(account)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w.Out, "%s\n", data)
+	return err
+}
+
+func (w *NDJSONWriter) Close() error { return nil }
+
+// CSVWriter prints a header followed by one row per account
+type CSVWriter struct {
+	Out    *csv.Writer
+	header bool
+}
+
+func (w *CSVWriter) Write(account *Account) error {
+	if !w.header {
+		w.header = true
+		if err := w.Out.Write([]string{"mcmAccountNumber", "wotsPublicKey", "wotsSecretKey"}); err != nil {
+			return err
+		}
+	}
+	return w.Out.Write([]string{account.MCMAccountNumber, account.WOTSPublicKey, account.WOTSSecretKey})
+}
+
+func (w *CSVWriter) Close() error {
+	w.Out.Flush()
+	return w.Out.Error()
+}
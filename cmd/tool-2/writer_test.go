@@ -0,0 +1,158 @@
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+// testAccounts generates n accounts from fixed seeds
+func testAccounts(t testing.TB, n int) []*Account {
+	t.Helper()
+	accounts := make([]*Account, n)
+	for i := range accounts {
+		seed := sha256.Sum256([]byte(fmt.Sprintf("tool-2 test %d", i)))
+		account, err := generateAccount(seed[:], uint64(i))
+		if err != nil {
+			t.Fatal(err)
+		}
+		accounts[i] = account
+	}
+	return accounts
+}
+
+// writeAll streams accounts through the writer of a format
+func writeAll(t *testing.T, format string, accounts []*Account) string {
+	t.Helper()
+	var out bytes.Buffer
+	writer, err := NewAccountWriter(format, &out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, account := range accounts {
+		if err := writer.Write(account); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := writer.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return out.String()
+}
+
+// TestJSONWriterMatchesEncoder pins the streamed JSON to what the tool printed when it buffered every account
+func TestJSONWriterMatchesEncoder(t *testing.T) {
+	for _, n := range []int{0, 1, 3} {
+		accounts := testAccounts(t, n)
+		buffered := struct {
+			Accounts []Account `json:"accounts"`
+		}{Accounts: make([]Account, 0, n)}
+		for _, account := range accounts {
+			buffered.Accounts = append(buffered.Accounts, *account)
+		}
+		var want bytes.Buffer
+		encoder := json.NewEncoder(&want)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(buffered); err != nil {
+			t.Fatal(err)
+		}
+		if got := writeAll(t, "json", accounts); got != want.String() {
+			t.Errorf("%d accounts:\n%s\nwant:\n%s", n, got, want.String())
+		}
+	}
+}
+
+func TestNDJSONWriter(t *testing.T) {
+	accounts := testAccounts(t, 3)
+	lines := strings.Split(strings.TrimSuffix(writeAll(t, "ndjson", accounts), "\n"), "\n")
+	if len(lines) != len(accounts) {
+		t.Fatalf("%d lines, want %d", len(lines), len(accounts))
+	}
+	for i, line := range lines {
+		var account Account
+		if err := json.Unmarshal([]byte(line), &account); err != nil || account != *accounts[i] {
+			t.Errorf("line %d: %+v, %v", i, account, err)
+		}
+	}
+	if out := writeAll(t, "ndjson", nil); out != "" {
+		t.Errorf("no accounts printed %q", out)
+	}
+}
+
+func TestCSVWriter(t *testing.T) {
+	accounts := testAccounts(t, 3)
+	records, err := csv.NewReader(strings.NewReader(writeAll(t, "csv", accounts))).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != len(accounts)+1 || strings.Join(records[0], ",") != "mcmAccountNumber,wotsPublicKey,wotsSecretKey" {
+		t.Fatalf("records %v", records)
+	}
+	for i, account := range accounts {
+		if got := records[i+1]; got[0] != account.MCMAccountNumber || got[1] != account.WOTSPublicKey || got[2] != account.WOTSSecretKey {
+			t.Errorf("row %d: %v", i, got[0])
+		}
+	}
+}
+
+func TestUnknownFormat(t *testing.T) {
+	if _, err := NewAccountWriter("xml", io.Discard); err == nil || !strings.Contains(err.Error(), `unknown format "xml"`) {
+		t.Errorf("got %v", err)
+	}
+}
+
+// heapInUse returns the heap in use after a garbage collection
+func heapInUse() uint64 {
+	var stats runtime.MemStats
+	runtime.GC()
+	runtime.ReadMemStats(&stats)
+	return stats.HeapInuse
+}
+
+/*
+ * TestStreamingMemory generates 50k accounts through the JSON writer and
+ * asserts the heap stays flat
+ *
+ * Buffered, 50k public keys in hex are over 400 MB; streamed, the heap must
+ * stay under a few MB whatever the count.
+ */
+func TestStreamingMemory(t *testing.T) {
+	if testing.Short() {
+		t.Skip("generates 50k keys")
+	}
+	const count = 50_000
+	const ceiling = 16 << 20
+
+	base := heapInUse()
+	writer, err := NewAccountWriter("json", io.Discard)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var peak uint64
+	for i := uint64(0); i < count; i++ {
+		seed := sha256.Sum256([]byte(fmt.Sprintf("tool-2 memory %d", i)))
+		account, err := generateAccount(seed[:], i)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := writer.Write(account); err != nil {
+			t.Fatal(err)
+		}
+		if i%5000 == 0 {
+			peak = max(peak, heapInUse())
+		}
+	}
+	if err := writer.Close(); err != nil {
+		t.Fatal(err)
+	}
+	peak = max(peak, heapInUse())
+	if peak > base+ceiling {
+		t.Errorf("heap grew from %d to %d bytes generating %d accounts", base, peak, count)
+	}
+}
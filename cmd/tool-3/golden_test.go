@@ -0,0 +1,56 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/NickP005/Vindax-MCM-tools/internal/clitest"
+)
+
+// TestGolden pins every mode; WOTS signatures are deterministic, so the signed transactions are pinned too
+func TestGolden(t *testing.T) {
+	source, change, message := newTestKey("source"), newTestKey("change"), newTestKey("message")
+	args := sendArgs(source, change)
+	secret := []string{SecretEnvVar + "=" + source.secret}
+	dir := t.TempDir()
+	for _, tc := range []struct {
+		name  string
+		stdin string
+		env   []string
+		args  []string
+	}{
+		{"send", "", secret, args},
+		{"send-memo", "", secret, append(args[:len(args)-2:len(args)-2], "-amount", "0.00005mcm", "-fee", "0.000001mcm", "-memo", "INV-1715")},
+		{"send-stdin", source.secret + "\n", nil, append(args, "-secret-stdin")},
+		{"missing-secret", "", nil, args},
+		{"insufficient-balance", "", secret, append(args, "-amount", "100000")},
+		{"amount-overflow", "", secret, append(args, "-amount", "18446744073709551615")},
+		{"same-change-key", "", secret, sendArgs(source, source)},
+		{"bad-memo", "", secret, append(args, "-memo", "inv 1715")},
+		{"sign-message", message.secret + "\n", nil, []string{"-secret-stdin", "-sign-message", "hello", "-consume-key"}},
+		{"sign-message-unconfirmed", message.secret + "\n", nil, []string{"-secret-stdin", "-sign-message", "hello"}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			r := runTool3(t, tc.stdin, tc.env, tc.args...)
+			clitest.CheckGolden(t, tc.name, r.Golden())
+			if r.Code != 0 {
+				return
+			}
+			// What was signed verifies, with an output of its own
+			path := filepath.Join(dir, tc.name+".out")
+			if err := os.WriteFile(path, []byte(r.Stdout), 0600); err != nil {
+				t.Fatal(err)
+			}
+			verify := "-verify"
+			if strings.HasPrefix(tc.name, "sign-message") {
+				verify = "-verify-message"
+			}
+			r = runTool3(t, "", nil, verify, path)
+			r.Stdout = strings.ReplaceAll(r.Stdout, path, tc.name+".out")
+			r.Stderr = strings.ReplaceAll(r.Stderr, path, tc.name+".out")
+			clitest.CheckGolden(t, tc.name+"-verify", r.Golden())
+		})
+	}
+}
@@ -0,0 +1,446 @@
+package main
+
+/*
+ * MCM 3.0 Transaction Submission Tool
+ *
+ * This tool creates and submits transactions to the Mochimo network via the Mesh API.
+ *
+ * Required inputs:
+ * -src: Source account address (20 bytes hex or base58)
+ * -dst: Destination account address (20 bytes hex or base58)
+ * -wots-pk: Source WOTS public key (2208 bytes hex)
+ * -change-pk: Change WOTS public key (2208 bytes hex)
+ * -balance: Source balance in nanoMCM (or MCM with a "mcm" suffix)
+ * -amount: Amount to send in nanoMCM, or in MCM with a "mcm" suffix (e.g. 2.5mcm)
+ * -secret-stdin: Read the secret key for signing (32 bytes hex) from stdin
+ * -memo: Optional transaction memo
+ * -fee: Transaction fee in nanoMCM, or in MCM with a "mcm" suffix (default: 500, or the fee of the shared config file)
+ * -unit: Unit of -amount and -fee values given without suffix (nmcm or mcm, default: nmcm)
+ * -api: Mesh API endpoint (default: http://localhost:8080)
+ * -print-config: Print the effective shared configuration and exit
+ *
+ * Verification mode:
+ * -verify: Signed transaction (hex or file) to check cryptographically; exits 0 only if valid
+ *
+ * Message signing mode:
+ * -sign-message: Text to sign with the WOTS key (requires -consume-key, the key is one-time)
+ * -verify-message: JSON bundle produced by -sign-message to verify
+ *
+ * The secret key is taken from the first available source, in order:
+ * -secret-stdin, the MCM_TX_SECRET environment variable, then the
+ * deprecated -secret flag (which exposes the key in ps output and shell history).
+ */
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"math/bits"
+	"os"
+	"strings"
+
+	"github.com/NickP005/Vindax-MCM-tools/pkg/amount"
+	"github.com/NickP005/Vindax-MCM-tools/pkg/config"
+	"github.com/NickP005/Vindax-MCM-tools/pkg/mcmaddr"
+	"github.com/NickP005/Vindax-MCM-tools/pkg/secure"
+	"github.com/NickP005/Vindax-MCM-tools/pkg/txbuild"
+	"github.com/NickP005/Vindax-MCM-tools/pkg/txentry"
+	"github.com/NickP005/Vindax-MCM-tools/pkg/wotsp"
+)
+
+// MeshAPISubmitRequest represents the request body for /construction/submit
+
+/*
+ * MeshAPISubmitRequest represents the JSON structure for submitting
+ * a signed transaction to the Mochimo Mesh API
+ *
+ * Fields:
+ * - NetworkIdentifier: Identifies the blockchain network
+ *   - Blockchain: Name of the blockchain (always "mochimo")
+ *   - Network: Network name (e.g. "mainnet")
+ * - SignedTransaction: Hex-encoded signed transaction data
+ */
+type MeshAPISubmitRequest struct {
+	NetworkIdentifier struct {
+		Blockchain string `json:"blockchain"`
+		Network    string `json:"network"`
+	} `json:"network_identifier"`
+	SignedTransaction string `json:"signed_transaction"`
+}
+
+// Add new type for parse request
+type ConstructionParseRequest struct {
+	NetworkIdentifier NetworkIdentifier `json:"network_identifier"`
+	Signed            bool              `json:"signed"`
+	Transaction       string            `json:"transaction"`
+}
+
+type NetworkIdentifier struct {
+	Blockchain string `json:"blockchain"`
+	Network    string `json:"network"`
+}
+
+// SecretEnvVar is the environment variable the signing secret can be read from
+const SecretEnvVar = "MCM_TX_SECRET"
+
+/*
+ * readSecret returns the hex encoded signing secret from the first available source
+ *
+ * Precedence:
+ * 1. stdin (one hex line) when fromStdin is set
+ * 2. the MCM_TX_SECRET environment variable
+ * 3. the -secret flag value (deprecated, prints a warning)
+ *
+ * The secret is returned as hex in a byte slice the caller must wipe. It is
+ * empty if no source provided a secret.
+ */
+func readSecret(fromStdin bool, stdin io.Reader, flagValue string) ([]byte, error) {
+	if fromStdin {
+		line, err := bufio.NewReader(stdin).ReadBytes('\n')
+		if err != nil && err != io.EOF {
+			secure.Wipe(line)
+			return nil, fmt.Errorf("failed to read secret from stdin: %v", err)
+		}
+		return line, nil
+	}
+
+	if env := os.Getenv(SecretEnvVar); env != "" {
+		return []byte(env), nil
+	}
+
+	if flagValue != "" {
+		fmt.Fprintf(os.Stderr, "Warning: -secret is deprecated, it exposes the key in ps output and shell history. Use -secret-stdin or %s instead\n", SecretEnvVar)
+	}
+	return []byte(flagValue), nil
+}
+
+/*
+ * samePublicKey reports whether two hex encoded WOTS public keys hold the same key
+ *
+ * The keys are compared as decoded bytes (so case differences don't matter),
+ * looking only at the 2144 bytes WOTS key portion.
+ */
+func samePublicKey(sourceHex string, changeHex string) (bool, error) {
+	source, err := hex.DecodeString(strings.TrimPrefix(sourceHex, "0x"))
+	if err != nil {
+		return false, fmt.Errorf("invalid source public key hex: %v", err)
+	}
+	change, err := hex.DecodeString(strings.TrimPrefix(changeHex, "0x"))
+	if err != nil {
+		return false, fmt.Errorf("invalid change public key hex: %v", err)
+	}
+	if len(source) > 2144 {
+		source = source[:2144]
+	}
+	if len(change) > 2144 {
+		change = change[:2144]
+	}
+	return secure.Equal(source, change), nil
+}
+
+// publicKey decodes the WOTS+ public key of a 2208 bytes hex key, the public seed and address that follow it ignored
+func publicKey(keyHex string) ([wotsp.SigSize]byte, error) {
+	var pk [wotsp.SigSize]byte
+	raw, err := hex.DecodeString(strings.TrimPrefix(keyHex, "0x"))
+	if err != nil {
+		return pk, err
+	}
+	if len(raw) < wotsp.SigSize {
+		return pk, fmt.Errorf("%d bytes, expected %d", len(raw), wotsp.SigSize+64)
+	}
+	copy(pk[:], raw)
+	return pk, nil
+}
+
+/*
+ * main is the entry point for the MCM transaction submission tool
+ *
+ * The function:
+ * 1. Parses and validates command line arguments
+ * 2. Creates a new transaction using the MCM interface
+ * 3. Sets transaction parameters (addresses, amounts, fee)
+ * 4. Generates transaction components from the secret key
+ * 5. Signs the transaction using WOTS
+ * 6. Creates a Mesh API submission request
+ * 7. Outputs the request as formatted JSON
+ *
+ * Required flags:
+ * -src: Source account address
+ * -source-pk: Source WOTS public key
+ * -change-pk: Change WOTS public key
+ * -balance: Source balance in nanoMCM (or MCM with a "mcm" suffix)
+ * -dst: Destination account address
+ * -amount: Amount to send (nanoMCM, or MCM with a "mcm" suffix; see pkg/amount)
+ * -secret-stdin / MCM_TX_SECRET / -secret: Secret key for signing
+ * -memo: Transaction memo
+ * -fee: Transaction fee (default: 500 nanoMCM)
+ *
+ * Optional flags:
+ * -memo: Transaction memo
+ * -fee: Transaction fee (default: 500 nanoMCM)
+ * -unit: Default unit for -amount and -fee (default: nmcm)
+ */
+func main() {
+	cfg, err := config.Load(config.Defaults())
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	// Define command line flags
+	sourceTag := flag.String("src", "", "Source account address (20 bytes hex or base58)")
+	sourcePk := flag.String("source-pk", "", "Source WOTS public key (2208 bytes hex)")
+	changePk := flag.String("change-pk", "", "Change WOTS public key (2208 bytes hex)")
+	balanceStr := flag.String("balance", "", "Source balance in nanoMCM, or suffixed with mcm (e.g. 2.5mcm); -unit does not apply")
+	dstAddress := flag.String("dst", "", "Destination account address (20 bytes hex or base58)")
+	amountStr := flag.String("amount", "", "Amount to send. Bare numbers use -unit, or suffix with nmcm/mcm (e.g. 2.5mcm)")
+	secret := flag.String("secret", "", "Secret key for signing (32 bytes hex). Deprecated: used only if -secret-stdin is not set and "+SecretEnvVar+" is empty")
+	secretStdin := flag.Bool("secret-stdin", false, "Read the secret key (32 bytes hex) as one line from stdin. Takes precedence over "+SecretEnvVar+" and -secret")
+	memo := flag.String("memo", "", "Optional transaction memo")
+	cfg.FeeFlag(flag.CommandLine, "Transaction fee. Bare numbers use -unit, or suffix with nmcm/mcm (e.g. 0.0000005mcm)")
+	verifyInput := flag.String("verify", "", "Verify a signed transaction (hex, or a file with the hex or this tool's JSON output) and exit")
+	signMessageText := flag.String("sign-message", "", "Sign an arbitrary message with the WOTS key and output a JSON bundle (consumes the one-time key, requires -consume-key)")
+	verifyMessageFile := flag.String("verify-message", "", "Verify a JSON bundle produced by -sign-message and exit")
+	consumeKey := flag.Bool("consume-key", false, "Confirm that -sign-message consumes the one-time WOTS key")
+	allowSameChangeKey := flag.Bool("allow-same-change-key", false, "Allow -change-pk to equal -source-pk (reuses a WOTS key, testnet experiments only)")
+	unitStr := flag.String("unit", "nmcm", "Unit of -amount and -fee values without suffix (nmcm or mcm)")
+	//api := flag.String("api", "http://localhost:8080", "Mesh API endpoint")
+	printConfig := cfg.PrintFlag(flag.CommandLine)
+
+	flag.Parse()
+	if err := cfg.Parsed(flag.CommandLine); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	if *printConfig {
+		cfg.Print(os.Stdout)
+		return
+	}
+
+	// Standalone verification mode
+	if *verifyInput != "" {
+		os.Exit(runVerify(*verifyInput))
+	}
+	if *verifyMessageFile != "" {
+		os.Exit(runVerifyMessage(*verifyMessageFile))
+	}
+
+	secretHex, err := readSecret(*secretStdin, os.Stdin, *secret)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	// Decode the secret once into a fixed buffer and wipe its hex form right away
+	haveSecret := len(bytes.TrimSpace(secretHex)) > 0
+	var privateKey [32]byte
+	if haveSecret {
+		privateKey, err = secure.DecodeKey(secretHex)
+		if err != nil {
+			secure.Wipe(secretHex)
+			fmt.Fprintln(os.Stderr, "Error: Secret key must be 32 bytes hex")
+			os.Exit(1)
+		}
+	}
+	secure.Wipe(secretHex)
+
+	// Message signing mode
+	if *signMessageText != "" {
+		if !*consumeKey {
+			fmt.Fprintln(os.Stderr, "Error: signing a message consumes the one-time WOTS key, funds held by it must then be moved with a fresh key. Pass -consume-key to confirm")
+			os.Exit(1)
+		}
+		if !haveSecret {
+			fmt.Fprintln(os.Stderr, "Error: Secret key is required (-secret-stdin, "+SecretEnvVar+" or -secret)")
+			os.Exit(1)
+		}
+		bundle, err := signMessage(*signMessageText, privateKey)
+		secure.Wipe(privateKey[:])
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error signing message: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Fprintf(os.Stderr, "Warning: the WOTS key of address %s is now consumed, do not sign anything else with it\n", bundle.Address)
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(bundle); err != nil {
+			fmt.Fprintf(os.Stderr, "Error encoding JSON: %v\n", err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	// Validate inputs
+	if *sourceTag == "" && len(*sourceTag) != 40 {
+		fmt.Fprintln(os.Stderr, "Error: Source account address is required")
+		os.Exit(1)
+	} else if *sourcePk == "" && len(*sourcePk) != 2208*2 {
+		fmt.Fprintln(os.Stderr, "Error: Source WOTS public key is required")
+		os.Exit(1)
+	} else if *changePk == "" && len(*changePk) != 2208*2 {
+		fmt.Fprintln(os.Stderr, "Error: Change WOTS public key is required")
+		os.Exit(1)
+	} else if *balanceStr == "" {
+		fmt.Fprintln(os.Stderr, "Error: Source balance is required")
+		os.Exit(1)
+	} else if *dstAddress == "" && len(*dstAddress) != 40 {
+		fmt.Fprintln(os.Stderr, "Error: Destination address is required")
+		os.Exit(1)
+	} else if *amountStr == "" {
+		fmt.Fprintln(os.Stderr, "Error: Amount to send is required")
+		os.Exit(1)
+	} else if !haveSecret {
+		fmt.Fprintln(os.Stderr, "Error: Secret key is required (-secret-stdin, "+SecretEnvVar+" or -secret)")
+		os.Exit(1)
+	}
+
+	// Parse amount and fee with exact decimal arithmetic
+	unit, err := amount.ParseUnit(*unitStr)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	sendAmount, err := amount.Parse(*amountStr, unit)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error parsing -amount: %v\n", err)
+		os.Exit(1)
+	}
+	fee, err := amount.Parse(cfg.Fee, unit)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error parsing -fee: %v\n", err)
+		os.Exit(1)
+	}
+	sourceBalance, err := amount.Parse(*balanceStr, amount.NanoMCM)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error parsing -balance: %v\n", err)
+		os.Exit(1)
+	}
+
+	// Echo both representations so the operator can sanity-check them
+	fmt.Fprintf(os.Stderr, "Amount: %s\n", amount.Describe(sendAmount))
+	fmt.Fprintf(os.Stderr, "Fee: %s\n", amount.Describe(fee))
+
+	sourceAddr, err := mcmaddr.Normalize(*sourceTag)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error decoding source tag: %v\n", err)
+		os.Exit(1)
+	}
+	dstTag, err := mcmaddr.Normalize(*dstAddress)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error decoding destination address: %v\n", err)
+		os.Exit(1)
+	}
+
+	// Source balance must be greater than amount + fee, a sum that must not wrap around
+	spent, carry := bits.Add64(sendAmount, fee, 0)
+	if carry != 0 {
+		fmt.Fprintln(os.Stderr, "Error: amount plus fee overflows")
+		os.Exit(1)
+	}
+	if sourceBalance < spent {
+		fmt.Fprintln(os.Stderr, "Error: Insufficient balance to send amount and fee")
+		os.Exit(1)
+	}
+
+	// The change must never land on the WOTS key being spent
+	sameKey, err := samePublicKey(*sourcePk, *changePk)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	if sameKey && !*allowSameChangeKey {
+		fmt.Fprintln(os.Stderr, "Error: Change public key is the same as the source public key")
+		fmt.Fprintln(os.Stderr, "Signing this transaction uses the source WOTS key, and a WOTS key must never sign twice:")
+		fmt.Fprintln(os.Stderr, "funds sent back to it as change could be stolen by anyone who saw this signature.")
+		fmt.Fprintln(os.Stderr, "Use a fresh, unused change key (or -allow-same-change-key for testnet experiments)")
+		os.Exit(1)
+	} else if sameKey {
+		fmt.Fprintln(os.Stderr, "Warning: Change public key equals the source public key, the change lands on an already used WOTS key")
+	}
+
+	// Source and change carry the source tag, with the address hashes of their public keys
+	srcKey, err := publicKey(*sourcePk)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: invalid source public key: %v\n", err)
+		os.Exit(1)
+	}
+	chgKey, err := publicKey(*changePk)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: invalid change public key: %v\n", err)
+		os.Exit(1)
+	}
+	srcAddr := txbuild.Address(sourceAddr, srcKey)
+	chgAddr := txbuild.Address(sourceAddr, chgKey)
+
+	// Add destination, its memo checked with the reference rules of txentry: mcm's ValidateReference refuses "INV-12"
+	dstEntry, err := txbuild.NewDestination(dstTag, *memo, sendAmount)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	tx, err := txbuild.NewTransfer(srcAddr, chgAddr, sourceBalance, fee, []txentry.Destination{dstEntry})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	// Sign transaction
+	signing_keypair := wotsp.Keygen(privateKey)
+	secure.Wipe(privateKey[:])
+
+	// Check that public key matches source address
+	derived_address := txbuild.Address(sourceAddr, signing_keypair.PublicKey)
+	if !secure.Equal40(derived_address, srcAddr) {
+		fmt.Println("wots from priv", hex.EncodeToString(derived_address[:]))
+		fmt.Println("given wots", hex.EncodeToString(srcAddr[:]))
+		fmt.Fprintln(os.Stderr, "Error: Public key does not match source address")
+		os.Exit(1)
+	}
+
+	// Make sure the signature verifies before it is ever broadcast
+	if err := txbuild.Sign(tx, &signing_keypair); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	// Scrub the secret material now that the transaction is signed
+	signing_keypair.Wipe()
+
+	/*
+			// Create parse request
+		request := ConstructionParseRequest{
+			NetworkIdentifier: struct {
+				Blockchain string `json:"blockchain"`
+				Network    string `json:"network"`
+			}{
+				Blockchain: "mochimo",
+				Network:    "mainnet",
+			},
+			Signed:      true,
+			Transaction: tx.String(),
+		}*/
+
+	// Create submit request
+	request := MeshAPISubmitRequest{
+		NetworkIdentifier: struct {
+			Blockchain string `json:"blockchain"`
+			Network    string `json:"network"`
+		}{
+			Blockchain: "mochimo",
+			Network:    "mainnet",
+		},
+		SignedTransaction: hex.EncodeToString(tx.Bytes()),
+	}
+
+	// Output JSON
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(request); err != nil {
+		fmt.Fprintf(os.Stderr, "Error encoding JSON: %v\n", err)
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,251 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/NickP005/Vindax-MCM-tools/internal/clitest"
+	"github.com/NickP005/Vindax-MCM-tools/pkg/wotsp"
+)
+
+// tool3 is the binary built by TestMain, run by the tests driving the command line
+var tool3 string
+
+func TestMain(m *testing.M) {
+	dir, err := os.MkdirTemp("", "tool-3-test")
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	tool3 = filepath.Join(dir, "tool-3")
+	if out, err := exec.Command("go", "build", "-o", tool3, ".").CombinedOutput(); err != nil {
+		fmt.Fprintf(os.Stderr, "failed to build tool-3: %v\n%s", err, out)
+		os.RemoveAll(dir)
+		os.Exit(1)
+	}
+	code := m.Run()
+	os.RemoveAll(dir)
+	os.Exit(code)
+}
+
+// testKey is a WOTS key derived from a label, with the hex forms the flags take
+type testKey struct {
+	secret    string
+	publicKey string
+}
+
+// newTestKey derives the key of a label, its public key as the 2208 bytes -source-pk takes
+func newTestKey(label string) testKey {
+	seed := sha256.Sum256([]byte("tool-3 test " + label))
+	keypair := wotsp.Keygen(seed)
+	defer keypair.Wipe()
+	full := append(keypair.PublicKey[:], keypair.Components.PublicSeed[:]...)
+	full = append(full, keypair.Address[:]...)
+	return testKey{secret: hex.EncodeToString(seed[:]), publicKey: hex.EncodeToString(full)}
+}
+
+// runTool3 runs the binary with args, stdin and the extra environment env, without the caller's MCM_* variables or config file
+func runTool3(t *testing.T, stdin string, env []string, args ...string) clitest.Result {
+	t.Helper()
+	return clitest.Run(t, tool3, stdin, env, args...)
+}
+
+// sendArgs are the flags of a transaction from source to a fixed destination, changing to change
+func sendArgs(source testKey, change testKey) []string {
+	return []string{
+		"-src", strings.Repeat("ab", 20),
+		"-source-pk", source.publicKey,
+		"-change-pk", change.publicKey,
+		"-balance", "100000",
+		"-dst", strings.Repeat("cd", 20),
+		"-amount", "1000",
+	}
+}
+
+func TestSecretSourcesSignTheSame(t *testing.T) {
+	source, change := newTestKey("source"), newTestKey("change")
+	args := sendArgs(source, change)
+
+	flagRun := runTool3(t, "", nil, append(args, "-secret", source.secret)...)
+	if flagRun.Code != 0 {
+		t.Fatalf("-secret exited %d: %s", flagRun.Code, flagRun.Stderr)
+	}
+	if !strings.Contains(flagRun.Stderr, "-secret is deprecated") {
+		t.Errorf("-secret printed no deprecation warning: %q", flagRun.Stderr)
+	}
+	if !strings.Contains(flagRun.Stdout, `"signed_transaction"`) {
+		t.Fatalf("-secret printed no transaction: %q", flagRun.Stdout)
+	}
+
+	for _, tc := range []struct {
+		name  string
+		stdin string
+		env   []string
+		args  []string
+	}{
+		{"stdin with newline", source.secret + "\n", nil, []string{"-secret-stdin"}},
+		{"stdin without newline", source.secret, nil, []string{"-secret-stdin"}},
+		{"stdin over env and flag", source.secret + "\n", []string{SecretEnvVar + "=" + change.secret}, []string{"-secret-stdin", "-secret", change.secret}},
+		{"env", "", []string{SecretEnvVar + "=" + source.secret}, nil},
+		{"env over flag", "", []string{SecretEnvVar + "=" + source.secret}, []string{"-secret", change.secret}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			r := runTool3(t, tc.stdin, tc.env, append(append([]string{}, args...), tc.args...)...)
+			if r.Code != 0 {
+				t.Fatalf("exited %d: %s", r.Code, r.Stderr)
+			}
+			if r.Stdout != flagRun.Stdout {
+				t.Errorf("output differs from the -secret run:\n%s\nwant:\n%s", r.Stdout, flagRun.Stdout)
+			}
+			if strings.Contains(r.Stderr, "deprecated") {
+				t.Errorf("deprecation warning without -secret being used: %q", r.Stderr)
+			}
+		})
+	}
+}
+
+func TestSecretMissingOrMalformed(t *testing.T) {
+	source, change := newTestKey("source"), newTestKey("change")
+	args := sendArgs(source, change)
+
+	for _, tc := range []struct {
+		name  string
+		stdin string
+		args  []string
+		want  string
+	}{
+		{"no source", "", nil, "Secret key is required"},
+		{"empty stdin", "", []string{"-secret-stdin"}, "Secret key is required"},
+		{"short stdin", source.secret[:40] + "\n", []string{"-secret-stdin"}, "must be 32 bytes hex"},
+		{"not hex", strings.Repeat("zz", 32) + "\n", []string{"-secret-stdin"}, "must be 32 bytes hex"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			r := runTool3(t, tc.stdin, nil, append(append([]string{}, args...), tc.args...)...)
+			if r.Code != 1 || !strings.Contains(r.Stderr, tc.want) {
+				t.Errorf("exited %d with %q, want 1 and %q", r.Code, r.Stderr, tc.want)
+			}
+			if r.Stdout != "" {
+				t.Errorf("printed a transaction: %q", r.Stdout)
+			}
+		})
+	}
+}
+
+func TestReadSecret(t *testing.T) {
+	t.Setenv(SecretEnvVar, "")
+	secret, err := readSecret(true, strings.NewReader("aa\nbb\n"), "cc")
+	if err != nil || string(secret) != "aa\n" {
+		t.Errorf("stdin: got %q, %v; want the first line", secret, err)
+	}
+	if secret, err = readSecret(false, strings.NewReader("aa\n"), "cc"); err != nil || string(secret) != "cc" {
+		t.Errorf("flag: got %q, %v", secret, err)
+	}
+	t.Setenv(SecretEnvVar, "dd")
+	if secret, err = readSecret(false, strings.NewReader("aa\n"), "cc"); err != nil || string(secret) != "dd" {
+		t.Errorf("env: got %q, %v", secret, err)
+	}
+}
+
+func TestSameChangeKey(t *testing.T) {
+	source := newTestKey("source")
+	same := sendArgs(source, source)
+
+	blocked := runTool3(t, source.secret+"\n", nil, append(same, "-secret-stdin")...)
+	if blocked.Code != 1 {
+		t.Fatalf("same change key exited %d, want 1", blocked.Code)
+	}
+	if !strings.Contains(blocked.Stderr, "a WOTS key must never sign twice") || !strings.Contains(blocked.Stderr, "-allow-same-change-key") {
+		t.Errorf("refusal does not explain the key reuse risk: %q", blocked.Stderr)
+	}
+	if blocked.Stdout != "" {
+		t.Errorf("refused run printed a transaction: %q", blocked.Stdout)
+	}
+
+	forced := runTool3(t, source.secret+"\n", nil, append(same, "-secret-stdin", "-allow-same-change-key")...)
+	if forced.Code != 0 {
+		t.Fatalf("-allow-same-change-key exited %d: %s", forced.Code, forced.Stderr)
+	}
+	if !strings.Contains(forced.Stderr, "Warning: Change public key equals the source public key") {
+		t.Errorf("forced run printed no warning: %q", forced.Stderr)
+	}
+	if !strings.Contains(forced.Stdout, `"signed_transaction"`) {
+		t.Errorf("forced run printed no transaction: %q", forced.Stdout)
+	}
+
+	// The comparison is on the decoded key: case and the 0x prefix make no difference
+	upper := source
+	upper.publicKey = "0x" + strings.ToUpper(source.publicKey)
+	r := runTool3(t, source.secret+"\n", nil, append(sendArgs(source, upper), "-secret-stdin")...)
+	if r.Code != 1 || !strings.Contains(r.Stderr, "same as the source public key") {
+		t.Errorf("upper case change key exited %d with %q, want the refusal", r.Code, r.Stderr)
+	}
+}
+
+func TestSamePublicKey(t *testing.T) {
+	a, b := newTestKey("a").publicKey, newTestKey("b").publicKey
+	for _, tc := range []struct {
+		name           string
+		source, change string
+		want           bool
+	}{
+		{"identical", a, a, true},
+		{"different", a, b, false},
+		{"case", a, strings.ToUpper(a), true},
+		{"prefix", a, "0x" + a, true},
+		// Only the 2144 bytes WOTS key counts, not the seeds after it
+		{"same key other seeds", a, a[:2144*2] + strings.Repeat("00", 64), true},
+		{"bare 2144 bytes key", a, a[:2144*2], true},
+	} {
+		got, err := samePublicKey(tc.source, tc.change)
+		if err != nil || got != tc.want {
+			t.Errorf("%s: got %v, %v; want %v", tc.name, got, err, tc.want)
+		}
+	}
+	if _, err := samePublicKey(a, "not hex"); err == nil {
+		t.Error("no error for a change key that is not hex")
+	}
+}
+
+// TestAmountShapes signs with the amounts written in every shape amount.Parse takes: the transaction is the same
+func TestAmountShapes(t *testing.T) {
+	source, change := newTestKey("source"), newTestKey("change")
+	env := []string{SecretEnvVar + "=" + source.secret}
+	base := sendArgs(source, change)
+	want := runTool3(t, "", env, base...)
+	if want.Code != 0 {
+		t.Fatalf("exited %d: %s", want.Code, want.Stderr)
+	}
+	for _, shape := range [][]string{
+		{"-amount", "1_000"},
+		{"-amount", "1,000"},
+		{"-amount", "1000 nMCM"},
+		{"-amount", "0.000001mcm"},
+		{"-amount", "0.000001", "-unit", "mcm", "-fee", "500nmcm"},
+		{"-balance", "100,000"},
+		{"-balance", "0.0001 MCM"},
+		{"-fee", "0.0000005mcm"},
+	} {
+		r := runTool3(t, "", env, append(base, shape...)...)
+		if r.Code != 0 || r.Stdout != want.Stdout {
+			t.Errorf("%q: exited %d, same transaction %v: %s", shape, r.Code, r.Stdout == want.Stdout, r.Stderr)
+		}
+	}
+
+	for _, shape := range [][]string{
+		{"-amount", "1,0"},
+		{"-amount", "1_000,000"},
+		{"-amount", "0.0000000001", "-unit", "mcm"},
+		{"-balance", "18446744073709551616"},
+	} {
+		r := runTool3(t, "", env, append(base, shape...)...)
+		if r.Code != 1 || !strings.Contains(r.Stderr, "invalid amount") || r.Stdout != "" {
+			t.Errorf("%q: exited %d: %s", shape, r.Code, r.Stderr)
+		}
+	}
+}
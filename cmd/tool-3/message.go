@@ -0,0 +1,122 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/NickP005/Vindax-MCM-tools/pkg/secure"
+	"github.com/NickP005/Vindax-MCM-tools/pkg/wotsp"
+	mcm "github.com/NickP005/go_mcminterface"
+)
+
+// MessagePrefix is prepended to messages before hashing so a message signature can never double as a transaction signature
+const MessagePrefix = "Mochimo Signed Message:\n"
+
+/*
+ * SignedMessage is the JSON bundle produced by -sign-message
+ *
+ * Fields:
+ * - Address: 20 bytes hex address of the signing key
+ * - Message: the signed text
+ * - Signature: 2144 bytes hex WOTS signature of sha256(MessagePrefix + Message)
+ * - PubSeed: 32 bytes hex public seed of the signing key
+ * - AddrSeed: 32 bytes hex address seed of the signing key
+ */
+type SignedMessage struct {
+	Address   string `json:"address"`
+	Message   string `json:"message"`
+	Signature string `json:"signature"`
+	PubSeed   string `json:"pubseed"`
+	AddrSeed  string `json:"addrseed"`
+}
+
+// messageHash returns the 32 bytes digest that is signed for a message
+func messageHash(message string) [32]byte {
+	return sha256.Sum256([]byte(MessagePrefix + message))
+}
+
+/*
+ * signMessage signs an arbitrary message with the WOTS key derived from secret
+ *
+ * WARNING: a WOTS key is one-time. Signing a message consumes the key exactly
+ * like signing a transaction does, funds must be moved with a fresh key.
+ */
+func signMessage(message string, secret [32]byte) (*SignedMessage, error) {
+	keypair := wotsp.Keygen(secret)
+	defer keypair.Wipe()
+
+	address := mcm.WotsAddressFromBytes(keypair.PublicKey[:2144])
+	signature := keypair.Sign(messageHash(message))
+
+	return &SignedMessage{
+		Address:   hex.EncodeToString(address.GetAddress()),
+		Message:   message,
+		Signature: hex.EncodeToString(signature[:]),
+		PubSeed:   hex.EncodeToString(keypair.Components.PublicSeed[:]),
+		AddrSeed:  hex.EncodeToString(keypair.Address[:]),
+	}, nil
+}
+
+/*
+ * verifyMessage recomputes the public key from the bundle's signature and
+ * checks that it derives the address claimed by the bundle
+ *
+ * Returns the derived address and an error describing why verification failed.
+ */
+func verifyMessage(bundle SignedMessage) ([]byte, error) {
+	claimed, err := hex.DecodeString(bundle.Address)
+	if err != nil || len(claimed) != 20 {
+		return nil, fmt.Errorf("invalid address: expected 20 bytes hex")
+	}
+	sigBytes, err := hex.DecodeString(bundle.Signature)
+	if err != nil || len(sigBytes) != wotsp.SigSize {
+		return nil, fmt.Errorf("invalid signature: expected %d bytes hex", wotsp.SigSize)
+	}
+	pubSeedBytes, err := hex.DecodeString(bundle.PubSeed)
+	if err != nil || len(pubSeedBytes) != 32 {
+		return nil, fmt.Errorf("invalid pubseed: expected 32 bytes hex")
+	}
+	addrSeedBytes, err := hex.DecodeString(bundle.AddrSeed)
+	if err != nil || len(addrSeedBytes) != 32 {
+		return nil, fmt.Errorf("invalid addrseed: expected 32 bytes hex")
+	}
+
+	var signature [wotsp.SigSize]byte
+	var pubSeed, addrSeed [32]byte
+	copy(signature[:], sigBytes)
+	copy(pubSeed[:], pubSeedBytes)
+	copy(addrSeed[:], addrSeedBytes)
+
+	pk := wotsp.PkFromSig(signature, messageHash(bundle.Message), pubSeed, addrSeed)
+	derived := mcm.WotsAddressFromBytes(pk[:])
+	if !secure.Equal(derived.GetAddress(), claimed) {
+		return derived.GetAddress(), fmt.Errorf("signature does not match address %x (derived %x)", claimed, derived.GetAddress())
+	}
+	return derived.GetAddress(), nil
+}
+
+// runVerifyMessage implements the -verify-message mode and returns the process exit code
+func runVerifyMessage(filename string) int {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error reading bundle: %v\n", err)
+		return 1
+	}
+
+	var bundle SignedMessage
+	if err := json.Unmarshal(data, &bundle); err != nil {
+		fmt.Fprintf(os.Stderr, "Error parsing bundle: %v\n", err)
+		return 1
+	}
+
+	address, err := verifyMessage(bundle)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	fmt.Printf("Message signed by address %x is valid\n", address)
+	return 0
+}
@@ -0,0 +1,154 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// signedTestMessage signs message with the key of label
+func signedTestMessage(t *testing.T, label string, message string) SignedMessage {
+	t.Helper()
+	bundle, err := signMessage(message, sha256.Sum256([]byte("tool-3 test "+label)))
+	if err != nil {
+		t.Fatalf("signMessage: %v", err)
+	}
+	return *bundle
+}
+
+// flipHex flips the lowest bit of the byte at index of a hex field
+func flipHex(t *testing.T, field string, index int) string {
+	t.Helper()
+	b, err := hex.DecodeString(field)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b[index] ^= 1
+	return hex.EncodeToString(b)
+}
+
+func TestMessageRoundTrip(t *testing.T) {
+	for _, message := range []string{"", "hello", "I control this address\nsince block 1000", strings.Repeat("x", 10000)} {
+		bundle := signedTestMessage(t, "message", message)
+		address, err := verifyMessage(bundle)
+		if err != nil {
+			t.Errorf("message %.20q: %v", message, err)
+			continue
+		}
+		if hex.EncodeToString(address) != bundle.Address {
+			t.Errorf("message %.20q: verified address %x, bundle claims %s", message, address, bundle.Address)
+		}
+	}
+}
+
+func TestMessageSurvivesJSON(t *testing.T) {
+	bundle := signedTestMessage(t, "message", "round trip through the file")
+	data, err := json.Marshal(bundle)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var decoded SignedMessage
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := verifyMessage(decoded); err != nil {
+		t.Errorf("decoded bundle: %v", err)
+	}
+}
+
+func TestMessageTamper(t *testing.T) {
+	bundle := signedTestMessage(t, "message", "pay 10 MCM to Alice")
+	other := signedTestMessage(t, "other", "pay 10 MCM to Alice")
+
+	for _, tc := range []struct {
+		name   string
+		tamper func(b *SignedMessage)
+	}{
+		{"message changed", func(b *SignedMessage) { b.Message = "pay 99 MCM to Alice" }},
+		{"message extended", func(b *SignedMessage) { b.Message += " " }},
+		{"signature first byte", func(b *SignedMessage) { b.Signature = flipHex(t, b.Signature, 0) }},
+		{"signature last byte", func(b *SignedMessage) { b.Signature = flipHex(t, b.Signature, 2143) }},
+		{"public seed", func(b *SignedMessage) { b.PubSeed = flipHex(t, b.PubSeed, 5) }},
+		{"address seed", func(b *SignedMessage) { b.AddrSeed = flipHex(t, b.AddrSeed, 0) }},
+		{"address", func(b *SignedMessage) { b.Address = flipHex(t, b.Address, 19) }},
+		{"address of another key", func(b *SignedMessage) { b.Address = other.Address }},
+		{"signature of another key", func(b *SignedMessage) { b.Signature = other.Signature }},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			tampered := bundle
+			tc.tamper(&tampered)
+			if _, err := verifyMessage(tampered); err == nil {
+				t.Error("tampered bundle verified")
+			}
+		})
+	}
+}
+
+func TestMessageMalformedFields(t *testing.T) {
+	bundle := signedTestMessage(t, "message", "hello")
+	for _, tc := range []struct {
+		name   string
+		tamper func(b *SignedMessage)
+		want   string
+	}{
+		{"address not hex", func(b *SignedMessage) { b.Address = "xyz" }, "invalid address"},
+		{"address short", func(b *SignedMessage) { b.Address = b.Address[:38] }, "invalid address"},
+		{"signature short", func(b *SignedMessage) { b.Signature = b.Signature[:100] }, "invalid signature"},
+		{"signature empty", func(b *SignedMessage) { b.Signature = "" }, "invalid signature"},
+		{"public seed long", func(b *SignedMessage) { b.PubSeed += "00" }, "invalid pubseed"},
+		{"address seed not hex", func(b *SignedMessage) { b.AddrSeed = strings.Repeat("g", 64) }, "invalid addrseed"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			malformed := bundle
+			tc.tamper(&malformed)
+			_, err := verifyMessage(malformed)
+			if err == nil || !strings.Contains(err.Error(), tc.want) {
+				t.Errorf("got %v, want an error containing %q", err, tc.want)
+			}
+		})
+	}
+}
+
+func TestSignMessageCommandLine(t *testing.T) {
+	key := newTestKey("message")
+
+	r := runTool3(t, key.secret+"\n", nil, "-secret-stdin", "-sign-message", "hello")
+	if r.Code != 1 || !strings.Contains(r.Stderr, "-consume-key") {
+		t.Fatalf("without -consume-key: exited %d with %q", r.Code, r.Stderr)
+	}
+
+	r = runTool3(t, key.secret+"\n", nil, "-secret-stdin", "-sign-message", "hello", "-consume-key")
+	if r.Code != 0 {
+		t.Fatalf("-sign-message exited %d: %s", r.Code, r.Stderr)
+	}
+	bundleFile := filepath.Join(t.TempDir(), "bundle.json")
+	if err := os.WriteFile(bundleFile, []byte(r.Stdout), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if r := runTool3(t, "", nil, "-verify-message", bundleFile); r.Code != 0 {
+		t.Errorf("-verify-message of the bundle exited %d: %s", r.Code, r.Stderr)
+	}
+
+	var bundle SignedMessage
+	if err := json.Unmarshal([]byte(r.Stdout), &bundle); err != nil {
+		t.Fatal(err)
+	}
+	bundle.Message = "goodbye"
+	data, _ := json.Marshal(bundle)
+	if err := os.WriteFile(bundleFile, data, 0600); err != nil {
+		t.Fatal(err)
+	}
+	if r := runTool3(t, "", nil, "-verify-message", bundleFile); r.Code != 1 {
+		t.Errorf("-verify-message of a tampered bundle exited %d, want 1", r.Code)
+	}
+}
+
+func TestMessageHashIsDomainSeparated(t *testing.T) {
+	if messageHash("x") == sha256.Sum256([]byte("x")) {
+		t.Error("message hash is the plain sha256 of the message")
+	}
+}
@@ -0,0 +1,185 @@
+package main
+
+import (
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"strings"
+
+	"github.com/NickP005/Vindax-MCM-tools/pkg/secure"
+	"github.com/NickP005/Vindax-MCM-tools/pkg/wotsp"
+	mcm "github.com/NickP005/go_mcminterface"
+)
+
+// verifyCheck is the outcome of a single verification step
+type verifyCheck struct {
+	Name   string
+	OK     bool
+	Detail string
+}
+
+/*
+ * readSignedTransaction loads a signed transaction given either as hex or as
+ * a path to a file containing the hex or the JSON output of this tool
+ *
+ * Returns the raw transaction bytes or an error if the input cannot be decoded.
+ */
+func readSignedTransaction(input string) ([]byte, error) {
+	data := strings.TrimSpace(input)
+	if content, err := os.ReadFile(input); err == nil {
+		data = strings.TrimSpace(string(content))
+	}
+
+	// Accept the JSON output of tool-3 as well as plain hex
+	if strings.HasPrefix(data, "{") {
+		var request MeshAPISubmitRequest
+		if err := json.Unmarshal([]byte(data), &request); err != nil {
+			return nil, fmt.Errorf("invalid JSON input: %v", err)
+		}
+		data = request.SignedTransaction
+	}
+
+	data = strings.TrimPrefix(data, "0x")
+	txBytes, err := hex.DecodeString(data)
+	if err != nil {
+		return nil, fmt.Errorf("invalid transaction hex: %v", err)
+	}
+	if len(txBytes) == 0 {
+		return nil, fmt.Errorf("empty transaction")
+	}
+	return txBytes, nil
+}
+
+// destinationAmount returns the amount of a destination, stored little-endian
+func destinationAmount(dst mcm.MDST) uint64 {
+	return binary.LittleEndian.Uint64(dst.Amount[:])
+}
+
+// decodeTransaction wraps mcm.TransactionFromBytes, turning a panic on malformed input into an error
+func decodeTransaction(txBytes []byte) (tx mcm.TXENTRY, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("malformed transaction: %v", r)
+		}
+	}()
+	return mcm.TransactionFromBytes(txBytes), nil
+}
+
+/*
+ * verifyTransaction runs every check on a decoded transaction
+ *
+ * The checks are:
+ * 1. The signature scheme is WOTS+
+ * 2. The destination count matches the destinations list
+ * 3. The destination amounts add up to the send total
+ * 4. send + change + fee does not overflow
+ * 5. The public key recomputed from the signature derives the source address
+ */
+func verifyTransaction(tx mcm.TXENTRY) []verifyCheck {
+	checks := []verifyCheck{}
+
+	scheme := tx.GetSignatureScheme()
+	checks = append(checks, verifyCheck{
+		Name:   "signature scheme",
+		OK:     scheme == "wotsp",
+		Detail: fmt.Sprintf("scheme %q", scheme),
+	})
+
+	destinations := tx.GetDestinations()
+	checks = append(checks, verifyCheck{
+		Name:   "destination count",
+		OK:     int(tx.GetDestinationCount()) == len(destinations) && len(destinations) > 0,
+		Detail: fmt.Sprintf("header says %d, found %d", tx.GetDestinationCount(), len(destinations)),
+	})
+
+	sum := uint64(0)
+	overflow := false
+	for _, dst := range destinations {
+		amount := destinationAmount(dst)
+		if sum > math.MaxUint64-amount {
+			overflow = true
+		}
+		sum += amount
+	}
+	checks = append(checks, verifyCheck{
+		Name:   "send total",
+		OK:     !overflow && sum == tx.GetSendTotal(),
+		Detail: fmt.Sprintf("destinations sum to %d, send total is %d", sum, tx.GetSendTotal()),
+	})
+
+	send, change, fee := tx.GetSendTotal(), tx.GetChangeTotal(), tx.GetFee()
+	arithmeticOK := send <= math.MaxUint64-change && send+change <= math.MaxUint64-fee
+	checks = append(checks, verifyCheck{
+		Name:   "amount arithmetic",
+		OK:     arithmeticOK,
+		Detail: fmt.Sprintf("send %d + change %d + fee %d = source balance %d", send, change, fee, send+change+fee),
+	})
+
+	checks = append(checks, verifySignature(tx))
+	return checks
+}
+
+// verifySignature recomputes the WOTS public key from the signature and compares its address with the source address
+func verifySignature(tx mcm.TXENTRY) verifyCheck {
+	check := verifyCheck{Name: "signature"}
+
+	message := tx.GetMessageToSign()
+	pubSeed := tx.GetWotsSigPubSeed()
+	pk, err := wotsp.PkFromSigBytes(tx.GetWotsSignature(), message[:], pubSeed[:], tx.GetWotsSigAddresses())
+	if err != nil {
+		check.Detail = err.Error()
+		return check
+	}
+	derived := mcm.WotsAddressFromBytes(pk[:])
+	source := tx.GetSourceAddress()
+
+	check.OK = secure.Equal(derived.GetAddress(), source.GetAddress())
+	check.Detail = fmt.Sprintf("derived %x, source %x", derived.GetAddress(), source.GetAddress())
+	return check
+}
+
+/*
+ * runVerify implements the -verify mode
+ *
+ * Prints a report of each check and returns the process exit code:
+ * 0 if every check passed, 1 otherwise.
+ */
+func runVerify(input string) int {
+	txBytes, err := readSignedTransaction(input)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+
+	tx, err := decodeTransaction(txBytes)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+
+	source := tx.GetSourceAddress()
+	fmt.Printf("Source address: %x\n", source.GetAddress())
+	for i, dst := range tx.GetDestinations() {
+		fmt.Printf("Destination %d: %x amount %d memo %q\n", i, dst.Tag, destinationAmount(dst), strings.TrimRight(dst.GetReference(), "\x00"))
+	}
+
+	failed := 0
+	for _, check := range verifyTransaction(tx) {
+		status := "OK"
+		if !check.OK {
+			status = "FAIL"
+			failed++
+		}
+		fmt.Printf("[%s] %s: %s\n", status, check.Name, check.Detail)
+	}
+
+	if failed > 0 {
+		fmt.Printf("Verification failed: %d check(s) did not pass\n", failed)
+		return 1
+	}
+	fmt.Println("Transaction verified successfully")
+	return 0
+}
@@ -0,0 +1,47 @@
+package main
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// TestVerify checks a transaction signed by the tool, as its JSON output and as hex, then with one byte flipped
+func TestVerify(t *testing.T) {
+	source, change := newTestKey("source"), newTestKey("change")
+	signed := runTool3(t, "", []string{SecretEnvVar + "=" + source.secret}, sendArgs(source, change)...)
+	if signed.Code != 0 {
+		t.Fatalf("signing exited %d: %s", signed.Code, signed.Stderr)
+	}
+	path := filepath.Join(t.TempDir(), "tx.json")
+	if err := os.WriteFile(path, []byte(signed.Stdout), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	var request MeshAPISubmitRequest
+	if err := json.Unmarshal([]byte(signed.Stdout), &request); err != nil {
+		t.Fatal(err)
+	}
+	for _, input := range []string{path, request.SignedTransaction} {
+		r := runTool3(t, "", nil, "-verify", input)
+		if r.Code != 0 || !strings.Contains(r.Stdout, "Transaction verified successfully") {
+			t.Errorf("-verify %.40s exited %d: %s%s", input, r.Code, r.Stdout, r.Stderr)
+		}
+	}
+
+	raw, err := hex.DecodeString(strings.TrimPrefix(request.SignedTransaction, "0x"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	raw[len(raw)/2] ^= 0x01
+	r := runTool3(t, "", nil, "-verify", hex.EncodeToString(raw))
+	if r.Code != 1 || !strings.Contains(r.Stdout, "[FAIL] signature") {
+		t.Errorf("-verify of a tampered transaction exited %d: %s%s", r.Code, r.Stdout, r.Stderr)
+	}
+
+	if r := runTool3(t, "", nil, "-verify", "not hex"); r.Code != 1 || r.Stdout != "" {
+		t.Errorf("-verify of malformed input exited %d: %s", r.Code, r.Stdout)
+	}
+}
@@ -0,0 +1,259 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/NickP005/Vindax-MCM-tools/pkg/mcmaddr"
+)
+
+/*
+ * ConversionResult is the outcome of converting one address in either direction
+ *
+ * Fields:
+ * - Input: the address as given
+ * - Hex: 40 characters hex address
+ * - Base58: base58 address with checksum
+ * - Valid: true if the input was converted, Error explains why not otherwise
+ * - Line: input line number in batch mode
+ * - Suggestions: valid addresses one typo away from an invalid input, with -suggest
+ * - Resolution, ResolvedAddress, Balance, ResolveError: on-chain lookup, with -resolve
+ */
+type ConversionResult struct {
+	Input  string `json:"input"`
+	Hex    string `json:"hex,omitempty"`
+	Base58 string `json:"base58,omitempty"`
+	Valid  bool   `json:"valid"`
+	Line   int    `json:"line,omitempty"`
+	Error  string `json:"error,omitempty"`
+
+	Suggestions []string `json:"suggestions,omitempty"`
+
+	Resolution      string  `json:"resolution,omitempty"`
+	ResolvedAddress string  `json:"resolvedAddress,omitempty"`
+	Balance         *uint64 `json:"balance,omitempty"`
+	ResolveError    string  `json:"resolveError,omitempty"`
+}
+
+// trimHexPrefix removes an optional 0x or 0X prefix
+func trimHexPrefix(s string) string {
+	if strings.HasPrefix(s, "0x") || strings.HasPrefix(s, "0X") {
+		return s[2:]
+	}
+	return s
+}
+
+// formatHex renders a lowercase hex address, 0x prefixed if prefix is set
+func formatHex(h string, prefix bool) string {
+	if prefix {
+		return "0x" + h
+	}
+	return h
+}
+
+/*
+ * Convert converts an address given either as 40 hex characters (optional 0x
+ * prefix, any case) or as base58 with checksum, detecting the format of the input
+ *
+ * Returns a result with both representations filled in, the hex address
+ * lowercase without prefix, or an error if the
+ * input is neither a valid hex address nor a valid base58 address.
+ */
+func Convert(input string) (ConversionResult, error) {
+	input = strings.TrimSpace(input)
+	result := ConversionResult{Input: input}
+
+	tag, err := mcmaddr.Normalize(input)
+	if err != nil {
+		if trimHexPrefix(input) != input {
+			return result, &InvalidAddressError{Reason: fmt.Sprintf("invalid hex address: %v", err)}
+		}
+		report := ExplainBase58(input)
+		return result, &InvalidAddressError{
+			Checksum: report.Problem == ProblemChecksum,
+			Reason:   "not a 40 character hex address nor a valid base58 address: " + report.Error(),
+		}
+	}
+	result.Hex = mcmaddr.ToHex(tag)
+	result.Base58 = mcmaddr.To58(tag)
+	result.Valid = true
+	return result, nil
+}
+
+// ConvertHex converts a 40 characters hex address (optional 0x prefix, any case) to base58
+func ConvertHex(input string) (ConversionResult, error) {
+	result := ConversionResult{Input: input}
+	trimmed := trimHexPrefix(input)
+	if len(trimmed) != 40 {
+		return result, &InvalidAddressError{Reason: fmt.Sprintf("hex address must be 40 characters (20 bytes), got %d", len(trimmed))}
+	}
+	tag, err := hex.DecodeString(trimmed)
+	if err != nil {
+		return result, &InvalidAddressError{Reason: fmt.Sprintf("invalid hex format: %v", err)}
+	}
+	base58Addr, err := mcmaddr.EncodeTag(tag)
+	if err != nil {
+		return result, err
+	}
+	result.Hex = hex.EncodeToString(tag)
+	result.Base58 = base58Addr
+	result.Valid = true
+	return result, nil
+}
+
+// ConvertBase58 validates a base58 address and converts it to hex
+func ConvertBase58(input string) (ConversionResult, error) {
+	result := ConversionResult{Input: input}
+	tag, err := mcmaddr.DecodeTag(input)
+	if err != nil {
+		report := ExplainBase58(input)
+		return result, &InvalidAddressError{
+			Checksum: report.Problem == ProblemChecksum,
+			Reason:   "invalid base58 address: " + report.Error(),
+		}
+	}
+	result.Hex = hex.EncodeToString(tag)
+	result.Base58 = input
+	result.Valid = true
+	return result, nil
+}
+
+// ResultWriter receives conversion results in input order
+type ResultWriter interface {
+	Write(result ConversionResult) error
+	Close() error
+}
+
+// PlainWriter prints the converted form of each address, one per line, errors go to ErrOut
+type PlainWriter struct {
+	Out    io.Writer
+	ErrOut io.Writer
+}
+
+func (w *PlainWriter) Write(result ConversionResult) error {
+	if result.Error != "" {
+		_, err := fmt.Fprintf(w.ErrOut, "line %d: %s\n", result.Line, result.Error)
+		return err
+	}
+	// Print the representation the input was not in
+	output := result.Base58
+	if result.Input == result.Base58 {
+		output = result.Hex
+	}
+	_, err := fmt.Fprintln(w.Out, output+resolveSuffix(result))
+	return err
+}
+
+func (w *PlainWriter) Close() error { return nil }
+
+// HexPrefixWriter adds the 0x prefix to the hex address of every result before passing it on
+type HexPrefixWriter struct {
+	Next ResultWriter
+}
+
+func (w *HexPrefixWriter) Write(result ConversionResult) error {
+	if result.Hex != "" {
+		result.Hex = formatHex(result.Hex, true)
+	}
+	return w.Next.Write(result)
+}
+
+func (w *HexPrefixWriter) Close() error { return w.Next.Close() }
+
+// JSONArrayWriter streams results as a JSON array, one element per input line
+type JSONArrayWriter struct {
+	Out     io.Writer
+	Compact bool
+	count   int
+}
+
+func (w *JSONArrayWriter) Write(result ConversionResult) error {
+	data, err := marshalResult(result, w.Compact, "  ")
+	if err != nil {
+		return err
+	}
+	sep := ",\n  "
+	if w.count == 0 {
+		sep = "[\n  "
+	}
+	if w.Compact {
+		sep = ","
+		if w.count == 0 {
+			sep = "["
+		}
+	}
+	w.count++
+	_, err = fmt.Fprintf(w.Out, "%s%s", sep, data)
+	return err
+}
+
+func (w *JSONArrayWriter) Close() error {
+	end := "\n]"
+	if w.count == 0 {
+		end = "[]"
+	} else if w.Compact {
+		end = "]"
+	}
+	_, err := fmt.Fprintln(w.Out, end)
+	return err
+}
+
+// marshalResult encodes a result on one line when compact, indented under prefix otherwise
+func marshalResult(result ConversionResult, compact bool, prefix string) ([]byte, error) {
+	if compact {
+		return json.Marshal(result)
+	}
+	return json.MarshalIndent(result, prefix, "  ")
+}
+
+/*
+ * ConvertLines converts every line of r, passing one result per line to w in
+ * input order. Blank lines and lines starting with # are skipped. Lines that
+ * fail produce a result carrying the error and the line number, and do not
+ * stop the conversion. Once ctx is done no further line is read, but w is
+ * still closed so the output written so far stays well-formed.
+ *
+ * Returns the counts of converted and failed lines.
+ */
+func ConvertLines(ctx context.Context, r io.Reader, w ResultWriter) (BatchSummary, error) {
+	scanner := bufio.NewScanner(r)
+
+	summary := BatchSummary{}
+	lineNum := 0
+	for ctx.Err() == nil && scanner.Scan() {
+		lineNum++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		result, err := Convert(line)
+		if err != nil {
+			result.Error = err.Error()
+		}
+		summary.add(err)
+		result.Line = lineNum
+		if err := w.Write(result); err != nil {
+			return summary, err
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return summary, err
+	}
+	return summary, w.Close()
+}
+
+// isTerminal reports whether f is an interactive terminal rather than a pipe or file
+func isTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	if err != nil {
+		return false
+	}
+	return info.Mode()&os.ModeCharDevice != 0
+}
@@ -0,0 +1,102 @@
+package main
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/NickP005/Vindax-MCM-tools/internal/clitest"
+)
+
+// collectWriter keeps every result written
+type collectWriter struct {
+	results []ConversionResult
+	closed  bool
+}
+
+func (w *collectWriter) Write(result ConversionResult) error {
+	w.results = append(w.results, result)
+	return nil
+}
+
+func (w *collectWriter) Close() error {
+	w.closed = true
+	return nil
+}
+
+func TestConvertLinesMixed(t *testing.T) {
+	a, b := newTestAddress("a"), newTestAddress("b")
+	input := strings.Join([]string{
+		"# addresses",
+		a.base58,
+		"",
+		"0x" + strings.ToUpper(b.hex),
+		"not an address",
+		a.hex[:30],
+		badChecksum(t, b),
+	}, "\n")
+
+	w := &collectWriter{}
+	summary, err := ConvertLines(context.Background(), strings.NewReader(input), w)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !w.closed || summary.Converted != 2 || summary.Failed != 3 || summary.InvalidChecksum != 1 || summary.InvalidFormat != 2 {
+		t.Fatalf("summary %+v", summary)
+	}
+	lines := []int{2, 4, 5, 6, 7}
+	for i, result := range w.results {
+		if result.Line != lines[i] {
+			t.Errorf("result %d on line %d, want %d", i, result.Line, lines[i])
+		}
+	}
+	if w.results[0].Hex != a.hex || w.results[1].Base58 != b.base58 || w.results[1].Hex != b.hex {
+		t.Errorf("converted %+v and %+v", w.results[0], w.results[1])
+	}
+	for _, result := range w.results[2:] {
+		if result.Valid || result.Error == "" {
+			t.Errorf("invalid line accepted: %+v", result)
+		}
+	}
+}
+
+func TestBatchFileAndStdin(t *testing.T) {
+	a, b := newTestAddress("a"), newTestAddress("b")
+	lines := []string{a.base58, "zzz", b.hex}
+	want := a.hex + "\n" + b.base58 + "\n"
+
+	for name, r := range map[string]clitest.Result{
+		"file":  runTool4(t, "", "-file", writeLines(t, lines)),
+		"stdin": runTool4(t, strings.Join(lines, "\n")+"\n"),
+	} {
+		if r.Code != ExitOK {
+			t.Errorf("%s: exited %d with lines converted: %s", name, r.Code, r.Stderr)
+		}
+		if r.Stdout != want {
+			t.Errorf("%s: stdout %q, want %q", name, r.Stdout, want)
+		}
+		if !strings.Contains(r.Stderr, "line 2: not a 40 character hex address nor a valid base58 address") || !strings.Contains(r.Stderr, "Converted 2 addresses, 1 failed") {
+			t.Errorf("%s: stderr %q", name, r.Stderr)
+		}
+	}
+
+	// Only a run where every line fails is a failure
+	r := runTool4(t, "", "-file", writeLines(t, []string{"zzz", badChecksum(t, a)}))
+	if r.Code != ExitInvalidFormat || r.Stdout != "" {
+		t.Errorf("every line failing exited %d with %q", r.Code, r.Stdout)
+	}
+	r = runTool4(t, "", "-file", writeLines(t, []string{"# nothing"}))
+	if r.Code != ExitOK || r.Stdout != "" {
+		t.Errorf("no address exited %d with %q", r.Code, r.Stdout)
+	}
+}
+
+func TestSingleFlagsUnchanged(t *testing.T) {
+	a := newTestAddress("a")
+	if r := runTool4(t, "", "-base58", a.base58); r.Code != ExitOK || r.Stdout != a.hex+"\n" {
+		t.Errorf("-base58 exited %d with %q", r.Code, r.Stdout)
+	}
+	if r := runTool4(t, "", "-hex", a.hex); r.Code != ExitOK || r.Stdout != a.base58+"\n" {
+		t.Errorf("-hex exited %d with %q", r.Code, r.Stdout)
+	}
+}
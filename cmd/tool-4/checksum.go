@@ -0,0 +1,142 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/NickP005/Vindax-MCM-tools/pkg/mcmaddr"
+)
+
+// maxSuggestions bounds the number of candidates reported by SuggestCorrections
+const maxSuggestions = 10
+
+// Classes of base58 validation failures
+const (
+	ProblemNone     = ""
+	ProblemAlphabet = "invalid base58 character"
+	ProblemLength   = "wrong length"
+	ProblemChecksum = "checksum mismatch"
+)
+
+/*
+ * ChecksumReport details why a base58 address is or is not valid
+ *
+ * Fields:
+ * - DecodedLength: number of bytes the input decodes to (22 expected)
+ * - StoredChecksum: little-endian CRC16 in the last 2 bytes, only for 22 bytes inputs
+ * - ComputedChecksum: CRC16-XMODEM over the 20 bytes tag, only for 22 bytes inputs
+ * - BadChar, BadOffset: first character outside the base58 alphabet
+ * - Problem: one of the Problem* classes
+ */
+type ChecksumReport struct {
+	DecodedLength    int
+	StoredChecksum   uint16
+	ComputedChecksum uint16
+	BadChar          rune
+	BadOffset        int
+	Problem          string
+}
+
+// ExplainBase58 decodes addr and reports the checksum details and the class of failure, if any
+func ExplainBase58(addr string) ChecksumReport {
+	report := ChecksumReport{BadOffset: -1}
+	for i, c := range addr {
+		if !strings.ContainsRune(mcmaddr.Alphabet, c) {
+			report.BadChar, report.BadOffset = c, i
+			report.Problem = ProblemAlphabet
+			return report
+		}
+	}
+
+	var lengthErr *mcmaddr.LengthError
+	var checksumErr *mcmaddr.ChecksumError
+	tag, err := mcmaddr.DecodeTag(addr)
+	switch {
+	case errors.As(err, &lengthErr):
+		report.DecodedLength = lengthErr.Length
+		report.Problem = ProblemLength
+	case errors.As(err, &checksumErr):
+		report.DecodedLength = mcmaddr.EncodedLength
+		report.StoredChecksum = checksumErr.Stored
+		report.ComputedChecksum = checksumErr.Computed
+		report.Problem = ProblemChecksum
+	default:
+		report.DecodedLength = mcmaddr.EncodedLength
+		report.StoredChecksum = mcmaddr.Checksum(tag)
+		report.ComputedChecksum = report.StoredChecksum
+	}
+	return report
+}
+
+// Error summarizes the failure on one line, empty for a valid address
+func (r ChecksumReport) Error() string {
+	switch r.Problem {
+	case ProblemAlphabet:
+		return fmt.Sprintf("%s %q at offset %d", ProblemAlphabet, r.BadChar, r.BadOffset)
+	case ProblemLength:
+		return fmt.Sprintf("%s: decodes to %d bytes, expected 22 (20 bytes tag + 2 bytes checksum)", ProblemLength, r.DecodedLength)
+	case ProblemChecksum:
+		return fmt.Sprintf("%s: stored 0x%04x, computed CRC16-XMODEM 0x%04x over the 20 bytes tag", ProblemChecksum, r.StoredChecksum, r.ComputedChecksum)
+	}
+	return ""
+}
+
+// Details renders the report as labeled lines for the plain output
+func (r ChecksumReport) Details() string {
+	var b strings.Builder
+	if r.Problem == ProblemAlphabet {
+		fmt.Fprintf(&b, "Invalid character: %q at offset %d\n", r.BadChar, r.BadOffset)
+	} else {
+		fmt.Fprintf(&b, "Decoded length: %d bytes (expected 22)\n", r.DecodedLength)
+		if r.DecodedLength == 22 {
+			fmt.Fprintf(&b, "Stored checksum: 0x%04x\n", r.StoredChecksum)
+			fmt.Fprintf(&b, "Computed CRC16-XMODEM: 0x%04x\n", r.ComputedChecksum)
+		}
+	}
+	fmt.Fprintf(&b, "Problem: %s\n", r.Problem)
+	return b.String()
+}
+
+/*
+ * SuggestCorrections looks for valid addresses one typo away from addr
+ *
+ * Every single character substitution from the base58 alphabet and every
+ * transposition of adjacent characters is tried, so the search is bounded by
+ * len(addr) * 58 candidates. At most maxSuggestions candidates are returned.
+ * The results are guesses: a 16 bit checksum lets random candidates validate
+ * too, so a suggestion must be confirmed with the owner of the address.
+ */
+func SuggestCorrections(addr string) []string {
+	suggestions := []string{}
+	seen := map[string]bool{addr: true}
+	try := func(candidate string) bool {
+		if seen[candidate] {
+			return len(suggestions) < maxSuggestions
+		}
+		seen[candidate] = true
+		if mcmaddr.Validate(candidate) == nil {
+			suggestions = append(suggestions, candidate)
+		}
+		return len(suggestions) < maxSuggestions
+	}
+
+	chars := []byte(addr)
+	for i := 0; i+1 < len(chars); i++ {
+		swapped := append([]byte{}, chars...)
+		swapped[i], swapped[i+1] = swapped[i+1], swapped[i]
+		if !try(string(swapped)) {
+			return suggestions
+		}
+	}
+	for i := range chars {
+		for j := 0; j < len(mcmaddr.Alphabet); j++ {
+			substituted := append([]byte{}, chars...)
+			substituted[i] = mcmaddr.Alphabet[j]
+			if !try(string(substituted)) {
+				return suggestions
+			}
+		}
+	}
+	return suggestions
+}
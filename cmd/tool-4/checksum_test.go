@@ -0,0 +1,110 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/NickP005/Vindax-MCM-tools/pkg/mcmaddr"
+)
+
+func TestExplainValid(t *testing.T) {
+	a := newTestAddress("a")
+	report := ExplainBase58(a.base58)
+	if report.Problem != ProblemNone || report.Error() != "" || report.DecodedLength != 22 || report.StoredChecksum != report.ComputedChecksum {
+		t.Errorf("valid address report %+v", report)
+	}
+}
+
+func TestExplainAlphabet(t *testing.T) {
+	a := newTestAddress("a")
+	// 0, O, I and l are outside the base58 alphabet
+	for _, c := range []string{"0", "O", "I", "l", "+"} {
+		input := a.base58[:5] + c + a.base58[6:]
+		report := ExplainBase58(input)
+		if report.Problem != ProblemAlphabet || report.BadOffset != 5 || string(report.BadChar) != c {
+			t.Errorf("%q: report %+v", c, report)
+		}
+		if !strings.Contains(report.Details(), "Invalid character: '"+c+"' at offset 5") {
+			t.Errorf("%q: details %q", c, report.Details())
+		}
+	}
+}
+
+func TestExplainLength(t *testing.T) {
+	a := newTestAddress("a")
+	for _, input := range []string{a.base58[:10], a.base58 + a.base58[:5], "", "1"} {
+		report := ExplainBase58(input)
+		if report.Problem != ProblemLength || report.DecodedLength == 22 {
+			t.Errorf("%q: report %+v", input, report)
+		}
+		// The checksum fields are meaningless for a wrong length and are not printed
+		if details := report.Details(); strings.Contains(details, "checksum") || !strings.Contains(details, "Problem: wrong length") {
+			t.Errorf("%q: details %q", input, details)
+		}
+	}
+}
+
+func TestExplainChecksum(t *testing.T) {
+	a := newTestAddress("a")
+	input := badChecksum(t, a)
+	report := ExplainBase58(input)
+	if report.Problem != ProblemChecksum || report.DecodedLength != 22 || report.StoredChecksum == report.ComputedChecksum {
+		t.Fatalf("report %+v", report)
+	}
+	tag, err := mcmaddr.Normalize(a.hex)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The computed checksum is the one of the tag, which did not change
+	if report.ComputedChecksum != mcmaddr.Checksum(tag[:]) {
+		t.Errorf("computed 0x%04x, want 0x%04x", report.ComputedChecksum, mcmaddr.Checksum(tag[:]))
+	}
+	for _, want := range []string{"Decoded length: 22 bytes", "Stored checksum: 0x", "Computed CRC16-XMODEM: 0x", "Problem: checksum mismatch"} {
+		if !strings.Contains(report.Details(), want) {
+			t.Errorf("details %q lack %q", report.Details(), want)
+		}
+	}
+}
+
+func TestSuggestCorrections(t *testing.T) {
+	a := newTestAddress("a")
+	typo := badChecksum(t, a)
+	suggestions := SuggestCorrections(typo)
+	found := false
+	for _, s := range suggestions {
+		if mcmaddr.Validate(s) != nil {
+			t.Errorf("suggestion %s does not validate", s)
+		}
+		found = found || s == a.base58
+	}
+	if !found {
+		t.Errorf("suggestions %v miss the original %s", suggestions, a.base58)
+	}
+	if len(suggestions) > maxSuggestions {
+		t.Errorf("%d suggestions, at most %d", len(suggestions), maxSuggestions)
+	}
+
+	// A transposition is found
+	swapped := []byte(a.base58)
+	swapped[3], swapped[4] = swapped[4], swapped[3]
+	if string(swapped) != a.base58 && mcmaddr.Validate(string(swapped)) != nil {
+		found = false
+		for _, s := range SuggestCorrections(string(swapped)) {
+			found = found || s == a.base58
+		}
+		if !found {
+			t.Errorf("transposition of %s not corrected", a.base58)
+		}
+	}
+}
+
+func TestSuggestOutputIsLabeledAGuess(t *testing.T) {
+	a := newTestAddress("a")
+	r := runTool4(t, "", "-base58", badChecksum(t, a), "-suggest")
+	if r.Code != ExitInvalidChecksum {
+		t.Errorf("exited %d", r.Code)
+	}
+	if !strings.Contains(r.Stdout, "GUESSES assuming a single typo") || !strings.Contains(r.Stdout, "  "+a.base58+"\n") {
+		t.Errorf("stdout %q", r.Stdout)
+	}
+}
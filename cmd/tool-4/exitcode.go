@@ -0,0 +1,80 @@
+package main
+
+import (
+	"errors"
+)
+
+// Process exit codes, stable for use from shell scripts
+const (
+	ExitOK              = 0 // valid address, converted
+	ExitFailure         = 1 // I/O failure (unreadable file, unwritable report)
+	ExitInvalidChecksum = 2 // well-formed base58 address whose checksum does not match
+	ExitInvalidFormat   = 3 // wrong length, non-hex or non-base58 characters
+	ExitUsage           = 4 // invalid flags or flag combination
+	// ExitInterrupted is shutdown.ExitInterrupted: stopped by Ctrl-C or SIGTERM, the output is partial
+	ExitInterrupted = 130
+)
+
+// InvalidAddressError is returned when an input is not a valid address
+type InvalidAddressError struct {
+	// Checksum is true when the input is well-formed and only its checksum is wrong
+	Checksum bool
+	Reason   string
+}
+
+func (e *InvalidAddressError) Error() string { return e.Reason }
+
+// ExitCodeFor maps a conversion error to the process exit code
+func ExitCodeFor(err error) int {
+	if err == nil {
+		return ExitOK
+	}
+	var invalid *InvalidAddressError
+	if errors.As(err, &invalid) {
+		if invalid.Checksum {
+			return ExitInvalidChecksum
+		}
+		return ExitInvalidFormat
+	}
+	return ExitFailure
+}
+
+/*
+ * BatchSummary counts the outcome of a batch run
+ *
+ * Invalid addresses are split by class so the batch exit code can tell a
+ * mistyped address (checksum) from input that is not an address at all.
+ */
+type BatchSummary struct {
+	Converted       int
+	Failed          int
+	InvalidChecksum int
+	InvalidFormat   int
+}
+
+func (s *BatchSummary) add(err error) {
+	switch ExitCodeFor(err) {
+	case ExitOK:
+		s.Converted++
+		return
+	case ExitInvalidChecksum:
+		s.InvalidChecksum++
+	case ExitInvalidFormat:
+		s.InvalidFormat++
+	}
+	s.Failed++
+}
+
+// ExitCode returns ExitInvalidFormat if any line had a format problem, ExitInvalidChecksum if any had a checksum mismatch, ExitOK otherwise
+func (s BatchSummary) ExitCode() int {
+	if s.InvalidFormat > 0 {
+		return ExitInvalidFormat
+	}
+	if s.InvalidChecksum > 0 {
+		return ExitInvalidChecksum
+	}
+	if s.Failed > 0 {
+		return ExitFailure
+	}
+	return ExitOK
+}
@@ -0,0 +1,91 @@
+package main
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+)
+
+func TestExitCodeFor(t *testing.T) {
+	for _, tc := range []struct {
+		err  error
+		want int
+	}{
+		{nil, ExitOK},
+		{&InvalidAddressError{Checksum: true}, ExitInvalidChecksum},
+		{&InvalidAddressError{}, ExitInvalidFormat},
+		{errors.New("disk full"), ExitFailure},
+	} {
+		if got := ExitCodeFor(tc.err); got != tc.want {
+			t.Errorf("%v: got %d, want %d", tc.err, got, tc.want)
+		}
+	}
+}
+
+func TestScriptExitCodes(t *testing.T) {
+	a := newTestAddress("a")
+	typo := badChecksum(t, a)
+	for _, tc := range []struct {
+		name string
+		args []string
+		code int
+		// quietOut is the whole stdout with -quiet added
+		quietOut string
+	}{
+		{"valid base58", []string{"-base58", a.base58}, ExitOK, a.hex + "\n"},
+		{"valid hex", []string{"-hex", a.hex}, ExitOK, a.base58 + "\n"},
+		{"checksum", []string{"-base58", typo}, ExitInvalidChecksum, ""},
+		{"short base58", []string{"-base58", a.base58[:8]}, ExitInvalidFormat, ""},
+		{"bad character", []string{"-base58", "0" + a.base58[1:]}, ExitInvalidFormat, ""},
+		{"short hex", []string{"-hex", a.hex[:38]}, ExitInvalidFormat, ""},
+		{"non-hex", []string{"-hex", "zz" + a.hex[2:]}, ExitInvalidFormat, ""},
+		{"both", []string{"-base58", a.base58, "-hex", a.hex}, ExitUsage, ""},
+		{"normalize and base58", []string{"-normalize", a.hex, "-base58", a.base58}, ExitUsage, ""},
+		{"unknown flag", []string{"-frobnicate"}, ExitUsage, ""},
+		{"positional argument", []string{"-hex", a.hex, "extra"}, ExitUsage, ""},
+		{"missing file", []string{"-file", filepath.Join(t.TempDir(), "missing")}, ExitFailure, ""},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			r := runTool4(t, "", tc.args...)
+			if r.Code != tc.code {
+				t.Errorf("exited %d, want %d (%s%s)", r.Code, tc.code, r.Stdout, r.Stderr)
+			}
+			// The usage error path never prints a converted value
+			if tc.code == ExitUsage && r.Stdout != "" {
+				t.Errorf("usage error printed %q", r.Stdout)
+			}
+
+			quiet := runTool4(t, "", append(tc.args, "-quiet")...)
+			if quiet.Code != tc.code {
+				t.Errorf("-quiet exited %d, want %d", quiet.Code, tc.code)
+			}
+			if quiet.Stdout != tc.quietOut {
+				t.Errorf("-quiet printed %q, want %q", quiet.Stdout, tc.quietOut)
+			}
+			if tc.code != ExitUsage && quiet.Stderr != "" {
+				t.Errorf("-quiet explained on stderr: %q", quiet.Stderr)
+			}
+		})
+	}
+}
+
+func TestBatchSummaryExitCode(t *testing.T) {
+	var s BatchSummary
+	s.add(nil)
+	if s.ExitCode() != ExitOK {
+		t.Errorf("valid only: %d", s.ExitCode())
+	}
+	s.add(&InvalidAddressError{Checksum: true})
+	if s.ExitCode() != ExitInvalidChecksum {
+		t.Errorf("with a checksum failure: %d", s.ExitCode())
+	}
+	s.add(&InvalidAddressError{})
+	if s.ExitCode() != ExitInvalidFormat {
+		t.Errorf("with a format failure: %d", s.ExitCode())
+	}
+	other := BatchSummary{}
+	other.add(errors.New("io"))
+	if other.ExitCode() != ExitFailure {
+		t.Errorf("other failure: %d", other.ExitCode())
+	}
+}
@@ -0,0 +1,42 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/NickP005/Vindax-MCM-tools/internal/clitest"
+)
+
+func TestGolden(t *testing.T) {
+	a, b := newTestAddress("a"), newTestAddress("b")
+	file := writeLines(t, []string{"# payees", a.base58, b.hex, "zzz", badChecksum(t, a), "0x" + strings.ToUpper(b.hex)})
+	for _, tc := range []struct {
+		name  string
+		stdin string
+		args  []string
+	}{
+		{"hex", "", []string{"-hex", a.hex}},
+		{"hex-prefixed", "", []string{"-hex", a.hex, "-json", "-prefix-hex"}},
+		{"base58", "", []string{"-base58", a.base58}},
+		{"base58-json", "", []string{"-base58", a.base58, "-json"}},
+		{"normalize", "", []string{"-normalize", "0x" + strings.ToUpper(a.hex)}},
+		{"bad-checksum", "", []string{"-base58", badChecksum(t, a)}},
+		{"suggest", "", []string{"-base58", badChecksum(t, a), "-suggest"}},
+		{"invalid-hex", "", []string{"-hex", "1234"}},
+		{"quiet", "", []string{"-hex", "1234", "-quiet"}},
+		{"random", "", []string{"-random", "3", "-seed", "1715"}},
+		{"random-prefix", "", []string{"-random", "2", "-seed", "1715", "-prefix", "Qa", "-json", "-compact"}},
+		{"file", "", []string{"-file", file}},
+		{"file-json", "", []string{"-file", file, "-json"}},
+		{"stdin", a.hex + "\r\n" + b.base58 + "\n", nil},
+		{"validate-file", "", []string{"-validate-file", file}},
+		{"print-config", "", []string{"-print-config"}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			r := runTool4(t, tc.stdin, tc.args...)
+			r.Stdout = strings.ReplaceAll(r.Stdout, file, "addresses.txt")
+			r.Stderr = strings.ReplaceAll(r.Stderr, file, "addresses.txt")
+			clitest.CheckGolden(t, tc.name, r.Golden())
+		})
+	}
+}
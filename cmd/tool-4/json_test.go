@@ -0,0 +1,68 @@
+package main
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestJSONSingle(t *testing.T) {
+	a := newTestAddress("a")
+	r := runTool4(t, "", "-hex", a.hex, "-json")
+	want := "{\n  \"input\": \"" + a.hex + "\",\n  \"hex\": \"" + a.hex + "\",\n  \"base58\": \"" + a.base58 + "\",\n  \"valid\": true\n}\n"
+	if r.Code != ExitOK || r.Stdout != want {
+		t.Errorf("exited %d with %q, want %q", r.Code, r.Stdout, want)
+	}
+
+	r = runTool4(t, "", "-base58", a.base58, "-json", "-compact")
+	want = `{"input":"` + a.base58 + `","hex":"` + a.hex + `","base58":"` + a.base58 + `","valid":true}` + "\n"
+	if r.Code != ExitOK || r.Stdout != want {
+		t.Errorf("compact exited %d with %q, want %q", r.Code, r.Stdout, want)
+	}
+}
+
+func TestJSONInvalidIsAnObject(t *testing.T) {
+	r := runTool4(t, "", "-base58", "abc", "-json", "-compact")
+	var result ConversionResult
+	if err := json.Unmarshal([]byte(r.Stdout), &result); err != nil {
+		t.Fatalf("output is not JSON: %v\n%s", err, r.Stdout)
+	}
+	if result.Valid || result.Error == "" || result.Input != "abc" || result.Hex != "" {
+		t.Errorf("result %+v", result)
+	}
+	// The exit code still tells the class of the failure
+	if r.Code != ExitInvalidFormat || r.Stderr != "" {
+		t.Errorf("exited %d with stderr %q", r.Code, r.Stderr)
+	}
+}
+
+func TestJSONBatch(t *testing.T) {
+	a, b := newTestAddress("a"), newTestAddress("b")
+	input := a.hex + "\nbad\n" + b.base58 + "\n"
+
+	for _, compact := range []bool{false, true} {
+		args := []string{"-json"}
+		if compact {
+			args = append(args, "-compact")
+		}
+		r := runTool4(t, input, args...)
+		if r.Code != ExitOK {
+			t.Fatalf("exited %d: %s", r.Code, r.Stderr)
+		}
+		if lines := strings.Count(r.Stdout, "\n"); compact != (lines == 1) {
+			t.Errorf("compact %v: %d lines", compact, lines)
+		}
+		var results []ConversionResult
+		if err := json.Unmarshal([]byte(r.Stdout), &results); err != nil {
+			t.Fatalf("output is not a JSON array: %v\n%s", err, r.Stdout)
+		}
+		if len(results) != 3 || !results[0].Valid || results[0].Base58 != a.base58 ||
+			results[1].Valid || results[1].Error == "" || results[1].Line != 2 || results[2].Hex != b.hex {
+			t.Errorf("compact %v: results %+v", compact, results)
+		}
+	}
+
+	if r := runTool4(t, "", "-json", "-file", writeLines(t, []string{"# empty"})); r.Stdout != "[]\n" {
+		t.Errorf("empty batch %q", r.Stdout)
+	}
+}
@@ -0,0 +1,319 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/NickP005/Vindax-MCM-tools/pkg/config"
+	"github.com/NickP005/Vindax-MCM-tools/pkg/meshclient"
+	"github.com/NickP005/Vindax-MCM-tools/pkg/shutdown"
+)
+
+/*
+ * runValidateFile implements the -validate-file mode: every line is checked
+ * and reported, streaming, to the CSV report
+ *
+ * Returns the process exit code: ExitOK if every address is valid, otherwise
+ * the code of the worst invalid address found (see BatchSummary.ExitCode).
+ */
+func runValidateFile(ctx context.Context, inPath string, reportPath string, prefixHex bool, quiet bool) int {
+	in, err := os.Open(inPath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error opening file: %v\n", err)
+		return ExitFailure
+	}
+	defer in.Close()
+
+	out := os.Stdout
+	if reportPath != "" {
+		out, err = os.Create(reportPath)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error creating report: %v\n", err)
+			return ExitFailure
+		}
+		defer out.Close()
+	}
+
+	report := &ReportWriter{Out: out}
+	var writer ResultWriter = report
+	if prefixHex {
+		writer = &HexPrefixWriter{Next: report}
+	}
+	summary, err := ConvertLines(ctx, in, writer)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error validating addresses: %v\n", err)
+		return ExitFailure
+	}
+	if ctx.Err() != nil {
+		fmt.Fprintf(os.Stderr, "Interrupted: %s\n", report.Summary())
+		return ExitInterrupted
+	}
+	if !quiet {
+		fmt.Fprintf(os.Stderr, "Validation: %s\n", report.Summary())
+	}
+	return summary.ExitCode()
+}
+
+// runRandom implements the -random mode: base58 addresses one per line, or a JSON array with the hex as well
+func runRandom(ctx context.Context, count int, prefix string, source io.Reader, asJSON bool, compact bool, prefixHex bool) int {
+	var writer ResultWriter = &JSONArrayWriter{Out: os.Stdout, Compact: compact}
+	if prefixHex {
+		writer = &HexPrefixWriter{Next: writer}
+	}
+
+	i := 0
+	for ; i < count && ctx.Err() == nil; i++ {
+		result, err := RandomAddress(source, prefix)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			return ExitFailure
+		}
+		if !asJSON {
+			fmt.Println(result.Base58)
+			continue
+		}
+		if err := writer.Write(result); err != nil {
+			fmt.Fprintf(os.Stderr, "Error writing output: %v\n", err)
+			return ExitFailure
+		}
+	}
+	if asJSON {
+		if err := writer.Close(); err != nil {
+			fmt.Fprintf(os.Stderr, "Error writing output: %v\n", err)
+			return ExitFailure
+		}
+	}
+	if ctx.Err() != nil {
+		fmt.Fprintf(os.Stderr, "Interrupted: %d of %d addresses generated\n", i, count)
+		return ExitInterrupted
+	}
+	return ExitOK
+}
+
+// runBatch implements the -file and stdin modes and returns the process exit code
+func runBatch(ctx context.Context, input io.Reader, writer ResultWriter, quiet bool) int {
+	summary, err := ConvertLines(ctx, input, writer)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error reading input: %v\n", err)
+		return ExitFailure
+	}
+	if ctx.Err() != nil {
+		fmt.Fprintf(os.Stderr, "Interrupted: converted %d addresses, %d failed\n", summary.Converted, summary.Failed)
+		return ExitInterrupted
+	}
+	if summary.Failed > 0 && !quiet {
+		fmt.Fprintf(os.Stderr, "Converted %d addresses, %d failed\n", summary.Converted, summary.Failed)
+	}
+	// Fail only if nothing could be converted
+	if summary.Converted == 0 && summary.Failed > 0 {
+		return summary.ExitCode()
+	}
+	return ExitOK
+}
+
+// printSuggestions prints the -suggest candidates, labeled as guesses
+func printSuggestions(suggestions []string) {
+	if len(suggestions) == 0 {
+		fmt.Println("No valid address found one typo away")
+		return
+	}
+	fmt.Println("Possible corrections (GUESSES assuming a single typo, confirm with the address owner):")
+	for _, candidate := range suggestions {
+		fmt.Println("  " + candidate)
+	}
+}
+
+// usageError reports a flag misuse on stderr and exits with ExitUsage
+func usageError(message string) {
+	fmt.Fprintf(os.Stderr, "Error: %s\n", message)
+	flag.Usage()
+	os.Exit(ExitUsage)
+}
+
+// newMeshClient returns a Mesh API client identifying tool-4 in its User-Agent
+func newMeshClient(cfg *config.Config) *meshclient.MeshAPIClient {
+	client := meshclient.NewMeshAPIClient(cfg.API, nil)
+	cfg.Apply(client)
+	client.SetUserAgent("tool-4")
+	return client
+}
+
+func main() {
+	cfg, err := config.Load(config.Defaults())
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(ExitUsage)
+	}
+	base58Addr := flag.String("base58", "", "Base58 address to convert to hex")
+	hexAddr := flag.String("hex", "", "Hex address (40 characters) to convert to base58")
+	inputFile := flag.String("file", "", "File with one address per line, hex or base58 (auto-detected)")
+	jsonFlag := flag.Bool("json", false, "Output JSON with the input, both representations and validity (an array in batch mode)")
+	validateFile := flag.String("validate-file", "", "File with one address per line to validate, hex or base58")
+	reportFile := flag.String("report", "", "CSV report written by -validate-file (default stdout)")
+	suggest := flag.Bool("suggest", false, "For an invalid -base58 address, list valid addresses one typo away (a guess)")
+	prefixHex := flag.Bool("prefix-hex", false, "Output hex addresses with the 0x prefix")
+	normalize := flag.String("normalize", "", "Address in any representation (hex or base58) to print as the canonical hex and base58 pair")
+	compact := flag.Bool("compact", false, "Output compact JSON instead of indented JSON")
+	resolve := flag.Bool("resolve", false, "Resolve valid addresses on chain via the Mesh API (-api) and print the full address and balance")
+	cfg.APIFlags(flag.CommandLine, "Mesh API URL used by -resolve")
+	concurrency := flag.Int("concurrency", 8, "Maximum concurrent -resolve lookups in batch mode")
+	randomCount := flag.Int("random", 0, "Generate this many random valid test addresses")
+	randomPrefix := flag.String("prefix", "", "Base58 prefix the -random addresses must start with")
+	seed := flag.Int64("seed", 0, "Seed making -random deterministic (default: crypto/rand)")
+	quiet := flag.Bool("quiet", false, "Print only the converted value, nothing on failure; check the exit code")
+	printConfig := cfg.PrintFlag(flag.CommandLine)
+
+	// Flag errors exit with ExitUsage rather than the flag package's default 2
+	flag.CommandLine.Init(os.Args[0], flag.ContinueOnError)
+	if err := flag.CommandLine.Parse(os.Args[1:]); err != nil {
+		if err == flag.ErrHelp {
+			os.Exit(ExitOK)
+		}
+		os.Exit(ExitUsage)
+	}
+	if flag.NArg() > 0 {
+		usageError(fmt.Sprintf("unexpected argument %q", flag.Arg(0)))
+	}
+	if err := cfg.Parsed(flag.CommandLine); err != nil {
+		usageError(err.Error())
+	}
+	if *printConfig {
+		cfg.Print(os.Stdout)
+		os.Exit(ExitOK)
+	}
+
+	sig := shutdown.Notify()
+	defer sig.Stop()
+	ctx := sig.Context()
+
+	if *randomCount > 0 {
+		seeded := false
+		flag.Visit(func(f *flag.Flag) { seeded = seeded || f.Name == "seed" })
+		sig.Exit(runRandom(ctx, *randomCount, *randomPrefix, RandomSource(*seed, seeded), *jsonFlag, *compact, *prefixHex))
+	}
+
+	if *validateFile != "" {
+		sig.Exit(runValidateFile(ctx, *validateFile, *reportFile, *prefixHex, *quiet))
+	}
+
+	// Batch mode, from -file or from a piped stdin
+	if *inputFile != "" || (*base58Addr == "" && *hexAddr == "" && *normalize == "" && !isTerminal(os.Stdin)) {
+		input := io.Reader(os.Stdin)
+		if *inputFile != "" {
+			file, err := os.Open(*inputFile)
+			if err != nil {
+				if !*quiet {
+					fmt.Printf("Error opening file: %v\n", err)
+				}
+				os.Exit(ExitFailure)
+			}
+			defer file.Close()
+			input = file
+		}
+
+		errOut := io.Writer(os.Stderr)
+		if *quiet {
+			errOut = io.Discard
+		}
+		var writer ResultWriter = &PlainWriter{Out: os.Stdout, ErrOut: errOut}
+		if *jsonFlag {
+			writer = &JSONArrayWriter{Out: os.Stdout, Compact: *compact}
+		}
+		if *resolve {
+			writer = &ResolveWriter{Ctx: ctx, Next: writer, Client: newMeshClient(cfg), Concurrency: *concurrency}
+		}
+		if *prefixHex {
+			writer = &HexPrefixWriter{Next: writer}
+		}
+		sig.Exit(runBatch(ctx, input, writer, *quiet))
+	}
+
+	// Exactly one of -normalize, -base58 and -hex converts a single address
+	input := *normalize
+	convert := Convert
+	switch {
+	case *normalize != "" && (*base58Addr != "" || *hexAddr != ""):
+		usageError("-normalize cannot be combined with -base58 or -hex")
+	case *base58Addr != "" && *hexAddr != "", *normalize == "" && *base58Addr == "" && *hexAddr == "":
+		usageError("Provide either -base58 OR -hex, but not both or neither")
+	case *base58Addr != "":
+		input, convert = *base58Addr, ConvertBase58
+	case *hexAddr != "":
+		input, convert = *hexAddr, ConvertHex
+	}
+
+	result, err := convert(input)
+	if err == nil && *resolve {
+		Resolve(ctx, newMeshClient(cfg), &result)
+	}
+	if result.Hex != "" {
+		result.Hex = formatHex(result.Hex, *prefixHex)
+	}
+
+	// JSON reports invalid input in the output object rather than as an error message
+	if *jsonFlag {
+		if err != nil {
+			result.Error = err.Error()
+			if *suggest && *base58Addr != "" {
+				result.Suggestions = SuggestCorrections(*base58Addr)
+			}
+		}
+		data, marshalErr := marshalResult(result, *compact, "")
+		if marshalErr != nil {
+			fmt.Fprintf(os.Stderr, "Error encoding JSON: %v\n", marshalErr)
+			os.Exit(ExitFailure)
+		}
+		fmt.Println(string(data))
+		os.Exit(ExitCodeFor(err))
+	}
+
+	if err != nil {
+		if !*quiet {
+			printError(input, err, *base58Addr != "", *suggest)
+		}
+		os.Exit(ExitCodeFor(err))
+	}
+
+	// -quiet prints only the converted value, without the resolution
+	suffix := resolveSuffix(result)
+	if *quiet {
+		suffix = ""
+	}
+	switch {
+	case *normalize != "":
+		if *quiet {
+			fmt.Println(result.Hex + " " + result.Base58)
+			return
+		}
+		fmt.Printf("hex:    %s\n", result.Hex)
+		fmt.Printf("base58: %s\n", result.Base58)
+		if suffix != "" {
+			fmt.Println(suffix[1:])
+		}
+	case *base58Addr != "":
+		fmt.Println(result.Hex + suffix)
+	default:
+		fmt.Println(result.Base58 + suffix)
+	}
+}
+
+/*
+ * printError explains why a single address failed to convert
+ *
+ * A failed -base58 address gets the checksum report and, with suggest, the
+ * candidate corrections.
+ */
+func printError(input string, err error, isBase58 bool, suggest bool) {
+	if !isBase58 {
+		fmt.Printf("Error: %v\n", err)
+		return
+	}
+	fmt.Println("Error: Invalid base58 address (wrong length or invalid checksum)")
+	fmt.Print(ExplainBase58(input).Details())
+	if suggest {
+		printSuggestions(SuggestCorrections(input))
+	}
+}
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/NickP005/Vindax-MCM-tools/internal/clitest"
+	"github.com/NickP005/Vindax-MCM-tools/pkg/mcmaddr"
+)
+
+// tool4 is the binary built by TestMain, run by the tests driving the command line
+var tool4 string
+
+func TestMain(m *testing.M) {
+	dir, err := os.MkdirTemp("", "tool-4-test")
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	tool4 = filepath.Join(dir, "tool-4")
+	if out, err := exec.Command("go", "build", "-o", tool4, ".").CombinedOutput(); err != nil {
+		fmt.Fprintf(os.Stderr, "failed to build tool-4: %v\n%s", err, out)
+		os.RemoveAll(dir)
+		os.Exit(1)
+	}
+	code := m.Run()
+	os.RemoveAll(dir)
+	os.Exit(code)
+}
+
+// testAddress is the tag derived from a label, in both representations
+type testAddress struct {
+	hex    string
+	base58 string
+}
+
+// newTestAddress derives a tag from label
+func newTestAddress(label string) testAddress {
+	sum := sha256.Sum256([]byte("tool-4 test " + label))
+	var tag [mcmaddr.TagLength]byte
+	copy(tag[:], sum[:])
+	return testAddress{hex: mcmaddr.ToHex(tag), base58: mcmaddr.To58(tag)}
+}
+
+// badChecksum is a, with its base58 form altered so only the checksum fails
+func badChecksum(t testing.TB, a testAddress) string {
+	t.Helper()
+	for _, c := range mcmaddr.Alphabet {
+		candidate := a.base58[:len(a.base58)-1] + string(c)
+		var checksumErr *mcmaddr.ChecksumError
+		if errors.As(mcmaddr.Validate(candidate), &checksumErr) {
+			return candidate
+		}
+	}
+	t.Fatalf("no checksum-only variant of %s", a.base58)
+	return ""
+}
+
+// runTool4 runs the binary with args and stdin, in an environment without the caller's MCM_* variables or config file
+func runTool4(t testing.TB, stdin string, args ...string) clitest.Result {
+	t.Helper()
+	return clitest.Run(t, tool4, stdin, nil, args...)
+}
+
+// writeLines writes lines to a file of a temporary directory and returns its path
+func writeLines(t testing.TB, lines []string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "addresses.txt")
+	if err := os.WriteFile(path, []byte(strings.Join(lines, "\n")+"\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+// representations are the accepted spellings of an address
+func representations(a testAddress) []string {
+	return []string{
+		a.hex,
+		strings.ToUpper(a.hex),
+		"0x" + a.hex,
+		"0X" + strings.ToUpper(a.hex),
+		"0x" + a.hex[:20] + strings.ToUpper(a.hex[20:]),
+		a.base58,
+		"  " + a.base58 + "\t",
+	}
+}
+
+func TestRoundTripAllRepresentations(t *testing.T) {
+	for _, label := range []string{"a", "b", "c"} {
+		a := newTestAddress(label)
+		for _, input := range representations(a) {
+			result, err := Convert(input)
+			if err != nil || result.Hex != a.hex || result.Base58 != a.base58 {
+				t.Errorf("%q: got %+v, %v", input, result, err)
+				continue
+			}
+			// Converting either output back gives the same pair
+			for _, again := range []string{result.Hex, result.Base58, formatHex(result.Hex, true)} {
+				if back, err := Convert(again); err != nil || back.Hex != a.hex || back.Base58 != a.base58 {
+					t.Errorf("%q: round trip through %q gives %+v, %v", input, again, back, err)
+				}
+			}
+		}
+	}
+}
+
+func TestConvertHexAnyCaseAndPrefix(t *testing.T) {
+	a := newTestAddress("a")
+	for _, input := range representations(a)[:5] {
+		result, err := ConvertHex(input)
+		if err != nil || result.Hex != a.hex || result.Base58 != a.base58 {
+			t.Errorf("%q: got %+v, %v", input, result, err)
+		}
+	}
+}
+
+func TestNormalizeFlag(t *testing.T) {
+	a := newTestAddress("a")
+	for _, input := range representations(a) {
+		r := runTool4(t, "", "-normalize", input)
+		if want := "hex:    " + a.hex + "\nbase58: " + a.base58 + "\n"; r.Code != ExitOK || r.Stdout != want {
+			t.Errorf("%q: exited %d with %q", input, r.Code, r.Stdout)
+		}
+	}
+	r := runTool4(t, "", "-normalize", strings.ToUpper(a.hex), "-prefix-hex", "-quiet")
+	if r.Code != ExitOK || r.Stdout != "0x"+a.hex+" "+a.base58+"\n" {
+		t.Errorf("quiet prefixed exited %d with %q", r.Code, r.Stdout)
+	}
+	if r := runTool4(t, "", "-normalize", a.hex, "-hex", a.hex); r.Code != ExitUsage {
+		t.Errorf("-normalize with -hex exited %d", r.Code)
+	}
+}
+
+func TestPrefixHexOutput(t *testing.T) {
+	a, b := newTestAddress("a"), newTestAddress("b")
+	if r := runTool4(t, "", "-base58", a.base58, "-prefix-hex"); r.Stdout != "0x"+a.hex+"\n" {
+		t.Errorf("-base58 -prefix-hex printed %q", r.Stdout)
+	}
+	if r := runTool4(t, "", "-base58", a.base58); r.Stdout != a.hex+"\n" {
+		t.Errorf("-base58 printed %q", r.Stdout)
+	}
+
+	r := runTool4(t, b.base58+"\n0X"+strings.ToUpper(a.hex)+"\n", "-prefix-hex", "-json", "-compact")
+	var results []ConversionResult
+	if err := json.Unmarshal([]byte(r.Stdout), &results); err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 2 || results[0].Hex != "0x"+b.hex || results[1].Hex != "0x"+a.hex || results[1].Base58 != a.base58 {
+		t.Errorf("results %+v", results)
+	}
+}
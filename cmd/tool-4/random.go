@@ -0,0 +1,57 @@
+package main
+
+import (
+	crand "crypto/rand"
+	"fmt"
+	"io"
+	"math/rand"
+	"strings"
+
+	"github.com/NickP005/Vindax-MCM-tools/pkg/mcmaddr"
+)
+
+// maxPrefixAttempts bounds the search for one address matching -prefix
+const maxPrefixAttempts = 1 << 24
+
+/*
+ * RandomSource returns the byte source of -random: crypto/rand, or a
+ * deterministic generator when a seed is given so fixtures can be reproduced
+ */
+func RandomSource(seed int64, seeded bool) io.Reader {
+	if seeded {
+		return rand.New(rand.NewSource(seed))
+	}
+	return crand.Reader
+}
+
+/*
+ * RandomAddress generates a random 20 bytes tag whose base58 address starts
+ * with prefix (any address if prefix is empty)
+ *
+ * The tags are meaningless: nobody holds the keys behind them. The search
+ * gives up after maxPrefixAttempts tags, long prefixes are rarely reachable.
+ */
+func RandomAddress(source io.Reader, prefix string) (ConversionResult, error) {
+	for i, c := range prefix {
+		if !strings.ContainsRune(mcmaddr.Alphabet, c) {
+			return ConversionResult{}, fmt.Errorf("prefix has character %q at offset %d outside the base58 alphabet", c, i)
+		}
+	}
+
+	var tag [mcmaddr.TagLength]byte
+	for attempt := 0; attempt < maxPrefixAttempts; attempt++ {
+		if _, err := io.ReadFull(source, tag[:]); err != nil {
+			return ConversionResult{}, err
+		}
+		base58Addr := mcmaddr.To58(tag)
+		if strings.HasPrefix(base58Addr, prefix) {
+			return ConversionResult{
+				Input:  base58Addr,
+				Hex:    mcmaddr.ToHex(tag),
+				Base58: base58Addr,
+				Valid:  true,
+			}, nil
+		}
+	}
+	return ConversionResult{}, fmt.Errorf("no address with prefix %q found in %d attempts", prefix, maxPrefixAttempts)
+}
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strconv"
+)
+
+// Report statuses
+const (
+	StatusValid   = "valid"
+	StatusInvalid = "invalid"
+)
+
+/*
+ * ReportWriter writes a CSV validation report, one row per address as it is
+ * read: line, input, status, normalized hex, normalized base58 and the error
+ * reason. Close appends a summary line with the counts.
+ */
+type ReportWriter struct {
+	Out     io.Writer
+	csv     *csv.Writer
+	Valid   int
+	Invalid int
+}
+
+// start writes the header row on first use
+func (w *ReportWriter) start() error {
+	if w.csv != nil {
+		return nil
+	}
+	w.csv = csv.NewWriter(w.Out)
+	return w.csv.Write([]string{"line", "input", "status", "hex", "base58", "error"})
+}
+
+func (w *ReportWriter) Write(result ConversionResult) error {
+	if err := w.start(); err != nil {
+		return err
+	}
+
+	status := StatusValid
+	if result.Error != "" {
+		status = StatusInvalid
+		w.Invalid++
+	} else {
+		w.Valid++
+	}
+	row := []string{strconv.Itoa(result.Line), result.Input, status, result.Hex, result.Base58, result.Error}
+	if err := w.csv.Write(row); err != nil {
+		return err
+	}
+	// Flush every row so a large report is written as it goes
+	w.csv.Flush()
+	return w.csv.Error()
+}
+
+func (w *ReportWriter) Close() error {
+	if err := w.start(); err != nil {
+		return err
+	}
+	w.csv.Flush()
+	if err := w.csv.Error(); err != nil {
+		return err
+	}
+	_, err := fmt.Fprintf(w.Out, "# %s\n", w.Summary())
+	return err
+}
+
+// Summary returns the counts of the report on one line
+func (w *ReportWriter) Summary() string {
+	return fmt.Sprintf("total %d, valid %d, invalid %d", w.Valid+w.Invalid, w.Valid, w.Invalid)
+}
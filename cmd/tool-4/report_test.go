@@ -0,0 +1,132 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestReportWriter(t *testing.T) {
+	a, b := newTestAddress("a"), newTestAddress("b")
+	var out bytes.Buffer
+	report := &ReportWriter{Out: &out}
+	summary, err := ConvertLines(context.Background(), strings.NewReader(a.base58+"\nbad,input\n"+b.hex+"\n"), report)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if summary.Converted != 2 || summary.Failed != 1 {
+		t.Errorf("summary %+v", summary)
+	}
+	lines := strings.Split(out.String(), "\n")
+	if len(lines) != 6 || lines[5] != "" {
+		t.Fatalf("report:\n%s", out.String())
+	}
+	if lines[0] != "line,input,status,hex,base58,error" {
+		t.Errorf("header %q", lines[0])
+	}
+	if lines[1] != "1,"+a.base58+",valid,"+a.hex+","+a.base58+"," {
+		t.Errorf("valid row %q", lines[1])
+	}
+	// A field holding the delimiter is quoted
+	if !strings.HasPrefix(lines[2], `2,"bad,input",invalid,,,`) {
+		t.Errorf("invalid row %q", lines[2])
+	}
+	if lines[4] != "# total 3, valid 2, invalid 1" {
+		t.Errorf("summary line %q", lines[4])
+	}
+}
+
+func TestReportWriterEmpty(t *testing.T) {
+	var out bytes.Buffer
+	report := &ReportWriter{Out: &out}
+	if err := report.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if out.String() != "line,input,status,hex,base58,error\n# total 0, valid 0, invalid 0\n" {
+		t.Errorf("empty report %q", out.String())
+	}
+}
+
+func TestValidateFileExitCode(t *testing.T) {
+	a, b := newTestAddress("a"), newTestAddress("b")
+	reportPath := filepath.Join(t.TempDir(), "report.csv")
+	for _, tc := range []struct {
+		name  string
+		lines []string
+		code  int
+		sum   string
+	}{
+		{"all valid", []string{a.base58, b.hex}, ExitOK, "total 2, valid 2, invalid 0"},
+		{"checksum", []string{a.base58, badChecksum(t, b)}, ExitInvalidChecksum, "total 2, valid 1, invalid 1"},
+		// A format problem outranks a checksum one
+		{"format and checksum", []string{badChecksum(t, b), "xyz", a.hex}, ExitInvalidFormat, "total 3, valid 1, invalid 2"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			r := runTool4(t, "", "-validate-file", writeLines(t, tc.lines), "-report", reportPath)
+			if r.Code != tc.code || r.Stderr != "Validation: "+tc.sum+"\n" {
+				t.Errorf("exited %d with %q, want %d", r.Code, r.Stderr, tc.code)
+			}
+			data, err := os.ReadFile(reportPath)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !strings.HasSuffix(string(data), "# "+tc.sum+"\n") || strings.Count(string(data), "\n") != len(tc.lines)+2 {
+				t.Errorf("report:\n%s", data)
+			}
+		})
+	}
+
+	// Without -report the report goes to stdout, -quiet drops the summary on stderr
+	r := runTool4(t, "", "-validate-file", writeLines(t, []string{a.hex}), "-quiet", "-prefix-hex")
+	if r.Code != ExitOK || r.Stderr != "" || !strings.Contains(r.Stdout, ",valid,0x"+a.hex+",") {
+		t.Errorf("exited %d with %q and %q", r.Code, r.Stdout, r.Stderr)
+	}
+	if r := runTool4(t, "", "-validate-file", filepath.Join(t.TempDir(), "missing.txt")); r.Code != ExitFailure {
+		t.Errorf("missing file exited %d", r.Code)
+	}
+}
+
+// lineWriter signals each write on written
+type lineWriter struct {
+	written chan string
+}
+
+func (w *lineWriter) Write(p []byte) (int, error) {
+	w.written <- string(p)
+	return len(p), nil
+}
+
+func TestReportStreams(t *testing.T) {
+	a := newTestAddress("a")
+	reader, writer := io.Pipe()
+	out := &lineWriter{written: make(chan string, 16)}
+	done := make(chan error, 1)
+	go func() {
+		_, err := ConvertLines(context.Background(), reader, &ReportWriter{Out: out})
+		done <- err
+	}()
+
+	// Each row is written as soon as its line is read, while the input is still open
+	for i := 0; i < 3; i++ {
+		if _, err := io.WriteString(writer, a.base58+"\n"); err != nil {
+			t.Fatal(err)
+		}
+		deadline := time.After(5 * time.Second)
+		for row := ""; !strings.Contains(row, a.hex); {
+			select {
+			case row = <-out.written:
+			case <-deadline:
+				t.Fatalf("row %d not written before the input ended", i+1)
+			}
+		}
+	}
+	writer.Close()
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,151 @@
+package main
+
+import (
+	"context"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/NickP005/Vindax-MCM-tools/pkg/meshclient"
+)
+
+// Outcomes of an on-chain resolution
+const (
+	ResolveFound       = "found"
+	ResolveNotFound    = "not found"
+	ResolveUnavailable = "unavailable"
+)
+
+// THROTTLE_ROUNDS bounds how many times the lookups rate limited by the API are made again
+const THROTTLE_ROUNDS = 5
+
+// THROTTLE_WAIT is the wait before looking up again when the API gives no Retry-After
+const THROTTLE_WAIT = time.Second
+
+/*
+ * Resolve looks up the tag of a converted address via the Mesh tag_resolve
+ * method and records the outcome in result
+ *
+ * A network failure only marks the result as unavailable: it never turns a
+ * valid address into an invalid one. The error of an unavailable lookup is
+ * returned, nil otherwise.
+ */
+func Resolve(ctx context.Context, client *meshclient.MeshAPIClient, result *ConversionResult) error {
+	result.Resolution, result.ResolveError, result.ResolvedAddress, result.Balance = "", "", "", nil
+	tag, err := hex.DecodeString(trimHexPrefix(result.Hex))
+	if err != nil {
+		result.Resolution = ResolveUnavailable
+		result.ResolveError = err.Error()
+		return err
+	}
+	resolution, err := client.ResolveTag(ctx, tag)
+	switch {
+	case err == nil:
+		result.Resolution = ResolveFound
+		result.ResolvedAddress = resolution.AddressHex
+		result.Balance = &resolution.Amount
+	case errors.Is(err, meshclient.ErrTagNotFound):
+		result.Resolution = ResolveNotFound
+	default:
+		result.Resolution = ResolveUnavailable
+		result.ResolveError = err.Error()
+		return err
+	}
+	return nil
+}
+
+// resolveSuffix renders the resolution of a result, empty if no lookup was made
+func resolveSuffix(result ConversionResult) string {
+	switch result.Resolution {
+	case ResolveFound:
+		return fmt.Sprintf(" resolved: %s balance: %d", result.ResolvedAddress, *result.Balance)
+	case "":
+		return ""
+	}
+	return " " + result.Resolution
+}
+
+/*
+ * ResolveWriter resolves every valid address before passing results on to
+ * the wrapped writer.
+ *
+ * Results are buffered in groups of Concurrency, resolved in parallel and
+ * then written in input order, so at most Concurrency requests are in flight.
+ * Lookups the API rate limited are made again once the longest Retry-After
+ * of the group has passed, up to THROTTLE_ROUNDS times, rather than being
+ * reported unavailable at once. Once Ctx is done the lookups left are
+ * reported unavailable without waiting.
+ */
+type ResolveWriter struct {
+	Ctx         context.Context
+	Next        ResultWriter
+	Client      *meshclient.MeshAPIClient
+	Concurrency int
+	pending     []ConversionResult
+}
+
+func (w *ResolveWriter) Write(result ConversionResult) error {
+	w.pending = append(w.pending, result)
+	if len(w.pending) >= max(w.Concurrency, 1) {
+		return w.flush()
+	}
+	return nil
+}
+
+func (w *ResolveWriter) flush() error {
+	var lookups []int
+	for i := range w.pending {
+		if w.pending[i].Valid {
+			lookups = append(lookups, i)
+		}
+	}
+	for round := 0; len(lookups) > 0; round++ {
+		var mu sync.Mutex
+		var wg sync.WaitGroup
+		var throttled []int
+		wait := THROTTLE_WAIT
+		for _, i := range lookups {
+			wg.Add(1)
+			go func(i int) {
+				defer wg.Done()
+				retryAfter, ok := meshclient.Throttled(Resolve(w.Ctx, w.Client, &w.pending[i]))
+				if !ok {
+					return
+				}
+				mu.Lock()
+				throttled = append(throttled, i)
+				wait = max(wait, retryAfter)
+				mu.Unlock()
+			}(i)
+		}
+		wg.Wait()
+
+		if len(throttled) == 0 || round+1 >= THROTTLE_ROUNDS || w.Ctx.Err() != nil {
+			break
+		}
+		fmt.Fprintf(os.Stderr, "Mesh API rate limiting, waiting %v before %d lookups\n", wait, len(throttled))
+		select {
+		case <-time.After(wait):
+		case <-w.Ctx.Done():
+		}
+		lookups = throttled
+	}
+
+	for _, result := range w.pending {
+		if err := w.Next.Write(result); err != nil {
+			return err
+		}
+	}
+	w.pending = w.pending[:0]
+	return nil
+}
+
+func (w *ResolveWriter) Close() error {
+	if err := w.flush(); err != nil {
+		return err
+	}
+	return w.Next.Close()
+}
@@ -0,0 +1,100 @@
+package main
+
+import (
+	"context"
+	"encoding/hex"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/NickP005/Vindax-MCM-tools/pkg/meshclient"
+	"github.com/NickP005/Vindax-MCM-tools/pkg/meshmock"
+)
+
+// fund gives the tag of a a full address and a balance on the mock, returning the full address
+func fund(t *testing.T, mock *meshmock.Server, a testAddress, balance uint64) string {
+	t.Helper()
+	tag, err := hex.DecodeString(a.hex)
+	if err != nil {
+		t.Fatal(err)
+	}
+	full := "0x" + a.hex + strings.Repeat("ab", 20)
+	mock.SetAccount(tag, full, balance)
+	return full
+}
+
+func TestResolve(t *testing.T) {
+	mock := meshmock.New()
+	defer mock.Close()
+	client := meshclient.NewMeshAPIClient(mock.URL(), nil)
+	known, unknown := newTestAddress("known"), newTestAddress("unknown")
+	full := fund(t, mock, known, 7_000)
+
+	result, _ := Convert(known.base58)
+	if err := Resolve(context.Background(), client, &result); err != nil {
+		t.Fatal(err)
+	}
+	if result.Resolution != ResolveFound || result.ResolvedAddress != full || result.Balance == nil || *result.Balance != 7_000 {
+		t.Errorf("known: %+v", result)
+	}
+	if resolveSuffix(result) != " resolved: "+full+" balance: 7000" {
+		t.Errorf("suffix %q", resolveSuffix(result))
+	}
+
+	result, _ = Convert(unknown.hex)
+	if err := Resolve(context.Background(), client, &result); err != nil || result.Resolution != ResolveNotFound || resolveSuffix(result) != " not found" {
+		t.Errorf("unknown: %+v, %v", result, err)
+	}
+
+	mock.Fail("/call", meshmock.Fault{Status: 500, Body: `{"code":1,"message":"internal"}`})
+	result, _ = Convert(known.hex)
+	if err := Resolve(context.Background(), client, &result); err == nil || result.Resolution != ResolveUnavailable || result.ResolveError == "" || result.Balance != nil {
+		t.Errorf("failing API: %+v, %v", result, err)
+	}
+}
+
+func TestResolveDoesNotChangeTheExitCode(t *testing.T) {
+	mock := meshmock.New()
+	a := newTestAddress("a")
+	url := mock.URL()
+	mock.Close()
+
+	r := runTool4(t, "", "-hex", a.hex, "-resolve", "-api", url)
+	if r.Code != ExitOK || r.Stdout != a.base58+" unavailable\n" {
+		t.Errorf("unreachable API: exited %d with %q", r.Code, r.Stdout)
+	}
+	r = runTool4(t, "", "-base58", badChecksum(t, a), "-resolve", "-api", url)
+	if r.Code != ExitInvalidChecksum {
+		t.Errorf("invalid address exited %d", r.Code)
+	}
+}
+
+func TestResolveBatch(t *testing.T) {
+	mock := meshmock.New()
+	defer mock.Close()
+	var lines, want []string
+	for i, label := range []string{"a", "b", "c", "d", "e"} {
+		a := newTestAddress(label)
+		if i%2 == 0 {
+			full := fund(t, mock, a, uint64(i))
+			want = append(want, a.base58+" resolved: "+full+" balance: "+strconv.Itoa(i))
+		} else {
+			want = append(want, a.base58+" not found")
+		}
+		lines = append(lines, a.hex)
+	}
+	lines = append(lines, "bad")
+	// One lookup is rate limited, and made again after the Retry-After
+	mock.Fail("/call", meshmock.Fault{Status: 429, RetryAfter: "1", Body: `{"code":429,"message":"slow down","retriable":true}`})
+
+	r := runTool4(t, strings.Join(lines, "\n")+"\n", "-resolve", "-api", mock.URL(), "-concurrency", "3")
+	if r.Code != ExitOK {
+		t.Fatalf("exited %d: %s", r.Code, r.Stderr)
+	}
+	if r.Stdout != strings.Join(want, "\n")+"\n" {
+		t.Errorf("stdout:\n%s\nwant, in input order:\n%s", r.Stdout, strings.Join(want, "\n"))
+	}
+	if !strings.Contains(r.Stderr, "Mesh API rate limiting, waiting 1s before 1 lookups") {
+		t.Errorf("stderr %q", r.Stderr)
+	}
+}
@@ -0,0 +1,72 @@
+//go:build !windows
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/NickP005/Vindax-MCM-tools/internal/clitest"
+	"github.com/NickP005/Vindax-MCM-tools/pkg/mcmaddr"
+)
+
+// endlessLines is an input repeating a line forever, which only a signal stops reading
+type endlessLines struct {
+	line string
+	off  int
+}
+
+func (r *endlessLines) Read(p []byte) (int, error) {
+	n := 0
+	for n < len(p) {
+		c := copy(p[n:], r.line[r.off:])
+		n += c
+		r.off = (r.off + c) % len(r.line)
+	}
+	return n, nil
+}
+
+// TestInterruptRandom stops a -random run far from its count: the JSON array written so far is closed and the count reported
+func TestInterruptRandom(t *testing.T) {
+	r := clitest.InterruptWhen(t, tool4, nil, func(stdout string) bool { return strings.Count(stdout, `"base58"`) >= 10 },
+		"-random", "100000000", "-seed", "1717", "-json", "-compact")
+	if r.Code != ExitInterrupted {
+		t.Fatalf("exit %d, want %d: %s", r.Code, ExitInterrupted, r.Stderr)
+	}
+	var results []ConversionResult
+	if err := json.Unmarshal([]byte(r.Stdout), &results); err != nil {
+		t.Fatalf("partial output is not a JSON array: %v\n%.200s", err, r.Stdout)
+	}
+	for _, result := range results {
+		if mcmaddr.Validate(result.Base58) != nil {
+			t.Fatalf("invalid address %+v", result)
+		}
+	}
+	if want := fmt.Sprintf("Interrupted: %d of 100000000 addresses generated\n", len(results)); r.Stderr != want {
+		t.Errorf("stderr %q, want %q", r.Stderr, want)
+	}
+}
+
+// TestInterruptBatch stops the conversion of an endless stdin: every line written is whole, and counted
+func TestInterruptBatch(t *testing.T) {
+	a := newTestAddress("a")
+	r := clitest.InterruptWhen(t, tool4, &endlessLines{line: a.hex + "\n"}, func(stdout string) bool { return strings.Count(stdout, "\n") >= 10 })
+	if r.Code != ExitInterrupted {
+		t.Fatalf("exit %d, want %d: %s", r.Code, ExitInterrupted, r.Stderr)
+	}
+	out := strings.Split(r.Stdout, "\n")
+	if out[len(out)-1] != "" {
+		t.Errorf("partial last line %q", out[len(out)-1])
+	}
+	out = out[:len(out)-1]
+	for _, line := range out {
+		if line != a.base58 {
+			t.Fatalf("line %q, want %s", line, a.base58)
+		}
+	}
+	if want := fmt.Sprintf("Interrupted: converted %d addresses, 0 failed\n", len(out)); r.Stderr != want {
+		t.Errorf("stderr %q, want %q", r.Stderr, want)
+	}
+}
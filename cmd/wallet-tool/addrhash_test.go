@@ -0,0 +1,66 @@
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"math/rand"
+	"testing"
+
+	"github.com/NickP005/Vindax-MCM-tools/pkg/wotsp"
+	mcm "github.com/NickP005/go_mcminterface"
+)
+
+// TestAddrHashMatchesInterface checks wotsp.AddrHashFromPK against the address go_mcminterface builds, which it replaced in the index search
+func TestAddrHashMatchesInterface(t *testing.T) {
+	rng := rand.New(rand.NewSource(1662))
+	keys := make([][]byte, 0, 40)
+	for i := 0; i < 8; i++ {
+		keypair := wotsp.KeychainKeygen(sha256.Sum256([]byte("wallet-tool test")), uint64(i))
+		keys = append(keys, keypair.PublicKey[:])
+		keypair.Wipe()
+	}
+	for i := 0; i < 32; i++ {
+		pk := make([]byte, wotsp.SigSize)
+		rng.Read(pk)
+		keys = append(keys, pk)
+	}
+
+	for i, pk := range keys {
+		hash, err := wotsp.AddrHashFromPK(pk)
+		if err != nil {
+			t.Fatal(err)
+		}
+		address := mcm.WotsAddressFromBytes(pk)
+		if !bytes.Equal(address.GetAddress(), hash[:]) {
+			t.Errorf("key %d: address hash %x, go_mcminterface %x", i, hash, address.GetAddress())
+		}
+		if !bytes.Equal(mcm.AddrHashGenerate(pk), hash[:]) {
+			t.Errorf("key %d: AddrHashGenerate differs", i)
+		}
+		// A full 2208 bytes address hashes as its public key
+		full := append(append([]byte(nil), pk...), make([]byte, 64)...)
+		if fullHash, _ := wotsp.AddrHashFromPK(full); fullHash != hash {
+			t.Errorf("key %d: full address hashes differently", i)
+		}
+	}
+}
+
+func BenchmarkAddrHash(b *testing.B) {
+	keypair := wotsp.KeychainKeygen(sha256.Sum256([]byte("wallet-tool test")), 0)
+	defer keypair.Wipe()
+	pk := keypair.PublicKey[:]
+	for _, impl := range []struct {
+		name string
+		hash func() []byte
+	}{
+		{"wotsp", func() []byte { h, _ := wotsp.AddrHashFromPK(pk); return h[:] }},
+		{"go_mcminterface", func() []byte { a := mcm.WotsAddressFromBytes(pk); return a.GetAddress() }},
+	} {
+		b.Run(impl.name, func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				impl.hash()
+			}
+		})
+	}
+}
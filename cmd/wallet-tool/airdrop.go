@@ -0,0 +1,102 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/NickP005/Vindax-MCM-tools/pkg/mcmaddr"
+	"github.com/NickP005/Vindax-MCM-tools/pkg/meshclient"
+)
+
+/*
+ * ReadAirdropList reads the addresses of an airdrop, one per line, each
+ * paid amountToSend
+ *
+ * Blank lines and lines starting with # are skipped. An address listed
+ * again, in the same or another form, is paid once: the repeats are only
+ * reported. Otherwise the entries are checked as ReadEntriesCSV checks
+ * those of a payout file, into the same report.
+ */
+func ReadAirdropList(ctx context.Context, client *meshclient.MeshAPIClient, filename string, amountToSend uint64, own [mcmaddr.TagLength]byte, requireExisting bool) (*ValidationReport, error) {
+	file, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	report := &ValidationReport{}
+	var parsed []parsedEntry
+	// Line of the first occurrence of each address
+	seen := make(map[[mcmaddr.TagLength]byte]int)
+	duplicates := 0
+
+	fmt.Println("Validating airdrop addresses:")
+	fmt.Println("-----------------------------")
+
+	scanner := bufio.NewScanner(file)
+	for lineNum := 1; scanner.Scan(); lineNum++ {
+		address := strings.TrimSpace(scanner.Text())
+		if address == "" || strings.HasPrefix(address, "#") {
+			continue
+		}
+		record := []string{address}
+		if fields := strings.Fields(address); len(fields) > 1 {
+			report.reject(lineNum, "record", address, ReasonBadRecord, record,
+				fmt.Errorf("expected one address per line, got %d fields", len(fields)))
+			continue
+		}
+
+		tag, err := mcmaddr.Normalize(address)
+		if err != nil {
+			report.reject(lineNum, "address", address, addressReason(err), record, err)
+			continue
+		}
+		if tag == own {
+			report.reject(lineNum, "address", address, ReasonOwnAddress, record, errOwnAddress)
+			continue
+		}
+		if first, ok := seen[tag]; ok {
+			fmt.Printf("Line %d: %s already listed on line %d, paid once\n", lineNum, address, first)
+			duplicates++
+			continue
+		}
+		seen[tag] = lineNum
+
+		parsed = append(parsed, parsedEntry{
+			entry: SendEntry{
+				Address:      mcmaddr.To58(tag),
+				AddressBin:   tag[:],
+				AmountToSend: amountToSend,
+			},
+			tag:    tag,
+			line:   lineNum,
+			record: record,
+		})
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	checkDestinations(ctx, client, report, parsed, requireExisting)
+	if duplicates > 0 {
+		fmt.Printf("%d duplicate addresses dropped\n", duplicates)
+	}
+	fmt.Println("-----------------------------")
+	return report, nil
+}
+
+// splitEntries splits entries in batches of at most size, in order, each paid by one transaction
+func splitEntries(entries []SendEntry, size int) [][]SendEntry {
+	var batches [][]SendEntry
+	for len(entries) > size {
+		batches = append(batches, entries[:size])
+		entries = entries[size:]
+	}
+	if len(entries) > 0 {
+		batches = append(batches, entries)
+	}
+	return batches
+}
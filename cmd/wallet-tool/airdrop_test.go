@@ -0,0 +1,264 @@
+package main
+
+import (
+	"context"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/NickP005/Vindax-MCM-tools/pkg/mcmaddr"
+	"github.com/NickP005/Vindax-MCM-tools/pkg/meshclient"
+	"github.com/NickP005/Vindax-MCM-tools/pkg/meshmock"
+	"github.com/NickP005/Vindax-MCM-tools/pkg/txentry"
+	"github.com/NickP005/Vindax-MCM-tools/pkg/walletstore"
+)
+
+// airdropTag is the tag of the i-th address of an airdrop list
+func airdropTag(i int) [mcmaddr.TagLength]byte {
+	var tag [mcmaddr.TagLength]byte
+	for j := range tag {
+		tag[j] = 0x5a
+	}
+	tag[0], tag[1], tag[2] = 0xd0, byte(i>>8), byte(i)
+	return tag
+}
+
+// writeList writes an airdrop list file of lines
+func writeList(t *testing.T, lines ...string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "airdrop.txt")
+	if err := os.WriteFile(path, []byte(strings.Join(lines, "\n")+"\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// TestReadAirdropList reads a list with repeated addresses, the wallet's own and malformed lines
+func TestReadAirdropList(t *testing.T) {
+	mock := meshmock.New()
+	defer mock.Close()
+	client := meshclient.NewMeshAPIClient(mock.URL(), nil)
+	own, _ := mcmaddr.Normalize("cr5m3GobqYe6BDY1jqdSNJMYsjADL5")
+	a, b, c := airdropTag(1), airdropTag(2), airdropTag(3)
+	badChecksum := []byte(mcmaddr.To58(c))
+	badChecksum[len(badChecksum)-1] ^= 1
+	path := writeList(t,
+		"# spring airdrop",
+		mcmaddr.To58(a),
+		"",
+		"0x"+mcmaddr.ToHex(b),
+		"  "+strings.ToUpper(mcmaddr.ToHex(a))+"  ",
+		"cr5m3GobqYe6BDY1jqdSNJMYsjADL5",
+		string(badChecksum),
+		mcmaddr.To58(c)+" 1000",
+		mcmaddr.To58(b),
+		mcmaddr.To58(c),
+	)
+
+	var report *ValidationReport
+	var err error
+	out := captureStdout(t, func() { report, err = ReadAirdropList(context.Background(), client, path, 1000, own, false) })
+	if err != nil {
+		t.Fatal(err)
+	}
+	var entries []string
+	for _, entry := range report.Entries {
+		if entry.AmountToSend != 1000 || entry.Memo != "" {
+			t.Errorf("entry %+v", entry)
+		}
+		entries = append(entries, entry.Address)
+	}
+	if want := []string{mcmaddr.To58(a), mcmaddr.To58(b), mcmaddr.To58(c)}; strings.Join(entries, " ") != strings.Join(want, " ") {
+		t.Errorf("entries %q, want %q", entries, want)
+	}
+
+	var rejected []string
+	for _, e := range report.Errors {
+		rejected = append(rejected, fmt.Sprintf("%d %s", e.Line, e.Reason))
+	}
+	if want := "6 OwnAddress, 7 BadChecksum, 8 BadRecord"; strings.Join(rejected, ", ") != want {
+		t.Errorf("rejected %q, want %q", rejected, want)
+	}
+	if report.Valid() || report.ExitCode() != EXIT_INVALID_ENTRIES {
+		t.Errorf("valid %v, exit %d", report.Valid(), report.ExitCode())
+	}
+	for _, want := range []string{"Line 5: " + strings.ToUpper(mcmaddr.ToHex(a)) + " already listed on line 2, paid once", "Line 9:", "2 duplicate addresses dropped"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("no %q in:\n%s", want, out)
+		}
+	}
+}
+
+/*
+ * TestAirdrop pays 1000 nanoMCM to 300 addresses, listed with repeats: the
+ * payout of a wallet short of 1 nanoMCM is refused before anything is
+ * signed, then a funded one takes two transactions, each address mapped to
+ * the one paying it in the receipt
+ */
+func TestAirdrop(t *testing.T) {
+	mock := meshmock.New()
+	defer mock.Close()
+	mock.MineBlock()
+	client := meshclient.NewMeshAPIClient(mock.URL(), nil)
+	requests := &requestCounter{counts: make(map[string]int), pending: make(chan struct{})}
+	client.SetHooks(requests)
+
+	dir := t.TempDir()
+	walletFile := filepath.Join(dir, "wallet.json")
+	if err := walletstore.Save(walletFile, &walletstore.WalletCache{SecretKey: strings.Repeat("17", 32), Index: 5}); err != nil {
+		t.Fatal(err)
+	}
+	var cache *walletstore.WalletCache
+	var err error
+	captureStdout(t, func() { cache, err = ReadWalletCache(walletFile) })
+	if err != nil {
+		t.Fatal(err)
+	}
+	keychain, _ := cache.Keychain()
+	defer keychain.Wipe()
+	walletTag, hash := keychain.Tag(), keychain.AddrHash(5)
+	wallet := "0x" + hex.EncodeToString(walletTag[:]) + hex.EncodeToString(hash[:])
+	own, _ := mcmaddr.Normalize(cache.RefillAddress)
+
+	var lines []string
+	for i := 0; i < 300; i++ {
+		lines = append(lines, mcmaddr.To58(airdropTag(i)))
+		if i%30 == 0 {
+			lines = append(lines, mcmaddr.ToHex(airdropTag(i/2)))
+		}
+	}
+	path := writeList(t, lines...)
+	var report *ValidationReport
+	captureStdout(t, func() { report, err = ReadAirdropList(context.Background(), client, path, 1000, own, false) })
+	if err != nil || !report.Valid() || len(report.Entries) != 300 {
+		t.Fatalf("%d entries, %d errors, %v", len(report.Entries), len(report.Errors), err)
+	}
+
+	pay := func(balance uint64) (payoutResult, *Receipt) {
+		t.Helper()
+		mock.SetAccount(walletTag[:], wallet, balance)
+		receipt, err := ReadReceipt(ReceiptPath(filepath.Join(dir, "receipts"), path))
+		if err != nil {
+			t.Fatal(err)
+		}
+		entries, _ := receipt.Unpaid(report.Entries)
+		p := &payout{
+			client: client, cache: cache, keychain: keychain, receipt: receipt,
+			walletCacheFile: walletFile, payoutFile: path, fee: 500, confirmations: 1, timeout: 1,
+			interval: 20 * time.Millisecond,
+		}
+		p.hashes, _ = walletstore.LoadDerivationCache(filepath.Join(dir, "hashes.json"), keychain.Fingerprint())
+
+		done := make(chan struct{})
+		defer close(done)
+		go func() {
+			for {
+				select {
+				case <-done:
+					return
+				case <-time.After(50 * time.Millisecond):
+					mock.MineBlock()
+				}
+			}
+		}()
+		var result payoutResult
+		captureStdout(t, func() {
+			currentIndex, tag, balance, err := VerifyCurrentIndex(context.Background(), client, keychain, cache.Index)
+			if err != nil {
+				t.Errorf("verifying the index: %v", err)
+				return
+			}
+			result = p.payEntries(context.Background(), entries, currentIndex, tag, balance)
+		})
+		return result, receipt
+	}
+
+	// 300 entries of 1000 and two fees of 500 need 301000
+	if result, receipt := pay(300999); result.Exit != 1 || len(receipt.Entries) != 0 {
+		t.Fatalf("short of 1 nanoMCM: %+v, %d entries in the receipt", result, len(receipt.Entries))
+	}
+	saved, _ := walletstore.Read(walletFile)
+	if n := requests.count("/construction/submit"); n != 0 || saved.Index != 5 || saved.Pending != nil {
+		t.Fatalf("short of 1 nanoMCM: %d submits, index %d, pending %+v", n, saved.Index, saved.Pending)
+	}
+	if _, err := os.Stat(ReceiptPath(filepath.Join(dir, "receipts"), path)); !os.IsNotExist(err) {
+		t.Errorf("receipt written: %v", err)
+	}
+
+	result, receipt := pay(400000)
+	if result.Exit != 0 || !result.Confirmed || !receipt.Confirmed() {
+		t.Fatalf("result %+v", result)
+	}
+	if receipt.Transactions() != 2 || len(mock.Submitted()) != 2 {
+		t.Fatalf("%d transactions, %d submitted", receipt.Transactions(), len(mock.Submitted()))
+	}
+	txIDs := map[int]string{}
+	for i, e := range receipt.Entries {
+		n := 1 + i/txentry.MaxDestinations
+		if e.Address != mcmaddr.To58(airdropTag(i)) || e.Amount != 1000 || e.Transaction != n || e.TxID == "" {
+			t.Fatalf("receipt entry %d: %+v", i, e)
+		}
+		if txIDs[n] == "" {
+			txIDs[n] = e.TxID
+		} else if e.TxID != txIDs[n] {
+			t.Errorf("receipt entry %d: txid %s, transaction %d is %s", i, e.TxID, n, txIDs[n])
+		}
+		tag := airdropTag(i)
+		if balance, _ := mock.Balance(tag[:]); balance != 1000 {
+			t.Errorf("%s holds %d", e.Address, balance)
+		}
+	}
+	if txIDs[1] == txIDs[2] {
+		t.Errorf("one txid for both transactions: %s", txIDs[1])
+	}
+	if balance, _ := mock.Balance(walletTag[:]); balance != 400000-301000 {
+		t.Errorf("wallet balance %d", balance)
+	}
+
+	var printed strings.Builder
+	receipt.Print(&printed)
+	for _, want := range []string{"300 entries, 2 transactions:", mcmaddr.To58(airdropTag(299)) + "  1000           2   " + txIDs[2]} {
+		if !strings.Contains(printed.String(), want) {
+			t.Errorf("no %q in:\n%s", want, printed.String())
+		}
+	}
+}
+
+// TestPayoutCost sums the amounts and fees of a payout, refusing totals that overflow before anything is signed
+func TestPayoutCost(t *testing.T) {
+	entries := func(n int, amount uint64) []SendEntry {
+		var list []SendEntry
+		for i := 0; i < n; i++ {
+			list = append(list, SendEntry{Address: mcmaddr.To58(airdropTag(i)), AmountToSend: amount})
+		}
+		return list
+	}
+	for _, tc := range []struct {
+		name    string
+		entries []SendEntry
+		fee     uint64
+		total   uint64
+		fees    uint64
+	}{
+		{"two transactions", entries(300, 1000), 500, 301000, 1000},
+		{"amounts overflow", entries(2, math.MaxUint64/2+1), 0, 0, 0},
+		{"fee overflows", entries(1, math.MaxUint64), 1, 0, 0},
+		{"fees overflow", entries(300, 1), math.MaxUint64/2 + 1, 0, 0},
+	} {
+		total, fees, err := payoutCost(tc.entries, tc.fee)
+		if tc.total == 0 && !errors.Is(err, errTotalOverflow) || tc.total != 0 && (err != nil || total != tc.total || fees != tc.fees) {
+			t.Errorf("%s: total %d, fees %d, %v", tc.name, total, fees, err)
+		}
+	}
+
+	p := &payout{cache: &walletstore.WalletCache{}, fee: 500}
+	if result := p.payEntries(context.Background(), entries(2, math.MaxUint64/2+1), 5, nil, math.MaxUint64); result.Exit != EXIT_INVALID_ENTRIES {
+		t.Errorf("overflowing payout: %+v", result)
+	}
+}
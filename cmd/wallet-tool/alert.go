@@ -0,0 +1,135 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/NickP005/Vindax-MCM-tools/pkg/amount"
+	"github.com/NickP005/Vindax-MCM-tools/pkg/config"
+	"github.com/NickP005/Vindax-MCM-tools/pkg/mcmaddr"
+	"github.com/NickP005/Vindax-MCM-tools/pkg/meshclient"
+	"github.com/NickP005/Vindax-MCM-tools/pkg/webhook"
+)
+
+// EXIT_BALANCE_LOW is the exit code of check-balance when the wallet balance is below -alert-below
+const EXIT_BALANCE_LOW = 5
+
+// Events of BalanceAlert
+const (
+	EventLowBalance      = "low-balance"
+	EventBalanceRestored = "balance-restored"
+)
+
+// LowBalance is the payload of a balance alert: what the wallet holds, and what to send to the refill address
+type LowBalance struct {
+	Time          time.Time `json:"time"`
+	Event         string    `json:"event"`
+	RefillAddress string    `json:"refillAddress"`
+	Balance       uint64    `json:"balance"`
+	Threshold     uint64    `json:"threshold"`
+	// Shortfall is what brings the balance back to the threshold, 0 once restored
+	Shortfall uint64 `json:"shortfall"`
+}
+
+// String renders an alert on one line, e.g. "balance 2 MCM is below 50 MCM: send 48 MCM to <address>"
+func (l LowBalance) String() string {
+	at := l.Time.Format(time.RFC3339)
+	if l.Event == EventBalanceRestored {
+		return fmt.Sprintf("%s balance %s is back above %s", at, amount.Describe(l.Balance), amount.Format(l.Threshold, amount.MCM))
+	}
+	return fmt.Sprintf("%s balance %s is below %s: send %s to %s", at, amount.Describe(l.Balance),
+		amount.Format(l.Threshold, amount.MCM), amount.Format(l.Shortfall, amount.MCM), l.RefillAddress)
+}
+
+/*
+ * BalanceAlert compares the wallet balance to a threshold, for -alert-below
+ *
+ * Check alerts once when the balance falls below the threshold, and not
+ * again until it is back at or above it, which is reported too: a wallet
+ * left drained alerts once, not at every poll.
+ */
+type BalanceAlert struct {
+	Threshold     uint64
+	RefillAddress string
+	Hook          config.Webhook
+	Out           io.Writer
+	AsJSON        bool
+	// low is set once an alert fired and until the balance is restored
+	low bool
+}
+
+// alertFor returns the alert of a balance
+func (a *BalanceAlert) alertFor(balance uint64) LowBalance {
+	alert := LowBalance{
+		Time:          time.Now().UTC(),
+		Event:         EventBalanceRestored,
+		RefillAddress: a.RefillAddress,
+		Balance:       balance,
+		Threshold:     a.Threshold,
+	}
+	if balance < a.Threshold {
+		alert.Event = EventLowBalance
+		alert.Shortfall = a.Threshold - balance
+	}
+	return alert
+}
+
+// emit prints an alert and posts it to the webhook; a webhook failure is only a warning
+func (a *BalanceAlert) emit(ctx context.Context, alert LowBalance) {
+	if a.AsJSON {
+		data, _ := json.Marshal(alert)
+		fmt.Fprintln(a.Out, string(data))
+	} else {
+		fmt.Fprintln(a.Out, alert)
+	}
+	if err := webhook.Post(ctx, a.Hook, "wallet-tool", alert.Event, alert); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+	}
+}
+
+// Check reports a balance crossing the threshold, in either direction, and returns whether it is below
+func (a *BalanceAlert) Check(ctx context.Context, balance uint64) bool {
+	below := balance < a.Threshold
+	if below != a.low {
+		a.low = below
+		a.emit(ctx, a.alertFor(balance))
+	}
+	return below
+}
+
+/*
+ * runCheckBalance implements `wallet-tool check-balance -alert-below
+ * <amount>`, for cron: it compares the wallet balance to the threshold
+ * once, and alerts when it is below
+ *
+ * Exits with 0 when the balance is at or above the threshold,
+ * EXIT_BALANCE_LOW when it is below, and 1 when it cannot be read.
+ */
+func runCheckBalance(ctx context.Context, client *meshclient.MeshAPIClient, walletCacheFile string, alert *BalanceAlert) int {
+	cache, err := ReadWalletCache(walletCacheFile)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error with wallet cache: %v\n", err)
+		return 1
+	}
+	tag, err := mcmaddr.Normalize(cache.RefillAddress)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: invalid wallet address in cache: %v\n", err)
+		return 1
+	}
+	balance, err := tagBalance(ctx, client, tag[:])
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error checking the wallet balance: %v\n", err)
+		return 1
+	}
+
+	alert.RefillAddress = cache.RefillAddress
+	if !alert.Check(ctx, balance) {
+		fmt.Fprintf(os.Stderr, "Balance %s, at or above %s\n", amount.Describe(balance), amount.Format(alert.Threshold, amount.MCM))
+		return 0
+	}
+	return EXIT_BALANCE_LOW
+}
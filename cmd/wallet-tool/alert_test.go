@@ -0,0 +1,209 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/NickP005/Vindax-MCM-tools/pkg/config"
+	"github.com/NickP005/Vindax-MCM-tools/pkg/meshclient"
+	"github.com/NickP005/Vindax-MCM-tools/pkg/meshmock"
+	"github.com/NickP005/Vindax-MCM-tools/pkg/walletstore"
+	"github.com/NickP005/Vindax-MCM-tools/pkg/webhook"
+)
+
+// alertHook is a webhook receiving the alerts, sent on the returned channel
+func alertHook(t *testing.T) (config.Webhook, chan LowBalance) {
+	t.Helper()
+	alerts := make(chan LowBalance, 100)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body struct {
+			webhook.Event
+			Data LowBalance `json:"data"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil || body.Event.Event != body.Data.Event {
+			t.Errorf("webhook body %+v, %v", body, err)
+		}
+		alerts <- body.Data
+	}))
+	t.Cleanup(server.Close)
+	return config.Webhook{URL: server.URL, Timeout: 5 * time.Second}, alerts
+}
+
+// nextAlert returns the next alert posted, or fails after a while
+func nextAlert(t *testing.T, alerts chan LowBalance) LowBalance {
+	t.Helper()
+	select {
+	case alert := <-alerts:
+		return alert
+	case <-time.After(10 * time.Second):
+		t.Fatal("no alert posted")
+	}
+	return LowBalance{}
+}
+
+// TestBalanceAlertHysteresis crosses the threshold both ways: each crossing alerts once, the polls in between do not
+func TestBalanceAlertHysteresis(t *testing.T) {
+	hook, alerts := alertHook(t)
+	var out bytes.Buffer
+	alert := &BalanceAlert{Threshold: 500, RefillAddress: "cr5m3GobqYe6BDY1jqdSNJMYsjADL5", Hook: hook, Out: &out}
+	type alerted struct {
+		event     string
+		shortfall uint64
+	}
+	for _, step := range []struct {
+		balance uint64
+		below   bool
+		alert   *alerted
+	}{
+		{1000, false, nil},
+		{499, true, &alerted{EventLowBalance, 1}},
+		{300, true, nil},
+		{499, true, nil},
+		{500, false, &alerted{EventBalanceRestored, 0}},
+		{800, false, nil},
+		{0, true, &alerted{EventLowBalance, 500}},
+		{0, true, nil},
+		{10000, false, &alerted{EventBalanceRestored, 0}},
+	} {
+		if below := alert.Check(context.Background(), step.balance); below != step.below {
+			t.Errorf("balance %d: below %v", step.balance, below)
+		}
+		if step.alert == nil {
+			select {
+			case got := <-alerts:
+				t.Errorf("balance %d: alert %+v", step.balance, got)
+			default:
+			}
+			continue
+		}
+		got := nextAlert(t, alerts)
+		if got.Event != step.alert.event || got.Shortfall != step.alert.shortfall || got.Balance != step.balance ||
+			got.Threshold != 500 || got.RefillAddress != alert.RefillAddress || got.Time.IsZero() {
+			t.Errorf("balance %d: alert %+v", step.balance, got)
+		}
+	}
+
+	want := []string{
+		"balance 499 nMCM (0.000000499 MCM) is below 0.0000005 MCM: send 0.000000001 MCM to cr5m3GobqYe6BDY1jqdSNJMYsjADL5",
+		"balance 500 nMCM (0.0000005 MCM) is back above 0.0000005 MCM",
+		"balance 0 nMCM (0 MCM) is below 0.0000005 MCM: send 0.0000005 MCM to cr5m3GobqYe6BDY1jqdSNJMYsjADL5",
+		"balance 10000 nMCM (0.00001 MCM) is back above 0.0000005 MCM",
+	}
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	if len(lines) != len(want) {
+		t.Fatalf("printed:\n%s", out.String())
+	}
+	for i, line := range lines {
+		if _, rest, _ := strings.Cut(line, " "); rest != want[i] {
+			t.Errorf("line %d: %q, want %q", i+1, rest, want[i])
+		}
+	}
+}
+
+// TestCheckBalance runs check-balance once per balance, as cron would
+func TestCheckBalance(t *testing.T) {
+	mock := meshmock.New()
+	defer mock.Close()
+	mock.MineBlock()
+	tag, _ := hex.DecodeString(depositsTag)
+	path := filepath.Join(t.TempDir(), "wallet-cache.json")
+	walletstore.Save(path, &walletstore.WalletCache{SecretKey: strings.Repeat("17", 32)})
+	client := meshclient.NewMeshAPIClient(mock.URL(), nil)
+
+	for _, tc := range []struct {
+		name    string
+		balance uint64
+		known   bool
+		exit    int
+		shorter uint64
+	}{
+		{"above", 2000, true, 0, 0},
+		{"at threshold", 1000, true, 0, 0},
+		{"below", 400, true, EXIT_BALANCE_LOW, 600},
+		{"unknown tag", 0, false, EXIT_BALANCE_LOW, 1000},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			if tc.known {
+				mock.SetAccount(tag, "0x"+depositsTag+strings.Repeat("00", 20), tc.balance)
+			} else {
+				mock.SetAccount(tag, "", 0)
+			}
+			hook, alerts := alertHook(t)
+			var out bytes.Buffer
+			alert := &BalanceAlert{Threshold: 1000, Hook: hook, Out: &out, AsJSON: true}
+			if exit := runCheckBalance(context.Background(), client, path, alert); exit != tc.exit {
+				t.Errorf("exit %d", exit)
+			}
+			if tc.exit == 0 {
+				if out.Len() != 0 || len(alerts) != 0 {
+					t.Errorf("alerted: %s", out.String())
+				}
+				return
+			}
+			var printed LowBalance
+			if err := json.Unmarshal(out.Bytes(), &printed); err != nil {
+				t.Fatalf("%v: %s", err, out.String())
+			}
+			posted := nextAlert(t, alerts)
+			for _, got := range []LowBalance{printed, posted} {
+				if got.Event != EventLowBalance || got.Balance != tc.balance || got.Shortfall != tc.shorter || got.RefillAddress != "cr5m3GobqYe6BDY1jqdSNJMYsjADL5" {
+					t.Errorf("alert %+v", got)
+				}
+			}
+		})
+	}
+
+	// A balance that cannot be read is neither low nor fine
+	mock.Outage(meshmock.Fault{Status: http.StatusServiceUnavailable}, time.Minute)
+	alert := &BalanceAlert{Threshold: 1000, Out: &bytes.Buffer{}}
+	if exit := runCheckBalance(context.Background(), client, path, alert); exit != 1 {
+		t.Errorf("outage: exit %d", exit)
+	}
+}
+
+// TestWatchDepositsAlert checks the balance of every block of watch-deposits against -alert-below, alerting once per crossing
+func TestWatchDepositsAlert(t *testing.T) {
+	tag, _ := hex.DecodeString(depositsTag)
+	wallet := "0x" + depositsTag + strings.Repeat("00", 20)
+	hook, alerts := alertHook(t)
+	alert := &BalanceAlert{Threshold: 800, Hook: hook, Out: &bytes.Buffer{}}
+	runDeposits(t, 0, alert, func(run *depositsRun, cancel func()) {
+		defer cancel()
+		// The balance of the start, 1000, is above the threshold
+		run.mock.SetAccount(tag, wallet, 500)
+		run.mock.MineBlock()
+		run.expect("balance 500 total 0")
+		if got := nextAlert(t, alerts); got.Event != EventLowBalance || got.Shortfall != 300 || got.RefillAddress != "cr5m3GobqYe6BDY1jqdSNJMYsjADL5" {
+			t.Errorf("alert %+v", got)
+		}
+
+		// Still low: no alert again
+		run.mock.SetAccount(tag, wallet, 400)
+		run.mock.MineBlock()
+		run.expect("balance 400 total 0")
+		run.mock.MineBlock()
+		run.waitBalanceReads(4)
+
+		// The refill arrives
+		run.mock.AddToMempool(transfer(1, wallet, 600, ""))
+		run.mock.SetAccount(tag, wallet, 1000)
+		run.mock.MineBlock()
+		run.expect("deposit 600 in 6 total 600", "balance 1000 total 600")
+		if got := nextAlert(t, alerts); got.Event != EventBalanceRestored || got.Balance != 1000 || got.Shortfall != 0 {
+			t.Errorf("alert %+v", got)
+		}
+	})
+	select {
+	case got := <-alerts:
+		t.Errorf("alerted again: %+v", got)
+	default:
+	}
+}
@@ -0,0 +1,132 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/NickP005/Vindax-MCM-tools/pkg/meshclient"
+	"github.com/NickP005/Vindax-MCM-tools/pkg/meshmock"
+)
+
+// TestDirectlyCheckTransaction checks only a not-found answer counts as an absence, so a failing API is not taken for a dropped transaction
+func TestDirectlyCheckTransaction(t *testing.T) {
+	mined := "0x" + strings.Repeat("ab", 32)
+	for _, tc := range []struct {
+		name   string
+		txID   string
+		fault  *meshmock.Fault
+		found  bool
+		failed bool
+	}{
+		{"mined", mined, nil, true, false},
+		{"unknown", "0x" + strings.Repeat("cd", 32), nil, false, false},
+		{"404", mined, &meshmock.Fault{Status: 404, Body: "not found"}, false, false},
+		{"throttled", mined, &meshmock.Fault{Status: 429, RetryAfter: "2"}, false, true},
+		{"unavailable", mined, &meshmock.Fault{Status: 503}, false, true},
+		{"other code", mined, &meshmock.Fault{Body: `{"code":2,"message":"internal error","retriable":true}`}, false, true},
+		{"not JSON", mined, &meshmock.Fault{Body: "<html>bad gateway</html>"}, false, true},
+	} {
+		mock := meshmock.New()
+		mock.AddToMempool(meshclient.Transaction{TransactionIdentifier: meshclient.TransactionIdentifier{Hash: mined}})
+		mock.MineBlock()
+		if tc.fault != nil {
+			mock.Fail("/block/transaction", *tc.fault)
+		}
+		client := meshclient.NewMeshAPIClient(mock.URL(), nil)
+		found, err := DirectlyCheckTransaction(context.Background(), client, tc.txID)
+		mock.Close()
+		if found != tc.found || (err != nil) != tc.failed {
+			t.Errorf("%s: found %v, %v", tc.name, found, err)
+		}
+	}
+
+	// An unreachable API is an error too
+	mock := meshmock.New()
+	url := mock.URL()
+	mock.Close()
+	if found, err := DirectlyCheckTransaction(context.Background(), meshclient.NewMeshAPIClient(url, nil), mined); found || err == nil {
+		t.Errorf("unreachable API: found %v, %v", found, err)
+	}
+}
+
+// TestVerifyTruncatedBlock reproduces a node listing only part of a block inline: a transaction among other_transactions is still found
+func TestVerifyTruncatedBlock(t *testing.T) {
+	mock := meshmock.New()
+	defer mock.Close()
+	var hashes []string
+	for i := 0; i < 5; i++ {
+		hash := fmt.Sprintf("0x%064x", i+1)
+		hashes = append(hashes, hash)
+		mock.AddToMempool(meshclient.Transaction{TransactionIdentifier: meshclient.TransactionIdentifier{Hash: hash}})
+	}
+	height := mock.MineBlock()
+	mock.SetBlockLimit(2)
+	client := meshclient.NewMeshAPIClient(mock.URL(), nil)
+
+	block, err := client.Block(context.Background(), height)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if block.Contains(hashes[3]) || !block.ListsOther(hashes[3]) {
+		t.Fatal("the mock did not truncate the block")
+	}
+	for i, hash := range hashes {
+		check := VerifyTransactionInBlock(context.Background(), client, height, strings.TrimPrefix(hash, "0x"))
+		if !check.Included || check.Err != nil || check.Block.Index != height {
+			t.Errorf("transaction %d: %+v", i, check)
+		}
+	}
+	if check := VerifyTransactionInBlock(context.Background(), client, height, fmt.Sprintf("%064x", 99)); check.Included || check.Err != nil {
+		t.Errorf("absent transaction: %+v", check)
+	}
+
+	// A failed lookup of other_transactions is not an absence
+	mock.Fail("/block/transaction", meshmock.Fault{Status: 503})
+	if check := VerifyTransactionInBlock(context.Background(), client, height, hashes[4]); check.Included || check.Err == nil {
+		t.Errorf("failed lookup: %+v", check)
+	}
+}
+
+// TestCheckMempool answers mempools where the txid is only part of other hashes, which must not count
+func TestCheckMempool(t *testing.T) {
+	txID := strings.Repeat("ab", 32)
+	for _, tc := range []struct {
+		name   string
+		hashes []string
+		found  bool
+	}{
+		{"empty", nil, false},
+		{"prefix of another", []string{"0x" + txID + "cd"}, false},
+		{"suffix of another", []string{"0xcd" + txID}, false},
+		{"truncated", []string{"0x" + txID[:62], "0x" + strings.Repeat("01", 32)}, false},
+		{"exact", []string{"0x" + strings.Repeat("01", 32), "0x" + txID}, true},
+		{"no prefix", []string{txID}, true},
+		{"uppercase", []string{"0X" + strings.ToUpper(txID)}, true},
+	} {
+		var identifiers []string
+		for _, hash := range tc.hashes {
+			identifiers = append(identifiers, `{"hash":"`+hash+`"}`)
+		}
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			io.WriteString(w, `{"transaction_identifiers":[`+strings.Join(identifiers, ",")+`]}`)
+		}))
+		check, err := CheckMempool(context.Background(), meshclient.NewMeshAPIClient(server.URL, nil), txID)
+		server.Close()
+		if err != nil || check.Found != tc.found || check.Size != len(tc.hashes) {
+			t.Errorf("%s: %+v, %v", tc.name, check, err)
+		}
+	}
+
+	// A failing mempool is an error, not an absence
+	mock := meshmock.New()
+	defer mock.Close()
+	mock.Fail("/mempool", meshmock.Fault{Status: 503})
+	if check, err := CheckMempool(context.Background(), meshclient.NewMeshAPIClient(mock.URL(), nil), txID); err == nil || check.Found {
+		t.Errorf("failing mempool: %+v, %v", check, err)
+	}
+}
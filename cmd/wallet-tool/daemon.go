@@ -0,0 +1,261 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/NickP005/Vindax-MCM-tools/pkg/fileutil"
+	"github.com/NickP005/Vindax-MCM-tools/pkg/mcmaddr"
+	"github.com/NickP005/Vindax-MCM-tools/pkg/meshclient"
+	"github.com/NickP005/Vindax-MCM-tools/pkg/payqueue"
+	"github.com/NickP005/Vindax-MCM-tools/pkg/shutdown"
+	"github.com/NickP005/Vindax-MCM-tools/pkg/txentry"
+	"github.com/NickP005/Vindax-MCM-tools/pkg/walletstore"
+)
+
+// DEFAULT_QUEUE_DIR is the queue directory drained by the daemon mode, unless -queue-dir names another
+const DEFAULT_QUEUE_DIR = "queue"
+
+// jsonJob is a job file in JSON, the entries of a payout file with the amounts as strings
+type jsonJob struct {
+	Entries []struct {
+		Address string `json:"address"`
+		Amount  string `json:"amount"`
+		Memo    string `json:"memo"`
+	} `json:"entries"`
+}
+
+/*
+ * ReadEntriesJSON reads and validates the entries of a JSON job file,
+ * {"entries": [{"address": ..., "amount": ..., "memo": ...}]}, as
+ * ReadEntriesCSV does those of a payout file
+ *
+ * The line of a rejected entry is its position in the list, from 1.
+ */
+func ReadEntriesJSON(ctx context.Context, client *meshclient.MeshAPIClient, filename string, own [mcmaddr.TagLength]byte, requireExisting bool) (*ValidationReport, error) {
+	file, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	var job jsonJob
+	decoder := json.NewDecoder(file)
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&job); err != nil {
+		return nil, fmt.Errorf("invalid job %s: %v", filename, err)
+	}
+
+	report := &ValidationReport{}
+	var parsed []parsedEntry
+	fmt.Println("Validating entries:")
+	fmt.Println("-------------------")
+	for i, e := range job.Entries {
+		record := []string{e.Address, e.Amount}
+		if e.Memo != "" {
+			record = append(record, e.Memo)
+		}
+		if entry, ok := parseEntry(report, i+1, record, own); ok {
+			parsed = append(parsed, entry)
+		}
+	}
+	checkDestinations(ctx, client, report, parsed, requireExisting)
+	fmt.Println("-------------------")
+	return report, nil
+}
+
+// DaemonOptions are the flags of the daemon mode
+type DaemonOptions struct {
+	QueueDir        string
+	MaxAttempts     int
+	PollInterval    time.Duration
+	ReceiptsDir     string
+	RequireExisting bool
+	// Alert, if set, is checked after every job and whenever a job waits for funds
+	Alert *BalanceAlert
+}
+
+/*
+ * queueDaemon is the payqueue.Handler of the daemon mode: it pays each job
+ * as a payout file, from the wallet of its payout
+ *
+ * The receipt of a job, named after it, makes a job resumed after a restart
+ * pay only its entries without a transaction; the transaction in flight is
+ * resumed from the wallet cache's PendingTx, see payout.resume.
+ */
+type queueDaemon struct {
+	// payout is the wallet and the flags every job is paid with
+	payout payout
+	own    [mcmaddr.TagLength]byte
+	opts   DaemonOptions
+
+	// receipt and entries of the job validated last
+	receipt *Receipt
+	entries []SendEntry
+}
+
+/*
+ * Validate reads the entries of a job, CSV or JSON by its extension, and
+ * checks the wallet can pay those without a transaction yet
+ *
+ * A job with an entry at fault fails its attempt; a failed balance lookup
+ * or too small a balance only make it wait.
+ */
+func (d *queueDaemon) Validate(ctx context.Context, job *payqueue.Job) error {
+	var report *ValidationReport
+	var err error
+	if strings.EqualFold(filepath.Ext(job.Name), ".json") {
+		report, err = ReadEntriesJSON(ctx, d.payout.client, job.Path, d.own, d.opts.RequireExisting)
+	} else {
+		report, err = ReadEntriesCSV(ctx, d.payout.client, job.Path, d.own, d.opts.RequireExisting)
+	}
+	if err != nil {
+		return err
+	}
+	if !report.Valid() {
+		report.Print(os.Stdout)
+		err := fmt.Errorf("%d entries cannot be paid, first: %v", len(report.Errors), report.Errors[0])
+		if report.ExitCode() != EXIT_INVALID_ENTRIES {
+			return fmt.Errorf("%w: %v", payqueue.ErrWait, err)
+		}
+		return err
+	}
+
+	receipt, err := ReadReceipt(ReceiptPath(d.opts.ReceiptsDir, job.Name))
+	if err != nil {
+		return err
+	}
+	receipt.Refresh(ctx, d.payout.client)
+	entries, paid := receipt.Unpaid(report.Entries)
+	if len(entries) == 0 && paid == 0 {
+		return errors.New("no entries to pay")
+	}
+	d.receipt, d.entries = receipt, entries
+
+	// A transaction in flight spends the balance first: its change is checked when the next one is signed
+	if _, _, inFlight := receipt.InFlight(); inFlight || len(entries) == 0 {
+		return nil
+	}
+	tag := d.payout.keychain.Tag()
+	balance, err := tagBalance(ctx, d.payout.client, tag[:])
+	if err != nil {
+		return fmt.Errorf("%w: failed to check the wallet balance: %v", payqueue.ErrWait, err)
+	}
+	needed, _, err := payoutCost(entries, d.payout.fee)
+	if err != nil {
+		return err
+	}
+	if balance < needed {
+		if d.opts.Alert != nil {
+			d.opts.Alert.Check(ctx, balance)
+		}
+		return fmt.Errorf("%w: the wallet holds %d nMCM, the job needs %d nMCM (refill %s)",
+			payqueue.ErrWait, balance, needed, d.payout.cache.RefillAddress)
+	}
+	return nil
+}
+
+/*
+ * Pay resumes the transaction of the job in flight, if any, then pays the
+ * entries validated, and archives the receipt once every transaction
+ * confirmed
+ */
+func (d *queueDaemon) Pay(ctx context.Context, job *payqueue.Job) error {
+	p := d.payout
+	p.receipt, p.payoutFile = d.receipt, job.Path
+	defer p.keychain.Wipe()
+
+	result := p.resume(ctx)
+	if result.Confirmed && len(d.entries) > 0 {
+		currentIndex, tag, balance, err := VerifyCurrentIndex(ctx, p.client, p.keychain, p.cache.Index)
+		if err := p.hashes.Save(); err != nil {
+			fmt.Printf("Warning: %v\n", err)
+		}
+		if err != nil {
+			return fmt.Errorf("%w: failed to verify the wallet index: %v", payqueue.ErrWait, err)
+		}
+		batches := splitEntries(d.entries, txentry.MaxDestinations)
+		if len(batches) > 1 {
+			fmt.Printf("%d entries need %d transactions, each signed once the previous one confirms\n", len(d.entries), len(batches))
+		}
+		result = p.pay(ctx, batches, currentIndex, tag, balance)
+	}
+	p.receipt.Print(os.Stdout)
+	if result.Exit != 0 {
+		return fmt.Errorf("payout failed with exit code %d, see %s", result.Exit, p.receipt.Path())
+	}
+	if !result.Confirmed || !p.receipt.Confirmed() {
+		return fmt.Errorf("%w: transaction %s", payqueue.ErrUnconfirmed, result.TxID)
+	}
+
+	if archived, err := p.receipt.Archive(time.Now()); err != nil {
+		fmt.Printf("Warning: failed to archive the receipt %s: %v\n", p.receipt.Path(), err)
+	} else {
+		fmt.Printf("Receipt saved to %s\n", archived)
+	}
+	if d.opts.Alert != nil {
+		tag := p.keychain.Tag()
+		if balance, err := tagBalance(ctx, p.client, tag[:]); err == nil {
+			d.opts.Alert.Check(ctx, balance)
+		}
+	}
+	return nil
+}
+
+/*
+ * runDaemon implements the daemon mode: it drains the queue directory,
+ * paying its jobs one at a time from the wallet, until interrupted
+ *
+ * template holds the client, the wallet cache file, the fee and the
+ * monitoring flags; the wallet cache is locked by the caller.
+ */
+func runDaemon(ctx context.Context, template payout, opts DaemonOptions) int {
+	cache, err := ReadWalletCache(template.walletCacheFile)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error with wallet cache: %v\n", err)
+		return 1
+	}
+	own, err := mcmaddr.Normalize(cache.RefillAddress)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error with wallet cache: invalid refill address: %v\n", err)
+		return 1
+	}
+	keychain, err := walletstore.NewKeychain(cache.SecretKey)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error with wallet secret key: %v\n", err)
+		return 1
+	}
+	defer keychain.Wipe()
+	hashes, err := walletstore.LoadDerivationCache(walletstore.DerivationCachePath(template.walletCacheFile), keychain.Fingerprint())
+	if err != nil {
+		fmt.Printf("Warning: %v\n", err)
+	}
+	keychain.UseDerivationCache(hashes)
+	template.cache, template.keychain, template.hashes = cache, keychain, hashes
+
+	q, err := payqueue.Open(opts.QueueDir, opts.MaxAttempts)
+	if errors.Is(err, fileutil.ErrLocked) {
+		fmt.Fprintf(os.Stderr, "Error: queue %s is drained by another wallet-tool daemon\n", opts.QueueDir)
+		return 1
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error opening queue: %v\n", err)
+		return 1
+	}
+	defer q.Close()
+	q.Log = func(line string) { fmt.Println(line) }
+
+	fmt.Printf("Draining %s every %v, paying from %s\n", opts.QueueDir, opts.PollInterval, cache.RefillAddress)
+	d := &queueDaemon{payout: template, own: own, opts: opts}
+	if err := q.Drain(ctx, d, opts.PollInterval); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	fmt.Println("Daemon stopped; a job in flight is resumed by the next run")
+	return shutdown.ExitInterrupted
+}
@@ -0,0 +1,242 @@
+package main
+
+import (
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/NickP005/Vindax-MCM-tools/pkg/mcmaddr"
+	"github.com/NickP005/Vindax-MCM-tools/pkg/meshclient"
+	"github.com/NickP005/Vindax-MCM-tools/pkg/meshmock"
+	"github.com/NickP005/Vindax-MCM-tools/pkg/payqueue"
+	"github.com/NickP005/Vindax-MCM-tools/pkg/shutdown"
+	"github.com/NickP005/Vindax-MCM-tools/pkg/walletstore"
+)
+
+// TestReadEntriesJSON reads a JSON job, its rejected entries numbered by position
+func TestReadEntriesJSON(t *testing.T) {
+	mock := meshmock.New()
+	defer mock.Close()
+	client := meshclient.NewMeshAPIClient(mock.URL(), nil)
+	own, _ := mcmaddr.Normalize("cr5m3GobqYe6BDY1jqdSNJMYsjADL5")
+	dir := t.TempDir()
+	path := filepath.Join(dir, "job.json")
+	os.WriteFile(path, []byte(`{"entries": [
+		{"address": "`+mcmaddr.To58(airdropTag(1))+`", "amount": "0.0001mcm", "memo": "INV-1"},
+		{"address": "0x`+mcmaddr.ToHex(airdropTag(2))+`", "amount": "250"},
+		{"address": "cr5m3GobqYe6BDY1jqdSNJMYsjADL5", "amount": "10"},
+		{"address": "`+mcmaddr.ToHex(airdropTag(3))+`", "amount": "ten"}
+	]}`), 0644)
+
+	var report *ValidationReport
+	var err error
+	captureStdout(t, func() { report, err = ReadEntriesJSON(context.Background(), client, path, own, false) })
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(report.Entries) != 2 || report.Entries[0].AmountToSend != 100000 || report.Entries[0].Memo != "INV-1" ||
+		report.Entries[1].Address != mcmaddr.To58(airdropTag(2)) || report.Entries[1].AmountToSend != 250 {
+		t.Errorf("entries %+v", report.Entries)
+	}
+	if len(report.Errors) != 2 || report.Errors[0].Line != 3 || report.Errors[0].Reason != ReasonOwnAddress ||
+		report.Errors[1].Line != 4 || report.Errors[1].Reason != ReasonBadAmount {
+		t.Errorf("errors %+v", report.Errors)
+	}
+
+	os.WriteFile(path, []byte(`{"entries": [{"address": "`+mcmaddr.To58(airdropTag(1))+`", "amount": "5", "fee": "1"}]}`), 0644)
+	if _, err := ReadEntriesJSON(context.Background(), client, path, own, false); err == nil || !strings.Contains(err.Error(), "unknown field") {
+		t.Errorf("unknown field: %v", err)
+	}
+}
+
+// daemonHooks counts the submits of a daemon run, and calls onSubmit after the first one
+type daemonHooks struct {
+	mu       sync.Mutex
+	submits  int
+	onSubmit func()
+}
+
+func (h *daemonHooks) OnRequestStart(info meshclient.RequestInfo) {}
+
+func (h *daemonHooks) OnRequestEnd(info meshclient.RequestInfo) {
+	if info.Op != "/construction/submit" || info.Err != nil {
+		return
+	}
+	h.mu.Lock()
+	h.submits++
+	first := h.submits == 1
+	h.mu.Unlock()
+	if first && h.onSubmit != nil {
+		h.onSubmit()
+	}
+}
+
+// readQueueIndex returns the jobs of the state index of a queue, by name
+func readQueueIndex(t *testing.T, dir string) map[string]payqueue.Job {
+	t.Helper()
+	data, err := os.ReadFile(filepath.Join(dir, payqueue.IndexFile))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var index struct {
+		Jobs []payqueue.Job `json:"jobs"`
+	}
+	if err := json.Unmarshal(data, &index); err != nil {
+		t.Fatal(err)
+	}
+	jobs := make(map[string]payqueue.Job)
+	for _, job := range index.Jobs {
+		jobs[job.Name] = job
+	}
+	return jobs
+}
+
+/*
+ * TestDaemon drains a queue of a CSV job, a JSON job, a poison job and one
+ * the wallet cannot pay, and is stopped once the first job is broadcast,
+ * before any block: the restarted daemon resumes its transaction from the
+ * wallet cache, submitting the same bytes, parks the poison job after its
+ * attempts and keeps the last one waiting for funds
+ */
+func TestDaemon(t *testing.T) {
+	mock := meshmock.New()
+	defer mock.Close()
+	mock.MineBlock()
+	dir := t.TempDir()
+	walletFile := filepath.Join(dir, "wallet.json")
+	if err := walletstore.Save(walletFile, &walletstore.WalletCache{SecretKey: strings.Repeat("17", 32), Index: 5}); err != nil {
+		t.Fatal(err)
+	}
+	keychain, _ := walletstore.NewKeychain(strings.Repeat("17", 32))
+	walletTag, hash := keychain.Tag(), keychain.AddrHash(5)
+	keychain.Wipe()
+	mock.SetAccount(walletTag[:], "0x"+hex.EncodeToString(walletTag[:])+hex.EncodeToString(hash[:]), 1000000)
+
+	queueDir := filepath.Join(dir, "queue")
+	os.Mkdir(queueDir, 0755)
+	start := time.Now().Add(-time.Hour)
+	for i, job := range []struct{ name, content string }{
+		{"a.csv", mcmaddr.To58(airdropTag(0)) + " 100000 INV-1\n" + mcmaddr.ToHex(airdropTag(1)) + " 50000\n"},
+		{"b.json", `{"entries": [{"address": "` + mcmaddr.To58(airdropTag(2)) + `", "amount": "70000"}]}`},
+		{"c.csv", "not-an-address 5000\n"},
+		{"d.csv", mcmaddr.To58(airdropTag(3)) + " 5mcm\n"},
+	} {
+		path := filepath.Join(queueDir, job.name)
+		os.WriteFile(path, []byte(job.content), 0644)
+		at := start.Add(time.Duration(i) * time.Second)
+		os.Chtimes(path, at, at)
+	}
+
+	run := func(ctx context.Context, hooks *daemonHooks) int {
+		client := meshclient.NewMeshAPIClient(mock.URL(), nil)
+		client.SetHooks(hooks)
+		template := payout{client: client, walletCacheFile: walletFile, fee: 500, confirmations: 1, timeout: 1,
+			outagePausesTimeout: true, interval: 20 * time.Millisecond}
+		opts := DaemonOptions{QueueDir: queueDir, MaxAttempts: 2, PollInterval: 20 * time.Millisecond, ReceiptsDir: filepath.Join(dir, "receipts")}
+		var exit int
+		captureStdout(t, func() { exit = runDaemon(ctx, template, opts) })
+		return exit
+	}
+
+	// The first run stops once a.csv is broadcast, no block mined
+	ctx, cancel := context.WithCancel(context.Background())
+	first := &daemonHooks{onSubmit: cancel}
+	if exit := run(ctx, first); exit != shutdown.ExitInterrupted {
+		t.Fatalf("first run: exit %d", exit)
+	}
+	cancel()
+	if job := readQueueIndex(t, queueDir)["a.csv"]; job.State != payqueue.StateSubmitted {
+		t.Fatalf("a.csv %+v after the first run", job)
+	}
+	cache, _ := walletstore.Read(walletFile)
+	if cache.Pending == nil || cache.Pending.Index != 5 || len(mock.Submitted()) != 1 || mock.Submitted()[0] != cache.Pending.SignedTx {
+		t.Fatalf("pending %+v, %d submitted", cache.Pending, len(mock.Submitted()))
+	}
+	if _, err := os.Stat(filepath.Join(queueDir, payqueue.StateSubmitted, "a.csv")); err != nil {
+		t.Fatal(err)
+	}
+
+	// The second run mines a block every 50ms, and stops once d.csv waits for funds
+	ctx, cancel = context.WithCancel(context.Background())
+	defer cancel()
+	go func() {
+		timeout := time.After(20 * time.Second)
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-timeout:
+				cancel()
+			case <-time.After(50 * time.Millisecond):
+				mock.MineBlock()
+				data, _ := os.ReadFile(filepath.Join(queueDir, payqueue.IndexFile))
+				var index struct{ Jobs []payqueue.Job }
+				json.Unmarshal(data, &index)
+				for _, job := range index.Jobs {
+					if job.Name == "d.csv" && strings.Contains(job.LastError, "the job needs") {
+						cancel()
+					}
+				}
+			}
+		}
+	}()
+	second := &daemonHooks{}
+	if exit := run(ctx, second); exit != shutdown.ExitInterrupted {
+		t.Fatalf("second run: exit %d", exit)
+	}
+
+	jobs := readQueueIndex(t, queueDir)
+	for name, want := range map[string]struct {
+		state    string
+		attempts int
+	}{
+		"a.csv":  {payqueue.StateConfirmed, 0},
+		"b.json": {payqueue.StateConfirmed, 0},
+		"c.csv":  {payqueue.StateParked, 2},
+		"d.csv":  {payqueue.StateQueued, 0},
+	} {
+		if job := jobs[name]; job.State != want.state || job.Attempts != want.attempts {
+			t.Errorf("job %s: %s after %d attempts (%s), want %s after %d", name, job.State, job.Attempts, job.LastError, want.state, want.attempts)
+		}
+	}
+
+	// a.csv signed once, its bytes submitted again; b.json signed from the change
+	signed := make(map[string]bool)
+	for _, tx := range mock.Submitted() {
+		signed[tx] = true
+	}
+	if len(signed) != 2 || second.submits != 2 {
+		t.Errorf("%d transactions signed, %d submits by the second run", len(signed), second.submits)
+	}
+	for i, want := range []uint64{100000, 50000, 70000, 0} {
+		tag := airdropTag(i)
+		if balance, _ := mock.Balance(tag[:]); balance != want {
+			t.Errorf("destination %d holds %d, want %d", i, balance, want)
+		}
+	}
+	if balance, _ := mock.Balance(walletTag[:]); balance != 1000000-220000-2*500 {
+		t.Errorf("wallet balance %d", balance)
+	}
+	cache, _ = walletstore.Read(walletFile)
+	if cache.Pending != nil || cache.Index != 7 {
+		t.Errorf("wallet cache at index %d, pending %+v", cache.Index, cache.Pending)
+	}
+
+	// Each job paid has its receipt archived, mapping its entries to the transaction
+	archived, _ := filepath.Glob(filepath.Join(dir, "receipts", "*.receipt.csv"))
+	if len(archived) != 2 {
+		t.Fatalf("receipts %q", archived)
+	}
+	for _, path := range archived {
+		receipt, err := ReadReceipt(path)
+		if err != nil || !receipt.Confirmed() || receipt.Transactions() != 1 || receipt.Entries[0].TxID == "" {
+			t.Errorf("receipt %s: %+v, %v", path, receipt, err)
+		}
+	}
+}
@@ -0,0 +1,291 @@
+package main
+
+import (
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/NickP005/Vindax-MCM-tools/pkg/amount"
+	"github.com/NickP005/Vindax-MCM-tools/pkg/config"
+	"github.com/NickP005/Vindax-MCM-tools/pkg/mcmaddr"
+	"github.com/NickP005/Vindax-MCM-tools/pkg/meshclient"
+	"github.com/NickP005/Vindax-MCM-tools/pkg/monitor"
+	"github.com/NickP005/Vindax-MCM-tools/pkg/shutdown"
+	"github.com/NickP005/Vindax-MCM-tools/pkg/webhook"
+)
+
+// Deposit is a payment to the wallet tag found in a block
+type Deposit struct {
+	Block     uint64 `json:"block"`
+	BlockHash string `json:"blockHash"`
+	TxID      string `json:"txId"`
+	// Source is the base58 address of the tag that paid
+	Source string `json:"source"`
+	Amount uint64 `json:"amount"`
+	Memo   string `json:"memo,omitempty"`
+}
+
+// DepositEvent is a line of watch-deposits: a deposit, a deposit reverted by a reorg, or a balance change
+type DepositEvent struct {
+	Time    time.Time `json:"time"`
+	Event   string    `json:"event"`
+	Deposit *Deposit  `json:"deposit,omitempty"`
+	// Balance is the wallet balance after the block, Total the deposits seen since the start
+	Balance *uint64 `json:"balance,omitempty"`
+	Total   uint64  `json:"total"`
+}
+
+// Events of DepositEvent
+const (
+	EventDeposit  = "deposit"
+	EventReverted = "reverted"
+	EventBalance  = "balance"
+)
+
+// tagAddress renders an account address of the Mesh API (hex tag or full address) as the base58 address of its tag
+func tagAddress(account string) string {
+	raw, err := hex.DecodeString(strings.TrimPrefix(strings.ToLower(account), "0x"))
+	if err != nil || len(raw) < mcmaddr.TagLength {
+		return account
+	}
+	return AddrToBase58(raw[:mcmaddr.TagLength])
+}
+
+/*
+ * DepositsIn returns the payments to tag made by the transfers of a block
+ *
+ * The wallet's own transactions are left out: what they credit to the tag
+ * is their change, not a deposit.
+ */
+func DepositsIn(transfers []meshclient.Transfer, tag []byte, block meshclient.BlockIdentifier) []Deposit {
+	tagHex := hex.EncodeToString(tag)
+	var deposits []Deposit
+	for _, transfer := range transfers {
+		if transfer.Source != "" && touchesTag(transfer.Source, tagHex) {
+			continue
+		}
+		for _, payment := range transfer.PaidTo(tag) {
+			deposits = append(deposits, Deposit{
+				Block:     block.Index,
+				BlockHash: block.Hash,
+				TxID:      transfer.TxID,
+				Source:    tagAddress(transfer.Source),
+				Amount:    payment.Amount,
+				Memo:      payment.Memo,
+			})
+		}
+	}
+	return deposits
+}
+
+// String renders an event on one line, e.g. "deposit of 2.5 MCM from <address> in block 12 (tx <id>)"
+func (e DepositEvent) String() string {
+	at := e.Time.Format(time.RFC3339)
+	switch e.Event {
+	case EventDeposit, EventReverted:
+		d := e.Deposit
+		line := fmt.Sprintf("deposit of %s from %s in block %d (tx %s)", amount.Describe(d.Amount), d.Source, d.Block, d.TxID)
+		if d.Memo != "" {
+			line += fmt.Sprintf(" memo %q", d.Memo)
+		}
+		if e.Event == EventReverted {
+			line = "reverted by a reorg: " + line
+		}
+		return at + " " + line
+	}
+	return fmt.Sprintf("%s balance %s, %s deposited since the start", at, amount.Describe(*e.Balance), amount.Format(e.Total, amount.MCM))
+}
+
+// depositWatcher finds the deposits to a tag, block by block, and prints and posts them
+type depositWatcher struct {
+	client *meshclient.MeshAPIClient
+	tag    []byte
+	out    io.Writer
+	asJSON bool
+	hook   config.Webhook
+	// hashes and byHeight hold the recent blocks scanned and their deposits, to revert them on a reorg
+	hashes   monitor.BlockHashes
+	byHeight map[uint64][]Deposit
+	total    uint64
+}
+
+// emit prints an event and posts it to the webhook; a webhook failure is only a warning
+func (w *depositWatcher) emit(ctx context.Context, event DepositEvent) {
+	event.Time = time.Now().UTC()
+	event.Total = w.total
+	if w.asJSON {
+		data, _ := json.Marshal(event)
+		fmt.Fprintln(w.out, string(data))
+	} else {
+		fmt.Fprintln(w.out, event)
+	}
+	if err := webhook.Post(ctx, w.hook, "wallet-tool", event.Event, event); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+	}
+}
+
+// scan reports the deposits of the block now at height; a block that cannot be read is only a warning
+func (w *depositWatcher) scan(ctx context.Context, height uint64) {
+	block, err := w.client.Block(ctx, height)
+	var transfers []meshclient.Transfer
+	if err == nil {
+		transfers, err = w.client.BlockTransfers(ctx, block)
+	}
+	if err != nil {
+		if ctx.Err() == nil {
+			fmt.Fprintf(os.Stderr, "Warning: block %d could not be read, its deposits are not reported: %v\n", height, err)
+		}
+		return
+	}
+	w.hashes[height] = block.Block.BlockIdentifier.Hash
+	deposits := DepositsIn(transfers, w.tag, block.Block.BlockIdentifier)
+	w.byHeight[height] = deposits
+	for i := range deposits {
+		w.total += deposits[i].Amount
+		w.emit(ctx, DepositEvent{Event: EventDeposit, Deposit: &deposits[i]})
+	}
+}
+
+/*
+ * reorg reports as reverted the deposits of the blocks a reorg replaced,
+ * below the new block at height, and scans the blocks replacing them
+ *
+ * The fork is found as the payout monitor finds it, from the hashes of the
+ * blocks scanned (see monitor.BlockHashes.ForkPoint).
+ */
+func (w *depositWatcher) reorg(ctx context.Context, height uint64) {
+	fork := height - min(height, 1)
+	if point, err := w.hashes.ForkPoint(ctx, w.client, fork); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: the fork of the reorg could not be found, only block %d is checked again: %v\n", height, err)
+	} else {
+		fork = point
+	}
+	for h, deposits := range w.byHeight {
+		if h <= fork {
+			continue
+		}
+		for i := range deposits {
+			w.total -= deposits[i].Amount
+			w.emit(ctx, DepositEvent{Event: EventReverted, Deposit: &deposits[i]})
+		}
+		delete(w.byHeight, h)
+	}
+	w.hashes.Forget(fork)
+	for h := fork + 1; h < height; h++ {
+		w.scan(ctx, h)
+	}
+}
+
+/*
+ * runWatchDeposits implements `wallet-tool watch-deposits`: it reports every
+ * payment to the wallet tag as its block arrives, with its amount, source
+ * address and block, and the balance whenever it changes
+ *
+ * Each new block is decoded, its transactions left out by the server
+ * included, and its payments to the tag reported; a reorg reports the
+ * deposits of the replaced blocks as reverted. With target, the watch ends
+ * with 0 once the deposits seen since the start add up to it. With alert,
+ * every balance read is also checked against its threshold. The blocks are
+ * polled every interval, CHECK_MEMPOOL_INTERVAL seconds when zero.
+ */
+func runWatchDeposits(ctx context.Context, client *meshclient.MeshAPIClient, walletCacheFile string, target uint64, asJSON bool, hook config.Webhook, alert *BalanceAlert, interval time.Duration) int {
+	cache, err := ReadWalletCache(walletCacheFile)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error with wallet cache: %v\n", err)
+		return 1
+	}
+	tag, err := mcmaddr.Normalize(cache.RefillAddress)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: invalid wallet address in cache: %v\n", err)
+		return 1
+	}
+	balance, err := tagBalance(ctx, client, tag[:])
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error checking the wallet balance: %v\n", err)
+		return 1
+	}
+	if interval <= 0 {
+		interval = CHECK_MEMPOOL_INTERVAL * time.Second
+	}
+	blocks, err := client.WatchBlocks(ctx, interval)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error watching blocks: %v\n", err)
+		return 1
+	}
+	fmt.Fprintf(os.Stderr, "Watching deposits to %s, balance %s\n", cache.RefillAddress, amount.Describe(balance))
+	if target > 0 {
+		fmt.Fprintf(os.Stderr, "Waiting for %s of deposits\n", amount.Describe(target))
+	}
+	if alert != nil {
+		alert.RefillAddress = cache.RefillAddress
+		alert.Check(ctx, balance)
+	}
+
+	w := &depositWatcher{
+		client:   client,
+		tag:      tag[:],
+		out:      os.Stdout,
+		asJSON:   asJSON,
+		hook:     hook,
+		hashes:   monitor.BlockHashes{},
+		byHeight: make(map[uint64][]Deposit),
+	}
+	for event := range blocks {
+		if event.Err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: %v\n", event.Err)
+			continue
+		}
+		if event.Reorg {
+			w.reorg(ctx, event.Height)
+		}
+		w.scan(ctx, event.Height)
+		// Blocks deeper than a reorg can reach are forgotten
+		if event.Height > monitor.MaxReorgDepth {
+			delete(w.hashes, event.Height-monitor.MaxReorgDepth)
+			delete(w.byHeight, event.Height-monitor.MaxReorgDepth)
+		}
+
+		if current, err := tagBalance(ctx, client, tag[:]); err != nil {
+			if ctx.Err() == nil {
+				fmt.Fprintf(os.Stderr, "Warning: failed to check the wallet balance: %v\n", err)
+			}
+		} else {
+			if current != balance {
+				balance = current
+				w.emit(ctx, DepositEvent{Event: EventBalance, Balance: &current})
+			}
+			if alert != nil {
+				alert.Check(ctx, current)
+			}
+		}
+
+		if target > 0 && w.total >= target {
+			fmt.Fprintf(os.Stderr, "Target reached: %s deposited\n", amount.Describe(w.total))
+			return 0
+		}
+	}
+	// The watch only ends on interrupt, or once the target is reached
+	if target > 0 {
+		fmt.Fprintf(os.Stderr, "Interrupted: %s of %s deposited\n", amount.Describe(w.total), amount.Describe(target))
+		return shutdown.ExitInterrupted
+	}
+	return 0
+}
+
+// tagBalance returns the balance of a tag, 0 for a tag the chain has never seen
+func tagBalance(ctx context.Context, client *meshclient.MeshAPIClient, tag []byte) (uint64, error) {
+	resolution, err := client.ResolveTag(ctx, tag)
+	if errors.Is(err, meshclient.ErrTagNotFound) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	return resolution.Amount, nil
+}
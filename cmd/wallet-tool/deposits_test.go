@@ -0,0 +1,288 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/NickP005/Vindax-MCM-tools/pkg/config"
+	"github.com/NickP005/Vindax-MCM-tools/pkg/meshclient"
+	"github.com/NickP005/Vindax-MCM-tools/pkg/meshmock"
+	"github.com/NickP005/Vindax-MCM-tools/pkg/shutdown"
+	"github.com/NickP005/Vindax-MCM-tools/pkg/walletstore"
+	"github.com/NickP005/Vindax-MCM-tools/pkg/webhook"
+)
+
+// depositsTag is the tag of the wallet cache of the 0x17 secret, cr5m3GobqYe6BDY1jqdSNJMYsjADL5
+const depositsTag = "8412dab16d9ca3ba10498d9fab6a38936b1f3e78"
+
+func TestDepositsIn(t *testing.T) {
+	tag, _ := hex.DecodeString(depositsTag)
+	wallet := "0x" + depositsTag + strings.Repeat("00", 20)
+	block := meshclient.BlockIdentifier{Index: 7, Hash: "0xb7"}
+	var transfers []meshclient.Transfer
+	for _, tx := range []meshclient.Transaction{
+		transfer(1, wallet, 1000, "INV-1"),
+		transfer(2, "0x"+strings.Repeat("a1", 20), 700, ""),
+		pendingTx("0x03", "0x"+strings.Repeat("cc", 20), payment("0x"+depositsTag, 500, ""), payment("0x"+strings.Repeat("a1", 20), 5, ""), payment(wallet, 250, "split")),
+		// The wallet's own payment, crediting its change and itself
+		pendingTx("0x04", wallet, payment("0x"+strings.Repeat("a1", 20), 100, ""), payment("0x"+depositsTag, 300, "")),
+	} {
+		decoded, err := meshclient.DecodeTransfer(tx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		transfers = append(transfers, decoded)
+	}
+
+	other := AddrToBase58(bytes.Repeat([]byte{0xee}, 20))
+	want := []Deposit{
+		{Block: 7, BlockHash: "0xb7", TxID: transfers[0].TxID, Source: other, Amount: 1000, Memo: "INV-1"},
+		{Block: 7, BlockHash: "0xb7", TxID: "03", Source: AddrToBase58(bytes.Repeat([]byte{0xcc}, 20)), Amount: 500},
+		{Block: 7, BlockHash: "0xb7", TxID: "03", Source: AddrToBase58(bytes.Repeat([]byte{0xcc}, 20)), Amount: 250, Memo: "split"},
+	}
+	got := DepositsIn(transfers, tag, block)
+	if len(got) != len(want) {
+		t.Fatalf("deposits %+v", got)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("deposit %d: %+v, want %+v", i, got[i], want[i])
+		}
+	}
+}
+
+// watchRequests closes started once the block watcher read the tip it starts from, and counts the balance reads
+type watchRequests struct {
+	once     sync.Once
+	started  chan struct{}
+	mu       sync.Mutex
+	balances int
+}
+
+func (w *watchRequests) OnRequestStart(info meshclient.RequestInfo) {}
+
+func (w *watchRequests) OnRequestEnd(info meshclient.RequestInfo) {
+	if info.Err != nil {
+		return
+	}
+	switch info.Op {
+	case "/network/status":
+		w.once.Do(func() { close(w.started) })
+	case "/call":
+		w.mu.Lock()
+		w.balances++
+		w.mu.Unlock()
+	}
+}
+
+// depositsRun is a watch-deposits run against a mock, its events received through the webhook
+type depositsRun struct {
+	t        *testing.T
+	mock     *meshmock.Server
+	events   chan DepositEvent
+	requests *watchRequests
+}
+
+// waitBalanceReads waits until the watch read the balance n times, once at the start and once per block
+func (run *depositsRun) waitBalanceReads(n int) {
+	run.t.Helper()
+	for deadline := time.Now().Add(10 * time.Second); time.Now().Before(deadline); time.Sleep(5 * time.Millisecond) {
+		run.requests.mu.Lock()
+		balances := run.requests.balances
+		run.requests.mu.Unlock()
+		if balances >= n {
+			return
+		}
+	}
+	run.t.Fatalf("the balance was not read %d times", n)
+}
+
+// expect waits for the next events of the webhook, comparing their kind, amount and total
+func (run *depositsRun) expect(want ...string) {
+	run.t.Helper()
+	for _, w := range want {
+		select {
+		case event := <-run.events:
+			got := event.Event
+			switch {
+			case event.Deposit != nil:
+				got += " " + describeDeposit(*event.Deposit)
+			case event.Balance != nil:
+				got += " " + jsonString(*event.Balance)
+			}
+			got += " total " + jsonString(event.Total)
+			if got != w {
+				run.t.Errorf("event %q, want %q", got, w)
+			}
+		case <-time.After(10 * time.Second):
+			run.t.Fatalf("no event, want %q", w)
+		}
+	}
+}
+
+func describeDeposit(d Deposit) string {
+	line := jsonString(d.Amount) + " in " + jsonString(d.Block)
+	if d.Memo != "" {
+		line += " " + d.Memo
+	}
+	return line
+}
+
+func jsonString(v any) string {
+	data, _ := json.Marshal(v)
+	return string(data)
+}
+
+/*
+ * runDeposits watches the deposits to the 0x17 wallet from height 2 and a
+ * balance of 1000, polling every 20ms, until it returns; script runs once
+ * the watch started, or is cancelled by it; alert may be nil
+ */
+func runDeposits(t *testing.T, target uint64, alert *BalanceAlert, script func(run *depositsRun, cancel func())) (int, string, []DepositEvent) {
+	t.Helper()
+	mock := meshmock.New()
+	defer mock.Close()
+	mock.MineBlock()
+	mock.MineBlock()
+	tag, _ := hex.DecodeString(depositsTag)
+	mock.SetAccount(tag, "0x"+depositsTag+strings.Repeat("00", 20), 1000)
+
+	path := filepath.Join(t.TempDir(), "wallet-cache.json")
+	if err := walletstore.Save(path, &walletstore.WalletCache{SecretKey: strings.Repeat("17", 32)}); err != nil {
+		t.Fatal(err)
+	}
+	events := make(chan DepositEvent, 100)
+	var (
+		mu     sync.Mutex
+		posted []DepositEvent
+	)
+	hook := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body struct {
+			webhook.Event
+			Data DepositEvent `json:"data"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil || body.Tool != "wallet-tool" || body.Event.Event != body.Data.Event {
+			t.Errorf("webhook body %+v, %v", body, err)
+		}
+		mu.Lock()
+		posted = append(posted, body.Data)
+		mu.Unlock()
+		events <- body.Data
+	}))
+	defer hook.Close()
+
+	client := meshclient.NewMeshAPIClient(mock.URL(), nil)
+	requests := &watchRequests{started: make(chan struct{})}
+	client.SetHooks(requests)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	exit := -1
+	out := captureStdout(t, func() {
+		finished := make(chan struct{})
+		go func() {
+			defer close(finished)
+			exit = runWatchDeposits(ctx, client, path, target, true, config.Webhook{URL: hook.URL, Timeout: 5 * time.Second}, alert, 20*time.Millisecond)
+		}()
+		select {
+		case <-requests.started:
+			script(&depositsRun{t: t, mock: mock, events: events, requests: requests}, cancel)
+		case <-finished:
+			t.Error("watch ended before it started")
+			return
+		case <-time.After(10 * time.Second):
+			t.Error("watch never started")
+			cancel()
+		}
+		select {
+		case <-finished:
+		case <-time.After(20 * time.Second):
+			t.Error("watch did not end")
+			cancel()
+			<-finished
+		}
+	})
+	mu.Lock()
+	defer mu.Unlock()
+	return exit, out, posted
+}
+
+/*
+ * TestWatchDeposits spreads deposits over several blocks, one of them
+ * replaced by a reorg, until the target is reached
+ */
+func TestWatchDeposits(t *testing.T) {
+	tag, _ := hex.DecodeString(depositsTag)
+	wallet := "0x" + depositsTag + strings.Repeat("00", 20)
+	exit, out, posted := runDeposits(t, 3500, nil, func(run *depositsRun, cancel func()) {
+		// Block 3 pays the wallet and another account, block 4 nobody
+		run.mock.AddToMempool(transfer(1, wallet, 1000, "INV-1"))
+		run.mock.AddToMempool(transfer(2, "0x"+strings.Repeat("a1", 20), 700, ""))
+		run.mock.SetAccount(tag, wallet, 2000)
+		run.mock.MineBlock()
+		run.mock.MineBlock()
+		run.expect("deposit 1000 in 3 INV-1 total 1000", "balance 2000 total 1000")
+		run.waitBalanceReads(3)
+
+		// Block 5 pays the wallet twice in one transaction, next to the wallet's own payment
+		run.mock.AddToMempool(pendingTx("0x"+strings.Repeat("05", 32), "0x"+strings.Repeat("cc", 20), payment(wallet, 500, ""), payment(wallet, 250, "split")))
+		run.mock.AddToMempool(pendingTx("0x"+strings.Repeat("06", 32), wallet, payment("0x"+strings.Repeat("a1", 20), 100, ""), payment(wallet, 300, "")))
+		run.mock.SetAccount(tag, wallet, 2650)
+		run.mock.MineBlock()
+		run.expect("deposit 500 in 5 total 1500", "deposit 250 in 5 split total 1750", "balance 2650 total 1750")
+
+		// A reorg replaces block 5 with one paying only 500
+		run.mock.SetAccount(tag, wallet, 2500)
+		run.mock.ReorgTo(1, []meshclient.Transaction{pendingTx("0x"+strings.Repeat("07", 32), "0x"+strings.Repeat("cc", 20), payment(wallet, 500, ""))})
+		run.expect("reverted 500 in 5 total 1250", "reverted 250 in 5 split total 1000", "deposit 500 in 5 total 1500", "balance 2500 total 1500")
+
+		// Block 6 reaches the target
+		run.mock.AddToMempool(transfer(8, wallet, 2000, ""))
+		run.mock.SetAccount(tag, wallet, 4500)
+		run.mock.MineBlock()
+		run.expect("deposit 2000 in 6 total 3500", "balance 4500 total 3500")
+	})
+	if exit != 0 {
+		t.Errorf("exit %d", exit)
+	}
+
+	// Stdout holds the events posted, one JSON object per line
+	lines := strings.Split(strings.TrimSpace(out), "\n")
+	if len(lines) != len(posted) || len(posted) != 11 {
+		t.Fatalf("%d events posted, printed:\n%s", len(posted), out)
+	}
+	for i, line := range lines {
+		var printed DepositEvent
+		if err := json.Unmarshal([]byte(line), &printed); err != nil {
+			t.Fatal(err)
+		}
+		if jsonString(printed.Deposit) != jsonString(posted[i].Deposit) || printed.Event != posted[i].Event || printed.Total != posted[i].Total {
+			t.Errorf("line %d: %s, posted %+v", i+1, line, posted[i])
+		}
+	}
+	if d := posted[0].Deposit; d.Source != AddrToBase58(bytes.Repeat([]byte{0xee}, 20)) || d.TxID != strings.Repeat("0", 63)+"1" || d.BlockHash == "" {
+		t.Errorf("first deposit %+v", d)
+	}
+}
+
+// TestWatchDepositsInterrupted ends a watch short of its target: the exit code is the one of an interrupt
+func TestWatchDepositsInterrupted(t *testing.T) {
+	wallet := "0x" + depositsTag + strings.Repeat("00", 20)
+	exit, _, _ := runDeposits(t, 5000, nil, func(run *depositsRun, cancel func()) {
+		run.mock.AddToMempool(transfer(1, wallet, 1000, ""))
+		run.mock.MineBlock()
+		run.expect("deposit 1000 in 3 total 1000")
+		cancel()
+	})
+	if exit != shutdown.ExitInterrupted {
+		t.Errorf("exit %d", exit)
+	}
+}
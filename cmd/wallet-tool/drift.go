@@ -0,0 +1,77 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+
+	"github.com/NickP005/Vindax-MCM-tools/pkg/meshclient"
+	"github.com/NickP005/Vindax-MCM-tools/pkg/walletstore"
+)
+
+// IndexDiagnosis compares the wallet index that signed a transaction with the index of the key the tag belongs to
+type IndexDiagnosis struct {
+	Signed uint64
+	// Controlling is the index of the key the tag belongs to, meaningful only when Found
+	Controlling uint64
+	Found       bool
+	Source      walletstore.SourceState
+}
+
+/*
+ * DiagnoseIndex scans the wallet keys for the one the tag belongs to, once
+ * a transaction signed at index signed was rejected for its signature or
+ * never confirmed
+ *
+ * This is the failure mode of a wallet cache whose index drifted: the
+ * transaction is signed by a key that does not hold the funds, so it can
+ * never confirm. The scan goes through the derivation cache, so it is cheap
+ * on a wallet searched before; the keypairs it derives stay in keychain.
+ */
+func DiagnoseIndex(ctx context.Context, client *meshclient.MeshAPIClient, keychain *walletstore.Keychain, tag []byte, signed uint64) (IndexDiagnosis, error) {
+	source, err := walletstore.ResolveSource(ctx, client, tag)
+	if err != nil {
+		return IndexDiagnosis{}, fmt.Errorf("failed to resolve wallet tag: %v", err)
+	}
+	diagnosis := IndexDiagnosis{Signed: signed, Source: source}
+	diagnosis.Controlling, diagnosis.Found = source.FindIndex(keychain, 0, walletstore.MaxIndexSearch)
+	return diagnosis, nil
+}
+
+// Drifted reports whether the tag belongs to another key than the one that signed
+func (d IndexDiagnosis) Drifted() bool {
+	return !d.Found || d.Controlling != d.Signed
+}
+
+// Report prints what the scan found and, when a key of the wallet holds the funds, the command resuming from its index
+func (d IndexDiagnosis) Report(w io.Writer, walletCacheFile string, csvFile string) {
+	switch {
+	case !d.Source.Found:
+		fmt.Fprintf(w, "The wallet tag is not on chain: no key holds funds to spend. Check -history for what happened to them.\n")
+	case !d.Found:
+		fmt.Fprintf(w, "The wallet tag belongs to %s, which no key of this wallet below index %d derives: is %s the right wallet cache?\n",
+			d.Source.AddressHex, walletstore.MaxIndexSearch, walletCacheFile)
+	case d.Controlling == d.Signed:
+		fmt.Fprintf(w, "Index %d, which signed, is the key the wallet tag belongs to: the index is right, the node rejected the signature itself. Check it with -derive-check.\n", d.Signed)
+	case d.Controlling == d.Signed+1:
+		fmt.Fprintf(w, "The wallet tag belongs to the change key of the transaction, index %d (%d nMCM): it most likely confirmed, check -history before paying again.\n",
+			d.Controlling, d.Source.Balance)
+	default:
+		fmt.Fprintf(w, "The transaction was signed at index %d, but the wallet tag belongs to the key at index %d (%d nMCM).\n",
+			d.Signed, d.Controlling, d.Source.Balance)
+		fmt.Fprintf(w, "The wallet cache index drifted. Check -history, then pay from the right key with:\n")
+		fmt.Fprintf(w, "  wallet-tool -wallet %s -csv %s -from-index %d\n", walletCacheFile, csvFile, d.Controlling)
+	}
+}
+
+// scanIndex runs DiagnoseIndex, then wipes the keypairs it derived and saves their address hashes
+func scanIndex(ctx context.Context, client *meshclient.MeshAPIClient, keychain *walletstore.Keychain, hashes *walletstore.DerivationCache,
+	tag []byte, signed uint64) (IndexDiagnosis, error) {
+	fmt.Println("Scanning the wallet keys for the one the tag belongs to...")
+	diagnosis, err := DiagnoseIndex(ctx, client, keychain, tag, signed)
+	keychain.Wipe()
+	if err := hashes.Save(); err != nil {
+		fmt.Printf("Warning: %v\n", err)
+	}
+	return diagnosis, err
+}
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"strings"
+	"testing"
+
+	"github.com/NickP005/Vindax-MCM-tools/pkg/meshclient"
+	"github.com/NickP005/Vindax-MCM-tools/pkg/meshmock"
+	"github.com/NickP005/Vindax-MCM-tools/pkg/walletstore"
+)
+
+// TestDiagnoseIndex scans for the key of a tag held by index 5, after signatures at several indices
+func TestDiagnoseIndex(t *testing.T) {
+	keychain, err := walletstore.NewKeychain(strings.Repeat("17", 32))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer keychain.Wipe()
+	tag, hash := keychain.Tag(), keychain.AddrHash(5)
+	mock := meshmock.New()
+	defer mock.Close()
+	mock.SetAccount(tag[:], "0x"+hex.EncodeToString(tag[:])+hex.EncodeToString(hash[:]), 1000)
+	client := meshclient.NewMeshAPIClient(mock.URL(), nil)
+
+	for _, tc := range []struct {
+		signed  uint64
+		drifted bool
+		report  string
+	}{
+		{3, true, "wallet-tool -wallet wallet.json -csv payout.csv -from-index 5"},
+		{5, false, "Index 5, which signed, is the key the wallet tag belongs to"},
+		{4, true, "belongs to the change key of the transaction, index 5 (1000 nMCM)"},
+	} {
+		diagnosis, err := DiagnoseIndex(context.Background(), client, keychain, tag[:], tc.signed)
+		if err != nil || !diagnosis.Found || diagnosis.Controlling != 5 || diagnosis.Drifted() != tc.drifted {
+			t.Errorf("signed at %d: %+v, %v", tc.signed, diagnosis, err)
+			continue
+		}
+		var report bytes.Buffer
+		diagnosis.Report(&report, "wallet.json", "payout.csv")
+		if !strings.Contains(report.String(), tc.report) {
+			t.Errorf("signed at %d: report %q", tc.signed, report.String())
+		}
+	}
+
+	// A tag held by no key of the wallet, then gone from the chain
+	mock.SetAccount(tag[:], "0x"+hex.EncodeToString(tag[:])+strings.Repeat("ee", 20), 1000)
+	diagnosis, err := DiagnoseIndex(context.Background(), client, keychain, tag[:], 3)
+	var report bytes.Buffer
+	if diagnosis.Report(&report, "wallet.json", "payout.csv"); err != nil || diagnosis.Found || !strings.Contains(report.String(), "is wallet.json the right wallet cache?") {
+		t.Errorf("foreign key: %+v, %v, %q", diagnosis, err, report.String())
+	}
+	mock.SetAccount(tag[:], "", 0)
+	diagnosis, err = DiagnoseIndex(context.Background(), client, keychain, tag[:], 3)
+	report.Reset()
+	if diagnosis.Report(&report, "wallet.json", "payout.csv"); err != nil || !strings.Contains(report.String(), "The wallet tag is not on chain") {
+		t.Errorf("tag gone: %+v, %v, %q", diagnosis, err, report.String())
+	}
+}
+
+/*
+ * TestSendDriftedIndex reproduces a drifted wallet cache: it says index 5
+ * while the funds are at index 7, so the node rejects the signature and
+ * the run prints the command paying from index 7
+ */
+func TestSendDriftedIndex(t *testing.T) {
+	r := newSendRun(t, 7)
+	result, out := r.send(500)
+	if result.Exit != 1 || r.signatures() != 1 {
+		t.Fatalf("exit %d, %d signatures", result.Exit, r.signatures())
+	}
+	for _, want := range []string{
+		"signature rejected",
+		"The transaction was signed at index 5, but the wallet tag belongs to the key at index 7 (1000 nMCM)",
+		"-from-index 7",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("output %q lacks %q", out, want)
+		}
+	}
+	if balance, _ := r.mock.Balance(r.tag[:]); balance != 1000 {
+		t.Errorf("balance %d", balance)
+	}
+}
@@ -0,0 +1,429 @@
+package main
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/NickP005/Vindax-MCM-tools/pkg/amount"
+	"github.com/NickP005/Vindax-MCM-tools/pkg/fileutil"
+	"github.com/NickP005/Vindax-MCM-tools/pkg/mcmaddr"
+	"github.com/NickP005/Vindax-MCM-tools/pkg/meshclient"
+	"github.com/NickP005/Vindax-MCM-tools/pkg/shutdown"
+)
+
+// EXPORT_CHECKPOINT_EVERY is how many blocks export-history walks between two checkpoints when none has rows
+const EXPORT_CHECKPOINT_EVERY = 100
+
+// exportHeader is the header line of the export-history CSV
+var exportHeader = []string{"block", "timestamp", "txid", "direction", "counterparty", "amount", "memo", "fee_share"}
+
+/*
+ * ExportRow is one movement of the exported address
+ *
+ * Direction is "in" for a payment received, "out" for a payment sent and
+ * "self" for a payment the address sent to its own tag, change excluded.
+ * The fee of a transaction sent is shared among its rows, the first one
+ * taking the remainder, so the shares add up to the fee.
+ */
+type ExportRow struct {
+	Block        uint64
+	Time         time.Time
+	TxID         string
+	Direction    string
+	Counterparty string
+	Amount       uint64
+	Memo         string
+	FeeShare     uint64
+}
+
+// record returns the row as CSV fields, in the order of exportHeader
+func (r ExportRow) record() []string {
+	timestamp := ""
+	if !r.Time.IsZero() {
+		timestamp = r.Time.UTC().Format(time.RFC3339)
+	}
+	return []string{strconv.FormatUint(r.Block, 10), timestamp, r.TxID, r.Direction, r.Counterparty,
+		strconv.FormatUint(r.Amount, 10), r.Memo, strconv.FormatUint(r.FeeShare, 10)}
+}
+
+/*
+ * ExportRows returns the movements of a tag in a decoded transfer
+ *
+ * A transfer from the tag gives a row per destination, the counterparty
+ * being the destination; one without destination gives a single row
+ * holding its fee. A transfer to the tag gives a row per payment to it,
+ * the counterparty being the source.
+ */
+func ExportRows(transfer meshclient.Transfer, tag []byte, block uint64, at time.Time) []ExportRow {
+	tagHex := hex.EncodeToString(tag)
+	var rows []ExportRow
+	if transfer.Source != "" && touchesTag(transfer.Source, tagHex) {
+		for _, payment := range transfer.Destinations {
+			direction := "out"
+			if touchesTag(payment.Address, tagHex) {
+				direction = "self"
+			}
+			rows = append(rows, ExportRow{Direction: direction, Counterparty: tagAddress(payment.Address), Amount: payment.Amount, Memo: payment.Memo})
+		}
+		if len(rows) == 0 {
+			rows = append(rows, ExportRow{Direction: "out"})
+		}
+		share := transfer.Fee / uint64(len(rows))
+		for i := range rows {
+			rows[i].FeeShare = share
+		}
+		rows[0].FeeShare += transfer.Fee % uint64(len(rows))
+	} else {
+		for _, payment := range transfer.PaidTo(tag) {
+			rows = append(rows, ExportRow{Direction: "in", Counterparty: tagAddress(transfer.Source), Amount: payment.Amount, Memo: payment.Memo})
+		}
+	}
+	for i := range rows {
+		rows[i].Block, rows[i].Time, rows[i].TxID = block, at, transfer.TxID
+	}
+	return rows
+}
+
+/*
+ * ExportCheckpoint is the progress of an export, saved next to the CSV so
+ * an interrupted export continues where it stopped
+ *
+ * Fields:
+ * - Address, From, To: the export, hex tag and block range, which a resumed run must match
+ * - Next: the first block not exported yet
+ * - Size: the bytes of the CSV holding the rows of the blocks below Next;
+ *   anything written after it by an interrupted run is cut off on resume
+ * - In, Out, Fees: the totals of the rows written, for the footer
+ * - Done: the footer is written, the export is complete
+ */
+type ExportCheckpoint struct {
+	Address string `json:"address"`
+	From    uint64 `json:"from"`
+	To      uint64 `json:"to"`
+	Next    uint64 `json:"next"`
+	Size    int64  `json:"size"`
+	Rows    int    `json:"rows"`
+	In      uint64 `json:"in"`
+	Out     uint64 `json:"out"`
+	Fees    uint64 `json:"fees"`
+	Done    bool   `json:"done"`
+}
+
+// ReadExportCheckpoint reads a checkpoint written by WriteExportCheckpoint; a missing file returns nil and no error
+func ReadExportCheckpoint(path string) (*ExportCheckpoint, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var checkpoint ExportCheckpoint
+	if err := json.Unmarshal(data, &checkpoint); err != nil {
+		return nil, fmt.Errorf("invalid checkpoint file %s: %v", path, err)
+	}
+	return &checkpoint, nil
+}
+
+// WriteExportCheckpoint replaces the checkpoint file atomically and durably, see fileutil.WriteAtomic
+func WriteExportCheckpoint(path string, checkpoint ExportCheckpoint) error {
+	data, err := json.MarshalIndent(checkpoint, "", "  ")
+	if err != nil {
+		return err
+	}
+	return fileutil.WriteAtomic(path, append(data, '\n'), true)
+}
+
+// ExportOptions are the flags of export-history
+type ExportOptions struct {
+	// Address is the address to export, the wallet's when empty
+	Address    string
+	From, To   uint64
+	Out        string
+	Checkpoint string
+}
+
+// exportWriter appends rows to the CSV and keeps the checkpoint in step with it
+type exportWriter struct {
+	file       *os.File
+	csv        *csv.Writer
+	path       string
+	checkpoint ExportCheckpoint
+}
+
+// write appends rows and adds them to the totals
+func (w *exportWriter) write(rows []ExportRow) error {
+	for _, row := range rows {
+		if err := w.csv.Write(row.record()); err != nil {
+			return err
+		}
+		w.checkpoint.Rows++
+		w.checkpoint.Fees += row.FeeShare
+		switch row.Direction {
+		case "in":
+			w.checkpoint.In += row.Amount
+		case "out":
+			w.checkpoint.Out += row.Amount
+		}
+	}
+	return nil
+}
+
+// save syncs the CSV, then records in the checkpoint that it holds every block below next
+func (w *exportWriter) save(next uint64) error {
+	w.csv.Flush()
+	if err := w.csv.Error(); err != nil {
+		return err
+	}
+	if err := w.file.Sync(); err != nil {
+		return err
+	}
+	size, err := w.file.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return err
+	}
+	w.checkpoint.Next, w.checkpoint.Size = next, size
+	return WriteExportCheckpoint(w.path, w.checkpoint)
+}
+
+// footer appends the grand totals: received, sent with the fees, and the net change of the balance
+func (w *exportWriter) footer() error {
+	c := w.checkpoint
+	net := int64(c.In) - int64(c.Out) - int64(c.Fees)
+	for _, record := range [][]string{
+		{"total", "", "", "in", "", strconv.FormatUint(c.In, 10), "", ""},
+		{"total", "", "", "out", "", strconv.FormatUint(c.Out, 10), "", strconv.FormatUint(c.Fees, 10)},
+		{"total", "", "", "net", "", strconv.FormatInt(net, 10), "", ""},
+	} {
+		if err := w.csv.Write(record); err != nil {
+			return err
+		}
+	}
+	w.checkpoint.Done = true
+	return w.save(c.To + 1)
+}
+
+// openExport opens the CSV of an export: a new file, or the file of checkpoint cut to the size it records
+func openExport(path string, checkpoint *ExportCheckpoint) (*os.File, error) {
+	if checkpoint != nil {
+		file, err := os.OpenFile(path, os.O_RDWR, 0)
+		if err != nil {
+			return nil, err
+		}
+		if err := file.Truncate(checkpoint.Size); err != nil {
+			file.Close()
+			return nil, err
+		}
+		if _, err := file.Seek(checkpoint.Size, io.SeekStart); err != nil {
+			file.Close()
+			return nil, err
+		}
+		return file, nil
+	}
+	// Without a checkpoint, an existing file is someone else's export: it is never overwritten
+	file, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE|os.O_EXCL, 0o600)
+	if errors.Is(err, os.ErrExist) {
+		return nil, fmt.Errorf("%s already exists and has no checkpoint to resume from", path)
+	}
+	return file, err
+}
+
+/*
+ * exportHeights returns the blocks of from..to holding transactions of
+ * tag, through /search/transactions, following its pages
+ *
+ * Returns false when the server does not offer the search, so the caller
+ * walks every block instead.
+ */
+func exportHeights(ctx context.Context, client *meshclient.MeshAPIClient, tag []byte, from uint64, to uint64) ([]uint64, bool, error) {
+	hits, _, err := client.SearchAllTransactions(ctx, meshclient.SearchQuery{Tag: tag, Limit: 100}, 0)
+	var meshErr *meshclient.MeshError
+	if errors.As(err, &meshErr) {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, err
+	}
+	seen := make(map[uint64]bool)
+	var heights []uint64
+	for _, hit := range hits {
+		height := hit.BlockIdentifier.Index
+		if height >= from && height <= to && !seen[height] {
+			seen[height] = true
+			heights = append(heights, height)
+		}
+	}
+	sort.Slice(heights, func(i, j int) bool { return heights[i] < heights[j] })
+	return heights, true, nil
+}
+
+/*
+ * runExportHistory implements `wallet-tool export-history`: it writes the
+ * movements of an address in a block range to a CSV, one row per payment
+ * with its block, time, transaction, direction, counterparty, amount, memo
+ * and share of the fee, followed by the grand totals
+ *
+ * The blocks holding transactions of the address are found through
+ * /search/transactions when the server offers it; otherwise every block of
+ * the range is read. Each block is decoded as mcm-block decodes it (see
+ * meshclient.BlockTransfers). The checkpoint is saved after every block
+ * with rows and every EXPORT_CHECKPOINT_EVERY blocks: an interrupted or
+ * failed export is resumed by running the same command again.
+ */
+func runExportHistory(ctx context.Context, client *meshclient.MeshAPIClient, walletCacheFile string, opts ExportOptions) int {
+	address := opts.Address
+	if address == "" {
+		cache, err := ReadWalletCache(walletCacheFile)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error with wallet cache: %v\n", err)
+			return 1
+		}
+		address = cache.RefillAddress
+	}
+	tag, err := mcmaddr.Normalize(address)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: invalid address %q: %v\n", address, err)
+		return 1
+	}
+	checkpointPath := opts.Checkpoint
+	if checkpointPath == "" {
+		checkpointPath = opts.Out + ".checkpoint.json"
+	}
+	checkpoint, err := ReadExportCheckpoint(checkpointPath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+
+	to := opts.To
+	if checkpoint != nil && to == 0 {
+		// The tip of the first run ends the range, so a resumed export covers the same blocks
+		to = checkpoint.To
+	}
+	if to == 0 {
+		status, err := client.NetworkStatus(ctx)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error reading the chain tip: %v\n", err)
+			return 1
+		}
+		to = status.CurrentBlockIdentifier.Index
+	}
+	if opts.From > to {
+		fmt.Fprintf(os.Stderr, "Error: -from-block %d is past -to-block %d\n", opts.From, to)
+		return 2
+	}
+	tagHex := hex.EncodeToString(tag[:])
+	if checkpoint != nil {
+		if checkpoint.Address != tagHex || checkpoint.From != opts.From || checkpoint.To != to {
+			fmt.Fprintf(os.Stderr, "Error: %s is the checkpoint of another export (blocks %d to %d of %s); remove it or choose another -out\n",
+				checkpointPath, checkpoint.From, checkpoint.To, tagAddress(checkpoint.Address))
+			return 1
+		}
+		if checkpoint.Done {
+			fmt.Fprintf(os.Stderr, "%s is complete: %d rows for blocks %d to %d\n", opts.Out, checkpoint.Rows, checkpoint.From, checkpoint.To)
+			return 0
+		}
+		fmt.Fprintf(os.Stderr, "Resuming the export at block %d (%d rows so far)\n", checkpoint.Next, checkpoint.Rows)
+	}
+
+	file, err := openExport(opts.Out, checkpoint)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	defer file.Close()
+	w := &exportWriter{file: file, csv: csv.NewWriter(file), path: checkpointPath}
+	if checkpoint != nil {
+		w.checkpoint = *checkpoint
+	} else {
+		w.checkpoint = ExportCheckpoint{Address: tagHex, From: opts.From, To: to, Next: opts.From}
+		if err := w.csv.Write(exportHeader); err != nil {
+			fmt.Fprintf(os.Stderr, "Error writing %s: %v\n", opts.Out, err)
+			return 1
+		}
+		if err := w.save(opts.From); err != nil {
+			fmt.Fprintf(os.Stderr, "Error writing %s: %v\n", opts.Out, err)
+			return 1
+		}
+	}
+
+	// The search only narrows the blocks to read: every row comes from the decoded block
+	heights, searched, err := exportHeights(ctx, client, tag[:], w.checkpoint.Next, to)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error searching transactions: %v\n", err)
+		return 1
+	}
+	if searched {
+		fmt.Fprintf(os.Stderr, "Exporting %s: %d blocks with transactions between %d and %d\n", tagAddress(tagHex), len(heights), w.checkpoint.Next, to)
+	} else {
+		fmt.Fprintf(os.Stderr, "Exporting %s: the server has no transaction search, reading blocks %d to %d\n", tagAddress(tagHex), w.checkpoint.Next, to)
+		for h := w.checkpoint.Next; h <= to; h++ {
+			heights = append(heights, h)
+		}
+	}
+
+	stop := func(height uint64, code int, format string, args ...interface{}) int {
+		fmt.Fprintf(os.Stderr, format, args...)
+		if err := w.save(height); err != nil {
+			fmt.Fprintf(os.Stderr, "Error saving the checkpoint: %v\n", err)
+			return 1
+		}
+		fmt.Fprintf(os.Stderr, "%d rows written up to block %d; run the same command again to resume\n", w.checkpoint.Rows, height)
+		return code
+	}
+	unsaved := 0
+	for _, height := range heights {
+		if ctx.Err() != nil {
+			return stop(height, shutdown.ExitInterrupted, "Interrupted\n")
+		}
+		block, err := client.Block(ctx, height)
+		var transfers []meshclient.Transfer
+		if err == nil {
+			transfers, err = client.BlockTransfers(ctx, block)
+		}
+		if err != nil {
+			if ctx.Err() != nil {
+				return stop(height, shutdown.ExitInterrupted, "Interrupted\n")
+			}
+			return stop(height, 1, "Error reading block %d: %v\n", height, err)
+		}
+
+		at := time.Time{}
+		if block.Block.Timestamp > 0 {
+			at = time.UnixMilli(block.Block.Timestamp)
+		}
+		var rows []ExportRow
+		for _, transfer := range transfers {
+			rows = append(rows, ExportRows(transfer, tag[:], height, at)...)
+		}
+		if err := w.write(rows); err != nil {
+			fmt.Fprintf(os.Stderr, "Error writing %s: %v\n", opts.Out, err)
+			return 1
+		}
+		if unsaved++; len(rows) > 0 || unsaved >= EXPORT_CHECKPOINT_EVERY {
+			if err := w.save(height + 1); err != nil {
+				fmt.Fprintf(os.Stderr, "Error writing %s: %v\n", opts.Out, err)
+				return 1
+			}
+			unsaved = 0
+		}
+	}
+
+	if err := w.footer(); err != nil {
+		fmt.Fprintf(os.Stderr, "Error writing %s: %v\n", opts.Out, err)
+		return 1
+	}
+	c := w.checkpoint
+	fmt.Fprintf(os.Stderr, "Exported %d rows to %s: %s in, %s out, %s in fees\n", c.Rows, opts.Out,
+		amount.Describe(c.In), amount.Describe(c.Out), amount.Describe(c.Fees))
+	return 0
+}
@@ -0,0 +1,273 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/NickP005/Vindax-MCM-tools/pkg/meshclient"
+	"github.com/NickP005/Vindax-MCM-tools/pkg/meshmock"
+	"github.com/NickP005/Vindax-MCM-tools/pkg/shutdown"
+	"github.com/NickP005/Vindax-MCM-tools/pkg/walletstore"
+)
+
+// exportChain is a mock chain scripted with the movements of a wallet, and the rows expected from its export
+type exportChain struct {
+	mock   *meshmock.Server
+	tag    []byte
+	wallet string
+	// rows are the expected records, the timestamp left empty
+	rows [][]string
+}
+
+// withFee adds a FEE operation to a transaction
+func withFee(tx meshclient.Transaction, fee int64) meshclient.Transaction {
+	tx.Operations = append(tx.Operations, meshclient.Operation{Type: meshclient.OpFee,
+		Amount: &meshclient.Amount{Value: fmt.Sprint(-fee), Currency: meshclient.MCM}})
+	return tx
+}
+
+/*
+ * newExportChain mines 36 transactions of the wallet, three per block from
+ * height 1, next to transactions of other accounts; the blocks list two
+ * transactions inline, so the others are read through /block/transaction,
+ * and the search lists them over two pages
+ */
+func newExportChain(t *testing.T) *exportChain {
+	t.Helper()
+	mock := meshmock.New()
+	t.Cleanup(mock.Close)
+	mock.SetBlockLimit(2)
+	tag, _ := hex.DecodeString(depositsTag)
+	c := &exportChain{mock: mock, tag: tag, wallet: "0x" + depositsTag + strings.Repeat("00", 20)}
+	alice, bob := "0x"+strings.Repeat("a1", 40), "0x"+strings.Repeat("b0", 40)
+	other := AddrToBase58(bytes.Repeat([]byte{0xee}, 20))
+
+	var pending [][]string
+	for i := 0; i < 36; i++ {
+		txID := fmt.Sprintf("%064x", i)
+		switch i % 4 {
+		case 0:
+			// A payment received, with a memo
+			mock.AddToMempool(transfer(i, c.wallet, int64(1000+i), fmt.Sprintf("INV-%d", i)))
+			pending = append(pending, []string{"", "", txID, "in", other, strconv.Itoa(1000 + i), fmt.Sprintf("INV-%d", i), "0"})
+		case 1:
+			// A payment to two destinations and the change, the fee of 7 shared 4 and 3
+			mock.AddToMempool(withFee(pendingTx("0x"+txID, c.wallet, payment(alice, int64(100+i), fmt.Sprintf("PAY-%d", i)), payment(bob, 50, ""), payment(c.wallet, 900, "")), 7))
+			pending = append(pending,
+				[]string{"", "", txID, "out", AddrToBase58(bytes.Repeat([]byte{0xa1}, 20)), strconv.Itoa(100 + i), fmt.Sprintf("PAY-%d", i), "4"},
+				[]string{"", "", txID, "out", AddrToBase58(bytes.Repeat([]byte{0xb0}, 20)), "50", "", "3"})
+		case 2:
+			// A payment received without memo, and one between two other accounts
+			mock.AddToMempool(transfer(i, c.wallet, 250, ""))
+			mock.AddToMempool(transfer(1000+i, alice, 5, ""))
+			pending = append(pending, []string{"", "", txID, "in", other, "250", "", "0"})
+		case 3:
+			// The wallet only moves its funds to its change: a single row holding the fee
+			mock.AddToMempool(withFee(pendingTx("0x"+txID, c.wallet, payment(c.wallet, 500, "")), 5))
+			pending = append(pending, []string{"", "", txID, "out", "", "0", "", "5"})
+		}
+		if i%3 == 2 {
+			height := mock.MineBlock()
+			for _, row := range pending {
+				row[0] = strconv.FormatUint(height, 10)
+			}
+			c.rows = append(c.rows, pending...)
+			pending = nil
+			// A block without any transaction of the wallet
+			mock.AddToMempool(transfer(2000+i, bob, 5, ""))
+			mock.MineBlock()
+		}
+	}
+	return c
+}
+
+// rowsIn returns the expected rows of the blocks from..to
+func (c *exportChain) rowsIn(from uint64, to uint64) [][]string {
+	var rows [][]string
+	for _, row := range c.rows {
+		if block, _ := strconv.ParseUint(row[0], 10, 64); block >= from && block <= to {
+			rows = append(rows, row)
+		}
+	}
+	return rows
+}
+
+// footer returns the total records of rows
+func footer(rows [][]string) [][]string {
+	var in, out, fees int64
+	for _, row := range rows {
+		value, _ := strconv.ParseInt(row[5], 10, 64)
+		fee, _ := strconv.ParseInt(row[7], 10, 64)
+		fees += fee
+		if row[3] == "in" {
+			in += value
+		} else {
+			out += value
+		}
+	}
+	return [][]string{
+		{"total", "", "", "in", "", fmt.Sprint(in), "", ""},
+		{"total", "", "", "out", "", fmt.Sprint(out), "", fmt.Sprint(fees)},
+		{"total", "", "", "net", "", fmt.Sprint(in - out - fees), "", ""},
+	}
+}
+
+// checkExport compares an exported CSV with the header, rows and footer expected, every row but the footer having a timestamp
+func checkExport(t *testing.T, path string, rows [][]string) {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	records, err := csv.NewReader(bytes.NewReader(data)).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := append(append([][]string{exportHeader}, rows...), footer(rows)...)
+	if len(records) != len(want) {
+		t.Fatalf("%d records, want %d:\n%s", len(records), len(want), data)
+	}
+	for i, record := range records {
+		if i > 0 && i <= len(rows) {
+			if _, err := time.Parse(time.RFC3339, record[1]); err != nil {
+				t.Errorf("record %d: timestamp %q", i, record[1])
+			}
+			record[1] = ""
+		}
+		if strings.Join(record, ",") != strings.Join(want[i], ",") {
+			t.Errorf("record %d: %q, want %q", i, record, want[i])
+		}
+	}
+}
+
+// TestExportHistory exports the whole chain, through the search and by reading every block, and a range of it
+func TestExportHistory(t *testing.T) {
+	c := newExportChain(t)
+	client := meshclient.NewMeshAPIClient(c.mock.URL(), nil)
+	path := filepath.Join(t.TempDir(), "wallet-cache.json")
+	walletstore.Save(path, &walletstore.WalletCache{SecretKey: strings.Repeat("17", 32)})
+	dir := t.TempDir()
+
+	out := filepath.Join(dir, "searched.csv")
+	if exit := runExportHistory(context.Background(), client, path, ExportOptions{Out: out}); exit != 0 {
+		t.Fatalf("exit %d", exit)
+	}
+	checkExport(t, out, c.rows)
+	if len(c.rows) != 45 {
+		t.Errorf("%d rows scripted", len(c.rows))
+	}
+
+	// A server without the search gives the same export
+	c.mock.Fail("/search/transactions", meshmock.Fault{Status: http.StatusNotFound})
+	walked := filepath.Join(dir, "walked.csv")
+	if exit := runExportHistory(context.Background(), client, path, ExportOptions{Out: walked}); exit != 0 {
+		t.Fatalf("walked: exit %d", exit)
+	}
+	checkExport(t, walked, c.rows)
+
+	// A range of blocks, of an address given as hex, its totals only of the range
+	ranged := filepath.Join(dir, "ranged.csv")
+	if exit := runExportHistory(context.Background(), client, "", ExportOptions{Address: "0x" + depositsTag, From: 5, To: 10, Out: ranged}); exit != 0 {
+		t.Fatalf("range: exit %d", exit)
+	}
+	checkExport(t, ranged, c.rowsIn(5, 10))
+
+	// A complete export is left as is, an existing file without checkpoint refused
+	if exit := runExportHistory(context.Background(), client, path, ExportOptions{Out: out}); exit != 0 {
+		t.Errorf("complete export: exit %d", exit)
+	}
+	checkExport(t, out, c.rows)
+	os.Remove(out + ".checkpoint.json")
+	if exit := runExportHistory(context.Background(), client, path, ExportOptions{Out: out}); exit != 1 {
+		t.Errorf("existing file: exit %d", exit)
+	}
+	// The checkpoint of another export is refused
+	if exit := runExportHistory(context.Background(), client, path, ExportOptions{From: 3, Out: ranged}); exit != 1 {
+		t.Errorf("other range: exit %d", exit)
+	}
+	checkExport(t, ranged, c.rowsIn(5, 10))
+}
+
+// cancelAfter cancels an export once it read n blocks
+type cancelAfter struct {
+	mu     sync.Mutex
+	n      int
+	cancel func()
+}
+
+func (c *cancelAfter) OnRequestStart(info meshclient.RequestInfo) {}
+
+func (c *cancelAfter) OnRequestEnd(info meshclient.RequestInfo) {
+	if info.Op != "/block" {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.n--; c.n == 0 {
+		c.cancel()
+	}
+}
+
+/*
+ * TestExportHistoryResume interrupts an export, leaves a partial row after
+ * its checkpoint as a crash would, fails the resumed run on a block, and
+ * resumes it again: the CSV is the one of an export never stopped
+ */
+func TestExportHistoryResume(t *testing.T) {
+	c := newExportChain(t)
+	path := filepath.Join(t.TempDir(), "wallet-cache.json")
+	walletstore.Save(path, &walletstore.WalletCache{SecretKey: strings.Repeat("17", 32)})
+	out := filepath.Join(t.TempDir(), "history.csv")
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	client := meshclient.NewMeshAPIClient(c.mock.URL(), nil)
+	client.SetHooks(&cancelAfter{n: 8, cancel: cancel})
+	// Every block is read, so the checkpoint moves block by block
+	c.mock.Fail("/search/transactions", meshmock.Fault{Status: http.StatusNotFound})
+	if exit := runExportHistory(ctx, client, path, ExportOptions{Out: out}); exit != shutdown.ExitInterrupted {
+		t.Fatalf("interrupted: exit %d", exit)
+	}
+	checkpoint, err := ReadExportCheckpoint(out + ".checkpoint.json")
+	if err != nil || checkpoint == nil || checkpoint.Done || checkpoint.Next == 0 || checkpoint.Next > 8 || checkpoint.To != c.mock.Height() {
+		t.Fatalf("checkpoint %+v, %v", checkpoint, err)
+	}
+	if want := len(c.rowsIn(0, checkpoint.Next-1)); checkpoint.Rows != want {
+		t.Errorf("checkpoint of %d rows, want %d", checkpoint.Rows, want)
+	}
+	file, _ := os.OpenFile(out, os.O_APPEND|os.O_WRONLY, 0)
+	file.WriteString("8,2025-01-02T03:04:05Z,00")
+	file.Close()
+
+	// A block the server fails to send stops the export where it is
+	client = meshclient.NewMeshAPIClient(c.mock.URL(), nil)
+	c.mock.Fail("/search/transactions", meshmock.Fault{Status: http.StatusNotFound})
+	c.mock.Fail("/block", meshmock.Fault{Status: http.StatusBadGateway})
+	if exit := runExportHistory(context.Background(), client, path, ExportOptions{Out: out}); exit != 1 {
+		t.Fatalf("failed block: exit %d", exit)
+	}
+	if failed, _ := ReadExportCheckpoint(out + ".checkpoint.json"); failed == nil || failed.Next != checkpoint.Next || failed.Rows != checkpoint.Rows {
+		t.Fatalf("checkpoint after the failure %+v, before %+v", failed, checkpoint)
+	}
+
+	// New blocks after the first run are not part of the export
+	c.mock.AddToMempool(transfer(99, c.wallet, 7, ""))
+	c.mock.MineBlock()
+	if exit := runExportHistory(context.Background(), client, path, ExportOptions{Out: out}); exit != 0 {
+		t.Fatalf("resumed: exit %d", exit)
+	}
+	checkExport(t, out, c.rows)
+	if done, _ := ReadExportCheckpoint(out + ".checkpoint.json"); done == nil || !done.Done || done.Rows != len(c.rows) {
+		t.Errorf("final checkpoint %+v", done)
+	}
+}
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/NickP005/Vindax-MCM-tools/internal/clitest"
+	"github.com/NickP005/Vindax-MCM-tools/pkg/mcmaddr"
+	"github.com/NickP005/Vindax-MCM-tools/pkg/meshclient"
+	"github.com/NickP005/Vindax-MCM-tools/pkg/meshmock"
+)
+
+/*
+ * TestGoldenValidation reads a payout file with an entry of every kind and
+ * pins what a run prints and writes about it: the console listing, the
+ * summary, the rejected file and the JSON report
+ */
+func TestGoldenValidation(t *testing.T) {
+	known, fresh, own := destination(0, 0x0a), destination(0, 0x0b), destination(0, 0x0f)
+	mock := meshmock.New()
+	defer mock.Close()
+	mock.SetAccount(known.tag[:], "0x"+hex.EncodeToString(known.tag[:])+strings.Repeat("00", mcmaddr.TagLength), 700)
+	client := meshclient.NewMeshAPIClient(mock.URL(), nil)
+
+	path := filepath.Join(t.TempDir(), "entries.csv")
+	lines := []string{
+		known.entry.Address + " 1,500 INV-12",
+		mcmaddr.To58(fresh.tag) + " 0.5mcm",
+		"zzz 100",
+		mcmaddr.To58(own.tag) + " 100",
+		fresh.entry.Address + " ten",
+		fresh.entry.Address + " 100 \"inv 12\"",
+		fresh.entry.Address + " 100 INV-1 extra",
+	}
+	if err := os.WriteFile(path, []byte(strings.Join(lines, "\n")+"\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, requireExisting := range []bool{false, true} {
+		name := "validation"
+		if requireExisting {
+			name += "-require-existing"
+		}
+		t.Run(name, func(t *testing.T) {
+			var report *ValidationReport
+			var err error
+			console := captureStdout(t, func() {
+				report, err = ReadEntriesCSV(context.Background(), client, path, own.tag, requireExisting)
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			var summary, rejected, jsonReport bytes.Buffer
+			report.Print(&summary)
+			if err := report.WriteRejected(&rejected); err != nil {
+				t.Fatal(err)
+			}
+			if err := report.WriteJSON(&jsonReport); err != nil {
+				t.Fatal(err)
+			}
+			clitest.CheckGolden(t, name, fmt.Sprintf("exit %d\n-- console --\n%s-- summary --\n%s-- rejected --\n%s-- json --\n%s",
+				report.ExitCode(), console, summary.String(), rejected.String(), jsonReport.String()))
+		})
+	}
+}
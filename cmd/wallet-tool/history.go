@@ -0,0 +1,140 @@
+package main
+
+import (
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/NickP005/Vindax-MCM-tools/pkg/mcmaddr"
+	"github.com/NickP005/Vindax-MCM-tools/pkg/meshclient"
+)
+
+// HistoryEntry is one transaction touching the wallet, as listed by -history
+type HistoryEntry struct {
+	Block     uint64   `json:"block"`
+	TxID      string   `json:"tx_id"`
+	Direction string   `json:"direction"` // "in", "out", "self" or "unknown"
+	Amount    int64    `json:"amount"`    // net change of the wallet balance in nanoMCM
+	Memos     []string `json:"memos,omitempty"`
+}
+
+// touchesTag reports whether an operation account (tag or full tagged address, hex) belongs to tag
+func touchesTag(address string, tagHex string) bool {
+	address = strings.ToLower(strings.TrimPrefix(address, "0x"))
+	return strings.HasPrefix(address, tagHex)
+}
+
+/*
+ * historyEntry summarizes a transaction from the wallet's point of view
+ *
+ * The amounts of the operations on the wallet's tag are summed: a negative
+ * total is a payment out, a positive one a payment in. Memos are collected
+ * from the metadata of every operation.
+ */
+func historyEntry(hit meshclient.BlockTransactionEntry, tagHex string) HistoryEntry {
+	entry := HistoryEntry{
+		Block: hit.BlockIdentifier.Index,
+		TxID:  strings.TrimPrefix(hit.Transaction.TransactionIdentifier.Hash, "0x"),
+	}
+	sawIn, sawOut := false, false
+	for _, op := range hit.Transaction.Operations {
+		if memo, ok := op.Metadata["memo"].(string); ok && memo != "" {
+			entry.Memos = append(entry.Memos, memo)
+		}
+		if op.Account == nil || op.Amount == nil || !touchesTag(op.Account.Address, tagHex) {
+			continue
+		}
+		value, err := strconv.ParseInt(op.Amount.Value, 10, 64)
+		if err != nil {
+			continue
+		}
+		entry.Amount += value
+		sawIn = sawIn || value > 0
+		sawOut = sawOut || value < 0
+	}
+	switch {
+	case entry.Amount < 0:
+		entry.Direction = "out"
+	case entry.Amount > 0:
+		entry.Direction = "in"
+	case sawIn && sawOut:
+		entry.Direction = "self"
+	default:
+		entry.Direction = "unknown"
+	}
+	return entry
+}
+
+/*
+ * WalletHistory lists the transactions touching a tag, newest first
+ *
+ * Parameters:
+ * - ctx, client: Mesh API access
+ * - tag: the wallet's 20 bytes tag
+ * - max: stop after this many transactions
+ *
+ * Returns the entries and whether max cut the list short.
+ */
+func WalletHistory(ctx context.Context, client *meshclient.MeshAPIClient, tag []byte, max int) ([]HistoryEntry, bool, error) {
+	hits, truncated, err := client.SearchAllTransactions(ctx, meshclient.SearchQuery{Tag: tag, Limit: 100}, max)
+	if err != nil {
+		return nil, false, err
+	}
+	tagHex := hex.EncodeToString(tag)
+	entries := make([]HistoryEntry, 0, len(hits))
+	for _, hit := range hits {
+		entries = append(entries, historyEntry(hit, tagHex))
+	}
+	sort.SliceStable(entries, func(i, j int) bool { return entries[i].Block > entries[j].Block })
+	return entries, truncated, nil
+}
+
+// runHistory implements the -history mode and returns the process exit code
+func runHistory(ctx context.Context, client *meshclient.MeshAPIClient, walletCacheFile string, max int, asJSON bool) int {
+	cache, err := ReadWalletCache(walletCacheFile)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error with wallet cache: %v\n", err)
+		return 1
+	}
+	tag, err := mcmaddr.Normalize(cache.RefillAddress)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: invalid wallet address in cache: %v\n", err)
+		return 1
+	}
+
+	entries, truncated, err := WalletHistory(ctx, client, tag[:], max)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error searching transactions: %v\n", err)
+		return 1
+	}
+	if err := PrintHistory(os.Stdout, entries, asJSON); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	if truncated {
+		fmt.Fprintf(os.Stderr, "Listed the first %d transactions only, raise -history-max to see more\n", max)
+	}
+	return 0
+}
+
+// PrintHistory writes the entries as an aligned table, or as a JSON array
+func PrintHistory(out io.Writer, entries []HistoryEntry, asJSON bool) error {
+	if asJSON {
+		encoder := json.NewEncoder(out)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(entries)
+	}
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "BLOCK\tDIRECTION\tAMOUNT (nMCM)\tTX ID\tMEMO")
+	for _, entry := range entries {
+		fmt.Fprintf(w, "%d\t%s\t%d\t%s\t%s\n", entry.Block, entry.Direction, entry.Amount, entry.TxID, strings.Join(entry.Memos, ", "))
+	}
+	return w.Flush()
+}
@@ -0,0 +1,158 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/NickP005/Vindax-MCM-tools/pkg/meshclient"
+)
+
+// searchPageLimit caps the pages of searchServer, below the limit WalletHistory asks for
+const searchPageLimit = 25
+
+// searchServer answers /search/transactions with hits, searchPageLimit at a time
+func searchServer(t *testing.T, hits []meshclient.BlockTransactionEntry) *meshclient.MeshAPIClient {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var request struct {
+			Offset int64 `json:"offset"`
+		}
+		if r.URL.Path != "/search/transactions" || json.NewDecoder(r.Body).Decode(&request) != nil {
+			http.Error(w, "bad request", http.StatusBadRequest)
+			return
+		}
+		page := meshclient.SearchResult{TotalCount: int64(len(hits))}
+		end := request.Offset + searchPageLimit
+		if end < int64(len(hits)) {
+			page.NextOffset = &end
+		} else {
+			end = int64(len(hits))
+		}
+		page.Transactions = hits[request.Offset:end]
+		json.NewEncoder(w).Encode(page)
+	}))
+	t.Cleanup(server.Close)
+	return meshclient.NewMeshAPIClient(server.URL, nil)
+}
+
+// transfer is a mock transaction moving value nanoMCM to the account of address, with a memo
+func transfer(id int, address string, value int64, memo string) meshclient.Transaction {
+	op := func(index int64, address string, value int64) meshclient.Operation {
+		return meshclient.Operation{
+			OperationIdentifier: meshclient.OperationIdentifier{Index: index},
+			Type:                "TRANSFER",
+			Account:             &meshclient.AccountIdentifier{Address: address},
+			Amount:              &meshclient.Amount{Value: fmt.Sprint(value), Currency: meshclient.MCM},
+		}
+	}
+	other := "0x" + strings.Repeat("ee", 20)
+	credit := op(1, address, value)
+	if memo != "" {
+		credit.Metadata = map[string]interface{}{"memo": memo}
+	}
+	return meshclient.Transaction{
+		TransactionIdentifier: meshclient.TransactionIdentifier{Hash: fmt.Sprintf("0x%064x", id)},
+		Operations:            []meshclient.Operation{op(0, other, -value), credit},
+	}
+}
+
+// TestWalletHistory pages through more transactions than the server lists per page
+func TestWalletHistory(t *testing.T) {
+	tag := bytes.Repeat([]byte{0x42}, 20)
+	address := "0x" + hex.EncodeToString(tag) + strings.Repeat("00", 12)
+	const count = 2*searchPageLimit + 7
+	// The server lists the oldest transactions first
+	var hits []meshclient.BlockTransactionEntry
+	for i := 0; i < count; i++ {
+		value := int64(1000 + i)
+		if i%2 == 1 {
+			value = -value
+		}
+		hits = append(hits, meshclient.BlockTransactionEntry{
+			BlockIdentifier: meshclient.BlockIdentifier{Index: uint64(100 + i)},
+			Transaction:     transfer(i, address, value, fmt.Sprintf("INV-%d", i)),
+		})
+	}
+	client := searchServer(t, hits)
+
+	entries, truncated, err := WalletHistory(context.Background(), client, tag, 1000)
+	if err != nil || truncated {
+		t.Fatalf("truncated %v, %v", truncated, err)
+	}
+	if len(entries) != count {
+		t.Fatalf("%d entries, want %d", len(entries), count)
+	}
+	for n, entry := range entries {
+		// Newest first
+		i := count - 1 - n
+		want := HistoryEntry{Block: uint64(100 + i), TxID: fmt.Sprintf("%064x", i), Direction: "in", Amount: int64(1000 + i)}
+		if i%2 == 1 {
+			want.Direction, want.Amount = "out", -want.Amount
+		}
+		if entry.Block != want.Block || entry.TxID != want.TxID || entry.Direction != want.Direction || entry.Amount != want.Amount ||
+			len(entry.Memos) != 1 || entry.Memos[0] != fmt.Sprintf("INV-%d", i) {
+			t.Errorf("entry %d: %+v, want %+v", n, entry, want)
+		}
+	}
+
+	// The cut keeps the first transactions the server lists, newest first
+	entries, truncated, err = WalletHistory(context.Background(), client, tag, 30)
+	if err != nil || !truncated || len(entries) != 30 || entries[0].Block != 129 || entries[29].Block != 100 {
+		t.Errorf("max 30: %d entries, truncated %v, %v", len(entries), truncated, err)
+	}
+}
+
+func TestHistoryEntryDirections(t *testing.T) {
+	tagHex := strings.Repeat("42", 20)
+	own := "0x" + tagHex
+	self := transfer(1, own, 100, "")
+	self.Operations[0].Account.Address = own
+	for _, tc := range []struct {
+		tx        meshclient.Transaction
+		direction string
+		amount    int64
+	}{
+		{transfer(1, own, 100, ""), "in", 100},
+		{transfer(1, own, -100, ""), "out", -100},
+		{self, "self", 0},
+		{transfer(1, "0x"+strings.Repeat("11", 20), 100, ""), "unknown", 0},
+	} {
+		entry := historyEntry(meshclient.BlockTransactionEntry{Transaction: tc.tx}, tagHex)
+		if entry.Direction != tc.direction || entry.Amount != tc.amount {
+			t.Errorf("%+v, want %s %d", entry, tc.direction, tc.amount)
+		}
+	}
+}
+
+func TestPrintHistory(t *testing.T) {
+	entries := []HistoryEntry{
+		{Block: 12, TxID: "ab", Direction: "in", Amount: 1500, Memos: []string{"INV-1", "INV-2"}},
+		{Block: 9, TxID: "cd", Direction: "out", Amount: -20},
+	}
+	var table bytes.Buffer
+	if err := PrintHistory(&table, entries, false); err != nil {
+		t.Fatal(err)
+	}
+	want := "BLOCK  DIRECTION  AMOUNT (nMCM)  TX ID  MEMO\n" +
+		"12     in         1500           ab     INV-1, INV-2\n" +
+		"9      out        -20            cd     \n"
+	if table.String() != want {
+		t.Errorf("table:\n%s\nwant:\n%s", table.String(), want)
+	}
+
+	var out bytes.Buffer
+	if err := PrintHistory(&out, entries, true); err != nil {
+		t.Fatal(err)
+	}
+	var decoded []HistoryEntry
+	if err := json.Unmarshal(out.Bytes(), &decoded); err != nil || len(decoded) != 2 || decoded[1].Amount != -20 || decoded[1].Memos != nil {
+		t.Errorf("JSON %s: %v", out.String(), err)
+	}
+}
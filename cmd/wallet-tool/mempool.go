@@ -0,0 +1,125 @@
+package main
+
+import (
+	"context"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strconv"
+
+	"github.com/NickP005/Vindax-MCM-tools/pkg/meshclient"
+)
+
+/*
+ * DiffPendingTransaction compares a mempool transaction with the intended payments
+ *
+ * Parameters:
+ * - tx: the pending transaction as returned by /mempool/transaction
+ * - tag: the wallet's 20 bytes tag, which must be spent from
+ * - entries: the intended destinations, amounts and memos
+ *
+ * Returns one line per difference, none if the transaction matches.
+ */
+func DiffPendingTransaction(tx meshclient.Transaction, tag []byte, entries []SendEntry) []string {
+	tagHex := hex.EncodeToString(tag)
+	spendsTag := false
+	paid := make(map[string]int64)
+	memos := make(map[string][]string)
+	for _, op := range tx.Operations {
+		if op.Account == nil || op.Amount == nil {
+			continue
+		}
+		value, err := strconv.ParseInt(op.Amount.Value, 10, 64)
+		if err != nil {
+			continue
+		}
+		if touchesTag(op.Account.Address, tagHex) {
+			spendsTag = spendsTag || value < 0
+			continue
+		}
+		if value <= 0 {
+			continue
+		}
+		// Destinations are keyed by their tag, the first 20 bytes of the address
+		dst := op.Account.Address
+		for _, entry := range entries {
+			entryHex := hex.EncodeToString(entry.AddressBin)
+			if touchesTag(dst, entryHex) {
+				dst = entryHex
+				break
+			}
+		}
+		paid[dst] += value
+		if memo, ok := op.Metadata["memo"].(string); ok && memo != "" {
+			memos[dst] = append(memos[dst], memo)
+		}
+	}
+
+	var diff []string
+	if !spendsTag {
+		diff = append(diff, fmt.Sprintf("does not spend from the wallet tag %s", tagHex))
+	}
+	for _, entry := range entries {
+		entryHex := hex.EncodeToString(entry.AddressBin)
+		got, ok := paid[entryHex]
+		delete(paid, entryHex)
+		switch {
+		case !ok:
+			diff = append(diff, fmt.Sprintf("pays nothing to %s, intended %d nMCM", entry.Address, entry.AmountToSend))
+			continue
+		case uint64(got) != entry.AmountToSend:
+			diff = append(diff, fmt.Sprintf("pays %d nMCM to %s, intended %d nMCM", got, entry.Address, entry.AmountToSend))
+		}
+		if entry.Memo != "" && !containsString(memos[entryHex], entry.Memo) {
+			diff = append(diff, fmt.Sprintf("memo to %s is %q, intended %q", entry.Address, memos[entryHex], entry.Memo))
+		}
+	}
+	for dst, got := range paid {
+		diff = append(diff, fmt.Sprintf("pays %d nMCM to unexpected account %s", got, dst))
+	}
+	return diff
+}
+
+// containsString reports whether list holds s
+func containsString(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
+
+/*
+ * ReportPendingTransaction fetches a mempool transaction and prints how it
+ * differs from the intended payments
+ *
+ * Unless verbose, only differences are printed. A transaction that left the
+ * mempool in the meantime is reported as such, the monitoring loop then
+ * finds it in a block or rebroadcasts it.
+ */
+func ReportPendingTransaction(ctx context.Context, client *meshclient.MeshAPIClient, txID string, tag []byte, entries []SendEntry, verbose bool) {
+	pending, err := client.MempoolTransaction(ctx, txID)
+	if errors.Is(err, meshclient.ErrNotInMempool) {
+		if verbose {
+			fmt.Println("Transaction left the mempool before its details could be fetched")
+		}
+		return
+	}
+	if err != nil {
+		fmt.Printf("Could not fetch the pending transaction: %v\n", err)
+		return
+	}
+
+	diff := DiffPendingTransaction(pending.Transaction, tag, entries)
+	if len(diff) == 0 {
+		if verbose {
+			fmt.Println("Pending transaction matches the intended payments")
+		}
+		return
+	}
+	fmt.Println("⚠️ Pending transaction differs from the intended payments:")
+	for _, line := range diff {
+		fmt.Printf("  - %s\n", line)
+	}
+}
@@ -0,0 +1,133 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/NickP005/Vindax-MCM-tools/pkg/meshclient"
+)
+
+// captureStdout returns what f prints on the standard output
+func captureStdout(t *testing.T, f func()) string {
+	t.Helper()
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	stdout := os.Stdout
+	os.Stdout = w
+	done := make(chan string)
+	go func() {
+		var out bytes.Buffer
+		io.Copy(&out, r)
+		done <- out.String()
+	}()
+	defer func() { os.Stdout = stdout }()
+	f()
+	w.Close()
+	return <-done
+}
+
+// pendingTx is a mempool transaction spending from the account of source, paying each address its amount with a memo
+func pendingTx(hash string, source string, payments ...meshclient.Operation) meshclient.Transaction {
+	var total int64
+	for _, op := range payments {
+		var value int64
+		fmt.Sscan(op.Amount.Value, &value)
+		total += value
+	}
+	debit := meshclient.Operation{Type: "SOURCE_TRANSFER", Account: &meshclient.AccountIdentifier{Address: source},
+		Amount: &meshclient.Amount{Value: fmt.Sprint(-total), Currency: meshclient.MCM}}
+	return meshclient.Transaction{TransactionIdentifier: meshclient.TransactionIdentifier{Hash: hash}, Operations: append([]meshclient.Operation{debit}, payments...)}
+}
+
+// payment is a destination operation of pendingTx
+func payment(address string, value int64, memo string) meshclient.Operation {
+	op := meshclient.Operation{Type: "DESTINATION_TRANSFER", Account: &meshclient.AccountIdentifier{Address: address},
+		Amount: &meshclient.Amount{Value: fmt.Sprint(value), Currency: meshclient.MCM}}
+	if memo != "" {
+		op.Metadata = map[string]interface{}{"memo": memo}
+	}
+	return op
+}
+
+func TestDiffPendingTransaction(t *testing.T) {
+	tag := bytes.Repeat([]byte{0x42}, 20)
+	source := "0x" + strings.Repeat("42", 20) + strings.Repeat("00", 12)
+	alice, bob, eve := bytes.Repeat([]byte{0xa1}, 20), bytes.Repeat([]byte{0xb0}, 20), "0x"+strings.Repeat("e5", 20)
+	hexOf := func(tag []byte) string { return fmt.Sprintf("0x%x", tag) }
+	entries := []SendEntry{
+		{Address: "alice", AddressBin: alice, AmountToSend: 100, Memo: "INV-1"},
+		{Address: "bob", AddressBin: bob, AmountToSend: 250},
+	}
+	for _, tc := range []struct {
+		name string
+		tx   meshclient.Transaction
+		want []string
+	}{
+		{"match", pendingTx("0x01", source, payment(hexOf(alice)+"0000", 100, "INV-1"), payment(hexOf(bob), 250, "")), nil},
+		{"other source", pendingTx("0x01", "0x"+strings.Repeat("77", 20), payment(hexOf(alice), 100, "INV-1"), payment(hexOf(bob), 250, "")),
+			[]string{"does not spend from the wallet tag " + strings.Repeat("42", 20)}},
+		{"wrong amount", pendingTx("0x01", source, payment(hexOf(alice), 100, "INV-1"), payment(hexOf(bob), 200, "")),
+			[]string{"pays 200 nMCM to bob, intended 250 nMCM"}},
+		{"missing payment and wrong memo", pendingTx("0x01", source, payment(hexOf(alice), 100, "INV-2")),
+			[]string{`memo to alice is ["INV-2"], intended "INV-1"`, "pays nothing to bob, intended 250 nMCM"}},
+		{"unexpected account", pendingTx("0x01", source, payment(hexOf(alice), 100, "INV-1"), payment(hexOf(bob), 250, ""), payment(eve, 9, "")),
+			[]string{"pays 9 nMCM to unexpected account " + eve}},
+	} {
+		if got := DiffPendingTransaction(tc.tx, tag, entries); strings.Join(got, "\n") != strings.Join(tc.want, "\n") {
+			t.Errorf("%s: %q, want %q", tc.name, got, tc.want)
+		}
+	}
+}
+
+func TestReportPendingTransaction(t *testing.T) {
+	tag := bytes.Repeat([]byte{0x42}, 20)
+	alice := bytes.Repeat([]byte{0xa1}, 20)
+	entries := []SendEntry{{Address: "alice", AddressBin: alice, AmountToSend: 100}}
+	hash := "0x" + strings.Repeat("0c", 32)
+	pending := meshclient.MempoolTransaction{Transaction: pendingTx(hash, fmt.Sprintf("0x%x", tag), payment(fmt.Sprintf("0x%x", alice), 90, ""))}
+	// The server answers status, with the pending transaction when it is 200
+	status := http.StatusOK
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/mempool/transaction" || status != http.StatusOK {
+			w.WriteHeader(status)
+			return
+		}
+		json.NewEncoder(w).Encode(pending)
+	}))
+	defer server.Close()
+	client := meshclient.NewMeshAPIClient(server.URL, nil)
+
+	out := captureStdout(t, func() { ReportPendingTransaction(context.Background(), client, hash, tag, entries, false) })
+	if !strings.Contains(out, "differs from the intended payments") || !strings.Contains(out, "- pays 90 nMCM to alice, intended 100 nMCM") {
+		t.Errorf("output %q", out)
+	}
+
+	// A hash evicted in the meantime is a 404, reported gracefully
+	status = http.StatusNotFound
+	if _, err := client.MempoolTransaction(context.Background(), hash); !errors.Is(err, meshclient.ErrNotInMempool) {
+		t.Errorf("evicted: %v", err)
+	}
+	out = captureStdout(t, func() { ReportPendingTransaction(context.Background(), client, hash, tag, entries, true) })
+	if out != "Transaction left the mempool before its details could be fetched\n" {
+		t.Errorf("evicted output %q", out)
+	}
+	if out := captureStdout(t, func() { ReportPendingTransaction(context.Background(), client, hash, tag, entries, false) }); out != "" {
+		t.Errorf("evicted, not verbose: %q", out)
+	}
+
+	status = http.StatusBadGateway
+	if out := captureStdout(t, func() { ReportPendingTransaction(context.Background(), client, hash, tag, entries, false) }); !strings.HasPrefix(out, "Could not fetch the pending transaction") {
+		t.Errorf("failed fetch output %q", out)
+	}
+}
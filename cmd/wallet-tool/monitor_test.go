@@ -0,0 +1,324 @@
+package main
+
+import (
+	"context"
+	"encoding/hex"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/NickP005/Vindax-MCM-tools/pkg/meshclient"
+	"github.com/NickP005/Vindax-MCM-tools/pkg/meshmock"
+	"github.com/NickP005/Vindax-MCM-tools/pkg/monitor"
+	"github.com/NickP005/Vindax-MCM-tools/pkg/walletstore"
+)
+
+// requestCounter counts the requests per endpoint, and closes pending once the transaction was read from the mempool
+type requestCounter struct {
+	mu      sync.Mutex
+	counts  map[string]int
+	once    sync.Once
+	pending chan struct{}
+}
+
+func (c *requestCounter) OnRequestStart(info meshclient.RequestInfo) {}
+
+func (c *requestCounter) OnRequestEnd(info meshclient.RequestInfo) {
+	c.mu.Lock()
+	c.counts[info.Op]++
+	if info.Err != nil {
+		c.counts["failed"]++
+	}
+	c.mu.Unlock()
+	if info.Op == "/mempool/transaction" && info.Err == nil {
+		c.once.Do(func() { close(c.pending) })
+	}
+}
+
+func (c *requestCounter) count(op string) int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.counts[op]
+}
+
+// monitorRun is a payout monitor running against a mock until script is done and the monitor finished
+type monitorRun struct {
+	t        *testing.T
+	mock     *meshmock.Server
+	tx       meshclient.Transaction
+	requests *requestCounter
+	state    string
+}
+
+/*
+ * runMonitor monitors a pending transaction of the mock, at height 2,
+ * polling every 20ms; script runs once the monitor saw the transaction in
+ * the mempool, and setup may change the payout and the source the
+ * transaction spends before the monitor starts
+ */
+func runMonitor(t *testing.T, confirmations int, script func(run *monitorRun), setup ...func(p *payout, source *walletstore.SourceState)) (payoutResult, string, *requestCounter) {
+	t.Helper()
+	mock := meshmock.New()
+	defer mock.Close()
+	mock.MineBlock()
+	mock.MineBlock()
+	tag := []byte(strings.Repeat("\x42", 20))
+	txID := strings.Repeat("0d", 32)
+	tx := pendingTx("0x"+txID, fmt.Sprintf("0x%x", tag), payment("0x"+strings.Repeat("a1", 20), 100, ""))
+	mock.AddToMempool(tx)
+
+	dir := t.TempDir()
+	receipt, err := ReadReceipt(filepath.Join(dir, "payout.receipt.csv"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := meshclient.NewMeshAPIClient(mock.URL(), nil)
+	requests := &requestCounter{counts: make(map[string]int), pending: make(chan struct{})}
+	client.SetHooks(requests)
+	p := &payout{
+		client:              client,
+		cache:               &walletstore.WalletCache{},
+		receipt:             receipt,
+		walletCacheFile:     filepath.Join(dir, "wallet.json"),
+		confirmations:       confirmations,
+		timeout:             1,
+		outagePausesTimeout: true,
+		stateFile:           filepath.Join(dir, "monitor.json"),
+		interval:            20 * time.Millisecond,
+	}
+	var source walletstore.SourceState
+	for _, f := range setup {
+		f(p, &source)
+	}
+
+	var result payoutResult
+	out := captureStdout(t, func() {
+		finished := make(chan struct{})
+		go func() {
+			defer close(finished)
+			result = p.monitor(context.Background(), 1, nil, "", txID, 0, tag, source)
+		}()
+		select {
+		case <-requests.pending:
+			script(&monitorRun{t: t, mock: mock, tx: tx, requests: requests, state: p.stateFile})
+		case <-time.After(10 * time.Second):
+			t.Error("transaction never seen in the mempool")
+			return
+		}
+		select {
+		case <-finished:
+		case <-time.After(20 * time.Second):
+			t.Error("monitor did not finish")
+		}
+	})
+	if result.TxID != txID {
+		t.Errorf("result %+v", result)
+	}
+	return result, out, requests
+}
+
+// waitState waits until the state file of the monitor satisfies done, described by what
+func (run *monitorRun) waitState(what string, done func(state monitor.State) bool) {
+	run.t.Helper()
+	for deadline := time.Now().Add(10 * time.Second); time.Now().Before(deadline); time.Sleep(5 * time.Millisecond) {
+		if state, err := monitor.ReadState(run.state); err == nil && done(state) {
+			return
+		}
+	}
+	run.t.Errorf("monitor never reached: %s", what)
+}
+
+// waitInclusion waits until the monitor found the transaction in block height
+func (run *monitorRun) waitInclusion(height uint64) {
+	run.t.Helper()
+	run.waitState(fmt.Sprintf("transaction in block %d", height), func(state monitor.State) bool { return state.InclusionBlock == height })
+}
+
+/*
+ * TestMonitorAcrossOutage takes the API down while the transaction and
+ * the block confirming it are mined: the monitor goes through an outage of
+ * 30 polls, as a 2 minutes one would be every 5s, and still finds the
+ * transaction with its 2 confirmations once the API is back
+ */
+func TestMonitorAcrossOutage(t *testing.T) {
+	result, out, _ := runMonitor(t, 2, func(run *monitorRun) {
+		run.mock.Outage(meshmock.Fault{Status: 502, Body: "bad gateway"}, 600*time.Millisecond)
+		run.mock.MineBlock()
+		run.mock.MineBlock()
+	})
+	if !result.Confirmed {
+		t.Fatalf("not confirmed:\n%s", out)
+	}
+	for _, line := range []string{"Mesh API unreachable", "Mesh API answering again", "Transaction found in block 3", "Transaction confirmed with 2 confirmations"} {
+		if !strings.Contains(out, line) {
+			t.Errorf("output does not say %q:\n%s", line, out)
+		}
+	}
+}
+
+// TestMonitorLongOutage keeps the API down for many polls: few failures are logged, and the transaction mined meanwhile confirms
+func TestMonitorLongOutage(t *testing.T) {
+	result, out, requests := runMonitor(t, 2, func(run *monitorRun) {
+		run.mock.Outage(meshmock.Fault{Status: 502, Body: "bad gateway"}, 2*time.Second)
+		run.mock.MineBlock()
+		run.mock.MineBlock()
+	})
+	if !result.Confirmed || !strings.Contains(out, "Transaction confirmed with 2 confirmations") {
+		t.Fatalf("not confirmed:\n%s", out)
+	}
+	failed, logged := requests.count("failed"), strings.Count(out, "bad gateway")
+	if failed <= monitor.OutageFailures || logged > monitor.OutageFailures+failed/monitor.OutageLogEvery+1 {
+		t.Errorf("%d failures logged in %d lines:\n%s", failed, logged, out)
+	}
+}
+
+// TestMonitorBlockJump mines the transaction and two blocks over it between two polls: each counts, without fetching the inclusion block again
+func TestMonitorBlockJump(t *testing.T) {
+	result, out, requests := runMonitor(t, 3, func(run *monitorRun) {
+		run.mock.MineBlock()
+		run.mock.MineBlock()
+		run.mock.MineBlock()
+	})
+	if !result.Confirmed || !strings.Contains(out, "Transaction found in block 3") || !strings.Contains(out, "Transaction confirmed with 3 confirmations") {
+		t.Fatalf("not confirmed:\n%s", out)
+	}
+	// Blocks 3 to 5 by the watch, blocks 2 and 3 by the scan
+	if n := requests.count("/block"); n != 5 {
+		t.Errorf("%d /block requests", n)
+	}
+}
+
+// TestMonitorOrphanedInclusion replaces the block holding the transaction by a branch holding it one block later
+func TestMonitorOrphanedInclusion(t *testing.T) {
+	result, out, _ := runMonitor(t, 3, func(run *monitorRun) {
+		run.mock.MineBlock()
+		run.waitInclusion(3)
+		run.mock.ReorgTo(1, nil, []meshclient.Transaction{run.tx})
+		run.waitInclusion(4)
+		run.mock.MineBlock()
+		run.mock.MineBlock()
+	})
+	if !result.Confirmed {
+		t.Fatalf("not confirmed:\n%s", out)
+	}
+	for _, line := range []string{"Transaction found in block 3", "Block 3 holding the transaction was replaced", "Transaction found in block 4", "Transaction confirmed with 3 confirmations"} {
+		if !strings.Contains(out, line) {
+			t.Errorf("output does not say %q:\n%s", line, out)
+		}
+	}
+	// Counted from block 4 only: 3 confirmations need block 6
+	if strings.Contains(out, "confirmation #3 of 3") && !strings.Contains(out, "Block changed to 6") {
+		t.Errorf("confirmed before block 6:\n%s", out)
+	}
+}
+
+// TestMonitorSameHeightReorg replaces a scanned block without the transaction by one with it, at the same height
+func TestMonitorSameHeightReorg(t *testing.T) {
+	result, out, _ := runMonitor(t, 2, func(run *monitorRun) {
+		// A block that leaves the transaction pending
+		run.mock.ReorgTo(0, nil)
+		run.waitState("block 3 scanned", func(state monitor.State) bool { return state.LastScannedBlock == 3 })
+		run.mock.DropFromMempool(run.tx.TransactionIdentifier.Hash)
+		run.mock.ReorgTo(1, []meshclient.Transaction{run.tx})
+		run.waitInclusion(3)
+		run.mock.MineBlock()
+	})
+	if !result.Confirmed {
+		t.Fatalf("not confirmed:\n%s", out)
+	}
+	for _, line := range []string{"Chain reorganized after block 2", "Transaction found in block 3", "Transaction confirmed with 2 confirmations"} {
+		if !strings.Contains(out, line) {
+			t.Errorf("output does not say %q:\n%s", line, out)
+		}
+	}
+}
+
+// TestMonitorOrphanedTransaction orphans the block holding the transaction, which goes back to the mempool or is dropped
+func TestMonitorOrphanedTransaction(t *testing.T) {
+	for _, toMempool := range []bool{true, false} {
+		result, out, requests := runMonitor(t, 2, func(run *monitorRun) {
+			run.mock.MineBlock()
+			run.waitInclusion(3)
+			run.mock.Reorg(1, toMempool)
+			if toMempool {
+				// Seen pending again, then found in block 4 and confirmed by block 5
+				run.waitState("transaction pending again", func(state monitor.State) bool {
+					return state.Phase == monitor.PhaseMempool && state.InclusionBlock == 0
+				})
+				run.mock.MineBlock()
+				run.waitInclusion(4)
+				run.mock.MineBlock()
+			}
+		})
+		if result.Confirmed != toMempool || !strings.Contains(out, "Block 3 holding the transaction was replaced") {
+			t.Errorf("back to the mempool %v: confirmed %v\n%s", toMempool, result.Confirmed, out)
+		}
+		// Rebroadcasting is only considered once in neither the chain nor the mempool, and -keeptrying is off
+		if toMempool && !strings.Contains(out, "back in the mempool after the reorg") {
+			t.Errorf("not seen back in the mempool:\n%s", out)
+		}
+		if !toMempool && !strings.Contains(out, "Transaction may have been orphaned") {
+			t.Errorf("orphan not reported:\n%s", out)
+		}
+		if n := requests.count("/construction/submit"); n != 0 {
+			t.Errorf("back to the mempool %v: %d submissions", toMempool, n)
+		}
+	}
+}
+
+// TestMonitorThrottled rate limits the monitor: it waits as asked, without taking it for an outage
+func TestMonitorThrottled(t *testing.T) {
+	result, out, _ := runMonitor(t, 1, func(run *monitorRun) {
+		var faults []meshmock.Fault
+		for i := 0; i < 2*monitor.OutageFailures; i++ {
+			faults = append(faults, meshmock.Fault{Status: 429, Body: "slow down"})
+		}
+		run.mock.Fail("/network/status", faults...)
+		run.mock.MineBlock()
+	})
+	if !result.Confirmed || !strings.Contains(out, "Mesh API rate limiting") {
+		t.Fatalf("confirmed %v:\n%s", result.Confirmed, out)
+	}
+	if strings.Contains(out, "Mesh API unreachable") || strings.Contains(out, "Error checking block status") {
+		t.Errorf("throttling taken for failures:\n%s", out)
+	}
+}
+
+/*
+ * TestMonitorSourceConsumed orphans the transaction with -keeptrying: it
+ * is rebroadcast only while the wallet tag still holds what it spends, not
+ * once another transaction spent the signing key
+ */
+func TestMonitorSourceConsumed(t *testing.T) {
+	tag := strings.Repeat("42", 20)
+	signer, change := strings.Repeat("51", 20), strings.Repeat("52", 20)
+	for _, consumed := range []bool{false, true} {
+		result, out, requests := runMonitor(t, 1, func(run *monitorRun) {
+			run.mock.SetAccount([]byte(strings.Repeat("\x42", 20)), "0x"+tag+signer, 1000)
+			if consumed {
+				run.mock.SetAccount([]byte(strings.Repeat("\x42", 20)), "0x"+tag+change, 400)
+			}
+			run.mock.DropFromMempool(run.tx.TransactionIdentifier.Hash)
+			run.mock.MineBlock()
+		}, func(p *payout, source *walletstore.SourceState) {
+			p.keepTrying = true
+			hash, _ := hex.DecodeString(signer)
+			copy(source.AddrHash[:], hash)
+			source.Found, source.Balance, source.AddressHex = true, 1000, "0x"+tag+signer
+		})
+		if result.Confirmed {
+			t.Errorf("consumed %v: confirmed\n%s", consumed, out)
+		}
+		submissions := requests.count("/construction/submit")
+		if consumed && (submissions != 0 || !strings.Contains(out, "Not rebroadcasting") || !strings.Contains(out, "signing key was spent by another transaction")) {
+			t.Errorf("consumed key: %d submissions\n%s", submissions, out)
+		}
+		// The test bytes are rejected, which ends the monitor after the one attempt
+		if !consumed && (submissions != 1 || !strings.Contains(out, "Rebroadcasting...")) {
+			t.Errorf("unchanged source: %d submissions\n%s", submissions, out)
+		}
+	}
+}
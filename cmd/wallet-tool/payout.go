@@ -0,0 +1,370 @@
+package main
+
+import (
+	"context"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math/bits"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/NickP005/Vindax-MCM-tools/pkg/meshclient"
+	"github.com/NickP005/Vindax-MCM-tools/pkg/txentry"
+	"github.com/NickP005/Vindax-MCM-tools/pkg/walletstore"
+)
+
+// payout is a payout from the wallet and its flags, paid by one transaction after another, see send
+type payout struct {
+	client   *meshclient.MeshAPIClient
+	cache    *walletstore.WalletCache
+	keychain *walletstore.Keychain
+	hashes   *walletstore.DerivationCache
+	receipt  *Receipt
+
+	walletCacheFile string
+	// payoutFile is the -csv or -airdrop-list file paid
+	payoutFile          string
+	fee                 uint64
+	confirmations       int
+	keepTrying          bool
+	timeout             int
+	outagePausesTimeout bool
+	stateFile           string
+	// interval is how often the monitor polls the Mesh API, CHECK_MEMPOOL_INTERVAL seconds when zero
+	interval time.Duration
+}
+
+// payoutResult is the outcome of one transaction of a payout: confirmed, not known to be, or Exit set to the exit code of a failure
+type payoutResult struct {
+	TxID      string
+	Confirmed bool
+	Exit      int
+}
+
+// pollInterval returns how often the monitor polls the Mesh API
+func (p *payout) pollInterval() time.Duration {
+	if p.interval > 0 {
+		return p.interval
+	}
+	return CHECK_MEMPOOL_INTERVAL * time.Second
+}
+
+// errTotalOverflow is returned for entries whose total, fees included, does not fit in a uint64
+var errTotalOverflow = errors.New("the total of the entries overflows")
+
+// totalAmount returns what entries pay, fees aside
+func totalAmount(entries []SendEntry) (uint64, error) {
+	total := uint64(0)
+	for _, entry := range entries {
+		var carry uint64
+		total, carry = bits.Add64(total, entry.AmountToSend, 0)
+		if carry != 0 {
+			return 0, errTotalOverflow
+		}
+	}
+	return total, nil
+}
+
+// payoutCost returns what paying entries takes, fees included, and the fees of its transactions of at most txentry.MaxDestinations entries each
+func payoutCost(entries []SendEntry, fee uint64) (total uint64, fees uint64, err error) {
+	total, err = totalAmount(entries)
+	if err != nil {
+		return 0, 0, err
+	}
+	hi, fees := bits.Mul64(uint64(len(splitEntries(entries, txentry.MaxDestinations))), fee)
+	if hi != 0 {
+		return 0, 0, errTotalOverflow
+	}
+	total, carry := bits.Add64(total, fees, 0)
+	if carry != 0 {
+		return 0, 0, errTotalOverflow
+	}
+	return total, fees, nil
+}
+
+/*
+ * pay pays batches with one transaction each, the first one from the key
+ * at currentIndex holding balance, and each next one from the change of
+ * the previous one once it confirmed
+ *
+ * The result is the one of the last transaction sent: not Confirmed stops
+ * the payout there, the entries left being paid by a later run.
+ */
+func (p *payout) pay(ctx context.Context, batches [][]SendEntry, currentIndex uint64, tag []byte, balance uint64) payoutResult {
+	var result payoutResult
+	for i, batch := range batches {
+		if i > 0 {
+			// The change of the transaction just confirmed is the source of the next one
+			fmt.Printf("Transaction %d of %d: paying %d more entries\n", i+1, len(batches), len(batch))
+			var err error
+			currentIndex, tag, balance, err = VerifyCurrentIndex(ctx, p.client, p.keychain, p.cache.Index)
+			if err := p.hashes.Save(); err != nil {
+				fmt.Printf("Warning: %v\n", err)
+			}
+			if err != nil {
+				p.keychain.Wipe()
+				fmt.Fprintf(os.Stderr, "Error verifying wallet index: %v\n", err)
+				return payoutResult{Exit: 1}
+			}
+		}
+		result = p.send(ctx, p.receipt.Transactions()+1, batch, currentIndex, tag, balance)
+		// The next transaction spends the change of this one, which only a confirmation makes spendable
+		if result.Exit != 0 || !result.Confirmed {
+			return result
+		}
+	}
+	return result
+}
+
+/*
+ * payEntries pays entries in transactions of at most
+ * txentry.MaxDestinations entries each, once the wallet's balance is known
+ * to cover them all, fees included: a payout it cannot finish is refused
+ * before anything is signed
+ */
+func (p *payout) payEntries(ctx context.Context, entries []SendEntry, currentIndex uint64, tag []byte, balance uint64) payoutResult {
+	batches := splitEntries(entries, txentry.MaxDestinations)
+	totalNeeded, fees, err := payoutCost(entries, p.fee)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v, refusing the payout\n", err)
+		return payoutResult{Exit: EXIT_INVALID_ENTRIES}
+	}
+	if balance < totalNeeded {
+		fmt.Fprintf(os.Stderr, "Error: Insufficient balance in wallet. Have %d nMCM, need %d nMCM\n",
+			balance, totalNeeded)
+		fmt.Fprintf(os.Stderr, "Please refill this address: %s\n", p.cache.RefillAddress)
+		return payoutResult{Exit: 1}
+	}
+
+	fmt.Printf("Wallet balance: %d nMCM, sending total: %d nMCM (including %d nMCM fee)\n",
+		balance, totalNeeded, fees)
+	if len(batches) > 1 {
+		fmt.Printf("%d entries need %d transactions, each signed once the previous one confirms\n", len(entries), len(batches))
+	}
+	fmt.Printf("Using wallet address: %s\n", p.cache.RefillAddress)
+	fmt.Printf("Required confirmations: %d\n", p.confirmations)
+	if p.keepTrying {
+		fmt.Println("Will keep broadcasting transaction until confirmed")
+	}
+	return p.pay(ctx, batches, currentIndex, tag, balance)
+}
+
+/*
+ * resume follows the last transaction of the receipt not known to be
+ * confirmed, signed by a run that stopped before it confirmed, and returns
+ * Confirmed at once when there is none
+ *
+ * The wallet cache's PendingTx holds its bytes. The funds gone from the
+ * key that signed them mean it confirmed: that key signed nothing else,
+ * but maybe a replacement paying the same entries with a higher fee.
+ * Otherwise the same bytes are broadcast again and monitored; a node that
+ * cannot be reached leaves the transaction unconfirmed, for a later try.
+ */
+func (p *payout) resume(ctx context.Context) payoutResult {
+	n, txID, ok := p.receipt.InFlight()
+	if !ok {
+		return payoutResult{Confirmed: true}
+	}
+	pending := p.cache.Pending
+	if pending == nil {
+		fmt.Fprintf(os.Stderr, "Error: transaction %d of %s is not confirmed and the wallet cache holds no signed transaction to resume\n", n, p.receipt.Path())
+		return payoutResult{TxID: txID, Exit: 1}
+	}
+	fmt.Printf("Resuming transaction %d, signed at index %d on %s\n", n, pending.Index, pending.SignedAt.Format(time.RFC3339))
+
+	currentIndex, tag, balance, err := VerifyCurrentIndex(ctx, p.client, p.keychain, p.cache.Index)
+	if err := p.hashes.Save(); err != nil {
+		fmt.Printf("Warning: %v\n", err)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error verifying wallet index: %v\n", err)
+		return payoutResult{TxID: txID}
+	}
+	if currentIndex > pending.Index {
+		fmt.Printf("✅ The funds moved past index %d: transaction %d confirmed\n", pending.Index, n)
+		p.cache.Pending = nil
+		if err := walletstore.Save(p.walletCacheFile, p.cache); err != nil {
+			fmt.Printf("Warning: failed to clear the pending transaction from the wallet cache: %v\n", err)
+		}
+		if err := p.receipt.Update(n, txID, ReceiptConfirmed); err != nil {
+			fmt.Printf("Warning: %v\n", err)
+		}
+		return payoutResult{TxID: txID, Confirmed: true}
+	}
+	if currentIndex < pending.Index {
+		fmt.Fprintf(os.Stderr, "Error: the wallet tag is held by index %d, below index %d that signed transaction %d\n", currentIndex, pending.Index, n)
+		return payoutResult{TxID: txID, Exit: 1}
+	}
+
+	newTxID, err := SubmitTransaction(ctx, p.client, pending.SignedTx)
+	var feeErr *meshclient.FeeTooLowError
+	switch {
+	case errors.As(err, &feeErr):
+		fmt.Fprintf(os.Stderr, "Error submitting transaction: %v\n", err)
+		pending.FeeRejected = true
+		if err := walletstore.Save(p.walletCacheFile, p.cache); err != nil {
+			fmt.Printf("Warning: failed to record the fee rejection in the wallet cache: %v\n", err)
+		}
+		fmt.Fprintln(os.Stderr, feeRemedy(feeErr, pending))
+		return payoutResult{TxID: txID, Exit: EXIT_FEE_TOO_LOW}
+	case err != nil && meshclient.DefaultRetryable(err):
+		fmt.Printf("Warning: could not submit the transaction again: %v\n", err)
+		return payoutResult{TxID: txID}
+	case err != nil:
+		fmt.Fprintf(os.Stderr, "Error submitting transaction: %v\n", err)
+		return payoutResult{TxID: txID, Exit: 1}
+	}
+	source := walletstore.SourceState{Found: true, AddrHash: p.keychain.AddrHash(currentIndex), Balance: balance}
+	return p.monitor(ctx, n, p.receipt.Batch(n), pending.SignedTx, newTxID, currentIndex, tag, source)
+}
+
+/*
+ * payoutSteps are the steps of a payout with side effects, injectable so
+ * that any of them can be made to fail
+ *
+ * - save persists the wallet cache durably, see walletstore.Save
+ * - sign builds and signs the transaction with the key at index, paying fee
+ * - submit broadcasts the signed transaction and returns its ID
+ */
+type payoutSteps struct {
+	save   func(cache *walletstore.WalletCache) error
+	sign   func(index uint64, fee uint64) (*txentry.Transaction, error)
+	submit func(signedTx string) (string, error)
+}
+
+/*
+ * run signs and broadcasts a payout from the key at index, in an order
+ * where no failure can lead to the same index signing twice across runs
+ *
+ * 1. the index of the change key is persisted: a later run starts its
+ *    search past the key about to sign
+ * 2. the transaction is signed
+ * 3. the signed bytes are persisted as the wallet's PendingTx
+ * 4. the transaction is submitted
+ *
+ * Each persistence failure stops the run before the next step. A signature
+ * that was not persisted never left the process, so a later run may sign
+ * with the key again; once persisted, the key is only ever used to
+ * rebroadcast those same bytes (see walletstore.CheckPending), even when the
+ * node rejected them for their fee.
+ *
+ * Returns the signed transaction and the ID the API gave it. On a submit
+ * failure the transaction is returned along with the error: it stays
+ * pending in the cache. A rejection for the fee wraps a
+ * *meshclient.FeeTooLowError and is recorded in the cache.
+ */
+func (p payoutSteps) run(cache *walletstore.WalletCache, index uint64, fee uint64) (*txentry.Transaction, string, error) {
+	if err := walletstore.CheckPending(cache, index); err != nil {
+		return nil, "", err
+	}
+
+	cache.Index = index + 1
+	if err := p.save(cache); err != nil {
+		return nil, "", fmt.Errorf("failed to save the wallet index before signing: %v", err)
+	}
+
+	tx, err := p.sign(index, fee)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to create transaction: %v", err)
+	}
+
+	cache.Pending = &walletstore.PendingTx{Index: index, SignedTx: hex.EncodeToString(tx.Bytes()), SignedAt: time.Now().UTC(), Fee: fee}
+	if err := p.save(cache); err != nil {
+		return nil, "", fmt.Errorf("failed to save the signed transaction before broadcasting it: %v", err)
+	}
+
+	txID, err := p.submit(cache.Pending.SignedTx)
+	if err != nil {
+		if errors.Is(err, meshclient.ErrFeeTooLow) {
+			cache.Pending.FeeRejected = true
+			if saveErr := p.save(cache); saveErr != nil {
+				fmt.Printf("Warning: failed to record the fee rejection in the wallet cache: %v\n", saveErr)
+			}
+		}
+		return tx, "", fmt.Errorf("failed to submit transaction: %w", err)
+	}
+	return tx, txID, nil
+}
+
+/*
+ * runRebroadcastPending implements -rebroadcast-pending: it submits the
+ * signed transaction saved in the wallet cache again
+ *
+ * A transaction of the payout receipt at receiptPath signed and never
+ * submitted is this one: it gets the ID the API gives.
+ */
+func runRebroadcastPending(ctx context.Context, client *meshclient.MeshAPIClient, walletCacheFile string, receiptPath string) int {
+	if _, err := os.Stat(walletCacheFile); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	cache, err := ReadWalletCache(walletCacheFile)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error with wallet cache: %v\n", err)
+		return 1
+	}
+	if cache.Pending == nil {
+		fmt.Fprintln(os.Stderr, "Error: no signed transaction is pending in the wallet cache")
+		return 1
+	}
+	fmt.Printf("Rebroadcasting the transaction signed at index %d on %s...\n", cache.Pending.Index, cache.Pending.SignedAt.Format(time.RFC3339))
+	txID, err := SubmitTransaction(ctx, client, cache.Pending.SignedTx)
+	var feeErr *meshclient.FeeTooLowError
+	if errors.As(err, &feeErr) {
+		fmt.Fprintf(os.Stderr, "Error submitting transaction: %v\n", err)
+		cache.Pending.FeeRejected = true
+		if err := walletstore.Save(walletCacheFile, cache); err != nil {
+			fmt.Fprintf(os.Stderr, "Error saving wallet cache: %v\n", err)
+			return 1
+		}
+		fmt.Fprintln(os.Stderr, feeRemedy(feeErr, cache.Pending))
+		return EXIT_FEE_TOO_LOW
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error submitting transaction: %v\n", err)
+		return 1
+	}
+	txID = strings.TrimPrefix(txID, "0x")
+	fmt.Printf("Transaction submitted! TX ID: %s\n", txID)
+
+	receipt, err := ReadReceipt(receiptPath)
+	if err != nil {
+		fmt.Printf("Warning: %v\n", err)
+	} else if n, ok := receipt.Signed(); ok {
+		if err := receipt.Update(n, txID, ReceiptSubmitted); err != nil {
+			fmt.Printf("Warning: %v\n", err)
+		}
+	}
+	return 0
+}
+
+// pendingHint tells the operator what to do about a payout error, if anything
+func pendingHint(err error, walletCacheFile string) string {
+	var pendingErr *walletstore.PendingTxError
+	if errors.As(err, &pendingErr) && pendingErr.Pending.FeeRejected {
+		return fmt.Sprintf("Rebroadcast the transaction saved in %s with -rebroadcast-pending once the node accepts its fee of %d nMCM, or check -history first if the fee rejection was a while ago", walletCacheFile, pendingErr.Pending.Fee)
+	}
+	if errors.As(err, &pendingErr) {
+		return fmt.Sprintf("Check -history: if the transaction saved in %s did not confirm, rebroadcast it with -rebroadcast-pending", walletCacheFile)
+	}
+	return ""
+}
+
+/*
+ * feeRemedy tells the operator how to get the pending transaction, rejected
+ * for its fee, through: its key signed it and never signs anything else, so
+ * the same bytes wait for the node to accept their fee
+ */
+func feeRemedy(feeErr *meshclient.FeeTooLowError, pending *walletstore.PendingTx) string {
+	remedy := fmt.Sprintf("The node rejected the fee of %d nMCM", pending.Fee)
+	if feeErr.MinimumKnown {
+		remedy += fmt.Sprintf(" and asks for at least %d nMCM", feeErr.Minimum)
+	}
+	remedy += fmt.Sprintf(". Index %d signed it and never signs again: rebroadcast it with -rebroadcast-pending once the node accepts its fee", pending.Index)
+	if feeErr.MinimumKnown {
+		remedy += fmt.Sprintf(", and pay later payouts with -fee %d", feeErr.Minimum)
+	}
+	return remedy
+}
@@ -0,0 +1,275 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/NickP005/Vindax-MCM-tools/pkg/meshclient"
+	"github.com/NickP005/Vindax-MCM-tools/pkg/meshmock"
+	"github.com/NickP005/Vindax-MCM-tools/pkg/txentry"
+	"github.com/NickP005/Vindax-MCM-tools/pkg/walletstore"
+)
+
+// stepsRun is a run of payoutSteps whose step named fail fails, on a wallet cache file
+type stepsRun struct {
+	t        *testing.T
+	path     string
+	keychain *walletstore.Keychain
+	// submitted collects every signed transaction that left the process
+	submitted map[string]bool
+}
+
+func newStepsRun(t *testing.T) *stepsRun {
+	keychain, err := walletstore.NewKeychain(strings.Repeat("17", 32))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(keychain.Wipe)
+	path := filepath.Join(t.TempDir(), "wallet-cache.json")
+	if err := walletstore.Save(path, &walletstore.WalletCache{SecretKey: strings.Repeat("17", 32), Index: 5}); err != nil {
+		t.Fatal(err)
+	}
+	return &stepsRun{t: t, path: path, keychain: keychain, submitted: map[string]bool{}}
+}
+
+/*
+ * run reads the wallet cache as a new process would and pays from index 5,
+ * which keeps the funds, making the step named fail fail; submitErr is
+ * what a submit answers
+ */
+func (r *stepsRun) run(fail string, fee uint64, submitErr error) error {
+	r.t.Helper()
+	cache, err := walletstore.Read(r.path)
+	if err != nil {
+		r.t.Fatal(err)
+	}
+	saves := 0
+	tag := r.keychain.Tag()
+	steps := payoutSteps{
+		save: func(cache *walletstore.WalletCache) error {
+			saves++
+			if fail == map[int]string{1: "index", 2: "pending"}[saves] {
+				return errors.New("disk full")
+			}
+			return walletstore.Save(r.path, cache)
+		},
+		sign: func(index uint64, fee uint64) (*txentry.Transaction, error) {
+			if fail == "sign" {
+				return nil, errors.New("bad key")
+			}
+			var tx *txentry.Transaction
+			var err error
+			captureStdout(r.t, func() {
+				tx, _, err = CreateTransaction(r.keychain, index, tag[:], 100000, []SendEntry{{AddressBin: make([]byte, 20), AmountToSend: 100}}, fee)
+			})
+			return tx, err
+		},
+		submit: func(signedTx string) (string, error) {
+			r.submitted[signedTx] = true
+			if fail == "submit" {
+				return "", submitErr
+			}
+			return "0d", nil
+		},
+	}
+	var runErr error
+	captureStdout(r.t, func() { _, _, runErr = steps.run(cache, 5, fee) })
+	return runErr
+}
+
+/*
+ * TestPayoutStepsFailures fails each step of a payout in turn, then runs
+ * it again: whatever failed, index 5 never has two signatures submitted
+ */
+func TestPayoutStepsFailures(t *testing.T) {
+	for _, tc := range []struct {
+		fail string
+		// retried is whether the second run may sign, the first signature not having left the process
+		retried bool
+	}{
+		{"index", true},
+		{"sign", true},
+		{"pending", true},
+		{"submit", false},
+	} {
+		r := newStepsRun(t)
+		if err := r.run(tc.fail, 500, errors.New("connection reset")); err == nil {
+			t.Fatalf("%s: first run did not fail", tc.fail)
+		}
+		cache, _ := walletstore.Read(r.path)
+		if tc.fail != "index" && cache.Index != 6 {
+			t.Errorf("%s: index %d persisted before signing", tc.fail, cache.Index)
+		}
+		if (cache.Pending != nil) != (tc.fail == "submit") {
+			t.Errorf("%s: pending %+v", tc.fail, cache.Pending)
+		}
+
+		err := r.run("", 500, nil)
+		var pendingErr *walletstore.PendingTxError
+		if tc.retried && err != nil || !tc.retried && !errors.As(err, &pendingErr) {
+			t.Errorf("%s: second run: %v", tc.fail, err)
+		}
+		if len(r.submitted) != 1 {
+			t.Errorf("%s: %d signatures of index 5 submitted", tc.fail, len(r.submitted))
+		}
+		// A third run never signs again either
+		if err := r.run("", 500, nil); !errors.As(err, &pendingErr) || len(r.submitted) != 1 {
+			t.Errorf("%s: third run: %v, %d signatures submitted", tc.fail, err, len(r.submitted))
+		}
+	}
+}
+
+// sendRun pays 100 nanoMCM from index 5 of a wallet whose tag, holding 1000, belongs to the key at index funded
+type sendRun struct {
+	t        *testing.T
+	mock     *meshmock.Server
+	requests *requestCounter
+	dir      string
+	tag      [20]byte
+}
+
+func newSendRun(t *testing.T, funded uint64) *sendRun {
+	mock := meshmock.New()
+	t.Cleanup(mock.Close)
+	keychain, err := walletstore.NewKeychain(strings.Repeat("17", 32))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer keychain.Wipe()
+	tag, hash := keychain.Tag(), keychain.AddrHash(funded)
+	mock.SetAccount(tag[:], "0x"+hex.EncodeToString(tag[:])+hex.EncodeToString(hash[:]), 1000)
+	dir := t.TempDir()
+	if err := walletstore.Save(filepath.Join(dir, "wallet.json"), &walletstore.WalletCache{SecretKey: strings.Repeat("17", 32), Index: 5}); err != nil {
+		t.Fatal(err)
+	}
+	return &sendRun{t: t, mock: mock, requests: &requestCounter{counts: make(map[string]int), pending: make(chan struct{})}, dir: dir, tag: tag}
+}
+
+// send runs a payout with fee as a new process would, mining blocks until it is done; it returns the result and the error output
+func (r *sendRun) send(fee uint64) (payoutResult, string) {
+	r.t.Helper()
+	walletFile := filepath.Join(r.dir, "wallet.json")
+	cache, err := walletstore.Read(walletFile)
+	if err != nil {
+		r.t.Fatal(err)
+	}
+	keychain, _ := cache.Keychain()
+	hashes, _ := walletstore.LoadDerivationCache(filepath.Join(r.dir, "hashes.json"), keychain.Fingerprint())
+	receipt, err := ReadReceipt(filepath.Join(r.dir, "payout.receipt.csv"))
+	if err != nil {
+		r.t.Fatal(err)
+	}
+	client := meshclient.NewMeshAPIClient(r.mock.URL(), nil)
+	client.SetHooks(r.requests)
+	p := &payout{
+		client: client, cache: cache, keychain: keychain, hashes: hashes, receipt: receipt,
+		walletCacheFile: walletFile, payoutFile: "payout.csv", fee: fee, confirmations: 1, timeout: 1,
+		outagePausesTimeout: true, interval: 20 * time.Millisecond,
+	}
+	entries := []SendEntry{{Address: strings.Repeat("a1", 20), AddressBin: bytes.Repeat([]byte{0xa1}, 20), AmountToSend: 100}}
+
+	done := make(chan struct{})
+	go func() {
+		for {
+			select {
+			case <-done:
+				return
+			case <-time.After(50 * time.Millisecond):
+				r.mock.MineBlock()
+			}
+		}
+	}()
+	defer close(done)
+	stderr := os.Stderr
+	errR, errW, _ := os.Pipe()
+	os.Stderr = errW
+	var result payoutResult
+	captureStdout(r.t, func() { result = p.send(context.Background(), 1, entries, 5, r.tag[:], 1000) })
+	errW.Close()
+	os.Stderr = stderr
+	out, _ := io.ReadAll(errR)
+	return result, string(out)
+}
+
+// signatures returns how many transactions the key at index 5 submitted
+func (r *sendRun) signatures() int {
+	return r.requests.count("/construction/submit")
+}
+
+/*
+ * TestSendFeeRejected never signs again from an index whose transaction
+ * the node rejected for its fee, whatever fee a later run is given: the
+ * same bytes go through once the node accepts their fee
+ */
+func TestSendFeeRejected(t *testing.T) {
+	r := newSendRun(t, 5)
+	r.mock.SetMinimumFee(800)
+	result, out := r.send(500)
+	if result.Exit != EXIT_FEE_TOO_LOW || !strings.Contains(out, "asks for at least 800 nMCM. Index 5 signed it and never signs again") ||
+		!strings.Contains(out, "pay later payouts with -fee 800") {
+		t.Fatalf("fee of 500: exit %d, %q", result.Exit, out)
+	}
+	cache, _ := walletstore.Read(filepath.Join(r.dir, "wallet.json"))
+	if cache.Pending == nil || !cache.Pending.FeeRejected || cache.Pending.Fee != 500 || cache.Pending.Index != 5 {
+		t.Fatalf("pending %+v", cache.Pending)
+	}
+	signed := cache.Pending.SignedTx
+
+	// A higher fee is refused before signing, as the same one is
+	for _, fee := range []uint64{500, 900} {
+		if result, out := r.send(fee); result.Exit != 1 || !strings.Contains(out, "rejected for its fee of 500 nMCM") ||
+			!strings.Contains(out, "Rebroadcast the transaction saved in") || r.signatures() != 1 {
+			t.Errorf("fee of %d: exit %d, %d signatures, %q", fee, result.Exit, r.signatures(), out)
+		}
+	}
+
+	// Once the node accepts the fee, the saved bytes are broadcast again
+	r.mock.SetMinimumFee(500)
+	var exit int
+	captureStdout(t, func() {
+		exit = runRebroadcastPending(context.Background(), meshclient.NewMeshAPIClient(r.mock.URL(), nil), filepath.Join(r.dir, "wallet.json"), filepath.Join(r.dir, "payout.receipt.csv"))
+	})
+	if exit != 0 {
+		t.Fatalf("rebroadcast: exit %d", exit)
+	}
+	r.mock.MineBlock()
+	for _, tx := range r.mock.Submitted() {
+		if tx != signed {
+			t.Errorf("index 5 signed another transaction: %s", tx)
+		}
+	}
+	if balance, _ := r.mock.Balance(r.tag[:]); balance != 1000-100-500 {
+		t.Errorf("balance %d after paying 100 with a fee of 500", balance)
+	}
+}
+
+// TestReadWalletCacheRefill sets a missing refill address, and refuses one that is not the wallet tag
+func TestReadWalletCacheRefill(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "wallet-cache.json")
+	walletstore.Save(path, &walletstore.WalletCache{SecretKey: strings.Repeat("17", 32), Index: 5})
+	cache, err := ReadWalletCache(path)
+	if err != nil || cache.RefillAddress != "cr5m3GobqYe6BDY1jqdSNJMYsjADL5" {
+		t.Fatalf("cache %+v, %v", cache, err)
+	}
+	if saved, _ := walletstore.Read(path); saved.RefillAddress != cache.RefillAddress {
+		t.Error("refill address not saved")
+	}
+
+	other, _ := walletstore.New()
+	cache.RefillAddress = other.RefillAddress
+	walletstore.Save(path, cache)
+	if _, err := ReadWalletCache(path); !errors.Is(err, walletstore.ErrRefillMismatch) || !strings.Contains(err.Error(), other.RefillAddress) {
+		t.Errorf("refill address of another wallet: %v", err)
+	}
+	if saved, _ := walletstore.Read(path); saved.RefillAddress != other.RefillAddress {
+		t.Error("mismatching refill address overwritten")
+	}
+}
@@ -0,0 +1,286 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/NickP005/Vindax-MCM-tools/pkg/fileutil"
+	"github.com/NickP005/Vindax-MCM-tools/pkg/mcmaddr"
+	"github.com/NickP005/Vindax-MCM-tools/pkg/meshclient"
+)
+
+// DEFAULT_RECEIPTS_DIR holds the receipts when neither -receipts-dir nor the configuration name a directory
+const DEFAULT_RECEIPTS_DIR = "receipts"
+
+// Statuses of a ReceiptEntry
+const (
+	// ReceiptSigned is an entry whose transaction is signed and saved, maybe broadcast
+	ReceiptSigned    = "signed"
+	ReceiptSubmitted = "submitted"
+	ReceiptConfirmed = "confirmed"
+)
+
+// receiptHeader is the first line of a receipt file
+var receiptHeader = []string{"address", "amount", "memo", "transaction", "txid", "status"}
+
+// ReceiptEntry is an entry of a payout and the transaction paying it
+type ReceiptEntry struct {
+	Address string
+	Amount  uint64
+	Memo    string
+	// Transaction numbers the transactions of the payout from 1, in signing order
+	Transaction int
+	TxID        string
+	Status      string
+}
+
+/*
+ * Receipt maps the entries of a payout to the transactions paying them, in
+ * a CSV file saved durably at every step
+ *
+ * An entry is added once its transaction is signed, before it is broadcast:
+ * a later run on the same payout file never pays it again, see Unpaid. The
+ * file of a payout in progress is named after the payout file; Archive
+ * gives it a timestamp once every transaction confirmed, so the next payout
+ * of a file with that name starts a new receipt.
+ */
+type Receipt struct {
+	path    string
+	Entries []ReceiptEntry
+}
+
+// ReceiptPath returns the receipt of a payout file in progress, in dir
+func ReceiptPath(dir string, payoutFile string) string {
+	return filepath.Join(dir, filepath.Base(payoutFile)+".receipt.csv")
+}
+
+// ReadReceipt reads the receipt at path; a missing file is an empty receipt, created by the first Sign
+func ReadReceipt(path string) (*Receipt, error) {
+	r := &Receipt{path: path}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return r, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	records, err := csv.NewReader(bytes.NewReader(data)).ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("invalid receipt %s: %v", path, err)
+	}
+	if len(records) > 0 && len(records[0]) != len(receiptHeader) {
+		return nil, fmt.Errorf("invalid receipt %s: %d columns, expected %d", path, len(records[0]), len(receiptHeader))
+	}
+	for i, record := range records {
+		if i == 0 {
+			continue
+		}
+		amount, err := strconv.ParseUint(record[1], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid receipt %s, line %d: %v", path, i+1, err)
+		}
+		transaction, err := strconv.Atoi(record[3])
+		if err != nil {
+			return nil, fmt.Errorf("invalid receipt %s, line %d: %v", path, i+1, err)
+		}
+		r.Entries = append(r.Entries, ReceiptEntry{
+			Address:     record[0],
+			Amount:      amount,
+			Memo:        record[2],
+			Transaction: transaction,
+			TxID:        record[4],
+			Status:      record[5],
+		})
+	}
+	return r, nil
+}
+
+// Path returns the file of the receipt
+func (r *Receipt) Path() string {
+	return r.path
+}
+
+// receiptKey identifies an entry of a payout file in its receipt
+func receiptKey(address string, amount uint64, memo string) string {
+	return fmt.Sprintf("%s %d %s", address, amount, memo)
+}
+
+// Unpaid returns the entries the receipt holds no transaction for, each receipt entry accounting for one equal entry, and the number of the others
+func (r *Receipt) Unpaid(entries []SendEntry) ([]SendEntry, int) {
+	paid := make(map[string]int)
+	for _, e := range r.Entries {
+		paid[receiptKey(e.Address, e.Amount, e.Memo)]++
+	}
+	var unpaid []SendEntry
+	for _, entry := range entries {
+		key := receiptKey(entry.Address, entry.AmountToSend, entry.Memo)
+		if paid[key] > 0 {
+			paid[key]--
+			continue
+		}
+		unpaid = append(unpaid, entry)
+	}
+	return unpaid, len(entries) - len(unpaid)
+}
+
+// Transactions returns the number of transactions of the payout so far
+func (r *Receipt) Transactions() int {
+	n := 0
+	for _, e := range r.Entries {
+		n = max(n, e.Transaction)
+	}
+	return n
+}
+
+// Sign records the entries paid by transaction number n, signed and not broadcast yet, replacing a transaction n signed before
+func (r *Receipt) Sign(n int, entries []SendEntry) error {
+	kept := r.Entries[:0]
+	for _, e := range r.Entries {
+		if e.Transaction != n {
+			kept = append(kept, e)
+		}
+	}
+	r.Entries = kept
+	for _, entry := range entries {
+		r.Entries = append(r.Entries, ReceiptEntry{
+			Address:     entry.Address,
+			Amount:      entry.AmountToSend,
+			Memo:        entry.Memo,
+			Transaction: n,
+			Status:      ReceiptSigned,
+		})
+	}
+	return r.save()
+}
+
+// Update sets the ID and the status of transaction number n
+func (r *Receipt) Update(n int, txID string, status string) error {
+	for i := range r.Entries {
+		if r.Entries[i].Transaction == n {
+			r.Entries[i].TxID = txID
+			r.Entries[i].Status = status
+		}
+	}
+	return r.save()
+}
+
+// Unconfirmed returns the ID of every transaction broadcast and not confirmed yet, by number
+func (r *Receipt) Unconfirmed() map[int]string {
+	unconfirmed := make(map[int]string)
+	for _, e := range r.Entries {
+		if e.TxID != "" && e.Status != ReceiptConfirmed {
+			unconfirmed[e.Transaction] = e.TxID
+		}
+	}
+	return unconfirmed
+}
+
+// InFlight returns the last transaction not known to be confirmed, by number and ID, if any
+func (r *Receipt) InFlight() (int, string, bool) {
+	n, txID := 0, ""
+	for _, e := range r.Entries {
+		if e.Status != ReceiptConfirmed && e.Transaction > n {
+			n, txID = e.Transaction, e.TxID
+		}
+	}
+	return n, txID, n > 0
+}
+
+// Batch returns the entries paid by transaction number n
+func (r *Receipt) Batch(n int) []SendEntry {
+	var entries []SendEntry
+	for _, e := range r.Entries {
+		if e.Transaction != n {
+			continue
+		}
+		entry := SendEntry{Address: e.Address, AmountToSend: e.Amount, Memo: e.Memo}
+		if tag, err := mcmaddr.Normalize(e.Address); err == nil {
+			entry.AddressBin = tag[:]
+		}
+		entries = append(entries, entry)
+	}
+	return entries
+}
+
+// Refresh confirms the transactions broadcast by an earlier run and found in the chain since then
+func (r *Receipt) Refresh(ctx context.Context, client *meshclient.MeshAPIClient) {
+	for n, txID := range r.Unconfirmed() {
+		if found, err := DirectlyCheckTransaction(ctx, client, txID); err == nil && found {
+			if err := r.Update(n, txID, ReceiptConfirmed); err != nil {
+				fmt.Printf("Warning: %v\n", err)
+			}
+		}
+	}
+}
+
+// Signed returns the number of the transaction signed and never submitted, if any
+func (r *Receipt) Signed() (int, bool) {
+	for _, e := range r.Entries {
+		if e.Status == ReceiptSigned {
+			return e.Transaction, true
+		}
+	}
+	return 0, false
+}
+
+// Confirmed reports whether every transaction of the receipt confirmed
+func (r *Receipt) Confirmed() bool {
+	for _, e := range r.Entries {
+		if e.Status != ReceiptConfirmed {
+			return false
+		}
+	}
+	return true
+}
+
+// save writes the receipt durably, creating its directory
+func (r *Receipt) save() error {
+	var b bytes.Buffer
+	writer := csv.NewWriter(&b)
+	writer.Write(receiptHeader)
+	for _, e := range r.Entries {
+		writer.Write([]string{e.Address, strconv.FormatUint(e.Amount, 10), e.Memo, strconv.Itoa(e.Transaction), e.TxID, e.Status})
+	}
+	writer.Flush()
+	if err := writer.Error(); err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(r.path), 0755); err != nil {
+		return err
+	}
+	if err := fileutil.WriteAtomic(r.path, b.Bytes(), true); err != nil {
+		return fmt.Errorf("failed to save receipt %s: %v", r.path, err)
+	}
+	return nil
+}
+
+// Archive renames the receipt of a payout done after the time it completed, and returns its new path
+func (r *Receipt) Archive(now time.Time) (string, error) {
+	archived := strings.TrimSuffix(r.path, ".receipt.csv") + "." + now.UTC().Format("20060102T150405Z") + ".receipt.csv"
+	if err := fileutil.Rename(r.path, archived); err != nil {
+		return "", err
+	}
+	r.path = archived
+	return archived, nil
+}
+
+// Print writes how many transactions the payout took and the transaction of each entry, in signing order
+func (r *Receipt) Print(out io.Writer) error {
+	fmt.Fprintf(out, "%d entries, %d transactions:\n", len(r.Entries), r.Transactions())
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "ADDRESS\tAMOUNT (nMCM)\tTX\tTXID\tSTATUS")
+	for _, e := range r.Entries {
+		fmt.Fprintf(w, "%s\t%d\t%d\t%s\t%s\n", e.Address, e.Amount, e.Transaction, e.TxID, e.Status)
+	}
+	return w.Flush()
+}
@@ -0,0 +1,54 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/NickP005/Vindax-MCM-tools/pkg/monitor"
+)
+
+/*
+ * runStatus implements `wallet-tool status [-follow] <statefile>`: it
+ * prints the state a monitor writes with -state-file, from any terminal or
+ * host that can read the file
+ *
+ * With -follow it prints every change until the monitor is done, and exits
+ * with 0 only if the transaction confirmed.
+ */
+func runStatus(args []string) int {
+	flags := flag.NewFlagSet("status", flag.ExitOnError)
+	follow := flags.Bool("follow", false, "Print every change of the state until the monitor is done")
+	interval := flags.Duration("interval", time.Second, "How often -follow reads the state file")
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "Usage: wallet-tool status [-follow] [-interval 1s] <statefile>")
+		flags.PrintDefaults()
+	}
+	flags.Parse(args)
+	if flags.NArg() != 1 {
+		flags.Usage()
+		return 2
+	}
+	path := flags.Arg(0)
+
+	var last time.Time
+	for {
+		state, err := monitor.ReadState(path)
+		if err != nil && !(*follow && os.IsNotExist(err)) {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			return 1
+		}
+		if err == nil && !state.UpdatedAt.Equal(last) {
+			fmt.Println(state)
+			last = state.UpdatedAt
+		}
+		if !*follow || (err == nil && monitor.FinalPhase(state.Phase)) {
+			if state.Phase == monitor.PhaseConfirmed || !*follow {
+				return 0
+			}
+			return 1
+		}
+		time.Sleep(*interval)
+	}
+}
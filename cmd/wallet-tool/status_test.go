@@ -0,0 +1,52 @@
+package main
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/NickP005/Vindax-MCM-tools/pkg/monitor"
+)
+
+// TestStatusFollow follows a state file from before it exists until the transaction confirms
+func TestStatusFollow(t *testing.T) {
+	for _, final := range []string{monitor.PhaseConfirmed, monitor.PhaseTimeout} {
+		path := filepath.Join(t.TempDir(), "monitor.json")
+		go func() {
+			writer := monitor.NewStateWriter(path, nil)
+			defer writer.Close()
+			for _, state := range []monitor.State{
+				{Phase: monitor.PhaseMempool, TxID: "0x0d", LastScannedBlock: 7},
+				{Phase: monitor.PhaseIncluded, TxID: "0x0d", InclusionBlock: 8, Confirmations: 1, RequiredConfirmations: 2, LastScannedBlock: 8},
+				{Phase: final, TxID: "0x0d", InclusionBlock: 8, Confirmations: 2, RequiredConfirmations: 2, LastScannedBlock: 9},
+			} {
+				time.Sleep(30 * time.Millisecond)
+				writer.Update(state)
+			}
+		}()
+
+		var code int
+		out := captureStdout(t, func() { code = runStatus([]string{"-follow", "-interval", "5ms", path}) })
+		if want := map[string]int{monitor.PhaseConfirmed: 0, monitor.PhaseTimeout: 1}[final]; code != want {
+			t.Errorf("%s: exit code %d", final, code)
+		}
+		lines := strings.Split(strings.TrimSpace(out), "\n")
+		if len(lines) != 3 || !strings.Contains(lines[0], "mempool tx 0x0d") || !strings.Contains(lines[1], "in block 8, 1 of 2 confirmations") ||
+			!strings.Contains(lines[2], final+" tx 0x0d in block 8, 2 of 2 confirmations, last scanned block 9") {
+			t.Errorf("%s: followed as %q", final, lines)
+		}
+
+		// Without -follow the state is printed once
+		out = captureStdout(t, func() { code = runStatus([]string{path}) })
+		if code != 0 || strings.Count(out, "\n") != 1 {
+			t.Errorf("%s: status %d, %q", final, code, out)
+		}
+	}
+}
+
+func TestStatusMissingFile(t *testing.T) {
+	if code := runStatus([]string{filepath.Join(t.TempDir(), "monitor.json")}); code != 1 {
+		t.Errorf("missing file: exit code %d", code)
+	}
+}
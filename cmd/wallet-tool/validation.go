@@ -0,0 +1,188 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/NickP005/Vindax-MCM-tools/pkg/mcmaddr"
+)
+
+// EXIT_INVALID_ENTRIES is the exit code of a payout file with invalid entries
+const EXIT_INVALID_ENTRIES = 4
+
+// Reason is the machine-readable cause of a ValidationError
+type Reason string
+
+/*
+ * Reasons of a ValidationError
+ *
+ * Duplicate, BelowDust and Blocklisted belong to the payout policies that
+ * reject otherwise valid entries; reading a payout file reports the others.
+ */
+const (
+	ReasonBadRecord           Reason = "BadRecord"
+	ReasonBadAddress          Reason = "BadAddress"
+	ReasonBadChecksum         Reason = "BadChecksum"
+	ReasonBadAmount           Reason = "BadAmount"
+	ReasonBadMemo             Reason = "BadMemo"
+	ReasonOwnAddress          Reason = "OwnAddress"
+	ReasonDuplicate           Reason = "Duplicate"
+	ReasonBelowDust           Reason = "BelowDust"
+	ReasonBlocklisted         Reason = "Blocklisted"
+	ReasonUnknownAddress      Reason = "UnknownAddress"
+	ReasonBalanceLookupFailed Reason = "BalanceLookupFailed"
+)
+
+// errOwnAddress rejects the wallet's own address as a destination: the wallet would pay itself a fee for nothing
+var errOwnAddress = errors.New("the wallet's own address cannot be paid")
+
+// ValidationError is a payout file entry that cannot be paid, with where and why
+type ValidationError struct {
+	// Line is the line of the entry in the file
+	Line int
+	// Field names the field at fault: record, address, amount or memo
+	Field string
+	// Value is the raw field, as read from the file
+	Value  string
+	Reason Reason
+	// Record holds every field of the entry, for the rejected file
+	Record []string
+	Err    error
+}
+
+func (e *ValidationError) Error() string {
+	return fmt.Sprintf("line %d: %s %q rejected (%s) - %v", e.Line, e.Field, e.Value, e.Reason, e.Err)
+}
+
+func (e *ValidationError) Unwrap() error {
+	return e.Err
+}
+
+// addressReason tells a mistyped address, whose checksum fails, from input that is not an address
+func addressReason(err error) Reason {
+	var checksum *mcmaddr.ChecksumError
+	if errors.As(err, &checksum) {
+		return ReasonBadChecksum
+	}
+	return ReasonBadAddress
+}
+
+/*
+ * ValidationReport is the outcome of reading a payout file: the entries to
+ * pay and the ones that cannot be, in file order
+ *
+ * The console listing, the rejected file, the JSON report and the exit code
+ * all come from the report, so they always agree on what was rejected.
+ */
+type ValidationReport struct {
+	Entries []SendEntry
+	Errors  []*ValidationError
+}
+
+// reject records an entry that cannot be paid
+func (r *ValidationReport) reject(line int, field string, value string, reason Reason, record []string, err error) {
+	r.Errors = append(r.Errors, &ValidationError{Line: line, Field: field, Value: value, Reason: reason, Record: record, Err: err})
+}
+
+// Valid reports whether every entry can be paid
+func (r *ValidationReport) Valid() bool {
+	return len(r.Errors) == 0
+}
+
+/*
+ * ExitCode returns the exit code of a run stopped by the report
+ *
+ * Failed balance lookups alone are worth a retry and exit 1; any entry at
+ * fault in the file exits with EXIT_INVALID_ENTRIES.
+ */
+func (r *ValidationReport) ExitCode() int {
+	for _, e := range r.Errors {
+		if e.Reason != ReasonBalanceLookupFailed {
+			return EXIT_INVALID_ENTRIES
+		}
+	}
+	if len(r.Errors) > 0 {
+		return 1
+	}
+	return 0
+}
+
+// Print lists the rejected entries, one per line
+func (r *ValidationReport) Print(w io.Writer) {
+	for _, e := range r.Errors {
+		fmt.Fprintln(w, e.Error())
+	}
+	fmt.Fprintf(w, "%d entries valid, %d rejected\n", len(r.Entries), len(r.Errors))
+}
+
+// WriteRejected writes the rejected entries as a payout file would hold them, followed by the reason and the error
+func (r *ValidationReport) WriteRejected(w io.Writer) error {
+	writer := csv.NewWriter(w)
+	writer.Comma = ' '
+	for _, e := range r.Errors {
+		row := append(append([]string{}, e.Record...), string(e.Reason), fmt.Sprintf("line %d: %v", e.Line, e.Err))
+		if err := writer.Write(row); err != nil {
+			return err
+		}
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+// validationErrorJSON is a ValidationError in the JSON report
+type validationErrorJSON struct {
+	Line   int    `json:"line"`
+	Field  string `json:"field"`
+	Value  string `json:"value"`
+	Reason Reason `json:"reason"`
+	Error  string `json:"error"`
+}
+
+// WriteJSON writes the report as JSON: the counts and every rejected entry
+func (r *ValidationReport) WriteJSON(w io.Writer) error {
+	report := struct {
+		Valid    int                   `json:"valid"`
+		Rejected int                   `json:"rejected"`
+		Errors   []validationErrorJSON `json:"errors"`
+	}{Valid: len(r.Entries), Rejected: len(r.Errors), Errors: []validationErrorJSON{}}
+	for _, e := range r.Errors {
+		report.Errors = append(report.Errors, validationErrorJSON{
+			Line:   e.Line,
+			Field:  e.Field,
+			Value:  e.Value,
+			Reason: e.Reason,
+			Error:  e.Err.Error(),
+		})
+	}
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(report)
+}
+
+// writeValidationReport writes the rejected file and the JSON report of the flags set, whether or not the file is valid
+func writeValidationReport(report *ValidationReport, rejectedPath string, jsonPath string) error {
+	for _, out := range []struct {
+		path  string
+		write func(io.Writer) error
+	}{{rejectedPath, report.WriteRejected}, {jsonPath, report.WriteJSON}} {
+		if out.path == "" {
+			continue
+		}
+		file, err := os.Create(out.path)
+		if err != nil {
+			return err
+		}
+		if err := out.write(file); err != nil {
+			file.Close()
+			return err
+		}
+		if err := file.Close(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,188 @@
+package main
+
+import (
+	"context"
+	"encoding/hex"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/NickP005/Vindax-MCM-tools/pkg/mcmaddr"
+	"github.com/NickP005/Vindax-MCM-tools/pkg/meshclient"
+	"github.com/NickP005/Vindax-MCM-tools/pkg/meshmock"
+)
+
+// destination is a parsed entry at line paying 100 nanoMCM to the tag whose bytes are all b
+func destination(line int, b byte) parsedEntry {
+	var tag [mcmaddr.TagLength]byte
+	for i := range tag {
+		tag[i] = b
+	}
+	address := fmt.Sprintf("%x", tag)
+	return parsedEntry{
+		entry:  SendEntry{Address: address, AddressBin: tag[:], AmountToSend: 100},
+		tag:    tag,
+		line:   line,
+		record: []string{address, "100"},
+	}
+}
+
+// TestCheckDestinations looks up a file with repeated destinations, an unknown one and a failing lookup
+func TestCheckDestinations(t *testing.T) {
+	for _, requireExisting := range []bool{false, true} {
+		mock := meshmock.New()
+		known, unknown, failing := destination(2, 0x0a), destination(3, 0x0b), destination(1, 0x0c)
+		mock.SetAccount(known.tag[:], "0x"+hex.EncodeToString(known.tag[:])+hex.EncodeToString(known.tag[:]), 700)
+		// One request at a time: the first distinct tag, failing, gets the fault
+		mock.Fail("/call", meshmock.Fault{Status: 500, Body: `{"code":2,"message":"internal error","retriable":false}`})
+		client := meshclient.NewMeshAPIClient(mock.URL(), nil)
+		client.SetBatchConcurrency(1)
+		again := destination(4, 0x0a)
+
+		var report ValidationReport
+		captureStdout(t, func() {
+			checkDestinations(context.Background(), client, &report, []parsedEntry{failing, known, unknown, again}, requireExisting)
+		})
+		mock.Close()
+
+		wantEntries, wantErrors := 3, 1
+		if requireExisting {
+			wantEntries, wantErrors = 2, 2
+		}
+		if len(report.Entries) != wantEntries || len(report.Errors) != wantErrors {
+			t.Fatalf("require %v: %d entries, %d errors", requireExisting, len(report.Entries), len(report.Errors))
+		}
+		if e := report.Errors[0]; e.Line != 1 || e.Reason != ReasonBalanceLookupFailed {
+			t.Errorf("require %v: first error %+v", requireExisting, e)
+		}
+		if requireExisting && (report.Errors[1].Line != 3 || report.Errors[1].Reason != ReasonUnknownAddress) {
+			t.Errorf("second error %+v", report.Errors[1])
+		}
+		for _, entry := range report.Entries {
+			exists := entry.Address == known.entry.Address
+			if entry.Exists != exists || (exists && entry.Balance != 700) || (!exists && entry.Balance != 0) {
+				t.Errorf("require %v: entry %+v", requireExisting, entry)
+			}
+		}
+	}
+}
+
+// TestCheckDestinationsDisplay lists an address the chain has never seen as new, and a known empty one with its 0 balance
+func TestCheckDestinationsDisplay(t *testing.T) {
+	mock := meshmock.New()
+	defer mock.Close()
+	empty, unknown := destination(1, 0x0e), destination(2, 0x0b)
+	mock.SetAccount(empty.tag[:], "0x"+hex.EncodeToString(empty.tag[:])+hex.EncodeToString(empty.tag[:]), 0)
+	client := meshclient.NewMeshAPIClient(mock.URL(), nil)
+
+	var report ValidationReport
+	out := captureStdout(t, func() {
+		checkDestinations(context.Background(), client, &report, []parsedEntry{empty, unknown}, false)
+	})
+	for _, want := range []string{
+		empty.entry.Address + " (balance: 0 nMCM) → sending 100 nMCM",
+		unknown.entry.Address + " (new address) → sending 100 nMCM",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("output %q lacks %q", out, want)
+		}
+	}
+	if len(report.Entries) != 2 || !report.Entries[0].Exists || report.Entries[1].Exists {
+		t.Errorf("entries %+v", report.Entries)
+	}
+}
+
+// TestParseEntryMemo accepts the memos of the reference rules, multi-character groups included
+func TestParseEntryMemo(t *testing.T) {
+	payee := destination(1, 0x0a)
+	for memo, valid := range map[string]bool{
+		"XYZ":               true,
+		"INV-12":            true,
+		"AB-12-CD":          true,
+		"AB-CD":             false,
+		"inv-12":            false,
+		"INV-":              false,
+		"ORDER-12345678901": false,
+	} {
+		var report ValidationReport
+		entry, ok := parseEntry(&report, 1, []string{payee.entry.Address, "100", memo}, [mcmaddr.TagLength]byte{})
+		if ok != valid {
+			t.Errorf("%q: accepted %v, %v", memo, ok, report.Errors)
+			continue
+		}
+		if valid && entry.entry.Memo != memo {
+			t.Errorf("%q: memo %q", memo, entry.entry.Memo)
+		}
+		if !valid && (len(report.Errors) != 1 || report.Errors[0].Reason != ReasonBadMemo) {
+			t.Errorf("%q: %v", memo, report.Errors)
+		}
+	}
+}
+
+/*
+ * TestReasonFixtures reads the payout files of testdata/payouts, one per
+ * reason a payout file is rejected for, each named after its reason: line 1
+ * pays the one address the chain knows and line 2 holds the fault
+ *
+ * Duplicate, BelowDust and Blocklisted have no fixture, no payout policy
+ * producing them yet.
+ */
+func TestReasonFixtures(t *testing.T) {
+	known, own := destination(0, 0x0a), destination(0, 0x0f)
+	for _, tc := range []struct {
+		fixture         string
+		reason          Reason
+		field           string
+		lines           []int
+		requireExisting bool
+		outage          bool
+		exit            int
+	}{
+		{"BadRecord.csv", ReasonBadRecord, "record", []int{2}, false, false, EXIT_INVALID_ENTRIES},
+		{"BadRecord-quote.csv", ReasonBadRecord, "record", []int{2}, false, false, EXIT_INVALID_ENTRIES},
+		{"BadAddress.csv", ReasonBadAddress, "address", []int{2}, false, false, EXIT_INVALID_ENTRIES},
+		{"BadChecksum.csv", ReasonBadChecksum, "address", []int{2}, false, false, EXIT_INVALID_ENTRIES},
+		{"BadAmount.csv", ReasonBadAmount, "amount", []int{2}, false, false, EXIT_INVALID_ENTRIES},
+		{"BadMemo.csv", ReasonBadMemo, "memo", []int{2}, false, false, EXIT_INVALID_ENTRIES},
+		{"OwnAddress.csv", ReasonOwnAddress, "address", []int{2}, false, false, EXIT_INVALID_ENTRIES},
+		{"UnknownAddress.csv", ReasonUnknownAddress, "address", []int{2}, true, false, EXIT_INVALID_ENTRIES},
+		// Every lookup fails, and a lookup failure alone is worth a retry
+		{"BalanceLookupFailed.csv", ReasonBalanceLookupFailed, "address", []int{1, 2}, false, true, 1},
+		{"valid.csv", "", "", nil, false, false, 0},
+	} {
+		t.Run(tc.fixture, func(t *testing.T) {
+			mock := meshmock.New()
+			defer mock.Close()
+			mock.SetAccount(known.tag[:], "0x"+hex.EncodeToString(known.tag[:])+hex.EncodeToString(known.tag[:]), 700)
+			if tc.outage {
+				mock.Outage(meshmock.Fault{Status: 500, Body: `{"code":2,"message":"internal error","retriable":false}`}, time.Minute)
+			}
+			client := meshclient.NewMeshAPIClient(mock.URL(), nil)
+
+			var report *ValidationReport
+			var err error
+			captureStdout(t, func() {
+				report, err = ReadEntriesCSV(context.Background(), client, filepath.Join("testdata", "payouts", tc.fixture), own.tag, tc.requireExisting)
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(report.Errors) != len(tc.lines) {
+				t.Fatalf("%d errors, want %d: %v", len(report.Errors), len(tc.lines), report.Errors)
+			}
+			for i, e := range report.Errors {
+				if e.Reason != tc.reason || e.Field != tc.field || e.Line != tc.lines[i] {
+					t.Errorf("error %d: %v, want %s of %s on line %d", i, e, tc.reason, tc.field, tc.lines[i])
+				}
+			}
+			if len(report.Entries)+len(report.Errors) < 2 {
+				t.Errorf("%d entries, %d errors: an entry was lost", len(report.Entries), len(report.Errors))
+			}
+			if code := report.ExitCode(); code != tc.exit {
+				t.Errorf("exit code %d, want %d", code, tc.exit)
+			}
+		})
+	}
+}
@@ -0,0 +1,144 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/NickP005/Vindax-MCM-tools/pkg/meshclient"
+	"github.com/NickP005/Vindax-MCM-tools/pkg/meshmock"
+	"github.com/NickP005/Vindax-MCM-tools/pkg/txentry"
+	"github.com/NickP005/Vindax-MCM-tools/pkg/walletstore"
+	"github.com/NickP005/Vindax-MCM-tools/pkg/wotsp"
+)
+
+/*
+ * TestVerifyCurrentIndexAnswers replays the tag_resolve answers that made
+ * VerifyCurrentIndex panic when it sliced the resolved address as a string
+ */
+func TestVerifyCurrentIndexAnswers(t *testing.T) {
+	keychain, err := walletstore.NewKeychain(strings.Repeat("17", 32))
+	if err != nil {
+		t.Fatal(err)
+	}
+	walletTag := keychain.Tag()
+	tag := hex.EncodeToString(walletTag[:])
+	hash := keychain.AddrHash(1)
+	current := tag + hex.EncodeToString(hash[:])
+
+	for _, tc := range []struct {
+		name   string
+		answer string
+		index  uint64
+		amount uint64
+		failed bool
+	}{
+		{"empty result", `{"result":{}}`, 0, 0, false},
+		{"empty address", `{"result":{"address":"","amount":0}}`, 0, 0, false},
+		{"prefixed", `{"result":{"address":"0x` + current + `","amount":900}}`, 1, 900, false},
+		{"no prefix", `{"result":{"address":"` + current + `","amount":900}}`, 1, 900, false},
+		{"short address", `{"result":{"address":"0x` + tag + `","amount":900}}`, 0, 0, true},
+		{"odd length", `{"result":{"address":"0x` + current[:59] + `","amount":900}}`, 0, 0, true},
+		{"only prefix", `{"result":{"address":"0x","amount":900}}`, 0, 0, true},
+	} {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Path != "/call" {
+				http.NotFound(w, r)
+				return
+			}
+			io.WriteString(w, tc.answer)
+		}))
+		client := meshclient.NewMeshAPIClient(server.URL, nil)
+		var index, amount uint64
+		var resolved []byte
+		captureStdout(t, func() {
+			index, resolved, amount, err = VerifyCurrentIndex(context.Background(), client, keychain, 1)
+		})
+		server.Close()
+		if (err != nil) != tc.failed || index != tc.index || amount != tc.amount {
+			t.Errorf("%s: index %d, amount %d, %v", tc.name, index, amount, err)
+			continue
+		}
+		if !tc.failed && hex.EncodeToString(resolved) != tag {
+			t.Errorf("%s: tag %x", tc.name, resolved)
+		}
+	}
+}
+
+// TestCreateTransactionIndex pins the index semantics: the key at currentIndex signs, the one after it takes the change and is the next index
+func TestCreateTransactionIndex(t *testing.T) {
+	keychain, err := walletstore.NewKeychain(strings.Repeat("17", 32))
+	if err != nil {
+		t.Fatal(err)
+	}
+	walletTag := keychain.Tag()
+	var seed [32]byte
+	for i := range seed {
+		seed[i] = 0x17
+	}
+	// The keychain derives index 5 as a direct derivation does
+	direct := wotsp.KeychainKeygen(seed, 5)
+	if keychain.Keypair(5).PublicKey != direct.PublicKey {
+		t.Fatal("keychain index 5 differs from wotsp.KeychainKeygen")
+	}
+
+	var tx *txentry.Transaction
+	var next uint64
+	captureStdout(t, func() {
+		tx, next, err = CreateTransaction(keychain, 5, walletTag[:], 1000, []SendEntry{{AddressBin: make([]byte, 20), AmountToSend: 100}}, 500)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	source, change := tx.Source, tx.Change
+	sourceHash, changeHash := keychain.AddrHash(5), keychain.AddrHash(6)
+	if next != 6 || !bytes.Equal(source[txentry.TagLength:], sourceHash[:]) || !bytes.Equal(change[txentry.TagLength:], changeHash[:]) {
+		t.Errorf("next index %d, source %x, change %x", next, source[txentry.TagLength:], change[txentry.TagLength:])
+	}
+	if !bytes.Equal(source[:txentry.TagLength], walletTag[:]) || !bytes.Equal(change[:txentry.TagLength], walletTag[:]) || tx.ChangeTotal != 400 {
+		t.Errorf("tags %x %x, change %d", source[:txentry.TagLength], change[:txentry.TagLength], tx.ChangeTotal)
+	}
+}
+
+/*
+ * TestVerifyCurrentIndexSigningKey is the acceptance test of the index:
+ * whatever index the wallet cache says, the one returned is the key
+ * controlling the funds
+ */
+func TestVerifyCurrentIndexSigningKey(t *testing.T) {
+	keychain, err := walletstore.NewKeychain(strings.Repeat("17", 32))
+	if err != nil {
+		t.Fatal(err)
+	}
+	walletTag := keychain.Tag()
+	mock := meshmock.New()
+	defer mock.Close()
+	client := meshclient.NewMeshAPIClient(mock.URL(), nil)
+
+	for _, funded := range []uint64{5, 6} {
+		hash := keychain.AddrHash(funded)
+		mock.SetAccount(walletTag[:], "0x"+hex.EncodeToString(walletTag[:])+hex.EncodeToString(hash[:]), 1000)
+		source, err := walletstore.ResolveSource(context.Background(), client, walletTag[:])
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, cached := range []uint64{0, 3, funded, funded + 1, funded + 2, 40} {
+			var index, amount uint64
+			captureStdout(t, func() {
+				index, _, amount, err = VerifyCurrentIndex(context.Background(), client, keychain, cached)
+			})
+			if err != nil || index != funded || amount != 1000 {
+				t.Errorf("funds at %d, cache index %d: index %d, %v", funded, cached, index, err)
+				continue
+			}
+			if !source.Controls(keychain.Keypair(index).PublicKey[:]) {
+				t.Errorf("funds at %d, cache index %d: the signing key does not control the funds", funded, cached)
+			}
+		}
+	}
+}
@@ -0,0 +1,15 @@
+module github.com/NickP005/Vindax-MCM-tools/cmd/wots-vectors
+
+go 1.23.5
+
+require (
+	github.com/NickP005/Vindax-MCM-tools/pkg v0.0.0-00010101000000-000000000000
+	github.com/NickP005/WOTS-Go v0.0.4
+)
+
+require (
+	golang.org/x/crypto v0.33.0 // indirect
+	golang.org/x/sys v0.30.0 // indirect
+)
+
+replace github.com/NickP005/Vindax-MCM-tools/pkg => ../../pkg
@@ -0,0 +1,244 @@
+/*
+ * wots-vectors cross-checks the shared WOTS package (pkg/wotsp) against
+ * WOTS-Go, the implementation wallet-tool and tool-2/tool-3 keygen rely on
+ *
+ * For a fixed set of seeds and messages it derives the components, the public
+ * key and a signature with both implementations and compares them byte for
+ * byte. Vectors can be written to a JSON fixture, and a fixture produced by
+ * another implementation (e.g. the Mochimo C reference) can be checked with
+ * -check. Any divergence exits with status 1: a mismatch means signatures
+ * one side produces would be rejected by the other.
+ *
+ * The keychain derivation of pkg/wotsp, which wallet-tool's wallets use, is
+ * checked against WOTS-Go's Keychain the same way: a mismatch there means a
+ * wallet would no longer find the keys holding its funds.
+ */
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/NickP005/Vindax-MCM-tools/pkg/secure"
+	"github.com/NickP005/Vindax-MCM-tools/pkg/wotsp"
+	wots "github.com/NickP005/WOTS-Go"
+)
+
+// Vector is one fixture entry, all fields hex encoded
+type Vector struct {
+	Seed      string `json:"seed"`
+	Message   string `json:"message"`
+	PubSeed   string `json:"pub_seed"`
+	AddrSeed  string `json:"addr_seed"`
+	PublicKey string `json:"public_key"`
+	Signature string `json:"signature"`
+}
+
+// fixedInput derives the i-th deterministic seed or message
+func fixedInput(kind string, i int) [32]byte {
+	return sha256.Sum256([]byte(fmt.Sprintf("wots-vectors %s %d", kind, i)))
+}
+
+/*
+ * localVector computes a vector with pkg/wotsp only
+ *
+ * Parameters:
+ * - seed: 32 bytes wallet seed
+ * - msg: 32 bytes message to sign
+ *
+ * Returns the vector.
+ */
+func localVector(seed [32]byte, msg [32]byte) Vector {
+	components := wotsp.GenerateComponents(seed)
+	defer secure.Wipe(components.PrivateSeed[:])
+	addr := wotsp.SigningAddress(components.AddrSeed)
+
+	pk := wotsp.PkGen(components.PrivateSeed, components.PublicSeed, addr)
+	sig := wotsp.Sign(msg, components.PrivateSeed, components.PublicSeed, addr)
+	return Vector{
+		Seed:      hex.EncodeToString(seed[:]),
+		Message:   hex.EncodeToString(msg[:]),
+		PubSeed:   hex.EncodeToString(components.PublicSeed[:]),
+		AddrSeed:  hex.EncodeToString(addr[:]),
+		PublicKey: hex.EncodeToString(pk[:]),
+		Signature: hex.EncodeToString(sig[:]),
+	}
+}
+
+// wotsGoVector computes the same vector with WOTS-Go
+func wotsGoVector(seed [32]byte, msg [32]byte) (Vector, error) {
+	keypair, err := wots.Keygen(seed)
+	if err != nil {
+		return Vector{}, fmt.Errorf("WOTS-Go keygen failed: %v", err)
+	}
+	defer secure.Wipe(keypair.PrivateKey[:])
+	defer secure.Wipe(keypair.Components.PrivateSeed[:])
+	addr := wotsp.SigningAddress(keypair.Components.AddrSeed)
+
+	sig := keypair.Sign(msg)
+	return Vector{
+		Seed:      hex.EncodeToString(seed[:]),
+		Message:   hex.EncodeToString(msg[:]),
+		PubSeed:   hex.EncodeToString(keypair.Components.PublicSeed[:]),
+		AddrSeed:  hex.EncodeToString(addr[:]),
+		PublicKey: hex.EncodeToString(keypair.PublicKey[:]),
+		Signature: hex.EncodeToString(sig[:]),
+	}, nil
+}
+
+// keychainMatches reports whether pkg/wotsp derives the same public key as WOTS-Go's Keychain at index
+func keychainMatches(master [32]byte, index uint64) (bool, error) {
+	keychain, err := wots.NewKeychain(master)
+	if err != nil {
+		return false, fmt.Errorf("WOTS-Go keychain failed: %v", err)
+	}
+	keychain.Index = index
+	reference := keychain.Next()
+	defer secure.Wipe(reference.PrivateKey[:])
+	defer secure.Wipe(reference.Components.PrivateSeed[:])
+
+	local := wotsp.KeychainKeygen(master, index)
+	defer local.Wipe()
+	return secure.Equal(local.PublicKey[:], reference.PublicKey[:wotsp.SigSize]), nil
+}
+
+// diff lists the fields in which two vectors differ
+func diff(a Vector, b Vector) []string {
+	var fields []string
+	if a.PubSeed != b.PubSeed {
+		fields = append(fields, "pub_seed")
+	}
+	if a.AddrSeed != b.AddrSeed {
+		fields = append(fields, "addr_seed")
+	}
+	if a.PublicKey != b.PublicKey {
+		fields = append(fields, "public_key")
+	}
+	if a.Signature != b.Signature {
+		fields = append(fields, "signature")
+	}
+	return fields
+}
+
+// decode32 decodes a 32 bytes hex field of a fixture
+func decode32(field string, value string) ([32]byte, error) {
+	var out [32]byte
+	b, err := hex.DecodeString(value)
+	if err != nil || len(b) != 32 {
+		return out, fmt.Errorf("%s must be 32 bytes hex", field)
+	}
+	copy(out[:], b)
+	return out, nil
+}
+
+/*
+ * checkFixture recomputes every vector of a fixture file with pkg/wotsp
+ *
+ * Returns the number of mismatching vectors, or an error if the file cannot
+ * be read or parsed.
+ */
+func checkFixture(path string) (int, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return 0, fmt.Errorf("failed to read fixture: %v", err)
+	}
+	var vectors []Vector
+	if err := json.Unmarshal(data, &vectors); err != nil {
+		return 0, fmt.Errorf("failed to parse fixture: %v", err)
+	}
+
+	failed := 0
+	for i, expected := range vectors {
+		seed, err := decode32("seed", expected.Seed)
+		if err != nil {
+			return failed, fmt.Errorf("vector %d: %v", i, err)
+		}
+		msg, err := decode32("message", expected.Message)
+		if err != nil {
+			return failed, fmt.Errorf("vector %d: %v", i, err)
+		}
+		if fields := diff(localVector(seed, msg), expected); len(fields) > 0 {
+			fmt.Printf("vector %d: MISMATCH in %v\n", i, fields)
+			failed++
+		} else {
+			fmt.Printf("vector %d: ok\n", i)
+		}
+	}
+	return failed, nil
+}
+
+func main() {
+	count := flag.Int("n", 8, "Number of fixed seed/message vectors to cross-check")
+	out := flag.String("out", "", "Write the vectors as a JSON fixture to this file")
+	check := flag.String("check", "", "Check a JSON fixture (e.g. from the C reference) against pkg/wotsp")
+	flag.Parse()
+
+	if *check != "" {
+		failed, err := checkFixture(*check)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		if failed > 0 {
+			fmt.Fprintf(os.Stderr, "Error: %d vectors diverge from %s\n", failed, *check)
+			os.Exit(1)
+		}
+		return
+	}
+
+	vectors := make([]Vector, 0, *count)
+	failed := 0
+	for i := 0; i < *count; i++ {
+		seed := fixedInput("seed", i)
+		msg := fixedInput("message", i)
+
+		local := localVector(seed, msg)
+		reference, err := wotsGoVector(seed, msg)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: vector %d: %v\n", i, err)
+			os.Exit(1)
+		}
+		if fields := diff(local, reference); len(fields) > 0 {
+			fmt.Printf("vector %d: MISMATCH with WOTS-Go in %v\n", i, fields)
+			failed++
+		} else {
+			fmt.Printf("vector %d: ok\n", i)
+		}
+		vectors = append(vectors, local)
+
+		// Indices spread from 0 up, as a wallet moves up its keychain
+		index := uint64(i * i * 37)
+		match, err := keychainMatches(fixedInput("master", i), index)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: keychain %d: %v\n", i, err)
+			os.Exit(1)
+		}
+		if !match {
+			fmt.Printf("keychain %d (index %d): MISMATCH with WOTS-Go\n", i, index)
+			failed++
+		} else {
+			fmt.Printf("keychain %d (index %d): ok\n", i, index)
+		}
+	}
+
+	if failed > 0 {
+		fmt.Fprintf(os.Stderr, "Error: pkg/wotsp and WOTS-Go diverge on %d of %d vectors and keychain indices\n", failed, 2**count)
+		os.Exit(1)
+	}
+
+	if *out != "" {
+		data, err := json.MarshalIndent(vectors, "", "  ")
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		if err := os.WriteFile(*out, append(data, '\n'), 0644); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: failed to write fixture: %v\n", err)
+			os.Exit(1)
+		}
+	}
+}
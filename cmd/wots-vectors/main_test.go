@@ -0,0 +1,66 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// TestMatchesWOTSGo derives every fixed vector with pkg/wotsp and WOTS-Go and fails on any byte of difference
+func TestMatchesWOTSGo(t *testing.T) {
+	for i := 0; i < 16; i++ {
+		seed, msg := fixedInput("seed", i), fixedInput("message", i)
+		reference, err := wotsGoVector(seed, msg)
+		if err != nil {
+			t.Fatalf("vector %d: %v", i, err)
+		}
+		if fields := diff(localVector(seed, msg), reference); len(fields) > 0 {
+			t.Errorf("vector %d: pkg/wotsp and WOTS-Go differ in %v", i, fields)
+		}
+	}
+}
+
+// TestKeychainMatchesWOTSGo checks wotsp.KeychainKeygen against WOTS-Go's Keychain at spread indices
+func TestKeychainMatchesWOTSGo(t *testing.T) {
+	for i, index := range []uint64{0, 1, 2, 37, 1000, 5000, 1 << 32, 1<<64 - 1} {
+		match, err := keychainMatches(fixedInput("master", i), index)
+		if err != nil {
+			t.Fatalf("index %d: %v", index, err)
+		}
+		if !match {
+			t.Errorf("index %d: pkg/wotsp and WOTS-Go keychains derive different keys", index)
+		}
+	}
+}
+
+// TestCheckFixture checks the fixture of pkg/wotsp and that a tampered fixture is reported
+func TestCheckFixture(t *testing.T) {
+	path := filepath.Join("..", "..", "pkg", "wotsp", "testdata", "vectors.json")
+	failed, err := checkFixture(path)
+	if err != nil || failed != 0 {
+		t.Fatalf("%d mismatches, %v", failed, err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var vectors []Vector
+	if err := json.Unmarshal(data, &vectors); err != nil {
+		t.Fatal(err)
+	}
+	vectors[1].Signature = strings.Repeat("0", len(vectors[1].Signature))
+	tampered, _ := json.Marshal(vectors)
+	tamperedPath := filepath.Join(t.TempDir(), "vectors.json")
+	if err := os.WriteFile(tamperedPath, tampered, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if failed, err := checkFixture(tamperedPath); err != nil || failed != 1 {
+		t.Errorf("tampered fixture: %d mismatches, %v", failed, err)
+	}
+	if _, err := checkFixture(filepath.Join(t.TempDir(), "missing.json")); err == nil {
+		t.Error("no error for a missing fixture")
+	}
+}
@@ -0,0 +1,20 @@
+/*
+ * Package integration runs end-to-end scenarios on the shared packages:
+ * keys from pkg/wotsp, transactions built and signed with pkg/txentry, and
+ * the Mesh API through pkg/meshclient, against a fresh pkg/meshmock chain
+ * per scenario
+ *
+ * The scenarios are Go tests behind the integration build tag:
+ *
+ *	go test -tags integration ./...
+ *
+ * They fund accounts on the mock chain, build and submit transactions, mine
+ * blocks and check the confirmations and balances. The live scenarios only
+ * read from a real node, and run only when MCM_INTEGRATION_API names one;
+ * nothing is ever submitted to it.
+ *
+ * The scripts of the scripts directory are scenarios too: a payout paid on
+ * a mock chain while faults are injected, checked against the outcome they
+ * expect (see Script).
+ */
+package integration
@@ -0,0 +1,115 @@
+//go:build integration
+
+package integration
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+
+	"github.com/NickP005/Vindax-MCM-tools/pkg/meshclient"
+	"github.com/NickP005/Vindax-MCM-tools/pkg/meshmock"
+	"github.com/NickP005/Vindax-MCM-tools/pkg/secure"
+	"github.com/NickP005/Vindax-MCM-tools/pkg/txbuild"
+	"github.com/NickP005/Vindax-MCM-tools/pkg/txentry"
+	"github.com/NickP005/Vindax-MCM-tools/pkg/wotsp"
+)
+
+// Env is what a scenario runs against: a fresh mock chain and a client of it, or a live node and no mock
+type Env struct {
+	Client *meshclient.MeshAPIClient
+	// Mock is nil for the live scenarios
+	Mock *meshmock.Server
+	// Logf prints to the log of the test running the scenario
+	Logf func(format string, args ...interface{})
+}
+
+// newMockEnv starts a mock chain for one scenario; the caller closes it
+func newMockEnv(logf func(format string, args ...interface{})) *Env {
+	mock := meshmock.New()
+	return &Env{Client: newMeshClient(mock.URL()), Mock: mock, Logf: logf}
+}
+
+// Close stops the mock, if any
+func (e *Env) Close() {
+	if e.Mock != nil {
+		e.Mock.Close()
+	}
+}
+
+/*
+ * Account is a test wallet: a tag and the keypair currently holding it
+ *
+ * The tag is the address hash of the first key, as for wallet-tool
+ * wallets. Next moves the tag to a fresh key, as change does.
+ */
+type Account struct {
+	Tag     [txentry.TagLength]byte
+	Keypair wotsp.Keypair
+}
+
+// newKeypair derives a keypair from a random seed
+func newKeypair() (wotsp.Keypair, error) {
+	var seed [secure.KeyLength]byte
+	if _, err := rand.Read(seed[:]); err != nil {
+		return wotsp.Keypair{}, fmt.Errorf("failed to generate random seed: %v", err)
+	}
+	defer secure.Wipe(seed[:])
+	return wotsp.Keygen(seed), nil
+}
+
+// NewAccount generates an account whose tag is the address hash of its first key
+func NewAccount() (*Account, error) {
+	keypair, err := newKeypair()
+	if err != nil {
+		return nil, err
+	}
+	return &Account{Tag: keypair.AddrHash(), Keypair: keypair}, nil
+}
+
+// Address returns the 40 bytes address of the account: its tag and the address hash of its current key
+func (a *Account) Address() [txentry.AddressLength]byte {
+	return txbuild.Address(a.Tag, a.Keypair.PublicKey)
+}
+
+// Next returns the account with its tag on a fresh key, the change address of a transaction spending it
+func (a *Account) Next() (*Account, error) {
+	keypair, err := newKeypair()
+	if err != nil {
+		return nil, err
+	}
+	return &Account{Tag: a.Tag, Keypair: keypair}, nil
+}
+
+// Fund sets the balance of an account on the mock chain
+func (e *Env) Fund(account *Account, balance uint64) {
+	address := account.Address()
+	e.Mock.SetAccount(account.Tag[:], "0x"+hex.EncodeToString(address[:]), balance)
+}
+
+/*
+ * Transfer builds and signs a transaction paying amount from source to
+ * destination, with the change on the next key of source
+ *
+ * Returns the signed transaction and the change account. The source key is
+ * wiped: it must not sign again.
+ */
+func Transfer(source *Account, balance uint64, fee uint64, destination [txentry.TagLength]byte, memo string, amount uint64) (*txentry.Transaction, *Account, error) {
+	change, err := source.Next()
+	if err != nil {
+		return nil, nil, err
+	}
+	payment, err := txbuild.NewDestination(destination, memo, amount)
+	if err != nil {
+		return nil, nil, err
+	}
+	tx, err := txbuild.NewTransfer(source.Address(), change.Address(), balance, fee, []txentry.Destination{payment})
+	if err != nil {
+		return nil, nil, err
+	}
+	defer source.Keypair.Wipe()
+	if err := txbuild.Sign(tx, &source.Keypair); err != nil {
+		return nil, nil, err
+	}
+	return tx, change, nil
+}
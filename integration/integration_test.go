@@ -0,0 +1,102 @@
+//go:build integration
+
+package integration
+
+import (
+	"context"
+	"flag"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/NickP005/Vindax-MCM-tools/pkg/meshclient"
+)
+
+// LIVE_API_ENV names the environment variable holding the Mesh API URL of the live scenarios
+const LIVE_API_ENV = "MCM_INTEGRATION_API"
+
+var (
+	scenarioTimeout = flag.Duration("scenario-timeout", 30*time.Second, "Timeout of each scenario")
+	scriptsDir      = flag.String("scripts", "scripts", "Directory of the scenario scripts (*.json)")
+)
+
+// newMeshClient returns a Mesh API client identifying the harness in its User-Agent
+func newMeshClient(api string) *meshclient.MeshAPIClient {
+	client := meshclient.NewMeshAPIClient(api, nil)
+	client.SetUserAgent("integration")
+	return client
+}
+
+// runScenario runs one scenario as a subtest, in its own environment; live scenarios are skipped without LIVE_API_ENV
+func runScenario(t *testing.T, scenario Scenario) {
+	t.Run(scenario.Name, func(t *testing.T) {
+		var env *Env
+		if scenario.Live {
+			api := os.Getenv(LIVE_API_ENV)
+			if api == "" {
+				t.Skipf("%s not set", LIVE_API_ENV)
+			}
+			client := newMeshClient(api)
+			client.SetRetryPolicy(meshclient.DefaultRetryPolicy())
+			env = &Env{Client: client, Logf: t.Logf}
+		} else {
+			env = newMockEnv(t.Logf)
+		}
+		defer env.Close()
+		ctx, cancel := context.WithTimeout(context.Background(), *scenarioTimeout)
+		defer cancel()
+		if err := scenario.Run(ctx, env); err != nil {
+			t.Fatal(err)
+		}
+	})
+}
+
+func TestScenarios(t *testing.T) {
+	for _, scenario := range Scenarios {
+		runScenario(t, scenario)
+	}
+}
+
+// TestScripts runs the scripts of -scripts, named script/<name>
+func TestScripts(t *testing.T) {
+	scripts, err := LoadScripts(*scriptsDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(scripts) == 0 {
+		t.Skipf("no script in %s", *scriptsDir)
+	}
+	for _, script := range scripts {
+		runScenario(t, script.Scenario())
+	}
+}
+
+// TestLoadScript checks that a script with a typo or a missing part is refused rather than run without it
+func TestLoadScript(t *testing.T) {
+	dir := t.TempDir()
+	const accounts = `"accounts": [{"name": "payout", "balance": 1000}, {"name": "alice"}], "source": "payout", "payouts": "payouts.csv"`
+	for content, message := range map[string]string{
+		`{` + accounts + `, "expect": {"outcome": "confirmed"}, "fualts": []}`: `unknown field "fualts"`,
+		`{` + accounts + `}`: "no expectation",
+		`{` + accounts + `, "expect": {"balances": {"bob": 1}}}`:                                   `"bob", which is not an account`,
+		`{` + accounts + `, "expect": {"signed": 1}, "faults": [{"min_fee": 9, "drift": 1}]}`:      "fault 1: exactly one of",
+		`{` + accounts + `, "expect": {"signed": 1}, "faults": [{"at": "later", "outage": "1s"}]}`: "fault 1: at must be",
+		`{` + accounts + `, "expect": {"signed": 1}, "faults": [{"reorg": {"to_mempool": true}}]}`: "a reorg needs a depth",
+		`{` + accounts + `, "expect": {"signed": 1}, "faults": [{"outage": 3}]}`:                   "duration must be a string",
+		`{"accounts": [{"name": "a"}, {"name": "a"}], "source": "a", "payouts": "p.csv"}`:          `unique, got "a"`,
+	} {
+		path := filepath.Join(dir, "script.json")
+		os.WriteFile(path, []byte(content), 0644)
+		if _, err := LoadScript(path); err == nil || !strings.Contains(err.Error(), message) {
+			t.Errorf("%s: %v", content, err)
+		}
+	}
+
+	// A script is named after its file unless it sets a name
+	script, err := LoadScript(filepath.Join("scripts", "payout-confirmed.json"))
+	if err != nil || script.Name != "payout-confirmed" || script.Scenario().Name != "script/payout-confirmed" {
+		t.Errorf("script %+v, %v", script, err)
+	}
+}
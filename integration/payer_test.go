@@ -0,0 +1,385 @@
+//go:build integration
+
+package integration
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/NickP005/Vindax-MCM-tools/pkg/meshclient"
+	"github.com/NickP005/Vindax-MCM-tools/pkg/secure"
+	"github.com/NickP005/Vindax-MCM-tools/pkg/txbuild"
+	"github.com/NickP005/Vindax-MCM-tools/pkg/txentry"
+	"github.com/NickP005/Vindax-MCM-tools/pkg/wotsp"
+)
+
+// MAX_DRIFT_SCAN is how many keys past the one that signed the payer scans for the key the tag belongs to
+const MAX_DRIFT_SCAN = 16
+
+// Outcomes of a payout, as reported by Payer.Run and expected by the scripts
+const (
+	OutcomeConfirmed = "confirmed" // the transaction has the confirmations asked
+	OutcomeStuck     = "stuck"     // the transaction stayed out of StuckAfter blocks
+	OutcomeRejected  = "rejected"  // the node refused the transaction for good
+	OutcomeFailed    = "failed"    // anything else, e.g. the scenario timed out
+)
+
+/*
+ * Wallet is a deterministic chain of keys sharing one tag, as a wallet-tool
+ * wallet: the key at each index is derived from the seed and the index,
+ * and the tag is the address hash of the key at index 0
+ */
+type Wallet struct {
+	seed [secure.KeyLength]byte
+	Tag  [txentry.TagLength]byte
+	// Index is the key the wallet signs with next, as the index of a wallet cache
+	Index uint64
+}
+
+// NewWallet generates a wallet from a random seed
+func NewWallet() (*Wallet, error) {
+	w := &Wallet{}
+	if _, err := rand.Read(w.seed[:]); err != nil {
+		return nil, fmt.Errorf("failed to generate random seed: %v", err)
+	}
+	first := w.Key(0)
+	defer first.Wipe()
+	w.Tag = first.AddrHash()
+	return w, nil
+}
+
+// Key derives the keypair at index; the caller wipes it
+func (w *Wallet) Key(index uint64) wotsp.Keypair {
+	var buf [secure.KeyLength + 8]byte
+	copy(buf[:], w.seed[:])
+	binary.BigEndian.PutUint64(buf[secure.KeyLength:], index)
+	seed := sha256.Sum256(buf[:])
+	defer secure.Wipe(seed[:])
+	return wotsp.Keygen(seed)
+}
+
+// Address returns the 40 bytes address of the tag on the key at index
+func (w *Wallet) Address(index uint64) [txentry.AddressLength]byte {
+	key := w.Key(index)
+	defer key.Wipe()
+	return txbuild.Address(w.Tag, key.PublicKey)
+}
+
+// PayerEvent is a step of a payout a script can inject faults at
+type PayerEvent struct {
+	// Kind is "submitted", once the node accepted the transaction, or "included", once it is seen in a block
+	Kind string
+	// Height is the block holding the transaction, for "included"
+	Height uint64
+}
+
+// PayerResult is what a payout did, compared by the scripts to their expectations
+type PayerResult struct {
+	Outcome string
+	Err     error
+	// Signed counts the transactions signed: a resumed drift signs again, with the key the tag belongs to
+	Signed int
+	// Rebroadcasts counts the signed transaction submitted again after it left the mempool
+	Rebroadcasts int
+	// Reorgs counts the reorgs that took the transaction out of its block
+	Reorgs int
+	// Fee is the fee of the last transaction signed
+	Fee uint64
+	// Drift is how many keys past its index the wallet found the tag, 0 when it did not drift
+	Drift uint64
+	// Height is the block holding the confirmed transaction
+	Height uint64
+}
+
+/*
+ * Payer pays a list of destinations from a wallet the way wallet-tool
+ * does, on the shared packages: the transaction is built and signed with
+ * pkg/txentry and followed block by block with meshclient.WatchBlocks
+ *
+ * - a rejection for the fee ends the payout: the key signed once and never
+ *   signs again
+ * - a rejection for the signature scans the next keys for the one the tag
+ *   belongs to, and resumes from it once
+ * - a transaction that left the mempool without being mined, or that a
+ *   reorg dropped, is broadcast again with the same bytes
+ */
+type Payer struct {
+	Client       *meshclient.MeshAPIClient
+	Wallet       *Wallet
+	Destinations []txentry.Destination
+	// Fee is paid as is; 0 pays the fee suggested by /construction/metadata
+	Fee uint64
+	// Confirmations is the count of blocks, the one holding the transaction included, to wait for
+	Confirmations int
+	// StuckAfter gives up once the transaction stayed out of that many blocks, 0 never does
+	StuckAfter   int
+	PollInterval time.Duration
+	// OnEvent, if set, is called at every step of the payout, before the payer goes on
+	OnEvent func(PayerEvent)
+	// OnSigned, if set, is called with every transaction signed and the index of its key, before it is submitted; an error stops the payout
+	OnSigned func(index uint64, raw []byte) error
+}
+
+// event reports a step of the payout
+func (p *Payer) event(event PayerEvent) {
+	if p.OnEvent != nil {
+		p.OnEvent(event)
+	}
+}
+
+// Run pays the destinations and follows the transaction until it is confirmed, stuck or rejected
+func (p *Payer) Run(ctx context.Context) PayerResult {
+	var result PayerResult
+	// The watch starts before the submit, so no block mined after it goes unseen
+	watch, err := p.Client.WatchBlocks(ctx, p.PollInterval)
+	if err != nil {
+		result.Outcome, result.Err = OutcomeFailed, fmt.Errorf("watching blocks: %v", err)
+		return result
+	}
+	status, err := p.Client.NetworkStatus(ctx)
+	if err != nil {
+		result.Outcome, result.Err = OutcomeFailed, fmt.Errorf("network status: %v", err)
+		return result
+	}
+
+	raw, txID, err := p.pay(ctx, &result)
+	if err != nil {
+		result.Outcome, result.Err = OutcomeRejected, err
+		if ctx.Err() != nil {
+			result.Outcome = OutcomeFailed
+		}
+		return result
+	}
+	p.event(PayerEvent{Kind: "submitted"})
+	p.follow(ctx, watch, status.CurrentBlockIdentifier.Index+1, raw, txID, &result)
+	return result
+}
+
+/*
+ * Resume broadcasts again raw, a payout an earlier run signed with the key
+ * at index, and follows it as Run does
+ *
+ * A wallet tag no longer held by that key means the transaction was mined
+ * already: it is confirmed at once, without a Height.
+ */
+func (p *Payer) Resume(ctx context.Context, index uint64, raw []byte) PayerResult {
+	var result PayerResult
+	watch, err := p.Client.WatchBlocks(ctx, p.PollInterval)
+	if err != nil {
+		result.Outcome, result.Err = OutcomeFailed, fmt.Errorf("watching blocks: %v", err)
+		return result
+	}
+	status, err := p.Client.NetworkStatus(ctx)
+	if err != nil {
+		result.Outcome, result.Err = OutcomeFailed, fmt.Errorf("network status: %v", err)
+		return result
+	}
+
+	// Mined since the status was read, the transaction no longer submits: the tag tells
+	moved := func() bool {
+		source, err := p.Client.ResolveTag(ctx, p.Wallet.Tag[:])
+		address := p.Wallet.Address(index)
+		return err == nil && !secure.Equal(source.Address, address[:])
+	}
+	if moved() {
+		result.Outcome = OutcomeConfirmed
+		return result
+	}
+	txID, err := p.submit(ctx, raw)
+	if err != nil {
+		result.Outcome, result.Err = OutcomeRejected, err
+		switch {
+		case ctx.Err() != nil:
+			result.Outcome = OutcomeFailed
+		case moved():
+			result.Outcome, result.Err = OutcomeConfirmed, nil
+		}
+		return result
+	}
+	result.Rebroadcasts++
+	p.event(PayerEvent{Kind: "submitted"})
+	p.follow(ctx, watch, status.CurrentBlockIdentifier.Index+1, raw, txID, &result)
+	return result
+}
+
+// pay signs and submits the payout, from the key the tag belongs to on a drift rejection, and returns the signed bytes and the ID of the transaction
+func (p *Payer) pay(ctx context.Context, result *PayerResult) ([]byte, string, error) {
+	resumed := false
+	for {
+		source, err := p.Client.ResolveTag(ctx, p.Wallet.Tag[:])
+		if err != nil {
+			return nil, "", fmt.Errorf("resolving the wallet tag: %v", err)
+		}
+		fee := p.Fee
+		if fee == 0 {
+			if fee, err = p.suggestedFee(ctx, source.Amount); err != nil {
+				return nil, "", err
+			}
+		}
+
+		raw, err := p.sign(source.Amount, fee)
+		if err != nil {
+			return nil, "", err
+		}
+		result.Signed++
+		result.Fee = fee
+		if p.OnSigned != nil {
+			if err := p.OnSigned(p.Wallet.Index, raw); err != nil {
+				return nil, "", err
+			}
+		}
+		txID, err := p.submit(ctx, raw)
+		switch {
+		case err == nil:
+			return raw, txID, nil
+		case errors.Is(err, meshclient.ErrSignatureRejected) && !resumed:
+			resumed = true
+			drift, found, scanErr := p.scan(ctx)
+			if scanErr != nil {
+				return nil, "", scanErr
+			}
+			if !found || drift == 0 {
+				return nil, "", err
+			}
+			result.Drift = drift
+			p.Wallet.Index += drift
+		default:
+			return nil, "", err
+		}
+	}
+}
+
+// suggestedFee asks the node the fee of a transaction spending balance from the wallet
+func (p *Payer) suggestedFee(ctx context.Context, balance uint64) (uint64, error) {
+	debit, err := meshclient.SourceOperation(0, p.Wallet.Tag[:], balance)
+	if err != nil {
+		return 0, err
+	}
+	preprocess, err := p.Client.ConstructionPreprocess(ctx, []meshclient.Operation{debit})
+	if err != nil {
+		return 0, fmt.Errorf("preprocess: %v", err)
+	}
+	metadata, err := p.Client.ConstructionMetadata(ctx, preprocess.Options)
+	if err != nil {
+		return 0, fmt.Errorf("metadata: %v", err)
+	}
+	fee, ok, err := metadata.Fee()
+	if err != nil || !ok {
+		return 0, fmt.Errorf("no suggested fee (%v)", err)
+	}
+	return fee, nil
+}
+
+// sign builds the transfer of the whole balance from the key at the wallet index, the change on the next key, and signs it
+func (p *Payer) sign(balance uint64, fee uint64) ([]byte, error) {
+	key := p.Wallet.Key(p.Wallet.Index)
+	defer key.Wipe()
+	source := txbuild.Address(p.Wallet.Tag, key.PublicKey)
+	tx, err := txbuild.NewTransfer(source, p.Wallet.Address(p.Wallet.Index+1), balance, fee, p.Destinations)
+	if err != nil {
+		return nil, err
+	}
+	if err := txbuild.Sign(tx, &key); err != nil {
+		return nil, err
+	}
+	return tx.Bytes(), nil
+}
+
+// submit broadcasts signed bytes, again while the node cannot be reached: the same bytes are never a second payment
+func (p *Payer) submit(ctx context.Context, raw []byte) (string, error) {
+	for {
+		result, err := p.Client.SubmitTransaction(ctx, hex.EncodeToString(raw))
+		if err == nil {
+			return result.TransactionIdentifier.Hash, nil
+		}
+		if !meshclient.DefaultRetryable(err) {
+			return "", fmt.Errorf("submit: %w", err)
+		}
+		select {
+		case <-ctx.Done():
+			return "", fmt.Errorf("submit: %w", err)
+		case <-time.After(p.PollInterval):
+		}
+	}
+}
+
+// scan looks for the key the wallet tag belongs to past the wallet index, and returns how far it is
+func (p *Payer) scan(ctx context.Context) (uint64, bool, error) {
+	source, err := p.Client.ResolveTag(ctx, p.Wallet.Tag[:])
+	if err != nil {
+		return 0, false, fmt.Errorf("resolving the wallet tag: %v", err)
+	}
+	for drift := uint64(0); drift <= MAX_DRIFT_SCAN; drift++ {
+		address := p.Wallet.Address(p.Wallet.Index + drift)
+		if secure.Equal(address[:], source.Address) {
+			return drift, true, nil
+		}
+	}
+	return 0, false, nil
+}
+
+/*
+ * follow checks every block from height next on for the transaction until
+ * it has the confirmations, or stayed out of StuckAfter blocks
+ *
+ * A block that cannot be checked, e.g. during an outage, is checked again
+ * at the next event. A reorg taking the transaction out of its block sends
+ * the check back to that height.
+ */
+func (p *Payer) follow(ctx context.Context, watch <-chan meshclient.BlockEvent, next uint64, raw []byte, txID string, result *PayerResult) {
+	var included uint64
+	pending := 0
+	for event := range watch {
+		if event.Err != nil {
+			continue
+		}
+		if included > 0 && event.Reorg {
+			found, err := p.Client.BlockHasTransaction(ctx, included, txID)
+			if err != nil {
+				continue
+			}
+			if !found {
+				result.Reorgs++
+				next, included = included, 0
+			}
+		}
+
+		for included == 0 && next <= event.Height {
+			found, err := p.Client.BlockHasTransaction(ctx, next, txID)
+			if err != nil {
+				break
+			}
+			if found {
+				included = next
+				p.event(PayerEvent{Kind: "included", Height: included})
+			} else {
+				pending++
+			}
+			next++
+		}
+
+		if included == 0 && next > event.Height {
+			if p.StuckAfter > 0 && pending >= p.StuckAfter {
+				result.Outcome, result.Err = OutcomeStuck, fmt.Errorf("transaction %s out of %d blocks", txID, pending)
+				return
+			}
+			// Gone from the mempool without a block: evicted, or dropped by a reorg
+			if _, err := p.Client.MempoolTransaction(ctx, txID); errors.Is(err, meshclient.ErrNotInMempool) {
+				if _, err := p.Client.SubmitTransaction(ctx, hex.EncodeToString(raw)); err == nil {
+					result.Rebroadcasts++
+				}
+			}
+		}
+		if included > 0 && event.Height+1-included >= uint64(p.Confirmations) {
+			result.Outcome, result.Height = OutcomeConfirmed, included
+			return
+		}
+	}
+	result.Outcome, result.Err = OutcomeFailed, fmt.Errorf("transaction %s not confirmed: %v", txID, ctx.Err())
+}
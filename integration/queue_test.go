@@ -0,0 +1,283 @@
+//go:build integration
+
+package integration
+
+import (
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/NickP005/Vindax-MCM-tools/pkg/amount"
+	"github.com/NickP005/Vindax-MCM-tools/pkg/fileutil"
+	"github.com/NickP005/Vindax-MCM-tools/pkg/mcmaddr"
+	"github.com/NickP005/Vindax-MCM-tools/pkg/payqueue"
+	"github.com/NickP005/Vindax-MCM-tools/pkg/txbuild"
+	"github.com/NickP005/Vindax-MCM-tools/pkg/txentry"
+)
+
+// QUEUE_FEE is the fee of every transaction of the queue scenarios, in nanoMCM
+const QUEUE_FEE = 500
+
+// queuePending is the transaction signed for a job, saved before it is broadcast as wallet-tool saves it in the wallet cache
+type queuePending struct {
+	Index    uint64 `json:"index"`
+	SignedTx string `json:"signedTx"`
+}
+
+/*
+ * queuePayer is a payqueue.Handler paying each job with a Payer, from one
+ * wallet
+ *
+ * A job file holds one "address amount" line per destination. The
+ * transaction signed for a job is kept in a file of dir until it confirms,
+ * so a payer restarted on the same dir resumes it rather than sign again.
+ */
+type queuePayer struct {
+	env    *Env
+	wallet *Wallet
+	dir    string
+	// OnEvent is the OnEvent of every Payer
+	OnEvent func(job string, event PayerEvent)
+
+	// destinations of the job validated last
+	destinations []txentry.Destination
+}
+
+// pendingPath returns the file keeping the transaction signed for job
+func (h *queuePayer) pendingPath(job *payqueue.Job) string {
+	return filepath.Join(h.dir, job.Name+".pending.json")
+}
+
+// Validate reads the destinations of the job
+func (h *queuePayer) Validate(ctx context.Context, job *payqueue.Job) error {
+	data, err := os.ReadFile(job.Path)
+	if err != nil {
+		return err
+	}
+	h.destinations = nil
+	for i, line := range strings.Split(strings.TrimSpace(string(data)), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) != 2 {
+			return fmt.Errorf("line %d: expected an address and an amount", i+1)
+		}
+		tag, err := mcmaddr.Normalize(fields[0])
+		if err != nil {
+			return fmt.Errorf("line %d: %v", i+1, err)
+		}
+		value, err := amount.Parse(fields[1], amount.NanoMCM)
+		if err != nil {
+			return fmt.Errorf("line %d: %v", i+1, err)
+		}
+		destination, err := txbuild.NewDestination(tag, "", value)
+		if err != nil {
+			return fmt.Errorf("line %d: %v", i+1, err)
+		}
+		h.destinations = append(h.destinations, destination)
+	}
+	return nil
+}
+
+// Pay pays the job, or resumes the transaction signed for it by an earlier run
+func (h *queuePayer) Pay(ctx context.Context, job *payqueue.Job) error {
+	payer := &Payer{
+		Client:        h.env.Client,
+		Wallet:        h.wallet,
+		Destinations:  h.destinations,
+		Fee:           QUEUE_FEE,
+		Confirmations: 1,
+		PollInterval:  DEFAULT_BLOCK_TIME / 4,
+		OnEvent: func(event PayerEvent) {
+			if h.OnEvent != nil {
+				h.OnEvent(job.Name, event)
+			}
+		},
+		OnSigned: func(index uint64, raw []byte) error {
+			data, err := json.Marshal(queuePending{Index: index, SignedTx: hex.EncodeToString(raw)})
+			if err != nil {
+				return err
+			}
+			return fileutil.WriteAtomic(h.pendingPath(job), data, true)
+		},
+	}
+
+	var result PayerResult
+	var pending queuePending
+	data, err := os.ReadFile(h.pendingPath(job))
+	switch {
+	case err == nil:
+		if err := json.Unmarshal(data, &pending); err != nil {
+			return err
+		}
+		raw, err := hex.DecodeString(pending.SignedTx)
+		if err != nil {
+			return err
+		}
+		result = payer.Resume(ctx, pending.Index, raw)
+	case errors.Is(err, os.ErrNotExist):
+		result = payer.Run(ctx)
+		pending.Index = h.wallet.Index
+	default:
+		return err
+	}
+
+	if result.Outcome != OutcomeConfirmed {
+		return fmt.Errorf("%s: %v", result.Outcome, result.Err)
+	}
+	// The change is on the next key, which signs the next job
+	h.wallet.Index = pending.Index + 1
+	return os.Remove(h.pendingPath(job))
+}
+
+// writeJob drops a job file in the queue, written aside and renamed in, modified at
+func writeJob(dir string, name string, lines string, at time.Time) error {
+	hidden := filepath.Join(dir, "."+name)
+	if err := os.WriteFile(hidden, []byte(lines), 0644); err != nil {
+		return err
+	}
+	if err := os.Chtimes(hidden, at, at); err != nil {
+		return err
+	}
+	return os.Rename(hidden, filepath.Join(dir, name))
+}
+
+// expectJobs checks the state and attempts of every job of the state index, by name
+func expectJobs(q *payqueue.Queue, want map[string]payqueue.Job) error {
+	jobs := q.Jobs()
+	if len(jobs) != len(want) {
+		return fmt.Errorf("%d jobs in the queue index, expected %d", len(jobs), len(want))
+	}
+	for _, job := range jobs {
+		expected := want[job.Name]
+		if job.State != expected.State || job.Attempts != expected.Attempts {
+			return fmt.Errorf("job %s %s after %d attempts (%s), expected %s after %d", job.Name, job.State, job.Attempts, job.LastError, expected.State, expected.Attempts)
+		}
+		if _, err := os.Stat(job.Path); err != nil {
+			return fmt.Errorf("job %s: %v", job.Name, err)
+		}
+	}
+	return nil
+}
+
+/*
+ * queueRestart drains a queue of three jobs, the second one poison, and
+ * stops the first run as soon as the first job is broadcast: the second
+ * run must resume its transaction rather than sign again, park the poison
+ * job after its attempts, and pay the third one
+ */
+func queueRestart(ctx context.Context, env *Env) error {
+	wallet, err := NewWallet()
+	if err != nil {
+		return err
+	}
+	address := wallet.Address(0)
+	env.Mock.SetAccount(wallet.Tag[:], "0x"+hex.EncodeToString(address[:]), FUNDING)
+	var accounts [3]*Account
+	for i := range accounts {
+		if accounts[i], err = NewAccount(); err != nil {
+			return err
+		}
+	}
+	dir, err := os.MkdirTemp("", "queue")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(dir)
+	queueDir := filepath.Join(dir, "queue")
+	if err := os.Mkdir(queueDir, 0755); err != nil {
+		return err
+	}
+	hexTag := func(a *Account) string { return hex.EncodeToString(a.Tag[:]) }
+	start := time.Now().Add(-time.Minute)
+	for i, job := range []struct{ name, lines string }{
+		{"a.csv", fmt.Sprintf("%s 100000\n%s 50000\n", hexTag(accounts[0]), hexTag(accounts[1]))},
+		{"b.csv", "not-an-address 5000\n"},
+		{"c.csv", fmt.Sprintf("%s 70000\n", hexTag(accounts[2]))},
+	} {
+		if err := writeJob(queueDir, job.name, job.lines, start.Add(time.Duration(i)*time.Second)); err != nil {
+			return err
+		}
+	}
+
+	blocks := time.NewTicker(DEFAULT_BLOCK_TIME)
+	defer blocks.Stop()
+	go func() {
+		for {
+			select {
+			case <-blocks.C:
+				env.Mock.MineBlock()
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	// The first run stops once the first job is broadcast, as a crash would
+	q, err := payqueue.Open(queueDir, 2)
+	if err != nil {
+		return err
+	}
+	stopped, stop := context.WithCancel(ctx)
+	defer stop()
+	h := &queuePayer{env: env, wallet: wallet, dir: dir, OnEvent: func(job string, event PayerEvent) {
+		if event.Kind == "submitted" {
+			stop()
+		}
+	}}
+	job, err := q.Step(stopped, h)
+	if err != nil {
+		return fmt.Errorf("first run: %v", err)
+	}
+	if job == nil || job.Name != "a.csv" || job.State != payqueue.StateSubmitted {
+		return fmt.Errorf("first run stopped on %+v, expected a.csv submitted", job)
+	}
+	if err := q.Close(); err != nil {
+		return err
+	}
+
+	// The second run starts from the files alone
+	if q, err = payqueue.Open(queueDir, 2); err != nil {
+		return err
+	}
+	defer q.Close()
+	h = &queuePayer{env: env, wallet: &Wallet{seed: wallet.seed, Tag: wallet.Tag}, dir: dir}
+	for {
+		job, err := q.Step(ctx, h)
+		if err != nil {
+			return fmt.Errorf("second run: %v", err)
+		}
+		if job == nil {
+			break
+		}
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+	}
+	if err := expectJobs(q, map[string]payqueue.Job{
+		"a.csv": {State: payqueue.StateConfirmed},
+		"b.csv": {State: payqueue.StateParked, Attempts: 2},
+		"c.csv": {State: payqueue.StateConfirmed},
+	}); err != nil {
+		return err
+	}
+
+	// Two jobs paid, each signed once: the first one submitted again with the same bytes
+	signed := make(map[string]bool)
+	for _, tx := range env.Mock.Submitted() {
+		signed[tx] = true
+	}
+	if len(signed) != 2 {
+		return fmt.Errorf("%d transactions signed for 2 jobs", len(signed))
+	}
+	for i, want := range []uint64{100000, 50000, 70000} {
+		if err := expectBalance(ctx, env, fmt.Sprintf("destination %d", i+1), accounts[i].Tag, want, nil); err != nil {
+			return err
+		}
+	}
+	return expectBalance(ctx, env, "wallet", wallet.Tag, FUNDING-220000-2*QUEUE_FEE, nil)
+}
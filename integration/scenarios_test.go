@@ -0,0 +1,417 @@
+//go:build integration
+
+package integration
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+
+	"github.com/NickP005/Vindax-MCM-tools/pkg/meshclient"
+	"github.com/NickP005/Vindax-MCM-tools/pkg/secure"
+	"github.com/NickP005/Vindax-MCM-tools/pkg/txbuild"
+	"github.com/NickP005/Vindax-MCM-tools/pkg/txentry"
+)
+
+// FUNDING is the balance of the source account of the mock scenarios, in nanoMCM
+const FUNDING = 1000000
+
+// Scenario is one end-to-end run; Live scenarios only read from a real node, the others run on a fresh mock chain
+type Scenario struct {
+	Name string
+	Live bool
+	Run  func(ctx context.Context, env *Env) error
+}
+
+// Scenarios lists every scenario, in the order they run
+var Scenarios = []Scenario{
+	{Name: "transfer-confirmed", Run: transferConfirmed},
+	{Name: "change-spent-again", Run: changeSpentAgain},
+	{Name: "reorg-back-to-mempool", Run: reorgBackToMempool},
+	{Name: "signature-rejected", Run: signatureRejected},
+	{Name: "insufficient-balance", Run: insufficientBalance},
+	{Name: "derivation-agrees", Run: derivationAgrees},
+	{Name: "history-paged", Run: historyPaged},
+	{Name: "sweep-accounts", Run: sweepAccounts},
+	{Name: "sweep-journal-replay", Run: sweepJournalReplay},
+	{Name: "queue-restart", Run: queueRestart},
+	{Name: "live-preflight", Live: true, Run: livePreflight},
+	{Name: "live-tip-block", Live: true, Run: liveTipBlock},
+	{Name: "live-unknown-tag", Live: true, Run: liveUnknownTag},
+}
+
+// fundedPair returns a funded source account and an unfunded destination account
+func fundedPair(env *Env) (*Account, *Account, error) {
+	source, err := NewAccount()
+	if err != nil {
+		return nil, nil, err
+	}
+	destination, err := NewAccount()
+	if err != nil {
+		return nil, nil, err
+	}
+	env.Fund(source, FUNDING)
+	return source, destination, nil
+}
+
+// submit sends a signed transaction and returns its identifier
+func submit(ctx context.Context, env *Env, tx *txentry.Transaction) (string, error) {
+	result, err := env.Client.SubmitTransaction(ctx, hex.EncodeToString(tx.Bytes()))
+	if err != nil {
+		return "", fmt.Errorf("submit: %v", err)
+	}
+	return result.TransactionIdentifier.Hash, nil
+}
+
+// expectBalance resolves a tag and checks its balance and, when address is set, the address holding it
+func expectBalance(ctx context.Context, env *Env, name string, tag [txentry.TagLength]byte, balance uint64, address []byte) error {
+	resolution, err := env.Client.ResolveTag(ctx, tag[:])
+	if err != nil {
+		return fmt.Errorf("resolving the %s tag: %v", name, err)
+	}
+	if resolution.Amount != balance {
+		return fmt.Errorf("%s balance %d, expected %d", name, resolution.Amount, balance)
+	}
+	if address != nil && !secure.Equal(resolution.Address, address) {
+		return fmt.Errorf("%s tag at %s, expected 0x%x", name, resolution.AddressHex, address)
+	}
+	return nil
+}
+
+// confirm mines a block and checks it holds the transaction, whose operations must decode to the payment
+func confirm(ctx context.Context, env *Env, txID string, destination *Account, amount uint64, memo string) (uint64, error) {
+	height := env.Mock.MineBlock()
+	found, err := env.Client.BlockHasTransaction(ctx, height, txID)
+	if err != nil {
+		return 0, fmt.Errorf("checking block %d: %v", height, err)
+	}
+	if !found {
+		return 0, fmt.Errorf("transaction %s not in block %d", txID, height)
+	}
+	mined, err := env.Client.BlockTransaction(ctx, txID)
+	if err != nil {
+		return 0, err
+	}
+	transfer, err := meshclient.DecodeTransfer(mined.Transaction)
+	if err != nil {
+		return 0, err
+	}
+	if len(transfer.Destinations) != 1 {
+		return 0, fmt.Errorf("%d destinations decoded, expected 1", len(transfer.Destinations))
+	}
+	payment := transfer.Destinations[0]
+	if !sameTag(payment.Address, destination.Tag) || payment.Amount != amount || payment.Memo != memo {
+		return 0, fmt.Errorf("decoded payment %+v, expected %d to %x with memo %q", payment, amount, destination.Tag, memo)
+	}
+	return height, nil
+}
+
+// sameTag reports whether a hex account address starts with tag
+func sameTag(address string, tag [txentry.TagLength]byte) bool {
+	decoded, err := hex.DecodeString(trimHex(address))
+	return err == nil && len(decoded) >= txentry.TagLength && secure.Equal(decoded[:txentry.TagLength], tag[:])
+}
+
+// trimHex removes the 0x prefix of a hex string
+func trimHex(s string) string {
+	if len(s) >= 2 && s[:2] == "0x" {
+		return s[2:]
+	}
+	return s
+}
+
+/*
+ * transferConfirmed funds an account, asks the fee, pays another account,
+ * and follows the transaction from the mempool to a block, then checks
+ * both balances and that the source tag moved to its change address
+ */
+func transferConfirmed(ctx context.Context, env *Env) error {
+	source, destination, err := fundedPair(env)
+	if err != nil {
+		return err
+	}
+	if err := expectBalance(ctx, env, "source", source.Tag, FUNDING, nil); err != nil {
+		return err
+	}
+
+	debit, err := meshclient.SourceOperation(0, source.Tag[:], FUNDING)
+	if err != nil {
+		return err
+	}
+	preprocess, err := env.Client.ConstructionPreprocess(ctx, []meshclient.Operation{debit})
+	if err != nil {
+		return fmt.Errorf("preprocess: %v", err)
+	}
+	metadata, err := env.Client.ConstructionMetadata(ctx, preprocess.Options)
+	if err != nil {
+		return fmt.Errorf("metadata: %v", err)
+	}
+	fee, ok, err := metadata.Fee()
+	if err != nil || !ok {
+		return fmt.Errorf("no suggested fee (%v)", err)
+	}
+
+	const amount, memo = 250000, "INV-1"
+	tx, change, err := Transfer(source, FUNDING, fee, destination.Tag, memo, amount)
+	if err != nil {
+		return err
+	}
+	txID, err := submit(ctx, env, tx)
+	if err != nil {
+		return err
+	}
+	if _, err := env.Client.MempoolTransaction(ctx, txID); err != nil {
+		return fmt.Errorf("submitted transaction not in mempool: %v", err)
+	}
+	if _, err := confirm(ctx, env, txID, destination, amount, memo); err != nil {
+		return err
+	}
+
+	changeAddress := change.Address()
+	if err := expectBalance(ctx, env, "source", source.Tag, FUNDING-amount-fee, changeAddress[:]); err != nil {
+		return err
+	}
+	return expectBalance(ctx, env, "destination", destination.Tag, amount, nil)
+}
+
+// changeSpentAgain spends the change of a confirmed transfer with the next key of the wallet
+func changeSpentAgain(ctx context.Context, env *Env) error {
+	source, destination, err := fundedPair(env)
+	if err != nil {
+		return err
+	}
+	const fee = 500
+	balance := uint64(FUNDING)
+	for i, amount := range []uint64{100000, 200000} {
+		tx, change, err := Transfer(source, balance, fee, destination.Tag, "", amount)
+		if err != nil {
+			return fmt.Errorf("transfer %d: %v", i+1, err)
+		}
+		txID, err := submit(ctx, env, tx)
+		if err != nil {
+			return fmt.Errorf("transfer %d: %v", i+1, err)
+		}
+		if _, err := confirm(ctx, env, txID, destination, amount, ""); err != nil {
+			return fmt.Errorf("transfer %d: %v", i+1, err)
+		}
+		source, balance = change, balance-amount-fee
+	}
+	address := source.Address()
+	if err := expectBalance(ctx, env, "source", source.Tag, balance, address[:]); err != nil {
+		return err
+	}
+	return expectBalance(ctx, env, "destination", destination.Tag, 300000, nil)
+}
+
+// reorgBackToMempool confirms a transaction, reorgs its block away with the transaction back in the mempool, and confirms it again
+func reorgBackToMempool(ctx context.Context, env *Env) error {
+	source, destination, err := fundedPair(env)
+	if err != nil {
+		return err
+	}
+	tx, _, err := Transfer(source, FUNDING, 500, destination.Tag, "", 1000)
+	if err != nil {
+		return err
+	}
+	txID, err := submit(ctx, env, tx)
+	if err != nil {
+		return err
+	}
+	height, err := confirm(ctx, env, txID, destination, 1000, "")
+	if err != nil {
+		return err
+	}
+
+	env.Mock.Reorg(1, true)
+	found, err := env.Client.BlockHasTransaction(ctx, height, txID)
+	if err != nil {
+		return err
+	}
+	if found {
+		return fmt.Errorf("transaction still in block %d after the reorg", height)
+	}
+	if _, err := env.Client.MempoolTransaction(ctx, txID); err != nil {
+		return fmt.Errorf("transaction not back in the mempool: %v", err)
+	}
+	reorged, err := confirm(ctx, env, txID, destination, 1000, "")
+	if err != nil {
+		return err
+	}
+	if reorged != height+1 {
+		return fmt.Errorf("confirmed again at %d, expected %d", reorged, height+1)
+	}
+	return nil
+}
+
+// signatureRejected submits a transaction changed after signing, which the node must reject for its signature
+func signatureRejected(ctx context.Context, env *Env) error {
+	source, destination, err := fundedPair(env)
+	if err != nil {
+		return err
+	}
+	tx, _, err := Transfer(source, FUNDING, 500, destination.Tag, "", 1000)
+	if err != nil {
+		return err
+	}
+	raw := tx.Bytes()
+	// Raise the amount of the destination
+	raw[txentry.HeaderLength+txentry.TagLength+txentry.ReferenceLength]++
+	_, err = env.Client.SubmitTransaction(ctx, hex.EncodeToString(raw))
+	if !errors.Is(err, meshclient.ErrSignatureRejected) {
+		return fmt.Errorf("submit of a tampered transaction: %v, expected a signature rejection", err)
+	}
+	if len(env.Mock.Submitted()) != 0 {
+		return fmt.Errorf("tampered transaction recorded as submitted")
+	}
+	return nil
+}
+
+// insufficientBalance checks a transfer spending more than the balance is refused before anything is signed
+func insufficientBalance(ctx context.Context, env *Env) error {
+	source, destination, err := fundedPair(env)
+	if err != nil {
+		return err
+	}
+	_, _, err = Transfer(source, FUNDING, 500, destination.Tag, "", FUNDING)
+	if !errors.Is(err, txbuild.ErrInsufficientBalance) {
+		return fmt.Errorf("transfer of the whole balance plus a fee: %v, expected %v", err, txbuild.ErrInsufficientBalance)
+	}
+	return nil
+}
+
+// derivationAgrees checks the node derives the same address hash from a public key as the local keygen
+func derivationAgrees(ctx context.Context, env *Env) error {
+	account, err := NewAccount()
+	if err != nil {
+		return err
+	}
+	derived, err := env.Client.CheckDerivation(ctx, account.Keypair.PublicKey[:])
+	if err != nil {
+		return err
+	}
+	if !sameTag(derived.Address, account.Tag) {
+		return fmt.Errorf("derived %s, expected %x", derived.Address, account.Tag)
+	}
+	return nil
+}
+
+/*
+ * historyPaged pays an account in 30 transactions over 15 blocks, more than
+ * a page of /search/transactions holds, then finds them all through the
+ * search pages and again by decoding every block, as export-history does
+ */
+func historyPaged(ctx context.Context, env *Env) error {
+	source, destination, err := fundedPair(env)
+	if err != nil {
+		return err
+	}
+	const payments, fee = 30, 500
+	balance, want := uint64(FUNDING), uint64(0)
+	for i := 0; i < payments; i++ {
+		amount := uint64(1000 + i)
+		tx, change, err := Transfer(source, balance, fee, destination.Tag, fmt.Sprintf("INV-%d", i+1), amount)
+		if err != nil {
+			return fmt.Errorf("transfer %d: %v", i+1, err)
+		}
+		if _, err := submit(ctx, env, tx); err != nil {
+			return fmt.Errorf("transfer %d: %v", i+1, err)
+		}
+		// The next transfer spends the change, which needs the block
+		env.Mock.MineBlock()
+		source, balance, want = change, balance-amount-fee, want+amount
+		if i%2 == 1 {
+			env.Mock.MineBlock()
+		}
+	}
+
+	hits, truncated, err := env.Client.SearchAllTransactions(ctx, meshclient.SearchQuery{Tag: destination.Tag[:], Limit: 100}, 0)
+	if err != nil {
+		return fmt.Errorf("search: %v", err)
+	}
+	seen := make(map[string]bool)
+	for _, hit := range hits {
+		seen[hit.Transaction.TransactionIdentifier.Hash] = true
+	}
+	if truncated || len(hits) != payments || len(seen) != payments {
+		return fmt.Errorf("search listed %d transactions (%d distinct, truncated %v), expected %d", len(hits), len(seen), truncated, payments)
+	}
+
+	got, found := uint64(0), 0
+	for height := uint64(1); height <= env.Mock.Height(); height++ {
+		block, err := env.Client.Block(ctx, height)
+		if err != nil {
+			return err
+		}
+		if block.Block.Timestamp == 0 {
+			return fmt.Errorf("block %d has no timestamp", height)
+		}
+		transfers, err := env.Client.BlockTransfers(ctx, block)
+		if err != nil {
+			return err
+		}
+		for _, transfer := range transfers {
+			for _, payment := range transfer.PaidTo(destination.Tag[:]) {
+				if !seen[transfer.TxID] && !seen["0x"+transfer.TxID] {
+					return fmt.Errorf("transaction %s of block %d not found by the search", transfer.TxID, height)
+				}
+				got += payment.Amount
+				found++
+			}
+		}
+	}
+	if found != payments || got != want {
+		return fmt.Errorf("blocks hold %d payments of %d in total, expected %d of %d", found, got, payments, want)
+	}
+	return expectBalance(ctx, env, "destination", destination.Tag, want, nil)
+}
+
+// livePreflight checks the node is a Mochimo Mesh API serving mainnet
+func livePreflight(ctx context.Context, env *Env) error {
+	preflight, err := env.Client.Preflight(ctx)
+	if err != nil {
+		return err
+	}
+	for _, warning := range preflight.Warnings {
+		env.Logf("warning: %s", warning)
+	}
+	return nil
+}
+
+// liveTipBlock reads the tip block and checks it links to the block below
+func liveTipBlock(ctx context.Context, env *Env) error {
+	status, err := env.Client.NetworkStatus(ctx)
+	if err != nil {
+		return err
+	}
+	tip := status.CurrentBlockIdentifier
+	block, err := env.Client.Block(ctx, tip.Index)
+	if err != nil {
+		return err
+	}
+	if tip.Index == 0 {
+		return nil
+	}
+	parent, err := env.Client.Block(ctx, tip.Index-1)
+	if err != nil {
+		return err
+	}
+	if trimHex(block.Block.ParentBlockIdentifier.Hash) != trimHex(parent.Block.BlockIdentifier.Hash) {
+		return fmt.Errorf("block %d does not link to block %d", tip.Index, tip.Index-1)
+	}
+	return nil
+}
+
+// liveUnknownTag resolves a random tag, which no account holds
+func liveUnknownTag(ctx context.Context, env *Env) error {
+	var tag [txentry.TagLength]byte
+	if _, err := rand.Read(tag[:]); err != nil {
+		return err
+	}
+	_, err := env.Client.ResolveTag(ctx, tag[:])
+	if !errors.Is(err, meshclient.ErrTagNotFound) {
+		return fmt.Errorf("resolving a random tag: %v, expected %v", err, meshclient.ErrTagNotFound)
+	}
+	return nil
+}
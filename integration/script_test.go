@@ -0,0 +1,485 @@
+//go:build integration
+
+package integration
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/NickP005/Vindax-MCM-tools/pkg/amount"
+	"github.com/NickP005/Vindax-MCM-tools/pkg/csvfile"
+	"github.com/NickP005/Vindax-MCM-tools/pkg/meshclient"
+	"github.com/NickP005/Vindax-MCM-tools/pkg/meshmock"
+	"github.com/NickP005/Vindax-MCM-tools/pkg/txbuild"
+	"github.com/NickP005/Vindax-MCM-tools/pkg/txentry"
+)
+
+// DEFAULT_BLOCK_TIME is the time between two blocks of a script's mock chain when it sets none
+const DEFAULT_BLOCK_TIME = 200 * time.Millisecond
+
+// Triggers of a fault besides a duration since the start, named after the PayerEvent kinds
+const (
+	TriggerSubmitted = "submitted"
+	TriggerIncluded  = "included"
+)
+
+// Duration is a time.Duration written as a string in the scripts, e.g. "300ms"
+type Duration time.Duration
+
+func (d *Duration) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return fmt.Errorf("duration must be a string such as \"300ms\": %v", err)
+	}
+	parsed, err := time.ParseDuration(s)
+	if err != nil {
+		return err
+	}
+	*d = Duration(parsed)
+	return nil
+}
+
+/*
+ * Script is a scenario read from a JSON file: the accounts of a mock chain,
+ * a payout file paid from one of them, the faults injected while it is
+ * paid and the outcome expected
+ *
+ * The accounts, the payout file and the expected balances refer to the
+ * accounts by name; their keys are generated afresh on every run. The
+ * source account is a Wallet, the others plain accounts.
+ */
+type Script struct {
+	Name        string          `json:"name"`
+	Description string          `json:"description"`
+	Accounts    []ScriptAccount `json:"accounts"`
+	Source      string          `json:"source"`
+	// Payouts is the payout file, relative to the script: one "name,amount[,memo]" line per destination
+	Payouts       string        `json:"payouts"`
+	Fee           uint64        `json:"fee"`
+	BlockTime     Duration      `json:"block_time"`
+	Confirmations int           `json:"confirmations"`
+	StuckAfter    int           `json:"stuck_after"`
+	Faults        []ScriptFault `json:"faults"`
+	Expect        Expectations  `json:"expect"`
+
+	// path is the file the script was read from
+	path string
+}
+
+// ScriptAccount is an account of the mock chain; an account without balance is unknown to the chain
+type ScriptAccount struct {
+	Name    string `json:"name"`
+	Balance uint64 `json:"balance"`
+}
+
+/*
+ * ScriptFault is a fault injected in the mock chain, exactly one of
+ * Outage, Reorg, MinFee, Hold or Drift
+ *
+ * At is when: empty for before the payout starts, a duration since the
+ * start, or the payer event "submitted" or "included".
+ */
+type ScriptFault struct {
+	At string `json:"at"`
+	// Outage fails every endpoint with 503 for that long
+	Outage Duration `json:"outage"`
+	Reorg  *struct {
+		// Depth is the count of blocks replaced, 0 for down to the block holding the payout (at "included" only)
+		Depth     int  `json:"depth"`
+		ToMempool bool `json:"to_mempool"`
+	} `json:"reorg"`
+	// MinFee is the lowest fee the node accepts from now on
+	MinFee uint64 `json:"min_fee"`
+	// Hold keeps the next transaction submitted out of that many blocks, then mines or evicts it
+	Hold *struct {
+		Blocks int  `json:"blocks"`
+		Evict  bool `json:"evict"`
+	} `json:"hold"`
+	// Drift moves the source tag that many keys past the wallet index, as another copy of the wallet spending would
+	Drift uint64 `json:"drift"`
+}
+
+// Expectations are compared to the result of the payout, each one that is set on its own
+type Expectations struct {
+	Outcome string `json:"outcome"`
+	// Error is a text the error of the payout must contain
+	Error        string            `json:"error"`
+	Signed       *int              `json:"signed"`
+	Rebroadcasts *int              `json:"rebroadcasts"`
+	Reorgs       *int              `json:"reorgs"`
+	Fee          *uint64           `json:"fee"`
+	Drift        *uint64           `json:"drift"`
+	Balances     map[string]uint64 `json:"balances"`
+}
+
+// LoadScripts reads the scripts (*.json) of a directory, sorted by name; a missing directory holds none
+func LoadScripts(dir string) ([]*Script, error) {
+	paths, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(paths)
+	scripts := make([]*Script, 0, len(paths))
+	for _, path := range paths {
+		script, err := LoadScript(path)
+		if err != nil {
+			return nil, err
+		}
+		scripts = append(scripts, script)
+	}
+	return scripts, nil
+}
+
+// LoadScript reads and checks a script; unknown keys are errors, so a typo does not silently drop a fault or an expectation
+func LoadScript(path string) (*Script, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	decoder := json.NewDecoder(file)
+	decoder.DisallowUnknownFields()
+	script := &Script{path: path}
+	if err := decoder.Decode(script); err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+	if script.Name == "" {
+		script.Name = strings.TrimSuffix(filepath.Base(path), ".json")
+	}
+	if err := script.check(); err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+	return script, nil
+}
+
+// check validates what decoding cannot
+func (s *Script) check() error {
+	names := make(map[string]bool)
+	for _, account := range s.Accounts {
+		if account.Name == "" || names[account.Name] {
+			return fmt.Errorf("account names must be set and unique, got %q", account.Name)
+		}
+		names[account.Name] = true
+	}
+	if !names[s.Source] {
+		return fmt.Errorf("source %q is not an account", s.Source)
+	}
+	if s.Payouts == "" {
+		return fmt.Errorf("no payouts file")
+	}
+	if s.Confirmations < 0 || s.StuckAfter < 0 {
+		return fmt.Errorf("confirmations and stuck_after cannot be negative")
+	}
+	e := s.Expect
+	if e.Outcome == "" && e.Error == "" && e.Signed == nil && e.Rebroadcasts == nil && e.Reorgs == nil && e.Fee == nil && e.Drift == nil && len(e.Balances) == 0 {
+		return fmt.Errorf("no expectation")
+	}
+	for i, fault := range s.Faults {
+		if err := fault.check(); err != nil {
+			return fmt.Errorf("fault %d: %v", i+1, err)
+		}
+	}
+	for name := range s.Expect.Balances {
+		if !names[name] {
+			return fmt.Errorf("expected balance of %q, which is not an account", name)
+		}
+	}
+	return nil
+}
+
+// check validates the trigger of a fault and that it has exactly one kind
+func (f ScriptFault) check() error {
+	if f.At != "" && f.At != TriggerSubmitted && f.At != TriggerIncluded {
+		if _, err := time.ParseDuration(f.At); err != nil {
+			return fmt.Errorf("at must be empty, a duration, %q or %q, got %q", TriggerSubmitted, TriggerIncluded, f.At)
+		}
+	}
+	kinds := 0
+	for _, set := range []bool{f.Outage > 0, f.Reorg != nil, f.MinFee > 0, f.Hold != nil, f.Drift > 0} {
+		if set {
+			kinds++
+		}
+	}
+	if kinds != 1 {
+		return fmt.Errorf("exactly one of outage, reorg, min_fee, hold or drift must be set")
+	}
+	if f.Reorg != nil && f.Reorg.Depth <= 0 && f.At != TriggerIncluded {
+		return fmt.Errorf("a reorg needs a depth unless at %q", TriggerIncluded)
+	}
+	if f.Hold != nil && f.Hold.Blocks <= 0 {
+		return fmt.Errorf("a hold needs a count of blocks")
+	}
+	return nil
+}
+
+// delay returns the time since the start a fault fires at, and false for the faults fired by a payer event
+func (f ScriptFault) delay() (time.Duration, bool) {
+	if f.At == "" {
+		return 0, true
+	}
+	d, err := time.ParseDuration(f.At)
+	return d, err == nil
+}
+
+// readPayouts reads the payout file of the script, with the tags of the accounts it names
+func (s *Script) readPayouts(tags map[string][txentry.TagLength]byte) ([]txentry.Destination, error) {
+	path := s.Payouts
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(filepath.Dir(s.path), path)
+	}
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	reader, err := csvfile.NewReader(file)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+
+	var destinations []txentry.Destination
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", path, err)
+		}
+		line, _ := reader.FieldPos(0)
+		if len(record) == 0 || strings.HasPrefix(strings.TrimSpace(record[0]), "#") {
+			continue
+		}
+		if len(record) < 2 || len(record) > 3 {
+			return nil, fmt.Errorf("%s:%d: expected name, amount and an optional memo", path, line)
+		}
+		name := strings.TrimSpace(record[0])
+		tag, ok := tags[name]
+		if !ok || name == s.Source {
+			return nil, fmt.Errorf("%s:%d: %q is not an account to pay", path, line, name)
+		}
+		value, err := amount.Parse(record[1], amount.NanoMCM)
+		if err != nil {
+			return nil, fmt.Errorf("%s:%d: %v", path, line, err)
+		}
+		memo := ""
+		if len(record) == 3 {
+			memo = strings.TrimSpace(record[2])
+		}
+		destination, err := txbuild.NewDestination(tag, memo, value)
+		if err != nil {
+			return nil, fmt.Errorf("%s:%d: %v", path, line, err)
+		}
+		destinations = append(destinations, destination)
+	}
+	if len(destinations) == 0 {
+		return nil, fmt.Errorf("%s: no payout", path)
+	}
+	return destinations, nil
+}
+
+// Scenario returns the script as a mock scenario, named after it under "script/"
+func (s *Script) Scenario() Scenario {
+	return Scenario{Name: "script/" + s.Name, Run: s.Run}
+}
+
+/*
+ * Run pays the payout file of the script on the mock chain of env while
+ * mining a block every BlockTime, injects the faults when they are due,
+ * then logs and checks every expectation
+ *
+ * Mining and the faults run on one goroutine, so a reorg at "included"
+ * always replaces the blocks down to the one the payer saw the payout in.
+ */
+func (s *Script) Run(ctx context.Context, env *Env) error {
+	env.Client.SetRetryPolicy(meshclient.DefaultRetryPolicy())
+	wallet, err := NewWallet()
+	if err != nil {
+		return err
+	}
+	tags := make(map[string][txentry.TagLength]byte)
+	for _, scripted := range s.Accounts {
+		if scripted.Name == s.Source {
+			tags[scripted.Name] = wallet.Tag
+			if scripted.Balance > 0 {
+				address := wallet.Address(0)
+				env.Mock.SetAccount(wallet.Tag[:], fmt.Sprintf("0x%x", address), scripted.Balance)
+			}
+			continue
+		}
+		account, err := NewAccount()
+		if err != nil {
+			return err
+		}
+		tags[scripted.Name] = account.Tag
+		if scripted.Balance > 0 {
+			env.Fund(account, scripted.Balance)
+		}
+	}
+	destinations, err := s.readPayouts(tags)
+	if err != nil {
+		return err
+	}
+
+	blockTime := time.Duration(s.BlockTime)
+	if blockTime <= 0 {
+		blockTime = DEFAULT_BLOCK_TIME
+	}
+	events := make(chan PayerEvent)
+	payer := &Payer{
+		Client:        env.Client,
+		Wallet:        wallet,
+		Destinations:  destinations,
+		Fee:           s.Fee,
+		Confirmations: max(s.Confirmations, 1),
+		StuckAfter:    s.StuckAfter,
+		PollInterval:  blockTime / 4,
+		OnEvent: func(event PayerEvent) {
+			select {
+			case events <- event:
+			case <-ctx.Done():
+			}
+		},
+	}
+
+	// The faults due at the start are in place before the payer runs, the others wait for their time or event
+	timed := make(chan ScriptFault, len(s.Faults))
+	for _, fault := range s.Faults {
+		d, ok := fault.delay()
+		switch {
+		case ok && d == 0:
+			s.inject(env, wallet, fault, 0)
+		case ok:
+			fault := fault
+			timer := time.AfterFunc(d, func() { timed <- fault })
+			defer timer.Stop()
+		}
+	}
+
+	done := make(chan PayerResult, 1)
+	go func() { done <- payer.Run(ctx) }()
+	blocks := time.NewTicker(blockTime)
+	defer blocks.Stop()
+	fired := make(map[string]bool)
+	var result PayerResult
+	for running := true; running; {
+		select {
+		case <-blocks.C:
+			env.Mock.MineBlock()
+		case fault := <-timed:
+			s.inject(env, wallet, fault, 0)
+		case event := <-events:
+			if fired[event.Kind] {
+				continue
+			}
+			fired[event.Kind] = true
+			for _, fault := range s.Faults {
+				if fault.At == event.Kind {
+					s.inject(env, wallet, fault, event.Height)
+				}
+			}
+		case result = <-done:
+			running = false
+		}
+	}
+	return s.verify(ctx, env, tags, result)
+}
+
+// inject applies a fault to the mock chain; included is the block holding the payout, for a reorg at "included"
+func (s *Script) inject(env *Env, wallet *Wallet, fault ScriptFault, included uint64) {
+	switch {
+	case fault.Outage > 0:
+		env.Mock.Outage(meshmock.Fault{Status: 503, Body: "service unavailable"}, time.Duration(fault.Outage))
+	case fault.Reorg != nil:
+		depth := fault.Reorg.Depth
+		if depth <= 0 {
+			depth = int(env.Mock.Height() - included + 1)
+		}
+		env.Mock.Reorg(depth, fault.Reorg.ToMempool)
+	case fault.MinFee > 0:
+		env.Mock.SetMinimumFee(fault.MinFee)
+	case fault.Hold != nil:
+		env.Mock.HoldNext(fault.Hold.Blocks, fault.Hold.Evict)
+	case fault.Drift > 0:
+		balance, _ := env.Mock.Balance(wallet.Tag[:])
+		address := wallet.Address(wallet.Index + fault.Drift)
+		env.Mock.SetAccount(wallet.Tag[:], fmt.Sprintf("0x%x", address), balance)
+	}
+}
+
+// verify logs every expectation with whether the result meets it, and fails if any does not
+func (s *Script) verify(ctx context.Context, env *Env, tags map[string][txentry.TagLength]byte, result PayerResult) error {
+	failed, total := 0, 0
+	expect := func(what string, got interface{}, want interface{}) {
+		total++
+		if fmt.Sprint(got) == fmt.Sprint(want) {
+			env.Logf("ok   %s: %v", what, got)
+			return
+		}
+		env.Logf("FAIL %s: %v, expected %v", what, got, want)
+		failed++
+	}
+
+	e := s.Expect
+	if e.Outcome != "" {
+		expect("outcome", result.Outcome, e.Outcome)
+	}
+	if e.Error != "" {
+		got := ""
+		if result.Err != nil {
+			got = result.Err.Error()
+		}
+		total++
+		if strings.Contains(got, e.Error) {
+			env.Logf("ok   error: %s", got)
+		} else {
+			env.Logf("FAIL error: %q, expected it to contain %q", got, e.Error)
+			failed++
+		}
+	}
+	if e.Signed != nil {
+		expect("signed", result.Signed, *e.Signed)
+	}
+	if e.Rebroadcasts != nil {
+		expect("rebroadcasts", result.Rebroadcasts, *e.Rebroadcasts)
+	}
+	if e.Reorgs != nil {
+		expect("reorgs", result.Reorgs, *e.Reorgs)
+	}
+	if e.Fee != nil {
+		expect("fee", result.Fee, *e.Fee)
+	}
+	if e.Drift != nil {
+		expect("drift", result.Drift, *e.Drift)
+	}
+	names := make([]string, 0, len(e.Balances))
+	for name := range e.Balances {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		tag := tags[name]
+		// A tag the chain does not know holds nothing
+		resolution, err := env.Client.ResolveTag(ctx, tag[:])
+		if err != nil && !errors.Is(err, meshclient.ErrTagNotFound) {
+			return fmt.Errorf("resolving the %s tag: %v", name, err)
+		}
+		expect("balance of "+name, resolution.Amount, e.Balances[name])
+	}
+
+	if failed > 0 {
+		if result.Err != nil {
+			return fmt.Errorf("%d of %d expectations not met (payout %s: %v)", failed, total, result.Outcome, result.Err)
+		}
+		return fmt.Errorf("%d of %d expectations not met", failed, total)
+	}
+	return nil
+}
@@ -0,0 +1,246 @@
+//go:build integration
+
+package integration
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/NickP005/Vindax-MCM-tools/pkg/meshmock"
+	"github.com/NickP005/Vindax-MCM-tools/pkg/secure"
+	"github.com/NickP005/Vindax-MCM-tools/pkg/sweep"
+	"github.com/NickP005/Vindax-MCM-tools/pkg/txentry"
+	"github.com/NickP005/Vindax-MCM-tools/pkg/wotsp"
+)
+
+// SWEEP_FEE is the fee of every sweep transaction of the sweep scenarios, in nanoMCM
+const SWEEP_FEE = 500
+
+// sweepSeed is a generated account to sweep: its seed and its tag, the address hash of its first key
+type sweepSeed struct {
+	seed [secure.KeyLength]byte
+	tag  [txentry.TagLength]byte
+}
+
+// newSweepSeeds generates n accounts as tool-2 does, from random seeds
+func newSweepSeeds(n int) ([]sweepSeed, error) {
+	seeds := make([]sweepSeed, n)
+	for i := range seeds {
+		if _, err := rand.Read(seeds[i].seed[:]); err != nil {
+			return nil, fmt.Errorf("failed to generate random seed: %v", err)
+		}
+		keypair := wotsp.Keygen(seeds[i].seed)
+		keypair.Wipe()
+		seeds[i].tag = keypair.AddrHash()
+	}
+	return seeds, nil
+}
+
+// fund sets the balance of a generated account at its implicit address, as a first payment to its tag does
+func (s sweepSeed) fund(env *Env, balance uint64) {
+	env.Mock.SetAccount(s.tag[:], "0x"+hex.EncodeToString(s.tag[:])+hex.EncodeToString(s.tag[:]), balance)
+}
+
+// tool2CSV renders accounts as the CSV output of tool-2, the seed in the wotsSecretKey column
+func tool2CSV(seeds []sweepSeed) string {
+	var b strings.Builder
+	b.WriteString("mcmAccountNumber,wotsPublicKey,wotsSecretKey\n")
+	for i, s := range seeds {
+		fmt.Fprintf(&b, "%020x,00,%x\n", i, s.seed[:])
+	}
+	return b.String()
+}
+
+// runSweep runs a sweep while blocks are mined, and returns its accounts once it ends
+func runSweep(ctx context.Context, env *Env, s *sweep.Sweeper, keys string) ([]*sweep.Account, error) {
+	accounts, err := sweep.ReadSeeds(strings.NewReader(keys))
+	if err != nil {
+		return nil, fmt.Errorf("reading the seeds: %v", err)
+	}
+	s.Client = env.Client
+	s.PollInterval = DEFAULT_BLOCK_TIME / 4
+
+	done := make(chan error, 1)
+	go func() { done <- s.Run(ctx, accounts) }()
+	blocks := time.NewTicker(DEFAULT_BLOCK_TIME)
+	defer blocks.Stop()
+	for {
+		select {
+		case <-blocks.C:
+			env.Mock.MineBlock()
+		case err := <-done:
+			sweep.Wipe(accounts)
+			return accounts, err
+		}
+	}
+}
+
+// expectStatuses checks the status of every account of a sweep, in input order
+func expectStatuses(accounts []*sweep.Account, statuses ...string) error {
+	if len(accounts) != len(statuses) {
+		return fmt.Errorf("%d accounts swept, expected %d", len(accounts), len(statuses))
+	}
+	for i, account := range accounts {
+		if account.Status != statuses[i] {
+			return fmt.Errorf("account on line %d %s (%s), expected %s", account.Line, account.Status, account.Reason, statuses[i])
+		}
+	}
+	return nil
+}
+
+/*
+ * sweepAccounts sweeps generated accounts into one destination: a dry run
+ * first, then the sweep itself, then again once a swept account is funded
+ * anew, its tag now held by its second key
+ */
+func sweepAccounts(ctx context.Context, env *Env) error {
+	seeds, err := newSweepSeeds(6)
+	if err != nil {
+		return err
+	}
+	destination, err := NewAccount()
+	if err != nil {
+		return err
+	}
+	// Four funded, one holding dust, one never funded, and the first listed twice
+	balances := []uint64{100000, 200000, 300000, 400000, 800, 0}
+	want := uint64(0)
+	for i, balance := range balances {
+		if balance > 0 {
+			seeds[i].fund(env, balance)
+		}
+		if balance > 1000 {
+			want += balance - SWEEP_FEE
+		}
+	}
+	keys := tool2CSV(append(seeds, seeds[0]))
+	dir, err := os.MkdirTemp("", "sweep")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(dir)
+	journal, err := sweep.OpenJournal(filepath.Join(dir, "journal.json"))
+	if err != nil {
+		return err
+	}
+
+	dryRun := &sweep.Sweeper{Destination: destination.Tag, Fee: SWEEP_FEE, Dust: 1000, DryRun: true, Journal: journal}
+	accounts, err := runSweep(ctx, env, dryRun, keys)
+	if err != nil {
+		return fmt.Errorf("dry run: %v", err)
+	}
+	would, skipped := sweep.StatusWouldSweep, sweep.StatusSkipped
+	if err := expectStatuses(accounts, would, would, would, would, skipped, skipped, skipped); err != nil {
+		return fmt.Errorf("dry run: %v", err)
+	}
+	if summary := sweep.Summarize(accounts); summary.Amount != want || len(env.Mock.Submitted()) != 0 {
+		return fmt.Errorf("dry run would send %d with %d transactions submitted, expected %d and none", summary.Amount, len(env.Mock.Submitted()), want)
+	}
+
+	s := &sweep.Sweeper{Destination: destination.Tag, Fee: SWEEP_FEE, Dust: 1000, BlockToLive: 20,
+		SubmitConcurrency: 2, Confirmations: 2, Journal: journal}
+	if accounts, err = runSweep(ctx, env, s, keys); err != nil {
+		return fmt.Errorf("sweep: %v", err)
+	}
+	swept := sweep.StatusSwept
+	if err := expectStatuses(accounts, swept, swept, swept, swept, skipped, skipped, skipped); err != nil {
+		return fmt.Errorf("sweep: %v", err)
+	}
+	txIDs := make(map[string]bool)
+	for _, account := range accounts[:4] {
+		if _, signed := journal.Get(account.Tag + account.Tag); !signed {
+			return fmt.Errorf("sweep of line %d not in the journal", account.Line)
+		}
+		if account.BlockToLive == 0 || account.Block == 0 || account.Block > account.BlockToLive {
+			return fmt.Errorf("sweep of line %d mined in block %d, block to live %d", account.Line, account.Block, account.BlockToLive)
+		}
+		txIDs[account.TxID] = true
+	}
+	if len(txIDs) != 4 {
+		return fmt.Errorf("%d distinct transactions for 4 sweeps", len(txIDs))
+	}
+	if err := expectBalance(ctx, env, "destination", destination.Tag, want, nil); err != nil {
+		return err
+	}
+
+	// Funded again, the first account is swept with the key its first sweep moved the tag to
+	resolution, err := env.Client.ResolveTag(ctx, seeds[0].tag[:])
+	if err != nil {
+		return err
+	}
+	if resolution.Amount != 0 || resolution.AddrHash() == seeds[0].tag {
+		return fmt.Errorf("first account at %s with %d after its sweep, expected a new key and nothing", resolution.AddressHex, resolution.Amount)
+	}
+	env.Mock.SetAccount(seeds[0].tag[:], resolution.AddressHex, 50000)
+	if accounts, err = runSweep(ctx, env, s, tool2CSV(seeds[:2])); err != nil {
+		return fmt.Errorf("second sweep: %v", err)
+	}
+	if err := expectStatuses(accounts, swept, skipped); err != nil {
+		return fmt.Errorf("second sweep: %v", err)
+	}
+	return expectBalance(ctx, env, "destination", destination.Tag, want+50000-SWEEP_FEE, nil)
+}
+
+/*
+ * sweepJournalReplay fails the submit of a sweep, then sweeps again: the
+ * second run must broadcast the transaction the first one signed rather
+ * than sign another with the same key
+ */
+func sweepJournalReplay(ctx context.Context, env *Env) error {
+	seeds, err := newSweepSeeds(1)
+	if err != nil {
+		return err
+	}
+	destination, err := NewAccount()
+	if err != nil {
+		return err
+	}
+	seeds[0].fund(env, FUNDING)
+	dir, err := os.MkdirTemp("", "sweep")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(dir)
+	journal, err := sweep.OpenJournal(filepath.Join(dir, "journal.json"))
+	if err != nil {
+		return err
+	}
+
+	env.Mock.Fail("/construction/submit", meshmock.Fault{Status: 500, Body: "node restarting"})
+	s := &sweep.Sweeper{Destination: destination.Tag, Fee: SWEEP_FEE, Confirmations: 1, Journal: journal}
+	keys := tool2CSV(seeds)
+	accounts, err := runSweep(ctx, env, s, keys)
+	if err != nil {
+		return fmt.Errorf("first sweep: %v", err)
+	}
+	if err := expectStatuses(accounts, sweep.StatusFailed); err != nil {
+		return fmt.Errorf("first sweep: %v", err)
+	}
+	source := hex.EncodeToString(seeds[0].tag[:]) + hex.EncodeToString(seeds[0].tag[:])
+	first, signed := journal.Get(source)
+	if !signed {
+		return fmt.Errorf("the failed sweep is not in the journal")
+	}
+
+	// Read back from the file, as a new run would
+	if s.Journal, err = sweep.OpenJournal(journal.Path()); err != nil {
+		return err
+	}
+	if accounts, err = runSweep(ctx, env, s, keys); err != nil {
+		return fmt.Errorf("second sweep: %v", err)
+	}
+	if err := expectStatuses(accounts, sweep.StatusSwept); err != nil {
+		return fmt.Errorf("second sweep: %v", err)
+	}
+	second, _ := s.Journal.Get(source)
+	if second.SignedTx != first.SignedTx || len(env.Mock.Submitted()) != 1 {
+		return fmt.Errorf("the second sweep signed again (%d transactions submitted)", len(env.Mock.Submitted()))
+	}
+	return expectBalance(ctx, env, "destination", destination.Tag, FUNDING-SWEEP_FEE, nil)
+}
@@ -0,0 +1,163 @@
+/*
+ * Package clitest holds what the tests of the tools share to drive their
+ * binary: the environment of a run, what it printed and its exit code, a
+ * buffer read while the binary writes to it, and the golden files pinning
+ * the output.
+ *
+ * It is internal to the repository: the tools require it for their tests
+ * only, through a replace directive as they do pkg.
+ */
+package clitest
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+var update = flag.Bool("update", false, "rewrite the golden files of testdata/golden")
+
+// SyncBuffer is a buffer the binary writes to while the test reads it
+type SyncBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *SyncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *SyncBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
+
+// Env is the environment of a run: no MCM_* variable of the caller and a HOME of its own, so no config file leaks in
+func Env(t testing.TB) []string {
+	t.Helper()
+	home := t.TempDir()
+	env := []string{"HOME=" + home, "XDG_CONFIG_HOME=" + home}
+	for _, v := range os.Environ() {
+		if !strings.HasPrefix(v, "MCM_") && !strings.HasPrefix(v, "HOME=") && !strings.HasPrefix(v, "XDG_CONFIG_HOME=") {
+			env = append(env, v)
+		}
+	}
+	return env
+}
+
+// Result is what a run of the binary printed and its exit code
+type Result struct {
+	Stdout string
+	Stderr string
+	Code   int
+}
+
+// Golden is the form of r in a golden file: its exit code, stdout and stderr
+func (r Result) Golden() string {
+	return fmt.Sprintf("exit %d\n-- stdout --\n%s-- stderr --\n%s", r.Code, r.Stdout, r.Stderr)
+}
+
+// Run runs binary with args and stdin, in Env with the variables of env added
+func Run(t testing.TB, binary string, stdin string, env []string, args ...string) Result {
+	t.Helper()
+	cmd := exec.Command(binary, args...)
+	cmd.Env = append(Env(t), env...)
+	cmd.Stdin = strings.NewReader(stdin)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout, cmd.Stderr = &stdout, &stderr
+
+	var exitErr *exec.ExitError
+	err := cmd.Run()
+	r := Result{Stdout: stdout.String(), Stderr: stderr.String()}
+	if errors.As(err, &exitErr) {
+		r.Code = exitErr.ExitCode()
+	} else if err != nil {
+		t.Fatalf("running %s: %v", filepath.Base(binary), err)
+	}
+	return r
+}
+
+/*
+ * InterruptWhen runs binary with args and stdin, sends it SIGINT once ready
+ * reports its output far enough, then waits for it to exit
+ *
+ * The tools install their handlers before the first output, so the signal
+ * always reaches them rather than killing the process.
+ */
+func InterruptWhen(t testing.TB, binary string, stdin io.Reader, ready func(stdout string) bool, args ...string) Result {
+	t.Helper()
+	cmd := exec.Command(binary, args...)
+	cmd.Env = Env(t)
+	cmd.Stdin = stdin
+	var stdout, stderr SyncBuffer
+	cmd.Stdout, cmd.Stderr = &stdout, &stderr
+	if err := cmd.Start(); err != nil {
+		t.Fatal(err)
+	}
+	exited := make(chan error, 1)
+	go func() { exited <- cmd.Wait() }()
+
+	deadline := time.After(30 * time.Second)
+	for signalled := false; ; {
+		select {
+		case err := <-exited:
+			if !signalled {
+				t.Fatalf("exited before the signal: %v\n%s", err, stderr.String())
+			}
+			r := Result{Stdout: stdout.String(), Stderr: stderr.String()}
+			var exitErr *exec.ExitError
+			if !errors.As(err, &exitErr) {
+				t.Fatalf("exited with %v, want an exit code", err)
+			}
+			r.Code = exitErr.ExitCode()
+			return r
+		case <-deadline:
+			cmd.Process.Kill()
+			t.Fatalf("no exit, signalled %v; stdout so far:\n%.200s", signalled, stdout.String())
+		case <-time.After(5 * time.Millisecond):
+			if !signalled && ready(stdout.String()) {
+				cmd.Process.Signal(os.Interrupt)
+				signalled = true
+			}
+		}
+	}
+}
+
+/*
+ * CheckGolden compares got with testdata/golden/<name>.golden, rewriting
+ * the file first with -update
+ *
+ * The golden files pin what a tool prints: a change to them is a change of
+ * behavior, to be made on purpose.
+ */
+func CheckGolden(t testing.TB, name string, got string) {
+	t.Helper()
+	path := filepath.Join("testdata", "golden", name+".golden")
+	if *update {
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(got), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	want, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("%v, run go test -update to create it", err)
+	}
+	if got != string(want) {
+		t.Errorf("output differs from %s, run go test -update if the change is intended:\n%s", path, got)
+	}
+}
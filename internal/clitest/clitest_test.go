@@ -0,0 +1,50 @@
+package clitest
+
+import (
+	"strings"
+	"testing"
+)
+
+// TestEnv leaves out the MCM_* variables and the HOME of the caller
+func TestEnv(t *testing.T) {
+	t.Setenv("MCM_API", "http://localhost:1")
+	t.Setenv("HOME", "/home/caller")
+	env := Env(t)
+	homes := 0
+	for _, v := range env {
+		if strings.HasPrefix(v, "MCM_") || v == "HOME=/home/caller" {
+			t.Errorf("%s kept", v)
+		}
+		if strings.HasPrefix(v, "HOME=") {
+			homes++
+		}
+	}
+	if homes != 1 {
+		t.Errorf("%d HOME variables in %q", homes, env)
+	}
+}
+
+// TestCheckGolden pins the form of a result in a golden file
+func TestCheckGolden(t *testing.T) {
+	CheckGolden(t, "result", Result{Stdout: "out\n", Stderr: "Error: err\n", Code: 3}.Golden())
+}
+
+// TestSyncBuffer reads what two writers wrote at once
+func TestSyncBuffer(t *testing.T) {
+	var b SyncBuffer
+	done := make(chan struct{})
+	go func() {
+		for i := 0; i < 100; i++ {
+			b.Write([]byte("a"))
+		}
+		close(done)
+	}()
+	for i := 0; i < 100; i++ {
+		b.Write([]byte("b"))
+		_ = b.String()
+	}
+	<-done
+	if s := b.String(); len(s) != 200 || strings.Count(s, "a") != 100 {
+		t.Errorf("%q", s)
+	}
+}
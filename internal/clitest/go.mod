@@ -0,0 +1,3 @@
+module github.com/NickP005/Vindax-MCM-tools/internal/clitest
+
+go 1.22.5
@@ -1,3 +0,0 @@
-module test
-
-go 1.23.5
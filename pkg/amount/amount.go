@@ -0,0 +1,195 @@
+/*
+ * Package amount parses and formats MCM amounts.
+ *
+ * Amounts are handled internally as nanoMCM (1 MCM = 1,000,000,000 nanoMCM).
+ * Inputs may carry a unit suffix ("2.5mcm", "500nmcm", "2.5 MCM"); bare
+ * numbers are interpreted in a caller supplied default unit. The integer
+ * part may be split in groups of three digits by underscores or commas
+ * ("2_500_000_000", "2,500,000,000"). All arithmetic is exact integer math,
+ * no floating point is involved.
+ *
+ * Every tool reading an amount, from a flag, a CSV file or a script, goes
+ * through Parse, and every tool printing one through Format, so that the
+ * same text always means the same amount.
+ */
+package amount
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// Decimals is the number of fractional digits of one MCM
+const Decimals = 9
+
+// NanoPerMCM is the number of nanoMCM in one MCM
+const NanoPerMCM uint64 = 1_000_000_000
+
+// Unit is the unit a bare amount is expressed in
+type Unit int
+
+const (
+	NanoMCM Unit = iota
+	MCM
+)
+
+// ParseUnit parses a unit name as used by the -unit flags ("nmcm" or "mcm")
+func ParseUnit(s string) (Unit, error) {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "nmcm", "nanomcm", "":
+		return NanoMCM, nil
+	case "mcm":
+		return MCM, nil
+	}
+	return NanoMCM, fmt.Errorf("unknown unit %q (expected nmcm or mcm)", s)
+}
+
+func (u Unit) String() string {
+	if u == MCM {
+		return "mcm"
+	}
+	return "nmcm"
+}
+
+/*
+ * Parse converts an amount string into nanoMCM
+ *
+ * Parameters:
+ * - s: amount, optionally suffixed with "mcm", "nmcm" or "nanomcm" (case
+ *      insensitive, spaces allowed before the suffix)
+ * - def: unit used when s carries no suffix
+ *
+ * Returns:
+ * - uint64: the amount in nanoMCM
+ * - error: on malformed input, misplaced group separators, more than 9
+ *          fractional digits, fractional nanoMCM or overflow
+ *
+ * Separators must split the integer part in groups of three digits, and
+ * one amount uses only one kind: "2,5" is rejected rather than read as 25
+ * or as a decimal comma.
+ */
+func Parse(s string, def Unit) (uint64, error) {
+	str := strings.ToLower(strings.TrimSpace(s))
+	unit := def
+	switch {
+	case strings.HasSuffix(str, "nanomcm"):
+		unit = NanoMCM
+		str = strings.TrimSpace(strings.TrimSuffix(str, "nanomcm"))
+	case strings.HasSuffix(str, "nmcm"):
+		unit = NanoMCM
+		str = strings.TrimSpace(strings.TrimSuffix(str, "nmcm"))
+	case strings.HasSuffix(str, "mcm"):
+		unit = MCM
+		str = strings.TrimSpace(strings.TrimSuffix(str, "mcm"))
+	}
+	if str == "" {
+		return 0, fmt.Errorf("invalid amount %q: missing value", s)
+	}
+
+	whole, frac, hasDot := strings.Cut(str, ".")
+	if hasDot && frac == "" {
+		return 0, fmt.Errorf("invalid amount %q: missing fractional digits", s)
+	}
+	if whole == "" {
+		whole = "0"
+	}
+	if strings.ContainsAny(frac, "_,") {
+		return 0, fmt.Errorf("invalid amount %q: separators are only allowed in the integer part", s)
+	}
+	whole, err := ungroup(whole)
+	if err != nil {
+		return 0, fmt.Errorf("invalid amount %q: %v", s, err)
+	}
+	if !isDigits(whole) || !isDigits(frac) {
+		return 0, fmt.Errorf("invalid amount %q: only digits and one decimal point are allowed", s)
+	}
+
+	if unit == NanoMCM {
+		if strings.Trim(frac, "0") != "" {
+			return 0, fmt.Errorf("invalid amount %q: nanoMCM cannot be fractional", s)
+		}
+		v, err := strconv.ParseUint(whole, 10, 64)
+		if err != nil {
+			return 0, fmt.Errorf("invalid amount %q: out of range", s)
+		}
+		return v, nil
+	}
+
+	if len(frac) > Decimals {
+		return 0, fmt.Errorf("invalid amount %q: at most %d fractional digits are allowed", s, Decimals)
+	}
+	w, err := strconv.ParseUint(whole, 10, 64)
+	if err != nil || w > math.MaxUint64/NanoPerMCM {
+		return 0, fmt.Errorf("invalid amount %q: out of range", s)
+	}
+	var f uint64
+	if frac != "" {
+		f, _ = strconv.ParseUint(frac+strings.Repeat("0", Decimals-len(frac)), 10, 64)
+	}
+	v := w * NanoPerMCM
+	if v > math.MaxUint64-f {
+		return 0, fmt.Errorf("invalid amount %q: out of range", s)
+	}
+	return v + f, nil
+}
+
+/*
+ * ungroup removes the group separators of the integer part of an amount
+ *
+ * Without separators digits is returned as is, for the caller to check.
+ */
+func ungroup(digits string) (string, error) {
+	sep := ""
+	switch {
+	case strings.Contains(digits, "_") && strings.Contains(digits, ","):
+		return "", fmt.Errorf("mixed group separators, use either _ or ,")
+	case strings.Contains(digits, "_"):
+		sep = "_"
+	case strings.Contains(digits, ","):
+		sep = ","
+	default:
+		return digits, nil
+	}
+	groups := strings.Split(digits, sep)
+	for i, group := range groups {
+		if !isDigits(group) || group == "" || len(group) > 3 || (i > 0 && len(group) != 3) {
+			return "", fmt.Errorf("%q must separate groups of three digits", sep)
+		}
+	}
+	return strings.Join(groups, ""), nil
+}
+
+// Format renders a nanoMCM amount in unit with its suffix, e.g. "2500000000 nMCM" or "2.5 MCM"; Parse reads it back
+func Format(nano uint64, unit Unit) string {
+	if unit == MCM {
+		return FormatMCM(nano) + " MCM"
+	}
+	return strconv.FormatUint(nano, 10) + " nMCM"
+}
+
+// FormatMCM renders a nanoMCM amount in MCM without trailing fractional zeroes nor unit, e.g. "2.5"
+func FormatMCM(nano uint64) string {
+	whole := nano / NanoPerMCM
+	frac := nano % NanoPerMCM
+	if frac == 0 {
+		return strconv.FormatUint(whole, 10)
+	}
+	fs := strings.TrimRight(fmt.Sprintf("%09d", frac), "0")
+	return strconv.FormatUint(whole, 10) + "." + fs
+}
+
+// Describe renders both representations of an amount, e.g. "2500000000 nMCM (2.5 MCM)"
+func Describe(nano uint64) string {
+	return fmt.Sprintf("%s (%s)", Format(nano, NanoMCM), Format(nano, MCM))
+}
+
+func isDigits(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if s[i] < '0' || s[i] > '9' {
+			return false
+		}
+	}
+	return true
+}
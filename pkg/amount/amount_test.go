@@ -0,0 +1,181 @@
+package amount
+
+import (
+	"math"
+	"strings"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	for _, tc := range []struct {
+		in   string
+		def  Unit
+		want uint64
+	}{
+		{"0", NanoMCM, 0},
+		{"2500000000", NanoMCM, 2_500_000_000},
+		{"2_500_000_000", NanoMCM, 2_500_000_000},
+		{"2,500,000,000", NanoMCM, 2_500_000_000},
+		{"2.5 MCM", NanoMCM, 2_500_000_000},
+		{"2.5mcm", NanoMCM, 2_500_000_000},
+		{"2.5", MCM, 2_500_000_000},
+		{"2,500.5", MCM, 2_500_500_000_000},
+		{" 42 ", NanoMCM, 42},
+		{"42", MCM, 42_000_000_000},
+		{"42nmcm", MCM, 42},
+		{"42 nMCM", MCM, 42},
+		{"42 NanoMCM", MCM, 42},
+		{"1.000 nmcm", NanoMCM, 1},
+		{".5mcm", NanoMCM, 500_000_000},
+		{"0.000000001mcm", NanoMCM, 1},
+		{"1.100000000mcm", NanoMCM, 1_100_000_000},
+		{"007", NanoMCM, 7},
+		{"999", NanoMCM, 999},
+		// The largest amounts, in both units
+		{"18446744073709551615", NanoMCM, math.MaxUint64},
+		{"18,446,744,073,709,551,615", NanoMCM, math.MaxUint64},
+		{"18446744073.709551615", MCM, math.MaxUint64},
+		{"18446744073mcm", NanoMCM, 18446744073 * NanoPerMCM},
+	} {
+		got, err := Parse(tc.in, tc.def)
+		if err != nil || got != tc.want {
+			t.Errorf("Parse(%q, %v) = %d, %v; want %d", tc.in, tc.def, got, err, tc.want)
+		}
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	for _, tc := range []struct {
+		in   string
+		def  Unit
+		want string
+	}{
+		{"", NanoMCM, "missing value"},
+		{"   ", NanoMCM, "missing value"},
+		{"mcm", NanoMCM, "missing value"},
+		{"1.", MCM, "missing fractional digits"},
+		{"1.mcm", NanoMCM, "missing fractional digits"},
+		{"1.5", NanoMCM, "nanoMCM cannot be fractional"},
+		{"1.5nmcm", MCM, "nanoMCM cannot be fractional"},
+		{"0.0000000001mcm", NanoMCM, "at most 9 fractional digits"},
+		{"1.0000000000", MCM, "at most 9 fractional digits"},
+		{"-1", NanoMCM, "only digits"},
+		{"+1", NanoMCM, "only digits"},
+		{"1e9", NanoMCM, "only digits"},
+		{"0x10", NanoMCM, "only digits"},
+		{"1 000", NanoMCM, "only digits"},
+		{"1.2.3", MCM, "only digits"},
+		{"ten", NanoMCM, "only digits"},
+		{"2.5 BTC", NanoMCM, "only digits"},
+		// Group separators
+		{"2,5", MCM, `"," must separate groups of three digits`},
+		{"2_5", NanoMCM, `"_" must separate groups of three digits`},
+		{",500", NanoMCM, "groups of three digits"},
+		{"1,000,", NanoMCM, "groups of three digits"},
+		{"1__000", NanoMCM, "groups of three digits"},
+		{"1234,567", NanoMCM, "groups of three digits"},
+		{"1,0000", NanoMCM, "groups of three digits"},
+		{"1,00a", NanoMCM, "groups of three digits"},
+		{"1_000,000", NanoMCM, "mixed group separators"},
+		{"1.000_5", MCM, "separators are only allowed in the integer part"},
+		{"1.000,5", MCM, "separators are only allowed in the integer part"},
+		// Overflow, in both units
+		{"18446744073709551616", NanoMCM, "out of range"},
+		{"18,446,744,073,709,551,616", NanoMCM, "out of range"},
+		{"99999999999999999999999", NanoMCM, "out of range"},
+		{"18446744073.709551616", MCM, "out of range"},
+		{"18446744074", MCM, "out of range"},
+		{"99999999999999999999mcm", NanoMCM, "out of range"},
+	} {
+		got, err := Parse(tc.in, tc.def)
+		if err == nil || !strings.Contains(err.Error(), tc.want) {
+			t.Errorf("Parse(%q, %v) = %d, %v; want an error containing %q", tc.in, tc.def, got, err, tc.want)
+			continue
+		}
+		// The error quotes the input as given
+		if !strings.Contains(err.Error(), "invalid amount "+`"`+tc.in+`"`) {
+			t.Errorf("Parse(%q): error %q does not quote the input", tc.in, err)
+		}
+	}
+}
+
+func TestParseUnit(t *testing.T) {
+	for in, want := range map[string]Unit{"": NanoMCM, "nmcm": NanoMCM, "NanoMCM": NanoMCM, " mcm ": MCM, "MCM": MCM} {
+		if got, err := ParseUnit(in); err != nil || got != want {
+			t.Errorf("ParseUnit(%q) = %v, %v; want %v", in, got, err, want)
+		}
+	}
+	if _, err := ParseUnit("btc"); err == nil || !strings.Contains(err.Error(), `unknown unit "btc"`) {
+		t.Errorf("ParseUnit(btc): %v", err)
+	}
+	if NanoMCM.String() != "nmcm" || MCM.String() != "mcm" {
+		t.Errorf("unit names %s, %s", NanoMCM, MCM)
+	}
+}
+
+func TestFormat(t *testing.T) {
+	for _, tc := range []struct {
+		nano     uint64
+		nanoText string
+		mcmText  string
+	}{
+		{0, "0 nMCM", "0 MCM"},
+		{1, "1 nMCM", "0.000000001 MCM"},
+		{500, "500 nMCM", "0.0000005 MCM"},
+		{NanoPerMCM, "1000000000 nMCM", "1 MCM"},
+		{2_500_000_000, "2500000000 nMCM", "2.5 MCM"},
+		{1_000_000_001, "1000000001 nMCM", "1.000000001 MCM"},
+		{math.MaxUint64, "18446744073709551615 nMCM", "18446744073.709551615 MCM"},
+	} {
+		if got := Format(tc.nano, NanoMCM); got != tc.nanoText {
+			t.Errorf("Format(%d, NanoMCM) = %q, want %q", tc.nano, got, tc.nanoText)
+		}
+		if got := Format(tc.nano, MCM); got != tc.mcmText {
+			t.Errorf("Format(%d, MCM) = %q, want %q", tc.nano, got, tc.mcmText)
+		}
+		if got, want := Describe(tc.nano), tc.nanoText+" ("+tc.mcmText+")"; got != want {
+			t.Errorf("Describe(%d) = %q, want %q", tc.nano, got, want)
+		}
+	}
+}
+
+// checkRoundTrip fails if an amount formatted in either unit does not parse back to itself
+func checkRoundTrip(t *testing.T, nano uint64) {
+	t.Helper()
+	for _, unit := range []Unit{NanoMCM, MCM} {
+		text := Format(nano, unit)
+		if got, err := Parse(text, NanoMCM); err != nil || got != nano {
+			t.Errorf("Parse(Format(%d, %v) = %q) = %d, %v", nano, unit, text, got, err)
+		}
+	}
+	if got, err := Parse(FormatMCM(nano), MCM); err != nil || got != nano {
+		t.Errorf("Parse(FormatMCM(%d), MCM) = %d, %v", nano, got, err)
+	}
+}
+
+func TestRoundTrip(t *testing.T) {
+	for _, nano := range []uint64{0, 1, 9, 10, 999_999_999, NanoPerMCM, NanoPerMCM + 1, 123_456_789_012, math.MaxUint64 / 2, math.MaxUint64 - 1, math.MaxUint64} {
+		checkRoundTrip(t, nano)
+	}
+}
+
+func FuzzParse(f *testing.F) {
+	for _, seed := range []string{"2,500,000,000", "2_500", "2.5 MCM", ".5mcm", "1.", "18446744073.709551615mcm", "1,0000", "0.0000000001mcm"} {
+		f.Add(seed, false)
+	}
+	f.Fuzz(func(t *testing.T, s string, inMCM bool) {
+		def := NanoMCM
+		if inMCM {
+			def = MCM
+		}
+		nano, err := Parse(s, def)
+		if err != nil {
+			if !strings.HasPrefix(err.Error(), "invalid amount ") {
+				t.Errorf("Parse(%q): error %q", s, err)
+			}
+			return
+		}
+		// Whatever was accepted formats back to text meaning the same amount
+		checkRoundTrip(t, nano)
+	})
+}
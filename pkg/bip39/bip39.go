@@ -0,0 +1,138 @@
+/*
+ * Package bip39 converts secret seeds to and from BIP39 mnemonics, the
+ * English word lists people can write down and type back.
+ *
+ * Only the entropy encoding is implemented: a mnemonic is the seed itself
+ * plus a checksum, not the PBKDF2 seed BIP39 wallets derive from it, so
+ * a 32 bytes WOTS secret seed is its 24 words and back. Mnemonics are
+ * handled as byte slices, as secure handles keys, for the caller to wipe.
+ */
+package bip39
+
+import (
+	"bytes"
+	"crypto/sha256"
+	_ "embed"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/NickP005/Vindax-MCM-tools/pkg/secure"
+)
+
+// english.txt is the BIP39 English word list, whose SHA-256 is 2f5eed53a4727b4bf8880d8f3f199efc90e58503646d9ff8eff3a2ed3b24dbda
+//
+//go:embed english.txt
+var englishText string
+
+// Words is the BIP39 English word list, sorted, 2048 words of 11 bits each
+var Words = strings.Fields(englishText)
+
+const (
+	// MinEntropy and MaxEntropy bound the length in bytes of the entropy of a mnemonic, a multiple of 4
+	MinEntropy = 16
+	MaxEntropy = 32
+)
+
+// WordError is returned for a word of a mnemonic that is not in the list, Position counting from 1
+type WordError struct {
+	Word     string
+	Position int
+}
+
+func (e *WordError) Error() string {
+	return fmt.Sprintf("word %d %q is not a BIP39 English word", e.Position, e.Word)
+}
+
+// ErrChecksum is returned for a mnemonic of known words whose checksum does not match, typically a word mistyped for another
+var ErrChecksum = errors.New("mnemonic checksum mismatch")
+
+// checkLength validates the length in bytes of an entropy
+func checkLength(length int) error {
+	if length < MinEntropy || length > MaxEntropy || length%4 != 0 {
+		return fmt.Errorf("invalid entropy length %d bytes: expected a multiple of 4 from %d to %d", length, MinEntropy, MaxEntropy)
+	}
+	return nil
+}
+
+/*
+ * Mnemonic returns the words of entropy separated by single spaces: 24
+ * words for 32 bytes, 3 words for every 4 bytes in general
+ *
+ * The entropy is followed by the first len(entropy)/4 bits of its SHA-256,
+ * and every 11 bits select a word.
+ */
+func Mnemonic(entropy []byte) ([]byte, error) {
+	if err := checkLength(len(entropy)); err != nil {
+		return nil, err
+	}
+	checksum := sha256.Sum256(entropy)
+	defer secure.Wipe(checksum[:])
+	bits := len(entropy) * 8
+	bit := func(i int) int {
+		if i < bits {
+			return int(entropy[i/8]>>(7-i%8)) & 1
+		}
+		i -= bits
+		return int(checksum[i/8]>>(7-i%8)) & 1
+	}
+
+	count := (bits + bits/32) / 11
+	mnemonic := make([]byte, 0, count*9)
+	for w := 0; w < count; w++ {
+		index := 0
+		for i := 0; i < 11; i++ {
+			index = index<<1 | bit(w*11+i)
+		}
+		if w > 0 {
+			mnemonic = append(mnemonic, ' ')
+		}
+		mnemonic = append(mnemonic, Words[index]...)
+	}
+	return mnemonic, nil
+}
+
+/*
+ * Entropy decodes a mnemonic back to its entropy, checking its checksum
+ *
+ * Words are separated by any whitespace and matched in any case. The
+ * caller remains responsible for wiping both mnemonic and the result.
+ */
+func Entropy(mnemonic []byte) ([]byte, error) {
+	fields := bytes.Fields(mnemonic)
+	if len(fields)%3 != 0 || checkLength(len(fields)/3*4) != nil {
+		return nil, fmt.Errorf("invalid mnemonic of %d words: expected 12, 15, 18, 21 or 24", len(fields))
+	}
+
+	bits := len(fields) * 11
+	buffer := make([]byte, (bits+7)/8)
+	defer secure.Wipe(buffer)
+	for w, field := range fields {
+		word := bytes.ToLower(field)
+		index := sort.SearchStrings(Words, string(word))
+		if index == len(Words) || Words[index] != string(word) {
+			return nil, &WordError{Word: string(field), Position: w + 1}
+		}
+		for i := 0; i < 11; i++ {
+			if index>>(10-i)&1 == 1 {
+				n := w*11 + i
+				buffer[n/8] |= 0x80 >> (n % 8)
+			}
+		}
+	}
+
+	entropy := make([]byte, len(fields)/3*4)
+	copy(entropy, buffer)
+	expected, err := Mnemonic(entropy)
+	if err != nil {
+		secure.Wipe(entropy)
+		return nil, err
+	}
+	defer secure.Wipe(expected)
+	if !secure.Equal(bytes.Fields(expected)[len(fields)-1], bytes.ToLower(fields[len(fields)-1])) {
+		secure.Wipe(entropy)
+		return nil, ErrChecksum
+	}
+	return entropy, nil
+}
@@ -0,0 +1,98 @@
+package bip39
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"os"
+	"sort"
+	"strings"
+	"testing"
+)
+
+// vectors are the English entropy to mnemonic vectors of the BIP39 reference implementation (trezor/python-mnemonic)
+var vectors = []struct {
+	entropy  string
+	mnemonic string
+}{
+	{"00000000000000000000000000000000", "abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon about"},
+	{"7f7f7f7f7f7f7f7f7f7f7f7f7f7f7f7f", "legal winner thank year wave sausage worth useful legal winner thank yellow"},
+	{"80808080808080808080808080808080", "letter advice cage absurd amount doctor acoustic avoid letter advice cage above"},
+	{"ffffffffffffffffffffffffffffffff", "zoo zoo zoo zoo zoo zoo zoo zoo zoo zoo zoo wrong"},
+	{"000000000000000000000000000000000000000000000000", "abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon agent"},
+	{"7f7f7f7f7f7f7f7f7f7f7f7f7f7f7f7f7f7f7f7f7f7f7f7f", "legal winner thank year wave sausage worth useful legal winner thank year wave sausage worth useful legal will"},
+	{"808080808080808080808080808080808080808080808080", "letter advice cage absurd amount doctor acoustic avoid letter advice cage absurd amount doctor acoustic avoid letter always"},
+	{"ffffffffffffffffffffffffffffffffffffffffffffffff", "zoo zoo zoo zoo zoo zoo zoo zoo zoo zoo zoo zoo zoo zoo zoo zoo zoo when"},
+	{"0000000000000000000000000000000000000000000000000000000000000000", "abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon art"},
+	{"7f7f7f7f7f7f7f7f7f7f7f7f7f7f7f7f7f7f7f7f7f7f7f7f7f7f7f7f7f7f7f7f", "legal winner thank year wave sausage worth useful legal winner thank year wave sausage worth useful legal winner thank year wave sausage worth title"},
+	{"8080808080808080808080808080808080808080808080808080808080808080", "letter advice cage absurd amount doctor acoustic avoid letter advice cage absurd amount doctor acoustic avoid letter advice cage absurd amount doctor acoustic bless"},
+	{"ffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffff", "zoo zoo zoo zoo zoo zoo zoo zoo zoo zoo zoo zoo zoo zoo zoo zoo zoo zoo zoo zoo zoo zoo zoo vote"},
+	{"9e885d952ad362caeb4efe34a8e91bd2", "ozone drill grab fiber curtain grace pudding thank cruise elder eight picnic"},
+	{"68a79eaca2324873eacc50cb9c6eca8cc68ea5d936f98787c60c7ebc74e6ce7c", "hamster diagram private dutch cause delay private meat slide toddler razor book happy fancy gospel tennis maple dilemma loan word shrug inflict delay length"},
+	{"f585c11aec520db57dd353c69554b21a89b20fb0650966fa0a9d6f74fd989d8f", "void come effort suffer camp survey warrior heavy shoot primary clutch crush open amazing screen patrol group space point ten exist slush involve unfold"},
+}
+
+func TestWordList(t *testing.T) {
+	data, err := os.ReadFile("english.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sum := sha256.Sum256(data); hex.EncodeToString(sum[:]) != "2f5eed53a4727b4bf8880d8f3f199efc90e58503646d9ff8eff3a2ed3b24dbda" {
+		t.Errorf("english.txt SHA-256 %x", sum)
+	}
+	if len(Words) != 2048 || !sort.StringsAreSorted(Words) || Words[0] != "abandon" || Words[2047] != "zoo" {
+		t.Errorf("%d words, sorted %v", len(Words), sort.StringsAreSorted(Words))
+	}
+}
+
+func TestVectors(t *testing.T) {
+	for _, v := range vectors {
+		entropy, _ := hex.DecodeString(v.entropy)
+		mnemonic, err := Mnemonic(entropy)
+		if err != nil || string(mnemonic) != v.mnemonic {
+			t.Errorf("%s: %q, %v", v.entropy, mnemonic, err)
+		}
+		decoded, err := Entropy([]byte(v.mnemonic))
+		if err != nil || !bytes.Equal(decoded, entropy) {
+			t.Errorf("%s: decoded %x, %v", v.entropy, decoded, err)
+		}
+	}
+}
+
+func TestEntropyErrors(t *testing.T) {
+	last := vectors[len(vectors)-1]
+	words := strings.Fields(last.mnemonic)
+
+	// Any whitespace, any case
+	loose := "  " + strings.ToUpper(strings.Join(words[:12], "\n")) + "\t" + strings.Join(words[12:], "   ") + "\n"
+	if entropy, err := Entropy([]byte(loose)); err != nil || hex.EncodeToString(entropy) != last.entropy {
+		t.Errorf("loose mnemonic: %x, %v", entropy, err)
+	}
+
+	typo := append([]string{}, words...)
+	typo[6] = "warior"
+	var wordErr *WordError
+	if _, err := Entropy([]byte(strings.Join(typo, " "))); !errors.As(err, &wordErr) || wordErr.Position != 7 || wordErr.Word != "warior" {
+		t.Errorf("unknown word: %v", err)
+	}
+
+	// Two words swapped keep every word valid, but not the checksum
+	swapped := append([]string{}, words...)
+	swapped[0], swapped[1] = swapped[1], swapped[0]
+	if _, err := Entropy([]byte(strings.Join(swapped, " "))); !errors.Is(err, ErrChecksum) {
+		t.Errorf("swapped words: %v", err)
+	}
+
+	for _, count := range []int{0, 11, 13, 23, 27} {
+		mnemonic := strings.Repeat("abandon ", count)
+		if _, err := Entropy([]byte(mnemonic)); err == nil || !strings.Contains(err.Error(), "expected 12, 15, 18, 21 or 24") {
+			t.Errorf("%d words: %v", count, err)
+		}
+	}
+	for _, length := range []int{0, 15, 17, 36} {
+		if _, err := Mnemonic(make([]byte, length)); err == nil {
+			t.Errorf("entropy of %d bytes accepted", length)
+		}
+	}
+}
@@ -0,0 +1,46 @@
+/*
+ * Package cli holds what the command lines of the tools share: the exit
+ * codes scripts rely on, and the handling of invalid flags.
+ *
+ * A tool numbers the outcomes of its own from 3 on, and exits with
+ * shutdown.ExitInterrupted when a signal stopped it.
+ */
+package cli
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/NickP005/Vindax-MCM-tools/pkg/config"
+)
+
+// Process exit codes, stable for use from shell scripts
+const (
+	ExitOK      = 0 // the tool did what it was asked
+	ExitFailure = 1 // a lookup, a read, a write or a check failed
+	ExitUsage   = 2 // invalid flags or arguments, the code the flag package exits with
+)
+
+/*
+ * Parse parses the command line flags, a flag error exiting with ExitUsage
+ * as the flag package does by default
+ *
+ * With cfg, the settings of its flags are marked and checked by
+ * cfg.Parsed, an invalid value exiting with ExitUsage too.
+ */
+func Parse(cfg *config.Config) {
+	flag.Parse()
+	if cfg == nil {
+		return
+	}
+	if err := cfg.Parsed(flag.CommandLine); err != nil {
+		Usagef("%v", err)
+	}
+}
+
+// Usagef prints an error about the flags or arguments, as "Error: ..." on stderr, and exits with ExitUsage
+func Usagef(format string, args ...any) {
+	fmt.Fprintf(os.Stderr, "Error: "+format+"\n", args...)
+	os.Exit(ExitUsage)
+}
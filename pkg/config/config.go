@@ -0,0 +1,381 @@
+/*
+ * Package config loads the settings shared by the tools: the Mesh API
+ * endpoints, the network, the default fee, the poll interval and timeout,
+ * the receipts and history directories and the webhook.
+ *
+ * Every setting is taken, by decreasing precedence, from a command line
+ * flag, an MCM_TOOLS_* environment variable, the config file and the
+ * defaults. The file is ~/.config/mcm-tools/config.json (the user config
+ * directory of the platform), or the file named by MCM_TOOLS_CONFIG; it may
+ * set only some of the settings, the others keep their defaults.
+ */
+package config
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/NickP005/Vindax-MCM-tools/pkg/meshclient"
+)
+
+// PATH_ENV names the environment variable holding the path of the config file
+const PATH_ENV = "MCM_TOOLS_CONFIG"
+
+// ENV_PREFIX starts the name of the environment variable of every setting, e.g. MCM_TOOLS_API
+const ENV_PREFIX = "MCM_TOOLS_"
+
+// Where a setting comes from, as printed by Print
+const (
+	SourceDefault = "default"
+	SourceFile    = "file"
+	SourceEnv     = "env"
+	SourceFlag    = "flag"
+)
+
+// Webhook is where the tools that notify post their events
+type Webhook struct {
+	URL string
+	// Secret signs the posted events; Print never shows it
+	Secret  string
+	Timeout time.Duration
+}
+
+/*
+ * Config is the effective configuration of a tool
+ *
+ * API is the primary Mesh API endpoint and Failover the endpoints tried
+ * after it when it cannot be reached. Fee keeps the syntax of the -fee
+ * flags, nanoMCM or with a unit suffix, and is parsed by the tools. An
+ * empty ReceiptsDir or HistoryDir leaves the choice to the tool.
+ */
+type Config struct {
+	API          string
+	Failover     []string
+	Network      string
+	Fee          string
+	PollInterval time.Duration
+	Timeout      time.Duration
+	ReceiptsDir  string
+	HistoryDir   string
+	Webhook      Webhook
+
+	// Path is the config file read, empty if there was none
+	Path string
+	// sources maps the key of every setting to where it comes from
+	sources map[string]string
+	// flags maps the flags bound to the configuration to their setting
+	flags map[string]string
+}
+
+// Defaults returns the configuration used when nothing else is set; tools change it before Load for their own defaults
+func Defaults() Config {
+	return Config{
+		API:          "http://localhost:8080",
+		Network:      "mainnet",
+		Fee:          "500",
+		PollInterval: 5 * time.Second,
+		Timeout:      5 * time.Minute,
+		Webhook:      Webhook{Timeout: 10 * time.Second},
+	}
+}
+
+// setting is one configuration value, read from the file and the environment under key
+type setting struct {
+	key string
+	// set parses and stores a value; the value of a file is its JSON text
+	set func(c *Config, value string, fromJSON bool) error
+	// get renders the value for Print
+	get func(c *Config) interface{}
+}
+
+// settings lists every setting
+var settings = []setting{
+	stringSetting("api", func(c *Config) *string { return &c.API }),
+	{
+		key: "failover",
+		set: func(c *Config, value string, fromJSON bool) error {
+			if fromJSON {
+				var endpoints []string
+				if err := json.Unmarshal([]byte(value), &endpoints); err != nil {
+					return fmt.Errorf("expected a list of URLs: %v", err)
+				}
+				c.Failover = endpoints
+				return nil
+			}
+			c.Failover = splitList(value)
+			return nil
+		},
+		get: func(c *Config) interface{} { return append([]string{}, c.Failover...) },
+	},
+	stringSetting("network", func(c *Config) *string { return &c.Network }),
+	stringSetting("fee", func(c *Config) *string { return &c.Fee }),
+	durationSetting("poll_interval", func(c *Config) *time.Duration { return &c.PollInterval }),
+	durationSetting("timeout", func(c *Config) *time.Duration { return &c.Timeout }),
+	stringSetting("receipts_dir", func(c *Config) *string { return &c.ReceiptsDir }),
+	stringSetting("history_dir", func(c *Config) *string { return &c.HistoryDir }),
+	stringSetting("webhook.url", func(c *Config) *string { return &c.Webhook.URL }),
+	{
+		key: "webhook.secret",
+		set: stringSetting("", func(c *Config) *string { return &c.Webhook.Secret }).set,
+		get: func(c *Config) interface{} {
+			if c.Webhook.Secret == "" {
+				return ""
+			}
+			return "(set)"
+		},
+	},
+	durationSetting("webhook.timeout", func(c *Config) *time.Duration { return &c.Webhook.Timeout }),
+}
+
+// stringSetting is a setting held in a string field
+func stringSetting(key string, field func(c *Config) *string) setting {
+	return setting{
+		key: key,
+		set: func(c *Config, value string, fromJSON bool) error {
+			if fromJSON {
+				return json.Unmarshal([]byte(value), field(c))
+			}
+			*field(c) = value
+			return nil
+		},
+		get: func(c *Config) interface{} { return *field(c) },
+	}
+}
+
+// durationSetting is a setting held in a duration field, written as "30s" or "5m"
+func durationSetting(key string, field func(c *Config) *time.Duration) setting {
+	return setting{
+		key: key,
+		set: func(c *Config, value string, fromJSON bool) error {
+			if fromJSON {
+				if err := json.Unmarshal([]byte(value), &value); err != nil {
+					return fmt.Errorf("expected a duration such as \"30s\": %v", err)
+				}
+			}
+			d, err := time.ParseDuration(value)
+			if err != nil {
+				return err
+			}
+			if d <= 0 {
+				return fmt.Errorf("%s is not a positive duration", value)
+			}
+			*field(c) = d
+			return nil
+		},
+		get: func(c *Config) interface{} { return field(c).String() },
+	}
+}
+
+// envName returns the environment variable of a setting, e.g. MCM_TOOLS_WEBHOOK_URL for webhook.url
+func envName(key string) string {
+	return ENV_PREFIX + strings.ToUpper(strings.ReplaceAll(key, ".", "_"))
+}
+
+// splitList splits a comma separated list, dropping empty items
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// DefaultPath returns the config file read by Load: MCM_TOOLS_CONFIG if set, else mcm-tools/config.json in the user config directory
+func DefaultPath() (string, error) {
+	if path := os.Getenv(PATH_ENV); path != "" {
+		return path, nil
+	}
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "mcm-tools", "config.json"), nil
+}
+
+/*
+ * Load returns base overridden by the config file, then by the environment
+ *
+ * Parameters:
+ * - base: the defaults of the tool, usually Defaults() with a few changes
+ *
+ * A missing file at the default location is not an error, a missing file
+ * named by MCM_TOOLS_CONFIG is. Unknown keys in the file are errors, so a
+ * misspelt setting does not go unnoticed. Flags bound with the *Flag
+ * methods then override the result once parsed, see Parsed.
+ */
+func Load(base Config) (*Config, error) {
+	c := base
+	c.sources = make(map[string]string)
+	c.flags = make(map[string]string)
+	for _, s := range settings {
+		c.sources[s.key] = SourceDefault
+	}
+
+	// DefaultPath fails only without MCM_TOOLS_CONFIG and a user config directory: there is no file to read then
+	path, _ := DefaultPath()
+	named := os.Getenv(PATH_ENV) != ""
+	if path != "" {
+		data, err := os.ReadFile(path)
+		switch {
+		case errors.Is(err, os.ErrNotExist) && !named:
+		case err != nil:
+			return nil, fmt.Errorf("failed to read config file: %v", err)
+		default:
+			if err := c.applyFile(data); err != nil {
+				return nil, fmt.Errorf("config file %s: %v", path, err)
+			}
+			c.Path = path
+		}
+	}
+
+	for _, s := range settings {
+		value, ok := os.LookupEnv(envName(s.key))
+		if !ok {
+			continue
+		}
+		if err := s.set(&c, value, false); err != nil {
+			return nil, fmt.Errorf("%s: %v", envName(s.key), err)
+		}
+		c.sources[s.key] = SourceEnv
+	}
+	return &c, nil
+}
+
+// applyFile sets the settings present in a config file, the webhook ones under a "webhook" object
+func (c *Config) applyFile(data []byte) error {
+	var top map[string]json.RawMessage
+	if err := json.Unmarshal(data, &top); err != nil {
+		return err
+	}
+	values := make(map[string]json.RawMessage)
+	for key, value := range top {
+		if key != "webhook" {
+			values[key] = value
+			continue
+		}
+		var webhook map[string]json.RawMessage
+		if err := json.Unmarshal(value, &webhook); err != nil {
+			return fmt.Errorf("webhook: %v", err)
+		}
+		for key, value := range webhook {
+			values["webhook."+key] = value
+		}
+	}
+
+	for _, s := range settings {
+		value, ok := values[s.key]
+		if !ok {
+			continue
+		}
+		delete(values, s.key)
+		if err := s.set(c, string(value), true); err != nil {
+			return fmt.Errorf("%s: %v", s.key, err)
+		}
+		c.sources[s.key] = SourceFile
+	}
+	if len(values) > 0 {
+		unknown := make([]string, 0, len(values))
+		for key := range values {
+			unknown = append(unknown, key)
+		}
+		sort.Strings(unknown)
+		return fmt.Errorf("unknown settings %s", strings.Join(unknown, ", "))
+	}
+	return nil
+}
+
+// bind records that a flag sets a setting, for Parsed
+func (c *Config) bind(name string, key string) {
+	c.flags[name] = key
+}
+
+// APIFlags binds -api, with usage, -failover and -network to the configuration
+func (c *Config) APIFlags(fs *flag.FlagSet, usage string) {
+	fs.StringVar(&c.API, "api", c.API, usage)
+	c.bind("api", "api")
+	fs.Func("failover", fmt.Sprintf("Comma separated Mesh API URLs tried in turn when -api cannot be reached (default %q)", strings.Join(c.Failover, ",")), func(value string) error {
+		c.Failover = splitList(value)
+		return nil
+	})
+	c.bind("failover", "failover")
+	fs.StringVar(&c.Network, "network", c.Network, "Mochimo network of the Mesh API")
+	c.bind("network", "network")
+}
+
+// FeeFlag binds -fee to the default fee of the configuration
+func (c *Config) FeeFlag(fs *flag.FlagSet, usage string) {
+	fs.StringVar(&c.Fee, "fee", c.Fee, usage)
+	c.bind("fee", "fee")
+}
+
+// TimeoutFlag binds -timeout to the timeout of the configuration
+func (c *Config) TimeoutFlag(fs *flag.FlagSet, usage string) {
+	fs.DurationVar(&c.Timeout, "timeout", c.Timeout, usage)
+	c.bind("timeout", "timeout")
+}
+
+// PollIntervalFlag binds the flag name to the poll interval of the configuration
+func (c *Config) PollIntervalFlag(fs *flag.FlagSet, name string, usage string) {
+	fs.DurationVar(&c.PollInterval, name, c.PollInterval, usage)
+	c.bind(name, "poll_interval")
+}
+
+// ReceiptsDirFlag binds -receipts-dir to the receipts directory of the configuration
+func (c *Config) ReceiptsDirFlag(fs *flag.FlagSet, usage string) {
+	fs.StringVar(&c.ReceiptsDir, "receipts-dir", c.ReceiptsDir, usage)
+	c.bind("receipts-dir", "receipts_dir")
+}
+
+// PrintFlag adds -print-config, whose value Parsed reports
+func (c *Config) PrintFlag(fs *flag.FlagSet) *bool {
+	return fs.Bool("print-config", false, "Print the effective configuration, and where each setting comes from, then exit")
+}
+
+// Parsed marks the settings of the flags set on the command line, once fs is parsed, and checks the durations
+func (c *Config) Parsed(fs *flag.FlagSet) error {
+	fs.Visit(func(f *flag.Flag) {
+		if key, ok := c.flags[f.Name]; ok {
+			c.sources[key] = SourceFlag
+		}
+	})
+	for name, key := range c.flags {
+		if (key == "timeout" && c.Timeout <= 0) || (key == "poll_interval" && c.PollInterval <= 0) {
+			return fmt.Errorf("-%s must be positive", name)
+		}
+	}
+	return nil
+}
+
+// printed is the JSON written by Print
+type printed struct {
+	File     string                 `json:"file"`
+	Settings map[string]interface{} `json:"settings"`
+	Sources  map[string]string      `json:"sources"`
+}
+
+// Print writes the effective configuration as JSON, with the file read and the source of every setting; the webhook secret is only said to be set
+func (c *Config) Print(w io.Writer) error {
+	out := printed{File: c.Path, Settings: make(map[string]interface{}), Sources: c.sources}
+	for _, s := range settings {
+		out.Settings[s.key] = s.get(c)
+	}
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(out)
+}
+
+// Apply sets the network and the failover endpoints of a client made for the API of the configuration
+func (c *Config) Apply(client *meshclient.MeshAPIClient) {
+	client.SetNetwork(c.Network)
+	client.SetFailover(c.Failover...)
+}
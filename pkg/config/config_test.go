@@ -0,0 +1,190 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+// writeConfig writes a config file and names it in MCM_TOOLS_CONFIG
+func writeConfig(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "config.json")
+	if err := os.WriteFile(path, []byte(content), 0600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv(PATH_ENV, path)
+	return path
+}
+
+// noUserConfig points the user config directory to an empty directory, so no real config file is read
+func noUserConfig(t *testing.T) {
+	t.Helper()
+	dir := t.TempDir()
+	t.Setenv("HOME", dir)
+	t.Setenv("XDG_CONFIG_HOME", dir)
+	t.Setenv("AppData", dir)
+	t.Setenv(PATH_ENV, "")
+}
+
+// printSources returns the sources of the settings as Print writes them
+func printSources(t *testing.T, c *Config) map[string]string {
+	t.Helper()
+	var out bytes.Buffer
+	if err := c.Print(&out); err != nil {
+		t.Fatal(err)
+	}
+	var p printed
+	if err := json.Unmarshal(out.Bytes(), &p); err != nil {
+		t.Fatal(err)
+	}
+	return p.Sources
+}
+
+func TestDefaultsWithoutFile(t *testing.T) {
+	noUserConfig(t)
+	c, err := Load(Defaults())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.Path != "" || c.API != "http://localhost:8080" || c.Network != "mainnet" || c.Fee != "500" || c.Timeout != 5*time.Minute {
+		t.Errorf("config %+v", c)
+	}
+	for key, source := range printSources(t, c) {
+		if source != SourceDefault {
+			t.Errorf("%s from %s", key, source)
+		}
+	}
+
+	// The default file is read when present
+	dir, _ := os.UserConfigDir()
+	os.MkdirAll(filepath.Join(dir, "mcm-tools"), 0700)
+	os.WriteFile(filepath.Join(dir, "mcm-tools", "config.json"), []byte(`{"network": "testnet"}`), 0600)
+	if c, err := Load(Defaults()); err != nil || c.Network != "testnet" || c.Path == "" {
+		t.Errorf("default file: %+v, %v", c, err)
+	}
+}
+
+// TestPartialFile sets a few settings in the file, the others keeping the defaults of the tool
+func TestPartialFile(t *testing.T) {
+	noUserConfig(t)
+	path := writeConfig(t, `{"api": "http://node:8080", "failover": ["http://b:8080", "http://c:8080"], "webhook": {"url": "http://hook"}}`)
+	base := Defaults()
+	base.PollInterval = time.Minute
+	c, err := Load(base)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.Path != path || c.API != "http://node:8080" || !slices.Equal(c.Failover, []string{"http://b:8080", "http://c:8080"}) || c.Webhook.URL != "http://hook" {
+		t.Errorf("file settings %+v", c)
+	}
+	if c.PollInterval != time.Minute || c.Webhook.Timeout != 10*time.Second || c.Fee != "500" {
+		t.Errorf("defaults %+v", c)
+	}
+	sources := printSources(t, c)
+	if sources["api"] != SourceFile || sources["webhook.url"] != SourceFile || sources["fee"] != SourceDefault || sources["webhook.timeout"] != SourceDefault {
+		t.Errorf("sources %v", sources)
+	}
+}
+
+// TestPrecedence sets the fee and the timeout at every level: flags beat the environment, which beats the file
+func TestPrecedence(t *testing.T) {
+	noUserConfig(t)
+	writeConfig(t, `{"fee": "600", "timeout": "1m", "network": "testnet", "api": "http://file:8080"}`)
+	t.Setenv("MCM_TOOLS_FEE", "700")
+	t.Setenv("MCM_TOOLS_TIMEOUT", "2m")
+	t.Setenv("MCM_TOOLS_FAILOVER", "http://b:8080, ,http://c:8080")
+	c, err := Load(Defaults())
+	if err != nil {
+		t.Fatal(err)
+	}
+	fs := flag.NewFlagSet("tool", flag.ContinueOnError)
+	c.APIFlags(fs, "Mesh API URL")
+	c.FeeFlag(fs, "Fee")
+	c.TimeoutFlag(fs, "Timeout")
+	c.PollIntervalFlag(fs, "interval", "Poll interval")
+	if err := fs.Parse([]string{"-fee", "800", "-interval", "3s"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Parsed(fs); err != nil {
+		t.Fatal(err)
+	}
+
+	if c.Fee != "800" || c.Timeout != 2*time.Minute || c.Network != "testnet" || c.API != "http://file:8080" || c.PollInterval != 3*time.Second {
+		t.Errorf("config %+v", c)
+	}
+	if !slices.Equal(c.Failover, []string{"http://b:8080", "http://c:8080"}) {
+		t.Errorf("failover %q", c.Failover)
+	}
+	want := map[string]string{"fee": SourceFlag, "poll_interval": SourceFlag, "timeout": SourceEnv, "failover": SourceEnv, "network": SourceFile, "api": SourceFile, "receipts_dir": SourceDefault}
+	sources := printSources(t, c)
+	for key, source := range want {
+		if sources[key] != source {
+			t.Errorf("%s from %s, want %s", key, sources[key], source)
+		}
+	}
+}
+
+func TestLoadErrors(t *testing.T) {
+	noUserConfig(t)
+	for content, message := range map[string]string{
+		`{"fees": "600", "webhook": {"secrt": "x"}}`: "unknown settings fees, webhook.secrt",
+		`{"timeout": "-1m"}`:                         "timeout: -1m is not a positive duration",
+		`{"timeout": 60}`:                            "timeout: expected a duration",
+		`{"failover": "http://b:8080"}`:              "failover: expected a list of URLs",
+		`{"webhook": "http://hook"}`:                 "webhook:",
+		`{"api": `:                                   "config file",
+	} {
+		writeConfig(t, content)
+		if _, err := Load(Defaults()); err == nil || !strings.Contains(err.Error(), message) {
+			t.Errorf("%s: %v", content, err)
+		}
+	}
+
+	// A file named by MCM_TOOLS_CONFIG must exist
+	t.Setenv(PATH_ENV, filepath.Join(t.TempDir(), "missing.json"))
+	if _, err := Load(Defaults()); err == nil || !strings.Contains(err.Error(), "failed to read config file") {
+		t.Errorf("missing named file: %v", err)
+	}
+
+	noUserConfig(t)
+	t.Setenv("MCM_TOOLS_POLL_INTERVAL", "soon")
+	if _, err := Load(Defaults()); err == nil || !strings.Contains(err.Error(), "MCM_TOOLS_POLL_INTERVAL") {
+		t.Errorf("bad environment: %v", err)
+	}
+}
+
+func TestParsedDurations(t *testing.T) {
+	noUserConfig(t)
+	c, _ := Load(Defaults())
+	fs := flag.NewFlagSet("tool", flag.ContinueOnError)
+	c.TimeoutFlag(fs, "Timeout")
+	fs.Parse([]string{"-timeout", "0s"})
+	if err := c.Parsed(fs); err == nil || err.Error() != "-timeout must be positive" {
+		t.Errorf("zero timeout: %v", err)
+	}
+}
+
+// TestPrintHidesSecret checks the webhook secret is only said to be set
+func TestPrintHidesSecret(t *testing.T) {
+	noUserConfig(t)
+	t.Setenv("MCM_TOOLS_WEBHOOK_SECRET", "hunter2")
+	c, err := Load(Defaults())
+	if err != nil {
+		t.Fatal(err)
+	}
+	var out bytes.Buffer
+	c.Print(&out)
+	if strings.Contains(out.String(), "hunter2") || !strings.Contains(out.String(), `"webhook.secret": "(set)"`) {
+		t.Errorf("printed:\n%s", out.String())
+	}
+	if c.Webhook.Secret != "hunter2" {
+		t.Errorf("secret %q", c.Webhook.Secret)
+	}
+}
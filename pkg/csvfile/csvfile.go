@@ -0,0 +1,106 @@
+/*
+ * Package csvfile reads the delimited files exchanged between the tools.
+ *
+ * Files produced by spreadsheets and wallet exports disagree on the
+ * delimiter, so it is detected from the first line: comma, semicolon or tab,
+ * whichever occurs most outside quotes, falling back to a single space (the
+ * historical wallet-tool format) when none of them is present. A UTF-8 byte
+ * order mark at the start of the file is dropped.
+ */
+package csvfile
+
+import (
+	"bufio"
+	"encoding/csv"
+	"io"
+	"strings"
+)
+
+// byteOrderMark is prepended to UTF-8 files by some spreadsheet exports
+const byteOrderMark = "\ufeff"
+
+// candidates are the delimiters considered by DetectDelimiter, in order of preference on ties
+var candidates = []rune{',', ';', '\t'}
+
+// Reader is a csv.Reader configured with the delimiter detected from its input
+type Reader struct {
+	*csv.Reader
+	Delimiter rune
+}
+
+/*
+ * NewReader detects the delimiter of r and returns a reader for it
+ *
+ * Records may have a varying number of fields, callers validate the field
+ * count themselves so they can report it per line.
+ */
+func NewReader(r io.Reader) (*Reader, error) {
+	br := bufio.NewReader(r)
+	first, err := br.ReadString('\n')
+	if err != nil && err != io.EOF {
+		return nil, err
+	}
+	first = strings.TrimPrefix(first, byteOrderMark)
+
+	delimiter := DetectDelimiter(first)
+	reader := csv.NewReader(io.MultiReader(strings.NewReader(first), br))
+	reader.Comma = delimiter
+	reader.FieldsPerRecord = -1
+	return &Reader{Reader: reader, Delimiter: delimiter}, nil
+}
+
+// DetectDelimiter picks the delimiter of a line, see the package documentation
+func DetectDelimiter(line string) rune {
+	counts := make(map[rune]int)
+	inQuotes := false
+	for _, c := range line {
+		if c == '"' {
+			inQuotes = !inQuotes
+			continue
+		}
+		if !inQuotes {
+			counts[c]++
+		}
+	}
+
+	best, bestCount := ',', 0
+	for _, c := range candidates {
+		if counts[c] > bestCount {
+			best, bestCount = c, counts[c]
+		}
+	}
+	if bestCount == 0 && counts[' '] > 0 {
+		return ' '
+	}
+	return best
+}
+
+/*
+ * HasHeader reports whether record is a header line, that is whether any of
+ * its fields names one of columns (case-insensitive)
+ */
+func HasHeader(record []string, columns ...string) bool {
+	for _, column := range columns {
+		if ColumnIndex(record, column) >= 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// ColumnIndex returns the position of the column named name in a header record, or -1
+func ColumnIndex(header []string, name string) int {
+	for i, field := range header {
+		if strings.EqualFold(strings.TrimSpace(field), name) {
+			return i
+		}
+	}
+	return -1
+}
+
+// NewWriter returns a csv.Writer using delimiter, so output matches the file it was derived from
+func NewWriter(w io.Writer, delimiter rune) *csv.Writer {
+	writer := csv.NewWriter(w)
+	writer.Comma = delimiter
+	return writer
+}
@@ -0,0 +1,86 @@
+package csvfile
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestDetectDelimiter(t *testing.T) {
+	for _, tc := range []struct {
+		line string
+		want rune
+	}{
+		{"name,wots_hex\n", ','},
+		{"name;wots_hex;note\n", ';'},
+		{"name\twots_hex\n", '\t'},
+		{"name wots_hex\n", ' '},
+		{"single\n", ','},
+		{"", ','},
+		// Delimiters inside quotes do not count
+		{`"a;b;c",x` + "\n", ','},
+		{`"a,b,c";x` + "\n", ';'},
+		// Ties go to the first candidate
+		{"a,b;c\n", ','},
+		// A space is used only when no candidate occurs
+		{"a b,c\n", ','},
+	} {
+		if got := DetectDelimiter(tc.line); got != tc.want {
+			t.Errorf("%q: got %q, want %q", tc.line, got, tc.want)
+		}
+	}
+}
+
+func TestReaderDropsByteOrderMarkAndKeepsFirstLine(t *testing.T) {
+	reader, err := NewReader(strings.NewReader(byteOrderMark + "name;wots_hex\nalice;ab;extra\nbob;cd\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if reader.Delimiter != ';' {
+		t.Fatalf("delimiter %q", reader.Delimiter)
+	}
+	records, err := reader.ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := [][]string{{"name", "wots_hex"}, {"alice", "ab", "extra"}, {"bob", "cd"}}
+	if len(records) != len(want) {
+		t.Fatalf("records %q", records)
+	}
+	for i := range want {
+		if strings.Join(records[i], "|") != strings.Join(want[i], "|") {
+			t.Errorf("record %d %q, want %q", i, records[i], want[i])
+		}
+	}
+}
+
+func TestReaderWithoutTrailingNewline(t *testing.T) {
+	reader, err := NewReader(strings.NewReader("a,b"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	record, err := reader.Read()
+	if err != nil || strings.Join(record, "|") != "a|b" {
+		t.Errorf("got %q, %v", record, err)
+	}
+}
+
+func TestHeader(t *testing.T) {
+	header := []string{" Name ", "WOTS_HEX", "note"}
+	if !HasHeader(header, "name", "wots_hex") || HasHeader([]string{"alice", "ab"}, "name", "wots_hex") {
+		t.Error("header detection")
+	}
+	if ColumnIndex(header, "name") != 0 || ColumnIndex(header, "wots_hex") != 1 || ColumnIndex(header, "missing") != -1 {
+		t.Error("column index")
+	}
+}
+
+func TestWriterUsesDelimiter(t *testing.T) {
+	var out bytes.Buffer
+	writer := NewWriter(&out, '\t')
+	writer.Write([]string{"a", "b c"})
+	writer.Flush()
+	if out.String() != "a\tb c\n" {
+		t.Errorf("got %q", out.String())
+	}
+}
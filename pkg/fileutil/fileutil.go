@@ -0,0 +1,137 @@
+/*
+ * Package fileutil holds the file operations the tools need to behave the
+ * same on Unix and Windows: atomic replacement of a file, a rename that
+ * survives the transient sharing violations of Windows, a move that falls
+ * back to copying across volumes, and an advisory lock.
+ *
+ * On Windows, a file opened by another process (an antivirus scanner, a
+ * backup agent, an editor) cannot be renamed or replaced for a moment, and
+ * a directory cannot be synced through os.File: Rename retries the first
+ * for up to RenameTimeout, and SyncDir does nothing there, NTFS journaling
+ * its metadata.
+ */
+package fileutil
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// RenameTimeout bounds how long Rename retries a rename failing with a transient error
+const RenameTimeout = 2 * time.Second
+
+// renameBackoff is the first wait between two attempts of Rename, doubled after each
+const renameBackoff = 10 * time.Millisecond
+
+/*
+ * WriteAtomic replaces path with data, through a temporary file of the same
+ * directory renamed over it, so a reader never sees a partial file
+ *
+ * The temporary file is created readable by its owner only, which path
+ * keeps. With durable, the data and then the rename are synced to disk
+ * before WriteAtomic returns, so a crash leaves either the old or the new
+ * file; without it, they reach the disk when the system decides.
+ */
+func WriteAtomic(path string, data []byte, durable bool) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if durable {
+		if err := tmp.Sync(); err != nil {
+			tmp.Close()
+			return err
+		}
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := Rename(tmp.Name(), path); err != nil {
+		return err
+	}
+	if durable {
+		return SyncDir(filepath.Dir(path))
+	}
+	return nil
+}
+
+// Rename renames oldpath to newpath, replacing it, and retries for up to RenameTimeout while another process holds either open
+func Rename(oldpath, newpath string) error {
+	deadline := time.Now().Add(RenameTimeout)
+	wait := renameBackoff
+	for {
+		err := os.Rename(oldpath, newpath)
+		if err == nil || !transient(err) || time.Now().Add(wait).After(deadline) {
+			return err
+		}
+		time.Sleep(wait)
+		wait *= 2
+	}
+}
+
+/*
+ * Move moves oldpath to newpath, e.g. a processed file into an archive
+ * directory
+ *
+ * Across volumes, where no rename is possible, the file is copied with
+ * its permissions, synced, and only then removed from oldpath. An existing
+ * newpath is replaced in both cases.
+ */
+func Move(oldpath, newpath string) error {
+	err := Rename(oldpath, newpath)
+	if err == nil || !crossDevice(err) {
+		return err
+	}
+	if err := copyFile(oldpath, newpath); err != nil {
+		return fmt.Errorf("failed to copy %s across volumes: %v", oldpath, err)
+	}
+	return os.Remove(oldpath)
+}
+
+// copyFile copies src to dst through WriteAtomic's temporary file, so dst is never left partial
+func copyFile(src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	info, err := in.Stat()
+	if err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(dst), filepath.Base(dst)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	_, err = io.Copy(tmp, in)
+	if err == nil {
+		err = tmp.Chmod(info.Mode().Perm())
+	}
+	if err == nil {
+		err = tmp.Sync()
+	}
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return err
+	}
+	if err := Rename(tmp.Name(), dst); err != nil {
+		return err
+	}
+	return SyncDir(filepath.Dir(dst))
+}
+
+// ErrLocked is returned by Lock when another process holds the lock
+var ErrLocked = errors.New("file is locked by another process")
@@ -0,0 +1,130 @@
+package fileutil
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+)
+
+// readFile returns the content of path, failing the test if it cannot be read
+func readFile(t *testing.T, path string) string {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data)
+}
+
+// checkNoTemporary fails the test if a temporary file of WriteAtomic or Move is left in dir
+func checkNoTemporary(t *testing.T, dir string) {
+	t.Helper()
+	leftovers, err := filepath.Glob(filepath.Join(dir, "*.tmp"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(leftovers) > 0 {
+		t.Errorf("temporary files left: %v", leftovers)
+	}
+}
+
+func TestWriteAtomic(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "wallet-cache.json")
+	for _, durable := range []bool{false, true} {
+		for _, content := range []string{"first", "second, longer than the first"} {
+			if err := WriteAtomic(path, []byte(content), durable); err != nil {
+				t.Fatal(err)
+			}
+			if got := readFile(t, path); got != content {
+				t.Errorf("durable %v: read %q, want %q", durable, got, content)
+			}
+		}
+	}
+	checkNoTemporary(t, dir)
+
+	// Only the owner can read the file, the wallet cache holding a secret
+	if runtime.GOOS != "windows" {
+		info, err := os.Stat(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if perm := info.Mode().Perm(); perm != 0600 {
+			t.Errorf("permissions %v, want 0600", perm)
+		}
+	}
+
+	// A missing directory fails without creating anything
+	if err := WriteAtomic(filepath.Join(dir, "missing", "file"), []byte("x"), true); err == nil {
+		t.Error("no error writing into a missing directory")
+	}
+}
+
+func TestRename(t *testing.T) {
+	dir := t.TempDir()
+	oldpath, newpath := filepath.Join(dir, "old"), filepath.Join(dir, "new")
+	os.WriteFile(oldpath, []byte("old"), 0600)
+	os.WriteFile(newpath, []byte("replaced"), 0600)
+	if err := Rename(oldpath, newpath); err != nil {
+		t.Fatal(err)
+	}
+	if got := readFile(t, newpath); got != "old" {
+		t.Errorf("read %q", got)
+	}
+	if _, err := os.Stat(oldpath); !os.IsNotExist(err) {
+		t.Errorf("old path still there: %v", err)
+	}
+	// A missing file is not transient, so it fails at once
+	if err := Rename(oldpath, newpath); !os.IsNotExist(err) {
+		t.Errorf("missing file: %v", err)
+	}
+}
+
+func TestMove(t *testing.T) {
+	dir := t.TempDir()
+	payout := filepath.Join(dir, "payout.csv")
+	archive := filepath.Join(dir, "correctly-send")
+	os.WriteFile(payout, []byte("address 100\n"), 0644)
+	if err := os.MkdirAll(archive, 0755); err != nil {
+		t.Fatal(err)
+	}
+	moved := filepath.Join(archive, "payout.csv")
+	os.WriteFile(moved, []byte("earlier run\n"), 0644)
+	if err := Move(payout, moved); err != nil {
+		t.Fatal(err)
+	}
+	if got := readFile(t, moved); got != "address 100\n" {
+		t.Errorf("read %q", got)
+	}
+	if _, err := os.Stat(payout); !os.IsNotExist(err) {
+		t.Errorf("payout file still there: %v", err)
+	}
+}
+
+// TestCopyFile is the cross-volume path of Move: the content and the permissions are kept, the destination replaced
+func TestCopyFile(t *testing.T) {
+	dir := t.TempDir()
+	src, dst := filepath.Join(dir, "src"), filepath.Join(dir, "dst")
+	os.WriteFile(src, []byte("payout"), 0640)
+	os.WriteFile(dst, []byte("replaced"), 0600)
+	if err := copyFile(src, dst); err != nil {
+		t.Fatal(err)
+	}
+	if got := readFile(t, dst); got != "payout" {
+		t.Errorf("read %q", got)
+	}
+	if runtime.GOOS != "windows" {
+		if info, _ := os.Stat(dst); info.Mode().Perm() != 0640 {
+			t.Errorf("permissions %v, want 0640", info.Mode().Perm())
+		}
+	}
+	checkNoTemporary(t, dir)
+
+	if err := copyFile(filepath.Join(dir, "missing"), dst); !os.IsNotExist(err) {
+		t.Errorf("missing source: %v", err)
+	}
+	if got := readFile(t, dst); got != "payout" {
+		t.Errorf("destination changed by a failed copy: %q", got)
+	}
+}
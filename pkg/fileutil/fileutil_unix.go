@@ -0,0 +1,29 @@
+//go:build !windows
+
+package fileutil
+
+import (
+	"errors"
+	"os"
+	"syscall"
+)
+
+// transient reports whether a rename may succeed if tried again; Unix renames never fail for an open file
+func transient(err error) bool {
+	return false
+}
+
+// crossDevice reports whether a rename failed because both paths are not on the same file system
+func crossDevice(err error) bool {
+	return errors.Is(err, syscall.EXDEV)
+}
+
+// SyncDir syncs a directory, making the renames and creations in it durable
+func SyncDir(dir string) error {
+	d, err := os.Open(dir)
+	if err != nil {
+		return err
+	}
+	defer d.Close()
+	return d.Sync()
+}
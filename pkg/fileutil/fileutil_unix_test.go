@@ -0,0 +1,53 @@
+//go:build !windows
+
+package fileutil
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+)
+
+func TestUnixErrors(t *testing.T) {
+	crossed := &os.LinkError{Op: "rename", Old: "a", New: "b", Err: syscall.EXDEV}
+	if !crossDevice(crossed) || transient(crossed) {
+		t.Errorf("EXDEV: cross device %v, transient %v", crossDevice(crossed), transient(crossed))
+	}
+	busy := &os.LinkError{Op: "rename", Old: "a", New: "b", Err: syscall.EBUSY}
+	if crossDevice(busy) || transient(busy) {
+		t.Errorf("EBUSY: cross device %v, transient %v", crossDevice(busy), transient(busy))
+	}
+	if err := SyncDir(t.TempDir()); err != nil {
+		t.Error(err)
+	}
+	if err := SyncDir(filepath.Join(t.TempDir(), "missing")); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("missing directory: %v", err)
+	}
+}
+
+// TestMoveAcrossFileSystems moves a file from the temporary directory to /dev/shm, where the rename fails with EXDEV
+func TestMoveAcrossFileSystems(t *testing.T) {
+	other, err := os.MkdirTemp("/dev/shm", "fileutil-test")
+	if err != nil {
+		t.Skip("no /dev/shm:", err)
+	}
+	defer os.RemoveAll(other)
+	src, dst := filepath.Join(t.TempDir(), "payout.csv"), filepath.Join(other, "payout.csv")
+	os.WriteFile(src, []byte("address 100\n"), 0644)
+	if err := os.Link(src, dst); err == nil || !crossDevice(err) {
+		os.Remove(dst)
+		t.Skip("/dev/shm is on the file system of the temporary directory")
+	}
+
+	if err := Move(src, dst); err != nil {
+		t.Fatal(err)
+	}
+	if got := readFile(t, dst); got != "address 100\n" {
+		t.Errorf("read %q", got)
+	}
+	if _, err := os.Stat(src); !os.IsNotExist(err) {
+		t.Errorf("source still there: %v", err)
+	}
+}
@@ -0,0 +1,37 @@
+//go:build windows
+
+package fileutil
+
+import (
+	"errors"
+	"syscall"
+)
+
+// Windows error codes of a file another process holds open, see transient
+const (
+	errorAccessDenied     syscall.Errno = 5
+	errorNotSameDevice    syscall.Errno = 17
+	errorSharingViolation syscall.Errno = 32
+	errorLockViolation    syscall.Errno = 33
+)
+
+/*
+ * transient reports whether a rename may succeed if tried again
+ *
+ * A file an antivirus scanner or an indexer opened without sharing its
+ * deletion fails the rename with a sharing violation, or with an access
+ * denied while it is being closed, until the scan ends.
+ */
+func transient(err error) bool {
+	return errors.Is(err, errorSharingViolation) || errors.Is(err, errorAccessDenied) || errors.Is(err, errorLockViolation)
+}
+
+// crossDevice reports whether a rename failed because both paths are not on the same volume
+func crossDevice(err error) bool {
+	return errors.Is(err, errorNotSameDevice)
+}
+
+// SyncDir does nothing on Windows, where directories cannot be synced and NTFS journals the renames
+func SyncDir(dir string) error {
+	return nil
+}
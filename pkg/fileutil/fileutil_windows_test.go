@@ -0,0 +1,83 @@
+//go:build windows
+
+package fileutil
+
+import (
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+	"time"
+
+	"golang.org/x/sys/windows"
+)
+
+func TestWindowsErrors(t *testing.T) {
+	for _, errno := range []syscall.Errno{errorSharingViolation, errorAccessDenied, errorLockViolation} {
+		err := &os.LinkError{Op: "rename", Old: "a", New: "b", Err: errno}
+		if !transient(err) || crossDevice(err) {
+			t.Errorf("%v: transient %v, cross device %v", errno, transient(err), crossDevice(err))
+		}
+	}
+	crossed := &os.LinkError{Op: "rename", Old: "a", New: "b", Err: errorNotSameDevice}
+	if !crossDevice(crossed) || transient(crossed) {
+		t.Errorf("not same device: cross device %v, transient %v", crossDevice(crossed), transient(crossed))
+	}
+	if err := SyncDir(filepath.Join(t.TempDir(), "missing")); err != nil {
+		t.Errorf("SyncDir: %v", err)
+	}
+}
+
+// openUnshared opens path without sharing it, as an antivirus scanner does, and closes it after hold
+func openUnshared(t *testing.T, path string, hold time.Duration) <-chan struct{} {
+	t.Helper()
+	name, err := windows.UTF16PtrFromString(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	handle, err := windows.CreateFile(name, windows.GENERIC_READ, 0, nil, windows.OPEN_EXISTING, windows.FILE_ATTRIBUTE_NORMAL, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	closed := make(chan struct{})
+	go func() {
+		time.Sleep(hold)
+		windows.CloseHandle(handle)
+		close(closed)
+	}()
+	return closed
+}
+
+// TestRenameWhileOpen replaces a file another handle holds open for a moment, which fails with a sharing violation until it is closed
+func TestRenameWhileOpen(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "wallet-cache.json")
+	os.WriteFile(path, []byte("old"), 0600)
+	closed := openUnshared(t, path, 200*time.Millisecond)
+	if err := WriteAtomic(path, []byte("new"), true); err != nil {
+		t.Fatal(err)
+	}
+	<-closed
+	if got := readFile(t, path); got != "new" {
+		t.Errorf("read %q", got)
+	}
+	checkNoTemporary(t, dir)
+}
+
+// TestRenameGivesUp checks a file held open longer than RenameTimeout fails the rename with the sharing violation
+func TestRenameGivesUp(t *testing.T) {
+	dir := t.TempDir()
+	src, dst := filepath.Join(dir, "src"), filepath.Join(dir, "dst")
+	os.WriteFile(src, []byte("src"), 0600)
+	os.WriteFile(dst, []byte("dst"), 0600)
+	closed := openUnshared(t, dst, RenameTimeout+time.Second)
+	start := time.Now()
+	err := Rename(src, dst)
+	if err == nil || !transient(err) {
+		t.Errorf("rename of a held file: %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > RenameTimeout+500*time.Millisecond {
+		t.Errorf("gave up after %v", elapsed)
+	}
+	<-closed
+}
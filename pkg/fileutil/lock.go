@@ -0,0 +1,47 @@
+package fileutil
+
+import (
+	"fmt"
+	"os"
+)
+
+/*
+ * FileLock is an advisory lock held on a lock file, e.g. next to a wallet
+ * cache so two runs never sign from it at once
+ *
+ * The lock belongs to the open file: it is released by Unlock, or by the
+ * system when the process exits, however it exits. The lock file itself is
+ * left in place, removing it would let a third process lock a new file
+ * while the second still waits on the old one.
+ */
+type FileLock struct {
+	file *os.File
+	path string
+}
+
+// Lock takes the lock of path, creating the file if needed; it fails at once with an error wrapping ErrLocked if another process holds it
+func Lock(path string) (*FileLock, error) {
+	file, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0600)
+	if err != nil {
+		return nil, err
+	}
+	if err := lockFile(file); err != nil {
+		file.Close()
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return &FileLock{file: file, path: path}, nil
+}
+
+// Path returns the lock file
+func (l *FileLock) Path() string {
+	return l.path
+}
+
+// Unlock releases the lock
+func (l *FileLock) Unlock() error {
+	err := unlockFile(l.file)
+	if closeErr := l.file.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
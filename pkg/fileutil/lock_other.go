@@ -0,0 +1,18 @@
+//go:build !(darwin || dragonfly || freebsd || linux || netbsd || openbsd || windows)
+
+package fileutil
+
+import (
+	"errors"
+	"os"
+)
+
+// lockFile fails on systems without a lock the package knows how to take
+func lockFile(file *os.File) error {
+	return errors.ErrUnsupported
+}
+
+// unlockFile does nothing, no lock being ever taken
+func unlockFile(file *os.File) error {
+	return nil
+}
@@ -0,0 +1,94 @@
+package fileutil
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+)
+
+// LOCK_HELPER_ENV names the lock file TestLockHelper holds, when the test binary runs as the other process
+const LOCK_HELPER_ENV = "FILEUTIL_LOCK_HELPER"
+
+// TestLockHelper is the other process of TestLockAcrossProcesses: it takes the lock, says so and holds it until killed
+func TestLockHelper(t *testing.T) {
+	path := os.Getenv(LOCK_HELPER_ENV)
+	if path == "" {
+		t.Skip("only run by TestLockAcrossProcesses")
+	}
+	if _, err := Lock(path); err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+	fmt.Println("locked")
+	select {}
+}
+
+// lockOrSkip takes the lock of path, skipping the test where the system has no lock
+func lockOrSkip(t *testing.T, path string) *FileLock {
+	t.Helper()
+	lock, err := Lock(path)
+	if errors.Is(err, errors.ErrUnsupported) {
+		t.Skip(err)
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	return lock
+}
+
+func TestLock(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "wallet-cache.json.lock")
+	lock := lockOrSkip(t, path)
+	if lock.Path() != path {
+		t.Errorf("path %s", lock.Path())
+	}
+	// The lock belongs to the open file, so a second one is refused even in the same process
+	if _, err := Lock(path); !errors.Is(err, ErrLocked) {
+		t.Errorf("second lock: %v", err)
+	}
+	if err := lock.Unlock(); err != nil {
+		t.Fatal(err)
+	}
+	again := lockOrSkip(t, path)
+	again.Unlock()
+	// The lock file stays, see FileLock
+	if _, err := os.Stat(path); err != nil {
+		t.Error(err)
+	}
+}
+
+// TestLockAcrossProcesses checks a lock held by another process is refused, and released by the system once it exits
+func TestLockAcrossProcesses(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "wallet-cache.json.lock")
+	lockOrSkip(t, path).Unlock()
+
+	cmd := exec.Command(os.Args[0], "-test.run=^TestLockHelper$")
+	cmd.Env = append(os.Environ(), LOCK_HELPER_ENV+"="+path)
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := cmd.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer cmd.Process.Kill()
+	if line, _ := bufio.NewReader(stdout).ReadString('\n'); line != "locked\n" {
+		t.Fatalf("helper printed %q", line)
+	}
+
+	if _, err := Lock(path); !errors.Is(err, ErrLocked) {
+		t.Errorf("lock held by another process: %v", err)
+	}
+	// Killed, the helper never unlocks: the system releases its lock
+	cmd.Process.Kill()
+	cmd.Wait()
+	lock, err := Lock(path)
+	if err != nil {
+		t.Fatalf("lock of a killed process: %v", err)
+	}
+	lock.Unlock()
+}
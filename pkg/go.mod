@@ -0,0 +1,11 @@
+module github.com/NickP005/Vindax-MCM-tools/pkg
+
+go 1.22.5
+
+require (
+	github.com/NickP005/go_mcminterface v1.1.1
+	github.com/btcsuite/btcutil v1.0.2
+	github.com/sigurn/crc16 v0.0.0-20240131213347-83fcde1e29d1
+	golang.org/x/crypto v0.33.0
+	golang.org/x/sys v0.30.0
+)
@@ -0,0 +1,110 @@
+/*
+ * Package mcmaddr encodes and validates MCM 3.0 base58 addresses.
+ *
+ * An address is the 20 bytes tag followed by its CRC16-XMODEM checksum
+ * stored little-endian, 22 bytes in total, encoded in base58.
+ */
+package mcmaddr
+
+import (
+	"fmt"
+
+	"github.com/btcsuite/btcutil/base58"
+	"github.com/sigurn/crc16"
+)
+
+const (
+	// TagLength is the length in bytes of an address tag
+	TagLength = 20
+	// EncodedLength is the length in bytes of a decoded base58 address: tag + 2 bytes checksum
+	EncodedLength = TagLength + 2
+	// MaxInputLength bounds the base58 strings that are decoded at all
+	MaxInputLength = 255
+)
+
+var crcTable = crc16.MakeTable(crc16.CRC16_XMODEM)
+
+/*
+ * LengthError is returned for a tag or a decoded address of the wrong length
+ *
+ * Length is the decoded length, or the length of the input itself when it is
+ * longer than MaxInputLength and is not decoded at all.
+ */
+type LengthError struct {
+	Length   int
+	Expected int
+}
+
+func (e *LengthError) Error() string {
+	return fmt.Sprintf("invalid length: got %d, expected %d bytes", e.Length, e.Expected)
+}
+
+// ChecksumError is returned when the stored checksum of an address does not match its tag
+type ChecksumError struct {
+	Stored   uint16
+	Computed uint16
+}
+
+func (e *ChecksumError) Error() string {
+	return fmt.Sprintf("invalid checksum: stored 0x%04x, computed 0x%04x", e.Stored, e.Computed)
+}
+
+// Checksum computes the CRC16-XMODEM of a tag
+func Checksum(tag []byte) uint16 {
+	return crc16.Checksum(tag, crcTable)
+}
+
+/*
+ * EncodeTag encodes a 20 bytes tag as a base58 address with checksum
+ *
+ * Returns a *LengthError if tag is not 20 bytes.
+ */
+func EncodeTag(tag []byte) (string, error) {
+	if len(tag) != TagLength {
+		return "", &LengthError{Length: len(tag), Expected: TagLength}
+	}
+
+	combined := make([]byte, EncodedLength)
+	copy(combined, tag)
+
+	// Append the checksum in little-endian
+	crc := Checksum(tag)
+	combined[20] = byte(crc & 0xFF)
+	combined[21] = byte((crc >> 8) & 0xFF)
+
+	return base58.Encode(combined), nil
+}
+
+/*
+ * Validate checks the length and the checksum of a base58 address
+ *
+ * Returns nil for a valid address, a *LengthError or a *ChecksumError otherwise.
+ */
+func Validate(addr string) error {
+	_, err := DecodeTag(addr)
+	return err
+}
+
+/*
+ * DecodeTag validates a base58 address and returns its 20 bytes tag
+ *
+ * Returns a *LengthError if the address does not decode to 22 bytes and a
+ * *ChecksumError if the stored checksum does not match the tag.
+ */
+func DecodeTag(addr string) ([]byte, error) {
+	if len(addr) > MaxInputLength {
+		return nil, &LengthError{Length: len(addr), Expected: EncodedLength}
+	}
+
+	decoded := base58.Decode(addr)
+	if len(decoded) != EncodedLength {
+		return nil, &LengthError{Length: len(decoded), Expected: EncodedLength}
+	}
+
+	tag := decoded[:TagLength]
+	stored := uint16(decoded[21])<<8 | uint16(decoded[20])
+	if computed := Checksum(tag); stored != computed {
+		return nil, &ChecksumError{Stored: stored, Computed: computed}
+	}
+	return tag, nil
+}
@@ -0,0 +1,134 @@
+package mcmaddr
+
+import (
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math/rand"
+	"strings"
+	"testing"
+
+	"github.com/btcsuite/btcutil/base58"
+	"github.com/sigurn/crc16"
+)
+
+/*
+ * vectors pin the base58 form and the checksum of known tags
+ *
+ * The 9f81... address is the one of the README; the others were produced by
+ * the copies of AddrTagToBase58 this package replaced, see legacyEncode.
+ */
+var vectors = []struct {
+	hex      string
+	base58   string
+	checksum uint16
+}{
+	{"0000000000000000000000000000000000000000", "1111111111111111111111", 0x0000},
+	{"ffffffffffffffffffffffffffffffffffffffff", "2CUupRZfa1aCgvwLsbRzNpuQJuZy18W", 0xb352},
+	{"9f810c2447a76e93b17ebff96c0b29952e4355f1", "kHtV35ttVpyiH42FePCiHo2iFmcJS3", 0xc89a},
+	{"0000000000000000000000000000000000000001", "1111111111111111111Nzs", 0x1021},
+	{"0102030405060708090a0b0c0d0e0f1011121314", "GsChQR2U32pvwJcDNPoYHhGXL1gD7", 0xead3},
+	{"d334c67417ff53dd17c409cf65a835b2585e12ee", "zKmqsN42eD8CHPD2TkiYweHdBiVXSa", 0x333a},
+	{"53b6e9a6cb0a73120e5193bf1a45a91c2c97cfb1", "Pipa5xMCzhnkoTXFv8FV6skzSJxptW", 0x4f1a},
+	{"2774a6629e9e3910e79512cebe714f14dfd345d1", "Bi75AgfEnqTQCvaQb9seMRjisbKjtE", 0xf37c},
+}
+
+// legacyEncode is AddrTagToBase58 as tool-1, tool-4 and wallet-tool each had it before this package
+func legacyEncode(tag []byte) (string, error) {
+	if len(tag) != 20 {
+		return "", fmt.Errorf("invalid address tag length")
+	}
+	combined := make([]byte, 22)
+	copy(combined, tag)
+	table := crc16.MakeTable(crc16.CRC16_XMODEM)
+	crc := crc16.Checksum(tag, table)
+	combined[20] = byte(crc & 0xFF)
+	combined[21] = byte((crc >> 8) & 0xFF)
+	return base58.Encode(combined), nil
+}
+
+// legacyValidate is ValidateBase58Tag as it was copied across the tools
+func legacyValidate(addr string) bool {
+	decoded := base58.Decode(addr)
+	if len(decoded) != 22 {
+		return false
+	}
+	storedCsum := uint16(decoded[21])<<8 | uint16(decoded[20])
+	table := crc16.MakeTable(crc16.CRC16_XMODEM)
+	return storedCsum == crc16.Checksum(decoded[:20], table)
+}
+
+func TestVectors(t *testing.T) {
+	for _, v := range vectors {
+		tag, _ := hex.DecodeString(v.hex)
+		if got := Checksum(tag); got != v.checksum {
+			t.Errorf("%s: checksum 0x%04x, want 0x%04x", v.hex, got, v.checksum)
+		}
+		encoded, err := EncodeTag(tag)
+		if err != nil || encoded != v.base58 {
+			t.Errorf("%s: encoded %q, %v; want %q", v.hex, encoded, err, v.base58)
+		}
+		decoded, err := DecodeTag(v.base58)
+		if err != nil || hex.EncodeToString(decoded) != v.hex {
+			t.Errorf("%s: decoded %x, %v", v.base58, decoded, err)
+		}
+		if err := Validate(v.base58); err != nil {
+			t.Errorf("%s: %v", v.base58, err)
+		}
+	}
+}
+
+// TestMatchesLegacy proves the package gives byte-identical results to the copies it replaced
+func TestMatchesLegacy(t *testing.T) {
+	rng := rand.New(rand.NewSource(1645))
+	tag := make([]byte, TagLength)
+	for i := 0; i < 5000; i++ {
+		rng.Read(tag)
+		want, _ := legacyEncode(tag)
+		got, err := EncodeTag(tag)
+		if err != nil || got != want {
+			t.Fatalf("%x: encoded %q, %v; legacy %q", tag, got, err, want)
+		}
+		if Validate(got) != nil || !legacyValidate(got) {
+			t.Fatalf("%s does not validate", got)
+		}
+
+		// Any one character changed validates in both or in neither
+		altered := []byte(got)
+		altered[rng.Intn(len(altered))] = Alphabet[rng.Intn(len(Alphabet))]
+		if (Validate(string(altered)) == nil) != legacyValidate(string(altered)) {
+			t.Fatalf("%s: validity differs from the legacy validation", altered)
+		}
+	}
+
+	for _, length := range []int{0, 19, 21, 32} {
+		tag := make([]byte, length)
+		_, legacyErr := legacyEncode(tag)
+		_, err := EncodeTag(tag)
+		var lengthErr *LengthError
+		if legacyErr == nil || !errors.As(err, &lengthErr) || lengthErr.Length != length || lengthErr.Expected != TagLength {
+			t.Errorf("%d bytes tag: %v", length, err)
+		}
+	}
+}
+
+func TestDecodeErrors(t *testing.T) {
+	v := vectors[2]
+	wrongSum := v.base58[:len(v.base58)-1] + "4"
+
+	var checksumErr *ChecksumError
+	if err := Validate(wrongSum); !errors.As(err, &checksumErr) || checksumErr.Computed != v.checksum || checksumErr.Stored == v.checksum {
+		t.Errorf("%s: %v", wrongSum, err)
+	}
+	for _, input := range []string{"", "1", v.base58[:10], v.base58 + "1111", strings.Repeat("z", MaxInputLength+1)} {
+		var lengthErr *LengthError
+		if err := Validate(input); !errors.As(err, &lengthErr) || lengthErr.Expected != EncodedLength {
+			t.Errorf("%q: %v", input, err)
+		}
+	}
+	// An input too long to decode reports its own length
+	var lengthErr *LengthError
+	if _, err := DecodeTag(strings.Repeat("z", 300)); !errors.As(err, &lengthErr) || lengthErr.Length != 300 {
+		t.Errorf("long input: %v", err)
+	}
+}
@@ -0,0 +1,104 @@
+package mcmaddr
+
+import (
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+// Alphabet is the base58 alphabet of MCM addresses
+const Alphabet = "123456789ABCDEFGHJKLMNPQRSTUVWXYZabcdefghijkmnopqrstuvwxyz"
+
+/*
+ * AlphabetError is returned for a character outside the hex or base58
+ * alphabet of the input
+ *
+ * Offset is the byte offset of the character in the input as given, the
+ * 0x prefix and any leading whitespace included.
+ */
+type AlphabetError struct {
+	Char   rune
+	Offset int
+}
+
+func (e *AlphabetError) Error() string {
+	return fmt.Sprintf("invalid character %q at offset %d", e.Char, e.Offset)
+}
+
+// OddLengthError is returned for 0x prefixed hex with an odd number of digits, which is no whole number of bytes
+type OddLengthError struct {
+	Digits int
+}
+
+func (e *OddLengthError) Error() string {
+	return fmt.Sprintf("odd number of hex digits: got %d, expected %d", e.Digits, TagLength*2)
+}
+
+/*
+ * Normalize parses an address in any supported representation and returns
+ * its canonical 20 bytes tag
+ *
+ * Accepted representations, after trimming surrounding whitespace:
+ * - hex: 40 characters, any case, optional 0x or 0X prefix
+ * - base58: 22 bytes with a valid CRC16-XMODEM checksum
+ *
+ * An input with a 0x prefix, or made of 40 hex characters, is read as hex,
+ * anything else as base58. Errors are a *LengthError, an *OddLengthError,
+ * an *AlphabetError or a *ChecksumError.
+ */
+func Normalize(input string) ([TagLength]byte, error) {
+	var tag [TagLength]byte
+	s := strings.TrimSpace(input)
+	// offset maps an index of s back to the input
+	offset := len(input) - len(strings.TrimLeftFunc(input, unicode.IsSpace))
+
+	prefixed := strings.HasPrefix(s, "0x") || strings.HasPrefix(s, "0X")
+	if prefixed || (len(s) == TagLength*2 && isHex(s)) {
+		if prefixed {
+			s = s[2:]
+			offset += 2
+		}
+		for i, c := range s {
+			if !strings.ContainsRune("0123456789abcdefABCDEF", c) {
+				return tag, &AlphabetError{Char: c, Offset: offset + i}
+			}
+		}
+		if len(s)%2 != 0 {
+			return tag, &OddLengthError{Digits: len(s)}
+		}
+		if len(s) != TagLength*2 {
+			return tag, &LengthError{Length: len(s) / 2, Expected: TagLength}
+		}
+		hex.Decode(tag[:], []byte(s))
+		return tag, nil
+	}
+
+	for i, c := range s {
+		if !strings.ContainsRune(Alphabet, c) {
+			return tag, &AlphabetError{Char: c, Offset: offset + i}
+		}
+	}
+	decoded, err := DecodeTag(s)
+	if err != nil {
+		return tag, err
+	}
+	copy(tag[:], decoded)
+	return tag, nil
+}
+
+// ToHex renders a tag as 40 lowercase hex characters without prefix
+func ToHex(tag [TagLength]byte) string {
+	return hex.EncodeToString(tag[:])
+}
+
+// To58 renders a tag as a base58 address with checksum
+func To58(tag [TagLength]byte) string {
+	addr, _ := EncodeTag(tag[:])
+	return addr
+}
+
+func isHex(s string) bool {
+	_, err := hex.DecodeString(s)
+	return err == nil
+}
@@ -0,0 +1,190 @@
+package mcmaddr
+
+import (
+	"encoding/hex"
+	"errors"
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+func TestNormalizeRepresentations(t *testing.T) {
+	for _, v := range vectors {
+		for _, input := range []string{
+			v.hex,
+			strings.ToUpper(v.hex),
+			"0x" + v.hex,
+			"0X" + strings.ToUpper(v.hex),
+			" \t" + v.base58 + "\n",
+			v.base58,
+		} {
+			tag, err := Normalize(input)
+			if err != nil || ToHex(tag) != v.hex || To58(tag) != v.base58 {
+				t.Errorf("%q: got %x, %v", input, tag, err)
+			}
+		}
+	}
+}
+
+func TestNormalizeErrors(t *testing.T) {
+	v := vectors[2]
+	for _, tc := range []struct {
+		name  string
+		input string
+		check func(error) bool
+	}{
+		{"prefixed short hex", "0x" + v.hex[:38], func(err error) bool {
+			var e *LengthError
+			return errors.As(err, &e) && e.Length == 19 && e.Expected == TagLength
+		}},
+		{"prefixed long hex", "0x" + v.hex + "00", func(err error) bool {
+			var e *LengthError
+			return errors.As(err, &e) && e.Length == 21
+		}},
+		{"prefixed odd hex", "0x" + v.hex[:39], func(err error) bool {
+			var e *OddLengthError
+			return errors.As(err, &e) && e.Digits == 39
+		}},
+		{"prefixed one digit", "0xa", func(err error) bool {
+			var e *OddLengthError
+			return errors.As(err, &e) && e.Digits == 1
+		}},
+		{"prefix only", "0x", func(err error) bool {
+			var e *LengthError
+			return errors.As(err, &e) && e.Length == 0
+		}},
+		// The offsets count the prefix and the leading whitespace
+		{"prefixed bad digit", "0x" + v.hex[:10] + "g" + v.hex[11:], func(err error) bool {
+			var e *AlphabetError
+			return errors.As(err, &e) && e.Char == 'g' && e.Offset == 12
+		}},
+		{"spaced prefixed bad digit", "  0X" + "z" + v.hex[1:], func(err error) bool {
+			var e *AlphabetError
+			return errors.As(err, &e) && e.Char == 'z' && e.Offset == 4
+		}},
+		{"base58 bad character", v.base58[:7] + "0" + v.base58[8:], func(err error) bool {
+			var e *AlphabetError
+			return errors.As(err, &e) && e.Char == '0' && e.Offset == 7
+		}},
+		{"spaced base58 bad character", "\t " + v.base58[:3] + "I" + v.base58[4:], func(err error) bool {
+			var e *AlphabetError
+			return errors.As(err, &e) && e.Char == 'I' && e.Offset == 5
+		}},
+		{"base58 checksum", v.base58[:len(v.base58)-1] + "4", func(err error) bool {
+			var e *ChecksumError
+			return errors.As(err, &e)
+		}},
+		{"base58 short", v.base58[:12], func(err error) bool {
+			var e *LengthError
+			return errors.As(err, &e) && e.Expected == EncodedLength
+		}},
+		// 39 hex digits without prefix are not hex: they read as base58, where 0 is not a character
+		{"unprefixed odd hex", v.hex[:39], func(err error) bool {
+			var e *AlphabetError
+			return errors.As(err, &e) && e.Char == '0' && e.Offset == 4
+		}},
+		{"empty", "", func(err error) bool {
+			var e *LengthError
+			return errors.As(err, &e)
+		}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			if _, err := Normalize(tc.input); !tc.check(err) {
+				t.Errorf("%q: unexpected error %v", tc.input, err)
+			}
+		})
+	}
+}
+
+func TestOffsetPointsAtTheCharacter(t *testing.T) {
+	for _, input := range []string{"0x12345z", " 0xabc?", "abc0def", "\n  12O4"} {
+		_, err := Normalize(input)
+		var e *AlphabetError
+		if !errors.As(err, &e) {
+			t.Fatalf("%q: %v", input, err)
+		}
+		if r, _ := utf8.DecodeRuneInString(input[e.Offset:]); r != e.Char {
+			t.Errorf("%q: offset %d holds %q, not %q", input, e.Offset, r, e.Char)
+		}
+	}
+}
+
+func FuzzNormalize(f *testing.F) {
+	for _, v := range vectors {
+		f.Add(v.hex)
+		f.Add("0x" + v.hex)
+		f.Add(v.base58)
+	}
+	f.Add("0x123")
+	f.Add(" 0xz ")
+	f.Add("")
+	f.Add("\xfa")
+	f.Fuzz(func(t *testing.T, input string) {
+		tag, err := Normalize(input)
+		if err != nil {
+			var alphabetErr *AlphabetError
+			var lengthErr *LengthError
+			var oddErr *OddLengthError
+			var checksumErr *ChecksumError
+			switch {
+			case errors.As(err, &alphabetErr):
+				if alphabetErr.Offset < 0 || alphabetErr.Offset >= len(input) {
+					t.Fatalf("%q: offset %d out of the input", input, alphabetErr.Offset)
+				}
+				// A byte that is not UTF-8 is reported as utf8.RuneError
+				if r, _ := utf8.DecodeRuneInString(input[alphabetErr.Offset:]); r != alphabetErr.Char {
+					t.Fatalf("%q: offset %d does not hold %q", input, alphabetErr.Offset, alphabetErr.Char)
+				}
+			case errors.As(err, &oddErr):
+				if oddErr.Digits%2 == 0 {
+					t.Fatalf("%q: even digit count %d", input, oddErr.Digits)
+				}
+			case errors.As(err, &lengthErr), errors.As(err, &checksumErr):
+			default:
+				t.Fatalf("%q: untyped error %v", input, err)
+			}
+			return
+		}
+		// A normalized tag renders to forms that normalize back to it
+		for _, form := range []string{ToHex(tag), "0x" + ToHex(tag), To58(tag)} {
+			again, err := Normalize(form)
+			if err != nil || again != tag {
+				t.Fatalf("%q: %q normalizes to %x, %v; want %x", input, form, again, err, tag)
+			}
+		}
+	})
+}
+
+func FuzzEncodeDecode(f *testing.F) {
+	for _, v := range vectors {
+		tag, _ := hex.DecodeString(v.hex)
+		f.Add(tag, v.base58)
+	}
+	f.Fuzz(func(t *testing.T, tag []byte, addr string) {
+		encoded, err := EncodeTag(tag)
+		if len(tag) != TagLength {
+			var lengthErr *LengthError
+			if !errors.As(err, &lengthErr) {
+				t.Fatalf("%d bytes tag: %v", len(tag), err)
+			}
+		} else {
+			decoded, err := DecodeTag(encoded)
+			if err != nil || hex.EncodeToString(decoded) != hex.EncodeToString(tag) {
+				t.Fatalf("%x: %s decodes to %x, %v", tag, encoded, decoded, err)
+			}
+		}
+
+		// Whatever decodes encodes back to the same string, unless it was not canonical base58
+		decoded, err := DecodeTag(addr)
+		if err != nil {
+			return
+		}
+		again, err := EncodeTag(decoded)
+		if err != nil {
+			t.Fatalf("%q decodes to a %d bytes tag: %v", addr, len(decoded), err)
+		}
+		if again2, err := DecodeTag(again); err != nil || hex.EncodeToString(again2) != hex.EncodeToString(decoded) {
+			t.Fatalf("%q: re-encoded %q does not decode to the same tag", addr, again)
+		}
+	})
+}
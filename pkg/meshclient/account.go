@@ -0,0 +1,30 @@
+package meshclient
+
+import (
+	"github.com/NickP005/Vindax-MCM-tools/pkg/mcmaddr"
+)
+
+// AccountFromTag returns the account identifier of a 20 bytes tag, or a *mcmaddr.LengthError
+func AccountFromTag(tag []byte) (AccountIdentifier, error) {
+	if len(tag) != mcmaddr.TagLength {
+		return AccountIdentifier{}, &mcmaddr.LengthError{Length: len(tag), Expected: mcmaddr.TagLength}
+	}
+	var fixed [mcmaddr.TagLength]byte
+	copy(fixed[:], tag)
+	return AccountIdentifier{Address: "0x" + mcmaddr.ToHex(fixed)}, nil
+}
+
+/*
+ * ParseAccount returns the account identifier of an address given as hex
+ * (with or without 0x) or base58
+ *
+ * The address is normalized by mcmaddr.Normalize, whose typed errors are
+ * returned as is.
+ */
+func ParseAccount(address string) (AccountIdentifier, error) {
+	tag, err := mcmaddr.Normalize(address)
+	if err != nil {
+		return AccountIdentifier{}, err
+	}
+	return AccountFromTag(tag[:])
+}
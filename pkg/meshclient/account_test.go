@@ -0,0 +1,76 @@
+package meshclient
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/NickP005/Vindax-MCM-tools/pkg/mcmaddr"
+)
+
+// testTag is a tag whose base58 form has no leading 1
+func testTag() [mcmaddr.TagLength]byte {
+	var tag [mcmaddr.TagLength]byte
+	for i := range tag {
+		tag[i] = byte(0xf0 - i)
+	}
+	return tag
+}
+
+func TestParseAccount(t *testing.T) {
+	tag := testTag()
+	want := `{"address":"0x` + mcmaddr.ToHex(tag) + `"}`
+
+	fromTag, err := AccountFromTag(tag[:])
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, input := range []string{
+		mcmaddr.ToHex(tag),
+		"0x" + mcmaddr.ToHex(tag),
+		"0X" + strings.ToUpper(mcmaddr.ToHex(tag)),
+		mcmaddr.To58(tag),
+	} {
+		account, err := ParseAccount(input)
+		if err != nil {
+			t.Errorf("%q: %v", input, err)
+			continue
+		}
+		encoded, _ := json.Marshal(account)
+		if account != fromTag || string(encoded) != want {
+			t.Errorf("%q: %s, want %s", input, encoded, want)
+		}
+	}
+}
+
+func TestAccountErrors(t *testing.T) {
+	var lengthErr *mcmaddr.LengthError
+	if _, err := AccountFromTag(make([]byte, 32)); !errors.As(err, &lengthErr) || lengthErr.Length != 32 || lengthErr.Expected != mcmaddr.TagLength {
+		t.Errorf("32 bytes tag: %v", err)
+	}
+	if _, err := AccountFromTag(nil); !errors.As(err, &lengthErr) || lengthErr.Length != 0 {
+		t.Errorf("nil tag: %v", err)
+	}
+	if _, err := ParseAccount("0x" + strings.Repeat("ab", 19)); !errors.As(err, &lengthErr) || lengthErr.Length != 19 {
+		t.Errorf("19 bytes hex: %v", err)
+	}
+	var oddErr *mcmaddr.OddLengthError
+	if _, err := ParseAccount("0x" + strings.Repeat("a", 39)); !errors.As(err, &oddErr) {
+		t.Errorf("odd hex: %v", err)
+	}
+	var alphabetErr *mcmaddr.AlphabetError
+	if _, err := ParseAccount("0x" + strings.Repeat("g", 40)); !errors.As(err, &alphabetErr) {
+		t.Errorf("bad digit: %v", err)
+	}
+	var checksumErr *mcmaddr.ChecksumError
+	mistyped := []byte(mcmaddr.To58(testTag()))
+	last := len(mistyped) - 1
+	mistyped[last] = map[bool]byte{true: '2', false: '3'}[mistyped[last] != '2']
+	if _, err := ParseAccount(string(mistyped)); !errors.As(err, &checksumErr) {
+		t.Errorf("mistyped base58: %v", err)
+	}
+	if _, err := ParseAccount(""); err == nil {
+		t.Error("empty address accepted")
+	}
+}
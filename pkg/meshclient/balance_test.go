@@ -0,0 +1,33 @@
+package meshclient_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/NickP005/Vindax-MCM-tools/pkg/meshclient"
+	"github.com/NickP005/Vindax-MCM-tools/pkg/meshmock"
+)
+
+// TestAccountBalanceCases tells an unknown account, an account holding 0 and a failed lookup apart on the mock
+func TestAccountBalanceCases(t *testing.T) {
+	mock := meshmock.New()
+	defer mock.Close()
+	client := meshclient.NewMeshAPIClient(mock.URL(), nil)
+	ctx := context.Background()
+	unknown, empty := make([]byte, 20), make([]byte, 20)
+	unknown[0], empty[0] = 0x0b, 0x0e
+	mock.SetAccount(empty, "", 0)
+
+	balance, err := client.AccountBalance(ctx, unknown)
+	if value, _ := balance.Value(); err != nil || balance.Found || value != 0 {
+		t.Errorf("unknown account: %+v, %v", balance, err)
+	}
+	balance, err = client.AccountBalance(ctx, empty)
+	if value, _ := balance.Value(); err != nil || !balance.Found || value != 0 {
+		t.Errorf("account holding 0: %+v, %v", balance, err)
+	}
+	mock.Fail("/account/balance", meshmock.Fault{Status: 502})
+	if balance, err := client.AccountBalance(ctx, empty); err == nil {
+		t.Errorf("failed lookup: %+v", balance)
+	}
+}
@@ -0,0 +1,78 @@
+package meshclient
+
+import (
+	"context"
+	"errors"
+	"sync"
+)
+
+// DefaultBatchConcurrency bounds the requests in flight of BatchResolveTags
+const DefaultBatchConcurrency = 8
+
+// SetBatchConcurrency bounds the requests in flight of BatchResolveTags (<= 0 uses DefaultBatchConcurrency)
+func (c *MeshAPIClient) SetBatchConcurrency(n int) {
+	c.batchConcurrency = n
+}
+
+/*
+ * BatchResolveTags resolves many tags, each identical tag only once
+ *
+ * Parameters:
+ * - ctx: cancels the lookups not yet made
+ * - tags: the tags to resolve, duplicates allowed
+ *
+ * The Mesh API has no batch method, so tag_resolve is called per distinct
+ * tag with at most SetBatchConcurrency requests in flight, each going
+ * through the client's retry policy and hooks. A failed lookup is reported
+ * in its TagResolution and does not stop the others.
+ *
+ * Returns a resolution for every distinct tag, and ctx's error if it was
+ * canceled before all lookups were made.
+ */
+func (c *MeshAPIClient) BatchResolveTags(ctx context.Context, tags [][20]byte) (map[[20]byte]TagResolution, error) {
+	concurrency := c.batchConcurrency
+	if concurrency <= 0 {
+		concurrency = DefaultBatchConcurrency
+	}
+
+	results := make(map[[20]byte]TagResolution, len(tags))
+	seen := make(map[[20]byte]bool, len(tags))
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	slots := make(chan struct{}, concurrency)
+	for _, tag := range tags {
+		if seen[tag] {
+			continue
+		}
+		seen[tag] = true
+
+		select {
+		case slots <- struct{}{}:
+		case <-ctx.Done():
+		}
+		if err := ctx.Err(); err != nil {
+			mu.Lock()
+			results[tag] = TagResolution{Err: err}
+			mu.Unlock()
+			continue
+		}
+		wg.Add(1)
+		go func(tag [20]byte) {
+			defer wg.Done()
+			defer func() { <-slots }()
+			resolution, err := c.ResolveTag(ctx, tag[:])
+			if err != nil && !errors.Is(err, ErrTagNotFound) {
+				resolution.Err = err
+			}
+			mu.Lock()
+			results[tag] = resolution
+			mu.Unlock()
+		}(tag)
+	}
+	wg.Wait()
+
+	if err := ctx.Err(); err != nil {
+		return results, err
+	}
+	return results, nil
+}
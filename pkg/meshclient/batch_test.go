@@ -0,0 +1,143 @@
+package meshclient
+
+import (
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+/*
+ * resolveServer answers tag_resolve: tags starting with 0xee are unknown,
+ * those starting with 0xff fail, and the others are found with their first
+ * byte as amount; it counts the lookups of each tag and the most in flight
+ */
+func resolveServer(t *testing.T) (*MeshAPIClient, func(tag string) int, *atomic.Int32) {
+	t.Helper()
+	var mu sync.Mutex
+	lookups := make(map[string]int)
+	var inFlight, peak atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/call" {
+			http.NotFound(w, r)
+			return
+		}
+		n := inFlight.Add(1)
+		defer inFlight.Add(-1)
+		for p := peak.Load(); n > p && !peak.CompareAndSwap(p, n); p = peak.Load() {
+		}
+		time.Sleep(5 * time.Millisecond)
+
+		var request struct {
+			Parameters map[string]string `json:"parameters"`
+		}
+		json.NewDecoder(r.Body).Decode(&request)
+		tag := strings.TrimPrefix(request.Parameters["tag"], "0x")
+		mu.Lock()
+		lookups[tag]++
+		mu.Unlock()
+		switch {
+		case strings.HasPrefix(tag, "ee"):
+			io.WriteString(w, `{"result":{}}`)
+		case strings.HasPrefix(tag, "ff"):
+			w.WriteHeader(http.StatusInternalServerError)
+			io.WriteString(w, `{"code":2,"message":"internal error","retriable":true}`)
+		default:
+			first, _ := hex.DecodeString(tag[:2])
+			fmt.Fprintf(w, `{"result":{"address":"0x%s%s","amount":%d}}`, tag, tag, first[0])
+		}
+	}))
+	t.Cleanup(server.Close)
+	return NewMeshAPIClient(server.URL, nil), func(tag string) int {
+		mu.Lock()
+		defer mu.Unlock()
+		return lookups[tag]
+	}, &peak
+}
+
+// batchTag is a tag whose bytes are all b
+func batchTag(b byte) [20]byte {
+	var tag [20]byte
+	for i := range tag {
+		tag[i] = b
+	}
+	return tag
+}
+
+func TestBatchResolveTags(t *testing.T) {
+	client, lookups, _ := resolveServer(t)
+	found, unknown, failing := batchTag(0x05), batchTag(0xee), batchTag(0xff)
+	tags := [][20]byte{found, unknown, found, failing, found, unknown}
+	results, err := client.BatchResolveTags(context.Background(), tags)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 3 {
+		t.Fatalf("%d results for 3 distinct tags", len(results))
+	}
+
+	// Each distinct tag is looked up once
+	for _, tag := range [][20]byte{found, unknown, failing} {
+		if n := lookups(hex.EncodeToString(tag[:])); n != 1 {
+			t.Errorf("tag %x looked up %d times", tag[:1], n)
+		}
+	}
+
+	if r := results[found]; !r.Found || r.Err != nil || r.Amount != 5 || len(r.Address) != AddressLength {
+		t.Errorf("found tag: %+v", r)
+	}
+	// An unknown tag is not an error, a failed lookup is and does not stop the others
+	if r := results[unknown]; r.Found || r.Err != nil {
+		t.Errorf("unknown tag: %+v", r)
+	}
+	if r := results[failing]; r.Found || r.Err == nil || !strings.Contains(r.Err.Error(), "internal error") {
+		t.Errorf("failing tag: %+v", r)
+	}
+}
+
+func TestBatchConcurrency(t *testing.T) {
+	var tags [][20]byte
+	for i := 0; i < 40; i++ {
+		tags = append(tags, batchTag(byte(i)))
+	}
+	for _, tc := range []struct {
+		set  int
+		want int32
+	}{
+		{0, DefaultBatchConcurrency},
+		{3, 3},
+		{1, 1},
+	} {
+		client, _, peak := resolveServer(t)
+		client.SetBatchConcurrency(tc.set)
+		results, err := client.BatchResolveTags(context.Background(), tags)
+		if err != nil || len(results) != 40 {
+			t.Fatalf("concurrency %d: %d results, %v", tc.set, len(results), err)
+		}
+		if p := peak.Load(); p > tc.want || p < 1 {
+			t.Errorf("concurrency %d: %d requests in flight, want at most %d", tc.set, p, tc.want)
+		}
+	}
+}
+
+func TestBatchCanceled(t *testing.T) {
+	client, lookups, _ := resolveServer(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	tag := batchTag(0x01)
+	results, err := client.BatchResolveTags(ctx, [][20]byte{tag, batchTag(0x02)})
+	if err != context.Canceled || len(results) != 2 {
+		t.Fatalf("%d results, %v", len(results), err)
+	}
+	if r := results[tag]; r.Found || r.Err == nil || lookups(hex.EncodeToString(tag[:])) != 0 {
+		t.Errorf("canceled tag: %+v", r)
+	}
+}
@@ -0,0 +1,199 @@
+package meshclient
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// MethodTagResolve is the /call method resolving a tag to its address and balance
+const MethodTagResolve = "tag_resolve"
+
+// ErrUnsupported is wrapped by every *UnsupportedError, for errors.Is
+var ErrUnsupported = errors.New("not supported by the server")
+
+// UnsupportedError is returned for a /call method the server does not offer
+type UnsupportedError struct {
+	Method string
+	// Err is the answer that revealed it, nil when the method was known to be missing
+	Err error
+}
+
+func (e *UnsupportedError) Error() string {
+	if e.Err != nil {
+		return fmt.Sprintf("server does not support %s: %v", e.Method, e.Err)
+	}
+	return fmt.Sprintf("server does not support %s", e.Method)
+}
+
+func (e *UnsupportedError) Unwrap() error { return ErrUnsupported }
+
+// Capabilities is what the client knows of the /call methods of the server
+type Capabilities struct {
+	// Listed is true when /network/options lists the methods in CallMethods
+	Listed bool
+	// CallMethods are the methods listed, or the ones seen working when not Listed
+	CallMethods []string
+	// Unsupported are the methods a call found missing
+	Unsupported []string
+}
+
+// capabilities holds the listed methods and the outcome of the calls made
+type capabilities struct {
+	mu      sync.Mutex
+	probed  bool
+	listed  bool
+	methods map[string]bool
+}
+
+// probe asks /network/options once for the call_methods it lists; after a failure the client only learns from calls
+func (c *MeshAPIClient) probe(ctx context.Context) error {
+	c.capabilities.mu.Lock()
+	defer c.capabilities.mu.Unlock()
+	if c.capabilities.probed {
+		return nil
+	}
+	if c.capabilities.methods == nil {
+		c.capabilities.methods = make(map[string]bool)
+	}
+	c.capabilities.probed = true
+	options, err := c.NetworkOptions(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to probe /call methods: %v", err)
+	}
+	c.capabilities.learn(options)
+	return nil
+}
+
+// learn records the call_methods listed by /network/options; the caller holds mu
+func (caps *capabilities) learn(options *NetworkOptions) {
+	caps.probed = true
+	if caps.methods == nil {
+		caps.methods = make(map[string]bool)
+	}
+	if len(options.Allow.CallMethods) > 0 {
+		caps.listed = true
+		caps.methods = make(map[string]bool, len(options.Allow.CallMethods))
+		for _, method := range options.Allow.CallMethods {
+			caps.methods[method] = true
+		}
+	}
+}
+
+/*
+ * Capabilities returns the /call methods known to be offered or missing
+ *
+ * Servers that list their methods in the call_methods of /network/options
+ * are described exactly. For the others the client learns as it goes: a
+ * method is missing once a call to it was rejected as unknown, and assumed
+ * to be there until then.
+ */
+func (c *MeshAPIClient) Capabilities(ctx context.Context) (*Capabilities, error) {
+	if err := c.probe(ctx); err != nil {
+		return nil, err
+	}
+	c.capabilities.mu.Lock()
+	defer c.capabilities.mu.Unlock()
+	caps := &Capabilities{Listed: c.capabilities.listed}
+	for method, supported := range c.capabilities.methods {
+		if supported {
+			caps.CallMethods = append(caps.CallMethods, method)
+		} else {
+			caps.Unsupported = append(caps.Unsupported, method)
+		}
+	}
+	sort.Strings(caps.CallMethods)
+	sort.Strings(caps.Unsupported)
+	return caps, nil
+}
+
+// Supports reports whether the server offers a /call method, see Capabilities
+func (c *MeshAPIClient) Supports(ctx context.Context, method string) (bool, error) {
+	if err := c.probe(ctx); err != nil {
+		return false, err
+	}
+	c.capabilities.mu.Lock()
+	defer c.capabilities.mu.Unlock()
+	supported, known := c.capabilities.methods[method]
+	if c.capabilities.listed {
+		return supported, nil
+	}
+	return supported || !known, nil
+}
+
+// remember records whether the server offers a method, unless it lists its methods
+func (c *MeshAPIClient) remember(method string, supported bool) {
+	c.capabilities.mu.Lock()
+	defer c.capabilities.mu.Unlock()
+	if c.capabilities.listed {
+		return
+	}
+	c.capabilities.methods[method] = supported
+}
+
+// unknownMethod reports whether an error answers a /call to a method the server does not have
+func unknownMethod(err error) bool {
+	var meshErr *MeshError
+	if !errors.As(err, &meshErr) {
+		return false
+	}
+	if meshErr.StatusCode == http.StatusNotFound || meshErr.StatusCode == http.StatusNotImplemented {
+		return true
+	}
+	message := strings.ToLower(meshErr.Message + " " + meshErr.Description + " " + meshErr.Body)
+	if !strings.Contains(message, "method") {
+		return false
+	}
+	for _, hint := range []string{"unsupported", "not supported", "unknown", "not found", "invalid method"} {
+		if strings.Contains(message, hint) {
+			return true
+		}
+	}
+	return false
+}
+
+/*
+ * call invokes a /call method, failing with an *UnsupportedError without a
+ * request when the method is known to be missing
+ *
+ * An "error" member in a 200 answer is returned as a *MeshError, as if the
+ * server had answered with an error status.
+ *
+ * The capability probe is best effort: if /network/options cannot be read
+ * the method is tried anyway, and the probe is not repeated. An answer
+ * rejecting the method is remembered so that later calls fail fast with the
+ * same clear error.
+ */
+func (c *MeshAPIClient) call(ctx context.Context, method string, parameters map[string]string, out interface{}) error {
+	if supported, err := c.Supports(ctx, method); err == nil && !supported {
+		return &UnsupportedError{Method: method}
+	}
+	request := struct {
+		NetworkIdentifier NetworkIdentifier `json:"network_identifier"`
+		Method            string            `json:"method"`
+		Parameters        map[string]string `json:"parameters"`
+	}{c.network, method, parameters}
+
+	var raw json.RawMessage
+	err := c.post(ctx, "/call", request, &raw)
+	if err == nil {
+		err = callError(raw)
+	}
+	if err != nil && unknownMethod(err) {
+		c.remember(method, false)
+		return &UnsupportedError{Method: method, Err: err}
+	}
+	if err != nil {
+		return err
+	}
+	c.remember(method, true)
+	if err := json.Unmarshal(raw, out); err != nil {
+		return &DecodeError{Path: "/call", Err: err}
+	}
+	return nil
+}
@@ -0,0 +1,114 @@
+package meshclient_test
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/NickP005/Vindax-MCM-tools/pkg/meshclient"
+	"github.com/NickP005/Vindax-MCM-tools/pkg/meshmock"
+)
+
+// callCounter counts the /call requests of a client
+type callCounter struct {
+	calls atomic.Int32
+}
+
+func (c *callCounter) OnRequestStart(info meshclient.RequestInfo) {
+	if info.Op == "/call" {
+		c.calls.Add(1)
+	}
+}
+
+func (c *callCounter) OnRequestEnd(info meshclient.RequestInfo) {}
+
+// capabilitiesClient returns a client of a mock offering methods, listed in /network/options if listed
+func capabilitiesClient(t *testing.T, listed bool, methods ...string) (*meshclient.MeshAPIClient, *callCounter, *meshmock.Server) {
+	t.Helper()
+	mock := meshmock.New()
+	t.Cleanup(mock.Close)
+	mock.SetCallMethods(listed, methods...)
+	client := meshclient.NewMeshAPIClient(mock.URL(), nil)
+	counter := &callCounter{}
+	client.SetHooks(counter)
+	return client, counter, mock
+}
+
+func TestCapabilitiesListed(t *testing.T) {
+	client, counter, _ := capabilitiesClient(t, true, "richlist", "fee_stats")
+	ctx := context.Background()
+	caps, err := client.Capabilities(ctx)
+	if err != nil || !caps.Listed || strings.Join(caps.CallMethods, ",") != "fee_stats,richlist" || len(caps.Unsupported) != 0 {
+		t.Fatalf("capabilities %+v, %v", caps, err)
+	}
+	for method, want := range map[string]bool{"richlist": true, "fee_stats": true, meshclient.MethodTagResolve: false, "other": false} {
+		if supported, err := client.Supports(ctx, method); err != nil || supported != want {
+			t.Errorf("Supports(%s): %v, %v", method, supported, err)
+		}
+	}
+
+	// A method the server does not list fails without a request
+	_, err = client.ResolveTag(ctx, make([]byte, 20))
+	var unsupported *meshclient.UnsupportedError
+	if !errors.As(err, &unsupported) || !errors.Is(err, meshclient.ErrUnsupported) || unsupported.Method != meshclient.MethodTagResolve || counter.calls.Load() != 0 {
+		t.Errorf("%d calls, %v", counter.calls.Load(), err)
+	}
+	if !strings.Contains(err.Error(), "server does not support tag_resolve") {
+		t.Errorf("message %q", err)
+	}
+}
+
+// TestCapabilitiesLearned uses servers that do not list their methods: the client learns from the calls
+func TestCapabilitiesLearned(t *testing.T) {
+	ctx := context.Background()
+	tag := make([]byte, 20)
+
+	// A server with tag_resolve: methods are assumed there, and remembered once seen working
+	client, _, _ := capabilitiesClient(t, false, meshclient.MethodTagResolve)
+	if supported, err := client.Supports(ctx, "richlist"); err != nil || !supported {
+		t.Errorf("unknown method: %v, %v", supported, err)
+	}
+	if _, err := client.ResolveTag(ctx, tag); err != meshclient.ErrTagNotFound {
+		t.Fatalf("resolve: %v", err)
+	}
+	if caps, _ := client.Capabilities(ctx); caps.Listed || strings.Join(caps.CallMethods, ",") != meshclient.MethodTagResolve {
+		t.Errorf("capabilities %+v", caps)
+	}
+
+	// A server without it: the first call reveals it, the next ones fail without a request
+	client, counter, _ := capabilitiesClient(t, false, "richlist")
+	var unsupported *meshclient.UnsupportedError
+	if _, err := client.ResolveTag(ctx, tag); !errors.As(err, &unsupported) || unsupported.Err == nil {
+		t.Fatalf("first call: %v", err)
+	}
+	if _, err := client.ResolveTag(ctx, tag); !errors.As(err, &unsupported) || unsupported.Err != nil || counter.calls.Load() != 1 {
+		t.Errorf("second call after %d calls: %v", counter.calls.Load(), err)
+	}
+	caps, _ := client.Capabilities(ctx)
+	if strings.Join(caps.Unsupported, ",") != meshclient.MethodTagResolve {
+		t.Errorf("capabilities %+v", caps)
+	}
+	if supported, _ := client.Supports(ctx, meshclient.MethodTagResolve); supported {
+		t.Error("tag_resolve still supported")
+	}
+}
+
+// TestCapabilitiesProbeFails checks a server whose /network/options fails is still called, and probed only once
+func TestCapabilitiesProbeFails(t *testing.T) {
+	client, counter, mock := capabilitiesClient(t, true, meshclient.MethodTagResolve)
+	mock.Fail("/network/options", meshmock.Fault{Status: 500, Body: "down"})
+	ctx := context.Background()
+	if _, err := client.Capabilities(ctx); err == nil {
+		t.Error("no error for a failed probe")
+	}
+	if _, err := client.ResolveTag(ctx, make([]byte, 20)); err != meshclient.ErrTagNotFound || counter.calls.Load() != 1 {
+		t.Errorf("%d calls, %v", counter.calls.Load(), err)
+	}
+	// The probe is not repeated: nothing is listed, tag_resolve was seen working
+	caps, err := client.Capabilities(ctx)
+	if err != nil || caps.Listed || strings.Join(caps.CallMethods, ",") != meshclient.MethodTagResolve {
+		t.Errorf("capabilities %+v, %v", caps, err)
+	}
+}
@@ -0,0 +1,170 @@
+/*
+ * Package meshclient is a minimal client for the Mochimo Mesh API, shared by the tools.
+ *
+ * Every request is a JSON POST to the Rosetta style endpoints of the API,
+ * targeting the mochimo mainnet network unless SetNetwork names another.
+ * Responses are decoded into the typed structs of types.go.
+ */
+package meshclient
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// DefaultTimeout bounds every request made with the default HTTP client
+const DefaultTimeout = 30 * time.Second
+
+type MeshAPIClient struct {
+	endpoint     string
+	network      NetworkIdentifier
+	httpClient   *http.Client
+	retry        *RetryPolicy
+	preflight    preflightCache
+	capabilities capabilities
+	failover     failover
+	statusCache  *StatusCache
+	userAgent    string
+	headers      http.Header
+	hooks        Hooks
+
+	batchConcurrency int
+	anyCurrency      bool
+}
+
+/*
+ * NewMeshAPIClient creates a client for the API at endpoint
+ *
+ * Parameters:
+ * - endpoint: base URL of the API, e.g. http://localhost:8080
+ * - httpClient: client used for every request, see NewHTTPClient; nil uses
+ *   a client with the default TransportOptions
+ */
+func NewMeshAPIClient(endpoint string, httpClient *http.Client) *MeshAPIClient {
+	if httpClient == nil {
+		// The default options cannot fail
+		httpClient, _ = NewHTTPClient(TransportOptions{})
+	}
+	return &MeshAPIClient{endpoint: endpoint, network: mainnet, httpClient: httpClient, userAgent: userAgent("")}
+}
+
+// Endpoint returns the base URL of the API requests go to, a failover endpoint after SetFailover moved on
+func (c *MeshAPIClient) Endpoint() string {
+	endpoint, _ := c.current()
+	return endpoint
+}
+
+// DefaultNetwork is the network of new clients
+const DefaultNetwork = "mainnet"
+
+// mainnet is the network identifier sent with every request by default
+var mainnet = NetworkIdentifier{Blockchain: "mochimo", Network: DefaultNetwork}
+
+// SetNetwork sets the mochimo network named in every request and checked by Preflight, mainnet by default
+func (c *MeshAPIClient) SetNetwork(network string) {
+	c.network = NetworkIdentifier{Blockchain: mainnet.Blockchain, Network: network}
+}
+
+// Network returns the mochimo network named in every request
+func (c *MeshAPIClient) Network() string {
+	return c.network.Network
+}
+
+/*
+ * post sends request as JSON to path and decodes the response into out
+ *
+ * Parameters:
+ * - ctx: cancels the request, or bounds it with its deadline
+ * - path: endpoint path, e.g. "/network/status"
+ * - request: value marshalled as the request body
+ * - out: pointer the 200 response is decoded into
+ *
+ * Every attempt carries the client's User-Agent, its static headers and a
+ * new X-Request-ID, which the errors keep and print.
+ *
+ * Returns a *MeshError for any status other than 200, a *TransportError if
+ * no answer was received and a *DecodeError if the answer is not valid JSON.
+ * Failed attempts are retried per the client's RetryPolicy, if any.
+ */
+func (c *MeshAPIClient) post(ctx context.Context, path string, request interface{}, out interface{}) error {
+	return c.withRetry(ctx, path, path == "/construction/submit", func(attempt int) error {
+		return c.postOnce(ctx, path, attempt, request, out)
+	})
+}
+
+// postOnce makes a single attempt of post, reported to the client's Hooks
+func (c *MeshAPIClient) postOnce(ctx context.Context, path string, attempt int, request interface{}, out interface{}) error {
+	info := RequestInfo{Op: path, Attempt: attempt, RequestID: newRequestID()}
+	if c.hooks != nil {
+		c.hooks.OnRequestStart(info)
+	}
+	start := time.Now()
+	info.Status, info.Err = c.send(ctx, path, info.RequestID, request, out)
+	info.Duration = time.Since(start)
+	if c.hooks != nil {
+		c.hooks.OnRequestEnd(info)
+	}
+	return info.Err
+}
+
+// send makes the HTTP request of an attempt and returns the answer's status, 0 if none
+func (c *MeshAPIClient) send(ctx context.Context, path string, requestID string, request interface{}, out interface{}) (int, error) {
+	body, err := json.Marshal(request)
+	if err != nil {
+		return 0, fmt.Errorf("failed to encode request: %v", err)
+	}
+	endpoint, position := c.current()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint+path, bytes.NewReader(body))
+	if err != nil {
+		return 0, fmt.Errorf("failed to create request: %v", err)
+	}
+	for key, values := range c.headers {
+		req.Header[key] = values
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", c.userAgent)
+	req.Header.Set(RequestIDHeader, requestID)
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		// A canceled request says nothing about the endpoint
+		if ctx.Err() == nil {
+			c.failed(position)
+		}
+		return 0, &TransportError{Path: path, RequestID: requestID, Err: err}
+	}
+	// Drain what the decoder left so the connection is reused by the next request
+	defer func() {
+		io.Copy(io.Discard, resp.Body)
+		resp.Body.Close()
+	}()
+
+	// The transport asks for gzip and decompresses it, unless compression is
+	// disabled; a server that compresses anyway is decompressed here
+	respReader := io.Reader(resp.Body)
+	if !resp.Uncompressed && strings.EqualFold(resp.Header.Get("Content-Encoding"), "gzip") {
+		gz, err := gzip.NewReader(resp.Body)
+		if err != nil {
+			return resp.StatusCode, &DecodeError{Path: path, RequestID: requestID, Err: err}
+		}
+		defer gz.Close()
+		respReader = gz
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		respBody, _ := io.ReadAll(io.LimitReader(respReader, maxErrorBody))
+		meshErr := newMeshError(resp.StatusCode, resp.Header, respBody)
+		meshErr.RequestID = requestID
+		return resp.StatusCode, meshErr
+	}
+	if err := json.NewDecoder(respReader).Decode(out); err != nil {
+		return resp.StatusCode, &DecodeError{Path: path, RequestID: requestID, Err: err}
+	}
+	return resp.StatusCode, nil
+}
@@ -0,0 +1,77 @@
+package meshclient
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// slowServer answers nothing until the test ends
+func slowServer(t *testing.T) *MeshAPIClient {
+	t.Helper()
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-release:
+		case <-r.Context().Done():
+		}
+	}))
+	t.Cleanup(func() {
+		close(release)
+		server.Close()
+	})
+	return NewMeshAPIClient(server.URL, nil)
+}
+
+// TestCanceledContext checks a canceled call returns at once instead of waiting for the server
+func TestCanceledContext(t *testing.T) {
+	client := slowServer(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(50*time.Millisecond, cancel)
+	start := time.Now()
+	_, err := client.NetworkStatus(ctx)
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Errorf("canceled call returned after %v", elapsed)
+	}
+	var transportErr *TransportError
+	if !errors.As(err, &transportErr) || !errors.Is(err, context.Canceled) || transportErr.Path != "/network/status" {
+		t.Errorf("got %v", err)
+	}
+	if DefaultRetryable(err) {
+		t.Error("a canceled call is retryable")
+	}
+}
+
+func TestContextDeadline(t *testing.T) {
+	client := slowServer(t)
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	if _, err := client.Block(ctx, 1); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Errorf("call past its deadline returned after %v", elapsed)
+	}
+}
+
+// TestHTTPClientTimeout checks the *http.Client given to NewMeshAPIClient bounds the requests
+func TestHTTPClientTimeout(t *testing.T) {
+	client := slowServer(t)
+	client.httpClient = &http.Client{Timeout: 50 * time.Millisecond}
+	start := time.Now()
+	_, err := client.Mempool(context.Background())
+	var transportErr *TransportError
+	if !errors.As(err, &transportErr) {
+		t.Errorf("got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Errorf("timed out call returned after %v", elapsed)
+	}
+	if NewMeshAPIClient("http://localhost", nil).httpClient.Timeout != DefaultTimeout {
+		t.Error("the default client has no DefaultTimeout")
+	}
+}
@@ -0,0 +1,207 @@
+package meshclient
+
+import (
+	"context"
+	"encoding/hex"
+	"fmt"
+	"strconv"
+
+	"github.com/NickP005/Vindax-MCM-tools/pkg/mcmaddr"
+	"github.com/NickP005/Vindax-MCM-tools/pkg/wotsp"
+)
+
+// CurveWOTS is the curve type of Mochimo WOTS+ public keys in /construction requests
+const CurveWOTS = "wotsp"
+
+// PublicKey is a public key as sent to the /construction endpoints
+type PublicKey struct {
+	HexBytes  string `json:"hex_bytes"`
+	CurveType string `json:"curve_type"`
+}
+
+// DerivationError is returned when the Mesh API derives another account than the local rule
+type DerivationError struct {
+	// Local is the address hash (hex) computed by wotsp.AddrHashFromPK
+	Local string
+	// Remote is the address returned by /construction/derive
+	Remote string
+}
+
+func (e *DerivationError) Error() string {
+	return fmt.Sprintf("derivation mismatch: local address hash 0x%s, Mesh API derived %s", e.Local, e.Remote)
+}
+
+// ConstructionDerive asks the Mesh API for the account identifier of a WOTS+ public key
+func (c *MeshAPIClient) ConstructionDerive(ctx context.Context, publicKey []byte) (AccountIdentifier, error) {
+	request := struct {
+		NetworkIdentifier NetworkIdentifier `json:"network_identifier"`
+		PublicKey         PublicKey         `json:"public_key"`
+	}{c.network, PublicKey{HexBytes: hex.EncodeToString(publicKey), CurveType: CurveWOTS}}
+
+	var response struct {
+		AccountIdentifier *AccountIdentifier `json:"account_identifier"`
+	}
+	if err := c.post(ctx, "/construction/derive", request, &response); err != nil {
+		return AccountIdentifier{}, err
+	}
+	if response.AccountIdentifier == nil {
+		return AccountIdentifier{}, &DecodeError{Path: "/construction/derive", Err: fmt.Errorf("no account_identifier in answer")}
+	}
+	return *response.AccountIdentifier, nil
+}
+
+/*
+ * CheckDerivation cross-checks the local address derivation of a WOTS+
+ * public key against the Mesh API
+ *
+ * Parameters:
+ * - ctx: cancels the request
+ * - publicKey: a WOTS+ public key of at least wotsp.SigSize bytes
+ *
+ * The address returned by /construction/derive is normalized and compared
+ * with wotsp.AddrHashFromPK. A divergence means the tools and the node
+ * disagree on which account a key controls, so it is reported as a
+ * *DerivationError holding both values rather than as a warning.
+ *
+ * Returns the account derived by the Mesh API.
+ */
+func (c *MeshAPIClient) CheckDerivation(ctx context.Context, publicKey []byte) (AccountIdentifier, error) {
+	local, err := wotsp.AddrHashFromPK(publicKey)
+	if err != nil {
+		return AccountIdentifier{}, err
+	}
+	account, err := c.ConstructionDerive(ctx, publicKey)
+	if err != nil {
+		return AccountIdentifier{}, err
+	}
+	remote, err := mcmaddr.Normalize(account.Address)
+	if err != nil || remote != local {
+		return account, &DerivationError{Local: hex.EncodeToString(local[:]), Remote: account.Address}
+	}
+	return account, nil
+}
+
+// Operation types of the Mochimo construction flow
+const (
+	OpSourceTransfer      = "SOURCE_TRANSFER"
+	OpDestinationTransfer = "DESTINATION_TRANSFER"
+	OpFee                 = "FEE"
+)
+
+// mcmAmount returns an amount of nanoMCM, negated for the side paying it
+func mcmAmount(nano uint64, negative bool) *Amount {
+	value := strconv.FormatUint(nano, 10)
+	if negative && nano > 0 {
+		value = "-" + value
+	}
+	return &Amount{Value: value, Currency: MCM}
+}
+
+// SourceOperation debits total (payments plus fee) from the 20 bytes source tag
+func SourceOperation(index int64, tag []byte, total uint64) (Operation, error) {
+	account, err := AccountFromTag(tag)
+	if err != nil {
+		return Operation{}, err
+	}
+	return Operation{
+		OperationIdentifier: OperationIdentifier{Index: index},
+		Type:                OpSourceTransfer,
+		Account:             &account,
+		Amount:              mcmAmount(total, true),
+	}, nil
+}
+
+// DestinationOperation credits value to the 20 bytes destination tag, with an optional memo
+func DestinationOperation(index int64, tag []byte, value uint64, memo string) (Operation, error) {
+	account, err := AccountFromTag(tag)
+	if err != nil {
+		return Operation{}, err
+	}
+	operation := Operation{
+		OperationIdentifier: OperationIdentifier{Index: index},
+		Type:                OpDestinationTransfer,
+		Account:             &account,
+		Amount:              mcmAmount(value, false),
+	}
+	if memo != "" {
+		operation.Metadata = map[string]interface{}{"memo": memo}
+	}
+	return operation, nil
+}
+
+// FeeOperation pays fee to the miner; it has no account
+func FeeOperation(index int64, fee uint64) Operation {
+	return Operation{
+		OperationIdentifier: OperationIdentifier{Index: index},
+		Type:                OpFee,
+		Amount:              mcmAmount(fee, false),
+	}
+}
+
+// PreprocessResult is the response of /construction/preprocess
+type PreprocessResult struct {
+	// Options are passed as is to Metadata
+	Options            map[string]interface{} `json:"options"`
+	RequiredPublicKeys []AccountIdentifier    `json:"required_public_keys,omitempty"`
+}
+
+// MetadataResult is the response of /construction/metadata
+type MetadataResult struct {
+	// Metadata is passed as is to /construction/payloads
+	Metadata     map[string]interface{} `json:"metadata"`
+	SuggestedFee []Amount               `json:"suggested_fee,omitempty"`
+}
+
+// Fee returns the first suggested fee in nanoMCM, or false if the server suggested none
+func (m *MetadataResult) Fee() (uint64, bool, error) {
+	if len(m.SuggestedFee) == 0 {
+		return 0, false, nil
+	}
+	fee, err := strconv.ParseUint(m.SuggestedFee[0].Value, 10, 64)
+	if err != nil {
+		return 0, false, fmt.Errorf("invalid suggested fee %q: %v", m.SuggestedFee[0].Value, err)
+	}
+	return fee, true, nil
+}
+
+// ConstructionPreprocess returns the options of /construction/metadata for a transaction of operations
+func (c *MeshAPIClient) ConstructionPreprocess(ctx context.Context, operations []Operation) (*PreprocessResult, error) {
+	request := struct {
+		NetworkIdentifier NetworkIdentifier `json:"network_identifier"`
+		Operations        []Operation       `json:"operations"`
+	}{c.network, operations}
+
+	var preprocess PreprocessResult
+	if err := c.post(ctx, "/construction/preprocess", request, &preprocess); err != nil {
+		return nil, err
+	}
+	return &preprocess, nil
+}
+
+/*
+ * ConstructionMetadata fetches what a transaction needs to be built, and
+ * the fee the server suggests for it
+ *
+ * Parameters:
+ * - ctx: cancels the request
+ * - options: the Options returned by ConstructionPreprocess
+ * - publicKeys: the keys listed in RequiredPublicKeys, if any
+ *
+ * The suggested fee is checked to be MCM like every other amount.
+ */
+func (c *MeshAPIClient) ConstructionMetadata(ctx context.Context, options map[string]interface{}, publicKeys ...PublicKey) (*MetadataResult, error) {
+	request := struct {
+		NetworkIdentifier NetworkIdentifier      `json:"network_identifier"`
+		Options           map[string]interface{} `json:"options"`
+		PublicKeys        []PublicKey            `json:"public_keys,omitempty"`
+	}{c.network, options, publicKeys}
+
+	var metadata MetadataResult
+	if err := c.post(ctx, "/construction/metadata", request, &metadata); err != nil {
+		return nil, err
+	}
+	if err := c.checkAmounts("/construction/metadata", metadata.SuggestedFee...); err != nil {
+		return nil, err
+	}
+	return &metadata, nil
+}
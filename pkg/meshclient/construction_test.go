@@ -0,0 +1,185 @@
+package meshclient
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/NickP005/Vindax-MCM-tools/pkg/wotsp"
+)
+
+// testPublicKey is the public key of the zero seed and its address hash in hex
+func testPublicKey(t *testing.T) ([]byte, string) {
+	t.Helper()
+	keypair := wotsp.Keygen([32]byte{})
+	hash, err := wotsp.AddrHashFromPK(keypair.PublicKey[:])
+	if err != nil {
+		t.Fatal(err)
+	}
+	return keypair.PublicKey[:], hex.EncodeToString(hash[:])
+}
+
+func TestConstructionDerive(t *testing.T) {
+	publicKey, _ := testPublicKey(t)
+	client, received := testServer(t, http.StatusOK, `{"account_identifier":{"address":"0x0102"}}`)
+	account, err := client.ConstructionDerive(context.Background(), publicKey)
+	if err != nil || account.Address != "0x0102" {
+		t.Fatalf("account %+v, %v", account, err)
+	}
+	checkRequest(t, *received, "/construction/derive",
+		`{`+network+`,"public_key":{"hex_bytes":"`+hex.EncodeToString(publicKey)+`","curve_type":"wotsp"}}`)
+
+	client, _ = testServer(t, http.StatusOK, `{}`)
+	var decodeErr *DecodeError
+	if _, err := client.ConstructionDerive(context.Background(), publicKey); !errors.As(err, &decodeErr) {
+		t.Errorf("no account_identifier: %v", err)
+	}
+}
+
+// TestCheckDerivation answers /construction/derive with the local address hash in several forms, and with others
+func TestCheckDerivation(t *testing.T) {
+	publicKey, local := testPublicKey(t)
+	for _, tc := range []struct {
+		address string
+		match   bool
+	}{
+		{"0x" + local, true},
+		{local, true},
+		{"0x" + strings.ToUpper(local), true},
+		{"0x" + strings.Repeat("00", 20), false},
+		{"0x" + local[:38], false},
+		{"not an address", false},
+	} {
+		client, _ := testServer(t, http.StatusOK, `{"account_identifier":{"address":"`+tc.address+`"}}`)
+		account, err := client.CheckDerivation(context.Background(), publicKey)
+		if tc.match {
+			if err != nil || account.Address != tc.address {
+				t.Errorf("%s: %+v, %v", tc.address, account, err)
+			}
+			continue
+		}
+		// A mismatch is an error holding both values
+		var derivationErr *DerivationError
+		if !errors.As(err, &derivationErr) || derivationErr.Local != local || derivationErr.Remote != tc.address {
+			t.Errorf("%s: %v", tc.address, err)
+			continue
+		}
+		if !strings.Contains(err.Error(), local) || !strings.Contains(err.Error(), tc.address) {
+			t.Errorf("%s: %q does not print both values", tc.address, err)
+		}
+	}
+
+	// A key too short is refused before any request
+	client, received := testServer(t, http.StatusOK, `{}`)
+	if _, err := client.CheckDerivation(context.Background(), publicKey[:100]); err == nil || len(*received) != 0 {
+		t.Errorf("short key: %d requests, %v", len(*received), err)
+	}
+	// A failing API is not a mismatch
+	client, _ = testServer(t, http.StatusInternalServerError, `{"code":8,"message":"invalid public key"}`)
+	var derivationErr *DerivationError
+	if _, err := client.CheckDerivation(context.Background(), publicKey); err == nil || errors.As(err, &derivationErr) {
+		t.Errorf("failing API: %v", err)
+	}
+}
+
+// fixture reads a file of testdata/construction, failing the test if it cannot
+func fixture(t *testing.T, name string) []byte {
+	t.Helper()
+	data, err := os.ReadFile(filepath.Join("testdata", "construction", name))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+/*
+ * constructionServer answers each construction path with its fixture
+ * <name>_response.json, after checking the request is <name>_request.json
+ * once decoded
+ */
+func constructionServer(t *testing.T) *MeshAPIClient {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		name := strings.TrimPrefix(r.URL.Path, "/construction/")
+		var got, want interface{}
+		if err := json.NewDecoder(r.Body).Decode(&got); err != nil {
+			t.Errorf("%s: %v", r.URL.Path, err)
+		}
+		json.Unmarshal(fixture(t, name+"_request.json"), &want)
+		if !reflect.DeepEqual(got, want) {
+			encoded, _ := json.Marshal(got)
+			t.Errorf("%s request: %s", r.URL.Path, encoded)
+		}
+		w.Write(fixture(t, name+"_response.json"))
+	}))
+	t.Cleanup(server.Close)
+	return NewMeshAPIClient(server.URL, nil)
+}
+
+// TestConstructionFixtures runs preprocess and metadata for a payment of 2500 with a fee of 500
+func TestConstructionFixtures(t *testing.T) {
+	client := constructionServer(t)
+	source, destination := bytes.Repeat([]byte{0x42}, 20), bytes.Repeat([]byte{0x43}, 20)
+	debit, err := SourceOperation(0, source, 3000)
+	if err != nil {
+		t.Fatal(err)
+	}
+	payment, err := DestinationOperation(1, destination, 2500, "INV-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	preprocess, err := client.ConstructionPreprocess(context.Background(), []Operation{debit, payment, FeeOperation(2, 500)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if preprocess.Options["source_addr"] != "0x"+hex.EncodeToString(source) {
+		t.Errorf("options %v", preprocess.Options)
+	}
+
+	metadata, err := client.ConstructionMetadata(context.Background(), preprocess.Options)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fee, ok, err := metadata.Fee(); fee != 500 || !ok || err != nil {
+		t.Errorf("fee %d, %v, %v", fee, ok, err)
+	}
+	if metadata.Metadata["source_balance"] != 10000.0 {
+		t.Errorf("metadata %v", metadata.Metadata)
+	}
+}
+
+func TestMetadataFee(t *testing.T) {
+	for _, tc := range []struct {
+		suggested []Amount
+		fee       uint64
+		ok        bool
+		failed    bool
+	}{
+		{nil, 0, false, false},
+		{[]Amount{{Value: "1200", Currency: MCM}}, 1200, true, false},
+		{[]Amount{{Value: "-5", Currency: MCM}}, 0, false, true},
+		{[]Amount{{Value: "lots", Currency: MCM}}, 0, false, true},
+	} {
+		fee, ok, err := (&MetadataResult{SuggestedFee: tc.suggested}).Fee()
+		if fee != tc.fee || ok != tc.ok || (err != nil) != tc.failed {
+			t.Errorf("%+v: %d, %v, %v", tc.suggested, fee, ok, err)
+		}
+	}
+
+	// An operation on an invalid tag is refused before any request
+	if _, err := SourceOperation(0, make([]byte, 19), 1); err == nil {
+		t.Error("19 bytes source tag accepted")
+	}
+	if _, err := DestinationOperation(1, nil, 1, ""); err == nil {
+		t.Error("empty destination tag accepted")
+	}
+}
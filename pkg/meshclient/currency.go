@@ -0,0 +1,62 @@
+package meshclient
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/NickP005/Vindax-MCM-tools/pkg/amount"
+)
+
+// MCM is the currency of every amount on the Mochimo chain
+var MCM = Currency{Symbol: "MCM", Decimals: amount.Decimals}
+
+// ErrUnexpectedCurrency is wrapped by every *CurrencyError, for errors.Is
+var ErrUnexpectedCurrency = errors.New("unexpected currency")
+
+// CurrencyError is returned when an answer holds an amount in another currency than MCM
+type CurrencyError struct {
+	// Op is the endpoint path of the answer
+	Op string
+	// Got is the currency received
+	Got Currency
+}
+
+func (e *CurrencyError) Error() string {
+	return fmt.Sprintf("%s: %v: got %s with %d decimals, want %s with %d", e.Op, ErrUnexpectedCurrency,
+		e.Got.Symbol, e.Got.Decimals, MCM.Symbol, MCM.Decimals)
+}
+
+func (e *CurrencyError) Unwrap() error { return ErrUnexpectedCurrency }
+
+// AllowAnyCurrency turns off the check that amounts are MCM with 9 decimals, for exotic deployments
+func (c *MeshAPIClient) AllowAnyCurrency(allow bool) {
+	c.anyCurrency = allow
+}
+
+// checkAmounts returns a *CurrencyError for the first amount that is not in MCM
+func (c *MeshAPIClient) checkAmounts(op string, amounts ...Amount) error {
+	if c.anyCurrency {
+		return nil
+	}
+	for _, a := range amounts {
+		if a.Currency != MCM {
+			return &CurrencyError{Op: op, Got: a.Currency}
+		}
+	}
+	return nil
+}
+
+// checkTransactions checks the amounts of the operations of transactions
+func (c *MeshAPIClient) checkTransactions(op string, transactions ...Transaction) error {
+	for _, tx := range transactions {
+		for _, operation := range tx.Operations {
+			if operation.Amount == nil {
+				continue
+			}
+			if err := c.checkAmounts(op, *operation.Amount); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
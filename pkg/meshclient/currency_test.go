@@ -0,0 +1,74 @@
+package meshclient
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+// transferIn is a transaction paying 10 in currency, after an operation without an amount
+func transferIn(currency string) string {
+	return `{"transaction_identifier":{"hash":"` + testHash + `"},"operations":[
+		{"operation_identifier":{"index":0},"type":"FEE"},
+		{"operation_identifier":{"index":1},"type":"DESTINATION_TRANSFER","amount":{"value":"10","currency":` + currency + `}}]}`
+}
+
+// TestUnexpectedCurrency answers every amount-bearing endpoint, named as in endpointCalls, with another currency than MCM
+func TestUnexpectedCurrency(t *testing.T) {
+	const (
+		mcmCurrency = `{"symbol":"MCM","decimals":9}`
+		btc         = `{"symbol":"BTC","decimals":8}`
+	)
+	for _, tc := range []struct {
+		name   string
+		answer string
+		call   string
+		op     string
+		got    Currency
+	}{
+		{"balance in two currencies", `{"balances":[{"value":"1","currency":` + mcmCurrency + `},{"value":"2","currency":` + btc + `}]}`,
+			"AccountBalance", "/account/balance", Currency{"BTC", 8}},
+		{"MCM with 8 decimals", `{"balances":[{"value":"1","currency":{"symbol":"MCM","decimals":8}}]}`,
+			"AccountBalance", "/account/balance", Currency{"MCM", 8}},
+		{"lowercase symbol", `{"transaction":` + transferIn(`{"symbol":"mcm","decimals":9}`) + `}`,
+			"MempoolTransaction", "/mempool/transaction", Currency{"mcm", 9}},
+		{"block", `{"block":{"block_identifier":{"index":9,"hash":"0x09"},"transactions":[` + transferIn(mcmCurrency) + `,` + transferIn(btc) + `]}}`,
+			"Block", "/block", Currency{"BTC", 8}},
+		{"block by hash", `{"block":{"block_identifier":{"index":9,"hash":"0x09"},"transactions":[` + transferIn(btc) + `]}}`,
+			"BlockByHash", "/block", Currency{"BTC", 8}},
+		{"block transaction", `{"transaction":` + transferIn(btc) + `}`,
+			"BlockTransaction", "/block/transaction", Currency{"BTC", 8}},
+		{"search hit", `{"transactions":[{"block_identifier":{"index":9,"hash":"0x09"},"transaction":` + transferIn(btc) + `}],"total_count":1}`,
+			"SearchTransactions", "/search/transactions", Currency{"BTC", 8}},
+		{"suggested fee", `{"metadata":{},"suggested_fee":[{"value":"500","currency":` + btc + `}]}`,
+			"ConstructionMetadata", "/construction/metadata", Currency{"BTC", 8}},
+	} {
+		client, _ := testServer(t, http.StatusOK, tc.answer)
+		err := endpointCalls[tc.call](context.Background(), client)
+		var currencyErr *CurrencyError
+		if !errors.Is(err, ErrUnexpectedCurrency) || !errors.As(err, &currencyErr) || currencyErr.Op != tc.op || currencyErr.Got != tc.got {
+			t.Errorf("%s: %v", tc.name, err)
+			continue
+		}
+		// The error says what was received
+		if !strings.Contains(err.Error(), tc.got.Symbol) {
+			t.Errorf("%s: %q does not name %s", tc.name, err, tc.got.Symbol)
+		}
+
+		client.AllowAnyCurrency(true)
+		if err := endpointCalls[tc.call](context.Background(), client); err != nil {
+			t.Errorf("%s with AllowAnyCurrency: %v", tc.name, err)
+		}
+	}
+}
+
+// TestMCMAmounts checks MCM amounts and operations without an amount pass
+func TestMCMAmounts(t *testing.T) {
+	client, _ := testServer(t, http.StatusOK, `{"block":{"block_identifier":{"index":9,"hash":"0x09"},"transactions":[`+
+		transferIn(`{"symbol":"MCM","decimals":9}`)+`]}}`)
+	if _, err := client.Block(context.Background(), 9); err != nil {
+		t.Error(err)
+	}
+}
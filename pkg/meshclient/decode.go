@@ -0,0 +1,138 @@
+package meshclient
+
+import (
+	"context"
+	"encoding/hex"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/NickP005/Vindax-MCM-tools/pkg/mcmaddr"
+)
+
+// Payment is an amount credited to an account by a transfer
+type Payment struct {
+	Address string `json:"address"`
+	Amount  uint64 `json:"amount"`
+	Memo    string `json:"memo,omitempty"`
+}
+
+/*
+ * Transfer is a transaction decoded from its operations
+ *
+ * Fields:
+ * - Source: the account debited, Spent: the amount debited (payments, change and fee)
+ * - Destinations: the accounts credited, in operation order, with their memo
+ * - Change: the amounts credited back to the source tag, under a new address
+ * - Fee: the amount paid to the miner
+ * - Other: the operations that fit none of the above, e.g. without amount
+ */
+type Transfer struct {
+	TxID         string      `json:"txId"`
+	Source       string      `json:"source,omitempty"`
+	Spent        uint64      `json:"spent"`
+	Destinations []Payment   `json:"destinations"`
+	Change       []Payment   `json:"change,omitempty"`
+	Fee          uint64      `json:"fee"`
+	Other        []Operation `json:"other,omitempty"`
+}
+
+// sameTag reports whether two account addresses (hex, tag or full address) start with the same 20 bytes tag
+func sameTag(a string, b string) bool {
+	a, b = trimHash(a), trimHash(b)
+	return len(a) >= mcmaddr.TagLength*2 && len(b) >= mcmaddr.TagLength*2 &&
+		strings.EqualFold(a[:mcmaddr.TagLength*2], b[:mcmaddr.TagLength*2])
+}
+
+/*
+ * DecodeTransfer sorts the operations of a transaction into its source,
+ * destinations, change and fee
+ *
+ * FEE operations are the fee. Other operations are classified by the sign
+ * of their amount rather than their type, so the generic TRANSFER type of
+ * some servers decodes too: a debit is the source, a credit is a
+ * destination, or change when it goes back to the source tag. The first
+ * debit names the source; the amounts of later ones are added to Spent.
+ * An amount that is not an integer fails the whole decoding.
+ */
+func DecodeTransfer(tx Transaction) (Transfer, error) {
+	transfer := Transfer{TxID: trimHash(tx.TransactionIdentifier.Hash), Destinations: []Payment{}}
+	var credits []Operation
+	for _, op := range tx.Operations {
+		if op.Amount == nil {
+			transfer.Other = append(transfer.Other, op)
+			continue
+		}
+		value, err := strconv.ParseInt(op.Amount.Value, 10, 64)
+		if err != nil {
+			return transfer, fmt.Errorf("operation %d: invalid amount %q", op.OperationIdentifier.Index, op.Amount.Value)
+		}
+		switch {
+		case op.Type == OpFee:
+			if value < 0 {
+				value = -value
+			}
+			transfer.Fee += uint64(value)
+		case value < 0 && op.Account != nil:
+			if transfer.Source == "" {
+				transfer.Source = op.Account.Address
+			}
+			transfer.Spent += uint64(-value)
+		case value > 0 && op.Account != nil:
+			credits = append(credits, op)
+		default:
+			transfer.Other = append(transfer.Other, op)
+		}
+	}
+
+	// Credits are sorted once the source is known, whatever the operation order
+	for _, op := range credits {
+		value, _ := strconv.ParseInt(op.Amount.Value, 10, 64)
+		payment := Payment{Address: op.Account.Address, Amount: uint64(value)}
+		payment.Memo, _ = op.Metadata["memo"].(string)
+		if transfer.Source != "" && sameTag(op.Account.Address, transfer.Source) {
+			transfer.Change = append(transfer.Change, payment)
+			continue
+		}
+		transfer.Destinations = append(transfer.Destinations, payment)
+	}
+	return transfer, nil
+}
+
+// PaidTo returns the destinations of the transfer credited to a 20 bytes tag, in operation order
+func (t Transfer) PaidTo(tag []byte) []Payment {
+	var payments []Payment
+	for _, payment := range t.Destinations {
+		if sameTag(payment.Address, hex.EncodeToString(tag)) {
+			payments = append(payments, payment)
+		}
+	}
+	return payments
+}
+
+/*
+ * BlockTransfers decodes every transaction of a block, in order
+ *
+ * The transactions the server left out of the inline list (see
+ * OtherTransactions) are fetched through /block/transaction after the
+ * inline ones, so the list is complete or the call fails.
+ */
+func (c *MeshAPIClient) BlockTransfers(ctx context.Context, block *Block) ([]Transfer, error) {
+	transactions := block.Block.Transactions
+	for _, other := range block.OtherTransactions {
+		tx, err := c.BlockTransactionIn(ctx, block.Block.BlockIdentifier, other.Hash)
+		if err != nil {
+			return nil, fmt.Errorf("transaction %s: %w", other.Hash, err)
+		}
+		transactions = append(transactions, tx.Transaction)
+	}
+	transfers := make([]Transfer, 0, len(transactions))
+	for _, tx := range transactions {
+		transfer, err := DecodeTransfer(tx)
+		if err != nil {
+			return nil, fmt.Errorf("transaction %s: %v", tx.TransactionIdentifier.Hash, err)
+		}
+		transfers = append(transfers, transfer)
+	}
+	return transfers, nil
+}
@@ -0,0 +1,70 @@
+package meshclient
+
+import (
+	"strings"
+	"testing"
+)
+
+// op is an operation of type on address moving value nanoMCM, with a memo if not empty
+func op(index int64, typ string, address string, value string, memo string) Operation {
+	o := Operation{OperationIdentifier: OperationIdentifier{Index: index}, Type: typ, Amount: &Amount{Value: value, Currency: MCM}}
+	if address != "" {
+		o.Account = &AccountIdentifier{Address: address}
+	}
+	if memo != "" {
+		o.Metadata = map[string]interface{}{"memo": memo}
+	}
+	return o
+}
+
+func TestDecodeTransfer(t *testing.T) {
+	source := "0x" + strings.Repeat("42", 20) + strings.Repeat("01", 20)
+	change := "0x" + strings.Repeat("42", 20) + strings.Repeat("02", 20)
+	alice, bob := "0x"+strings.Repeat("a1", 20), "0x"+strings.Repeat("b0", 20)
+
+	for _, tc := range []struct {
+		name string
+		ops  []Operation
+	}{
+		{"typed", []Operation{
+			op(0, OpSourceTransfer, source, "-1000", ""),
+			op(1, OpDestinationTransfer, alice, "300", "INV-1"),
+			op(2, OpDestinationTransfer, bob, "200", ""),
+			op(3, OpDestinationTransfer, change, "490", ""),
+			op(4, OpFee, "", "10", ""),
+		}},
+		// Generic types, credits listed before the debit, and a negative fee
+		{"generic", []Operation{
+			op(0, "TRANSFER", alice, "300", "INV-1"),
+			op(1, "TRANSFER", change, "490", ""),
+			op(2, "TRANSFER", bob, "200", ""),
+			op(3, "TRANSFER", source, "-1000", ""),
+			op(4, OpFee, "", "-10", ""),
+		}},
+	} {
+		transfer, err := DecodeTransfer(Transaction{TransactionIdentifier: TransactionIdentifier{Hash: "0xabcd"}, Operations: tc.ops})
+		if err != nil {
+			t.Fatalf("%s: %v", tc.name, err)
+		}
+		if transfer.TxID != "abcd" || transfer.Source != source || transfer.Spent != 1000 || transfer.Fee != 10 {
+			t.Errorf("%s: %+v", tc.name, transfer)
+		}
+		if len(transfer.Destinations) != 2 || len(transfer.Change) != 1 || transfer.Change[0] != (Payment{Address: change, Amount: 490}) {
+			t.Errorf("%s: destinations %+v, change %+v", tc.name, transfer.Destinations, transfer.Change)
+		}
+		paid := transfer.PaidTo([]byte(strings.Repeat("\xa1", 20)))
+		if len(paid) != 1 || paid[0].Amount != 300 || paid[0].Memo != "INV-1" {
+			t.Errorf("%s: paid to alice %+v", tc.name, paid)
+		}
+	}
+
+	// Operations without amount are kept aside; an amount that is no integer fails
+	noAmount := Operation{OperationIdentifier: OperationIdentifier{Index: 5}, Type: "MEMO"}
+	transfer, err := DecodeTransfer(Transaction{Operations: []Operation{noAmount}})
+	if err != nil || len(transfer.Other) != 1 || transfer.Source != "" || len(transfer.Destinations) != 0 {
+		t.Errorf("no amount: %+v, %v", transfer, err)
+	}
+	if _, err := DecodeTransfer(Transaction{Operations: []Operation{op(2, OpDestinationTransfer, alice, "1.5", "")}}); err == nil || !strings.Contains(err.Error(), "operation 2") {
+		t.Errorf("decimal amount: %v", err)
+	}
+}
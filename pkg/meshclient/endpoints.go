@@ -0,0 +1,265 @@
+package meshclient
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// networkRequest is the body of requests that only carry the network identifier
+type networkRequest struct {
+	NetworkIdentifier NetworkIdentifier `json:"network_identifier"`
+}
+
+/*
+ * AccountBalance returns the balance of a 20 bytes tag
+ *
+ * An account the node does not know is not an error: Found tells it from an
+ * account holding 0, whether the server lists no balance or answers 404.
+ */
+func (c *MeshAPIClient) AccountBalance(ctx context.Context, tag []byte) (*AccountBalance, error) {
+	account, err := AccountFromTag(tag)
+	if err != nil {
+		return nil, err
+	}
+	request := struct {
+		NetworkIdentifier NetworkIdentifier `json:"network_identifier"`
+		AccountIdentifier AccountIdentifier `json:"account_identifier"`
+	}{c.network, account}
+
+	var balance AccountBalance
+	err = c.post(ctx, "/account/balance", request, &balance)
+	var meshErr *MeshError
+	if errors.As(err, &meshErr) && meshErr.StatusCode == http.StatusNotFound {
+		return &AccountBalance{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := c.checkAmounts("/account/balance", balance.Balances...); err != nil {
+		return nil, err
+	}
+	balance.Found = len(balance.Balances) > 0
+	return &balance, nil
+}
+
+// NetworkStatus returns the current and genesis blocks of the network, through the StatusCache if set
+func (c *MeshAPIClient) NetworkStatus(ctx context.Context) (*NetworkStatus, error) {
+	if c.statusCache != nil {
+		return c.statusCache.get(ctx, func() (*NetworkStatus, error) {
+			return c.fetchNetworkStatus(ctx)
+		})
+	}
+	return c.fetchNetworkStatus(ctx)
+}
+
+// fetchNetworkStatus requests /network/status
+func (c *MeshAPIClient) fetchNetworkStatus(ctx context.Context) (*NetworkStatus, error) {
+	var status NetworkStatus
+	if err := c.post(ctx, "/network/status", networkRequest{c.network}, &status); err != nil {
+		return nil, err
+	}
+	return &status, nil
+}
+
+// Mempool returns the identifiers of the transactions waiting in the mempool; one that is not a hex hash is a *DecodeError
+func (c *MeshAPIClient) Mempool(ctx context.Context) (*Mempool, error) {
+	var mempool Mempool
+	if err := c.post(ctx, "/mempool", networkRequest{c.network}, &mempool); err != nil {
+		return nil, err
+	}
+	for _, tx := range mempool.TransactionIdentifiers {
+		if !validHash(tx.Hash) {
+			return nil, &DecodeError{Path: "/mempool", Err: fmt.Errorf("invalid transaction hash %q", tx.Hash)}
+		}
+	}
+	return &mempool, nil
+}
+
+/*
+ * MempoolTransaction returns a pending transaction with its operations
+ *
+ * A 404 answer, as given by servers for a hash that just left the
+ * mempool, yields ErrNotInMempool; other failures are returned as is.
+ */
+func (c *MeshAPIClient) MempoolTransaction(ctx context.Context, txID string) (*MempoolTransaction, error) {
+	request := struct {
+		NetworkIdentifier     NetworkIdentifier     `json:"network_identifier"`
+		TransactionIdentifier TransactionIdentifier `json:"transaction_identifier"`
+	}{c.network, TransactionIdentifier{Hash: "0x" + strings.TrimPrefix(txID, "0x")}}
+
+	var tx MempoolTransaction
+	if err := c.post(ctx, "/mempool/transaction", request, &tx); err != nil {
+		var meshErr *MeshError
+		if errors.As(err, &meshErr) && meshErr.StatusCode == http.StatusNotFound {
+			return nil, ErrNotInMempool
+		}
+		return nil, err
+	}
+	if err := c.checkTransactions("/mempool/transaction", tx.Transaction); err != nil {
+		return nil, err
+	}
+	return &tx, nil
+}
+
+// Block returns the block at a height with its transactions
+func (c *MeshAPIClient) Block(ctx context.Context, index uint64) (*Block, error) {
+	request := struct {
+		NetworkIdentifier NetworkIdentifier `json:"network_identifier"`
+		BlockIdentifier   struct {
+			Index uint64 `json:"index"`
+		} `json:"block_identifier"`
+	}{NetworkIdentifier: c.network}
+	request.BlockIdentifier.Index = index
+
+	var block Block
+	if err := c.post(ctx, "/block", request, &block); err != nil {
+		return nil, err
+	}
+	if err := c.checkTransactions("/block", block.Block.Transactions...); err != nil {
+		return nil, err
+	}
+	return &block, nil
+}
+
+// BlockByHash returns the block with a hash (with or without 0x) with its transactions; an unknown hash yields a *MeshError
+func (c *MeshAPIClient) BlockByHash(ctx context.Context, hash string) (*Block, error) {
+	request := struct {
+		NetworkIdentifier NetworkIdentifier `json:"network_identifier"`
+		BlockIdentifier   struct {
+			Hash string `json:"hash"`
+		} `json:"block_identifier"`
+	}{NetworkIdentifier: c.network}
+	request.BlockIdentifier.Hash = "0x" + trimHash(hash)
+
+	var block Block
+	if err := c.post(ctx, "/block", request, &block); err != nil {
+		return nil, err
+	}
+	if err := c.checkTransactions("/block", block.Block.Transactions...); err != nil {
+		return nil, err
+	}
+	return &block, nil
+}
+
+/*
+ * BlockTransaction looks a transaction up by its hash (with or without 0x)
+ *
+ * Only the transaction identifier is sent: the Mochimo Mesh API finds the
+ * block itself. A transaction it does not know yields a *MeshError for
+ * which TransactionNotFound is true.
+ */
+func (c *MeshAPIClient) BlockTransaction(ctx context.Context, txID string) (*BlockTransaction, error) {
+	request := struct {
+		NetworkIdentifier     NetworkIdentifier     `json:"network_identifier"`
+		TransactionIdentifier TransactionIdentifier `json:"transaction_identifier"`
+	}{c.network, TransactionIdentifier{Hash: "0x" + strings.TrimPrefix(txID, "0x")}}
+
+	var tx BlockTransaction
+	if err := c.post(ctx, "/block/transaction", request, &tx); err != nil {
+		return nil, err
+	}
+	if err := c.checkTransactions("/block/transaction", tx.Transaction); err != nil {
+		return nil, err
+	}
+	return &tx, nil
+}
+
+// BlockTransactionIn fetches a transaction of a given block, as listed in its other_transactions
+func (c *MeshAPIClient) BlockTransactionIn(ctx context.Context, block BlockIdentifier, txID string) (*BlockTransaction, error) {
+	request := struct {
+		NetworkIdentifier     NetworkIdentifier     `json:"network_identifier"`
+		BlockIdentifier       BlockIdentifier       `json:"block_identifier"`
+		TransactionIdentifier TransactionIdentifier `json:"transaction_identifier"`
+	}{c.network, block, TransactionIdentifier{Hash: "0x" + strings.TrimPrefix(txID, "0x")}}
+
+	var tx BlockTransaction
+	if err := c.post(ctx, "/block/transaction", request, &tx); err != nil {
+		return nil, err
+	}
+	if err := c.checkTransactions("/block/transaction", tx.Transaction); err != nil {
+		return nil, err
+	}
+	return &tx, nil
+}
+
+// BlockCheck is the outcome of looking for a transaction in the block at a height
+type BlockCheck struct {
+	// Block identifies the block read, zero if it could not be fetched
+	Block BlockIdentifier
+	// Included reports whether the block holds the transaction; meaningless when Err is set
+	Included bool
+	// Err is set when the block or the lookup of its other_transactions failed, so presence is unknown
+	Err error
+}
+
+/*
+ * CheckBlock looks for a transaction in the block at a height
+ *
+ * Only the transaction identifiers of the block are compared, ignoring 0x
+ * prefixes: a hash merely appearing elsewhere in the block (e.g. as a
+ * related transaction) does not count. Servers may truncate the inline
+ * transaction list of large blocks and list the rest as other_transactions
+ * only. A transaction found there is fetched through /block/transaction to
+ * confirm the block really holds it; an answer that the transaction is
+ * not found (see TransactionNotFound) means it does not, any other error
+ * sets Err.
+ *
+ * "Block read, transaction absent" is Included false with a nil Err, while
+ * a failed fetch sets Err: callers must not take it for an absence.
+ */
+func (c *MeshAPIClient) CheckBlock(ctx context.Context, index uint64, txID string) BlockCheck {
+	block, err := c.Block(ctx, index)
+	if err != nil {
+		return BlockCheck{Err: err}
+	}
+	check := BlockCheck{Block: block.Block.BlockIdentifier}
+	if block.Contains(txID) {
+		check.Included = true
+		return check
+	}
+	if !block.ListsOther(txID) {
+		return check
+	}
+
+	tx, err := c.BlockTransactionIn(ctx, block.Block.BlockIdentifier, txID)
+	if TransactionNotFound(err) {
+		return check
+	}
+	if err != nil {
+		check.Err = err
+		return check
+	}
+	check.Included = sameHash(tx.Transaction.TransactionIdentifier.Hash, txID)
+	return check
+}
+
+// BlockHasTransaction reports whether the block at a height holds a transaction, see CheckBlock
+func (c *MeshAPIClient) BlockHasTransaction(ctx context.Context, index uint64, txID string) (bool, error) {
+	check := c.CheckBlock(ctx, index, txID)
+	return check.Included, check.Err
+}
+
+// SubmitTransaction broadcasts a signed transaction (hex) and returns its identifier
+// A rejection for the fee is a *FeeTooLowError, errors.Is(err, ErrFeeTooLow),
+// one for the signature or the ownership of the source a *SignatureRejectedError
+func (c *MeshAPIClient) SubmitTransaction(ctx context.Context, signedTx string) (*SubmitResult, error) {
+	request := struct {
+		NetworkIdentifier NetworkIdentifier `json:"network_identifier"`
+		SignedTransaction string            `json:"signed_transaction"`
+	}{c.network, signedTx}
+
+	var result SubmitResult
+	if err := c.post(ctx, "/construction/submit", request, &result); err != nil {
+		if feeErr := feeTooLow(err); feeErr != nil {
+			return nil, feeErr
+		}
+		if signatureErr := signatureRejected(err); signatureErr != nil {
+			return nil, signatureErr
+		}
+		return nil, err
+	}
+	return &result, nil
+}
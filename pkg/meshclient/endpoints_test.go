@@ -0,0 +1,283 @@
+package meshclient
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// testHash is a transaction hash of the answers
+var testHash = "0xab12" + strings.Repeat("00", 30)
+
+// exchange is one request the test server received
+type exchange struct {
+	path string
+	body map[string]interface{}
+}
+
+/*
+ * testServer answers every request with status and answer, recording the
+ * path and decoded body of the requests
+ */
+func testServer(t *testing.T, status int, answer string) (*MeshAPIClient, *[]exchange) {
+	t.Helper()
+	var received []exchange
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]interface{}
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			t.Errorf("%s: request body: %v", r.URL.Path, err)
+		}
+		if r.Method != http.MethodPost || r.Header.Get("Content-Type") != "application/json" {
+			t.Errorf("%s: %s request of %q", r.URL.Path, r.Method, r.Header.Get("Content-Type"))
+		}
+		received = append(received, exchange{r.URL.Path, body})
+		w.WriteHeader(status)
+		io.WriteString(w, answer)
+	}))
+	t.Cleanup(server.Close)
+	return NewMeshAPIClient(server.URL, nil), &received
+}
+
+// checkRequest fails unless the only request went to path with a body matching want once encoded
+func checkRequest(t *testing.T, received []exchange, path string, want string) {
+	t.Helper()
+	if len(received) != 1 {
+		t.Fatalf("%d requests, want 1", len(received))
+	}
+	var wantBody map[string]interface{}
+	if err := json.Unmarshal([]byte(want), &wantBody); err != nil {
+		t.Fatal(err)
+	}
+	got, _ := json.Marshal(received[0].body)
+	expected, _ := json.Marshal(wantBody)
+	if received[0].path != path || string(got) != string(expected) {
+		t.Errorf("request to %s: %s\nwant to %s: %s", received[0].path, got, path, expected)
+	}
+}
+
+const network = `"network_identifier":{"blockchain":"mochimo","network":"mainnet"}`
+
+func TestAccountBalance(t *testing.T) {
+	tag := make([]byte, 20)
+	tag[0] = 0x42
+	client, received := testServer(t, http.StatusOK, `{"block_identifier":{"index":7,"hash":"0x01"},
+		"balances":[{"value":"1500","currency":{"symbol":"MCM","decimals":9}}]}`)
+	balance, err := client.AccountBalance(context.Background(), tag)
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkRequest(t, *received, "/account/balance", `{`+network+`,"account_identifier":{"address":"0x4200000000000000000000000000000000000000"}}`)
+	if value, err := balance.Value(); err != nil || value != 1500 || balance.BlockIdentifier.Index != 7 {
+		t.Errorf("balance %+v, %d, %v", balance, value, err)
+	}
+
+	client, _ = testServer(t, http.StatusOK, `{"block_identifier":{"index":7,"hash":"0x01"},"balances":[]}`)
+	if balance, err := client.AccountBalance(context.Background(), tag); err != nil {
+		t.Fatal(err)
+	} else if value, err := balance.Value(); err != nil || value != 0 {
+		t.Errorf("no balances: %d, %v", value, err)
+	}
+
+	client, _ = testServer(t, http.StatusOK, `{"balances":[{"value":"-1","currency":{"symbol":"MCM","decimals":9}}]}`)
+	if balance, err := client.AccountBalance(context.Background(), tag); err != nil {
+		t.Fatal(err)
+	} else if _, err := balance.Value(); err == nil {
+		t.Error("no error for a negative balance")
+	}
+}
+
+func TestNetworkStatus(t *testing.T) {
+	client, received := testServer(t, http.StatusOK, `{"current_block_identifier":{"index":1200,"hash":"0xaa"},
+		"current_block_timestamp":1700000000,"genesis_block_identifier":{"index":0,"hash":"0x00"}}`)
+	status, err := client.NetworkStatus(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkRequest(t, *received, "/network/status", `{`+network+`}`)
+	if status.CurrentBlockIdentifier != (BlockIdentifier{1200, "0xaa"}) || status.CurrentBlockTimestamp != 1700000000 {
+		t.Errorf("status %+v", status)
+	}
+}
+
+func TestMempool(t *testing.T) {
+	client, received := testServer(t, http.StatusOK, `{"transaction_identifiers":[{"hash":"`+testHash+`"}]}`)
+	mempool, err := client.Mempool(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkRequest(t, *received, "/mempool", `{`+network+`}`)
+	if !mempool.Contains(strings.ToUpper(testHash[2:])) || mempool.Contains("0x01") {
+		t.Errorf("mempool %+v", mempool)
+	}
+}
+
+func TestBlock(t *testing.T) {
+	client, received := testServer(t, http.StatusOK, `{"block":{"block_identifier":{"index":9,"hash":"0x09"},
+		"parent_block_identifier":{"index":8,"hash":"0x08"},"timestamp":1700000000000,
+		"transactions":[{"transaction_identifier":{"hash":"0x01"}}]}}`)
+	block, err := client.Block(context.Background(), 9)
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkRequest(t, *received, "/block", `{`+network+`,"block_identifier":{"index":9}}`)
+	if block.Block.ParentBlockIdentifier.Index != 8 || !block.Contains("01") || block.Contains(testHash) {
+		t.Errorf("block %+v", block)
+	}
+}
+
+func TestBlockIdentifierSame(t *testing.T) {
+	block := BlockIdentifier{Index: 9, Hash: "0x0a09"}
+	for _, tc := range []struct {
+		other BlockIdentifier
+		want  bool
+	}{
+		{BlockIdentifier{Index: 9, Hash: "0x0a09"}, true},
+		{BlockIdentifier{Index: 9, Hash: "0A09"}, true},
+		{BlockIdentifier{Index: 8, Hash: "0x0a09"}, false},
+		{BlockIdentifier{Index: 9, Hash: "0x0b09"}, false},
+		{BlockIdentifier{Index: 9}, false},
+	} {
+		if got := block.Same(tc.other); got != tc.want {
+			t.Errorf("%+v: %v, want %v", tc.other, got, tc.want)
+		}
+	}
+}
+
+func TestBlockTransaction(t *testing.T) {
+	client, received := testServer(t, http.StatusOK, `{"transaction":{"transaction_identifier":{"hash":"`+testHash+`"}}}`)
+	tx, err := client.BlockTransaction(context.Background(), testHash[2:])
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkRequest(t, *received, "/block/transaction", `{`+network+`,"transaction_identifier":{"hash":"`+testHash+`"}}`)
+	if tx.Transaction.TransactionIdentifier.Hash != testHash {
+		t.Errorf("transaction %+v", tx)
+	}
+}
+
+func TestSubmitTransaction(t *testing.T) {
+	client, received := testServer(t, http.StatusOK, `{"transaction_identifier":{"hash":"`+testHash+`"}}`)
+	result, err := client.SubmitTransaction(context.Background(), "0xdeadbeef")
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkRequest(t, *received, "/construction/submit", `{`+network+`,"signed_transaction":"0xdeadbeef"}`)
+	if result.TransactionIdentifier.Hash != testHash {
+		t.Errorf("result %+v", result)
+	}
+}
+
+// TestCheckBlockOtherTransactions checks the lookup of a transaction listed in other_transactions
+func TestCheckBlockOtherTransactions(t *testing.T) {
+	block := `{"block":{"block_identifier":{"index":9,"hash":"0x09"},"transactions":[]},"other_transactions":[{"hash":"` + testHash + `"}]}`
+	for _, tc := range []struct {
+		name     string
+		status   int
+		answer   string
+		included bool
+		failed   bool
+	}{
+		{"found", http.StatusOK, `{"transaction":{"transaction_identifier":{"hash":"` + testHash + `"}}}`, true, false},
+		{"not found code", http.StatusInternalServerError, `{"code":5,"message":"transaction not found"}`, false, false},
+		{"404", http.StatusNotFound, "not found", false, false},
+		{"other code", http.StatusInternalServerError, `{"code":2,"message":"internal error","retriable":true}`, false, true},
+		{"throttled", http.StatusTooManyRequests, "slow down", false, true},
+	} {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Path == "/block" {
+				io.WriteString(w, block)
+				return
+			}
+			w.WriteHeader(tc.status)
+			io.WriteString(w, tc.answer)
+		}))
+		check := NewMeshAPIClient(server.URL, nil).CheckBlock(context.Background(), 9, testHash)
+		server.Close()
+		if check.Included != tc.included || (check.Err != nil) != tc.failed || check.Block.Index != 9 {
+			t.Errorf("%s: %+v", tc.name, check)
+		}
+	}
+}
+
+func TestMempoolTransaction(t *testing.T) {
+	client, received := testServer(t, http.StatusOK, `{"transaction":{"transaction_identifier":{"hash":"`+testHash+`"},
+		"operations":[{"operation_identifier":{"index":0},"type":"TRANSFER"}]}}`)
+	tx, err := client.MempoolTransaction(context.Background(), testHash[2:])
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkRequest(t, *received, "/mempool/transaction", `{`+network+`,"transaction_identifier":{"hash":"`+testHash+`"}}`)
+	if tx.Transaction.TransactionIdentifier.Hash != testHash || len(tx.Transaction.Operations) != 1 {
+		t.Errorf("transaction %+v", tx)
+	}
+
+	client, _ = testServer(t, http.StatusNotFound, "")
+	if _, err := client.MempoolTransaction(context.Background(), testHash); !errors.Is(err, ErrNotInMempool) {
+		t.Errorf("404: %v", err)
+	}
+	client, _ = testServer(t, http.StatusBadGateway, "")
+	if _, err := client.MempoolTransaction(context.Background(), testHash); err == nil || errors.Is(err, ErrNotInMempool) {
+		t.Errorf("502: %v", err)
+	}
+}
+
+// TestCheckBlockSubstring answers blocks naming the transaction outside the identifiers of their transactions
+func TestCheckBlockSubstring(t *testing.T) {
+	txID := strings.TrimPrefix(testHash, "0x")
+	other := `{"transaction_identifier":{"hash":"0x` + strings.Repeat("cd", 32) + `"}`
+	for _, tc := range []struct {
+		name         string
+		transactions string
+		extra        string
+		included     bool
+	}{
+		{"inside a longer hash", `{"transaction_identifier":{"hash":"0xff` + txID + `"}}`, ``, false},
+		{"truncated hash", `{"transaction_identifier":{"hash":"` + testHash[:40] + `"}}`, ``, false},
+		{"related transaction", other + `,"related_transactions":[{"transaction_identifier":{"hash":"` + testHash + `"},"direction":"backward"}]}`, ``, false},
+		{"operation metadata", other + `,"operations":[{"operation_identifier":{"index":0},"type":"FEE","metadata":{"replaces":"` + testHash + `"}}]}`, ``, false},
+		{"block metadata", other + `}`, `,"metadata":{"note":"` + testHash + `"}`, false},
+		{"uppercase", `{"transaction_identifier":{"hash":"0X` + strings.ToUpper(txID) + `"}}`, ``, true},
+		{"no prefix", `{"transaction_identifier":{"hash":"` + txID + `"}}`, ``, true},
+	} {
+		client, count := routeServer(t, map[string]string{
+			"/block": `{"block":{"block_identifier":{"index":9,"hash":"0x09"},"transactions":[` + tc.transactions + `]` + tc.extra + `}}`,
+		})
+		check := client.CheckBlock(context.Background(), 9, txID)
+		if check.Included != tc.included || check.Err != nil || check.Block.Index != 9 {
+			t.Errorf("%s: %+v", tc.name, check)
+		}
+		if count("/block/transaction") != 0 {
+			t.Errorf("%s: looked up a transaction not in other_transactions", tc.name)
+		}
+	}
+
+	// A block that cannot be fetched says nothing of the transaction
+	client, _ := routeServer(t, nil)
+	if check := client.CheckBlock(context.Background(), 9, txID); check.Included || check.Err == nil || check.Block.Index != 0 {
+		t.Errorf("failed block: %+v", check)
+	}
+}
+
+func TestTransactionNotFound(t *testing.T) {
+	for _, tc := range []struct {
+		err  error
+		want bool
+	}{
+		{&MeshError{StatusCode: 500, Schema: true, Code: CodeTransactionNotFound}, true},
+		{&MeshError{StatusCode: 404}, true},
+		{&MeshError{StatusCode: 500, Code: CodeTransactionNotFound}, false},
+		{&MeshError{StatusCode: 500, Schema: true, Code: 2}, false},
+		{&MeshError{StatusCode: 429}, false},
+		{&TransportError{Path: "/block/transaction", Err: context.Canceled}, false},
+		{nil, false},
+	} {
+		if got := TransactionNotFound(tc.err); got != tc.want {
+			t.Errorf("%v: %v, want %v", tc.err, got, tc.want)
+		}
+	}
+}
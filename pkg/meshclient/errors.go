@@ -0,0 +1,312 @@
+package meshclient
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ErrTagNotFound is returned by ResolveTag when the API answers but knows no account with the tag
+var ErrTagNotFound = errors.New("TAG not found")
+
+// ErrNotInMempool is returned by MempoolTransaction when the server answers 404, e.g. for a just evicted hash
+var ErrNotInMempool = errors.New("transaction not in mempool")
+
+/*
+ * Error codes of the Mesh API answers the client classifies, as meshmock
+ * numbers them
+ *
+ * Only the codes callers branch on are named: the rejections of
+ * SubmitTransaction and a transaction /block/transaction does not know.
+ */
+const (
+	CodeInvalidRequest      = 1
+	CodeTransactionNotFound = 5
+	CodeInvalidTransaction  = 6
+	CodeSignatureInvalid    = 11
+	CodeSourceMismatch      = 12
+	CodeFeeTooLow           = 13
+)
+
+// submitCodes are the codes a /construction/submit rejection is classified by, before any text matching
+var submitCodes = map[int]bool{
+	CodeInvalidRequest:     true,
+	CodeInvalidTransaction: true,
+	CodeSignatureInvalid:   true,
+	CodeSourceMismatch:     true,
+	CodeFeeTooLow:          true,
+}
+
+// maxErrorBody bounds the body of a non-200 answer read into a *MeshError
+const maxErrorBody = 64 << 10
+
+/*
+ * MeshError is returned when the API answers with a status other than 200
+ *
+ * The body is decoded from the Rosetta error schema when possible; Schema
+ * reports whether it was, otherwise only StatusCode and the raw Body are set.
+ * Use errors.As to branch on Code or Retriable, and Throttled to tell rate
+ * limiting from real failures.
+ */
+type MeshError struct {
+	StatusCode  int                    `json:"-"`
+	Code        int                    `json:"code"`
+	Message     string                 `json:"message"`
+	Description string                 `json:"description,omitempty"`
+	Retriable   bool                   `json:"retriable"`
+	Details     map[string]interface{} `json:"details,omitempty"`
+	Schema      bool                   `json:"-"`
+	Body        string                 `json:"-"`
+	RequestID   string                 `json:"-"`
+	// RetryAfter is the wait asked by a Retry-After header, 0 if none
+	RetryAfter time.Duration `json:"-"`
+}
+
+// newMeshError decodes the body of a non-200 answer
+func newMeshError(statusCode int, header http.Header, body []byte) *MeshError {
+	body = bytes.TrimSpace(body)
+	e := &MeshError{StatusCode: statusCode, Body: string(body), RetryAfter: retryAfter(header, time.Now())}
+	var decoded MeshError
+	if json.Unmarshal(body, &decoded) == nil && decoded.Message != "" {
+		decoded.StatusCode, decoded.Body, decoded.Schema, decoded.RetryAfter = statusCode, e.Body, true, e.RetryAfter
+		return &decoded
+	}
+	return e
+}
+
+/*
+ * TransactionNotFound reports whether err is the API answering that it
+ * does not know a transaction: the CodeTransactionNotFound code or a 404
+ *
+ * Any other error, a throttled or failed answer included, says nothing of
+ * the transaction and must not be taken for its absence.
+ */
+func TransactionNotFound(err error) bool {
+	var meshErr *MeshError
+	if !errors.As(err, &meshErr) {
+		return false
+	}
+	return meshErr.StatusCode == http.StatusNotFound || meshErr.Schema && meshErr.Code == CodeTransactionNotFound
+}
+
+/*
+ * MaxRetryAfter caps the wait a Retry-After header can ask for: a longer
+ * one, a date far ahead or a count of seconds past any time.Duration
+ * included, is taken as this long
+ */
+const MaxRetryAfter = 5 * time.Minute
+
+// retryAfter parses a Retry-After header, in seconds or as an HTTP date, capped at MaxRetryAfter; 0 if absent, invalid or past
+func retryAfter(header http.Header, now time.Time) time.Duration {
+	value := strings.TrimSpace(header.Get("Retry-After"))
+	if value == "" {
+		return 0
+	}
+	// Out of range, ParseInt returns the closest int64: clamped before the conversion can overflow
+	if seconds, err := strconv.ParseInt(value, 10, 64); err == nil || errors.Is(err, strconv.ErrRange) {
+		if seconds >= int64(MaxRetryAfter/time.Second) {
+			return MaxRetryAfter
+		}
+		return time.Duration(max(seconds, 0)) * time.Second
+	}
+	if date, err := http.ParseTime(value); err == nil && date.After(now) {
+		return min(date.Sub(now), MaxRetryAfter)
+	}
+	return 0
+}
+
+/*
+ * Throttled reports whether err is the server rate limiting or shedding
+ * load, a 429 Too Many Requests or 503 Service Unavailable answer, and
+ * returns the wait it asked for with Retry-After, 0 if none, never more
+ * than MaxRetryAfter
+ *
+ * Such answers say "not now" rather than "failed": callers pacing
+ * themselves should wait instead of counting them as errors.
+ */
+func Throttled(err error) (time.Duration, bool) {
+	var meshErr *MeshError
+	if !errors.As(err, &meshErr) {
+		return 0, false
+	}
+	if meshErr.StatusCode != http.StatusTooManyRequests && meshErr.StatusCode != http.StatusServiceUnavailable {
+		return 0, false
+	}
+	return min(meshErr.RetryAfter, MaxRetryAfter), true
+}
+
+func (e *MeshError) Error() string {
+	suffix := requestSuffix(e.RequestID)
+	if e.RetryAfter > 0 {
+		suffix = fmt.Sprintf(" (retry after %v)", e.RetryAfter) + suffix
+	}
+	switch {
+	case e.Schema && e.Description != "":
+		return fmt.Sprintf("API error %d: %s (%s)%s", e.Code, e.Message, e.Description, suffix)
+	case e.Schema:
+		return fmt.Sprintf("API error %d: %s%s", e.Code, e.Message, suffix)
+	case e.Body != "":
+		return fmt.Sprintf("API returned status %d: %s%s", e.StatusCode, e.Body, suffix)
+	}
+	return fmt.Sprintf("API returned status %d%s", e.StatusCode, suffix)
+}
+
+// TransportError is returned when no answer was received (connection, timeout, cancellation)
+type TransportError struct {
+	Path      string
+	RequestID string
+	Err       error
+}
+
+func (e *TransportError) Error() string {
+	return fmt.Sprintf("request to %s failed: %v%s", e.Path, e.Err, requestSuffix(e.RequestID))
+}
+
+func (e *TransportError) Unwrap() error { return e.Err }
+
+// DecodeError is returned when a 200 answer cannot be decoded
+type DecodeError struct {
+	Path      string
+	RequestID string
+	Err       error
+}
+
+func (e *DecodeError) Error() string {
+	return fmt.Sprintf("failed to decode %s response: %v%s", e.Path, e.Err, requestSuffix(e.RequestID))
+}
+
+func (e *DecodeError) Unwrap() error { return e.Err }
+
+// ErrFeeTooLow is wrapped by every *FeeTooLowError, for errors.Is
+var ErrFeeTooLow = errors.New("transaction fee too low")
+
+// FeeTooLowError is returned by SubmitTransaction when the node rejects a transaction for its fee
+type FeeTooLowError struct {
+	// Minimum is the lowest fee the node accepts, in nanoMCM, when its answer gives one
+	Minimum uint64
+	// MinimumKnown is false when the answer gives no minimum
+	MinimumKnown bool
+	Err          *MeshError
+}
+
+func (e *FeeTooLowError) Error() string {
+	if e.MinimumKnown {
+		return fmt.Sprintf("fee too low, the node asks for at least %d nMCM: %v", e.Minimum, e.Err)
+	}
+	return fmt.Sprintf("fee too low: %v", e.Err)
+}
+
+// Unwrap gives both ErrFeeTooLow and the *MeshError of the answer
+func (e *FeeTooLowError) Unwrap() []error { return []error{ErrFeeTooLow, e.Err} }
+
+// feeText is the fallback of feeTooLow for unknown codes: the error text mentions a fee that is too low
+func feeText(meshErr *MeshError) bool {
+	details, _ := json.Marshal(meshErr.Details)
+	text := strings.ToLower(meshErr.Message + " " + meshErr.Description + " " + string(details))
+	if !strings.Contains(text, "fee") {
+		return false
+	}
+	for _, hint := range []string{"too low", "insufficient", "minimum", "below", "too small"} {
+		if strings.Contains(text, hint) {
+			return true
+		}
+	}
+	return false
+}
+
+// minimumFeeDetails are the details keys servers use for the lowest fee accepted
+var minimumFeeDetails = []string{"minimum_fee", "min_fee", "required_fee", "suggested_fee", "minimum"}
+
+/*
+ * feeTooLow returns the *FeeTooLowError of a rejected submit, or nil if the
+ * rejection is not about the fee
+ *
+ * The Rosetta error code decides first: CodeFeeTooLow is a fee rejection
+ * and the other codes of submitCodes are not. Only for a code the client
+ * does not know, as from a server numbering its errors otherwise, are the
+ * message, description and details searched for a fee that is too low,
+ * insufficient or under a minimum. The minimum is read from the details,
+ * as a number or a string of nanoMCM.
+ */
+func feeTooLow(err error) *FeeTooLowError {
+	var meshErr *MeshError
+	if !errors.As(err, &meshErr) || !meshErr.Schema {
+		return nil
+	}
+	if known := submitCodes[meshErr.Code]; known && meshErr.Code != CodeFeeTooLow || !known && !feeText(meshErr) {
+		return nil
+	}
+
+	feeErr := &FeeTooLowError{Err: meshErr}
+	for _, key := range minimumFeeDetails {
+		var value string
+		switch v := meshErr.Details[key].(type) {
+		case float64:
+			value = strconv.FormatFloat(v, 'f', -1, 64)
+		case string:
+			value = v
+		default:
+			continue
+		}
+		if minimum, err := strconv.ParseUint(strings.TrimSpace(value), 10, 64); err == nil {
+			feeErr.Minimum, feeErr.MinimumKnown = minimum, true
+			break
+		}
+	}
+	return feeErr
+}
+
+// ErrSignatureRejected is wrapped by every *SignatureRejectedError, for errors.Is
+var ErrSignatureRejected = errors.New("transaction signature rejected")
+
+/*
+ * SignatureRejectedError is returned by SubmitTransaction when the node
+ * rejects a transaction because its signature does not verify or its key
+ * does not own the source address
+ *
+ * With WOTS+ addresses this usually means the transaction was signed by
+ * another key of the wallet than the one the tag currently belongs to.
+ */
+type SignatureRejectedError struct {
+	Err *MeshError
+}
+
+func (e *SignatureRejectedError) Error() string {
+	return fmt.Sprintf("signature rejected: %v", e.Err)
+}
+
+// Unwrap gives both ErrSignatureRejected and the *MeshError of the answer
+func (e *SignatureRejectedError) Unwrap() []error { return []error{ErrSignatureRejected, e.Err} }
+
+/*
+ * signatureRejected returns the *SignatureRejectedError of a rejected
+ * submit, or nil
+ *
+ * As in feeTooLow the code decides first, CodeSignatureInvalid and
+ * CodeSourceMismatch being signature rejections; the error text is only
+ * searched for a code the client does not know.
+ */
+func signatureRejected(err error) *SignatureRejectedError {
+	var meshErr *MeshError
+	if !errors.As(err, &meshErr) || !meshErr.Schema {
+		return nil
+	}
+	if submitCodes[meshErr.Code] {
+		if meshErr.Code == CodeSignatureInvalid || meshErr.Code == CodeSourceMismatch {
+			return &SignatureRejectedError{Err: meshErr}
+		}
+		return nil
+	}
+	text := strings.ToLower(meshErr.Message + " " + meshErr.Description)
+	for _, hint := range []string{"signature", "owner", "does not control", "source address mismatch", "wrong source"} {
+		if strings.Contains(text, hint) {
+			return &SignatureRejectedError{Err: meshErr}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,141 @@
+package meshclient
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSubmitRejections(t *testing.T) {
+	for _, tc := range []struct {
+		name      string
+		answer    string
+		fee       bool
+		minimum   uint64
+		signature bool
+	}{
+		{"fee code", `{"code":13,"message":"fee too low","details":{"minimum_fee":500}}`, true, 500, false},
+		{"fee code, no fee in the text", `{"code":13,"message":"rejected","details":{"min_fee":"800"}}`, true, 800, false},
+		{"signature code", `{"code":11,"message":"invalid transaction","description":"signature does not verify"}`, false, 0, true},
+		{"source mismatch code", `{"code":12,"message":"invalid transaction","description":"the tag belongs to 0x01"}`, false, 0, true},
+		// A known code wins over a text that would match the other class
+		{"signature code, fee in the text", `{"code":11,"message":"signature invalid, fee below minimum"}`, false, 0, true},
+		{"fee code, signature in the text", `{"code":13,"message":"fee too low for this signature"}`, true, 0, false},
+		{"invalid transaction code", `{"code":6,"message":"invalid signed transaction: fee too low, bad signature"}`, false, 0, false},
+		// Unknown codes fall back to the text
+		{"unknown code, fee text", `{"code":42,"message":"Insufficient fee","description":"below the minimum","details":{"required_fee":"1000"}}`, true, 1000, false},
+		{"unknown code, signature text", `{"code":42,"message":"wrong source address"}`, false, 0, true},
+		{"unknown code, other text", `{"code":42,"message":"node is syncing"}`, false, 0, false},
+		{"not the Rosetta schema", `fee too low, bad signature`, false, 0, false},
+	} {
+		client, _ := testServer(t, http.StatusInternalServerError, tc.answer)
+		_, err := client.SubmitTransaction(context.Background(), "0x00")
+		var meshErr *MeshError
+		if !errors.As(err, &meshErr) {
+			t.Errorf("%s: %v is not a *MeshError", tc.name, err)
+		}
+		var feeErr *FeeTooLowError
+		if errors.As(err, &feeErr) != tc.fee || errors.Is(err, ErrFeeTooLow) != tc.fee {
+			t.Errorf("%s: fee too low %v, want %v", tc.name, err, tc.fee)
+		} else if tc.fee && (feeErr.Minimum != tc.minimum || feeErr.MinimumKnown != (tc.minimum != 0)) {
+			t.Errorf("%s: minimum %d (%v), want %d", tc.name, feeErr.Minimum, feeErr.MinimumKnown, tc.minimum)
+		}
+		if errors.Is(err, ErrSignatureRejected) != tc.signature {
+			t.Errorf("%s: signature rejected %v, want %v", tc.name, err, tc.signature)
+		}
+		if (tc.fee || tc.signature) && DefaultRetryable(err) {
+			t.Errorf("%s: rejection is retryable", tc.name)
+		}
+	}
+}
+
+func TestMeshError(t *testing.T) {
+	client, _ := testServer(t, http.StatusInternalServerError, ` {"code":2,"message":"internal error","description":"node down","retriable":true,"details":{"node":"n1"}} `)
+	_, err := client.NetworkStatus(context.Background())
+	var meshErr *MeshError
+	if !errors.As(err, &meshErr) {
+		t.Fatalf("got %v", err)
+	}
+	if !meshErr.Schema || meshErr.StatusCode != 500 || meshErr.Code != 2 || !meshErr.Retriable || meshErr.Details["node"] != "n1" {
+		t.Errorf("error %+v", meshErr)
+	}
+	if err.Error() != "API error 2: internal error (node down) [request "+meshErr.RequestID+"]" || len(meshErr.RequestID) != 16 {
+		t.Errorf("message %q", err)
+	}
+
+	// A body outside the Rosetta schema is kept raw
+	client, _ = testServer(t, http.StatusBadGateway, "<html>bad gateway</html>\n")
+	_, err = client.Mempool(context.Background())
+	if !errors.As(err, &meshErr) || meshErr.Schema || meshErr.Body != "<html>bad gateway</html>" {
+		t.Fatalf("got %v", err)
+	}
+	if !strings.HasPrefix(err.Error(), "API returned status 502: <html>bad gateway</html> [request ") {
+		t.Errorf("message %q", err)
+	}
+
+	client, _ = testServer(t, http.StatusBadGateway, "")
+	if _, err := client.Mempool(context.Background()); err == nil || !strings.HasPrefix(err.Error(), "API returned status 502 [request ") {
+		t.Errorf("empty body: %v", err)
+	}
+}
+
+// TestMeshErrorBodyLimit checks an oversized error body is cut at maxErrorBody
+func TestMeshErrorBodyLimit(t *testing.T) {
+	client, _ := testServer(t, http.StatusBadGateway, strings.Repeat("x", 4*maxErrorBody))
+	_, err := client.NetworkStatus(context.Background())
+	var meshErr *MeshError
+	if !errors.As(err, &meshErr) || len(meshErr.Body) != maxErrorBody {
+		t.Errorf("got %v, want a body of %d bytes", err, maxErrorBody)
+	}
+}
+
+func TestDecodeFailure(t *testing.T) {
+	client, _ := testServer(t, http.StatusOK, "not json")
+	_, err := client.Block(context.Background(), 1)
+	var decodeErr *DecodeError
+	if !errors.As(err, &decodeErr) || decodeErr.Path != "/block" || !strings.Contains(err.Error(), "failed to decode /block response") {
+		t.Errorf("got %v", err)
+	}
+}
+
+func TestThrottled(t *testing.T) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	for _, tc := range []struct {
+		value string
+		want  time.Duration
+	}{
+		{"", 0},
+		{"7", 7 * time.Second},
+		{"-3", 0},
+		{"soon", 0},
+		{now.Add(90 * time.Second).Format(http.TimeFormat), 90 * time.Second},
+		{now.Add(-time.Hour).Format(http.TimeFormat), 0},
+		// Waits past MaxRetryAfter, or past any time.Duration, are clamped
+		{"86400", MaxRetryAfter},
+		{"9223372036854775807", MaxRetryAfter},
+		{"99999999999999999999", MaxRetryAfter},
+		{"-99999999999999999999", 0},
+		{now.AddDate(1, 0, 0).Format(http.TimeFormat), MaxRetryAfter},
+	} {
+		header := http.Header{}
+		header.Set("Retry-After", tc.value)
+		if got := retryAfter(header, now); got != tc.want {
+			t.Errorf("Retry-After %q: %v, want %v", tc.value, got, tc.want)
+		}
+	}
+
+	if wait, _ := Throttled(&MeshError{StatusCode: 429, RetryAfter: 1000 * time.Hour}); wait != MaxRetryAfter {
+		t.Errorf("throttled for %v", wait)
+	}
+	for status, want := range map[int]bool{429: true, 503: true, 500: false, 404: false} {
+		if _, throttled := Throttled(&MeshError{StatusCode: status}); throttled != want {
+			t.Errorf("status %d: throttled %v", status, throttled)
+		}
+	}
+	if _, throttled := Throttled(errors.New("other")); throttled {
+		t.Error("a plain error is throttled")
+	}
+}
@@ -0,0 +1,47 @@
+package meshclient
+
+import "sync"
+
+// failover holds the endpoints tried after the one given to NewMeshAPIClient, and which one is in use
+type failover struct {
+	mu        sync.Mutex
+	endpoints []string
+	active    int
+}
+
+/*
+ * SetFailover sets the endpoints tried in turn when the current one cannot
+ * be reached
+ *
+ * A request failing with a *TransportError moves the client to the next
+ * endpoint, the first one again after the last; the retries of the
+ * RetryPolicy, if any, then go there. Answers with an error status do not
+ * move the client: the node answered. With no endpoints, the client keeps
+ * the one given to NewMeshAPIClient.
+ */
+func (c *MeshAPIClient) SetFailover(endpoints ...string) {
+	c.failover.mu.Lock()
+	defer c.failover.mu.Unlock()
+	c.failover.endpoints = append([]string(nil), endpoints...)
+	c.failover.active = 0
+}
+
+// current returns the endpoint requests go to and its position, 0 for the one given to NewMeshAPIClient
+func (c *MeshAPIClient) current() (string, int) {
+	c.failover.mu.Lock()
+	defer c.failover.mu.Unlock()
+	if c.failover.active == 0 {
+		return c.endpoint, 0
+	}
+	return c.failover.endpoints[c.failover.active-1], c.failover.active
+}
+
+// failed moves to the next endpoint after a transport error at position, unless another request already moved on
+func (c *MeshAPIClient) failed(position int) {
+	c.failover.mu.Lock()
+	defer c.failover.mu.Unlock()
+	if len(c.failover.endpoints) == 0 || c.failover.active != position {
+		return
+	}
+	c.failover.active = (position + 1) % (len(c.failover.endpoints) + 1)
+}
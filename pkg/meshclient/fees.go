@@ -0,0 +1,287 @@
+package meshclient
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"sync"
+)
+
+// DefaultHistogramBuckets is the number of buckets of SummarizeFees when none is given
+const DefaultHistogramBuckets = 10
+
+/*
+ * BlockFees is the fee paid by each transaction of a block, in nanoMCM
+ *
+ * Transactions without a source, like the block reward, pay no fee and are
+ * not listed, so an empty block and a block of rewards both have no fees.
+ */
+type BlockFees struct {
+	Height     uint64   `json:"height"`
+	Hash       string   `json:"hash"`
+	ParentHash string   `json:"parentHash"`
+	Fees       []uint64 `json:"fees"`
+	// Err is set, and Fees is not, when the block could not be fetched or decoded
+	Err error `json:"-"`
+}
+
+// FeeBucket counts the fees from From to To, both included
+type FeeBucket struct {
+	From  uint64 `json:"from"`
+	To    uint64 `json:"to"`
+	Count int    `json:"count"`
+}
+
+// FeeStats summarizes fees in nanoMCM; the fields other than Transactions are 0 when there is no fee
+type FeeStats struct {
+	Transactions int         `json:"transactions"`
+	Min          uint64      `json:"min"`
+	Median       uint64      `json:"median"`
+	P90          uint64      `json:"p90"`
+	Max          uint64      `json:"max"`
+	Histogram    []FeeBucket `json:"histogram"`
+}
+
+/*
+ * FeeSample is the fees of a range of recent blocks
+ *
+ * Blocks holds every block of the range that could be read, in height
+ * order, and Failed the heights of the others, which Stats leaves out.
+ */
+type FeeSample struct {
+	From   uint64      `json:"from"`
+	To     uint64      `json:"to"`
+	Blocks []BlockFees `json:"-"`
+	Failed []uint64    `json:"failed,omitempty"`
+	Stats  FeeStats    `json:"stats"`
+}
+
+// Empty returns the number of blocks of the sample holding no fee
+func (s *FeeSample) Empty() int {
+	empty := 0
+	for _, block := range s.Blocks {
+		if len(block.Fees) == 0 {
+			empty++
+		}
+	}
+	return empty
+}
+
+// Fees returns the fees of every block of the sample, in height order
+func (s *FeeSample) Fees() []uint64 {
+	var fees []uint64
+	for _, block := range s.Blocks {
+		fees = append(fees, block.Fees...)
+	}
+	return fees
+}
+
+/*
+ * BlockFeesAt reads the fees of the block at a height
+ *
+ * The transactions the server left out of the block (other_transactions)
+ * are fetched one by one, so the fees are complete or the call fails.
+ */
+func (c *MeshAPIClient) BlockFeesAt(ctx context.Context, height uint64) (BlockFees, error) {
+	block, err := c.Block(ctx, height)
+	if err != nil {
+		return BlockFees{}, err
+	}
+	result := BlockFees{
+		Height:     block.Block.BlockIdentifier.Index,
+		Hash:       block.Block.BlockIdentifier.Hash,
+		ParentHash: block.Block.ParentBlockIdentifier.Hash,
+		Fees:       []uint64{},
+	}
+	transfers, err := c.BlockTransfers(ctx, block)
+	if err != nil {
+		return BlockFees{}, err
+	}
+	for _, transfer := range transfers {
+		if transfer.Source != "" {
+			result.Fees = append(result.Fees, transfer.Fee)
+		}
+	}
+	return result, nil
+}
+
+/*
+ * StreamBlockFees reads the fees of many blocks, with at most
+ * SetBatchConcurrency requests in flight
+ *
+ * Blocks are sent on the channel as they are read, in no particular order;
+ * a block that cannot be read is sent with Err. The channel is closed once
+ * every height was sent, or ctx is done.
+ */
+func (c *MeshAPIClient) StreamBlockFees(ctx context.Context, heights []uint64) <-chan BlockFees {
+	concurrency := c.batchConcurrency
+	if concurrency <= 0 {
+		concurrency = DefaultBatchConcurrency
+	}
+
+	results := make(chan BlockFees)
+	go func() {
+		defer close(results)
+		var wg sync.WaitGroup
+		slots := make(chan struct{}, concurrency)
+		for _, height := range heights {
+			select {
+			case slots <- struct{}{}:
+			case <-ctx.Done():
+			}
+			if ctx.Err() != nil {
+				break
+			}
+			wg.Add(1)
+			go func(height uint64) {
+				defer wg.Done()
+				defer func() { <-slots }()
+				fees, err := c.BlockFeesAt(ctx, height)
+				if err != nil {
+					fees = BlockFees{Height: height, Err: err}
+				}
+				select {
+				case results <- fees:
+				case <-ctx.Done():
+				}
+			}(height)
+		}
+		wg.Wait()
+	}()
+	return results
+}
+
+/*
+ * RecentFees reads the fees of the last count blocks and summarizes them
+ *
+ * Parameters:
+ * - ctx: cancels the reads not yet made
+ * - count: the number of blocks, the tip included
+ * - known: blocks read earlier, e.g. by a previous run, reused instead of
+ *   being fetched again; nil fetches every block
+ * - onBlock: called with every block once read or failed, in no particular
+ *   order, known blocks excluded; nil for none
+ *
+ * Known blocks are checked against the chain: walking down from the tip,
+ * each block must be the parent of the one above it. A known block that is
+ * not, replaced by a reorg, is fetched again, and the walk goes on with the
+ * parent of the new block.
+ * Blocks that fail are listed in Failed and left out of the statistics; an
+ * error is returned only when the tip cannot be read or ctx is done.
+ */
+func (c *MeshAPIClient) RecentFees(ctx context.Context, count int, known map[uint64]BlockFees, onBlock func(BlockFees)) (*FeeSample, error) {
+	if count <= 0 {
+		return nil, fmt.Errorf("invalid block count %d", count)
+	}
+	status, err := c.NetworkStatus(ctx)
+	if err != nil {
+		return nil, err
+	}
+	tip := status.CurrentBlockIdentifier
+	sample := &FeeSample{To: tip.Index}
+	if uint64(count) <= tip.Index {
+		sample.From = tip.Index - uint64(count) + 1
+	}
+
+	blocks := make(map[uint64]BlockFees, count)
+	for height := sample.From; height <= sample.To; height++ {
+		if block, ok := known[height]; ok && block.Err == nil {
+			blocks[height] = block
+		}
+	}
+	fetched := make(map[uint64]bool, count)
+	fetch := func(heights []uint64) {
+		for block := range c.StreamBlockFees(ctx, heights) {
+			fetched[block.Height] = true
+			blocks[block.Height] = block
+			if onBlock != nil {
+				onBlock(block)
+			}
+		}
+	}
+
+	var missing []uint64
+	for height := sample.From; height <= sample.To; height++ {
+		if _, ok := blocks[height]; !ok {
+			missing = append(missing, height)
+		}
+	}
+	fetch(missing)
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	// Reorgs are shallow: the known blocks replaced are read again one by one, down to the fork
+	expected := tip.Hash
+	for height := sample.To; height >= sample.From && height <= sample.To; height-- {
+		if !fetched[height] && !sameHash(blocks[height].Hash, expected) {
+			fetch([]uint64{height})
+			if err := ctx.Err(); err != nil {
+				return nil, err
+			}
+		}
+		// A failed block breaks the walk, the known blocks below it are trusted
+		if blocks[height].Err != nil {
+			break
+		}
+		expected = blocks[height].ParentHash
+	}
+
+	for height := sample.From; height <= sample.To; height++ {
+		block := blocks[height]
+		if block.Err != nil {
+			sample.Failed = append(sample.Failed, height)
+			continue
+		}
+		sample.Blocks = append(sample.Blocks, block)
+	}
+	sample.Stats = SummarizeFees(sample.Fees(), DefaultHistogramBuckets)
+	return sample, nil
+}
+
+/*
+ * SummarizeFees computes the statistics of fees
+ *
+ * Percentiles use the nearest rank: the median is the fee at rank
+ * ceil(n/2) of the sorted fees, p90 the one at rank ceil(0.9 n). The
+ * histogram splits min to max in at most buckets ranges of equal width
+ * (DefaultHistogramBuckets when buckets <= 0), so identical fees make a
+ * single bucket.
+ */
+func SummarizeFees(fees []uint64, buckets int) FeeStats {
+	stats := FeeStats{Transactions: len(fees), Histogram: []FeeBucket{}}
+	if len(fees) == 0 {
+		return stats
+	}
+	if buckets <= 0 {
+		buckets = DefaultHistogramBuckets
+	}
+	sorted := slices.Clone(fees)
+	slices.Sort(sorted)
+	rank := func(percent int) uint64 {
+		return sorted[(len(sorted)*percent+99)/100-1]
+	}
+	stats.Min, stats.Max = sorted[0], sorted[len(sorted)-1]
+	stats.Median, stats.P90 = rank(50), rank(90)
+
+	// width is ceil((span+1)/buckets), without overflowing; the last bucket may be narrower
+	width := (stats.Max-stats.Min)/uint64(buckets) + 1
+	for from := stats.Min; ; from += width {
+		to := from + width - 1
+		if to >= stats.Max || to < from {
+			to = stats.Max
+		}
+		stats.Histogram = append(stats.Histogram, FeeBucket{From: from, To: to})
+		if to == stats.Max {
+			break
+		}
+	}
+	for _, fee := range sorted {
+		bucket := int((fee - stats.Min) / width)
+		if bucket >= len(stats.Histogram) {
+			bucket = len(stats.Histogram) - 1
+		}
+		stats.Histogram[bucket].Count++
+	}
+	return stats
+}
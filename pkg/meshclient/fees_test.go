@@ -0,0 +1,244 @@
+package meshclient_test
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"net/http/httputil"
+	"net/url"
+	"slices"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/NickP005/Vindax-MCM-tools/pkg/meshclient"
+	"github.com/NickP005/Vindax-MCM-tools/pkg/meshmock"
+)
+
+// feeTx is a transfer of 1000 nanoMCM paying fee, or a block reward without source when fee is 0
+func feeTx(hash string, fee uint64) meshclient.Transaction {
+	amount := func(value int64) *meshclient.Amount {
+		return &meshclient.Amount{Value: fmt.Sprint(value), Currency: meshclient.MCM}
+	}
+	payee := &meshclient.AccountIdentifier{Address: "0x" + strings.Repeat("b0", 20)}
+	if fee == 0 {
+		return meshclient.Transaction{
+			TransactionIdentifier: meshclient.TransactionIdentifier{Hash: hash},
+			Operations:            []meshclient.Operation{{Type: "REWARD", Account: payee, Amount: amount(5000)}},
+		}
+	}
+	source := &meshclient.AccountIdentifier{Address: "0x" + strings.Repeat("a1", 40)}
+	return meshclient.Transaction{
+		TransactionIdentifier: meshclient.TransactionIdentifier{Hash: hash},
+		Operations: []meshclient.Operation{
+			{OperationIdentifier: meshclient.OperationIdentifier{Index: 0}, Type: meshclient.OpSourceTransfer, Account: source, Amount: amount(-1000 - int64(fee))},
+			{OperationIdentifier: meshclient.OperationIdentifier{Index: 1}, Type: meshclient.OpDestinationTransfer, Account: payee, Amount: amount(1000)},
+			{OperationIdentifier: meshclient.OperationIdentifier{Index: 2}, Type: meshclient.OpFee, Amount: amount(int64(fee))},
+		},
+	}
+}
+
+// mine mines a block holding a transaction per fee
+func mine(mock *meshmock.Server, fees ...uint64) {
+	for _, fee := range fees {
+		mock.AddToMempool(feeTx(fmt.Sprintf("0x%02x%04x", mock.Height()+1, fee), fee))
+	}
+	mock.MineBlock()
+}
+
+func TestSummarizeFees(t *testing.T) {
+	if stats := meshclient.SummarizeFees(nil, 0); stats.Transactions != 0 || stats.Histogram == nil || len(stats.Histogram) != 0 || stats.Max != 0 {
+		t.Errorf("no fees: %+v", stats)
+	}
+
+	var fees []uint64
+	for fee := uint64(100); fee >= 1; fee-- {
+		fees = append(fees, fee)
+	}
+	stats := meshclient.SummarizeFees(fees, 0)
+	if stats.Transactions != 100 || stats.Min != 1 || stats.Median != 50 || stats.P90 != 90 || stats.Max != 100 {
+		t.Errorf("1 to 100: %+v", stats)
+	}
+	if len(stats.Histogram) != meshclient.DefaultHistogramBuckets || stats.Histogram[9] != (meshclient.FeeBucket{From: 91, To: 100, Count: 10}) {
+		t.Errorf("1 to 100: histogram %+v", stats.Histogram)
+	}
+	if fees[0] != 100 {
+		t.Error("the fees were sorted in place")
+	}
+
+	// Nearest rank on few fees
+	stats = meshclient.SummarizeFees([]uint64{500, 100, 300}, 4)
+	if stats.Median != 300 || stats.P90 != 500 || len(stats.Histogram) != 4 {
+		t.Errorf("3 fees: %+v", stats)
+	}
+	if stats := meshclient.SummarizeFees([]uint64{7, 7, 7}, 10); len(stats.Histogram) != 1 || stats.Histogram[0] != (meshclient.FeeBucket{From: 7, To: 7, Count: 3}) {
+		t.Errorf("identical fees: %+v", stats.Histogram)
+	}
+
+	// The widest span does not overflow the bucket bounds
+	stats = meshclient.SummarizeFees([]uint64{0, math.MaxUint64, math.MaxUint64 / 2}, 10)
+	last := stats.Histogram[len(stats.Histogram)-1]
+	total := 0
+	for _, bucket := range stats.Histogram {
+		total += bucket.Count
+	}
+	if len(stats.Histogram) != 10 || last.To != math.MaxUint64 || last.Count != 1 || total != 3 {
+		t.Errorf("full span: %+v", stats.Histogram)
+	}
+}
+
+/*
+ * TestRecentFees reads a chain of fee paying, empty and reward only
+ * blocks, then reads it again reusing the blocks read, after a new block
+ * and after a reorg
+ */
+func TestRecentFees(t *testing.T) {
+	mock := meshmock.New()
+	defer mock.Close()
+	// Blocks list one transaction inline, the others are fetched one by one
+	mock.SetBlockLimit(1)
+	mine(mock, 500, 700, 100)
+	mine(mock)
+	mine(mock, 0)
+	mine(mock, 900)
+	client := meshclient.NewMeshAPIClient(mock.URL(), nil)
+	ctx := context.Background()
+
+	if _, err := client.RecentFees(ctx, 0, nil, nil); err == nil {
+		t.Error("0 blocks accepted")
+	}
+
+	// More blocks than the chain holds: the range starts at genesis
+	var read []uint64
+	sample, err := client.RecentFees(ctx, 100, nil, func(block meshclient.BlockFees) { read = append(read, block.Height) })
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sample.From != 0 || sample.To != 4 || len(sample.Blocks) != 5 || len(read) != 5 || sample.Empty() != 3 || len(sample.Failed) != 0 {
+		t.Fatalf("sample %d to %d: %d blocks, %d read, %d empty, failed %v", sample.From, sample.To, len(sample.Blocks), len(read), sample.Empty(), sample.Failed)
+	}
+	if fees := sample.Fees(); !slices.Equal(fees, []uint64{500, 700, 100, 900}) || sample.Stats.Median != 500 || sample.Stats.Max != 900 {
+		t.Errorf("fees %v, stats %+v", fees, sample.Stats)
+	}
+	for i, block := range sample.Blocks {
+		if block.Height != uint64(i) || (i > 0 && block.ParentHash != sample.Blocks[i-1].Hash) {
+			t.Errorf("block %d: %+v", i, block)
+		}
+	}
+
+	// known maps the blocks of the last sample by height
+	known := func(sample *meshclient.FeeSample) map[uint64]meshclient.BlockFees {
+		blocks := make(map[uint64]meshclient.BlockFees)
+		for _, block := range sample.Blocks {
+			blocks[block.Height] = block
+		}
+		return blocks
+	}
+	rerun := func(count int, last *meshclient.FeeSample) (*meshclient.FeeSample, []uint64) {
+		t.Helper()
+		var read []uint64
+		sample, err := client.RecentFees(ctx, count, known(last), func(block meshclient.BlockFees) { read = append(read, block.Height) })
+		if err != nil {
+			t.Fatal(err)
+		}
+		slices.Sort(read)
+		return sample, read
+	}
+
+	// Nothing new: nothing read
+	if again, read := rerun(3, sample); len(read) != 0 || again.From != 2 || !slices.Equal(again.Fees(), []uint64{900}) {
+		t.Errorf("same chain: read %v, fees %v", read, again.Fees())
+	}
+	// A new block: only it is read
+	mine(mock, 300)
+	sample, read = rerun(5, sample)
+	if !slices.Equal(read, []uint64{5}) || !slices.Equal(sample.Fees(), []uint64{500, 700, 100, 900, 300}) {
+		t.Errorf("new block: read %v, fees %v", read, sample.Fees())
+	}
+	// A reorg of the 2 last blocks: those are read again, down to the fork
+	mock.ReorgTo(2, []meshclient.Transaction{feeTx("0xfe01", 50)}, nil)
+	sample, read = rerun(5, sample)
+	if !slices.Equal(read, []uint64{4, 5}) || !slices.Equal(sample.Fees(), []uint64{500, 700, 100, 50}) {
+		t.Errorf("reorg: read %v, fees %v", read, sample.Fees())
+	}
+}
+
+func TestRecentFeesFailures(t *testing.T) {
+	mock := meshmock.New()
+	defer mock.Close()
+	for i := 0; i < 4; i++ {
+		mine(mock, 100*uint64(i+1))
+	}
+	client := meshclient.NewMeshAPIClient(mock.URL(), nil)
+	ctx := context.Background()
+
+	// A block that cannot be read is left out of the statistics, not an error
+	mock.Fail("/block", meshmock.Fault{Status: 500})
+	var failed []meshclient.BlockFees
+	sample, err := client.RecentFees(ctx, 4, nil, func(block meshclient.BlockFees) {
+		if block.Err != nil {
+			failed = append(failed, block)
+		}
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(sample.Failed) != 1 || len(sample.Blocks) != 3 || sample.Stats.Transactions != 3 || len(failed) != 1 || failed[0].Height != sample.Failed[0] {
+		t.Errorf("failed %v, %d blocks, stats %+v", sample.Failed, len(sample.Blocks), sample.Stats)
+	}
+
+	// The tip is needed
+	mock.Fail("/network/status", meshmock.Fault{Status: 500})
+	if _, err := client.RecentFees(ctx, 4, nil, nil); err == nil {
+		t.Error("failed status ignored")
+	}
+
+	canceled, cancel := context.WithCancel(ctx)
+	cancel()
+	if _, err := client.RecentFees(canceled, 4, nil, nil); err == nil {
+		t.Error("canceled context ignored")
+	}
+}
+
+// TestStreamBlockFeesConcurrency counts the block reads in flight behind a slow API
+func TestStreamBlockFeesConcurrency(t *testing.T) {
+	mock := meshmock.New()
+	defer mock.Close()
+	for i := 0; i < 8; i++ {
+		mine(mock, 10)
+	}
+	mock.SetLatency(20 * time.Millisecond)
+	target, _ := url.Parse(mock.URL())
+	proxy := httputil.NewSingleHostReverseProxy(target)
+	var inFlight, peak atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/block" {
+			n := inFlight.Add(1)
+			defer inFlight.Add(-1)
+			for p := peak.Load(); n > p && !peak.CompareAndSwap(p, n); p = peak.Load() {
+			}
+		}
+		proxy.ServeHTTP(w, r)
+	}))
+	defer server.Close()
+
+	client := meshclient.NewMeshAPIClient(server.URL, nil)
+	client.SetBatchConcurrency(3)
+	var heights []uint64
+	for block := range client.StreamBlockFees(context.Background(), []uint64{1, 2, 3, 4, 5, 6, 7, 8}) {
+		if block.Err != nil || len(block.Fees) != 1 {
+			t.Errorf("block %d: %+v", block.Height, block)
+		}
+		heights = append(heights, block.Height)
+	}
+	slices.Sort(heights)
+	if !slices.Equal(heights, []uint64{1, 2, 3, 4, 5, 6, 7, 8}) {
+		t.Errorf("heights %v", heights)
+	}
+	if p := peak.Load(); p > 3 || p < 2 {
+		t.Errorf("%d block reads in flight, want at most 3", p)
+	}
+}
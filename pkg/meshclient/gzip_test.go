@@ -0,0 +1,124 @@
+package meshclient
+
+import (
+	"compress/gzip"
+	"context"
+	"encoding/hex"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+)
+
+const mcm = `"currency":{"symbol":"MCM","decimals":9}`
+
+// answers is a valid answer of each path the endpointCalls reach
+var answers = map[string]string{
+	"/account/balance":         `{"block_identifier":{"index":7,"hash":"0x07"},"balances":[{"value":"1500",` + mcm + `}]}`,
+	"/network/status":          statusAnswer,
+	"/network/list":            `{"network_identifiers":[{"blockchain":"mochimo","network":"mainnet"}]}`,
+	"/network/options":         mochimoOptions,
+	"/mempool":                 `{"transaction_identifiers":[{"hash":"` + testHash + `"}]}`,
+	"/mempool/transaction":     `{"transaction":{"transaction_identifier":{"hash":"` + testHash + `"}}}`,
+	"/block":                   `{"block":{"block_identifier":{"index":9,"hash":"0x09"},"transactions":[{"transaction_identifier":{"hash":"` + testHash + `"}}]}}`,
+	"/block/transaction":       `{"transaction":{"transaction_identifier":{"hash":"` + testHash + `"}}}`,
+	"/construction/submit":     `{"transaction_identifier":{"hash":"` + testHash + `"}}`,
+	"/search/transactions":     `{"transactions":[],"total_count":0}`,
+	"/call":                    `{"result":{"address":"0x` + hex.EncodeToString(make([]byte, AddressLength)) + `","amount":5}}`,
+	"/construction/derive":     `{"account_identifier":{"address":"0x` + hex.EncodeToString(make([]byte, 20)) + `"}}`,
+	"/construction/preprocess": `{"options":{}}`,
+	"/construction/metadata":   `{"metadata":{},"suggested_fee":[{"value":"500",` + mcm + `}]}`,
+}
+
+/*
+ * gzipServer answers every path of answers gzip-compressed, whether or not
+ * the client asked for it, and records the Accept-Encoding of the requests
+ */
+func gzipServer(t *testing.T) (*httptest.Server, func() []string) {
+	t.Helper()
+	var mu sync.Mutex
+	var accepted []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		accepted = append(accepted, r.Header.Get("Accept-Encoding"))
+		mu.Unlock()
+		answer, ok := answers[r.URL.Path]
+		if !ok {
+			t.Errorf("no answer for %s", r.URL.Path)
+		}
+		w.Header().Set("Content-Encoding", "gzip")
+		gz := gzip.NewWriter(w)
+		io.WriteString(gz, answer)
+		gz.Close()
+	}))
+	t.Cleanup(server.Close)
+	return server, func() []string {
+		mu.Lock()
+		defer mu.Unlock()
+		return accepted
+	}
+}
+
+// TestGzipEndpoints parses the compressed answers of every endpoint, with gzip asked for by the transport and with identity
+func TestGzipEndpoints(t *testing.T) {
+	for _, tc := range []struct {
+		name   string
+		opts   TransportOptions
+		accept string
+	}{
+		{"gzip", TransportOptions{}, "gzip"},
+		{"identity", TransportOptions{DisableCompression: true}, ""},
+	} {
+		server, accepted := gzipServer(t)
+		httpClient, err := NewHTTPClient(tc.opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		client := NewMeshAPIClient(server.URL, httpClient)
+		for name, call := range endpointCalls {
+			if err := call(context.Background(), client); err != nil {
+				t.Errorf("%s: %s: %v", tc.name, name, err)
+			}
+		}
+		for _, accept := range accepted() {
+			if accept != tc.accept {
+				t.Errorf("%s: Accept-Encoding %q", tc.name, accept)
+			}
+		}
+	}
+}
+
+func TestGzipErrors(t *testing.T) {
+	corrupt := false
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Encoding", "gzip")
+		if corrupt {
+			io.WriteString(w, "not gzip")
+			return
+		}
+		w.WriteHeader(http.StatusInternalServerError)
+		gz := gzip.NewWriter(w)
+		io.WriteString(gz, `{"code":13,"message":"fee too low","retriable":false}`)
+		gz.Close()
+	}))
+	defer server.Close()
+	for _, opts := range []TransportOptions{{}, {DisableCompression: true}} {
+		httpClient, _ := NewHTTPClient(opts)
+		client := NewMeshAPIClient(server.URL, httpClient)
+
+		// A compressed error body is decompressed before being parsed
+		corrupt = false
+		var meshErr *MeshError
+		if _, err := client.NetworkStatus(context.Background()); !errors.As(err, &meshErr) || !meshErr.Schema || meshErr.Code != CodeFeeTooLow {
+			t.Errorf("%+v: error %v", opts, err)
+		}
+
+		corrupt = true
+		var decodeErr *DecodeError
+		if _, err := client.NetworkStatus(context.Background()); !errors.As(err, &decodeErr) {
+			t.Errorf("%+v: corrupt body: %v", opts, err)
+		}
+	}
+}
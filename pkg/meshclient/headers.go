@@ -0,0 +1,51 @@
+package meshclient
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+)
+
+// Version is the version reported in the User-Agent, set at build time with
+// -ldflags "-X github.com/NickP005/Vindax-MCM-tools/pkg/meshclient.Version=..."
+var Version = "dev"
+
+// RequestIDHeader carries the random identifier of every request, also found in the errors
+const RequestIDHeader = "X-Request-ID"
+
+// userAgent returns the User-Agent of a tool, "vindax-mcm-tools/<Version> (<tool>)"
+func userAgent(tool string) string {
+	if tool == "" {
+		return "vindax-mcm-tools/" + Version
+	}
+	return fmt.Sprintf("vindax-mcm-tools/%s (%s)", Version, tool)
+}
+
+// SetUserAgent names the tool in the User-Agent of every request
+func (c *MeshAPIClient) SetUserAgent(tool string) {
+	c.userAgent = userAgent(tool)
+}
+
+// SetHeader adds a static header to every request, e.g. an API key expected by a proxy
+func (c *MeshAPIClient) SetHeader(key string, value string) {
+	if c.headers == nil {
+		c.headers = make(http.Header)
+	}
+	c.headers.Set(key, value)
+}
+
+// newRequestID returns 16 random hex characters
+func newRequestID() string {
+	var id [8]byte
+	rand.Read(id[:])
+	return hex.EncodeToString(id[:])
+}
+
+// requestSuffix is appended to error messages to correlate them with the server logs
+func requestSuffix(requestID string) string {
+	if requestID == "" {
+		return ""
+	}
+	return " [request " + requestID + "]"
+}
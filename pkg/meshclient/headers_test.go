@@ -0,0 +1,178 @@
+package meshclient
+
+import (
+	"context"
+	"encoding/hex"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/NickP005/Vindax-MCM-tools/pkg/wotsp"
+)
+
+// endpointCalls makes a request of every endpoint of the client
+var endpointCalls = map[string]func(ctx context.Context, c *MeshAPIClient) error{
+	"AccountBalance": func(ctx context.Context, c *MeshAPIClient) error {
+		_, err := c.AccountBalance(ctx, make([]byte, 20))
+		return err
+	},
+	"NetworkStatus": func(ctx context.Context, c *MeshAPIClient) error {
+		_, err := c.NetworkStatus(ctx)
+		return err
+	},
+	"NetworkList": func(ctx context.Context, c *MeshAPIClient) error {
+		_, err := c.NetworkList(ctx)
+		return err
+	},
+	"NetworkOptions": func(ctx context.Context, c *MeshAPIClient) error {
+		_, err := c.NetworkOptions(ctx)
+		return err
+	},
+	"Mempool": func(ctx context.Context, c *MeshAPIClient) error {
+		_, err := c.Mempool(ctx)
+		return err
+	},
+	"MempoolTransaction": func(ctx context.Context, c *MeshAPIClient) error {
+		_, err := c.MempoolTransaction(ctx, testHash)
+		return err
+	},
+	"Block": func(ctx context.Context, c *MeshAPIClient) error {
+		_, err := c.Block(ctx, 9)
+		return err
+	},
+	"BlockByHash": func(ctx context.Context, c *MeshAPIClient) error {
+		_, err := c.BlockByHash(ctx, "0x09")
+		return err
+	},
+	"BlockTransaction": func(ctx context.Context, c *MeshAPIClient) error {
+		_, err := c.BlockTransaction(ctx, testHash)
+		return err
+	},
+	"BlockHasTransaction": func(ctx context.Context, c *MeshAPIClient) error {
+		_, err := c.BlockHasTransaction(ctx, 9, testHash)
+		return err
+	},
+	"SubmitTransaction": func(ctx context.Context, c *MeshAPIClient) error {
+		_, err := c.SubmitTransaction(ctx, "0xdeadbeef")
+		return err
+	},
+	"SearchTransactions": func(ctx context.Context, c *MeshAPIClient) error {
+		_, err := c.SearchTransactions(ctx, SearchQuery{Type: "TRANSFER"})
+		return err
+	},
+	"ResolveTag": func(ctx context.Context, c *MeshAPIClient) error {
+		_, err := c.ResolveTag(ctx, make([]byte, 20))
+		return err
+	},
+	"ConstructionDerive": func(ctx context.Context, c *MeshAPIClient) error {
+		_, err := c.ConstructionDerive(ctx, make([]byte, wotsp.SigSize))
+		return err
+	},
+	"ConstructionPreprocess": func(ctx context.Context, c *MeshAPIClient) error {
+		_, err := c.ConstructionPreprocess(ctx, nil)
+		return err
+	},
+	"ConstructionMetadata": func(ctx context.Context, c *MeshAPIClient) error {
+		_, err := c.ConstructionMetadata(ctx, map[string]interface{}{})
+		return err
+	},
+}
+
+// sentRequest is the path and headers of a request the server received
+type sentRequest struct {
+	path   string
+	header http.Header
+}
+
+// headerServer fails every request with a Mesh error, recording its path and headers
+func headerServer(t *testing.T) (*MeshAPIClient, func() []sentRequest) {
+	t.Helper()
+	var mu sync.Mutex
+	var sent []sentRequest
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		sent = append(sent, sentRequest{r.URL.Path, r.Header.Clone()})
+		mu.Unlock()
+		w.WriteHeader(http.StatusInternalServerError)
+		io.WriteString(w, `{"code":2,"message":"internal error","retriable":false}`)
+	}))
+	t.Cleanup(server.Close)
+	return NewMeshAPIClient(server.URL, nil), func() []sentRequest {
+		mu.Lock()
+		defer mu.Unlock()
+		requests := sent
+		sent = nil
+		return requests
+	}
+}
+
+// TestHeaders checks every endpoint sends the User-Agent, the static headers and a new request ID found in its error
+func TestHeaders(t *testing.T) {
+	client, sent := headerServer(t)
+	client.SetUserAgent("header-test")
+	client.SetHeader("X-Api-Key", "k3y")
+	ids := make(map[string]bool)
+	for name, call := range endpointCalls {
+		err := call(context.Background(), client)
+		requests := sent()
+		if err == nil || len(requests) == 0 {
+			t.Errorf("%s: %d requests, %v", name, len(requests), err)
+			continue
+		}
+		for _, request := range requests {
+			header := request.header
+			if header.Get("User-Agent") != "vindax-mcm-tools/dev (header-test)" || header.Get("X-Api-Key") != "k3y" {
+				t.Errorf("%s: %s request headers %v", name, request.path, header)
+			}
+			id := header.Get(RequestIDHeader)
+			if _, err := hex.DecodeString(id); err != nil || len(id) != 16 || ids[id] {
+				t.Errorf("%s: %s request ID %q", name, request.path, id)
+			}
+			ids[id] = true
+		}
+		// The error names the request that failed, the last one
+		last := requests[len(requests)-1].header.Get(RequestIDHeader)
+		if !strings.Contains(err.Error(), "[request "+last+"]") {
+			t.Errorf("%s: error %q does not name request %s", name, err, last)
+		}
+	}
+}
+
+func TestDefaultUserAgent(t *testing.T) {
+	client, sent := headerServer(t)
+	client.NetworkStatus(context.Background())
+	if requests := sent(); len(requests) != 1 || requests[0].header.Get("User-Agent") != "vindax-mcm-tools/dev" {
+		t.Errorf("requests %+v", requests)
+	}
+
+	// The static headers do not replace those of the client
+	client.SetHeader("User-Agent", "curl/8.0")
+	client.SetHeader("Content-Type", "text/plain")
+	client.NetworkStatus(context.Background())
+	if requests := sent(); requests[0].header.Get("User-Agent") != "vindax-mcm-tools/dev" || requests[0].header.Get("Content-Type") != "application/json" {
+		t.Errorf("headers %v", requests[0].header)
+	}
+}
+
+// TestRequestIDInErrors checks the transport and decode errors name their request too
+func TestRequestIDInErrors(t *testing.T) {
+	var id string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id = r.Header.Get(RequestIDHeader)
+		io.WriteString(w, "not json")
+	}))
+	client := NewMeshAPIClient(server.URL, nil)
+	_, err := client.NetworkStatus(context.Background())
+	if decodeErr, ok := err.(*DecodeError); !ok || decodeErr.RequestID != id || !strings.HasSuffix(err.Error(), "[request "+id+"]") {
+		t.Errorf("decode error %v for request %s", err, id)
+	}
+
+	server.Close()
+	_, err = client.NetworkStatus(context.Background())
+	if transportErr, ok := err.(*TransportError); !ok || len(transportErr.RequestID) != 16 || !strings.HasSuffix(err.Error(), "[request "+transportErr.RequestID+"]") {
+		t.Errorf("transport error %v", err)
+	}
+}
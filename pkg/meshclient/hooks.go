@@ -0,0 +1,196 @@
+package meshclient
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+)
+
+// RequestInfo describes one attempt of a request; Duration, Status and Err are set once it ends
+type RequestInfo struct {
+	// Op is the endpoint path, e.g. "/block"
+	Op string
+	// Attempt counts the attempts of the request, starting at 1
+	Attempt   int
+	RequestID string
+	Duration  time.Duration
+	// Status is the HTTP status of the answer, 0 if none was received
+	Status int
+	Err    error
+}
+
+/*
+ * Hooks instruments the client: both methods are called once per attempt,
+ * retries included, from the goroutine making the request, so they must be
+ * safe for concurrent use
+ *
+ * LogHooks and Metrics are the built-in implementations.
+ */
+type Hooks interface {
+	OnRequestStart(info RequestInfo)
+	OnRequestEnd(info RequestInfo)
+}
+
+// SetHooks reports every attempt to hooks; nil disables instrumentation (the default)
+func (c *MeshAPIClient) SetHooks(hooks Hooks) {
+	c.hooks = hooks
+}
+
+// MultiHooks reports every attempt to each of its hooks, in order
+type MultiHooks []Hooks
+
+func (m MultiHooks) OnRequestStart(info RequestInfo) {
+	for _, h := range m {
+		h.OnRequestStart(info)
+	}
+}
+
+func (m MultiHooks) OnRequestEnd(info RequestInfo) {
+	for _, h := range m {
+		h.OnRequestEnd(info)
+	}
+}
+
+// LogHooks logs the end of every attempt with its duration and outcome
+type LogHooks struct {
+	// Logger receives the lines; nil uses the standard logger
+	Logger *log.Logger
+}
+
+func (h LogHooks) OnRequestStart(info RequestInfo) {}
+
+func (h LogHooks) OnRequestEnd(info RequestInfo) {
+	logf := log.Printf
+	if h.Logger != nil {
+		logf = h.Logger.Printf
+	}
+	if wait, throttled := Throttled(info.Err); throttled {
+		logf("mesh %s attempt %d throttled (%d, retry after %v) after %v [request %s]", info.Op, info.Attempt, info.Status, wait, info.Duration.Round(time.Millisecond), info.RequestID)
+		return
+	}
+	if info.Err != nil {
+		logf("mesh %s attempt %d failed after %v: %v", info.Op, info.Attempt, info.Duration.Round(time.Millisecond), info.Err)
+		return
+	}
+	logf("mesh %s attempt %d: %d in %v [request %s]", info.Op, info.Attempt, info.Status, info.Duration.Round(time.Millisecond), info.RequestID)
+}
+
+// MetricsBuckets are the upper bounds, in seconds, of the latency histograms of Metrics
+var MetricsBuckets = []float64{0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30}
+
+// opMetrics are the metrics of one endpoint
+type opMetrics struct {
+	buckets   []uint64 // cumulative counts per MetricsBuckets bound
+	count     uint64
+	sum       float64
+	errors    uint64
+	throttled uint64
+}
+
+/*
+ * Metrics collects per-endpoint latency histograms and error counters
+ *
+ * It serves them in the Prometheus text format as an http.Handler, so it
+ * can be mounted on a metrics endpoint without depending on the Prometheus
+ * client library:
+ * - mesh_request_duration_seconds{op}: histogram of the attempts
+ * - mesh_request_errors_total{op}: attempts that ended with an error
+ * - mesh_request_throttled_total{op}: attempts rate limited by the server
+ *   (429 or 503), which are not counted as errors
+ */
+type Metrics struct {
+	mu  sync.Mutex
+	ops map[string]*opMetrics
+}
+
+// NewMetrics returns an empty collector
+func NewMetrics() *Metrics {
+	return &Metrics{ops: make(map[string]*opMetrics)}
+}
+
+func (m *Metrics) OnRequestStart(info RequestInfo) {}
+
+func (m *Metrics) OnRequestEnd(info RequestInfo) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	op := m.ops[info.Op]
+	if op == nil {
+		op = &opMetrics{buckets: make([]uint64, len(MetricsBuckets))}
+		m.ops[info.Op] = op
+	}
+	seconds := info.Duration.Seconds()
+	for i, bound := range MetricsBuckets {
+		if seconds <= bound {
+			op.buckets[i]++
+		}
+	}
+	op.count++
+	op.sum += seconds
+	if _, throttled := Throttled(info.Err); throttled {
+		op.throttled++
+	} else if info.Err != nil {
+		op.errors++
+	}
+}
+
+// WriteTo writes the metrics in the Prometheus text format
+func (m *Metrics) WriteTo(w io.Writer) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	names := make([]string, 0, len(m.ops))
+	for name := range m.ops {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	cw := &countingWriter{w: w}
+	fmt.Fprintln(cw, "# HELP mesh_request_duration_seconds Duration of Mesh API request attempts.")
+	fmt.Fprintln(cw, "# TYPE mesh_request_duration_seconds histogram")
+	for _, name := range names {
+		op := m.ops[name]
+		for i, bound := range MetricsBuckets {
+			fmt.Fprintf(cw, "mesh_request_duration_seconds_bucket{op=%q,le=\"%g\"} %d\n", name, bound, op.buckets[i])
+		}
+		fmt.Fprintf(cw, "mesh_request_duration_seconds_bucket{op=%q,le=\"+Inf\"} %d\n", name, op.count)
+		fmt.Fprintf(cw, "mesh_request_duration_seconds_sum{op=%q} %g\n", name, op.sum)
+		fmt.Fprintf(cw, "mesh_request_duration_seconds_count{op=%q} %d\n", name, op.count)
+	}
+	fmt.Fprintln(cw, "# HELP mesh_request_errors_total Mesh API request attempts that failed.")
+	fmt.Fprintln(cw, "# TYPE mesh_request_errors_total counter")
+	for _, name := range names {
+		fmt.Fprintf(cw, "mesh_request_errors_total{op=%q} %d\n", name, m.ops[name].errors)
+	}
+	fmt.Fprintln(cw, "# HELP mesh_request_throttled_total Mesh API request attempts rate limited by the server.")
+	fmt.Fprintln(cw, "# TYPE mesh_request_throttled_total counter")
+	for _, name := range names {
+		fmt.Fprintf(cw, "mesh_request_throttled_total{op=%q} %d\n", name, m.ops[name].throttled)
+	}
+	return cw.n, cw.err
+}
+
+// ServeHTTP serves the metrics to a Prometheus scraper
+func (m *Metrics) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+	m.WriteTo(w)
+}
+
+// countingWriter counts the bytes written and keeps the first error
+type countingWriter struct {
+	w   io.Writer
+	n   int64
+	err error
+}
+
+func (cw *countingWriter) Write(p []byte) (int, error) {
+	if cw.err != nil {
+		return 0, cw.err
+	}
+	n, err := cw.w.Write(p)
+	cw.n += int64(n)
+	cw.err = err
+	return n, err
+}
@@ -0,0 +1,151 @@
+package meshclient
+
+import (
+	"bytes"
+	"context"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// recorder keeps every RequestInfo reported to it
+type recorder struct {
+	mu     sync.Mutex
+	starts []RequestInfo
+	ends   []RequestInfo
+}
+
+func (r *recorder) OnRequestStart(info RequestInfo) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.starts = append(r.starts, info)
+}
+
+func (r *recorder) OnRequestEnd(info RequestInfo) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.ends = append(r.ends, info)
+}
+
+// TestHooksOncePerAttempt checks each attempt of a retried request is reported once at its start and once at its end
+func TestHooksOncePerAttempt(t *testing.T) {
+	client, requests := flakyServer(t, 3, http.StatusServiceUnavailable, "busy", statusAnswer)
+	client.SetRetryPolicy(testPolicy(5, &fakeClock{}))
+	hooks := &recorder{}
+	client.SetHooks(hooks)
+	if _, err := client.NetworkStatus(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if len(hooks.starts) != 4 || len(hooks.ends) != 4 || requests.Load() != 4 {
+		t.Fatalf("%d requests, %d starts, %d ends", requests.Load(), len(hooks.starts), len(hooks.ends))
+	}
+	for i := range hooks.ends {
+		start, end := hooks.starts[i], hooks.ends[i]
+		if start.Attempt != i+1 || start.Op != "/network/status" || start.Status != 0 || start.Err != nil || start.Duration != 0 {
+			t.Errorf("start %d: %+v", i+1, start)
+		}
+		if end.Attempt != i+1 || end.Op != start.Op || end.RequestID != start.RequestID || end.Duration <= 0 {
+			t.Errorf("end %d: %+v", i+1, end)
+		}
+		wantStatus := http.StatusServiceUnavailable
+		if i == 3 {
+			wantStatus = http.StatusOK
+		}
+		if end.Status != wantStatus || (end.Err == nil) != (i == 3) {
+			t.Errorf("end %d: status %d, %v", i+1, end.Status, end.Err)
+		}
+	}
+
+	// An attempt that got no answer has status 0
+	server := httptest.NewServer(http.NotFoundHandler())
+	server.Close()
+	client = NewMeshAPIClient(server.URL, nil)
+	hooks = &recorder{}
+	client.SetHooks(hooks)
+	client.Mempool(context.Background())
+	if len(hooks.ends) != 1 || hooks.ends[0].Status != 0 || hooks.ends[0].Err == nil || hooks.ends[0].Op != "/mempool" {
+		t.Errorf("ends %+v", hooks.ends)
+	}
+}
+
+func TestMultiHooks(t *testing.T) {
+	client, _ := testServer(t, http.StatusOK, statusAnswer)
+	first, second := &recorder{}, &recorder{}
+	client.SetHooks(MultiHooks{first, second})
+	client.NetworkStatus(context.Background())
+	if len(first.ends) != 1 || len(second.ends) != 1 || len(first.starts) != 1 || len(second.starts) != 1 {
+		t.Errorf("first %d/%d, second %d/%d", len(first.starts), len(first.ends), len(second.starts), len(second.ends))
+	}
+}
+
+func TestLogHooks(t *testing.T) {
+	var buf bytes.Buffer
+	hooks := LogHooks{Logger: log.New(&buf, "", 0)}
+	for _, info := range []RequestInfo{
+		{Op: "/block", Attempt: 1, RequestID: "r1", Duration: 12 * time.Millisecond, Status: 200},
+		{Op: "/block", Attempt: 2, RequestID: "r2", Duration: time.Millisecond, Status: 429,
+			Err: &MeshError{StatusCode: 429, RetryAfter: 2 * time.Second}},
+		{Op: "/mempool", Attempt: 1, RequestID: "r3", Duration: time.Second,
+			Err: &TransportError{Path: "/mempool", RequestID: "r3", Err: context.DeadlineExceeded}},
+	} {
+		hooks.OnRequestStart(info)
+		hooks.OnRequestEnd(info)
+	}
+	want := []string{
+		"mesh /block attempt 1: 200 in 12ms [request r1]",
+		"mesh /block attempt 2 throttled (429, retry after 2s) after 1ms [request r2]",
+		"mesh /mempool attempt 1 failed after 1s: request to /mempool failed: context deadline exceeded [request r3]",
+	}
+	if got := strings.Split(strings.TrimSpace(buf.String()), "\n"); strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("logged:\n%s\nwant:\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+}
+
+func TestMetrics(t *testing.T) {
+	metrics := NewMetrics()
+	for _, info := range []RequestInfo{
+		{Op: "/block", Duration: 5 * time.Millisecond, Status: 200},
+		{Op: "/block", Duration: 300 * time.Millisecond, Status: 200},
+		{Op: "/block", Duration: time.Minute, Status: 502, Err: &MeshError{StatusCode: 502}},
+		{Op: "/mempool", Duration: 20 * time.Millisecond, Status: 429, Err: &MeshError{StatusCode: 429}},
+	} {
+		metrics.OnRequestStart(info)
+		metrics.OnRequestEnd(info)
+	}
+
+	recorder := httptest.NewRecorder()
+	metrics.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	if !strings.HasPrefix(recorder.Header().Get("Content-Type"), "text/plain") {
+		t.Errorf("Content-Type %q", recorder.Header().Get("Content-Type"))
+	}
+	text := recorder.Body.String()
+	for _, line := range []string{
+		`mesh_request_duration_seconds_bucket{op="/block",le="0.01"} 1`,
+		`mesh_request_duration_seconds_bucket{op="/block",le="0.25"} 1`,
+		`mesh_request_duration_seconds_bucket{op="/block",le="0.5"} 2`,
+		`mesh_request_duration_seconds_bucket{op="/block",le="30"} 2`,
+		`mesh_request_duration_seconds_bucket{op="/block",le="+Inf"} 3`,
+		`mesh_request_duration_seconds_sum{op="/block"} 60.305`,
+		`mesh_request_duration_seconds_count{op="/block"} 3`,
+		`mesh_request_duration_seconds_count{op="/mempool"} 1`,
+		`mesh_request_errors_total{op="/block"} 1`,
+		`mesh_request_errors_total{op="/mempool"} 0`,
+		`mesh_request_throttled_total{op="/block"} 0`,
+		`mesh_request_throttled_total{op="/mempool"} 1`,
+		"# TYPE mesh_request_duration_seconds histogram",
+		"# TYPE mesh_request_errors_total counter",
+	} {
+		if !strings.Contains(text, line+"\n") {
+			t.Errorf("no line %q in\n%s", line, text)
+		}
+	}
+
+	var buf bytes.Buffer
+	if n, err := metrics.WriteTo(&buf); err != nil || n != int64(buf.Len()) || buf.String() != text {
+		t.Errorf("WriteTo wrote %d bytes of %d, %v", n, buf.Len(), err)
+	}
+}
@@ -0,0 +1,123 @@
+package meshclient
+
+import (
+	"context"
+	"fmt"
+	"sync"
+)
+
+// RosettaVersion is the Rosetta API version the client's types follow
+const RosettaVersion = "1.4.13"
+
+// NetworkList is the response of /network/list
+type NetworkList struct {
+	NetworkIdentifiers []NetworkIdentifier `json:"network_identifiers"`
+}
+
+// NetworkOptions is the response of /network/options
+type NetworkOptions struct {
+	Version struct {
+		RosettaVersion    string `json:"rosetta_version"`
+		NodeVersion       string `json:"node_version"`
+		MiddlewareVersion string `json:"middleware_version,omitempty"`
+	} `json:"version"`
+	Allow struct {
+		OperationTypes          []string `json:"operation_types"`
+		HistoricalBalanceLookup bool     `json:"historical_balance_lookup"`
+		CallMethods             []string `json:"call_methods,omitempty"`
+	} `json:"allow"`
+}
+
+// NetworkList returns the networks served by the API
+func (c *MeshAPIClient) NetworkList(ctx context.Context) (*NetworkList, error) {
+	var list NetworkList
+	if err := c.post(ctx, "/network/list", struct{}{}, &list); err != nil {
+		return nil, err
+	}
+	return &list, nil
+}
+
+// NetworkOptions returns the versions and the operation types of the API
+func (c *MeshAPIClient) NetworkOptions(ctx context.Context) (*NetworkOptions, error) {
+	var options NetworkOptions
+	if err := c.post(ctx, "/network/options", networkRequest{c.network}, &options); err != nil {
+		return nil, err
+	}
+	return &options, nil
+}
+
+// Preflight is what the startup check learnt about the API
+type Preflight struct {
+	RosettaVersion string
+	NodeVersion    string
+	OperationTypes []string
+	// CallMethods are the /call methods listed by the server, if it lists them
+	CallMethods []string
+	// Warnings are mismatches that do not prevent using the API
+	Warnings []string
+}
+
+// preflightCache holds the result of the first successful Preflight call
+type preflightCache struct {
+	mu     sync.Mutex
+	result *Preflight
+}
+
+/*
+ * Preflight checks that the endpoint is a Mochimo Mesh API serving the
+ * network of the client, mainnet unless SetNetwork names another
+ *
+ * /network/list must list the mochimo blockchain with that network,
+ * otherwise an error names what the server offers instead, which is what a
+ * -api URL pointing at another Rosetta implementation yields. /network/options
+ * then records the Rosetta version, the operation types and the /call
+ * methods, if listed, which also answer Capabilities; a version other than
+ * RosettaVersion is only a warning.
+ *
+ * A successful result is cached for the life of the client; failures are
+ * not, so a later call tries again.
+ */
+func (c *MeshAPIClient) Preflight(ctx context.Context) (*Preflight, error) {
+	c.preflight.mu.Lock()
+	defer c.preflight.mu.Unlock()
+	if c.preflight.result != nil {
+		return c.preflight.result, nil
+	}
+
+	list, err := c.NetworkList(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list networks: %v", err)
+	}
+	found := false
+	for _, network := range list.NetworkIdentifiers {
+		if network == c.network {
+			found = true
+			break
+		}
+	}
+	if !found {
+		return nil, fmt.Errorf("%s is not a Mochimo Mesh API: it serves %v, not %s/%s",
+			c.Endpoint(), list.NetworkIdentifiers, c.network.Blockchain, c.network.Network)
+	}
+
+	options, err := c.NetworkOptions(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get network options: %v", err)
+	}
+	result := &Preflight{
+		RosettaVersion: options.Version.RosettaVersion,
+		NodeVersion:    options.Version.NodeVersion,
+		OperationTypes: options.Allow.OperationTypes,
+		CallMethods:    options.Allow.CallMethods,
+	}
+	c.capabilities.mu.Lock()
+	c.capabilities.learn(options)
+	c.capabilities.mu.Unlock()
+	if result.RosettaVersion != RosettaVersion {
+		result.Warnings = append(result.Warnings, fmt.Sprintf("server speaks Rosetta %q, this client was built against %s",
+			result.RosettaVersion, RosettaVersion))
+	}
+
+	c.preflight.result = result
+	return result, nil
+}
@@ -0,0 +1,117 @@
+package meshclient
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// routeServer answers each path with its answer in routes, 404 for the others, and counts the requests per path
+func routeServer(t *testing.T, routes map[string]string) (*MeshAPIClient, func(path string) int) {
+	t.Helper()
+	var mu sync.Mutex
+	counts := make(map[string]int)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		counts[r.URL.Path]++
+		mu.Unlock()
+		answer, ok := routes[r.URL.Path]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		io.WriteString(w, answer)
+	}))
+	t.Cleanup(server.Close)
+	return NewMeshAPIClient(server.URL, nil), func(path string) int {
+		mu.Lock()
+		defer mu.Unlock()
+		return counts[path]
+	}
+}
+
+const mochimoOptions = `{"version":{"rosetta_version":"1.4.13","node_version":"2.4.3"},
+	"allow":{"operation_types":["TRANSFER","REWARD"],"historical_balance_lookup":false,"call_methods":["tag_resolve"]}}`
+
+func TestPreflight(t *testing.T) {
+	client, count := routeServer(t, map[string]string{
+		"/network/list":    `{"network_identifiers":[{"blockchain":"mochimo","network":"mainnet"},{"blockchain":"mochimo","network":"testnet"}]}`,
+		"/network/options": mochimoOptions,
+	})
+	result, err := client.Preflight(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.RosettaVersion != "1.4.13" || result.NodeVersion != "2.4.3" || len(result.Warnings) != 0 ||
+		strings.Join(result.OperationTypes, ",") != "TRANSFER,REWARD" || strings.Join(result.CallMethods, ",") != "tag_resolve" {
+		t.Errorf("result %+v", result)
+	}
+
+	// The result is cached for the life of the client
+	again, err := client.Preflight(context.Background())
+	if err != nil || again != result || count("/network/list") != 1 || count("/network/options") != 1 {
+		t.Errorf("second call made %d list and %d options requests, %v", count("/network/list"), count("/network/options"), err)
+	}
+}
+
+// TestPreflightOtherRosetta points the client at a Rosetta server of another blockchain
+func TestPreflightOtherRosetta(t *testing.T) {
+	client, count := routeServer(t, map[string]string{
+		"/network/list":    `{"network_identifiers":[{"blockchain":"Bitcoin","network":"Mainnet"}]}`,
+		"/network/options": `{"version":{"rosetta_version":"1.4.10","node_version":"0.21"}}`,
+	})
+	_, err := client.Preflight(context.Background())
+	want := "is not a Mochimo Mesh API: it serves [{Bitcoin Mainnet}], not mochimo/mainnet"
+	if err == nil || !strings.Contains(err.Error(), want) || !strings.HasPrefix(err.Error(), client.Endpoint()) {
+		t.Fatalf("got %v, want %q", err, want)
+	}
+	if count("/network/options") != 0 {
+		t.Error("options fetched from a refused server")
+	}
+	// Failures are not cached
+	if _, err := client.Preflight(context.Background()); err == nil || count("/network/list") != 2 {
+		t.Errorf("second call: %d list requests, %v", count("/network/list"), err)
+	}
+}
+
+func TestPreflightOtherNetwork(t *testing.T) {
+	client, _ := routeServer(t, map[string]string{
+		"/network/list":    `{"network_identifiers":[{"blockchain":"mochimo","network":"mainnet"}]}`,
+		"/network/options": mochimoOptions,
+	})
+	client.SetNetwork("testnet")
+	if _, err := client.Preflight(context.Background()); err == nil || !strings.Contains(err.Error(), "not mochimo/testnet") {
+		t.Errorf("got %v", err)
+	}
+}
+
+func TestPreflightVersionWarning(t *testing.T) {
+	client, _ := routeServer(t, map[string]string{
+		"/network/list":    `{"network_identifiers":[{"blockchain":"mochimo","network":"mainnet"}]}`,
+		"/network/options": `{"version":{"rosetta_version":"1.5.0","node_version":"3.0"},"allow":{"operation_types":["TRANSFER"]}}`,
+	})
+	result, err := client.Preflight(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result.Warnings) != 1 || result.Warnings[0] != `server speaks Rosetta "1.5.0", this client was built against 1.4.13` {
+		t.Errorf("warnings %q", result.Warnings)
+	}
+}
+
+func TestPreflightErrors(t *testing.T) {
+	client, _ := routeServer(t, map[string]string{})
+	if _, err := client.Preflight(context.Background()); err == nil || !strings.HasPrefix(err.Error(), "failed to list networks") {
+		t.Errorf("no /network/list: %v", err)
+	}
+	client, _ = routeServer(t, map[string]string{
+		"/network/list": `{"network_identifiers":[{"blockchain":"mochimo","network":"mainnet"}]}`,
+	})
+	if _, err := client.Preflight(context.Background()); err == nil || !strings.HasPrefix(err.Error(), "failed to get network options") {
+		t.Errorf("no /network/options: %v", err)
+	}
+}
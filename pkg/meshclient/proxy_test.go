@@ -0,0 +1,202 @@
+package meshclient
+
+import (
+	"encoding/base64"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+// apiServer is the Mesh API behind the proxies, answering /network/status
+func apiServer(t *testing.T) *httptest.Server {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, statusAnswer)
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+// httpProxy is a forward proxy requiring the credentials user:password, counting the requests it relays
+func httpProxy(t *testing.T, user string, password string) (*httptest.Server, *atomic.Int32) {
+	t.Helper()
+	var relayed atomic.Int32
+	want := "Basic " + base64.StdEncoding.EncodeToString([]byte(user+":"+password))
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Proxy-Authorization") != want {
+			w.WriteHeader(http.StatusProxyAuthRequired)
+			return
+		}
+		if !r.URL.IsAbs() {
+			t.Errorf("proxy got a request for %q", r.RequestURI)
+		}
+		out, _ := http.NewRequest(r.Method, r.URL.String(), r.Body)
+		out.Header = r.Header.Clone()
+		out.Header.Del("Proxy-Authorization")
+		resp, err := (&http.Transport{}).RoundTrip(out)
+		if err != nil {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		defer resp.Body.Close()
+		relayed.Add(1)
+		w.WriteHeader(resp.StatusCode)
+		io.Copy(w, resp.Body)
+	}))
+	t.Cleanup(server.Close)
+	return server, &relayed
+}
+
+/*
+ * socks5Proxy is a SOCKS5 server of RFC 1928 accepting only the
+ * user/password method of RFC 1929 with the given credentials, counting
+ * the connections it relays; it returns its host:port
+ */
+func socks5Proxy(t *testing.T, user string, password string) (string, *atomic.Int32) {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { listener.Close() })
+	var relayed atomic.Int32
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				target, err := socks5Handshake(conn, user, password)
+				if err != nil {
+					return
+				}
+				upstream, err := net.Dial("tcp", target)
+				if err != nil {
+					conn.Write([]byte{5, 5, 0, 1, 0, 0, 0, 0, 0, 0})
+					return
+				}
+				defer upstream.Close()
+				conn.Write([]byte{5, 0, 0, 1, 0, 0, 0, 0, 0, 0})
+				relayed.Add(1)
+				go io.Copy(upstream, conn)
+				io.Copy(conn, upstream)
+			}()
+		}
+	}()
+	return listener.Addr().String(), &relayed
+}
+
+// socks5Handshake authenticates a SOCKS5 client and returns the host:port of its CONNECT request
+func socks5Handshake(conn net.Conn, user string, password string) (string, error) {
+	read := func(n int) ([]byte, error) {
+		buf := make([]byte, n)
+		_, err := io.ReadFull(conn, buf)
+		return buf, err
+	}
+	greeting, err := read(2)
+	if err != nil || greeting[0] != 5 {
+		return "", fmt.Errorf("bad greeting")
+	}
+	methods, err := read(int(greeting[1]))
+	if err != nil || !strings.Contains(string(methods), "\x02") {
+		conn.Write([]byte{5, 0xff})
+		return "", fmt.Errorf("no user/password method")
+	}
+	conn.Write([]byte{5, 2})
+
+	header, err := read(2)
+	if err != nil {
+		return "", err
+	}
+	gotUser, _ := read(int(header[1]))
+	length, _ := read(1)
+	gotPassword, err := read(int(length[0]))
+	if err != nil || string(gotUser) != user || string(gotPassword) != password {
+		conn.Write([]byte{1, 1})
+		return "", fmt.Errorf("bad credentials")
+	}
+	conn.Write([]byte{1, 0})
+
+	request, err := read(4)
+	if err != nil || request[1] != 1 {
+		return "", fmt.Errorf("not a CONNECT")
+	}
+	var host string
+	switch request[3] {
+	case 1:
+		ip, _ := read(4)
+		host = net.IP(ip).String()
+	case 3:
+		length, _ := read(1)
+		name, _ := read(int(length[0]))
+		host = string(name)
+	default:
+		return "", fmt.Errorf("address type %d", request[3])
+	}
+	port, err := read(2)
+	if err != nil {
+		return "", err
+	}
+	return net.JoinHostPort(host, strconv.Itoa(int(binary.BigEndian.Uint16(port)))), nil
+}
+
+func TestHTTPProxy(t *testing.T) {
+	api := apiServer(t)
+	proxy, relayed := httpProxy(t, "payer", "s3cret")
+	proxyURL := strings.Replace(proxy.URL, "http://", "http://payer:s3cret@", 1)
+	if err := statusOver(api.URL, TransportOptions{Proxy: proxyURL}); err != nil || relayed.Load() != 1 {
+		t.Errorf("%d requests relayed, %v", relayed.Load(), err)
+	}
+	wrong := strings.Replace(proxy.URL, "http://", "http://payer:wrong@", 1)
+	if err := statusOver(api.URL, TransportOptions{Proxy: wrong}); err == nil || !strings.Contains(err.Error(), "407") || relayed.Load() != 1 {
+		t.Errorf("wrong password: %v", err)
+	}
+}
+
+func TestSOCKS5Proxy(t *testing.T) {
+	api := apiServer(t)
+	address, relayed := socks5Proxy(t, "payer", "s3cret")
+	for _, scheme := range []string{"socks5", "socks5h"} {
+		relayed.Store(0)
+		if err := statusOver(api.URL, TransportOptions{Proxy: scheme + "://payer:s3cret@" + address}); err != nil || relayed.Load() != 1 {
+			t.Errorf("%s: %d connections relayed, %v", scheme, relayed.Load(), err)
+		}
+	}
+	if err := statusOver(api.URL, TransportOptions{Proxy: "socks5://payer:wrong@" + address}); err == nil {
+		t.Error("wrong password accepted")
+	}
+}
+
+func TestProxyOptions(t *testing.T) {
+	for _, tc := range []struct {
+		proxy string
+		want  string
+	}{
+		{"ftp://proxy:21", `unsupported proxy scheme "ftp"`},
+		{"socks5://", "has no host"},
+		{"http://[::1", "invalid proxy URL"},
+	} {
+		if _, err := NewTransport(TransportOptions{Proxy: tc.proxy}); err == nil || !strings.Contains(err.Error(), tc.want) {
+			t.Errorf("%q: %v, want %q", tc.proxy, err, tc.want)
+		}
+	}
+
+	// Without -proxy the standard environment variables apply
+	transport, err := NewTransport(TransportOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if reflect.ValueOf(transport.Proxy).Pointer() != reflect.ValueOf(http.ProxyFromEnvironment).Pointer() {
+		t.Error("the transport ignores HTTP_PROXY, HTTPS_PROXY and NO_PROXY")
+	}
+}
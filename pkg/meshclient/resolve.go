@@ -0,0 +1,138 @@
+package meshclient
+
+import (
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/NickP005/Vindax-MCM-tools/pkg/mcmaddr"
+	"github.com/NickP005/Vindax-MCM-tools/pkg/wotsp"
+)
+
+// AddressLength is the length in bytes of a full address: the tag followed by the WOTS+ address hash
+const AddressLength = mcmaddr.TagLength + wotsp.AddrHashLength
+
+// TagResolution is the outcome of resolving one tag
+type TagResolution struct {
+	// Address is the full address of a found tag, always AddressLength bytes
+	Address []byte
+	// AddressHex is Address as answered by the server, 0x prefixed
+	AddressHex string
+	// Amount is the balance of a found tag in nanoMCM
+	Amount uint64
+	// Found is false for a tag the chain does not know, or one that failed
+	Found bool
+	// Err is set by BatchResolveTags when the lookup failed; a tag not found is not an error
+	Err error
+}
+
+/*
+ * ResolveTag resolves a 20 bytes tag to its full address and balance
+ * through the tag_resolve /call method
+ *
+ * Parameters:
+ * - ctx: cancels the request
+ * - tag: the 20 bytes tag
+ *
+ * A non-200 answer is a *MeshError, like an "error" member in a 200
+ * answer. A tag the server does not know, reported by a missing or empty
+ * result or by a "not found" error, is ErrTagNotFound. A server without
+ * tag_resolve yields an *UnsupportedError, and an address that is not
+ * AddressLength bytes of hex, with or without 0x, a *DecodeError.
+ */
+func (c *MeshAPIClient) ResolveTag(ctx context.Context, tag []byte) (TagResolution, error) {
+	account, err := AccountFromTag(tag)
+	if err != nil {
+		return TagResolution{}, err
+	}
+	var result struct {
+		Result *struct {
+			Address string `json:"address"`
+			Amount  uint64 `json:"amount"`
+		} `json:"result"`
+	}
+	if err := c.call(ctx, MethodTagResolve, map[string]string{"tag": account.Address}, &result); err != nil {
+		if tagNotFound(err) {
+			return TagResolution{}, ErrTagNotFound
+		}
+		return TagResolution{}, err
+	}
+	if result.Result == nil || result.Result.Address == "" {
+		return TagResolution{}, ErrTagNotFound
+	}
+
+	address, err := parseAddress(result.Result.Address)
+	if err != nil {
+		return TagResolution{}, &DecodeError{Path: "/call", Err: fmt.Errorf("invalid tag_resolve address %q: %v", result.Result.Address, err)}
+	}
+	return TagResolution{
+		Address:    address,
+		AddressHex: "0x" + hex.EncodeToString(address),
+		Amount:     result.Result.Amount,
+		Found:      true,
+	}, nil
+}
+
+// parseAddress decodes a full address given as hex, with or without 0x, or a *mcmaddr.LengthError
+func parseAddress(s string) ([]byte, error) {
+	if len(s) >= 2 && (s[:2] == "0x" || s[:2] == "0X") {
+		s = s[2:]
+	}
+	address, err := hex.DecodeString(s)
+	if err != nil {
+		return nil, err
+	}
+	if len(address) != AddressLength {
+		return nil, &mcmaddr.LengthError{Length: len(address), Expected: AddressLength}
+	}
+	return address, nil
+}
+
+// Tag returns the tag part of a found address
+func (r TagResolution) Tag() [mcmaddr.TagLength]byte {
+	var tag [mcmaddr.TagLength]byte
+	copy(tag[:], r.Address)
+	return tag
+}
+
+// AddrHash returns the WOTS+ address hash part of a found address, to compare with wotsp.AddrHashFromPK
+func (r TagResolution) AddrHash() [wotsp.AddrHashLength]byte {
+	var hash [wotsp.AddrHashLength]byte
+	if len(r.Address) == AddressLength {
+		copy(hash[:], r.Address[mcmaddr.TagLength:])
+	}
+	return hash
+}
+
+// tagNotFound reports whether an error is the server saying it knows no such tag
+func tagNotFound(err error) bool {
+	var meshErr *MeshError
+	if !errors.As(err, &meshErr) {
+		return false
+	}
+	message := strings.ToLower(meshErr.Message + " " + meshErr.Description)
+	return strings.Contains(message, "not found") &&
+		(strings.Contains(message, "tag") || strings.Contains(message, "account") || strings.Contains(message, "address"))
+}
+
+// callError returns the "error" member of a /call answer as a *MeshError, nil if it has none
+func callError(raw json.RawMessage) error {
+	var answer struct {
+		Error json.RawMessage `json:"error"`
+	}
+	if json.Unmarshal(raw, &answer) != nil || len(answer.Error) == 0 || string(answer.Error) == "null" {
+		return nil
+	}
+	meshErr := &MeshError{StatusCode: 200, Body: string(answer.Error)}
+	var message string
+	switch {
+	case json.Unmarshal(answer.Error, meshErr) == nil && meshErr.Message != "":
+		meshErr.Schema = true
+	case json.Unmarshal(answer.Error, &message) == nil && message != "":
+		meshErr.Message, meshErr.Schema = message, true
+	}
+	return meshErr
+}
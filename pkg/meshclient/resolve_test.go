@@ -0,0 +1,106 @@
+package meshclient
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// callServer lists tag_resolve in /network/options and answers /call with status and answer
+func callServer(t *testing.T, status int, answer string) *MeshAPIClient {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/network/options" {
+			io.WriteString(w, mochimoOptions)
+			return
+		}
+		w.WriteHeader(status)
+		io.WriteString(w, answer)
+	}))
+	t.Cleanup(server.Close)
+	return NewMeshAPIClient(server.URL, nil)
+}
+
+func TestResolveTag(t *testing.T) {
+	tag := strings.Repeat("42", 20)
+	hash := strings.Repeat("ab", 20)
+	client := callServer(t, http.StatusOK, `{"result":{"address":"0x`+tag+hash+`","amount":1500},"idempotent":false}`)
+	resolution, err := client.ResolveTag(context.Background(), bytes.Repeat([]byte{0x42}, 20))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !resolution.Found || resolution.Amount != 1500 || resolution.AddressHex != "0x"+tag+hash || len(resolution.Address) != AddressLength {
+		t.Errorf("resolution %+v", resolution)
+	}
+	if resolution.Tag() != batchTag(0x42) || resolution.AddrHash()[0] != 0xab {
+		t.Errorf("tag %x, hash %x", resolution.Tag(), resolution.AddrHash())
+	}
+
+	// The address may come without 0x
+	client = callServer(t, http.StatusOK, `{"result":{"address":"`+tag+hash+`","amount":1}}`)
+	if resolution, err := client.ResolveTag(context.Background(), bytes.Repeat([]byte{0x42}, 20)); err != nil || resolution.AddressHex != "0x"+tag+hash {
+		t.Errorf("no 0x: %+v, %v", resolution, err)
+	}
+
+	// A tag that is not 20 bytes is refused
+	if _, err := client.ResolveTag(context.Background(), make([]byte, 12)); err == nil {
+		t.Error("12 bytes tag accepted")
+	}
+}
+
+func TestResolveTagNotFound(t *testing.T) {
+	for _, tc := range []struct {
+		name   string
+		status int
+		answer string
+	}{
+		{"no result", http.StatusOK, `{}`},
+		{"null result", http.StatusOK, `{"result":null}`},
+		{"empty address", http.StatusOK, `{"result":{"address":"","amount":0}}`},
+		{"error status", http.StatusInternalServerError, `{"code":5,"message":"Account not found","retriable":false}`},
+		{"error member", http.StatusOK, `{"error":{"code":5,"message":"tag not found"}}`},
+		{"error string", http.StatusOK, `{"error":"address not found"}`},
+	} {
+		client := callServer(t, tc.status, tc.answer)
+		if resolution, err := client.ResolveTag(context.Background(), bytes.Repeat([]byte{0x01}, 20)); err != ErrTagNotFound || resolution.Found {
+			t.Errorf("%s: %+v, %v", tc.name, resolution, err)
+		}
+	}
+}
+
+func TestResolveTagErrors(t *testing.T) {
+	for _, tc := range []struct {
+		name    string
+		status  int
+		answer  string
+		decode  bool
+		message string
+	}{
+		{"HTTP 500", http.StatusInternalServerError, `{"code":2,"message":"internal error","retriable":false}`, false, "internal error"},
+		{"HTTP 500 without schema", http.StatusInternalServerError, `upstream down`, false, "upstream down"},
+		{"error member", http.StatusOK, `{"error":{"code":3,"message":"node busy"}}`, false, "node busy"},
+		{"malformed JSON", http.StatusOK, `{"result":{"address":`, true, ""},
+		{"address not hex", http.StatusOK, `{"result":{"address":"0xzz","amount":1}}`, true, "0xzz"},
+		{"short address", http.StatusOK, `{"result":{"address":"0x4242","amount":1}}`, true, "0x4242"},
+	} {
+		client := callServer(t, tc.status, tc.answer)
+		resolution, err := client.ResolveTag(context.Background(), bytes.Repeat([]byte{0x01}, 20))
+		if err == nil || err == ErrTagNotFound || resolution.Found {
+			t.Errorf("%s: %+v, %v", tc.name, resolution, err)
+			continue
+		}
+		var decodeErr *DecodeError
+		var meshErr *MeshError
+		if tc.decode && !errors.As(err, &decodeErr) || !tc.decode && !errors.As(err, &meshErr) {
+			t.Errorf("%s: %T %v", tc.name, err, err)
+		}
+		if !strings.Contains(err.Error(), tc.message) {
+			t.Errorf("%s: %q does not say %q", tc.name, err, tc.message)
+		}
+	}
+}
@@ -0,0 +1,166 @@
+package meshclient
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+	"time"
+)
+
+/*
+ * RetryPolicy retries failed requests with exponential backoff
+ *
+ * It applies to the read-only calls (ResolveTag, AccountBalance,
+ * NetworkStatus, Mempool, Block, BlockTransaction). SubmitTransaction is
+ * only retried when RetrySubmit is set: a submit that reached the node but
+ * whose answer was lost would otherwise be broadcast twice.
+ */
+type RetryPolicy struct {
+	// MaxAttempts is the total number of attempts, the first one included; <= 1 disables retries
+	MaxAttempts int
+	// BaseBackoff is the delay before the first retry, doubled for every following one
+	BaseBackoff time.Duration
+	// MaxBackoff caps the delay between two attempts (0 means no cap)
+	MaxBackoff time.Duration
+	// Jitter randomly shortens each delay by up to this fraction (0 to 1)
+	Jitter float64
+	// MaxRetryAfter is the longest Retry-After waited for before a retry; a
+	// longer one returns the error at once, for the caller to pace itself (0 means no cap)
+	MaxRetryAfter time.Duration
+	// Retryable decides whether an error is worth another attempt; nil uses DefaultRetryable
+	Retryable func(err error) bool
+	// OnRetry, if set, is called before every retry, for logging or metrics
+	OnRetry func(op string, attempt int, delay time.Duration, err error)
+	// RetrySubmit opts SubmitTransaction in
+	RetrySubmit bool
+	// Sleep waits between attempts; nil waits on a timer, returning early with ctx's error
+	Sleep func(ctx context.Context, d time.Duration) error
+}
+
+// DefaultRetryPolicy returns 4 attempts with a 500ms base backoff capped at 10s and 20% jitter, waiting up to 30s for a Retry-After
+func DefaultRetryPolicy() *RetryPolicy {
+	return &RetryPolicy{
+		MaxAttempts:   4,
+		BaseBackoff:   500 * time.Millisecond,
+		MaxBackoff:    10 * time.Second,
+		Jitter:        0.2,
+		MaxRetryAfter: 30 * time.Second,
+	}
+}
+
+/*
+ * DefaultRetryable reports whether another attempt may succeed:
+ * - a *TransportError is retried, unless the context was canceled or expired
+ * - a throttled answer (429 or 503, see Throttled) is retried
+ * - a *FeeTooLowError or *SignatureRejectedError is final: the same
+ *   transaction will be rejected again
+ * - another *MeshError with the Rosetta schema follows its retriable flag,
+ *   one without is retried on 5xx
+ * - anything else (e.g. a *DecodeError) is final
+ */
+func DefaultRetryable(err error) bool {
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+	if errors.Is(err, ErrFeeTooLow) || errors.Is(err, ErrSignatureRejected) {
+		return false
+	}
+	if _, throttled := Throttled(err); throttled {
+		return true
+	}
+	var meshErr *MeshError
+	if errors.As(err, &meshErr) {
+		if meshErr.Schema {
+			return meshErr.Retriable
+		}
+		return meshErr.StatusCode >= 500
+	}
+	var transportErr *TransportError
+	return errors.As(err, &transportErr)
+}
+
+// Backoff returns the delay before retry number attempt (1 for the first retry), jitter not applied
+func (p *RetryPolicy) Backoff(attempt int) time.Duration {
+	delay := p.BaseBackoff
+	for i := 1; i < attempt; i++ {
+		delay *= 2
+		if p.MaxBackoff > 0 && delay >= p.MaxBackoff {
+			break
+		}
+	}
+	if p.MaxBackoff > 0 && delay > p.MaxBackoff {
+		delay = p.MaxBackoff
+	}
+	return delay
+}
+
+// SetRetryPolicy makes the client retry per policy; nil disables retries (the default)
+func (c *MeshAPIClient) SetRetryPolicy(policy *RetryPolicy) {
+	c.retry = policy
+}
+
+/*
+ * withRetry runs attempt until it succeeds, fails with an error the policy
+ * does not retry, or the attempts run out
+ *
+ * Parameters:
+ * - ctx: stops the waits between attempts
+ * - op: name of the operation, passed to OnRetry
+ * - submit: whether attempt submits a transaction (retried only if RetrySubmit)
+ * - attempt: the request, given the attempt number starting at 1
+ *
+ * A throttled attempt waits at least the Retry-After it was given, unless
+ * that is longer than MaxRetryAfter: its error is then returned at once.
+ *
+ * Returns the error of the last attempt.
+ */
+func (c *MeshAPIClient) withRetry(ctx context.Context, op string, submit bool, attempt func(n int) error) error {
+	policy := c.retry
+	if policy == nil || policy.MaxAttempts <= 1 || (submit && !policy.RetrySubmit) {
+		return attempt(1)
+	}
+	retryable := policy.Retryable
+	if retryable == nil {
+		retryable = DefaultRetryable
+	}
+	sleep := policy.Sleep
+	if sleep == nil {
+		sleep = sleepContext
+	}
+
+	var err error
+	for n := 1; ; n++ {
+		err = attempt(n)
+		if err == nil || n >= policy.MaxAttempts || !retryable(err) {
+			return err
+		}
+		delay := policy.Backoff(n)
+		if policy.Jitter > 0 {
+			delay -= time.Duration(rand.Float64() * policy.Jitter * float64(delay))
+		}
+		if wait, throttled := Throttled(err); throttled && wait > 0 {
+			if policy.MaxRetryAfter > 0 && wait > policy.MaxRetryAfter {
+				return err
+			}
+			delay = max(delay, wait)
+		}
+		if policy.OnRetry != nil {
+			policy.OnRetry(op, n+1, delay, err)
+		}
+		if sleepErr := sleep(ctx, delay); sleepErr != nil {
+			return err
+		}
+	}
+}
+
+// sleepContext waits for d, returning ctx's error early if it is canceled first
+func sleepContext(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
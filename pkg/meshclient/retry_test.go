@@ -0,0 +1,232 @@
+package meshclient
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+/*
+ * flakyServer fails the first failures requests with status and body, then
+ * answers ok; it returns the client and the count of requests received
+ */
+func flakyServer(t *testing.T, failures int, status int, body string, ok string) (*MeshAPIClient, *atomic.Int32) {
+	t.Helper()
+	var requests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if int(requests.Add(1)) <= failures {
+			w.Header().Set("Retry-After", r.Header.Get("X-Test-Retry-After"))
+			w.WriteHeader(status)
+			io.WriteString(w, body)
+			return
+		}
+		io.WriteString(w, ok)
+	}))
+	t.Cleanup(server.Close)
+	return NewMeshAPIClient(server.URL, nil), &requests
+}
+
+// fakeClock is the Sleep of a RetryPolicy adding up the waits instead of sleeping
+type fakeClock struct {
+	waits []time.Duration
+}
+
+func (c *fakeClock) sleep(ctx context.Context, d time.Duration) error {
+	c.waits = append(c.waits, d)
+	return ctx.Err()
+}
+
+func (c *fakeClock) elapsed() time.Duration {
+	var total time.Duration
+	for _, d := range c.waits {
+		total += d
+	}
+	return total
+}
+
+// testPolicy is a jitter-free policy of attempts attempts sleeping on clock
+func testPolicy(attempts int, clock *fakeClock) *RetryPolicy {
+	return &RetryPolicy{MaxAttempts: attempts, BaseBackoff: 100 * time.Millisecond, MaxBackoff: time.Second, Sleep: clock.sleep}
+}
+
+const statusAnswer = `{"current_block_identifier":{"index":5,"hash":"0x05"}}`
+
+func TestRetrySucceeds(t *testing.T) {
+	client, requests := flakyServer(t, 3, http.StatusBadGateway, "bad gateway", statusAnswer)
+	clock := &fakeClock{}
+	policy := testPolicy(5, clock)
+	var retries []int
+	policy.OnRetry = func(op string, attempt int, delay time.Duration, err error) {
+		if op != "/network/status" || err == nil {
+			t.Errorf("OnRetry(%q, %d, %v, %v)", op, attempt, delay, err)
+		}
+		retries = append(retries, attempt)
+	}
+	client.SetRetryPolicy(policy)
+
+	status, err := client.NetworkStatus(context.Background())
+	if err != nil || status.CurrentBlockIdentifier.Index != 5 {
+		t.Fatalf("%+v, %v", status, err)
+	}
+	if requests.Load() != 4 || len(retries) != 3 || retries[2] != 4 {
+		t.Errorf("%d requests, retries %v", requests.Load(), retries)
+	}
+	// 100ms, 200ms then 400ms
+	if clock.elapsed() != 700*time.Millisecond {
+		t.Errorf("waited %v (%v), want 700ms", clock.elapsed(), clock.waits)
+	}
+}
+
+func TestRetryGivesUp(t *testing.T) {
+	client, requests := flakyServer(t, 10, http.StatusBadGateway, "bad gateway", statusAnswer)
+	clock := &fakeClock{}
+	client.SetRetryPolicy(testPolicy(4, clock))
+	_, err := client.NetworkStatus(context.Background())
+	var meshErr *MeshError
+	if !errors.As(err, &meshErr) || meshErr.StatusCode != http.StatusBadGateway {
+		t.Errorf("got %v", err)
+	}
+	if requests.Load() != 4 || clock.elapsed() != 700*time.Millisecond {
+		t.Errorf("%d requests, waited %v", requests.Load(), clock.elapsed())
+	}
+}
+
+func TestRetryFinalErrors(t *testing.T) {
+	for _, tc := range []struct {
+		name   string
+		status int
+		body   string
+	}{
+		{"client error", http.StatusBadRequest, "bad request"},
+		{"not found", http.StatusNotFound, "not found"},
+		{"not retriable", http.StatusInternalServerError, `{"code":2,"message":"internal error","retriable":false}`},
+	} {
+		client, requests := flakyServer(t, 10, tc.status, tc.body, statusAnswer)
+		client.SetRetryPolicy(testPolicy(4, &fakeClock{}))
+		if _, err := client.NetworkStatus(context.Background()); err == nil || requests.Load() != 1 {
+			t.Errorf("%s: %d requests, %v", tc.name, requests.Load(), err)
+		}
+	}
+
+	// An undecodable 200 answer is final
+	client, requests := flakyServer(t, 0, http.StatusOK, "", "not json")
+	client.SetRetryPolicy(testPolicy(4, &fakeClock{}))
+	var decodeErr *DecodeError
+	if _, err := client.NetworkStatus(context.Background()); !errors.As(err, &decodeErr) || requests.Load() != 1 {
+		t.Errorf("undecodable answer: %d requests, %v", requests.Load(), err)
+	}
+
+	// The retriable flag wins over the status
+	client, requests = flakyServer(t, 1, http.StatusBadRequest, `{"code":3,"message":"busy","retriable":true}`, statusAnswer)
+	client.SetRetryPolicy(testPolicy(4, &fakeClock{}))
+	if _, err := client.NetworkStatus(context.Background()); err != nil || requests.Load() != 2 {
+		t.Errorf("retriable 400: %d requests, %v", requests.Load(), err)
+	}
+
+	// Too Many Requests is retried
+	client, requests = flakyServer(t, 1, http.StatusTooManyRequests, "slow down", statusAnswer)
+	client.SetRetryPolicy(testPolicy(4, &fakeClock{}))
+	if _, err := client.NetworkStatus(context.Background()); err != nil || requests.Load() != 2 {
+		t.Errorf("429: %d requests, %v", requests.Load(), err)
+	}
+}
+
+func TestRetrySubmit(t *testing.T) {
+	answer := `{"transaction_identifier":{"hash":"0x01"}}`
+	client, requests := flakyServer(t, 1, http.StatusBadGateway, "bad gateway", answer)
+	policy := testPolicy(4, &fakeClock{})
+	client.SetRetryPolicy(policy)
+	if _, err := client.SubmitTransaction(context.Background(), "0x00"); err == nil || requests.Load() != 1 {
+		t.Errorf("submit retried without RetrySubmit: %d requests, %v", requests.Load(), err)
+	}
+
+	policy.RetrySubmit = true
+	client, requests = flakyServer(t, 1, http.StatusBadGateway, "bad gateway", answer)
+	client.SetRetryPolicy(policy)
+	if _, err := client.SubmitTransaction(context.Background(), "0x00"); err != nil || requests.Load() != 2 {
+		t.Errorf("with RetrySubmit: %d requests, %v", requests.Load(), err)
+	}
+}
+
+// TestRetryCanceled checks a context canceled between two attempts stops the retries
+func TestRetryCanceled(t *testing.T) {
+	client, requests := flakyServer(t, 10, http.StatusBadGateway, "bad gateway", statusAnswer)
+	ctx, cancel := context.WithCancel(context.Background())
+	clock := &fakeClock{}
+	policy := testPolicy(4, clock)
+	policy.OnRetry = func(op string, attempt int, delay time.Duration, err error) { cancel() }
+	client.SetRetryPolicy(policy)
+	var meshErr *MeshError
+	if _, err := client.NetworkStatus(ctx); !errors.As(err, &meshErr) || requests.Load() != 1 {
+		t.Errorf("%d requests, %v", requests.Load(), err)
+	}
+}
+
+func TestRetryAfter(t *testing.T) {
+	client, requests := flakyServer(t, 1, http.StatusTooManyRequests, "slow down", statusAnswer)
+	client.SetHeader("X-Test-Retry-After", "3")
+	clock := &fakeClock{}
+	client.SetRetryPolicy(testPolicy(4, clock))
+	if _, err := client.NetworkStatus(context.Background()); err != nil || requests.Load() != 2 {
+		t.Fatalf("%d requests, %v", requests.Load(), err)
+	}
+	if clock.elapsed() != 3*time.Second {
+		t.Errorf("waited %v, want the 3s of Retry-After", clock.elapsed())
+	}
+
+	// A Retry-After over MaxRetryAfter is returned at once
+	client, requests = flakyServer(t, 1, http.StatusServiceUnavailable, "down", statusAnswer)
+	client.SetHeader("X-Test-Retry-After", "120")
+	policy := testPolicy(4, &fakeClock{})
+	policy.MaxRetryAfter = time.Minute
+	client.SetRetryPolicy(policy)
+	_, err := client.NetworkStatus(context.Background())
+	if wait, throttled := Throttled(err); !throttled || wait != 2*time.Minute || requests.Load() != 1 {
+		t.Errorf("%d requests, %v", requests.Load(), err)
+	}
+}
+
+// TestRetryHooks checks every attempt reaches the hooks with its number
+func TestRetryHooks(t *testing.T) {
+	client, _ := flakyServer(t, 2, http.StatusBadGateway, "bad gateway", statusAnswer)
+	client.SetRetryPolicy(testPolicy(4, &fakeClock{}))
+	var ends []RequestInfo
+	client.SetHooks(hookFuncs{end: func(info RequestInfo) { ends = append(ends, info) }})
+	if _, err := client.NetworkStatus(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if len(ends) != 3 {
+		t.Fatalf("%d attempts reported", len(ends))
+	}
+	for i, info := range ends {
+		if info.Attempt != i+1 || info.Op != "/network/status" || (info.Err == nil) != (i == 2) {
+			t.Errorf("attempt %d: %+v", i+1, info)
+		}
+	}
+	if ends[0].RequestID == ends[1].RequestID {
+		t.Error("two attempts share a request ID")
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	policy := &RetryPolicy{BaseBackoff: time.Second, MaxBackoff: 5 * time.Second}
+	for attempt, want := range map[int]time.Duration{1: time.Second, 2: 2 * time.Second, 3: 4 * time.Second, 4: 5 * time.Second, 40: 5 * time.Second} {
+		if policy.Backoff(attempt) != want {
+			t.Errorf("attempt %d: %v, want %v", attempt, policy.Backoff(attempt), want)
+		}
+	}
+}
+
+// hookFuncs adapts functions to Hooks
+type hookFuncs struct {
+	end func(info RequestInfo)
+}
+
+func (h hookFuncs) OnRequestStart(info RequestInfo) {}
+
+func (h hookFuncs) OnRequestEnd(info RequestInfo) { h.end(info) }
@@ -0,0 +1,103 @@
+package meshclient
+
+import (
+	"context"
+)
+
+// SearchQuery filters /search/transactions; zero fields are not sent
+type SearchQuery struct {
+	// Tag restricts the search to transactions touching this 20 bytes tag
+	Tag []byte
+	// Type restricts the search to one operation type (e.g. "TRANSFER")
+	Type string
+	// Limit asks for at most this many transactions per page; servers may return fewer
+	Limit int64
+	// Offset is the pagination cursor, the NextOffset of the previous page
+	Offset int64
+}
+
+// BlockTransactionEntry is a search hit: a transaction and the block that holds it
+type BlockTransactionEntry struct {
+	BlockIdentifier BlockIdentifier `json:"block_identifier"`
+	Transaction     Transaction     `json:"transaction"`
+}
+
+// SearchResult is one page of /search/transactions
+type SearchResult struct {
+	Transactions []BlockTransactionEntry `json:"transactions"`
+	TotalCount   int64                   `json:"total_count"`
+	NextOffset   *int64                  `json:"next_offset,omitempty"`
+}
+
+// SearchTransactions returns one page of the transactions matching query
+func (c *MeshAPIClient) SearchTransactions(ctx context.Context, query SearchQuery) (*SearchResult, error) {
+	request := struct {
+		NetworkIdentifier NetworkIdentifier  `json:"network_identifier"`
+		AccountIdentifier *AccountIdentifier `json:"account_identifier,omitempty"`
+		Type              string             `json:"type,omitempty"`
+		Limit             int64              `json:"limit,omitempty"`
+		Offset            int64              `json:"offset,omitempty"`
+	}{NetworkIdentifier: c.network, Type: query.Type, Limit: query.Limit, Offset: query.Offset}
+	if len(query.Tag) > 0 {
+		account, err := AccountFromTag(query.Tag)
+		if err != nil {
+			return nil, err
+		}
+		request.AccountIdentifier = &account
+	}
+
+	var result SearchResult
+	if err := c.post(ctx, "/search/transactions", request, &result); err != nil {
+		return nil, err
+	}
+	for _, hit := range result.Transactions {
+		if err := c.checkTransactions("/search/transactions", hit.Transaction); err != nil {
+			return nil, err
+		}
+	}
+	return &result, nil
+}
+
+/*
+ * SearchAllTransactions follows the pagination of /search/transactions
+ *
+ * Parameters:
+ * - ctx: cancels the search between or during pages
+ * - query: filter and page size; its Offset is where the search starts
+ * - max: stop once this many transactions are collected (<= 0 for no limit)
+ *
+ * Pages are followed through next_offset. Servers that cap page sizes
+ * below query.Limit are handled: when next_offset is missing but
+ * total_count says more remain, the offset advances by the page length.
+ * The search stops on an empty page or an offset that does not advance.
+ *
+ * Returns the transactions in the order the server lists them, and whether
+ * the search was cut short by max.
+ */
+func (c *MeshAPIClient) SearchAllTransactions(ctx context.Context, query SearchQuery, max int) ([]BlockTransactionEntry, bool, error) {
+	var all []BlockTransactionEntry
+	for {
+		page, err := c.SearchTransactions(ctx, query)
+		if err != nil {
+			return all, false, err
+		}
+		all = append(all, page.Transactions...)
+		if max > 0 && len(all) >= max {
+			return all[:max], len(all) > max || page.NextOffset != nil || int64(len(all)) < page.TotalCount, nil
+		}
+		if len(page.Transactions) == 0 {
+			return all, false, nil
+		}
+
+		next := query.Offset + int64(len(page.Transactions))
+		if page.NextOffset != nil {
+			next = *page.NextOffset
+		} else if int64(len(all)) >= page.TotalCount {
+			return all, false, nil
+		}
+		if next <= query.Offset {
+			return all, false, nil
+		}
+		query.Offset = next
+	}
+}
@@ -0,0 +1,143 @@
+package meshclient
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// pagingServer serves total search hits in pages of at most pageCap, with next_offset only if withNext
+func pagingServer(t *testing.T, total int64, pageCap int64, withNext bool) (*MeshAPIClient, *[]map[string]interface{}) {
+	t.Helper()
+	var requests []map[string]interface{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var request struct {
+			Limit  int64 `json:"limit"`
+			Offset int64 `json:"offset"`
+		}
+		var raw map[string]interface{}
+		json.NewDecoder(r.Body).Decode(&raw)
+		encoded, _ := json.Marshal(raw)
+		json.Unmarshal(encoded, &request)
+		requests = append(requests, raw)
+
+		limit := min(request.Limit, pageCap)
+		if limit <= 0 {
+			limit = pageCap
+		}
+		result := SearchResult{TotalCount: total, Transactions: []BlockTransactionEntry{}}
+		for i := request.Offset; i < min(request.Offset+limit, total); i++ {
+			result.Transactions = append(result.Transactions, BlockTransactionEntry{
+				BlockIdentifier: BlockIdentifier{Index: uint64(total - i)},
+				Transaction:     Transaction{TransactionIdentifier: TransactionIdentifier{Hash: fmt.Sprintf("0x%04x", i)}},
+			})
+		}
+		if end := request.Offset + int64(len(result.Transactions)); withNext && end < total {
+			result.NextOffset = &end
+		}
+		json.NewEncoder(w).Encode(result)
+	}))
+	t.Cleanup(server.Close)
+	return NewMeshAPIClient(server.URL, nil), &requests
+}
+
+// checkHits fails unless hits are the first n of a pagingServer, in order
+func checkHits(t *testing.T, hits []BlockTransactionEntry, n int) {
+	t.Helper()
+	if len(hits) != n {
+		t.Fatalf("%d hits, want %d", len(hits), n)
+	}
+	for i, hit := range hits {
+		if hit.Transaction.TransactionIdentifier.Hash != fmt.Sprintf("0x%04x", i) {
+			t.Fatalf("hit %d is %s", i, hit.Transaction.TransactionIdentifier.Hash)
+		}
+	}
+}
+
+func TestSearchTransactionsRequest(t *testing.T) {
+	client, received := testServer(t, http.StatusOK, `{"transactions":[{"block_identifier":{"index":3,"hash":"0x03"},
+		"transaction":{"transaction_identifier":{"hash":"0x01"}}}],"total_count":9,"next_offset":1}`)
+	tag := make([]byte, 20)
+	tag[19] = 0x07
+	result, err := client.SearchTransactions(context.Background(), SearchQuery{Tag: tag, Type: "TRANSFER", Limit: 1, Offset: 4})
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkRequest(t, *received, "/search/transactions", `{`+network+`,
+		"account_identifier":{"address":"0x0000000000000000000000000000000000000007"},"type":"TRANSFER","limit":1,"offset":4}`)
+	if len(result.Transactions) != 1 || result.TotalCount != 9 || *result.NextOffset != 1 || result.Transactions[0].BlockIdentifier.Index != 3 {
+		t.Errorf("result %+v", result)
+	}
+
+	// Zero fields are not sent
+	*received = nil
+	client.SearchTransactions(context.Background(), SearchQuery{})
+	checkRequest(t, *received, "/search/transactions", `{`+network+`}`)
+}
+
+func TestSearchAllTransactions(t *testing.T) {
+	for _, tc := range []struct {
+		name     string
+		pageCap  int64
+		withNext bool
+		requests int
+	}{
+		{"next_offset", 10, true, 5},
+		// The server caps pages at 4 and gives no next_offset: the offset advances by the page length
+		{"capped pages", 4, false, 12},
+		{"capped pages with next_offset", 4, true, 12},
+	} {
+		client, requests := pagingServer(t, 45, tc.pageCap, tc.withNext)
+		hits, truncated, err := client.SearchAllTransactions(context.Background(), SearchQuery{Limit: 10}, 0)
+		if err != nil || truncated {
+			t.Fatalf("%s: truncated %v, %v", tc.name, truncated, err)
+		}
+		checkHits(t, hits, 45)
+		if len(*requests) != tc.requests {
+			t.Errorf("%s: %d requests, want %d", tc.name, len(*requests), tc.requests)
+		}
+	}
+}
+
+func TestSearchAllTransactionsMax(t *testing.T) {
+	client, requests := pagingServer(t, 45, 10, true)
+	hits, truncated, err := client.SearchAllTransactions(context.Background(), SearchQuery{Limit: 10}, 15)
+	if err != nil || !truncated {
+		t.Fatalf("truncated %v, %v", truncated, err)
+	}
+	checkHits(t, hits, 15)
+	if len(*requests) != 2 {
+		t.Errorf("%d requests, want 2", len(*requests))
+	}
+
+	// A max equal to the total does not report a cut
+	client, _ = pagingServer(t, 20, 10, true)
+	if hits, truncated, err := client.SearchAllTransactions(context.Background(), SearchQuery{Limit: 10}, 20); err != nil || truncated || len(hits) != 20 {
+		t.Errorf("max of the total: %d hits, truncated %v, %v", len(hits), truncated, err)
+	}
+}
+
+// TestSearchAllTransactionsStops checks a server that does not advance its offset cannot loop the search
+func TestSearchAllTransactionsStops(t *testing.T) {
+	client, received := testServer(t, http.StatusOK, `{"transactions":[{"transaction":{"transaction_identifier":{"hash":"0x01"}}}],
+		"total_count":100,"next_offset":0}`)
+	hits, truncated, err := client.SearchAllTransactions(context.Background(), SearchQuery{}, 0)
+	if err != nil || truncated || len(hits) != 1 || len(*received) != 1 {
+		t.Errorf("%d hits after %d requests, %v", len(hits), len(*received), err)
+	}
+
+	client, received = testServer(t, http.StatusOK, `{"transactions":[],"total_count":100}`)
+	if hits, _, err := client.SearchAllTransactions(context.Background(), SearchQuery{}, 0); err != nil || len(hits) != 0 || len(*received) != 1 {
+		t.Errorf("empty page: %d hits after %d requests, %v", len(hits), len(*received), err)
+	}
+}
+
+func TestSearchAllTransactionsError(t *testing.T) {
+	client, _ := testServer(t, http.StatusBadGateway, "bad gateway")
+	if _, _, err := client.SearchAllTransactions(context.Background(), SearchQuery{}, 0); err == nil {
+		t.Error("no error")
+	}
+}
@@ -0,0 +1,97 @@
+package meshclient
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// DefaultStatusTTL is how long a StatusCache serves a /network/status answer
+const DefaultStatusTTL = 2 * time.Second
+
+/*
+ * StatusCache shares /network/status answers between the callers of
+ * NetworkStatus for a short time
+ *
+ * Concurrent callers that find the cache expired wait for a single upstream
+ * request, made with the context of the caller that started it, and share
+ * its answer. Errors are not cached. Set it with SetStatusCache; a
+ * StatusCache must not be shared between clients.
+ */
+type StatusCache struct {
+	// TTL is how long an answer is served (0 uses DefaultStatusTTL)
+	TTL time.Duration
+	// Now returns the current time; nil uses time.Now
+	Now func() time.Time
+
+	mu       sync.Mutex
+	status   *NetworkStatus
+	fetched  time.Time
+	inflight *statusFetch
+}
+
+// statusFetch is an upstream request that callers of an expired cache wait for
+type statusFetch struct {
+	done   chan struct{}
+	status *NetworkStatus
+	err    error
+}
+
+// SetStatusCache makes NetworkStatus go through cache; nil disables caching (the default)
+func (c *MeshAPIClient) SetStatusCache(cache *StatusCache) {
+	c.statusCache = cache
+}
+
+// InvalidateStatus drops the cached network status, e.g. once a block change is detected
+func (c *MeshAPIClient) InvalidateStatus() {
+	cache := c.statusCache
+	if cache == nil {
+		return
+	}
+	cache.mu.Lock()
+	cache.status = nil
+	cache.mu.Unlock()
+}
+
+// get returns the cached status or fetches it, sharing the fetch between concurrent callers
+func (cache *StatusCache) get(ctx context.Context, fetch func() (*NetworkStatus, error)) (*NetworkStatus, error) {
+	now := time.Now
+	if cache.Now != nil {
+		now = cache.Now
+	}
+	ttl := cache.TTL
+	if ttl <= 0 {
+		ttl = DefaultStatusTTL
+	}
+
+	cache.mu.Lock()
+	if cache.status != nil && now().Sub(cache.fetched) < ttl {
+		status := cache.status
+		cache.mu.Unlock()
+		return status, nil
+	}
+	flight := cache.inflight
+	if flight == nil {
+		flight = &statusFetch{done: make(chan struct{})}
+		cache.inflight = flight
+		cache.mu.Unlock()
+
+		flight.status, flight.err = fetch()
+		cache.mu.Lock()
+		if flight.err == nil {
+			cache.status, cache.fetched = flight.status, now()
+		}
+		cache.inflight = nil
+		cache.mu.Unlock()
+		close(flight.done)
+		return flight.status, flight.err
+	}
+	cache.mu.Unlock()
+
+	select {
+	case <-flight.done:
+		return flight.status, flight.err
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
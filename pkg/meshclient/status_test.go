@@ -0,0 +1,173 @@
+package meshclient
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// statusServer answers /network/status with a height counting its requests, after waiting for release if set
+func statusServer(t *testing.T, release chan struct{}) (*MeshAPIClient, *atomic.Int32) {
+	t.Helper()
+	var requests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := requests.Add(1)
+		if release != nil {
+			<-release
+		}
+		fmt.Fprintf(w, `{"current_block_identifier":{"index":%d,"hash":"0x01"}}`, n)
+	}))
+	t.Cleanup(server.Close)
+	return NewMeshAPIClient(server.URL, nil), &requests
+}
+
+// testClock is a StatusCache clock moved by the test
+type testClock struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+func (c *testClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *testClock) advance(d time.Duration) {
+	c.mu.Lock()
+	c.now = c.now.Add(d)
+	c.mu.Unlock()
+}
+
+// height is the current height of the network status, failing the test on error
+func height(t *testing.T, client *MeshAPIClient) uint64 {
+	t.Helper()
+	status, err := client.NetworkStatus(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return status.CurrentBlockIdentifier.Index
+}
+
+func TestStatusCacheTTL(t *testing.T) {
+	client, requests := statusServer(t, nil)
+	clock := &testClock{now: time.Unix(1700000000, 0)}
+	client.SetStatusCache(&StatusCache{Now: clock.Now})
+
+	if height(t, client) != 1 || height(t, client) != 1 {
+		t.Fatal("second call not served from the cache")
+	}
+	clock.advance(DefaultStatusTTL - time.Millisecond)
+	if height(t, client) != 1 || requests.Load() != 1 {
+		t.Errorf("%d requests within the TTL", requests.Load())
+	}
+	clock.advance(time.Millisecond)
+	if height(t, client) != 2 || requests.Load() != 2 {
+		t.Errorf("%d requests once expired", requests.Load())
+	}
+
+	// InvalidateStatus forces the next call upstream
+	client.InvalidateStatus()
+	if height(t, client) != 3 {
+		t.Error("invalidated status served")
+	}
+
+	client.SetStatusCache(&StatusCache{TTL: time.Minute, Now: clock.Now})
+	height(t, client)
+	clock.advance(30 * time.Second)
+	if height(t, client) != 4 {
+		t.Error("custom TTL not applied")
+	}
+
+	// Without a cache every call goes upstream
+	client.SetStatusCache(nil)
+	client.InvalidateStatus()
+	if height(t, client) != 5 || height(t, client) != 6 {
+		t.Error("answer cached without a StatusCache")
+	}
+}
+
+// TestStatusCacheShared checks concurrent callers of an expired cache share one upstream request
+func TestStatusCacheShared(t *testing.T) {
+	release := make(chan struct{})
+	client, requests := statusServer(t, release)
+	client.SetStatusCache(&StatusCache{Now: (&testClock{now: time.Unix(1700000000, 0)}).Now})
+
+	const callers = 20
+	var wg sync.WaitGroup
+	heights := make(chan uint64, callers)
+	for i := 0; i < callers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			status, err := client.NetworkStatus(context.Background())
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			heights <- status.CurrentBlockIdentifier.Index
+		}()
+	}
+	// Let every caller reach the cache before the answer comes
+	for requests.Load() == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	time.Sleep(50 * time.Millisecond)
+	close(release)
+	wg.Wait()
+	close(heights)
+	for h := range heights {
+		if h != 1 {
+			t.Errorf("caller got height %d", h)
+		}
+	}
+	if requests.Load() != 1 {
+		t.Errorf("%d upstream requests for %d callers", requests.Load(), callers)
+	}
+}
+
+// TestStatusCacheWaiterCanceled checks a caller waiting for another's request can give up
+func TestStatusCacheWaiterCanceled(t *testing.T) {
+	release := make(chan struct{})
+	defer close(release)
+	client, requests := statusServer(t, release)
+	client.SetStatusCache(&StatusCache{})
+	go client.NetworkStatus(context.Background())
+	for requests.Load() == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if _, err := client.NetworkStatus(ctx); err != context.DeadlineExceeded {
+		t.Errorf("got %v", err)
+	}
+}
+
+func TestStatusCacheErrors(t *testing.T) {
+	var fail atomic.Bool
+	fail.Store(true)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if fail.Load() {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		io.WriteString(w, statusAnswer)
+	}))
+	defer server.Close()
+	client := NewMeshAPIClient(server.URL, nil)
+	client.SetStatusCache(&StatusCache{})
+	if _, err := client.NetworkStatus(context.Background()); err == nil {
+		t.Fatal("no error")
+	}
+	// The error was not cached
+	fail.Store(false)
+	if _, err := client.NetworkStatus(context.Background()); err != nil {
+		t.Errorf("error cached: %v", err)
+	}
+}
@@ -0,0 +1,219 @@
+package meshclient
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io"
+	"log"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// testCA is a certificate authority issuing the certificates of a test
+type testCA struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+	dir  string
+	// file is the PEM bundle of the CA certificate
+	file string
+}
+
+// newTestCA generates a CA and writes its certificate in a temporary directory
+func newTestCA(t *testing.T) *testCA {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "meshclient test CA"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ca := &testCA{cert: cert, key: key, dir: t.TempDir()}
+	ca.file = filepath.Join(ca.dir, "ca.pem")
+	writePEM(t, ca.file, "CERTIFICATE", der)
+	return ca
+}
+
+/*
+ * issue returns a certificate signed by the CA for name, a host name or an
+ * IP address, for a server or, if client, for a client; the certificate
+ * and its key are also written as PEM files, whose paths are returned
+ */
+func (ca *testCA) issue(t *testing.T, name string, client bool) (tls.Certificate, string, string) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: name},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}
+	if ip := net.ParseIP(name); ip != nil {
+		template.IPAddresses = []net.IP{ip}
+	} else {
+		template.DNSNames = []string{name}
+	}
+	if client {
+		template.ExtKeyUsage = []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth}
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, ca.cert, &key.PublicKey, ca.key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	certFile, keyFile := filepath.Join(ca.dir, name+".pem"), filepath.Join(ca.dir, name+".key")
+	writePEM(t, certFile, "CERTIFICATE", der)
+	writePEM(t, keyFile, "EC PRIVATE KEY", keyDER)
+	return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key}, certFile, keyFile
+}
+
+func writePEM(t *testing.T, path string, kind string, der []byte) {
+	t.Helper()
+	if err := os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: kind, Bytes: der}), 0600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// tlsServer starts a TLS server presenting a certificate of the CA for name, requiring a client certificate of the CA if mutual
+func tlsServer(t *testing.T, ca *testCA, name string, mutual bool) *httptest.Server {
+	t.Helper()
+	cert, _, _ := ca.issue(t, name, false)
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, statusAnswer)
+	}))
+	server.TLS = &tls.Config{Certificates: []tls.Certificate{cert}}
+	if mutual {
+		pool := x509.NewCertPool()
+		pool.AddCert(ca.cert)
+		server.TLS.ClientCAs, server.TLS.ClientAuth = pool, tls.RequireAndVerifyClientCert
+	}
+	// Keep the handshakes the tests make fail out of the output
+	server.Config.ErrorLog = log.New(io.Discard, "", 0)
+	server.StartTLS()
+	t.Cleanup(server.Close)
+	return server
+}
+
+// statusOver makes a /network/status request to url through a client built from opts
+func statusOver(url string, opts TransportOptions) error {
+	httpClient, err := NewHTTPClient(opts)
+	if err != nil {
+		return err
+	}
+	_, err = NewMeshAPIClient(url, httpClient).NetworkStatus(context.Background())
+	return err
+}
+
+func TestTLSCustomCA(t *testing.T) {
+	ca := newTestCA(t)
+	server := tlsServer(t, ca, "127.0.0.1", false)
+	if err := statusOver(server.URL, TransportOptions{}); err == nil || !strings.Contains(err.Error(), "certificate") {
+		t.Errorf("system CAs: %v", err)
+	}
+	if err := statusOver(server.URL, TransportOptions{CAFile: ca.file}); err != nil {
+		t.Errorf("custom CA: %v", err)
+	}
+	if err := statusOver(server.URL, TransportOptions{CAFile: newTestCA(t).file}); err == nil {
+		t.Error("another CA is trusted")
+	}
+	if err := statusOver(server.URL, TransportOptions{InsecureSkipVerify: true}); err != nil {
+		t.Errorf("insecure: %v", err)
+	}
+}
+
+// TestTLSServerName reaches a server by IP whose certificate only names its host
+func TestTLSServerName(t *testing.T) {
+	ca := newTestCA(t)
+	server := tlsServer(t, ca, "mesh.internal", false)
+	if err := statusOver(server.URL, TransportOptions{CAFile: ca.file}); err == nil {
+		t.Error("certificate of mesh.internal accepted for 127.0.0.1")
+	}
+	if err := statusOver(server.URL, TransportOptions{CAFile: ca.file, ServerName: "mesh.internal"}); err != nil {
+		t.Errorf("with ServerName: %v", err)
+	}
+	if err := statusOver(server.URL, TransportOptions{CAFile: ca.file, ServerName: "other.internal"}); err == nil {
+		t.Error("certificate of mesh.internal accepted for other.internal")
+	}
+}
+
+func TestTLSClientCertificate(t *testing.T) {
+	ca := newTestCA(t)
+	server := tlsServer(t, ca, "127.0.0.1", true)
+	_, certFile, keyFile := ca.issue(t, "wallet-tool", true)
+	if err := statusOver(server.URL, TransportOptions{CAFile: ca.file}); err == nil {
+		t.Error("mutual TLS server answered without a client certificate")
+	}
+	if err := statusOver(server.URL, TransportOptions{CAFile: ca.file, CertFile: certFile, KeyFile: keyFile}); err != nil {
+		t.Errorf("client certificate: %v", err)
+	}
+
+	// A certificate of another CA is refused by the server
+	_, otherCert, otherKey := newTestCA(t).issue(t, "intruder", true)
+	if err := statusOver(server.URL, TransportOptions{CAFile: ca.file, CertFile: otherCert, KeyFile: otherKey}); err == nil {
+		t.Error("client certificate of another CA accepted")
+	}
+}
+
+func TestTLSOptionErrors(t *testing.T) {
+	ca := newTestCA(t)
+	_, certFile, keyFile := ca.issue(t, "wallet-tool", true)
+	notPEM := filepath.Join(t.TempDir(), "notes.txt")
+	os.WriteFile(notPEM, []byte("not a certificate"), 0600)
+	for _, tc := range []struct {
+		opts TransportOptions
+		want string
+	}{
+		{TransportOptions{CAFile: filepath.Join(t.TempDir(), "missing.pem")}, "failed to read CA bundle"},
+		{TransportOptions{CAFile: notPEM}, "no PEM certificate found in CA bundle"},
+		{TransportOptions{CertFile: certFile}, "needs both a certificate and a key file"},
+		{TransportOptions{KeyFile: keyFile}, "needs both a certificate and a key file"},
+		{TransportOptions{CertFile: certFile, KeyFile: notPEM}, "failed to load client certificate"},
+	} {
+		if _, err := NewTransport(tc.opts); err == nil || !strings.Contains(err.Error(), tc.want) {
+			t.Errorf("%+v: %v, want %q", tc.opts, err, tc.want)
+		}
+	}
+
+	transport, err := NewTransport(TransportOptions{CAFile: ca.file, ServerName: "mesh.internal"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if config := transport.TLSClientConfig; config.MinVersion != tls.VersionTLS12 || config.ServerName != "mesh.internal" || config.RootCAs == nil {
+		t.Errorf("TLS config %+v", config)
+	}
+}
@@ -0,0 +1,152 @@
+package meshclient
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"time"
+)
+
+// Defaults of TransportOptions; the tools poll a single host, so few idle connections are needed
+const (
+	DefaultMaxIdleConnsPerHost = 4
+	DefaultIdleConnTimeout     = 90 * time.Second
+)
+
+// TransportOptions tunes the HTTP transport shared by every request of a client
+type TransportOptions struct {
+	// MaxIdleConnsPerHost is the number of kept-alive connections to the API (0 uses DefaultMaxIdleConnsPerHost)
+	MaxIdleConnsPerHost int
+	// IdleConnTimeout closes kept-alive connections unused for this long (0 uses DefaultIdleConnTimeout)
+	IdleConnTimeout time.Duration
+	// HTTP2 attempts HTTP/2 on TLS connections
+	HTTP2 bool
+	// DisableCompression asks for identity encoding instead of gzip, for debugging with traffic dumps
+	DisableCompression bool
+	// Timeout bounds every request, body included (0 uses DefaultTimeout)
+	Timeout time.Duration
+
+	// CAFile is a PEM bundle of the CAs trusted for the API's certificate, instead of the system ones
+	CAFile string
+	// CertFile and KeyFile are a PEM client certificate and key, for APIs behind mutual TLS
+	CertFile string
+	KeyFile  string
+	// ServerName overrides the name sent in SNI and checked against the API's certificate
+	ServerName string
+	// InsecureSkipVerify accepts any certificate; only for throwaway dev setups
+	InsecureSkipVerify bool
+
+	// Proxy routes every request through this proxy (http, https, socks5 or
+	// socks5h URL, credentials as user:password@), instead of the one set in
+	// the HTTP_PROXY, HTTPS_PROXY and NO_PROXY environment variables
+	Proxy string
+}
+
+// proxyFunc returns the transport's Proxy function for opts
+func (opts TransportOptions) proxyFunc() (func(*http.Request) (*url.URL, error), error) {
+	if opts.Proxy == "" {
+		return http.ProxyFromEnvironment, nil
+	}
+	proxyURL, err := url.Parse(opts.Proxy)
+	if err != nil {
+		return nil, fmt.Errorf("invalid proxy URL: %v", err)
+	}
+	switch proxyURL.Scheme {
+	case "http", "https", "socks5", "socks5h":
+	default:
+		return nil, fmt.Errorf("unsupported proxy scheme %q (use http, https, socks5 or socks5h)", proxyURL.Scheme)
+	}
+	if proxyURL.Host == "" {
+		return nil, fmt.Errorf("proxy URL %q has no host", opts.Proxy)
+	}
+	return http.ProxyURL(proxyURL), nil
+}
+
+// tlsConfig returns the TLS configuration described by opts, nil if they set none
+func (opts TransportOptions) tlsConfig() (*tls.Config, error) {
+	if opts.CAFile == "" && opts.CertFile == "" && opts.KeyFile == "" && opts.ServerName == "" && !opts.InsecureSkipVerify {
+		return nil, nil
+	}
+	config := &tls.Config{
+		MinVersion:         tls.VersionTLS12,
+		ServerName:         opts.ServerName,
+		InsecureSkipVerify: opts.InsecureSkipVerify,
+	}
+
+	if opts.CAFile != "" {
+		pem, err := os.ReadFile(opts.CAFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read CA bundle: %v", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no PEM certificate found in CA bundle %s", opts.CAFile)
+		}
+		config.RootCAs = pool
+	}
+
+	if (opts.CertFile == "") != (opts.KeyFile == "") {
+		return nil, fmt.Errorf("a client certificate needs both a certificate and a key file")
+	}
+	if opts.CertFile != "" {
+		cert, err := tls.LoadX509KeyPair(opts.CertFile, opts.KeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load client certificate: %v", err)
+		}
+		config.Certificates = []tls.Certificate{cert}
+	}
+	return config, nil
+}
+
+/*
+ * NewTransport builds the transport described by opts
+ *
+ * It starts from a clone of http.DefaultTransport, so dial and TLS
+ * handshake timeouts stay the standard ones. SOCKS5 proxies are handled
+ * by net/http itself, no extra dependency is needed. Returns an error if a
+ * TLS file cannot be loaded or the proxy URL is invalid.
+ */
+func NewTransport(opts TransportOptions) (*http.Transport, error) {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.MaxIdleConnsPerHost = DefaultMaxIdleConnsPerHost
+	if opts.MaxIdleConnsPerHost > 0 {
+		transport.MaxIdleConnsPerHost = opts.MaxIdleConnsPerHost
+	}
+	transport.IdleConnTimeout = DefaultIdleConnTimeout
+	if opts.IdleConnTimeout > 0 {
+		transport.IdleConnTimeout = opts.IdleConnTimeout
+	}
+	transport.ForceAttemptHTTP2 = opts.HTTP2
+	transport.DisableCompression = opts.DisableCompression
+
+	proxy, err := opts.proxyFunc()
+	if err != nil {
+		return nil, err
+	}
+	transport.Proxy = proxy
+
+	tlsConfig, err := opts.tlsConfig()
+	if err != nil {
+		return nil, err
+	}
+	if tlsConfig != nil {
+		transport.TLSClientConfig = tlsConfig
+	}
+	return transport, nil
+}
+
+// NewHTTPClient returns an *http.Client for NewMeshAPIClient using a transport built from opts
+func NewHTTPClient(opts TransportOptions) (*http.Client, error) {
+	transport, err := NewTransport(opts)
+	if err != nil {
+		return nil, err
+	}
+	timeout := DefaultTimeout
+	if opts.Timeout > 0 {
+		timeout = opts.Timeout
+	}
+	return &http.Client{Transport: transport, Timeout: timeout}, nil
+}
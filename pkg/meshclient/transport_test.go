@@ -0,0 +1,101 @@
+package meshclient
+
+import (
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// countingServer serves handler and counts the TCP connections it accepts
+func countingServer(t *testing.T, handler http.HandlerFunc) (*httptest.Server, *atomic.Int32) {
+	t.Helper()
+	var conns atomic.Int32
+	server := httptest.NewUnstartedServer(handler)
+	server.Config.ConnState = func(conn net.Conn, state http.ConnState) {
+		if state == http.StateNew {
+			conns.Add(1)
+		}
+	}
+	server.Start()
+	t.Cleanup(server.Close)
+	return server, &conns
+}
+
+/*
+ * TestConnectionReuse polls 100 times, every tenth answer an error longer
+ * than maxErrorBody and the
+ * others followed by bytes the decoder leaves unread: the bodies are drained
+ * so a single connection serves every poll
+ */
+func TestConnectionReuse(t *testing.T) {
+	var polls atomic.Int32
+	server, conns := countingServer(t, func(w http.ResponseWriter, r *http.Request) {
+		if polls.Add(1)%10 == 0 {
+			w.WriteHeader(http.StatusBadGateway)
+			io.WriteString(w, strings.Repeat("upstream down ", 10000))
+			return
+		}
+		io.WriteString(w, statusAnswer+"\n"+strings.Repeat(" ", 256<<10))
+	})
+	client := NewMeshAPIClient(server.URL, nil)
+	for i := 1; i <= 100; i++ {
+		_, err := client.NetworkStatus(context.Background())
+		if (err != nil) != (i%10 == 0) {
+			t.Fatalf("poll %d: %v", i, err)
+		}
+	}
+	if n := conns.Load(); n != 1 {
+		t.Errorf("100 polls opened %d connections", n)
+	}
+}
+
+func TestNewTransport(t *testing.T) {
+	transport, err := NewTransport(TransportOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if transport.MaxIdleConnsPerHost != DefaultMaxIdleConnsPerHost || transport.IdleConnTimeout != DefaultIdleConnTimeout ||
+		transport.ForceAttemptHTTP2 || transport.DisableCompression {
+		t.Errorf("defaults: %+v", transport)
+	}
+
+	transport, err = NewTransport(TransportOptions{MaxIdleConnsPerHost: 9, IdleConnTimeout: time.Minute, HTTP2: true, DisableCompression: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if transport.MaxIdleConnsPerHost != 9 || transport.IdleConnTimeout != time.Minute || !transport.ForceAttemptHTTP2 || !transport.DisableCompression {
+		t.Errorf("options not applied: %+v", transport)
+	}
+
+	for timeout, want := range map[time.Duration]time.Duration{0: DefaultTimeout, 5 * time.Second: 5 * time.Second} {
+		client, err := NewHTTPClient(TransportOptions{Timeout: timeout})
+		if err != nil || client.Timeout != want {
+			t.Errorf("Timeout %v: client timeout %v, %v", timeout, client.Timeout, err)
+		}
+	}
+}
+
+// TestSharedTransport checks two clients built on one *http.Client share its connections
+func TestSharedTransport(t *testing.T) {
+	server, conns := countingServer(t, func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, statusAnswer)
+	})
+	httpClient, err := NewHTTPClient(TransportOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 20; i++ {
+		if _, err := NewMeshAPIClient(server.URL, httpClient).NetworkStatus(context.Background()); err != nil {
+			t.Fatalf("client %d: %v", i, err)
+		}
+	}
+	if n := conns.Load(); n != 1 {
+		t.Errorf("20 clients opened %d connections", n)
+	}
+}
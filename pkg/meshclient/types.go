@@ -0,0 +1,202 @@
+package meshclient
+
+import (
+	"encoding/hex"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+type NetworkIdentifier struct {
+	Blockchain string `json:"blockchain"`
+	Network    string `json:"network"`
+}
+
+type BlockIdentifier struct {
+	Index uint64 `json:"index"`
+	Hash  string `json:"hash"`
+}
+
+// Same reports whether two identifiers name the same block, ignoring 0x prefixes
+func (b BlockIdentifier) Same(other BlockIdentifier) bool {
+	return b.Index == other.Index && sameHash(b.Hash, other.Hash)
+}
+
+type TransactionIdentifier struct {
+	Hash string `json:"hash"`
+}
+
+type AccountIdentifier struct {
+	Address string `json:"address"`
+}
+
+type Currency struct {
+	Symbol   string `json:"symbol"`
+	Decimals int    `json:"decimals"`
+}
+
+type Amount struct {
+	Value    string   `json:"value"`
+	Currency Currency `json:"currency"`
+}
+
+type OperationIdentifier struct {
+	Index int64 `json:"index"`
+}
+
+// Operation is one balance change of a transaction (source, destination, change or fee)
+type Operation struct {
+	OperationIdentifier OperationIdentifier    `json:"operation_identifier"`
+	Type                string                 `json:"type"`
+	Status              string                 `json:"status,omitempty"`
+	Account             *AccountIdentifier     `json:"account,omitempty"`
+	Amount              *Amount                `json:"amount,omitempty"`
+	Metadata            map[string]interface{} `json:"metadata,omitempty"`
+}
+
+type Transaction struct {
+	TransactionIdentifier TransactionIdentifier  `json:"transaction_identifier"`
+	Operations            []Operation            `json:"operations,omitempty"`
+	Metadata              map[string]interface{} `json:"metadata,omitempty"`
+}
+
+// Touches reports whether an operation of the transaction is on the account of a 20 bytes tag, i.e. an address starting with it
+func (t Transaction) Touches(tag []byte) bool {
+	prefix := hex.EncodeToString(tag)
+	for _, op := range t.Operations {
+		if op.Account != nil && strings.HasPrefix(strings.ToLower(trimHash(op.Account.Address)), prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// NetworkStatus is the response of /network/status
+type NetworkStatus struct {
+	CurrentBlockIdentifier BlockIdentifier `json:"current_block_identifier"`
+	CurrentBlockTimestamp  int64           `json:"current_block_timestamp"`
+	GenesisBlockIdentifier BlockIdentifier `json:"genesis_block_identifier"`
+}
+
+// AccountBalance is the response of /account/balance
+type AccountBalance struct {
+	BlockIdentifier BlockIdentifier `json:"block_identifier"`
+	Balances        []Amount        `json:"balances"`
+	// Found is false for an account the node does not know: no balance listed, or a 404 answer
+	Found bool `json:"-"`
+}
+
+// Value returns the first balance in nanoMCM, 0 if the account has none (see Found); AccountBalance checked it is MCM
+func (b *AccountBalance) Value() (uint64, error) {
+	if len(b.Balances) == 0 {
+		return 0, nil
+	}
+	value, err := strconv.ParseUint(b.Balances[0].Value, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid balance value %q: %v", b.Balances[0].Value, err)
+	}
+	return value, nil
+}
+
+// Mempool is the response of /mempool
+type Mempool struct {
+	TransactionIdentifiers []TransactionIdentifier `json:"transaction_identifiers"`
+}
+
+// Contains reports whether the transaction is in the mempool, ignoring 0x prefixes
+func (m *Mempool) Contains(txID string) bool {
+	for _, tx := range m.TransactionIdentifiers {
+		if sameHash(tx.Hash, txID) {
+			return true
+		}
+	}
+	return false
+}
+
+// Block is the response of /block
+type Block struct {
+	Block struct {
+		BlockIdentifier       BlockIdentifier `json:"block_identifier"`
+		ParentBlockIdentifier BlockIdentifier `json:"parent_block_identifier"`
+		Timestamp             int64           `json:"timestamp"`
+		Transactions          []Transaction   `json:"transactions"`
+	} `json:"block"`
+	// OtherTransactions lists the transactions the server left out of the
+	// inline list, to be fetched one by one through /block/transaction
+	OtherTransactions []TransactionIdentifier `json:"other_transactions,omitempty"`
+}
+
+// TransactionHashes returns the hashes of every transaction of the block, inline or among other_transactions
+func (b *Block) TransactionHashes() []string {
+	hashes := make([]string, 0, len(b.Block.Transactions)+len(b.OtherTransactions))
+	for _, tx := range b.Block.Transactions {
+		hashes = append(hashes, tx.TransactionIdentifier.Hash)
+	}
+	for _, tx := range b.OtherTransactions {
+		hashes = append(hashes, tx.Hash)
+	}
+	return hashes
+}
+
+// Contains reports whether the transaction is in the inline list of the block, ignoring 0x prefixes
+func (b *Block) Contains(txID string) bool {
+	for _, tx := range b.Block.Transactions {
+		if sameHash(tx.TransactionIdentifier.Hash, txID) {
+			return true
+		}
+	}
+	return false
+}
+
+// ListsOther reports whether the transaction is among the block's other_transactions, ignoring 0x prefixes
+func (b *Block) ListsOther(txID string) bool {
+	for _, tx := range b.OtherTransactions {
+		if sameHash(tx.Hash, txID) {
+			return true
+		}
+	}
+	return false
+}
+
+// MempoolTransaction is the response of /mempool/transaction
+type MempoolTransaction struct {
+	Transaction Transaction            `json:"transaction"`
+	Metadata    map[string]interface{} `json:"metadata,omitempty"`
+}
+
+// BlockTransaction is the response of /block/transaction
+type BlockTransaction struct {
+	Transaction Transaction `json:"transaction"`
+}
+
+// SubmitResult is the response of /construction/submit
+type SubmitResult struct {
+	TransactionIdentifier TransactionIdentifier `json:"transaction_identifier"`
+}
+
+// trimHash drops the 0x or 0X prefix of a hex hash
+func trimHash(h string) string {
+	if len(h) >= 2 && h[0] == '0' && (h[1] == 'x' || h[1] == 'X') {
+		return h[2:]
+	}
+	return h
+}
+
+// sameHash compares two whole hex hashes case-insensitively, with or without the 0x prefix
+func sameHash(a string, b string) bool {
+	return strings.EqualFold(trimHash(a), trimHash(b))
+}
+
+// validHash reports whether h is a non-empty hex string, with or without the 0x prefix
+func validHash(h string) bool {
+	h = trimHash(h)
+	if h == "" || len(h)%2 != 0 {
+		return false
+	}
+	for _, c := range h {
+		if !('0' <= c && c <= '9' || 'a' <= c && c <= 'f' || 'A' <= c && c <= 'F') {
+			return false
+		}
+	}
+	return true
+}
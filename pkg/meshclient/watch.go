@@ -0,0 +1,155 @@
+package meshclient
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// MaxWatchBackoff caps the delay between two polls of WatchBlocks while the API keeps failing
+const MaxWatchBackoff = time.Minute
+
+// BlockEvent is sent by WatchBlocks for every new block, or for a failed poll
+type BlockEvent struct {
+	Height     uint64
+	Hash       string
+	ParentHash string
+	// Reorg is set when the parent of this block is not the previously seen tip
+	Reorg bool
+	// Err is set, and the other fields are not, when polling the API failed; watching goes on
+	Err error
+}
+
+/*
+ * WatchBlocks polls the network status and sends an event for every new block
+ *
+ * Parameters:
+ * - ctx: stops the watch and closes the channel when done
+ * - pollInterval: time between two polls of /network/status
+ *
+ * The current tip is read once before returning, an error then means the API
+ * cannot be watched at all. Afterwards every height after the last seen one
+ * is fetched in order, so blocks mined between two polls are backfilled with
+ * one event each. A tip that changes hash at the same height, or goes back,
+ * is reported as a Reorg event for the new tip. A block that cannot be
+ * fetched is reported with Err and fetched again on the next poll.
+ *
+ * While polls keep failing, e.g. during a node restart, the delay doubles
+ * after each failure up to MaxWatchBackoff (or pollInterval if longer), and
+ * is back to pollInterval after the first successful poll. The heights
+ * mined during the outage are then backfilled like any others. A throttled
+ * poll (see Throttled) is not a failure: the next one waits for its
+ * Retry-After, or pollInterval if longer, and the backoff does not grow.
+ */
+func (c *MeshAPIClient) WatchBlocks(ctx context.Context, pollInterval time.Duration) (<-chan BlockEvent, error) {
+	status, err := c.NetworkStatus(ctx)
+	if err != nil {
+		return nil, err
+	}
+	last := status.CurrentBlockIdentifier
+
+	events := make(chan BlockEvent)
+	go func() {
+		defer close(events)
+		backoff := RetryPolicy{BaseBackoff: pollInterval, MaxBackoff: max(pollInterval, MaxWatchBackoff)}
+		failures := 0
+		// throttle is the wait asked by the server that throttled the last poll
+		var throttle time.Duration
+		timer := time.NewTimer(pollInterval)
+		defer timer.Stop()
+
+		send := func(event BlockEvent) bool {
+			if wait, throttled := Throttled(event.Err); throttled {
+				// The server's wait, at most MaxRetryAfter, and no shorter than a poll
+				throttle = max(min(wait, MaxRetryAfter), pollInterval)
+				failures--
+			}
+			select {
+			case events <- event:
+				return true
+			case <-ctx.Done():
+				return false
+			}
+		}
+
+		for {
+			// failures is the count of polls failed in a row, reset by a complete one
+			delay := backoff.Backoff(failures + 1)
+			if throttle > 0 {
+				delay, throttle = throttle, 0
+			}
+			timer.Reset(delay)
+			select {
+			case <-ctx.Done():
+				return
+			case <-timer.C:
+			}
+			failures++
+
+			status, err := c.NetworkStatus(ctx)
+			if err != nil {
+				if ctx.Err() != nil || !send(BlockEvent{Err: err}) {
+					return
+				}
+				continue
+			}
+			tip := status.CurrentBlockIdentifier
+
+			// The tip was replaced without growing: report the new tip only
+			if tip.Index < last.Index || (tip.Index == last.Index && !sameHash(tip.Hash, last.Hash)) {
+				c.InvalidateStatus()
+				block, err := c.Block(ctx, tip.Index)
+				if err != nil {
+					if ctx.Err() != nil || !send(BlockEvent{Err: fmt.Errorf("failed to fetch block %d: %w", tip.Index, err)}) {
+						return
+					}
+					continue
+				}
+				event := blockEvent(block)
+				event.Reorg = true
+				if !send(event) {
+					return
+				}
+				last = block.Block.BlockIdentifier
+				failures = 0
+				continue
+			}
+			if tip.Index == last.Index {
+				failures = 0
+				continue
+			}
+
+			c.InvalidateStatus()
+			complete := true
+			for height := last.Index + 1; height <= tip.Index; height++ {
+				block, err := c.Block(ctx, height)
+				if err != nil {
+					if ctx.Err() != nil || !send(BlockEvent{Err: fmt.Errorf("failed to fetch block %d: %w", height, err)}) {
+						return
+					}
+					complete = false
+					break
+				}
+				event := blockEvent(block)
+				event.Reorg = last.Hash != "" && !sameHash(event.ParentHash, last.Hash)
+				if !send(event) {
+					return
+				}
+				last = block.Block.BlockIdentifier
+			}
+			if complete {
+				failures = 0
+			}
+		}
+	}()
+	return events, nil
+}
+
+// blockEvent returns the event of a block, Reorg unset
+func blockEvent(block *Block) BlockEvent {
+	return BlockEvent{
+		Height:     block.Block.BlockIdentifier.Index,
+		Hash:       block.Block.BlockIdentifier.Hash,
+		ParentHash: block.Block.ParentBlockIdentifier.Hash,
+	}
+}
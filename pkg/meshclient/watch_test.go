@@ -0,0 +1,297 @@
+package meshclient_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/NickP005/Vindax-MCM-tools/pkg/meshclient"
+)
+
+// watchInterval is the poll interval of the watch tests
+const watchInterval = 5 * time.Millisecond
+
+/*
+ * chain is a fake Mesh API serving /network/status and /block from a list
+ * of block hashes, the genesis block at height 0
+ */
+type chain struct {
+	mu      sync.Mutex
+	server  *httptest.Server
+	hashes  []string
+	minted  int
+	failing int
+	// throttled requests are answered status with retryAfter, before anything else
+	throttled  int
+	status     int
+	retryAfter string
+}
+
+func newChain(t *testing.T) *chain {
+	t.Helper()
+	c := &chain{}
+	c.hashes = []string{c.mint()}
+	c.server = httptest.NewServer(http.HandlerFunc(c.serve))
+	t.Cleanup(c.server.Close)
+	return c
+}
+
+// mint returns a hash no other block has; c.mu is held
+func (c *chain) mint() string {
+	c.minted++
+	return fmt.Sprintf("0x%064x", c.minted)
+}
+
+func (c *chain) serve(w http.ResponseWriter, r *http.Request) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.throttled > 0 {
+		c.throttled--
+		if c.retryAfter != "" {
+			w.Header().Set("Retry-After", c.retryAfter)
+		}
+		http.Error(w, "slow down", c.status)
+		return
+	}
+	if c.failing != 0 {
+		http.Error(w, "bad gateway", c.failing)
+		return
+	}
+	id := func(height uint64) meshclient.BlockIdentifier {
+		return meshclient.BlockIdentifier{Index: height, Hash: c.hashes[height]}
+	}
+	tip := uint64(len(c.hashes) - 1)
+	switch r.URL.Path {
+	case "/network/status":
+		json.NewEncoder(w).Encode(meshclient.NetworkStatus{CurrentBlockIdentifier: id(tip), GenesisBlockIdentifier: id(0)})
+	case "/block":
+		var request struct {
+			BlockIdentifier struct {
+				Index uint64 `json:"index"`
+			} `json:"block_identifier"`
+		}
+		json.NewDecoder(r.Body).Decode(&request)
+		height := request.BlockIdentifier.Index
+		if height == 0 || height > tip {
+			http.Error(w, "no such block", http.StatusNotFound)
+			return
+		}
+		var block meshclient.Block
+		block.Block.BlockIdentifier, block.Block.ParentBlockIdentifier = id(height), id(height-1)
+		json.NewEncoder(w).Encode(block)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func (c *chain) client() *meshclient.MeshAPIClient {
+	return meshclient.NewMeshAPIClient(c.server.URL, nil)
+}
+
+// mine adds a block on the tip
+func (c *chain) mine() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.hashes = append(c.hashes, c.mint())
+}
+
+// reorg drops the last depth blocks and mines length new ones in their place, returning the new tip
+func (c *chain) reorg(depth, length int) uint64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.hashes = c.hashes[:len(c.hashes)-depth]
+	for i := 0; i < length; i++ {
+		c.hashes = append(c.hashes, c.mint())
+	}
+	return uint64(len(c.hashes) - 1)
+}
+
+// fail answers every request with status, or serves again when status is 0
+func (c *chain) fail(status int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.failing = status
+}
+
+// throttle answers the next n requests with status and the Retry-After header, none if empty
+func (c *chain) throttle(n int, status int, retryAfter string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.throttled, c.status, c.retryAfter = n, status, retryAfter
+}
+
+// startWatch watches the blocks of the chain until the test ends
+func startWatch(t *testing.T, c *chain) (<-chan meshclient.BlockEvent, *meshclient.MeshAPIClient) {
+	t.Helper()
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	client := c.client()
+	events, err := client.WatchBlocks(ctx, watchInterval)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return events, client
+}
+
+// next returns the next event, failing the test if none comes within a second
+func next(t *testing.T, events <-chan meshclient.BlockEvent) meshclient.BlockEvent {
+	t.Helper()
+	select {
+	case event, ok := <-events:
+		if !ok {
+			t.Fatal("events closed")
+		}
+		return event
+	case <-time.After(time.Second):
+		t.Fatal("no event")
+	}
+	return meshclient.BlockEvent{}
+}
+
+// nextBlock skips the failed polls and returns the next block event
+func nextBlock(t *testing.T, events <-chan meshclient.BlockEvent) meshclient.BlockEvent {
+	t.Helper()
+	for {
+		if event := next(t, events); event.Err == nil {
+			return event
+		}
+	}
+}
+
+// TestWatchBlocksBackfill mines several blocks between two polls: each gets its event, in order
+func TestWatchBlocksBackfill(t *testing.T) {
+	c := newChain(t)
+	c.mine()
+	events, client := startWatch(t, c)
+
+	block, err := client.Block(context.Background(), 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	parent := block.Block.BlockIdentifier.Hash
+	for i := 0; i < 5; i++ {
+		c.mine()
+	}
+	for height := uint64(2); height <= 6; height++ {
+		event := next(t, events)
+		if event.Err != nil || event.Height != height || event.Reorg || event.ParentHash != parent {
+			t.Fatalf("height %d: %+v", height, event)
+		}
+		parent = event.Hash
+	}
+}
+
+// TestWatchBlocksReorg replaces the tip at the same height, by a longer branch and by a shorter one
+func TestWatchBlocksReorg(t *testing.T) {
+	c := newChain(t)
+	for i := 0; i < 3; i++ {
+		c.mine()
+	}
+	events, client := startWatch(t, c)
+	old, err := client.Block(context.Background(), 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The tip is replaced at the same height
+	c.reorg(1, 1)
+	event := next(t, events)
+	if event.Err != nil || event.Height != 3 || !event.Reorg || event.Hash == old.Block.BlockIdentifier.Hash {
+		t.Fatalf("same height reorg: %+v", event)
+	}
+
+	// A longer branch replaces the last two blocks: its new tip does not follow the seen one
+	tip := c.reorg(2, 3)
+	event = next(t, events)
+	if event.Err != nil || event.Height != tip || tip != 4 || !event.Reorg {
+		t.Fatalf("longer branch: %+v", event)
+	}
+	c.mine()
+	if event = next(t, events); event.Height != 5 || event.Reorg {
+		t.Errorf("after the branch: %+v", event)
+	}
+
+	// A shorter branch takes the tip back
+	tip = c.reorg(3, 1)
+	if event = next(t, events); event.Height != tip || tip != 3 || !event.Reorg {
+		t.Errorf("shorter branch: %+v", event)
+	}
+}
+
+// TestWatchBlocksOutage fails every poll for a while, then backfills the blocks mined meanwhile
+func TestWatchBlocksOutage(t *testing.T) {
+	c := newChain(t)
+	events, _ := startWatch(t, c)
+	c.fail(http.StatusBadGateway)
+	c.mine()
+	c.mine()
+	if event := next(t, events); event.Err == nil {
+		t.Fatalf("no error during the outage: %+v", event)
+	}
+	time.AfterFunc(50*time.Millisecond, func() { c.fail(0) })
+	for height := uint64(1); height <= 2; height++ {
+		if event := nextBlock(t, events); event.Height != height || event.Reorg {
+			t.Errorf("height %d: %+v", height, event)
+		}
+	}
+}
+
+// TestWatchBlocksThrottled rate limits polls: the next one waits for the Retry-After, or the interval without one
+func TestWatchBlocksThrottled(t *testing.T) {
+	c := newChain(t)
+	events, _ := startWatch(t, c)
+	c.throttle(1, http.StatusTooManyRequests, "1")
+	c.mine()
+
+	event := next(t, events)
+	if wait, ok := meshclient.Throttled(event.Err); !ok || wait != time.Second {
+		t.Fatalf("throttled poll: %+v", event)
+	}
+	start := time.Now()
+	select {
+	case event = <-events:
+	case <-time.After(3 * time.Second):
+		t.Fatal("no event after the Retry-After")
+	}
+	if elapsed := time.Since(start); event.Height != 1 || elapsed < 900*time.Millisecond {
+		t.Errorf("%+v after %v, want block 1 after 1s", event, elapsed)
+	}
+
+	// Throttled polls without Retry-After keep the normal interval
+	c.throttle(3, http.StatusServiceUnavailable, "")
+	c.mine()
+	start = time.Now()
+	if event := nextBlock(t, events); event.Height != 2 || time.Since(start) > 500*time.Millisecond {
+		t.Errorf("%+v after %v", event, time.Since(start))
+	}
+}
+
+func TestWatchBlocksStops(t *testing.T) {
+	c := newChain(t)
+	client := c.client()
+	ctx, cancel := context.WithCancel(context.Background())
+	events, err := client.WatchBlocks(ctx, watchInterval)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cancel()
+	select {
+	case _, ok := <-events:
+		if ok {
+			t.Error("event after cancel")
+		}
+	case <-time.After(time.Second):
+		t.Error("events not closed after cancel")
+	}
+
+	// An API that cannot be read at the start is an error
+	c.fail(http.StatusInternalServerError)
+	if _, err := client.WatchBlocks(context.Background(), watchInterval); err == nil {
+		t.Error("no error for a failing API")
+	}
+}
@@ -0,0 +1,59 @@
+package meshmock_test
+
+import (
+	"context"
+	"encoding/hex"
+	"fmt"
+
+	"github.com/NickP005/Vindax-MCM-tools/pkg/meshclient"
+	"github.com/NickP005/Vindax-MCM-tools/pkg/meshmock"
+	"github.com/NickP005/Vindax-MCM-tools/pkg/txbuild"
+	"github.com/NickP005/Vindax-MCM-tools/pkg/txentry"
+	"github.com/NickP005/Vindax-MCM-tools/pkg/wotsp"
+)
+
+// Example submits a signed transfer, mines it and checks the payment landed
+func Example() {
+	ctx := context.Background()
+	mock := meshmock.New()
+	defer mock.Close()
+	client := meshclient.NewMeshAPIClient(mock.URL(), nil)
+
+	// The payer's tag is the address hash of its first key; the change goes to its next key
+	key, next := wotsp.Keygen([32]byte{1}), wotsp.Keygen([32]byte{2})
+	tag := key.AddrHash()
+	source, change := txbuild.Address(tag, key.PublicKey), txbuild.Address(tag, next.PublicKey)
+	mock.SetAccount(tag[:], "0x"+hex.EncodeToString(source[:]), 10000)
+
+	payee := [txentry.TagLength]byte{0x42}
+	payment, _ := txbuild.NewDestination(payee, "INV-1", 2500)
+	tx, _ := txbuild.NewTransfer(source, change, 10000, 500, []txentry.Destination{payment})
+	if err := txbuild.Sign(tx, &key); err != nil {
+		fmt.Println(err)
+		return
+	}
+
+	result, err := client.SubmitTransaction(ctx, hex.EncodeToString(tx.Bytes()))
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+	txID := result.TransactionIdentifier.Hash
+	mempool, _ := client.Mempool(ctx)
+	fmt.Println("pending:", mempool.Contains(txID))
+
+	height := mock.MineBlock()
+	found, _ := client.BlockHasTransaction(ctx, height, txID)
+	fmt.Printf("in block %d: %v\n", height, found)
+	payerBalance, _ := client.AccountBalance(ctx, tag[:])
+	payeeBalance, _ := client.AccountBalance(ctx, payee[:])
+	left, _ := payerBalance.Value()
+	received, _ := payeeBalance.Value()
+	fmt.Println("payer:", left)
+	fmt.Println("payee:", received)
+	// Output:
+	// pending: true
+	// in block 1: true
+	// payer: 7000
+	// payee: 2500
+}
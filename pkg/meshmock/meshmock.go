@@ -0,0 +1,761 @@
+/*
+ * Package meshmock is an in-memory Mochimo Mesh API, served by an
+ * httptest.Server, to exercise the tools without a live node.
+ *
+ * The chain is scripted by the caller: transactions are injected into the
+ * mempool or submitted through /construction/submit, MineBlock moves the
+ * mempool into a new block, Reorg replaces the last blocks and ReorgTo
+ * replaces them with a scripted branch. Submitted transactions that decode
+ * as a txentry are listed with their operations, rejected when their
+ * signature does not verify, when their source is not the address the tag
+ * belongs to or when their fee is under SetMinimumFee, and applied to the
+ * balances once mined, until a reorg takes their block away; while
+ * /construction/derive answers the address hash of wotsp.AddrHashFromPK and
+ * /construction/metadata suggests the fee set by SetSuggestedFee and
+ * /search/transactions pages through the transactions of the chain.
+ * SetMempoolLimit and SetBlockLimit truncate the listings as large nodes do.
+ * Latency, error answers and malformed answers can be injected per endpoint,
+ * Outage fails them all for a while, and HoldNext keeps a submitted
+ * transaction out of the next blocks, as a stuck mempool does.
+ *
+ *	mock := meshmock.New()
+ *	defer mock.Close()
+ *	client := meshclient.NewMeshAPIClient(mock.URL(), nil)
+ *	result, _ := client.SubmitTransaction(ctx, signedTxHex)
+ *	mock.MineBlock()
+ *	found, _ := client.BlockHasTransaction(ctx, mock.Height(), result.TransactionIdentifier.Hash)
+ */
+package meshmock
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/NickP005/Vindax-MCM-tools/pkg/meshclient"
+	"github.com/NickP005/Vindax-MCM-tools/pkg/txentry"
+	"github.com/NickP005/Vindax-MCM-tools/pkg/wotsp"
+)
+
+// RosettaVersion is the version the mock reports in /network/options
+const RosettaVersion = meshclient.RosettaVersion
+
+// SearchPageLimit caps the transactions of a /search/transactions page, as servers do
+const SearchPageLimit = 25
+
+// DefaultSuggestedFee is the fee in nanoMCM /construction/metadata suggests until SetSuggestedFee
+const DefaultSuggestedFee = 500
+
+// Fault is an answer injected in place of the normal one
+type Fault struct {
+	// Status is the HTTP status of the answer (0 means 500)
+	Status int
+	// Body is sent as is, e.g. a Rosetta error or malformed JSON
+	Body string
+	// RetryAfter, if set, is sent as the Retry-After header, e.g. "2" with a 429 Status
+	RetryAfter string
+}
+
+// account is what the mock knows of a tag
+type account struct {
+	address string
+	balance uint64
+}
+
+// block is a mined block
+type block struct {
+	hash         string
+	time         time.Time
+	transactions []meshclient.Transaction
+	// undo holds the accounts its transfers changed, as they were before, in the order they changed
+	undo []change
+}
+
+// change is the state of an account before a mined transfer changed it, nil when the tag was unknown
+type change struct {
+	key  string
+	prev *account
+}
+
+// hold keeps a submitted transaction in the mempool while blocks are mined, see HoldNext
+type hold struct {
+	blocks int
+	evict  bool
+}
+
+// Server is the mock API; all its methods are safe for concurrent use
+type Server struct {
+	server *httptest.Server
+
+	mu        sync.Mutex
+	blocks    []block
+	mempool   []meshclient.Transaction
+	accounts  map[string]*account
+	submitted []string
+	// transfers are the submitted transactions that decoded, by hashKey of their hash, applied when mined
+	transfers map[string]*txentry.Transaction
+	latency   time.Duration
+	faults    map[string][]Fault
+	reorgs    int
+	fee       uint64
+	// blockLimit caps the transactions listed inline by /block, 0 for no cap
+	blockLimit int
+	// mempoolLimit caps the transactions listed by /mempool, 0 for no cap
+	mempoolLimit int
+	// minFee is the lowest fee /construction/submit accepts from a decoded transfer, 0 for any
+	minFee uint64
+	// outage, until outageEnd, is answered by every endpoint
+	outage    Fault
+	outageEnd time.Time
+	// nextHold is applied to the next transaction entering the mempool through /construction/submit
+	nextHold *hold
+	// held are the transactions kept out of the next blocks, by hashKey of their hash
+	held map[string]*hold
+
+	callMethods map[string]bool
+	listMethods bool
+}
+
+// New starts a mock whose chain holds only the genesis block
+func New() *Server {
+	s := &Server{
+		accounts:  make(map[string]*account),
+		transfers: make(map[string]*txentry.Transaction),
+		faults:    make(map[string][]Fault),
+		held:      make(map[string]*hold),
+		fee:       DefaultSuggestedFee,
+
+		callMethods: map[string]bool{meshclient.MethodTagResolve: true},
+	}
+	s.blocks = []block{{hash: s.blockHash(0, ""), time: time.Now()}}
+	s.server = httptest.NewServer(http.HandlerFunc(s.handle))
+	return s
+}
+
+// URL returns the endpoint to give to meshclient.NewMeshAPIClient
+func (s *Server) URL() string {
+	return s.server.URL
+}
+
+// Close stops the server
+func (s *Server) Close() {
+	s.server.Close()
+}
+
+// blockHash derives a block hash from its height, its parent and the reorg count
+func (s *Server) blockHash(height uint64, parent string) string {
+	var buf [16]byte
+	binary.BigEndian.PutUint64(buf[:8], height)
+	binary.BigEndian.PutUint64(buf[8:], uint64(s.reorgs))
+	sum := sha256.Sum256(append(buf[:], parent...))
+	return "0x" + hex.EncodeToString(sum[:])
+}
+
+// SetLatency delays every answer by d
+func (s *Server) SetLatency(d time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.latency = d
+}
+
+// Fail queues faults answered, one per request, by the endpoint at path (e.g. "/block")
+func (s *Server) Fail(path string, faults ...Fault) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.faults[path] = append(s.faults[path], faults...)
+}
+
+/*
+ * Outage answers fault on every endpoint for d, as a node restarting or
+ * unreachable behind its proxy does
+ *
+ * It takes precedence over the faults queued by Fail, which are kept for
+ * after the outage. A new Outage replaces the current one.
+ */
+func (s *Server) Outage(fault Fault, d time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.outage, s.outageEnd = fault, time.Now().Add(d)
+}
+
+// SetAccount sets the full address (hex) and the balance of a 20 bytes tag
+func (s *Server) SetAccount(tag []byte, address string, balance uint64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.accounts[hex.EncodeToString(tag)] = &account{address: address, balance: balance}
+}
+
+// SetSuggestedFee sets the fee in nanoMCM suggested by /construction/metadata
+func (s *Server) SetSuggestedFee(fee uint64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.fee = fee
+}
+
+// SetMinimumFee makes /construction/submit reject decoded transfers paying less than fee nanoMCM (0 for none),
+// with the minimum in the details as nodes do; /construction/metadata keeps suggesting the fee of SetSuggestedFee
+func (s *Server) SetMinimumFee(fee uint64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.minFee = fee
+}
+
+/*
+ * SetCallMethods sets the /call methods the mock offers (tag_resolve only
+ * by default), to simulate servers with other method sets
+ *
+ * With listed, /network/options advertises them in call_methods; otherwise
+ * clients only learn them by calling. Methods other than tag_resolve answer
+ * an empty result.
+ */
+func (s *Server) SetCallMethods(listed bool, methods ...string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.listMethods = listed
+	s.callMethods = make(map[string]bool, len(methods))
+	for _, method := range methods {
+		s.callMethods[method] = true
+	}
+}
+
+// AddToMempool injects a transaction into the mempool
+func (s *Server) AddToMempool(tx meshclient.Transaction) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.mempool = append(s.mempool, tx)
+}
+
+// SetBlockLimit makes /block list at most n transactions inline (0 for all), the others only as
+// other_transactions, like servers truncating a large block; /block/transaction still finds them
+func (s *Server) SetBlockLimit(n int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.blockLimit = n
+}
+
+// DropFromMempool removes a transaction from the mempool without mining it, as a node evicting it does
+func (s *Server) DropFromMempool(txID string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for i, tx := range s.mempool {
+		if sameHash(tx.TransactionIdentifier.Hash, txID) {
+			s.mempool = append(s.mempool[:i], s.mempool[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+/*
+ * HoldNext keeps the next transaction submitted through
+ * /construction/submit out of the next blocks blocks mined, as a mempool
+ * where it is stuck does
+ *
+ * It is mined by the block after those, or dropped from the mempool
+ * instead when evict is set, as a node evicting it does. Submitting it
+ * again once evicted is not held.
+ */
+func (s *Server) HoldNext(blocks int, evict bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.nextHold = &hold{blocks: blocks, evict: evict}
+}
+
+// SetMempoolLimit makes /mempool list at most n transactions (0 for all), like servers truncating a large mempool;
+// /mempool/transaction still finds the others
+func (s *Server) SetMempoolLimit(n int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.mempoolLimit = n
+}
+
+// Submitted returns the signed transactions received by /construction/submit, in order
+func (s *Server) Submitted() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]string(nil), s.submitted...)
+}
+
+// Height returns the index of the tip
+func (s *Server) Height() uint64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return uint64(len(s.blocks) - 1)
+}
+
+// Balance returns the balance of a 20 bytes tag, and whether the mock knows the tag
+func (s *Server) Balance(tag []byte) (uint64, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	acct := s.accounts[hex.EncodeToString(tag)]
+	if acct == nil {
+		return 0, false
+	}
+	return acct.balance, true
+}
+
+/*
+ * MineBlock moves the whole mempool into a new block and returns its height
+ *
+ * The submitted transactions of the block are applied to the balances: the
+ * source tag moves to the change address with the change total, and each
+ * destination tag is credited, created with its implicit address when
+ * unknown. Reorgs undo them when they replace the block. Transactions held
+ * by HoldNext stay in the mempool, or leave it once their hold is over if
+ * it evicts them.
+ */
+func (s *Server) MineBlock() uint64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	height := uint64(len(s.blocks))
+	mined := block{hash: s.blockHash(height, s.blocks[height-1].hash), time: time.Now()}
+	var pending []meshclient.Transaction
+	for _, tx := range s.mempool {
+		key := hashKey(tx.TransactionIdentifier.Hash)
+		if h := s.held[key]; h != nil {
+			if h.blocks--; h.blocks <= 0 {
+				delete(s.held, key)
+				if h.evict {
+					continue
+				}
+			}
+			pending = append(pending, tx)
+			continue
+		}
+		mined.transactions = append(mined.transactions, tx)
+		if transfer := s.transfers[key]; transfer != nil {
+			mined.undo = s.apply(transfer, mined.undo)
+		}
+	}
+	s.blocks = append(s.blocks, mined)
+	s.mempool = pending
+	return height
+}
+
+// apply moves the funds of a mined transfer, appending the accounts it changes to undo
+func (s *Server) apply(tx *txentry.Transaction, undo []change) []change {
+	save := func(key string) {
+		var prev *account
+		if acct := s.accounts[key]; acct != nil {
+			copied := *acct
+			prev = &copied
+		}
+		undo = append(undo, change{key: key, prev: prev})
+	}
+	source := hex.EncodeToString(tx.Source[:txentry.TagLength])
+	save(source)
+	delete(s.accounts, source)
+	changeKey := hex.EncodeToString(tx.Change[:txentry.TagLength])
+	save(changeKey)
+	s.accounts[changeKey] = &account{address: "0x" + hex.EncodeToString(tx.Change[:]), balance: tx.ChangeTotal}
+	for _, destination := range tx.Destinations {
+		key := hex.EncodeToString(destination.Tag[:])
+		save(key)
+		acct := s.accounts[key]
+		if acct == nil {
+			acct = &account{address: "0x" + key + key}
+			s.accounts[key] = acct
+		}
+		acct.balance += destination.Amount
+	}
+	return undo
+}
+
+// revert undoes the transfers applied by the blocks from height start to the tip, the last mined first
+func (s *Server) revert(start int) {
+	for i := len(s.blocks) - 1; i >= start; i-- {
+		undo := s.blocks[i].undo
+		for j := len(undo) - 1; j >= 0; j-- {
+			if undo[j].prev == nil {
+				delete(s.accounts, undo[j].key)
+			} else {
+				s.accounts[undo[j].key] = undo[j].prev
+			}
+		}
+	}
+}
+
+// operations lists a decoded transfer as the Mesh API does: the source debit, the payments, the change and the fee
+func operations(tx *txentry.Transaction) []meshclient.Operation {
+	var ops []meshclient.Operation
+	source, _ := meshclient.SourceOperation(0, tx.Source[:txentry.TagLength], tx.SendTotal+tx.ChangeTotal+tx.Fee)
+	ops = append(ops, source)
+	for _, destination := range tx.Destinations {
+		op, _ := meshclient.DestinationOperation(int64(len(ops)), destination.Tag[:], destination.Amount, destination.Memo())
+		ops = append(ops, op)
+	}
+	if tx.ChangeTotal > 0 {
+		change, _ := meshclient.DestinationOperation(int64(len(ops)), tx.Change[:txentry.TagLength], tx.ChangeTotal, "")
+		ops = append(ops, change)
+	}
+	return append(ops, meshclient.FeeOperation(int64(len(ops)), tx.Fee))
+}
+
+/*
+ * Reorg replaces the last depth blocks with as many empty ones, with new hashes
+ *
+ * The transactions of the replaced blocks go back to the mempool when
+ * toMempool is set, and are dropped otherwise. Either way the balances
+ * they changed are restored.
+ */
+func (s *Server) Reorg(depth int, toMempool bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if depth > len(s.blocks)-1 {
+		depth = len(s.blocks) - 1
+	}
+	s.reorgs++
+	start := len(s.blocks) - depth
+	s.revert(start)
+	for i := start; i < len(s.blocks); i++ {
+		if toMempool {
+			s.mempool = append(s.mempool, s.blocks[i].transactions...)
+		}
+		s.blocks[i] = block{hash: s.blockHash(uint64(i), s.blocks[i-1].hash), time: time.Now()}
+	}
+}
+
+/*
+ * ReorgTo replaces the last depth blocks with a new branch of one block per
+ * entry of blocks, holding the given transactions, and returns the new tip
+ *
+ * The branch may be shorter or longer than the blocks it replaces, so a
+ * transaction can move to another height or leave the chain. The
+ * transactions of the replaced blocks are dropped and the balances they
+ * changed restored; AddToMempool puts back the ones that should be
+ * pending. The branch does not change the balances.
+ */
+func (s *Server) ReorgTo(depth int, blocks ...[]meshclient.Transaction) uint64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if depth > len(s.blocks)-1 {
+		depth = len(s.blocks) - 1
+	}
+	s.reorgs++
+	s.revert(len(s.blocks) - depth)
+	s.blocks = s.blocks[:len(s.blocks)-depth]
+	for _, transactions := range blocks {
+		height := uint64(len(s.blocks))
+		s.blocks = append(s.blocks, block{
+			hash:         s.blockHash(height, s.blocks[height-1].hash),
+			time:         time.Now(),
+			transactions: transactions,
+		})
+	}
+	return uint64(len(s.blocks) - 1)
+}
+
+// handle answers one request, after the latency and any outage or queued fault
+func (s *Server) handle(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	latency := s.latency
+	var fault *Fault
+	if time.Now().Before(s.outageEnd) {
+		outage := s.outage
+		fault = &outage
+	} else if queue := s.faults[r.URL.Path]; len(queue) > 0 {
+		fault = &queue[0]
+		s.faults[r.URL.Path] = queue[1:]
+	}
+	s.mu.Unlock()
+
+	if latency > 0 {
+		select {
+		case <-time.After(latency):
+		case <-r.Context().Done():
+			return
+		}
+	}
+	if fault != nil {
+		status := fault.Status
+		if status == 0 {
+			status = http.StatusInternalServerError
+		}
+		if fault.RetryAfter != "" {
+			w.Header().Set("Retry-After", fault.RetryAfter)
+		}
+		w.WriteHeader(status)
+		fmt.Fprint(w, fault.Body)
+		return
+	}
+
+	var request struct {
+		AccountIdentifier     meshclient.AccountIdentifier     `json:"account_identifier"`
+		BlockIdentifier       meshclient.BlockIdentifier       `json:"block_identifier"`
+		TransactionIdentifier meshclient.TransactionIdentifier `json:"transaction_identifier"`
+		Method                string                           `json:"method"`
+		Parameters            map[string]string                `json:"parameters"`
+		SignedTransaction     string                           `json:"signed_transaction"`
+		PublicKey             meshclient.PublicKey             `json:"public_key"`
+		Operations            []meshclient.Operation           `json:"operations"`
+		Options               map[string]interface{}           `json:"options"`
+		Limit                 int64                            `json:"limit"`
+		Offset                int64                            `json:"offset"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		rosettaError(w, http.StatusBadRequest, meshclient.CodeInvalidRequest, "invalid request", err.Error())
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	switch r.URL.Path {
+	case "/network/list":
+		answer(w, meshclient.NetworkList{NetworkIdentifiers: []meshclient.NetworkIdentifier{{Blockchain: "mochimo", Network: "mainnet"}}})
+	case "/network/options":
+		var options meshclient.NetworkOptions
+		options.Version.RosettaVersion = RosettaVersion
+		options.Version.NodeVersion = "meshmock"
+		options.Allow.OperationTypes = []string{"TRANSFER"}
+		if s.listMethods {
+			for method := range s.callMethods {
+				options.Allow.CallMethods = append(options.Allow.CallMethods, method)
+			}
+			sort.Strings(options.Allow.CallMethods)
+		}
+		answer(w, options)
+	case "/network/status":
+		tip := len(s.blocks) - 1
+		answer(w, meshclient.NetworkStatus{
+			CurrentBlockIdentifier: meshclient.BlockIdentifier{Index: uint64(tip), Hash: s.blocks[tip].hash},
+			CurrentBlockTimestamp:  time.Now().UnixMilli(),
+			GenesisBlockIdentifier: meshclient.BlockIdentifier{Index: 0, Hash: s.blocks[0].hash},
+		})
+	case "/account/balance":
+		// An account never set is unknown to the node: no balance listed, unlike an account holding 0
+		balances := []meshclient.Amount{}
+		if acct := s.accounts[tagKey(request.AccountIdentifier.Address)]; acct != nil {
+			balances = []meshclient.Amount{{Value: fmt.Sprint(acct.balance), Currency: meshclient.Currency{Symbol: "MCM", Decimals: 9}}}
+		}
+		answer(w, meshclient.AccountBalance{
+			BlockIdentifier: meshclient.BlockIdentifier{Index: uint64(len(s.blocks) - 1), Hash: s.blocks[len(s.blocks)-1].hash},
+			Balances:        balances,
+		})
+	case "/call":
+		if !s.callMethods[request.Method] {
+			rosettaError(w, http.StatusInternalServerError, 2, "unsupported method", request.Method)
+			return
+		}
+		if request.Method != meshclient.MethodTagResolve {
+			answer(w, map[string]interface{}{"result": map[string]interface{}{}})
+			return
+		}
+		var result struct {
+			Result struct {
+				Address string `json:"address"`
+				Amount  uint64 `json:"amount"`
+			} `json:"result"`
+		}
+		if acct := s.accounts[tagKey(request.Parameters["tag"])]; acct != nil {
+			result.Result.Address, result.Result.Amount = acct.address, acct.balance
+		}
+		answer(w, result)
+	case "/mempool":
+		var mempool meshclient.Mempool
+		for i, tx := range s.mempool {
+			if s.mempoolLimit > 0 && i >= s.mempoolLimit {
+				break
+			}
+			mempool.TransactionIdentifiers = append(mempool.TransactionIdentifiers, tx.TransactionIdentifier)
+		}
+		answer(w, mempool)
+	case "/mempool/transaction":
+		for _, tx := range s.mempool {
+			if sameHash(tx.TransactionIdentifier.Hash, request.TransactionIdentifier.Hash) {
+				answer(w, meshclient.MempoolTransaction{Transaction: tx})
+				return
+			}
+		}
+		rosettaError(w, http.StatusNotFound, 3, "transaction not in mempool", "")
+	case "/block":
+		index := request.BlockIdentifier.Index
+		if request.BlockIdentifier.Hash != "" {
+			index = uint64(len(s.blocks))
+			for i, b := range s.blocks {
+				if sameHash(b.hash, request.BlockIdentifier.Hash) {
+					index = uint64(i)
+				}
+			}
+		}
+		if index >= uint64(len(s.blocks)) {
+			rosettaError(w, http.StatusInternalServerError, 4, "block not found", fmt.Sprint(index))
+			return
+		}
+		var b meshclient.Block
+		b.Block.BlockIdentifier = meshclient.BlockIdentifier{Index: index, Hash: s.blocks[index].hash}
+		if index > 0 {
+			b.Block.ParentBlockIdentifier = meshclient.BlockIdentifier{Index: index - 1, Hash: s.blocks[index-1].hash}
+		}
+		b.Block.Timestamp = s.blocks[index].time.UnixMilli()
+		b.Block.Transactions = s.blocks[index].transactions
+		if s.blockLimit > 0 && len(b.Block.Transactions) > s.blockLimit {
+			for _, tx := range b.Block.Transactions[s.blockLimit:] {
+				b.OtherTransactions = append(b.OtherTransactions, tx.TransactionIdentifier)
+			}
+			b.Block.Transactions = b.Block.Transactions[:s.blockLimit]
+		}
+		answer(w, b)
+	case "/block/transaction":
+		for i, b := range s.blocks {
+			if request.BlockIdentifier.Hash != "" && (uint64(i) != request.BlockIdentifier.Index || !sameHash(b.hash, request.BlockIdentifier.Hash)) {
+				continue
+			}
+			for _, tx := range b.transactions {
+				if sameHash(tx.TransactionIdentifier.Hash, request.TransactionIdentifier.Hash) {
+					answer(w, meshclient.BlockTransaction{Transaction: tx})
+					return
+				}
+			}
+		}
+		rosettaError(w, http.StatusInternalServerError, meshclient.CodeTransactionNotFound, "transaction not found", request.TransactionIdentifier.Hash)
+	case "/search/transactions":
+		// Newest first, as the Mesh API lists them, paged by offset
+		var hits []meshclient.BlockTransactionEntry
+		tag, _ := hex.DecodeString(tagKey(request.AccountIdentifier.Address))
+		for i := len(s.blocks) - 1; i >= 0; i-- {
+			for _, tx := range s.blocks[i].transactions {
+				if len(tag) == 0 || tx.Touches(tag) {
+					id := meshclient.BlockIdentifier{Index: uint64(i), Hash: s.blocks[i].hash}
+					hits = append(hits, meshclient.BlockTransactionEntry{BlockIdentifier: id, Transaction: tx})
+				}
+			}
+		}
+		result := meshclient.SearchResult{TotalCount: int64(len(hits)), Transactions: []meshclient.BlockTransactionEntry{}}
+		limit := request.Limit
+		if limit <= 0 || limit > SearchPageLimit {
+			limit = SearchPageLimit
+		}
+		if request.Offset < int64(len(hits)) {
+			end := min(request.Offset+limit, int64(len(hits)))
+			result.Transactions = hits[request.Offset:end]
+			if end < int64(len(hits)) {
+				result.NextOffset = &end
+			}
+		}
+		answer(w, result)
+	case "/construction/derive":
+		pk, err := hex.DecodeString(strings.TrimPrefix(request.PublicKey.HexBytes, "0x"))
+		if err != nil || len(pk) < wotsp.SigSize || request.PublicKey.CurveType != meshclient.CurveWOTS {
+			rosettaError(w, http.StatusInternalServerError, 8, "invalid public key", "")
+			return
+		}
+		hash, _ := wotsp.AddrHashFromPK(pk) // cannot fail: pk was checked to hold a public key
+		answer(w, struct {
+			AccountIdentifier meshclient.AccountIdentifier `json:"account_identifier"`
+		}{meshclient.AccountIdentifier{Address: "0x" + hex.EncodeToString(hash[:])}})
+	case "/construction/preprocess":
+		var source string
+		for _, op := range request.Operations {
+			if op.Type == meshclient.OpSourceTransfer && op.Account != nil {
+				source = op.Account.Address
+			}
+		}
+		if source == "" {
+			rosettaError(w, http.StatusInternalServerError, 9, "no source operation", "")
+			return
+		}
+		answer(w, meshclient.PreprocessResult{Options: map[string]interface{}{"source_addr": source}})
+	case "/construction/metadata":
+		source, _ := request.Options["source_addr"].(string)
+		if source == "" {
+			rosettaError(w, http.StatusInternalServerError, 10, "missing source_addr option", "")
+			return
+		}
+		balance := uint64(0)
+		if acct := s.accounts[tagKey(source)]; acct != nil {
+			balance = acct.balance
+		}
+		answer(w, meshclient.MetadataResult{
+			Metadata:     map[string]interface{}{"source_balance": balance},
+			SuggestedFee: []meshclient.Amount{{Value: fmt.Sprint(s.fee), Currency: meshclient.MCM}},
+		})
+	case "/construction/submit":
+		raw, err := hex.DecodeString(strings.TrimPrefix(request.SignedTransaction, "0x"))
+		if err != nil || len(raw) == 0 {
+			rosettaError(w, http.StatusInternalServerError, meshclient.CodeInvalidTransaction, "invalid signed transaction", "")
+			return
+		}
+		sum := sha256.Sum256(raw)
+		id := meshclient.TransactionIdentifier{Hash: "0x" + hex.EncodeToString(sum[:])}
+		tx := meshclient.Transaction{TransactionIdentifier: id}
+		// Anything else is accepted as is, for the tools sending placeholder transactions
+		if transfer, err := txentry.Decode(raw); err == nil {
+			if valid, _, err := transfer.VerifySignature(); err != nil || !valid {
+				rosettaError(w, http.StatusInternalServerError, meshclient.CodeSignatureInvalid, "invalid transaction", "signature does not verify against the source address")
+				return
+			}
+			if acct := s.accounts[hex.EncodeToString(transfer.Source[:txentry.TagLength])]; acct != nil && !sameHash(acct.address, hex.EncodeToString(transfer.Source[:])) {
+				rosettaError(w, http.StatusInternalServerError, meshclient.CodeSourceMismatch, "invalid transaction", "source address mismatch: the tag belongs to "+acct.address)
+				return
+			}
+			if transfer.Fee < s.minFee {
+				writeError(w, http.StatusInternalServerError, meshclient.MeshError{Code: meshclient.CodeFeeTooLow, Message: "fee too low",
+					Description: fmt.Sprintf("fee of %d is below the minimum", transfer.Fee), Details: map[string]interface{}{"minimum_fee": s.minFee}})
+				return
+			}
+			s.transfers[hex.EncodeToString(sum[:])] = transfer
+			tx.Operations = operations(transfer)
+		}
+		s.submitted = append(s.submitted, request.SignedTransaction)
+		// A transaction broadcast again while pending is not listed twice
+		for _, pending := range s.mempool {
+			if sameHash(pending.TransactionIdentifier.Hash, id.Hash) {
+				answer(w, meshclient.SubmitResult{TransactionIdentifier: id})
+				return
+			}
+		}
+		if s.nextHold != nil {
+			s.held[hex.EncodeToString(sum[:])] = s.nextHold
+			s.nextHold = nil
+		}
+		s.mempool = append(s.mempool, tx)
+		answer(w, meshclient.SubmitResult{TransactionIdentifier: id})
+	default:
+		rosettaError(w, http.StatusNotFound, 7, "unknown endpoint", r.URL.Path)
+	}
+}
+
+// answer writes a 200 JSON answer
+func answer(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(v)
+}
+
+// rosettaError writes an answer in the Rosetta error schema
+func rosettaError(w http.ResponseWriter, status int, code int, message string, description string) {
+	writeError(w, status, meshclient.MeshError{Code: code, Message: message, Description: description})
+}
+
+// writeError writes a Rosetta error, for the answers with details
+func writeError(w http.ResponseWriter, status int, meshErr meshclient.MeshError) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(meshErr)
+}
+
+// tagKey returns the lowercase hex tag of an address or tag, without 0x
+func tagKey(address string) string {
+	key := strings.ToLower(strings.TrimPrefix(address, "0x"))
+	if len(key) > 40 {
+		key = key[:40]
+	}
+	return key
+}
+
+// hashKey returns the lowercase hex of a hash, without 0x
+func hashKey(hash string) string {
+	return strings.ToLower(strings.TrimPrefix(hash, "0x"))
+}
+
+// sameHash compares two hex hashes case-insensitively, with or without the 0x prefix
+func sameHash(a string, b string) bool {
+	return strings.EqualFold(strings.TrimPrefix(a, "0x"), strings.TrimPrefix(b, "0x"))
+}
@@ -0,0 +1,369 @@
+package meshmock_test
+
+import (
+	"context"
+	"encoding/hex"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/NickP005/Vindax-MCM-tools/pkg/meshclient"
+	"github.com/NickP005/Vindax-MCM-tools/pkg/meshmock"
+	"github.com/NickP005/Vindax-MCM-tools/pkg/txbuild"
+	"github.com/NickP005/Vindax-MCM-tools/pkg/txentry"
+	"github.com/NickP005/Vindax-MCM-tools/pkg/wotsp"
+)
+
+// pendingTx is a transaction without operations, as injected in the mempool
+func pendingTx(id string) meshclient.Transaction {
+	return meshclient.Transaction{TransactionIdentifier: meshclient.TransactionIdentifier{Hash: id}}
+}
+
+const (
+	txA = "0x" + "aa00000000000000000000000000000000000000000000000000000000000000"
+	txB = "0x" + "bb00000000000000000000000000000000000000000000000000000000000000"
+)
+
+func newMock(t *testing.T) (*meshmock.Server, *meshclient.MeshAPIClient) {
+	t.Helper()
+	mock := meshmock.New()
+	t.Cleanup(mock.Close)
+	return mock, meshclient.NewMeshAPIClient(mock.URL(), nil)
+}
+
+func TestChain(t *testing.T) {
+	mock, client := newMock(t)
+	ctx := context.Background()
+	for want := uint64(1); want <= 3; want++ {
+		if height := mock.MineBlock(); height != want {
+			t.Fatalf("mined height %d, want %d", height, want)
+		}
+	}
+	status, err := client.NetworkStatus(ctx)
+	if err != nil || status.CurrentBlockIdentifier.Index != 3 || mock.Height() != 3 {
+		t.Fatalf("status %+v, %v", status, err)
+	}
+	// Each block names the one before it
+	for height := uint64(1); height <= 3; height++ {
+		block, err := client.Block(ctx, height)
+		if err != nil {
+			t.Fatal(err)
+		}
+		parent, err := client.Block(ctx, height-1)
+		if err != nil || block.Block.ParentBlockIdentifier != parent.Block.BlockIdentifier {
+			t.Errorf("block %d: parent %+v, %v", height, block.Block.ParentBlockIdentifier, err)
+		}
+	}
+	if _, err := client.Block(ctx, 4); err == nil {
+		t.Error("block past the tip found")
+	}
+}
+
+func TestAccounts(t *testing.T) {
+	mock, client := newMock(t)
+	ctx := context.Background()
+	tag := make([]byte, 20)
+	tag[0] = 0x42
+	address := "0x42" + strings.Repeat("00", 19) + strings.Repeat("11", 20)
+	mock.SetAccount(tag, address, 1500)
+
+	balance, err := client.AccountBalance(ctx, tag)
+	if value, _ := balance.Value(); err != nil || value != 1500 {
+		t.Errorf("balance %+v, %v", balance, err)
+	}
+	resolved, err := client.ResolveTag(ctx, tag)
+	if err != nil || resolved.AddressHex != address || resolved.Amount != 1500 {
+		t.Errorf("resolved %+v, %v", resolved, err)
+	}
+
+	// A tag never set holds nothing and does not resolve
+	unknown := make([]byte, 20)
+	if balance, err := client.AccountBalance(ctx, unknown); err != nil {
+		t.Error(err)
+	} else if value, _ := balance.Value(); value != 0 {
+		t.Errorf("unknown balance %+v", balance)
+	}
+	if _, err := client.ResolveTag(ctx, unknown); err != meshclient.ErrTagNotFound {
+		t.Errorf("unknown tag: %v", err)
+	}
+}
+
+func TestMempool(t *testing.T) {
+	mock, client := newMock(t)
+	ctx := context.Background()
+	mock.AddToMempool(pendingTx(txA))
+	mock.AddToMempool(pendingTx(txB))
+	mempool, err := client.Mempool(ctx)
+	if err != nil || !mempool.Contains(txA) || !mempool.Contains(txB) {
+		t.Fatalf("mempool %+v, %v", mempool, err)
+	}
+	if _, err := client.MempoolTransaction(ctx, txA); err != nil {
+		t.Error(err)
+	}
+
+	if _, err := client.MempoolTransaction(ctx, "0x"+strings.Repeat("cc", 32)); err != meshclient.ErrNotInMempool {
+		t.Errorf("unknown transaction: %v", err)
+	}
+
+	// Mining empties the mempool into the block
+	height := mock.MineBlock()
+	if mempool, _ := client.Mempool(ctx); len(mempool.TransactionIdentifiers) != 0 {
+		t.Errorf("mempool after mining %+v", mempool)
+	}
+	if found, err := client.BlockHasTransaction(ctx, height, txA); err != nil || !found {
+		t.Errorf("mined transaction: %v, %v", found, err)
+	}
+	if tx, err := client.BlockTransaction(ctx, txA); err != nil || tx.Transaction.TransactionIdentifier.Hash != txA {
+		t.Errorf("block transaction %+v, %v", tx, err)
+	}
+	if _, err := client.BlockTransaction(ctx, "0x"+strings.Repeat("cc", 32)); !meshclient.TransactionNotFound(err) {
+		t.Errorf("unknown transaction in a block: %v", err)
+	}
+}
+
+func TestSubmit(t *testing.T) {
+	mock, client := newMock(t)
+	ctx := context.Background()
+	result, err := client.SubmitTransaction(ctx, "0xdeadbeef")
+	if err != nil {
+		t.Fatal(err)
+	}
+	txID := result.TransactionIdentifier.Hash
+	if mempool, _ := client.Mempool(ctx); !mempool.Contains(txID) {
+		t.Error("submitted transaction not pending")
+	}
+	height := mock.MineBlock()
+	if found, err := client.BlockHasTransaction(ctx, height, txID); err != nil || !found {
+		t.Errorf("submitted transaction not mined: %v, %v", found, err)
+	}
+	if submitted := mock.Submitted(); len(submitted) != 1 || submitted[0] != "0xdeadbeef" {
+		t.Errorf("submitted %v", submitted)
+	}
+}
+
+func TestReorg(t *testing.T) {
+	for _, toMempool := range []bool{false, true} {
+		mock, client := newMock(t)
+		ctx := context.Background()
+		mock.AddToMempool(pendingTx(txA))
+		mock.MineBlock()
+		old, _ := client.Block(ctx, 1)
+
+		mock.Reorg(1, toMempool)
+		block, err := client.Block(ctx, 1)
+		if err != nil || block.Block.BlockIdentifier.Hash == old.Block.BlockIdentifier.Hash || block.Contains(txA) || mock.Height() != 1 {
+			t.Errorf("toMempool %v: block %+v, %v", toMempool, block, err)
+		}
+		if mempool, _ := client.Mempool(ctx); mempool.Contains(txA) != toMempool {
+			t.Errorf("toMempool %v: mempool %+v", toMempool, mempool)
+		}
+	}
+}
+
+func TestReorgTo(t *testing.T) {
+	mock, client := newMock(t)
+	ctx := context.Background()
+	mock.AddToMempool(pendingTx(txA))
+	mock.MineBlock()
+	mock.MineBlock()
+
+	// A longer branch moves the transaction up a block
+	tip := mock.ReorgTo(2, nil, nil, []meshclient.Transaction{pendingTx(txA)})
+	if tip != 3 || mock.Height() != 3 {
+		t.Fatalf("tip %d", tip)
+	}
+	if check := client.CheckBlock(ctx, 1, txA); check.Included {
+		t.Error("transaction left at its old height")
+	}
+	if check := client.CheckBlock(ctx, 3, txA); !check.Included {
+		t.Error("transaction not at its new height")
+	}
+
+	// A shorter branch takes it out of the chain
+	if tip = mock.ReorgTo(3); tip != 0 {
+		t.Fatalf("tip %d", tip)
+	}
+	if _, err := client.BlockTransaction(ctx, txA); !meshclient.TransactionNotFound(err) {
+		t.Errorf("transaction still in the chain: %v", err)
+	}
+}
+
+func TestFaults(t *testing.T) {
+	mock, client := newMock(t)
+	ctx := context.Background()
+	mock.Fail("/network/status",
+		meshmock.Fault{Status: 429, Body: "slow down", RetryAfter: "2"},
+		meshmock.Fault{Status: 200, Body: `{"current_block_identifier":`})
+
+	var meshErr *meshclient.MeshError
+	if _, err := client.NetworkStatus(ctx); !errors.As(err, &meshErr) || meshErr.StatusCode != 429 || meshErr.RetryAfter != 2*time.Second {
+		t.Errorf("first fault: %v", err)
+	}
+	var decodeErr *meshclient.DecodeError
+	if _, err := client.NetworkStatus(ctx); !errors.As(err, &decodeErr) {
+		t.Errorf("malformed answer: %v", err)
+	}
+	// The queue is spent, and did not touch the other endpoints
+	if _, err := client.NetworkStatus(ctx); err != nil {
+		t.Errorf("after the faults: %v", err)
+	}
+
+	// A fault without a status is a 500
+	mock.Fail("/mempool", meshmock.Fault{Body: "down"})
+	if _, err := client.Mempool(ctx); !errors.As(err, &meshErr) || meshErr.StatusCode != 500 {
+		t.Errorf("default status: %v", err)
+	}
+}
+
+func TestLatency(t *testing.T) {
+	mock, client := newMock(t)
+	mock.SetLatency(50 * time.Millisecond)
+	start := time.Now()
+	if _, err := client.NetworkStatus(context.Background()); err != nil || time.Since(start) < 50*time.Millisecond {
+		t.Errorf("answered in %v, %v", time.Since(start), err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if _, err := client.NetworkStatus(ctx); err == nil {
+		t.Error("answered before the deadline")
+	}
+}
+
+// signedTransfer pays 1000 from the key of seed 1, funded with 10000 under its own tag, to a tag of 0x33, the change on the key of seed 2
+func signedTransfer(t *testing.T, fee uint64) (string, [txentry.TagLength]byte, [txentry.AddressLength]byte, [txentry.AddressLength]byte) {
+	t.Helper()
+	key, next := wotsp.Keygen([32]byte{1}), wotsp.Keygen([32]byte{2})
+	tag := key.AddrHash()
+	source, change := txbuild.Address(tag, key.PublicKey), txbuild.Address(tag, next.PublicKey)
+	var to [txentry.TagLength]byte
+	to[0] = 0x33
+	payment, _ := txbuild.NewDestination(to, "", 1000)
+	tx, err := txbuild.NewTransfer(source, change, 10000, fee, []txentry.Destination{payment})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := txbuild.Sign(tx, &key); err != nil {
+		t.Fatal(err)
+	}
+	return hex.EncodeToString(tx.Bytes()), tag, source, change
+}
+
+func TestSubmitRejections(t *testing.T) {
+	mock, client := newMock(t)
+	ctx := context.Background()
+	var meshErr *meshclient.MeshError
+	if _, err := client.SubmitTransaction(ctx, "0xnothex"); !errors.As(err, &meshErr) || meshErr.Code != 6 {
+		t.Errorf("undecodable transaction: %v", err)
+	}
+
+	raw, tag, source, _ := signedTransfer(t, 500)
+	// Raise the amount of the destination after signing
+	tampered, _ := hex.DecodeString(raw)
+	tampered[txentry.HeaderLength+txentry.TagLength+txentry.ReferenceLength]++
+	if _, err := client.SubmitTransaction(ctx, hex.EncodeToString(tampered)); !errors.Is(err, meshclient.ErrSignatureRejected) {
+		t.Errorf("tampered signature: %v", err)
+	}
+
+	// The tag belongs to another key: the node refuses the source
+	mock.SetAccount(tag[:], "0x"+hex.EncodeToString(tag[:])+strings.Repeat("ee", 20), 10000)
+	if _, err := client.SubmitTransaction(ctx, raw); !errors.Is(err, meshclient.ErrSignatureRejected) || !strings.Contains(err.Error(), "source address mismatch") {
+		t.Errorf("source moved: %v", err)
+	}
+
+	mock.SetAccount(tag[:], "0x"+hex.EncodeToString(source[:]), 10000)
+	mock.SetMinimumFee(800)
+	var feeErr *meshclient.FeeTooLowError
+	if _, err := client.SubmitTransaction(ctx, raw); !errors.As(err, &feeErr) || !feeErr.MinimumKnown || feeErr.Minimum != 800 {
+		t.Errorf("fee under the minimum: %v", err)
+	}
+	if submitted := mock.Submitted(); len(submitted) != 0 {
+		t.Errorf("rejected transactions recorded: %v", submitted)
+	}
+}
+
+// TestTransferBalances mines a signed transfer, then reorgs it away and back
+func TestTransferBalances(t *testing.T) {
+	mock, client := newMock(t)
+	ctx := context.Background()
+	raw, tag, source, change := signedTransfer(t, 500)
+	to := []byte{0x33}
+	to = append(to, make([]byte, txentry.TagLength-1)...)
+	mock.SetAccount(tag[:], "0x"+hex.EncodeToString(source[:]), 10000)
+
+	// Broadcast twice while pending, listed once
+	for i := 0; i < 2; i++ {
+		if _, err := client.SubmitTransaction(ctx, raw); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if mempool, _ := client.Mempool(ctx); len(mempool.TransactionIdentifiers) != 1 || len(mock.Submitted()) != 2 {
+		t.Errorf("mempool %+v, submitted %d", mempool, len(mock.Submitted()))
+	}
+
+	mock.MineBlock()
+	balance, _ := mock.Balance(tag[:])
+	received, _ := mock.Balance(to)
+	if balance != 8500 || received != 1000 {
+		t.Errorf("balances after the block: %d and %d", balance, received)
+	}
+	resolution, err := client.ResolveTag(ctx, tag[:])
+	if err != nil || !strings.EqualFold(resolution.AddressHex, "0x"+hex.EncodeToString(change[:])) {
+		t.Errorf("source tag at %+v, %v, expected the change address", resolution, err)
+	}
+
+	// The reorg undoes the block, the transaction going back to the mempool
+	mock.Reorg(1, true)
+	if balance, _ := mock.Balance(tag[:]); balance != 10000 {
+		t.Errorf("balance after the reorg: %d", balance)
+	}
+	if received, found := mock.Balance(to); received != 0 && found {
+		t.Errorf("destination kept %d after the reorg", received)
+	}
+	mock.MineBlock()
+	if balance, _ := mock.Balance(tag[:]); balance != 8500 {
+		t.Errorf("balance mined again: %d", balance)
+	}
+}
+
+func TestBlockLimit(t *testing.T) {
+	mock, client := newMock(t)
+	ctx := context.Background()
+	mock.AddToMempool(pendingTx(txA))
+	mock.AddToMempool(pendingTx(txB))
+	mock.SetBlockLimit(1)
+	height := mock.MineBlock()
+	block, err := client.Block(ctx, height)
+	if err != nil || !block.Contains(txA) || block.Contains(txB) || !block.ListsOther(txB) {
+		t.Fatalf("block %+v, %v", block, err)
+	}
+	if found, err := client.BlockHasTransaction(ctx, height, txB); !found || err != nil {
+		t.Errorf("other transaction: %v, %v", found, err)
+	}
+}
+
+func TestConstruction(t *testing.T) {
+	mock, client := newMock(t)
+	ctx := context.Background()
+	source := make([]byte, 20)
+	source[0] = 0x42
+	mock.SetAccount(source, "0x42"+strings.Repeat("00", 39), 10000)
+	mock.SetSuggestedFee(1200)
+	debit, _ := meshclient.SourceOperation(0, source, 1500)
+	preprocess, err := client.ConstructionPreprocess(ctx, []meshclient.Operation{debit, meshclient.FeeOperation(1, 1200)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	metadata, err := client.ConstructionMetadata(ctx, preprocess.Options)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fee, ok, _ := metadata.Fee(); fee != 1200 || !ok || metadata.Metadata["source_balance"] != 10000.0 {
+		t.Errorf("metadata %+v", metadata)
+	}
+
+	// Without a source operation there is nothing to preprocess
+	if _, err := client.ConstructionPreprocess(ctx, []meshclient.Operation{meshclient.FeeOperation(0, 1)}); err == nil {
+		t.Error("no source operation accepted")
+	}
+}
@@ -0,0 +1,169 @@
+/*
+ * Package monitor holds the parts of wallet-tool's transaction monitor
+ * that do not depend on how a payout was built: the health of the Mesh API
+ * while polling it, the block hashes that reveal a reorg, and the state
+ * file a monitor keeps for other processes to follow.
+ */
+package monitor
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/NickP005/Vindax-MCM-tools/pkg/meshclient"
+)
+
+// OutageFailures is the number of failed Mesh API requests in a row after which the monitor backs off
+const OutageFailures = 3
+
+// OutageLogEvery is how many failures of an outage go by between two logged ones
+const OutageLogEvery = 10
+
+/*
+ * Health tracks the Mesh API during monitoring
+ *
+ * A few failures in a row are an outage: the polling then backs off
+ * exponentially, from the interval given to NewHealth up to a minute, and
+ * the time spent in outages can be left out of the monitoring timeout. The
+ * first success ends the outage. Rate limiting (429 or 503) is not a
+ * failure: the polling only waits for the Retry-After asked by the server.
+ *
+ * Failures are logged by Health itself, so that a long outage (e.g. a
+ * flapping DNS entry) does not log a line per request: past the first
+ * OutageFailures, only every OutageLogEvery-th one is, with its count.
+ */
+type Health struct {
+	// Log receives every line worth telling the operator; nil logs nothing
+	Log func(line string)
+
+	failures    int
+	outageStart time.Time
+	// downtime is the length of the outages that ended
+	downtime time.Duration
+	nextTry  time.Time
+	backoff  meshclient.RetryPolicy
+	// lastErr is the last failed or throttled request, kept after recovery for the state file
+	lastErr error
+}
+
+// NewHealth tracks an API polled every interval
+func NewHealth(interval time.Duration, log func(line string)) *Health {
+	return &Health{Log: log, backoff: meshclient.RetryPolicy{
+		BaseBackoff: interval,
+		MaxBackoff:  time.Minute,
+	}}
+}
+
+// logf formats a line for Log
+func (h *Health) logf(format string, args ...any) {
+	if h.Log != nil {
+		h.Log(fmt.Sprintf(format, args...))
+	}
+}
+
+// Failure records a failed request and logs it as "what: err" unless the outage is past its first failures,
+// or paces the polling if the server throttled it
+func (h *Health) Failure(now time.Time, what string, err error) {
+	h.lastErr = err
+	if wait, throttled := meshclient.Throttled(err); throttled {
+		wait = max(min(wait, meshclient.MaxRetryAfter), h.backoff.BaseBackoff)
+		h.logf("⏳ Mesh API rate limiting, waiting %v before the next check", wait)
+		h.nextTry = now.Add(wait)
+		return
+	}
+	h.failures++
+	switch {
+	case h.failures <= OutageFailures:
+		h.logf("%s: %v", what, err)
+	case h.failures%OutageLogEvery == 0:
+		h.logf("%s: %v (%d failures in a row, logging one in %d)", what, err, h.failures, OutageLogEvery)
+	}
+	if h.failures < OutageFailures {
+		return
+	}
+	if h.failures == OutageFailures {
+		h.outageStart = now
+		h.logf("⚠️ Mesh API unreachable, backing off until it answers again")
+	}
+	h.nextTry = now.Add(h.backoff.Backoff(h.failures - OutageFailures + 1))
+}
+
+// Success records a successful request, ending any outage
+func (h *Health) Success(now time.Time) {
+	if h.failures >= OutageFailures {
+		outage := now.Sub(h.outageStart)
+		h.downtime += outage
+		h.logf("✅ Mesh API answering again after %v (%d failed requests)", outage.Round(time.Second), h.failures)
+	}
+	h.failures = 0
+	h.nextTry = time.Time{}
+}
+
+// Due reports whether the backoff allows another polling request
+func (h *Health) Due(now time.Time) bool {
+	return !now.Before(h.nextTry)
+}
+
+// Down returns the total time spent in outages, the current one included
+func (h *Health) Down(now time.Time) time.Duration {
+	if h.failures >= OutageFailures {
+		return h.downtime + now.Sub(h.outageStart)
+	}
+	return h.downtime
+}
+
+// LastErr returns the last failed or throttled request, nil if there was none
+func (h *Health) LastErr() error {
+	return h.lastErr
+}
+
+// MaxReorgDepth bounds how far back ForkPoint looks for the block where a reorganized chain diverged
+const MaxReorgDepth = 100
+
+// BlockHashes remembers the hash of every block seen while monitoring, by height
+type BlockHashes map[uint64]string
+
+// Changed reports whether a height already seen now has another hash
+func (b BlockHashes) Changed(height uint64, hash string) bool {
+	seen, ok := b[height]
+	return ok && !(meshclient.BlockIdentifier{Index: height, Hash: seen}).Same(meshclient.BlockIdentifier{Index: height, Hash: hash})
+}
+
+/*
+ * ForkPoint returns the highest height, at most from, whose block is still
+ * the one seen, fetching the known blocks one by one going down
+ *
+ * Heights never seen are skipped. When no seen block survives within
+ * MaxReorgDepth, the lowest height looked at minus one is returned, so
+ * everything above it is scanned again.
+ */
+func (b BlockHashes) ForkPoint(ctx context.Context, client *meshclient.MeshAPIClient, from uint64) (uint64, error) {
+	height := from
+	for ; height > 0 && from-height < MaxReorgDepth; height-- {
+		seen, ok := b[height]
+		if !ok {
+			continue
+		}
+		block, err := client.Block(ctx, height)
+		if err != nil {
+			return 0, err
+		}
+		if block.Block.BlockIdentifier.Same(meshclient.BlockIdentifier{Index: height, Hash: seen}) {
+			return height, nil
+		}
+	}
+	if height > 0 {
+		height--
+	}
+	return height, nil
+}
+
+// Forget drops the blocks above height
+func (b BlockHashes) Forget(height uint64) {
+	for h := range b {
+		if h > height {
+			delete(b, h)
+		}
+	}
+}
@@ -0,0 +1,164 @@
+package monitor
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/NickP005/Vindax-MCM-tools/pkg/meshclient"
+	"github.com/NickP005/Vindax-MCM-tools/pkg/meshmock"
+)
+
+/*
+ * TestHealthOutage scripts a 2 minutes outage of an API polled every 5
+ * seconds: each poll allowed by Due fails until the API is back
+ */
+func TestHealthOutage(t *testing.T) {
+	var lines []string
+	health := NewHealth(5*time.Second, func(line string) { lines = append(lines, line) })
+	start := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	down := errors.New("connection refused")
+
+	polls := 0
+	now := start
+	for ; now.Sub(start) < 2*time.Minute; now = now.Add(time.Second) {
+		if health.Due(now) {
+			polls++
+			health.Failure(now, "Error checking mempool", down)
+		}
+	}
+	// Without backoff, 24 polls would have failed
+	if polls < OutageFailures+1 || polls >= 24 {
+		t.Errorf("%d polls during the outage", polls)
+	}
+	outageStart := start.Add((OutageFailures - 1) * time.Second)
+	if got := health.Down(now); got != now.Sub(outageStart) {
+		t.Errorf("down %v during the outage, want %v", got, now.Sub(outageStart))
+	}
+	if health.LastErr() != down {
+		t.Errorf("last error %v", health.LastErr())
+	}
+
+	// The first success ends the outage and keeps its length
+	health.Success(now)
+	if !health.Due(now) || health.Down(now.Add(time.Hour)) != now.Sub(outageStart) {
+		t.Errorf("after recovery: due %v, down %v", health.Due(now), health.Down(now.Add(time.Hour)))
+	}
+	if last := lines[len(lines)-1]; !strings.Contains(last, "answering again after 1m58s") {
+		t.Errorf("recovery logged as %q", last)
+	}
+	// Only the first failures of the outage are logged, then one in OutageLogEvery
+	if len(lines) > OutageFailures+2+polls/OutageLogEvery {
+		t.Errorf("%d lines logged for %d failures: %q", len(lines), polls, lines)
+	}
+
+	// A failure after the outage is logged again, and is not an outage
+	health.Failure(now, "Error checking block", down)
+	if !health.Due(now) || !strings.HasPrefix(lines[len(lines)-1], "Error checking block") {
+		t.Errorf("single failure: due %v, logged %q", health.Due(now), lines[len(lines)-1])
+	}
+}
+
+/*
+ * TestHealthLongOutage replays a DNS entry failing for two hours under a
+ * 5 seconds polling: a handful of lines and requests instead of 1440, and
+ * the two hours counted as downtime for the timeout
+ */
+func TestHealthLongOutage(t *testing.T) {
+	var lines []string
+	health := NewHealth(5*time.Second, func(line string) { lines = append(lines, line) })
+	start := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	down := errors.New("lookup mesh.example: no such host")
+
+	polls := 0
+	now := start
+	for ; now.Sub(start) < 2*time.Hour; now = now.Add(5 * time.Second) {
+		if health.Due(now) {
+			polls++
+			health.Failure(now, "Error getting network status", down)
+		}
+	}
+	if polls > 200 {
+		t.Errorf("%d requests in two hours", polls)
+	}
+	if len(lines) > OutageFailures+1+polls/OutageLogEvery {
+		t.Errorf("%d lines logged for %d failures", len(lines), polls)
+	}
+	if got := health.Down(now); got < 2*time.Hour-time.Minute {
+		t.Errorf("down %v", got)
+	}
+	health.Success(now)
+	if last := lines[len(lines)-1]; !strings.Contains(last, fmt.Sprintf("(%d failed requests)", polls)) {
+		t.Errorf("recovery logged as %q", last)
+	}
+}
+
+func TestHealthThrottled(t *testing.T) {
+	health := NewHealth(5*time.Second, nil)
+	now := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	throttled := &meshclient.MeshError{StatusCode: 429, RetryAfter: 20 * time.Second}
+	for i := 0; i < 2*OutageFailures; i++ {
+		health.Failure(now, "Error checking mempool", throttled)
+	}
+	// Rate limiting waits for the server, and is no outage
+	if health.Due(now.Add(19*time.Second)) || !health.Due(now.Add(20*time.Second)) || health.Down(now.Add(time.Minute)) != 0 {
+		t.Errorf("throttled: down %v", health.Down(now.Add(time.Minute)))
+	}
+	// A wait past MaxRetryAfter is cut to it
+	health.Failure(now, "Error checking mempool", &meshclient.MeshError{StatusCode: 503, RetryAfter: 1000 * time.Hour})
+	if health.Due(now.Add(meshclient.MaxRetryAfter-time.Second)) || !health.Due(now.Add(meshclient.MaxRetryAfter)) {
+		t.Error("throttled past MaxRetryAfter")
+	}
+}
+
+func TestBlockHashes(t *testing.T) {
+	mock := meshmock.New()
+	defer mock.Close()
+	for i := 0; i < 6; i++ {
+		mock.MineBlock()
+	}
+	client := meshclient.NewMeshAPIClient(mock.URL(), nil)
+	ctx := context.Background()
+	seen := BlockHashes{}
+	for height := uint64(1); height <= 6; height++ {
+		block, err := client.Block(ctx, height)
+		if err != nil {
+			t.Fatal(err)
+		}
+		seen[height] = block.Block.BlockIdentifier.Hash
+	}
+	if seen.Changed(6, strings.ToUpper(seen[6])) || seen.Changed(7, "0x07") || !seen.Changed(6, "0x06") {
+		t.Error("Changed compares hashes wrongly")
+	}
+
+	// The fork is the highest block still seen, even when the branch is longer or shorter
+	if fork, err := seen.ForkPoint(ctx, client, 6); err != nil || fork != 6 {
+		t.Errorf("no reorg: fork %d, %v", fork, err)
+	}
+	mock.ReorgTo(2, nil, nil, nil)
+	if fork, err := seen.ForkPoint(ctx, client, 6); err != nil || fork != 4 {
+		t.Errorf("reorg of 2 blocks: fork %d, %v", fork, err)
+	}
+	mock.ReorgTo(5, nil)
+	if fork, err := seen.ForkPoint(ctx, client, 3); err != nil || fork != 2 {
+		t.Errorf("shorter branch: fork %d, %v", fork, err)
+	}
+	// Heights never seen are skipped
+	delete(seen, 2)
+	if fork, err := seen.ForkPoint(ctx, client, 3); err != nil || fork != 1 {
+		t.Errorf("unseen height: fork %d, %v", fork, err)
+	}
+
+	seen.Forget(3)
+	if len(seen) != 2 || seen[1] == "" || seen[3] == "" {
+		t.Errorf("after Forget(3): %v", seen)
+	}
+
+	mock.Fail("/block", meshmock.Fault{Status: 500})
+	if _, err := seen.ForkPoint(ctx, client, 3); err == nil {
+		t.Error("failed block fetch ignored")
+	}
+}
@@ -0,0 +1,156 @@
+package monitor
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/NickP005/Vindax-MCM-tools/pkg/fileutil"
+)
+
+// Phases of the monitor, in the state file
+const (
+	PhaseSubmitted = "submitted"
+	PhaseMempool   = "mempool"
+	PhaseOrphaned  = "orphaned"
+	PhaseIncluded  = "included"
+	PhaseConfirmed = "confirmed"
+	PhaseTimeout   = "timeout"
+	PhaseStopped   = "stopped"
+)
+
+// FinalPhase reports whether the monitor is done once in phase
+func FinalPhase(phase string) bool {
+	return phase == PhaseConfirmed || phase == PhaseTimeout || phase == PhaseStopped
+}
+
+// State is the progress of a monitor, as written to its state file
+type State struct {
+	Phase string `json:"phase"`
+	TxID  string `json:"txId"`
+	// InclusionBlock is the block holding the transaction, 0 until it is found
+	InclusionBlock        uint64    `json:"inclusionBlock"`
+	Confirmations         int       `json:"confirmations"`
+	RequiredConfirmations int       `json:"requiredConfirmations"`
+	LastScannedBlock      uint64    `json:"lastScannedBlock"`
+	LastError             string    `json:"lastError,omitempty"`
+	StartedAt             time.Time `json:"startedAt"`
+	UpdatedAt             time.Time `json:"updatedAt"`
+}
+
+/*
+ * StateWriter keeps a state file up to date without ever blocking the
+ * monitoring loop
+ *
+ * Update only hands the state over: a goroutine writes it, and the states
+ * given while a write is in progress are coalesced into the latest one.
+ * Every write goes to a temporary file renamed over the state file, so a
+ * reader never sees a partial state. A nil *StateWriter does nothing.
+ */
+type StateWriter struct {
+	path string
+	// onError is told about the first write that fails, see NewStateWriter
+	onError func(err error)
+	mu      sync.Mutex
+	latest  *State
+	wake    chan struct{}
+	done    chan struct{}
+}
+
+/*
+ * NewStateWriter starts writing states to path; an empty path returns nil,
+ * which writes nothing
+ *
+ * The monitoring goes on without its state file when a write fails:
+ * onError, if set, is called with the first failure only.
+ */
+func NewStateWriter(path string, onError func(err error)) *StateWriter {
+	if path == "" {
+		return nil
+	}
+	w := &StateWriter{path: path, onError: onError, wake: make(chan struct{}, 1), done: make(chan struct{})}
+	go w.run()
+	return w
+}
+
+// Update schedules state to be written, replacing any state not written yet
+func (w *StateWriter) Update(state State) {
+	if w == nil {
+		return
+	}
+	state.UpdatedAt = time.Now()
+	w.mu.Lock()
+	w.latest = &state
+	w.mu.Unlock()
+	select {
+	case w.wake <- struct{}{}:
+	default:
+	}
+}
+
+// Close writes the last state given, then stops the writer; Update must not be called afterwards
+func (w *StateWriter) Close() {
+	if w == nil {
+		return
+	}
+	close(w.wake)
+	<-w.done
+}
+
+// run writes the latest state every time it is woken up, until Close
+func (w *StateWriter) run() {
+	defer close(w.done)
+	warned := false
+	for range w.wake {
+		w.mu.Lock()
+		state := w.latest
+		w.latest = nil
+		w.mu.Unlock()
+		if state == nil {
+			continue
+		}
+		if err := writeStateFile(w.path, *state); err != nil && !warned {
+			if w.onError != nil {
+				w.onError(fmt.Errorf("failed to write state file %s: %v", w.path, err))
+			}
+			warned = true
+		}
+	}
+}
+
+// writeStateFile replaces the state file atomically, see fileutil.WriteAtomic
+func writeStateFile(path string, state State) error {
+	data, err := json.MarshalIndent(state, "", "  ")
+	if err != nil {
+		return err
+	}
+	return fileutil.WriteAtomic(path, append(data, '\n'), false)
+}
+
+// ReadState reads a state file written by StateWriter
+func ReadState(path string) (State, error) {
+	var state State
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return state, err
+	}
+	if err := json.Unmarshal(data, &state); err != nil {
+		return state, fmt.Errorf("invalid state file %s: %v", path, err)
+	}
+	return state, nil
+}
+
+// String renders a state on one line, for the status command
+func (s State) String() string {
+	line := fmt.Sprintf("[%s] %s tx %s", s.UpdatedAt.Format(time.TimeOnly), s.Phase, s.TxID)
+	if s.InclusionBlock > 0 {
+		line += fmt.Sprintf(" in block %d, %d of %d confirmations", s.InclusionBlock, s.Confirmations, s.RequiredConfirmations)
+	}
+	line += fmt.Sprintf(", last scanned block %d", s.LastScannedBlock)
+	if s.LastError != "" {
+		line += ", last error: " + s.LastError
+	}
+	return line
+}
@@ -0,0 +1,85 @@
+package monitor
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestStateWriter coalesces a burst of updates while a reader polls the file: it always parses, and ends with the last state
+func TestStateWriter(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "monitor.json")
+	writer := NewStateWriter(path, func(err error) { t.Error(err) })
+
+	stop, read := make(chan struct{}), make(chan int)
+	go func() {
+		reads := 0
+		for {
+			select {
+			case <-stop:
+				read <- reads
+				return
+			default:
+			}
+			state, err := ReadState(path)
+			if errors.Is(err, os.ErrNotExist) {
+				continue
+			}
+			if err != nil || state.TxID != "0x0d" {
+				t.Errorf("read %+v, %v", state, err)
+			}
+			reads++
+		}
+	}()
+	for block := uint64(1); block <= 2000; block++ {
+		writer.Update(State{Phase: PhaseMempool, TxID: "0x0d", LastScannedBlock: block})
+	}
+	writer.Update(State{Phase: PhaseIncluded, TxID: "0x0d", InclusionBlock: 2001, Confirmations: 1, RequiredConfirmations: 3, LastScannedBlock: 2001})
+	writer.Update(State{Phase: PhaseConfirmed, TxID: "0x0d", InclusionBlock: 2001, Confirmations: 3, RequiredConfirmations: 3, LastScannedBlock: 2003})
+	writer.Close()
+	close(stop)
+	if reads := <-read; reads == 0 {
+		t.Log("the reader never saw the file before Close")
+	}
+
+	state, err := ReadState(path)
+	if err != nil || state.Phase != PhaseConfirmed || state.Confirmations != 3 || state.LastScannedBlock != 2003 || state.UpdatedAt.IsZero() {
+		t.Fatalf("last state %+v, %v", state, err)
+	}
+	if line := state.String(); !strings.Contains(line, "confirmed tx 0x0d in block 2001, 3 of 3 confirmations, last scanned block 2003") {
+		t.Errorf("rendered as %q", line)
+	}
+	if matches, _ := filepath.Glob(filepath.Join(filepath.Dir(path), "*")); len(matches) != 1 {
+		t.Errorf("temporary files left: %v", matches)
+	}
+}
+
+// TestStateWriterFailure reports only the first failed write, and keeps accepting updates
+func TestStateWriterFailure(t *testing.T) {
+	var failures []error
+	writer := NewStateWriter(filepath.Join(t.TempDir(), "missing", "monitor.json"), func(err error) { failures = append(failures, err) })
+	for block := uint64(1); block <= 3; block++ {
+		writer.Update(State{Phase: PhaseMempool, LastScannedBlock: block})
+		time.Sleep(10 * time.Millisecond)
+	}
+	writer.Close()
+	if len(failures) != 1 || !strings.Contains(failures[0].Error(), "failed to write state file") {
+		t.Errorf("%d failures reported: %v", len(failures), failures)
+	}
+
+	// Without a path nothing is written, and nothing fails
+	none := NewStateWriter("", nil)
+	none.Update(State{Phase: PhaseMempool})
+	none.Close()
+}
+
+func TestReadStateInvalid(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "monitor.json")
+	os.WriteFile(path, []byte(`{"phase":`), 0600)
+	if _, err := ReadState(path); err == nil || !strings.Contains(err.Error(), "invalid state file") {
+		t.Errorf("truncated file: %v", err)
+	}
+}
@@ -0,0 +1,406 @@
+/*
+ * Package payqueue is a durable queue of payout jobs, files dropped in a
+ * directory by other systems and paid strictly one at a time.
+ *
+ * A job moves through the subdirectories of the queue as it is paid, the
+ * directory holding its file being its state:
+ *
+ *	queue/             queued, waiting for its turn, oldest first
+ *	queue/validating/  read and checked; nothing is signed yet
+ *	queue/submitted/   signing started: a transaction may be broadcast
+ *	queue/confirmed/   every transaction of the job confirmed
+ *	queue/failed/      the payout failed once signing started, for an operator to look at
+ *	queue/parked/      failed its checks MaxAttempts times (a poison job)
+ *
+ * The state index, queue/state.json, keeps the attempts and the last error
+ * of each job; a crash between a move and the index update is settled by
+ * the directory. A job file must appear whole: write it elsewhere, or under
+ * a name starting with ".", and rename it into the queue. Job names are
+ * unique, a name used again is a new job once the first one is over.
+ *
+ * Draining survives restarts: Open sends a job left in validating/ back to
+ * the queue, as nothing of it was signed, and Step takes a job left in
+ * submitted/ before any queued one, for its Handler to resume the
+ * transaction in flight rather than pay it again:
+ *
+ *	q, _ := payqueue.Open("queue", payqueue.DefaultMaxAttempts)
+ *	defer q.Close()
+ *	err := q.Drain(ctx, handler, 10*time.Second)
+ */
+package payqueue
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/NickP005/Vindax-MCM-tools/pkg/fileutil"
+)
+
+// States of a job, each the subdirectory holding its file but StateQueued, the queue directory itself
+const (
+	StateQueued     = "queued"
+	StateValidating = "validating"
+	StateSubmitted  = "submitted"
+	StateConfirmed  = "confirmed"
+	StateFailed     = "failed"
+	StateParked     = "parked"
+)
+
+// DefaultMaxAttempts is how many times a job may fail its checks before it is parked
+const DefaultMaxAttempts = 3
+
+// IndexFile is the state index, in the queue directory
+const IndexFile = "state.json"
+
+// lockFile keeps a second process from draining the queue
+const lockFile = ".lock"
+
+var (
+	// ErrWait is wrapped by a Handler error meaning the job cannot be paid yet, e.g. for want of funds: it is tried again later, as an attempt that does not count
+	ErrWait = errors.New("job cannot be paid yet")
+	// ErrUnconfirmed is wrapped by a Pay error meaning a transaction is out and not confirmed yet: the job stays submitted
+	ErrUnconfirmed = errors.New("transaction not confirmed yet")
+)
+
+// states lists the states with a subdirectory
+var states = []string{StateValidating, StateSubmitted, StateConfirmed, StateFailed, StateParked}
+
+// Job is a job of the queue, as the state index records it
+type Job struct {
+	Name  string `json:"name"`
+	State string `json:"state"`
+	// Attempts counts the failed checks, see MaxAttempts
+	Attempts  int       `json:"attempts"`
+	LastError string    `json:"lastError,omitempty"`
+	UpdatedAt time.Time `json:"updatedAt"`
+	// Path is the file of the job, in the directory of its state
+	Path string `json:"-"`
+}
+
+// Final reports whether the job is over: confirmed, failed or parked
+func (j *Job) Final() bool {
+	return j.State == StateConfirmed || j.State == StateFailed || j.State == StateParked
+}
+
+/*
+ * Handler pays the jobs of a queue, see Step
+ *
+ * Validate reads the file of a job and checks it can be paid; it is called
+ * again for a job found in submitted/, before Pay resumes it. Pay pays the
+ * job checked last, resuming whatever an earlier run of it signed. Both
+ * wrap ErrWait to try the job again later, and Pay wraps ErrUnconfirmed to
+ * keep the job submitted; any other Pay error fails the job.
+ */
+type Handler interface {
+	Validate(ctx context.Context, job *Job) error
+	Pay(ctx context.Context, job *Job) error
+}
+
+// Queue is a queue directory, open for draining by one process; its methods are not safe for concurrent use
+type Queue struct {
+	dir string
+	// MaxAttempts is how many failed checks park a queued job, or fail a submitted one
+	MaxAttempts int
+	// Log, if set, is called with a line at every move of a job
+	Log  func(line string)
+	jobs map[string]*Job
+	lock *fileutil.FileLock
+}
+
+/*
+ * Open opens the queue in dir, creating its subdirectories, and locks it
+ *
+ * The state index is read and settled with the directories: a job left in
+ * validating/ by a run that stopped goes back to the queue. An error
+ * wrapping fileutil.ErrLocked means another process drains the queue.
+ */
+func Open(dir string, maxAttempts int) (*Queue, error) {
+	for _, state := range states {
+		if err := os.MkdirAll(filepath.Join(dir, state), 0755); err != nil {
+			return nil, err
+		}
+	}
+	lock, err := fileutil.Lock(filepath.Join(dir, lockFile))
+	if err != nil && !errors.Is(err, errors.ErrUnsupported) {
+		return nil, fmt.Errorf("queue %s: %w", dir, err)
+	}
+	q := &Queue{dir: dir, MaxAttempts: maxAttempts, jobs: make(map[string]*Job), lock: lock}
+	if err := q.load(); err != nil {
+		q.Close()
+		return nil, err
+	}
+	return q, nil
+}
+
+// Close releases the lock of the queue
+func (q *Queue) Close() error {
+	if q.lock == nil {
+		return nil
+	}
+	err := q.lock.Unlock()
+	q.lock = nil
+	return err
+}
+
+// Dir returns the queue directory
+func (q *Queue) Dir() string {
+	return q.dir
+}
+
+// Jobs returns every job of the state index, sorted by name
+func (q *Queue) Jobs() []Job {
+	jobs := make([]Job, 0, len(q.jobs))
+	for _, job := range q.jobs {
+		jobs = append(jobs, *job)
+	}
+	sort.Slice(jobs, func(i, j int) bool { return jobs[i].Name < jobs[j].Name })
+	return jobs
+}
+
+// load reads the state index and settles it with the job files found
+func (q *Queue) load() error {
+	data, err := os.ReadFile(filepath.Join(q.dir, IndexFile))
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	if err == nil {
+		var index struct {
+			Jobs []*Job `json:"jobs"`
+		}
+		if err := json.Unmarshal(data, &index); err != nil {
+			return fmt.Errorf("invalid queue index %s: %v", filepath.Join(q.dir, IndexFile), err)
+		}
+		for _, job := range index.Jobs {
+			q.jobs[job.Name] = job
+		}
+	}
+
+	for _, state := range append([]string{StateQueued}, states...) {
+		names, err := q.files(state)
+		if err != nil {
+			return err
+		}
+		for _, name := range names {
+			job := q.job(name, state)
+			job.State, job.Path = state, q.path(name, state)
+		}
+	}
+	// Nothing of a job being validated was signed: it goes back in line
+	for _, job := range q.Jobs() {
+		if job.State == StateValidating {
+			if err := q.move(q.jobs[job.Name], StateQueued, nil); err != nil {
+				return err
+			}
+		}
+	}
+	return q.save()
+}
+
+// job returns the job named name found in state, a new one if the index has none or only a job of that name that is over
+func (q *Queue) job(name string, state string) *Job {
+	job := q.jobs[name]
+	if job == nil || job.Final() && job.State != state {
+		job = &Job{Name: name, State: state, UpdatedAt: time.Now().UTC()}
+		q.jobs[name] = job
+	}
+	return job
+}
+
+// path returns the file of a job in state
+func (q *Queue) path(name string, state string) string {
+	if state == StateQueued {
+		return filepath.Join(q.dir, name)
+	}
+	return filepath.Join(q.dir, state, name)
+}
+
+// files lists the job files in the directory of state, oldest first; hidden and temporary files are being written
+func (q *Queue) files(state string) ([]string, error) {
+	dir := q.dir
+	if state != StateQueued {
+		dir = filepath.Join(q.dir, state)
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	type file struct {
+		name    string
+		modTime time.Time
+	}
+	var found []file
+	for _, entry := range entries {
+		name := entry.Name()
+		if !entry.Type().IsRegular() || strings.HasPrefix(name, ".") || strings.HasSuffix(name, ".tmp") || state == StateQueued && name == IndexFile {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			continue
+		}
+		found = append(found, file{name, info.ModTime()})
+	}
+	sort.SliceStable(found, func(i, j int) bool {
+		if !found[i].modTime.Equal(found[j].modTime) {
+			return found[i].modTime.Before(found[j].modTime)
+		}
+		return found[i].name < found[j].name
+	})
+	names := make([]string, len(found))
+	for i, f := range found {
+		names[i] = f.name
+	}
+	return names, nil
+}
+
+// save writes the state index durably
+func (q *Queue) save() error {
+	index := struct {
+		Jobs []Job `json:"jobs"`
+	}{Jobs: q.Jobs()}
+	data, err := json.MarshalIndent(index, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := fileutil.WriteAtomic(filepath.Join(q.dir, IndexFile), data, true); err != nil {
+		return fmt.Errorf("failed to save queue index: %v", err)
+	}
+	return nil
+}
+
+// move moves the file of a job to the directory of state, then records it in the index with cause as its last error
+func (q *Queue) move(job *Job, state string, cause error) error {
+	to := q.path(job.Name, state)
+	if job.Path != to {
+		if err := fileutil.Move(job.Path, to); err != nil {
+			return fmt.Errorf("failed to move job %s to %s: %v", job.Name, state, err)
+		}
+		if err := fileutil.SyncDir(filepath.Dir(to)); err != nil {
+			return err
+		}
+	}
+	job.State, job.Path, job.UpdatedAt = state, to, time.Now().UTC()
+	job.LastError = ""
+	if cause != nil {
+		job.LastError = cause.Error()
+	}
+	if q.Log != nil {
+		line := fmt.Sprintf("Job %s: %s", job.Name, state)
+		if cause != nil {
+			line += fmt.Sprintf(" (%v)", cause)
+		}
+		q.Log(line)
+	}
+	return q.save()
+}
+
+/*
+ * next returns the job to run: the oldest job left in submitted/ by an
+ * earlier step, then the oldest queued job; nil when there is none
+ *
+ * The queue directory is listed again each time, to see the jobs dropped
+ * since.
+ */
+func (q *Queue) next() (*Job, error) {
+	for _, state := range []string{StateSubmitted, StateQueued} {
+		names, err := q.files(state)
+		if err != nil {
+			return nil, err
+		}
+		if len(names) > 0 {
+			job := q.job(names[0], state)
+			job.State, job.Path = state, q.path(names[0], state)
+			return job, nil
+		}
+	}
+	return nil, nil
+}
+
+// failed counts a failed check of job, a queued one going back in line or parked, a submitted one staying or failed, past MaxAttempts
+func (q *Queue) failed(job *Job, from string, err error) error {
+	if errors.Is(err, ErrWait) {
+		return q.move(job, from, err)
+	}
+	job.Attempts++
+	if job.Attempts < q.MaxAttempts {
+		return q.move(job, from, fmt.Errorf("attempt %d of %d: %v", job.Attempts, q.MaxAttempts, err))
+	}
+	if from == StateSubmitted {
+		return q.move(job, StateFailed, err)
+	}
+	return q.move(job, StateParked, err)
+}
+
+/*
+ * Step runs the next job as far as it can go now, and returns it, or nil
+ * when the queue is empty
+ *
+ * A queued job is validated in validating/, then moved to submitted/ and
+ * paid; a job found in submitted/ is validated and paid there. The job
+ * returned is confirmed, failed or parked, or back where it was to be tried
+ * again later. The error is only for the queue files: the errors of the
+ * Handler end up in the job. A job cut short by the end of ctx stays where
+ * it is, for the next run to resume.
+ */
+func (q *Queue) Step(ctx context.Context, h Handler) (*Job, error) {
+	job, err := q.next()
+	if job == nil || err != nil {
+		return nil, err
+	}
+	from := job.State
+	if from == StateQueued {
+		if err := q.move(job, StateValidating, nil); err != nil {
+			return job, err
+		}
+	}
+	if err := h.Validate(ctx, job); err != nil {
+		if ctx.Err() != nil {
+			return job, q.move(job, from, err)
+		}
+		return job, q.failed(job, from, err)
+	}
+	if err := q.move(job, StateSubmitted, nil); err != nil {
+		return job, err
+	}
+
+	err = h.Pay(ctx, job)
+	switch {
+	case err == nil:
+		return job, q.move(job, StateConfirmed, nil)
+	case ctx.Err() != nil || errors.Is(err, ErrWait) || errors.Is(err, ErrUnconfirmed):
+		return job, q.move(job, StateSubmitted, err)
+	default:
+		return job, q.move(job, StateFailed, err)
+	}
+}
+
+/*
+ * Drain runs Step until ctx ends, waiting poll between two steps when the
+ * queue is empty or a job could not go further; ctx ending is not an error
+ *
+ * The queue is drained in order: a job to be tried again later is still
+ * the next one.
+ */
+func (q *Queue) Drain(ctx context.Context, h Handler, poll time.Duration) error {
+	for ctx.Err() == nil {
+		job, err := q.Step(ctx, h)
+		if err != nil {
+			return err
+		}
+		if job != nil && job.Final() {
+			continue
+		}
+		select {
+		case <-ctx.Done():
+		case <-time.After(poll):
+		}
+	}
+	return nil
+}
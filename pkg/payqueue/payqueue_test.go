@@ -0,0 +1,272 @@
+package payqueue
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/NickP005/Vindax-MCM-tools/pkg/fileutil"
+)
+
+// scripted is a Handler answering from a script per job name, one answer per call; a job without answers left succeeds
+type scripted struct {
+	validate map[string][]error
+	pay      map[string][]error
+	// calls lists "validate a.csv", "pay a.csv"... in order
+	calls []string
+	// cancel, if set, is called by Pay on the job named cancelOn, as a process stopping mid-payout
+	cancel   func()
+	cancelOn string
+}
+
+func (h *scripted) next(answers map[string][]error, name string) error {
+	if len(answers[name]) == 0 {
+		return nil
+	}
+	err := answers[name][0]
+	answers[name] = answers[name][1:]
+	return err
+}
+
+func (h *scripted) Validate(ctx context.Context, job *Job) error {
+	h.calls = append(h.calls, "validate "+job.Name)
+	if _, err := os.Stat(job.Path); err != nil {
+		return fmt.Errorf("validating a job not at its path: %v", err)
+	}
+	return h.next(h.validate, job.Name)
+}
+
+func (h *scripted) Pay(ctx context.Context, job *Job) error {
+	h.calls = append(h.calls, "pay "+job.Name)
+	if job.State != StateSubmitted || filepath.Base(filepath.Dir(job.Path)) != StateSubmitted {
+		return fmt.Errorf("paying a job %s at %s", job.State, job.Path)
+	}
+	if h.cancel != nil && job.Name == h.cancelOn {
+		h.cancel()
+		return ctx.Err()
+	}
+	return h.next(h.pay, job.Name)
+}
+
+// dropJobs writes job files in dir, in line in the order given
+func dropJobs(t *testing.T, dir string, names ...string) {
+	t.Helper()
+	start := time.Now().Add(-time.Hour)
+	for i, name := range names {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(name), 0644); err != nil {
+			t.Fatal(err)
+		}
+		at := start.Add(time.Duration(i) * time.Second)
+		if err := os.Chtimes(path, at, at); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+// checkJobs compares the jobs of the index, by name, with "state attempts" each, and checks each file is in the directory of its state
+func checkJobs(t *testing.T, q *Queue, want map[string]string) {
+	t.Helper()
+	jobs := q.Jobs()
+	if len(jobs) != len(want) {
+		t.Errorf("%d jobs, want %d: %+v", len(jobs), len(want), jobs)
+	}
+	for _, job := range jobs {
+		if got := fmt.Sprintf("%s %d", job.State, job.Attempts); got != want[job.Name] {
+			t.Errorf("job %s: %s (%s), want %s", job.Name, got, job.LastError, want[job.Name])
+		}
+		if job.Path != q.path(job.Name, job.State) {
+			t.Errorf("job %s %s at %s", job.Name, job.State, job.Path)
+		}
+		if _, err := os.Stat(job.Path); err != nil {
+			t.Errorf("job %s: %v", job.Name, err)
+		}
+	}
+}
+
+// drain steps q until it is empty or a job could not go further
+func drain(t *testing.T, q *Queue, h Handler) {
+	t.Helper()
+	for i := 0; i < 100; i++ {
+		job, err := q.Step(context.Background(), h)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if job == nil || !job.Final() {
+			return
+		}
+	}
+	t.Fatal("the queue never emptied")
+}
+
+/*
+ * TestStep drains jobs oldest first: one paid, one poison parked after its
+ * attempts, one failing once signed, one waiting for funds, none of the
+ * waits counting as an attempt
+ */
+func TestStep(t *testing.T) {
+	dir := t.TempDir()
+	dropJobs(t, dir, "paid.csv", "poison.csv", "failing.json", "waiting.csv")
+	// Files being written are not jobs yet
+	os.WriteFile(filepath.Join(dir, ".late.csv"), nil, 0644)
+	os.WriteFile(filepath.Join(dir, "late.csv.tmp"), nil, 0644)
+	q, err := Open(dir, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer q.Close()
+	var log []string
+	q.Log = func(line string) { log = append(log, line) }
+	bad := errors.New("line 1: invalid address")
+	h := &scripted{
+		validate: map[string][]error{
+			"paid.csv":    {fmt.Errorf("%w: balance lookup failed", ErrWait)},
+			"poison.csv":  {bad, bad, bad},
+			"waiting.csv": {fmt.Errorf("%w: the wallet holds 10 nMCM", ErrWait), fmt.Errorf("%w: the wallet holds 20 nMCM", ErrWait)},
+		},
+		pay: map[string][]error{
+			"paid.csv":     {fmt.Errorf("%w: transaction 0a", ErrUnconfirmed)},
+			"failing.json": {errors.New("payout failed with exit code 1")},
+		},
+	}
+	checkJobs(t, q, map[string]string{"paid.csv": "queued 0", "poison.csv": "queued 0", "failing.json": "queued 0", "waiting.csv": "queued 0"})
+
+	// The oldest job goes first, and a job that waits stays first in line
+	drain(t, q, h)
+	drain(t, q, h)
+	checkJobs(t, q, map[string]string{"paid.csv": "submitted 0", "poison.csv": "queued 0", "failing.json": "queued 0", "waiting.csv": "queued 0"})
+	if job := q.jobs["paid.csv"]; !strings.Contains(job.LastError, "transaction 0a") {
+		t.Errorf("last error %q", job.LastError)
+	}
+	drain(t, q, h)
+	checkJobs(t, q, map[string]string{"paid.csv": "confirmed 0", "poison.csv": "queued 1", "failing.json": "queued 0", "waiting.csv": "queued 0"})
+	drain(t, q, h)
+	if job := q.jobs["poison.csv"]; job.Attempts != 2 || job.LastError != "attempt 2 of 3: line 1: invalid address" {
+		t.Errorf("poison.csv after %d attempts: %q", job.Attempts, job.LastError)
+	}
+	// The last attempt parks it, and the next jobs go on
+	drain(t, q, h)
+	checkJobs(t, q, map[string]string{"paid.csv": "confirmed 0", "poison.csv": "parked 3", "failing.json": "failed 0", "waiting.csv": "queued 0"})
+	drain(t, q, h)
+	drain(t, q, h)
+	checkJobs(t, q, map[string]string{"paid.csv": "confirmed 0", "poison.csv": "parked 3", "failing.json": "failed 0", "waiting.csv": "confirmed 0"})
+	if job, err := q.Step(context.Background(), h); err != nil || job != nil {
+		t.Errorf("empty queue: %+v, %v", job, err)
+	}
+
+	want := []string{
+		"validate paid.csv", "validate paid.csv", "pay paid.csv", "validate paid.csv", "pay paid.csv",
+		"validate poison.csv", "validate poison.csv", "validate poison.csv",
+		"validate failing.json", "pay failing.json",
+		"validate waiting.csv", "validate waiting.csv", "validate waiting.csv", "pay waiting.csv",
+	}
+	if strings.Join(h.calls, ", ") != strings.Join(want, ", ") {
+		t.Errorf("calls:\n%s\nwant:\n%s", strings.Join(h.calls, "\n"), strings.Join(want, "\n"))
+	}
+	if !strings.Contains(strings.Join(log, "\n"), "Job poison.csv: parked (line 1: invalid address)") {
+		t.Errorf("log:\n%s", strings.Join(log, "\n"))
+	}
+	for _, name := range []string{".late.csv", "late.csv.tmp"} {
+		if _, err := os.Stat(filepath.Join(dir, name)); err != nil {
+			t.Errorf("%s moved: %v", name, err)
+		}
+	}
+}
+
+// TestDrain stops when ctx ends, a job cut short by it left where it was
+func TestDrain(t *testing.T) {
+	dir := t.TempDir()
+	dropJobs(t, dir, "a.csv", "b.csv")
+	q, err := Open(dir, DefaultMaxAttempts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer q.Close()
+	ctx, cancel := context.WithCancel(context.Background())
+	h := &scripted{cancel: cancel, cancelOn: "b.csv"}
+	if err := q.Drain(ctx, h, time.Millisecond); err != nil {
+		t.Fatal(err)
+	}
+	checkJobs(t, q, map[string]string{"a.csv": "confirmed 0", "b.csv": "submitted 0"})
+}
+
+/*
+ * TestOpenRestart reopens a queue as a restarted process would: a job left
+ * validating goes back in line, and one left submitted is resumed before
+ * any queued job, its attempts kept from the index
+ */
+func TestOpenRestart(t *testing.T) {
+	dir := t.TempDir()
+	dropJobs(t, dir, "a.csv", "b.csv", "c.csv")
+	q, err := Open(dir, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// b.csv fails a check once signing started, then the process stops while paying it
+	q.Step(context.Background(), &scripted{})
+	ctx, cancel := context.WithCancel(context.Background())
+	q.Step(ctx, &scripted{pay: map[string][]error{"b.csv": {fmt.Errorf("%w: transaction 0b", ErrUnconfirmed)}}})
+	q.Step(ctx, &scripted{validate: map[string][]error{"b.csv": {errors.New("balance changed")}}})
+	q.Step(ctx, &scripted{cancel: cancel, cancelOn: "b.csv"})
+	checkJobs(t, q, map[string]string{"a.csv": "confirmed 0", "b.csv": "submitted 1", "c.csv": "queued 0"})
+	q.Close()
+	// c.csv was being validated when it stopped
+	os.Rename(filepath.Join(dir, "c.csv"), filepath.Join(dir, StateValidating, "c.csv"))
+
+	if q, err = Open(dir, 2); err != nil {
+		t.Fatal(err)
+	}
+	defer q.Close()
+	checkJobs(t, q, map[string]string{"a.csv": "confirmed 0", "b.csv": "submitted 1", "c.csv": "queued 0"})
+	h := &scripted{validate: map[string][]error{"b.csv": {errors.New("balance changed")}}}
+	drain(t, q, h)
+	drain(t, q, h)
+	checkJobs(t, q, map[string]string{"a.csv": "confirmed 0", "b.csv": "failed 2", "c.csv": "confirmed 0"})
+	if want := "validate b.csv, validate c.csv, pay c.csv"; strings.Join(h.calls, ", ") != want {
+		t.Errorf("calls %q, want %q", h.calls, want)
+	}
+
+	// The index is the one the files tell
+	data, err := os.ReadFile(filepath.Join(dir, IndexFile))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var index struct {
+		Jobs []Job `json:"jobs"`
+	}
+	if err := json.Unmarshal(data, &index); err != nil || len(index.Jobs) != 3 || index.Jobs[1].State != StateFailed || index.Jobs[1].LastError != "balance changed" {
+		t.Errorf("index %s, %v", data, err)
+	}
+
+	// A name used again once its job is over is a new job
+	dropJobs(t, dir, "a.csv")
+	drain(t, q, &scripted{validate: map[string][]error{"a.csv": {errors.New("empty")}}})
+	checkJobs(t, q, map[string]string{"a.csv": "queued 1", "b.csv": "failed 2", "c.csv": "confirmed 0"})
+}
+
+// TestOpenLocked refuses a second drain of the same queue until the first one closes it
+func TestOpenLocked(t *testing.T) {
+	dir := t.TempDir()
+	q, err := Open(dir, DefaultMaxAttempts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if q.lock == nil {
+		t.Skip("no file lock on this system")
+	}
+	if _, err := Open(dir, DefaultMaxAttempts); !errors.Is(err, fileutil.ErrLocked) {
+		t.Errorf("second open: %v", err)
+	}
+	q.Close()
+	q, err = Open(dir, DefaultMaxAttempts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	q.Close()
+}
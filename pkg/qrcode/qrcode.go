@@ -0,0 +1,449 @@
+/*
+ * Package qrcode encodes short text, such as an address, as a QR code
+ *
+ * Only what the tools need is implemented: byte mode, error correction
+ * level M (15% of the code can be damaged), and versions 1 to 10, i.e. up
+ * to 213 bytes. The mask is chosen by the penalty rules of ISO/IEC 18004.
+ */
+package qrcode
+
+import (
+	"fmt"
+	"strings"
+)
+
+// MaxLength is the longest text Encode accepts, the byte mode capacity of version 10 at level M
+const MaxLength = 213
+
+// QuietZone is the light border, in modules, readers need around the code
+const QuietZone = 4
+
+// block layout of a version at level M: data codewords per block and error correction codewords per block
+type version struct {
+	ecPerBlock int
+	// blocks lists the data codewords of each block, the short blocks first
+	blocks    []int
+	alignment []int
+}
+
+// versions at level M, indexed by version number - 1
+var versions = []version{
+	{10, []int{16}, nil},
+	{16, []int{28}, []int{6, 18}},
+	{26, []int{44}, []int{6, 22}},
+	{18, []int{32, 32}, []int{6, 26}},
+	{24, []int{43, 43}, []int{6, 30}},
+	{16, []int{27, 27, 27, 27}, []int{6, 34}},
+	{18, []int{31, 31, 31, 31}, []int{6, 22, 38}},
+	{22, []int{38, 38, 39, 39}, []int{6, 24, 42}},
+	{22, []int{36, 36, 36, 37, 37}, []int{6, 26, 46}},
+	{26, []int{43, 43, 43, 43, 44}, []int{6, 28, 50}},
+}
+
+// dataCodewords returns the number of data codewords of a version
+func (v version) dataCodewords() int {
+	total := 0
+	for _, n := range v.blocks {
+		total += n
+	}
+	return total
+}
+
+// Code is an encoded QR code, a square of Size modules
+type Code struct {
+	Version int
+	Size    int
+	Mask    int
+	modules [][]bool
+	// function marks the modules of the finder, timing, alignment, format and version patterns
+	function [][]bool
+}
+
+// Dark reports whether the module at column x and row y is dark; modules outside the code are light
+func (c *Code) Dark(x int, y int) bool {
+	return x >= 0 && y >= 0 && x < c.Size && y < c.Size && c.modules[y][x]
+}
+
+/*
+ * Encode returns the QR code of text, in the smallest version that holds it
+ *
+ * Returns an error for text longer than MaxLength bytes.
+ */
+func Encode(text string) (*Code, error) {
+	return encode([]byte(text), -1)
+}
+
+// encode builds the code of data with a mask, or the mask of least penalty when mask < 0
+func encode(data []byte, mask int) (*Code, error) {
+	number := 0
+	for i, v := range versions {
+		// 4 bits of mode, 8 or 16 bits of length, then the data
+		if 4+countBits(i+1)+8*len(data) <= 8*v.dataCodewords() {
+			number = i + 1
+			break
+		}
+	}
+	if number == 0 {
+		return nil, fmt.Errorf("text of %d bytes, a QR code holds at most %d", len(data), MaxLength)
+	}
+	v := versions[number-1]
+
+	codewords := interleave(v, dataCodewords(v, number, data))
+	code := &Code{Version: number, Size: 17 + 4*number}
+	code.modules = grid(code.Size)
+	code.function = grid(code.Size)
+	code.drawFunctionPatterns(v)
+	code.drawCodewords(codewords)
+
+	if mask < 0 {
+		best := -1
+		for candidate := 0; candidate < 8; candidate++ {
+			code.applyMask(candidate)
+			code.drawFormat(candidate)
+			if penalty := code.penalty(); best < 0 || penalty < best {
+				best, mask = penalty, candidate
+			}
+			code.applyMask(candidate) // masks are XORs, applying again undoes them
+		}
+	}
+	code.Mask = mask
+	code.applyMask(mask)
+	code.drawFormat(mask)
+	return code, nil
+}
+
+// grid returns a size x size matrix of light modules
+func grid(size int) [][]bool {
+	rows := make([][]bool, size)
+	for y := range rows {
+		rows[y] = make([]bool, size)
+	}
+	return rows
+}
+
+// countBits returns the length in bits of the byte count of a version
+func countBits(number int) int {
+	if number < 10 {
+		return 8
+	}
+	return 16
+}
+
+// dataCodewords returns the data of a version: mode, count, bytes, terminator and padding
+func dataCodewords(v version, number int, data []byte) []byte {
+	var bits []bool
+	appendBits := func(value int, length int) {
+		for i := length - 1; i >= 0; i-- {
+			bits = append(bits, value>>i&1 == 1)
+		}
+	}
+	appendBits(0b0100, 4)
+	appendBits(len(data), countBits(number))
+	for _, b := range data {
+		appendBits(int(b), 8)
+	}
+
+	capacity := 8 * v.dataCodewords()
+	appendBits(0, min(4, capacity-len(bits)))
+	appendBits(0, (8-len(bits)%8)%8)
+	for pad := 0xEC; len(bits) < capacity; pad ^= 0xEC ^ 0x11 {
+		appendBits(pad, 8)
+	}
+
+	codewords := make([]byte, len(bits)/8)
+	for i, bit := range bits {
+		if bit {
+			codewords[i/8] |= 0x80 >> (i % 8)
+		}
+	}
+	return codewords
+}
+
+// interleave splits the data in blocks, adds their error correction, and interleaves them
+func interleave(v version, data []byte) []byte {
+	divisor := rsGenerator(v.ecPerBlock)
+	var blocks, ecBlocks [][]byte
+	for _, n := range v.blocks {
+		blocks = append(blocks, data[:n])
+		ecBlocks = append(ecBlocks, rsRemainder(data[:n], divisor))
+		data = data[n:]
+	}
+
+	var result []byte
+	longest := v.blocks[len(v.blocks)-1]
+	for i := 0; i < longest; i++ {
+		for _, block := range blocks {
+			if i < len(block) {
+				result = append(result, block[i])
+			}
+		}
+	}
+	for i := 0; i < v.ecPerBlock; i++ {
+		for _, block := range ecBlocks {
+			result = append(result, block[i])
+		}
+	}
+	return result
+}
+
+// setFunction sets a module of a function pattern
+func (c *Code) setFunction(x int, y int, dark bool) {
+	c.modules[y][x] = dark
+	c.function[y][x] = true
+}
+
+// drawFunctionPatterns draws the finder, timing and alignment patterns, and reserves the format and version areas
+func (c *Code) drawFunctionPatterns(v version) {
+	for i := 0; i < c.Size; i++ {
+		c.setFunction(6, i, i%2 == 0)
+		c.setFunction(i, 6, i%2 == 0)
+	}
+
+	// Finder patterns with their separators, clipped at the edges
+	for _, corner := range [][2]int{{3, 3}, {c.Size - 4, 3}, {3, c.Size - 4}} {
+		for dy := -4; dy <= 4; dy++ {
+			for dx := -4; dx <= 4; dx++ {
+				x, y := corner[0]+dx, corner[1]+dy
+				if x < 0 || y < 0 || x >= c.Size || y >= c.Size {
+					continue
+				}
+				distance := max(abs(dx), abs(dy))
+				c.setFunction(x, y, distance != 2 && distance != 4)
+			}
+		}
+	}
+
+	// Alignment patterns, except the three overlapping the finders
+	last := len(v.alignment) - 1
+	for i, cy := range v.alignment {
+		for j, cx := range v.alignment {
+			if (i == 0 && j == 0) || (i == 0 && j == last) || (i == last && j == 0) {
+				continue
+			}
+			for dy := -2; dy <= 2; dy++ {
+				for dx := -2; dx <= 2; dx++ {
+					c.setFunction(cx+dx, cy+dy, max(abs(dx), abs(dy)) != 1)
+				}
+			}
+		}
+	}
+
+	// Format areas are drawn with the mask; drawing them light now marks them as function modules
+	c.drawFormat(0)
+
+	if c.Version >= 7 {
+		remainder := c.Version
+		for i := 0; i < 12; i++ {
+			remainder = remainder<<1 ^ (remainder>>11)*0x1F25
+		}
+		bits := c.Version<<12 | remainder
+		for i := 0; i < 18; i++ {
+			dark := bits>>i&1 == 1
+			a, b := c.Size-11+i%3, i/3
+			c.setFunction(a, b, dark)
+			c.setFunction(b, a, dark)
+		}
+	}
+}
+
+// drawFormat draws both copies of the format information: level M and the mask, with their BCH code
+func (c *Code) drawFormat(mask int) {
+	data := 0b00<<3 | mask // 00 is level M
+	remainder := data
+	for i := 0; i < 10; i++ {
+		remainder = remainder<<1 ^ (remainder>>9)*0x537
+	}
+	bits := (data<<10 | remainder) ^ 0x5412
+	bit := func(i int) bool { return bits>>i&1 == 1 }
+
+	for i := 0; i <= 5; i++ {
+		c.setFunction(8, i, bit(i))
+	}
+	c.setFunction(8, 7, bit(6))
+	c.setFunction(8, 8, bit(7))
+	c.setFunction(7, 8, bit(8))
+	for i := 9; i < 15; i++ {
+		c.setFunction(14-i, 8, bit(i))
+	}
+
+	for i := 0; i < 8; i++ {
+		c.setFunction(c.Size-1-i, 8, bit(i))
+	}
+	for i := 8; i < 15; i++ {
+		c.setFunction(8, c.Size-15+i, bit(i))
+	}
+	c.setFunction(8, c.Size-8, true) // the dark module
+}
+
+// drawCodewords places the codewords in the zigzag order, two columns at a time from the bottom right
+func (c *Code) drawCodewords(codewords []byte) {
+	i := 0
+	for right := c.Size - 1; right >= 1; right -= 2 {
+		if right == 6 {
+			right = 5 // the vertical timing pattern
+		}
+		upward := (right+1)&2 == 0
+		for vertical := 0; vertical < c.Size; vertical++ {
+			y := vertical
+			if upward {
+				y = c.Size - 1 - vertical
+			}
+			for dx := 0; dx < 2; dx++ {
+				x := right - dx
+				if c.function[y][x] {
+					continue
+				}
+				// The modules left after the last codeword are the light remainder bits
+				if i < len(codewords)*8 {
+					c.modules[y][x] = codewords[i/8]>>(7-i%8)&1 == 1
+					i++
+				}
+			}
+		}
+	}
+}
+
+// applyMask flips the data modules selected by a mask pattern
+func (c *Code) applyMask(mask int) {
+	for y := 0; y < c.Size; y++ {
+		for x := 0; x < c.Size; x++ {
+			var flip bool
+			switch mask {
+			case 0:
+				flip = (x+y)%2 == 0
+			case 1:
+				flip = y%2 == 0
+			case 2:
+				flip = x%3 == 0
+			case 3:
+				flip = (x+y)%3 == 0
+			case 4:
+				flip = (x/3+y/2)%2 == 0
+			case 5:
+				flip = x*y%2+x*y%3 == 0
+			case 6:
+				flip = (x*y%2+x*y%3)%2 == 0
+			case 7:
+				flip = ((x+y)%2+x*y%3)%2 == 0
+			}
+			if flip && !c.function[y][x] {
+				c.modules[y][x] = !c.modules[y][x]
+			}
+		}
+	}
+}
+
+/*
+ * penalty scores how hard the code is to read, the lower the better: runs
+ * of 5 or more modules of one color, 2x2 blocks of one color, patterns
+ * looking like a finder, and an unbalanced proportion of dark modules
+ */
+func (c *Code) penalty() int {
+	penalty := 0
+	line := func(get func(i int) bool) {
+		run := 1
+		for i := 1; i <= c.Size; i++ {
+			if i < c.Size && get(i) == get(i-1) {
+				run++
+				continue
+			}
+			if run >= 5 {
+				penalty += run - 2
+			}
+			run = 1
+		}
+		// 1:1:3:1:1 dark-light pattern with 4 light modules on either side
+		finder := []bool{true, false, true, true, true, false, true}
+		for i := 0; i+7 <= c.Size; i++ {
+			matches := true
+			for k, dark := range finder {
+				matches = matches && get(i+k) == dark
+			}
+			if !matches {
+				continue
+			}
+			lightBefore, lightAfter := true, true
+			for k := 1; k <= 4; k++ {
+				lightBefore = lightBefore && (i-k < 0 || !get(i-k))
+				lightAfter = lightAfter && (i+6+k >= c.Size || !get(i+6+k))
+			}
+			if lightBefore || lightAfter {
+				penalty += 40
+			}
+		}
+	}
+	for y := 0; y < c.Size; y++ {
+		line(func(x int) bool { return c.modules[y][x] })
+	}
+	for x := 0; x < c.Size; x++ {
+		line(func(y int) bool { return c.modules[y][x] })
+	}
+
+	dark := 0
+	for y := 0; y < c.Size; y++ {
+		for x := 0; x < c.Size; x++ {
+			if c.modules[y][x] {
+				dark++
+			}
+			if x+1 < c.Size && y+1 < c.Size {
+				color := c.modules[y][x]
+				if c.modules[y][x+1] == color && c.modules[y+1][x] == color && c.modules[y+1][x+1] == color {
+					penalty += 3
+				}
+			}
+		}
+	}
+	total := c.Size * c.Size
+	// 10 points per 5% away from half dark
+	deviation := abs(dark*20-total*10) / total
+	penalty += deviation * 10
+	return penalty
+}
+
+/*
+ * Text renders the code with two characters per module, full blocks for
+ * the dark ones, so it prints about square in a monospaced font
+ *
+ * The quiet zone is included. Dark modules must print dark: the text is
+ * meant for paper or a light background, a terminal with a dark background
+ * shows a negative that some readers reject.
+ */
+func (c *Code) Text() string {
+	var b strings.Builder
+	for y := -QuietZone; y < c.Size+QuietZone; y++ {
+		for x := -QuietZone; x < c.Size+QuietZone; x++ {
+			if c.Dark(x, y) {
+				b.WriteString("██")
+			} else {
+				b.WriteString("  ")
+			}
+		}
+		b.WriteString("\n")
+	}
+	return b.String()
+}
+
+// SVG renders the code as an SVG image of scale pixels per module, the quiet zone included
+func (c *Code) SVG(scale int) string {
+	side := (c.Size + 2*QuietZone) * scale
+	var b strings.Builder
+	fmt.Fprintf(&b, `<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d" viewBox="0 0 %d %d" shape-rendering="crispEdges">`,
+		side, side, c.Size+2*QuietZone, c.Size+2*QuietZone)
+	fmt.Fprintf(&b, `<rect width="100%%" height="100%%" fill="#fff"/><path fill="#000" d="`)
+	for y := 0; y < c.Size; y++ {
+		for x := 0; x < c.Size; x++ {
+			if c.modules[y][x] {
+				fmt.Fprintf(&b, "M%d %dh1v1h-1z", x+QuietZone, y+QuietZone)
+			}
+		}
+	}
+	b.WriteString(`"/></svg>`)
+	return b.String()
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
@@ -0,0 +1,279 @@
+package qrcode
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+// TestReedSolomon checks the error correction of the 1-M "HELLO WORLD" example of the QR code literature
+func TestReedSolomon(t *testing.T) {
+	data := []byte{32, 91, 11, 120, 209, 114, 220, 77, 67, 64, 236, 17, 236, 17, 236, 17}
+	want := []byte{196, 35, 39, 119, 235, 215, 231, 226, 93, 23}
+	if got := rsRemainder(data, rsGenerator(10)); !bytes.Equal(got, want) {
+		t.Errorf("error correction %v, want %v", got, want)
+	}
+}
+
+// formatBits are the published format strings of level M, by mask
+var formatBits = []int{
+	0b101010000010010, 0b101000100100101, 0b101111001111100, 0b101101101001011,
+	0b100010111111001, 0b100000011001110, 0b100111110010111, 0b100101010100000,
+}
+
+// readFormat returns both copies of the format information of a code
+func readFormat(c *Code) (int, int) {
+	var first, second int
+	bit := func(bits *int, i int, x int, y int) {
+		if c.Dark(x, y) {
+			*bits |= 1 << i
+		}
+	}
+	for i := 0; i <= 5; i++ {
+		bit(&first, i, 8, i)
+	}
+	bit(&first, 6, 8, 7)
+	bit(&first, 7, 8, 8)
+	bit(&first, 8, 7, 8)
+	for i := 9; i < 15; i++ {
+		bit(&first, i, 14-i, 8)
+	}
+	for i := 0; i < 8; i++ {
+		bit(&second, i, c.Size-1-i, 8)
+	}
+	for i := 8; i < 15; i++ {
+		bit(&second, i, 8, c.Size-15+i)
+	}
+	return first, second
+}
+
+// checkPatterns checks the finder and timing patterns, the dark module and, from version 7, the version information
+func checkPatterns(t *testing.T, c *Code) {
+	t.Helper()
+	for _, corner := range [][2]int{{0, 0}, {c.Size - 7, 0}, {0, c.Size - 7}} {
+		for dy := -1; dy <= 7; dy++ {
+			for dx := -1; dx <= 7; dx++ {
+				x, y := corner[0]+dx, corner[1]+dy
+				if x < 0 || y < 0 || x >= c.Size || y >= c.Size {
+					continue
+				}
+				ring := max(abs(dx-3), abs(dy-3))
+				if c.Dark(x, y) != (ring != 2 && ring != 4) {
+					t.Fatalf("version %d: finder module %d,%d", c.Version, x, y)
+				}
+			}
+		}
+	}
+	for i := 8; i < c.Size-8; i++ {
+		if c.Dark(6, i) != (i%2 == 0) || c.Dark(i, 6) != (i%2 == 0) {
+			t.Fatalf("version %d: timing module %d", c.Version, i)
+		}
+	}
+	if !c.Dark(8, c.Size-8) {
+		t.Fatalf("version %d: no dark module", c.Version)
+	}
+	if c.Version < 7 {
+		return
+	}
+	// The published version information strings of versions 7 to 10
+	want := map[int]int{7: 0x07C94, 8: 0x085BC, 9: 0x09A99, 10: 0x0A4D3}[c.Version]
+	var bottom, right int
+	for i := 0; i < 18; i++ {
+		a, b := c.Size-11+i%3, i/3
+		if c.Dark(a, b) {
+			bottom |= 1 << i
+		}
+		if c.Dark(b, a) {
+			right |= 1 << i
+		}
+	}
+	if bottom != want || right != want {
+		t.Fatalf("version %d: version information %x and %x", c.Version, bottom, right)
+	}
+}
+
+/*
+ * decode reads a code back as a reader does: format, unmasking, codewords
+ * in zigzag order, blocks, error correction, and the byte mode segment
+ */
+func decode(t *testing.T, c *Code) []byte {
+	t.Helper()
+	first, second := readFormat(c)
+	if first != second || first != formatBits[c.Mask] {
+		t.Fatalf("version %d mask %d: format %015b and %015b", c.Version, c.Mask, first, second)
+	}
+
+	var bits []bool
+	for right := c.Size - 1; right >= 1; right -= 2 {
+		if right == 6 {
+			right--
+		}
+		for vertical := 0; vertical < c.Size; vertical++ {
+			y := vertical
+			if (right+1)&2 == 0 {
+				y = c.Size - 1 - vertical
+			}
+			for x := right; x > right-2; x-- {
+				if c.function[y][x] {
+					continue
+				}
+				dark := c.Dark(x, y)
+				var flip bool
+				switch c.Mask {
+				case 0:
+					flip = (x+y)%2 == 0
+				case 1:
+					flip = y%2 == 0
+				case 2:
+					flip = x%3 == 0
+				case 3:
+					flip = (x+y)%3 == 0
+				case 4:
+					flip = (x/3+y/2)%2 == 0
+				case 5:
+					flip = x*y%2+x*y%3 == 0
+				case 6:
+					flip = (x*y%2+x*y%3)%2 == 0
+				case 7:
+					flip = ((x+y)%2+x*y%3)%2 == 0
+				}
+				bits = append(bits, dark != flip)
+			}
+		}
+	}
+	codewords := make([]byte, len(bits)/8)
+	for i := range codewords {
+		for _, bit := range bits[8*i : 8*i+8] {
+			codewords[i] <<= 1
+			if bit {
+				codewords[i] |= 1
+			}
+		}
+	}
+
+	v := versions[c.Version-1]
+	blocks := make([][]byte, len(v.blocks))
+	next := 0
+	for i := 0; i < v.blocks[len(v.blocks)-1]; i++ {
+		for b, n := range v.blocks {
+			if i < n {
+				blocks[b] = append(blocks[b], codewords[next])
+				next++
+			}
+		}
+	}
+	generator := rsGenerator(v.ecPerBlock)
+	var data []byte
+	for b, block := range blocks {
+		ec := make([]byte, v.ecPerBlock)
+		for i := range ec {
+			ec[i] = codewords[next+i*len(blocks)+b]
+		}
+		if !bytes.Equal(rsRemainder(block, generator), ec) {
+			t.Fatalf("version %d: block %d fails its error correction", c.Version, b)
+		}
+		data = append(data, block...)
+	}
+
+	read := func(offset int, length int) int {
+		value := 0
+		for i := offset; i < offset+length; i++ {
+			value = value<<1 | int(data[i/8]>>(7-i%8)&1)
+		}
+		return value
+	}
+	if mode := read(0, 4); mode != 0b0100 {
+		t.Fatalf("version %d: mode %04b", c.Version, mode)
+	}
+	count := read(4, countBits(c.Version))
+	text := make([]byte, count)
+	for i := range text {
+		text[i] = byte(read(4+countBits(c.Version)+8*i, 8))
+	}
+	return text
+}
+
+func TestEncodeDecode(t *testing.T) {
+	// Lengths at the capacity of each version, and one byte more
+	capacities := []int{14, 26, 42, 62, 84, 106, 122, 152, 180, 213}
+	for number, capacity := range capacities {
+		for _, length := range []int{capacity, capacity + 1} {
+			if length > MaxLength {
+				continue
+			}
+			text := strings.Repeat("0123456789abcdefXYZ", 12)[:length]
+			code, err := Encode(text)
+			if err != nil {
+				t.Fatal(err)
+			}
+			want := number + 1
+			if length > capacity {
+				want++
+			}
+			if code.Version != want || code.Size != 17+4*want {
+				t.Errorf("%d bytes: version %d, want %d", length, code.Version, want)
+			}
+			checkPatterns(t, code)
+			if got := decode(t, code); string(got) != text {
+				t.Errorf("%d bytes: decoded %q", length, got)
+			}
+		}
+	}
+
+	// Every mask decodes
+	address := "cr5m3GobqYe6BDY1jqdSNJMYsjADL5"
+	for mask := 0; mask < 8; mask++ {
+		code, err := encode([]byte(address), mask)
+		if err != nil || code.Mask != mask {
+			t.Fatalf("mask %d: %v", mask, err)
+		}
+		if got := decode(t, code); string(got) != address {
+			t.Errorf("mask %d: decoded %q", mask, got)
+		}
+	}
+}
+
+// TestEncodeMask checks that Encode keeps the mask of least penalty
+func TestEncodeMask(t *testing.T) {
+	address := "cr5m3GobqYe6BDY1jqdSNJMYsjADL5"
+	chosen, _ := Encode(address)
+	for mask := 0; mask < 8; mask++ {
+		code, _ := encode([]byte(address), mask)
+		if code.penalty() < chosen.penalty() {
+			t.Errorf("mask %d has a lower penalty than the chosen mask %d", mask, chosen.Mask)
+		}
+	}
+}
+
+func TestEncodeTooLong(t *testing.T) {
+	if _, err := Encode(strings.Repeat("x", MaxLength+1)); err == nil {
+		t.Error("text beyond MaxLength encoded")
+	}
+}
+
+func TestRender(t *testing.T) {
+	code, _ := Encode("MCM")
+	lines := strings.Split(strings.TrimSuffix(code.Text(), "\n"), "\n")
+	side := code.Size + 2*QuietZone
+	if len(lines) != side || len([]rune(lines[0])) != 2*side || strings.TrimSpace(lines[0]) != "" {
+		t.Fatalf("%d lines of %d characters", len(lines), len([]rune(lines[0])))
+	}
+	// The top left finder starts after the quiet zone
+	row := []rune(lines[QuietZone])
+	if string(row[2*QuietZone:2*QuietZone+14]) != strings.Repeat("█", 14) || row[2*QuietZone-1] != ' ' {
+		t.Errorf("finder row %q", string(row))
+	}
+
+	svg := code.SVG(6)
+	dark := 0
+	for y := 0; y < code.Size; y++ {
+		for x := 0; x < code.Size; x++ {
+			if code.Dark(x, y) {
+				dark++
+			}
+		}
+	}
+	if !strings.HasPrefix(svg, `<svg xmlns="http://www.w3.org/2000/svg" width="174" height="174" viewBox="0 0 29 29"`) || strings.Count(svg, "h1v1h-1z") != dark {
+		t.Errorf("SVG %.120s", svg)
+	}
+}
@@ -0,0 +1,44 @@
+package qrcode
+
+// gfMultiply multiplies in GF(2^8) modulo x^8 + x^4 + x^3 + x^2 + 1, the field of QR codes
+func gfMultiply(x byte, y byte) byte {
+	var product byte
+	for i := 7; i >= 0; i-- {
+		carry := product >> 7
+		product = product<<1 ^ carry*0x1D
+		product ^= (y >> i & 1) * x
+	}
+	return product
+}
+
+// rsGenerator returns the coefficients of the Reed-Solomon generator polynomial of a degree, the leading 1 omitted
+func rsGenerator(degree int) []byte {
+	coefficients := make([]byte, degree)
+	coefficients[degree-1] = 1
+	// Product of (x - 2^i) for i in [0, degree)
+	var root byte = 1
+	for i := 0; i < degree; i++ {
+		for j := range coefficients {
+			coefficients[j] = gfMultiply(coefficients[j], root)
+			if j+1 < degree {
+				coefficients[j] ^= coefficients[j+1]
+			}
+		}
+		root = gfMultiply(root, 0x02)
+	}
+	return coefficients
+}
+
+// rsRemainder returns the error correction codewords of data: its remainder by the generator polynomial
+func rsRemainder(data []byte, generator []byte) []byte {
+	remainder := make([]byte, len(generator))
+	for _, b := range data {
+		factor := b ^ remainder[0]
+		copy(remainder, remainder[1:])
+		remainder[len(remainder)-1] = 0
+		for i, coefficient := range generator {
+			remainder[i] ^= gfMultiply(coefficient, factor)
+		}
+	}
+	return remainder
+}
@@ -0,0 +1,26 @@
+package secure
+
+import "crypto/subtle"
+
+/*
+ * Equal reports whether a and b hold the same bytes, in time that depends
+ * only on their lengths
+ *
+ * Use it instead of bytes.Equal or == for keys, signatures and the address
+ * hashes derived from them.
+ */
+func Equal(a, b []byte) bool {
+	return subtle.ConstantTimeCompare(a, b) == 1
+}
+
+// Equal20 compares two 20 bytes address hashes (tags) in constant time
+func Equal20(a, b [20]byte) bool { return Equal(a[:], b[:]) }
+
+// Equal32 compares two 32 bytes keys or seeds in constant time
+func Equal32(a, b [32]byte) bool { return Equal(a[:], b[:]) }
+
+// Equal40 compares two 40 bytes tagged WOTS addresses in constant time
+func Equal40(a, b [40]byte) bool { return Equal(a[:], b[:]) }
+
+// Equal2144 compares two 2144 bytes WOTS public keys or signatures in constant time
+func Equal2144(a, b [2144]byte) bool { return Equal(a[:], b[:]) }
@@ -0,0 +1,123 @@
+package secure
+
+import (
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"io/fs"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestEqual(t *testing.T) {
+	for _, tc := range []struct {
+		a, b string
+		want bool
+	}{
+		{"", "", true},
+		{"abc", "abc", true},
+		{"abc", "abd", false},
+		{"abc", "ab", false},
+		{"", "a", false},
+	} {
+		if got := Equal([]byte(tc.a), []byte(tc.b)); got != tc.want {
+			t.Errorf("Equal(%q, %q) = %v", tc.a, tc.b, got)
+		}
+	}
+	if !Equal(nil, []byte{}) {
+		t.Error("nil and empty differ")
+	}
+}
+
+func TestEqualArrays(t *testing.T) {
+	var a20, b20 [20]byte
+	var a32, b32 [32]byte
+	var a40, b40 [40]byte
+	var a2144, b2144 [2144]byte
+	if !Equal20(a20, b20) || !Equal32(a32, b32) || !Equal40(a40, b40) || !Equal2144(a2144, b2144) {
+		t.Fatal("zero arrays differ")
+	}
+	// A difference in the last byte is found
+	b20[19], b32[31], b40[39], b2144[2143] = 1, 1, 1, 1
+	if Equal20(a20, b20) || Equal32(a32, b32) || Equal40(a40, b40) || Equal2144(a2144, b2144) {
+		t.Error("last byte difference not found")
+	}
+}
+
+// sensitiveNames are the identifiers of keys, signatures and the addresses derived from them
+var sensitiveNames = []string{"addr", "key", "sig", "seed", "tag", "pk", "hash", "secret"}
+
+// sensitive reports whether an expression mentions a key, signature or address
+func sensitive(expr ast.Expr) bool {
+	found := false
+	ast.Inspect(expr, func(n ast.Node) bool {
+		if id, ok := n.(*ast.Ident); ok {
+			name := strings.ToLower(id.Name)
+			for _, s := range sensitiveNames {
+				found = found || strings.Contains(name, s)
+			}
+		}
+		return !found
+	})
+	return found
+}
+
+/*
+ * TestNoVariableTimeCompares scans the Go sources of every module for
+ * bytes.Equal on keys, signatures and addresses, which must go through
+ * Equal instead
+ *
+ * Test files are skipped, they compare fixtures.
+ */
+func TestNoVariableTimeCompares(t *testing.T) {
+	root := filepath.Join("..", "..")
+	fset := token.NewFileSet()
+	scanned := 0
+	err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			if name := d.Name(); path != root && (strings.HasPrefix(name, ".") || name == "testdata" || name == "vendor") {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !strings.HasSuffix(path, ".go") || strings.HasSuffix(path, "_test.go") {
+			return nil
+		}
+		file, err := parser.ParseFile(fset, path, nil, 0)
+		if err != nil {
+			return err
+		}
+		scanned++
+		ast.Inspect(file, func(n ast.Node) bool {
+			call, ok := n.(*ast.CallExpr)
+			if !ok {
+				return true
+			}
+			sel, ok := call.Fun.(*ast.SelectorExpr)
+			if !ok || sel.Sel.Name != "Equal" {
+				return true
+			}
+			if pkg, ok := sel.X.(*ast.Ident); !ok || pkg.Name != "bytes" {
+				return true
+			}
+			for _, arg := range call.Args {
+				if sensitive(arg) {
+					t.Errorf("%s: bytes.Equal on a key, signature or address, use secure.Equal", fset.Position(call.Pos()))
+					break
+				}
+			}
+			return true
+		})
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if scanned == 0 {
+		t.Fatal("no Go file scanned")
+	}
+}
@@ -0,0 +1,51 @@
+/*
+ * Package secure holds helpers for handling secret key material.
+ *
+ * Go gives no hard guarantee that a secret leaves no copies behind (the
+ * garbage collector may move buffers, strings are immutable), so the aim is
+ * to keep secrets in byte slices and arrays that the caller owns and wipes
+ * as soon as they are no longer needed.
+ */
+package secure
+
+import (
+	"bytes"
+	"encoding/hex"
+	"fmt"
+	"runtime"
+)
+
+// KeyLength is the length in bytes of a WOTS secret seed
+const KeyLength = 32
+
+// Wipe overwrites a buffer holding secret material with zeroes
+func Wipe(b []byte) {
+	for i := range b {
+		b[i] = 0
+	}
+	// Keep the buffer alive so the stores are not optimized away
+	runtime.KeepAlive(b)
+}
+
+/*
+ * DecodeKey decodes a 32 bytes secret key from its hex form held in a byte
+ * slice, so the secret never goes through an intermediate string
+ *
+ * Surrounding whitespace and an optional 0x prefix are accepted. The caller
+ * remains responsible for wiping both hexKey and the returned key.
+ */
+func DecodeKey(hexKey []byte) ([KeyLength]byte, error) {
+	var key [KeyLength]byte
+	trimmed := bytes.TrimSpace(hexKey)
+	if bytes.HasPrefix(trimmed, []byte("0x")) {
+		trimmed = trimmed[2:]
+	}
+	if len(trimmed) != KeyLength*2 {
+		return key, fmt.Errorf("secret key must be %d bytes hex", KeyLength)
+	}
+	if _, err := hex.Decode(key[:], trimmed); err != nil {
+		Wipe(key[:])
+		return key, fmt.Errorf("secret key must be %d bytes hex", KeyLength)
+	}
+	return key, nil
+}
@@ -0,0 +1,66 @@
+package secure
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestWipe(t *testing.T) {
+	buf := bytes.Repeat([]byte{0xa5}, 100)
+	Wipe(buf[10:20])
+	for i, b := range buf {
+		if zero := i >= 10 && i < 20; zero != (b == 0) {
+			t.Fatalf("byte %d is 0x%02x after wiping [10:20]", i, b)
+		}
+	}
+
+	// Arrays are wiped through a slice of them, in place
+	key := [KeyLength]byte{1, 2, 3}
+	key[KeyLength-1] = 0xff
+	Wipe(key[:])
+	if key != [KeyLength]byte{} {
+		t.Errorf("array not wiped: %x", key)
+	}
+
+	Wipe(nil)
+	Wipe([]byte{})
+}
+
+func TestDecodeKey(t *testing.T) {
+	want := [KeyLength]byte{0x01, 0x23, 0x45, 0x67, 0x89, 0xab, 0xcd, 0xef}
+	hexKey := "0123456789abcdef" + strings.Repeat("0", 48)
+	for _, input := range []string{hexKey, strings.ToUpper(hexKey), "0x" + hexKey, "  " + hexKey + "\n"} {
+		key, err := DecodeKey([]byte(input))
+		if err != nil || key != want {
+			t.Errorf("%q: got %x, %v", input, key, err)
+		}
+	}
+	for _, input := range []string{"", hexKey[:62], hexKey + "00", hexKey[:63] + "g", "0X" + hexKey} {
+		key, err := DecodeKey([]byte(input))
+		if err == nil || err.Error() != "secret key must be 32 bytes hex" {
+			t.Errorf("%q: %v", input, err)
+		}
+		// Nothing of a rejected key is left in the result
+		if key != [KeyLength]byte{} {
+			t.Errorf("%q: rejected key returned %x", input, key)
+		}
+	}
+}
+
+// TestDecodeKeyLeavesInput checks that the caller keeps ownership of the hex buffer it must wipe
+func TestDecodeKeyLeavesInput(t *testing.T) {
+	input := []byte(strings.Repeat("ab", KeyLength))
+	key, err := DecodeKey(input)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(input) != strings.Repeat("ab", KeyLength) {
+		t.Errorf("input modified: %q", input)
+	}
+	Wipe(input)
+	Wipe(key[:])
+	if !bytes.Equal(input, make([]byte, 2*KeyLength)) || key != [KeyLength]byte{} {
+		t.Error("buffers not wiped")
+	}
+}
@@ -0,0 +1,130 @@
+/*
+ * Package shutdown gives the tools one way to stop on Ctrl-C or SIGTERM.
+ *
+ * Notify installs the handlers and returns a Handler whose Context is the
+ * root context of the tool: the first signal cancels it, so the long loops
+ * finish the item in progress, flush what they wrote so far and report how
+ * far they got. A second signal exits at once with ExitInterrupted, for a
+ * tool stuck somewhere that does not watch the context.
+ *
+ *	sig := shutdown.Notify()
+ *	defer sig.Stop()
+ *	ctx := sig.Context()
+ *	for _, item := range items {
+ *		if ctx.Err() != nil {
+ *			break
+ *		}
+ *		...
+ *	}
+ *	if sig.Interrupted() {
+ *		fmt.Fprintf(os.Stderr, "Interrupted: %d of %d done\n", done, len(items))
+ *		sig.Exit(shutdown.ExitInterrupted)
+ *	}
+ */
+package shutdown
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+)
+
+// ExitInterrupted is the exit code of a tool stopped by a signal, as a shell reports a process killed by SIGINT
+const ExitInterrupted = 130
+
+// Handler is the signal handling of a process, see Notify
+type Handler struct {
+	ctx     context.Context
+	cancel  context.CancelFunc
+	signals chan os.Signal
+	done    chan struct{}
+
+	mu       sync.Mutex
+	received os.Signal
+	cleanups []func()
+	stopped  bool
+}
+
+// Notify installs the SIGINT and SIGTERM handlers of the process; Stop removes them
+func Notify() *Handler {
+	ctx, cancel := context.WithCancel(context.Background())
+	h := &Handler{ctx: ctx, cancel: cancel, signals: make(chan os.Signal, 2), done: make(chan struct{})}
+	signal.Notify(h.signals, os.Interrupt, syscall.SIGTERM)
+	go h.run()
+	return h
+}
+
+// run cancels the context on the first signal and exits on the second
+func (h *Handler) run() {
+	for {
+		select {
+		case sig := <-h.signals:
+			h.mu.Lock()
+			first := h.received == nil
+			if first {
+				h.received = sig
+			}
+			h.mu.Unlock()
+			if !first {
+				fmt.Fprintf(os.Stderr, "Interrupted again (%v), exiting now\n", sig)
+				os.Exit(ExitInterrupted)
+			}
+			h.cancel()
+		case <-h.done:
+			return
+		}
+	}
+}
+
+// Context returns the root context of the process, cancelled by the first signal or by Stop
+func (h *Handler) Context() context.Context {
+	return h.ctx
+}
+
+// Interrupted reports whether a signal was received
+func (h *Handler) Interrupted() bool {
+	return h.Signal() != nil
+}
+
+// Signal returns the first signal received, nil if there was none
+func (h *Handler) Signal() os.Signal {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.received
+}
+
+// OnCleanup registers fn to run on Stop, e.g. to release a lock or flush a file; cleanups run last registered first
+func (h *Handler) OnCleanup(fn func()) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.cleanups = append(h.cleanups, fn)
+}
+
+// Stop removes the signal handlers, cancels the context and runs the cleanups; later calls do nothing
+func (h *Handler) Stop() {
+	h.mu.Lock()
+	if h.stopped {
+		h.mu.Unlock()
+		return
+	}
+	h.stopped = true
+	cleanups := h.cleanups
+	h.cleanups = nil
+	h.mu.Unlock()
+
+	signal.Stop(h.signals)
+	close(h.done)
+	h.cancel()
+	for i := len(cleanups) - 1; i >= 0; i-- {
+		cleanups[i]()
+	}
+}
+
+// Exit runs Stop, which deferred calls would skip, then exits with code
+func (h *Handler) Exit(code int) {
+	h.Stop()
+	os.Exit(code)
+}
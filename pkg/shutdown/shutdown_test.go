@@ -0,0 +1,32 @@
+package shutdown
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestStopRunsCleanups(t *testing.T) {
+	h := Notify()
+	var ran []int
+	for i := 1; i <= 3; i++ {
+		h.OnCleanup(func() { ran = append(ran, i) })
+	}
+	if h.Context().Err() != nil || h.Interrupted() {
+		t.Fatal("stopped before Stop")
+	}
+	h.Stop()
+	if !slices.Equal(ran, []int{3, 2, 1}) {
+		t.Errorf("cleanups ran %v, want last registered first", ran)
+	}
+	if h.Context().Err() == nil {
+		t.Error("context not cancelled by Stop")
+	}
+	// Stop is not a signal
+	if h.Interrupted() || h.Signal() != nil {
+		t.Errorf("interrupted by %v", h.Signal())
+	}
+	h.Stop()
+	if len(ran) != 3 {
+		t.Errorf("cleanups ran again: %v", ran)
+	}
+}
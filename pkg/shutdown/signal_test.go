@@ -0,0 +1,93 @@
+//go:build !windows
+
+package shutdown
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+)
+
+// HELPER_ENV makes TestHelper run as the process TestSecondSignalExits signals
+const HELPER_ENV = "SHUTDOWN_TEST_HELPER"
+
+// TestHelper installs the handlers, says so, and never watches its context, as a tool stuck in a call would
+func TestHelper(t *testing.T) {
+	if os.Getenv(HELPER_ENV) == "" {
+		t.Skip("only run by TestSecondSignalExits")
+	}
+	h := Notify()
+	h.OnCleanup(func() { fmt.Println("cleanup") })
+	fmt.Println("ready")
+	<-h.Context().Done()
+	fmt.Println("cancelled")
+	select {}
+}
+
+func TestFirstSignalCancels(t *testing.T) {
+	h := Notify()
+	defer h.Stop()
+	if err := syscall.Kill(os.Getpid(), syscall.SIGTERM); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case <-h.Context().Done():
+	case <-time.After(5 * time.Second):
+		t.Fatal("context not cancelled by SIGTERM")
+	}
+	if !h.Interrupted() || h.Signal() != syscall.SIGTERM {
+		t.Errorf("signal %v", h.Signal())
+	}
+}
+
+// TestSecondSignalExits interrupts the helper twice: the first signal cancels its context, the second exits with ExitInterrupted
+func TestSecondSignalExits(t *testing.T) {
+	cmd := exec.Command(os.Args[0], "-test.run=^TestHelper$")
+	cmd.Env = append(os.Environ(), HELPER_ENV+"=1")
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := cmd.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer cmd.Process.Kill()
+	lines := bufio.NewScanner(stdout)
+	expect := func(want string) {
+		t.Helper()
+		if !lines.Scan() || lines.Text() != want {
+			t.Fatalf("helper printed %q, want %q", lines.Text(), want)
+		}
+	}
+
+	expect("ready")
+	cmd.Process.Signal(os.Interrupt)
+	expect("cancelled")
+	cmd.Process.Signal(os.Interrupt)
+	// The rest of the output is read before Wait closes the pipe
+	var rest []string
+	for lines.Scan() {
+		rest = append(rest, lines.Text())
+	}
+
+	var exitErr *exec.ExitError
+	if err := cmd.Wait(); !errors.As(err, &exitErr) || exitErr.ExitCode() != ExitInterrupted {
+		t.Fatalf("helper exited with %v, want %d", err, ExitInterrupted)
+	}
+	if !strings.Contains(stderr.String(), "Interrupted again (interrupt), exiting now") {
+		t.Errorf("stderr %q", stderr.String())
+	}
+	// Exiting at once skips the cleanups
+	if len(rest) > 0 {
+		t.Errorf("printed %q after the second signal", rest)
+	}
+}
@@ -0,0 +1,93 @@
+package sweep
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/NickP005/Vindax-MCM-tools/pkg/fileutil"
+)
+
+// JournalEntry is a sweep transaction signed by a key, saved before it is broadcast
+type JournalEntry struct {
+	// Source is the hex address spent, the tag and the address hash of the key that signed
+	Source   string    `json:"source"`
+	SignedTx string    `json:"signedTx"`
+	TxID     string    `json:"txId,omitempty"`
+	Amount   uint64    `json:"amount"`
+	Fee      uint64    `json:"fee"`
+	SignedAt time.Time `json:"signedAt"`
+	// BlockToLive is the last block the transaction can be mined in, 0 for none
+	BlockToLive uint64 `json:"blockToLive,omitempty"`
+}
+
+/*
+ * Journal keeps every sweep transaction signed, by source address, in a
+ * JSON file
+ *
+ * A WOTS+ key must sign only once: an entry is saved, durably, before its
+ * transaction is broadcast, and a later run finding the source address
+ * still funded broadcasts the same bytes again instead of signing anew.
+ * Entries are never removed, a swept address is not funded again under
+ * the same key. Its methods are safe for concurrent use.
+ */
+type Journal struct {
+	path    string
+	mu      sync.Mutex
+	entries map[string]JournalEntry
+}
+
+// OpenJournal reads the journal at path; a missing file is an empty journal, created by the first Put
+func OpenJournal(path string) (*Journal, error) {
+	j := &Journal{path: path, entries: make(map[string]JournalEntry)}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return j, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var entries []JournalEntry
+	if err := json.Unmarshal(data, &entries); err != nil {
+		return nil, fmt.Errorf("invalid sweep journal %s: %v", path, err)
+	}
+	for _, entry := range entries {
+		j.entries[entry.Source] = entry
+	}
+	return j, nil
+}
+
+// Path returns the file of the journal
+func (j *Journal) Path() string {
+	return j.path
+}
+
+// Get returns the transaction signed from a source address, if any
+func (j *Journal) Get(source string) (JournalEntry, bool) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	entry, ok := j.entries[source]
+	return entry, ok
+}
+
+// Put saves an entry, replacing the one of its source address, and writes the journal durably
+func (j *Journal) Put(entry JournalEntry) error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	j.entries[entry.Source] = entry
+
+	entries := make([]JournalEntry, 0, len(j.entries))
+	for _, e := range j.entries {
+		entries = append(entries, e)
+	}
+	sort.Slice(entries, func(a, b int) bool { return entries[a].Source < entries[b].Source })
+	data, err := json.MarshalIndent(entries, "", "  ")
+	if err != nil {
+		return err
+	}
+	return fileutil.WriteAtomic(j.path, data, true)
+}
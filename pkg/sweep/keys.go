@@ -0,0 +1,142 @@
+package sweep
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"github.com/NickP005/Vindax-MCM-tools/pkg/secure"
+)
+
+// secretKeyColumn is the CSV column, and JSON field, of the seed in tool-2 output
+const secretKeyColumn = "wotsSecretKey"
+
+// tool2Account is an account of tool-2 output; the seed stays raw JSON so it never becomes a string
+type tool2Account struct {
+	WOTSSecretKey json.RawMessage `json:"wotsSecretKey"`
+}
+
+/*
+ * ReadSeeds reads the seeds of the accounts to sweep
+ *
+ * Accepted formats, told apart by their first character or header:
+ * - tool-2 JSON, {"accounts": [...]}, or NDJSON, one account per line;
+ *   the seed is the wotsSecretKey field
+ * - tool-2 CSV, whose header names a wotsSecretKey column
+ * - a seeds file: one 32 bytes hex seed per line, optionally followed by
+ *   a label; blank lines and lines starting with # are skipped
+ *
+ * Line is the line of each seed in the file, or its position in the
+ * accounts list of a JSON object. The whole input is wiped once read; the
+ * caller wipes the accounts with Wipe.
+ */
+func ReadSeeds(r io.Reader) ([]*Account, error) {
+	data, err := io.ReadAll(r)
+	defer secure.Wipe(data)
+	if err != nil {
+		return nil, err
+	}
+	trimmed := bytes.TrimSpace(data)
+	if len(trimmed) > 0 && trimmed[0] == '{' {
+		var list struct {
+			Accounts []tool2Account `json:"accounts"`
+		}
+		// More than one object is NDJSON
+		if json.Unmarshal(trimmed, &list) == nil {
+			return seedsOfJSON(list.Accounts)
+		}
+		return readNDJSON(data)
+	}
+	return readLines(data)
+}
+
+// seedOf decodes the seed of a tool-2 account, a JSON string
+func seedOf(account tool2Account) ([secure.KeyLength]byte, error) {
+	raw := bytes.TrimSpace(account.WOTSSecretKey)
+	if len(raw) < 2 || raw[0] != '"' || raw[len(raw)-1] != '"' {
+		return [secure.KeyLength]byte{}, fmt.Errorf("no %s", secretKeyColumn)
+	}
+	return secure.DecodeKey(raw[1 : len(raw)-1])
+}
+
+// seedsOfJSON decodes the seeds of a tool-2 accounts list
+func seedsOfJSON(list []tool2Account) ([]*Account, error) {
+	accounts := make([]*Account, 0, len(list))
+	for i, entry := range list {
+		seed, err := seedOf(entry)
+		secure.Wipe(entry.WOTSSecretKey)
+		if err != nil {
+			Wipe(accounts)
+			return nil, fmt.Errorf("account %d: %v", i+1, err)
+		}
+		accounts = append(accounts, &Account{Line: i + 1, seed: seed})
+	}
+	return accounts, nil
+}
+
+// readNDJSON reads tool-2 NDJSON, one account object per line; lines are sliced from data, never copied
+func readNDJSON(data []byte) ([]*Account, error) {
+	var accounts []*Account
+	for i, text := range bytes.Split(data, []byte("\n")) {
+		line := i + 1
+		text = bytes.TrimSpace(text)
+		if len(text) == 0 {
+			continue
+		}
+		var entry tool2Account
+		err := json.Unmarshal(text, &entry)
+		var seed [secure.KeyLength]byte
+		if err == nil {
+			seed, err = seedOf(entry)
+		}
+		secure.Wipe(entry.WOTSSecretKey)
+		if err != nil {
+			Wipe(accounts)
+			return nil, fmt.Errorf("line %d: %v", line, err)
+		}
+		accounts = append(accounts, &Account{Line: line, seed: seed})
+	}
+	return accounts, nil
+}
+
+// readLines reads tool-2 CSV, or a seeds file when the first line is not a header naming wotsSecretKey
+func readLines(data []byte) ([]*Account, error) {
+	var accounts []*Account
+	// column is the CSV column of the seed, -1 for a seeds file, known once the first line is read
+	column := -2
+	for i, text := range bytes.Split(data, []byte("\n")) {
+		line := i + 1
+		text = bytes.TrimSpace(text)
+		if len(text) == 0 || text[0] == '#' {
+			continue
+		}
+		if column == -2 {
+			column = -1
+			for col, name := range bytes.Split(text, []byte(",")) {
+				if string(bytes.TrimSpace(name)) == secretKeyColumn {
+					column = col
+				}
+			}
+			if column >= 0 {
+				continue
+			}
+		}
+
+		var field []byte
+		if column >= 0 {
+			if fields := bytes.Split(text, []byte(",")); column < len(fields) {
+				field = fields[column]
+			}
+		} else {
+			field = bytes.Fields(text)[0]
+		}
+		seed, err := secure.DecodeKey(field)
+		if err != nil {
+			Wipe(accounts)
+			return nil, fmt.Errorf("line %d: %v", line, err)
+		}
+		accounts = append(accounts, &Account{Line: line, seed: seed})
+	}
+	return accounts, nil
+}
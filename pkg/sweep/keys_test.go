@@ -0,0 +1,59 @@
+package sweep
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/NickP005/Vindax-MCM-tools/pkg/secure"
+)
+
+// testSeed is the seed of a label, so each test account has a seed of its own
+func testSeed(label string) [secure.KeyLength]byte {
+	return sha256.Sum256([]byte("sweep test " + label))
+}
+
+func TestReadSeeds(t *testing.T) {
+	a, b := testSeed("a"), testSeed("b")
+	hexA, hexB := hex.EncodeToString(a[:]), hex.EncodeToString(b[:])
+	for _, tc := range []struct {
+		name  string
+		input string
+		lines []int
+	}{
+		{"json", fmt.Sprintf(`{"accounts": [{"wotsSecretKey": "%s"}, {"wotsSecretKey": "0x%s"}]}`, hexA, hexB), []int{1, 2}},
+		{"ndjson", fmt.Sprintf("{\"wotsSecretKey\": \"%s\"}\n\n{\"wotsSecretKey\": \"%s\"}\n", hexA, hexB), []int{1, 3}},
+		{"csv", fmt.Sprintf("mcmAccountNumber,wotsPublicKey,wotsSecretKey\n01,00,%s\r\n02,00,%s\n", hexA, hexB), []int{2, 3}},
+		{"seeds", fmt.Sprintf("# airdrop accounts\n%s first\n\n%s\n", hexA, strings.ToUpper(hexB)), []int{2, 4}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			accounts, err := ReadSeeds(strings.NewReader(tc.input))
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer Wipe(accounts)
+			if len(accounts) != 2 || accounts[0].seed != a || accounts[1].seed != b {
+				t.Fatalf("%d accounts, seeds differ", len(accounts))
+			}
+			for i, account := range accounts {
+				if account.Line != tc.lines[i] {
+					t.Errorf("account %d on line %d, want %d", i, account.Line, tc.lines[i])
+				}
+			}
+		})
+	}
+
+	for input, message := range map[string]string{
+		`{"accounts": [{"wotsSecretKey": "` + hexA + `"}, {"wotsPublicKey": "00"}]}`: "account 2: no wotsSecretKey",
+		`{"accounts": [{"wotsSecretKey": 7}]}`:                                       "account 1: no wotsSecretKey",
+		"{\"wotsSecretKey\": \"" + hexA + "\"}\n{\"wotsSecretKey\": \"zz\"}":         "line 2:",
+		"mcmAccountNumber,wotsSecretKey\n01\n":                                       "line 2:",
+		hexA + "\n" + hexA[:60] + "\n":                                               "line 2:",
+	} {
+		if _, err := ReadSeeds(strings.NewReader(input)); err == nil || !strings.Contains(err.Error(), message) {
+			t.Errorf("%.40q: %v", input, err)
+		}
+	}
+}
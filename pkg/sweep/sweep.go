@@ -0,0 +1,538 @@
+/*
+ * Package sweep gathers the funds of many accounts, each held by its own
+ * seed (e.g. generated by tool-2), into one destination.
+ *
+ * Every account pays its whole balance, less the fee, to the destination
+ * in a transaction of its own. Its tag is the address hash of the key of
+ * its seed, and each sweep moves the tag to the next key of the account,
+ * derived from the seed with wotsp.DeriveSeed, so an account funded again
+ * after a sweep is swept again from the same seed:
+ *
+ *	accounts, _ := sweep.ReadSeeds(file)
+ *	defer sweep.Wipe(accounts)
+ *	s := &sweep.Sweeper{Client: client, Destination: tag, Fee: 500, Confirmations: 1}
+ *	err := s.Run(ctx, accounts)
+ *	summary := sweep.Summarize(accounts)
+ */
+package sweep
+
+import (
+	"context"
+	"encoding/hex"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/NickP005/Vindax-MCM-tools/pkg/amount"
+	"github.com/NickP005/Vindax-MCM-tools/pkg/mcmaddr"
+	"github.com/NickP005/Vindax-MCM-tools/pkg/meshclient"
+	"github.com/NickP005/Vindax-MCM-tools/pkg/monitor"
+	"github.com/NickP005/Vindax-MCM-tools/pkg/secure"
+	"github.com/NickP005/Vindax-MCM-tools/pkg/txbuild"
+	"github.com/NickP005/Vindax-MCM-tools/pkg/txentry"
+	"github.com/NickP005/Vindax-MCM-tools/pkg/wotsp"
+)
+
+// MaxGenerations is how many keys of an account, from the seed's own, Plan tries for the one its tag belongs to
+const MaxGenerations = 16
+
+// Outcomes of an account, once swept
+const (
+	StatusSwept      = "swept"       // the transaction has the confirmations asked
+	StatusSubmitted  = "submitted"   // the node accepted the transaction, not confirmed when the sweep ended
+	StatusWouldSweep = "would-sweep" // dry run: the account would be swept
+	StatusSkipped    = "skipped"     // nothing to sweep, see Reason
+	StatusFailed     = "failed"      // funds left on the account, see Reason
+)
+
+/*
+ * Account is a seed to sweep and, once swept, its outcome
+ *
+ * Fields:
+ * - Line: where the seed is in the input, see ReadSeeds
+ * - Address, Tag: the tag of the account, base58 and hex
+ * - Balance: the balance found; Amount is what is sent, Balance less Fee
+ * - BlockToLive: the last block the transaction can be mined in, 0 for none
+ * - TxID, Block: the transaction, and the block it was mined in once seen
+ * - Status: one of the Status constants, Reason explains it
+ */
+type Account struct {
+	Line        int    `json:"line"`
+	Address     string `json:"address"`
+	Tag         string `json:"tag"`
+	Balance     uint64 `json:"balance"`
+	Amount      uint64 `json:"amount,omitempty"`
+	Fee         uint64 `json:"fee,omitempty"`
+	BlockToLive uint64 `json:"blockToLive,omitempty"`
+	TxID        string `json:"txId,omitempty"`
+	Block       uint64 `json:"block,omitempty"`
+	Status      string `json:"status"`
+	Reason      string `json:"reason,omitempty"`
+
+	seed [secure.KeyLength]byte
+	tag  [txentry.TagLength]byte
+	// generation is the key the tag belongs to, source its address and signedTx the hex transaction
+	generation uint64
+	source     [txentry.AddressLength]byte
+	signedTx   string
+	// ready is set by Plan on the accounts to submit
+	ready bool
+}
+
+// skip sets the account skipped, for a reason
+func (a *Account) skip(format string, args ...any) {
+	a.Status, a.Reason, a.ready = StatusSkipped, fmt.Sprintf(format, args...), false
+}
+
+// fail sets the account failed, for a reason
+func (a *Account) fail(format string, args ...any) {
+	a.Status, a.Reason, a.ready = StatusFailed, fmt.Sprintf(format, args...), false
+}
+
+// Wipe overwrites the seeds of the accounts
+func Wipe(accounts []*Account) {
+	for _, account := range accounts {
+		secure.Wipe(account.seed[:])
+	}
+}
+
+// keyAt derives the key of a generation of an account: the seed's own for 0, then the key of DeriveSeed(seed, generation)
+func keyAt(seed [secure.KeyLength]byte, generation uint64) wotsp.Keypair {
+	if generation == 0 {
+		return wotsp.Keygen(seed)
+	}
+	derived := wotsp.DeriveSeed(seed, generation)
+	defer secure.Wipe(derived[:])
+	return wotsp.Keygen(derived)
+}
+
+// findGeneration returns the generation after the first of the key whose address hash is hash, below MaxGenerations
+func findGeneration(seed [secure.KeyLength]byte, hash [wotsp.AddrHashLength]byte) (uint64, bool) {
+	for generation := uint64(1); generation < MaxGenerations; generation++ {
+		key := keyAt(seed, generation)
+		key.Wipe()
+		if secure.Equal20(key.AddrHash(), hash) {
+			return generation, true
+		}
+	}
+	return 0, false
+}
+
+/*
+ * Sweeper sweeps accounts into Destination
+ *
+ * Accounts holding less than Dust, or not more than Fee, are skipped. With
+ * BlockToLive, each transaction can only be mined in the BlockToLive blocks
+ * after the tip it was signed at. At most SubmitConcurrency transactions
+ * are signed and submitted at once. DryRun stops after Plan, signing and
+ * sending nothing. Journal, if set, keeps the signed transactions, see
+ * Journal. Log, if set, receives a line per step.
+ */
+type Sweeper struct {
+	Client            *meshclient.MeshAPIClient
+	Destination       [txentry.TagLength]byte
+	Fee               uint64
+	Dust              uint64
+	BlockToLive       uint64
+	SubmitConcurrency int
+	DryRun            bool
+	// Confirmations is the count of blocks, the one holding a transaction included, to wait for; 0 does not wait
+	Confirmations int
+	PollInterval  time.Duration
+	Journal       *Journal
+	Log           func(line string)
+}
+
+// logf sends a line to Log, if set
+func (s *Sweeper) logf(format string, args ...any) {
+	if s.Log != nil {
+		s.Log(fmt.Sprintf(format, args...))
+	}
+}
+
+/*
+ * Plan finds the balance of every account and decides which to sweep
+ *
+ * An account listed twice, the destination itself, a tag the chain does
+ * not know and a balance under Dust or not above Fee are skipped; a failed
+ * lookup, or a tag held by a key that is not one of the account's, fails.
+ * An account whose key already signed a sweep in the Journal is to
+ * broadcast that transaction again, unless it can no longer be mined: the
+ * key must not sign twice. tip is the current height, which the block to
+ * live of the journal entries is compared with.
+ *
+ * Returns ctx's error if it was canceled before all balances were known.
+ */
+func (s *Sweeper) Plan(ctx context.Context, accounts []*Account, tip uint64) error {
+	first := make(map[[txentry.TagLength]byte]int)
+	var tags [][txentry.TagLength]byte
+	for _, account := range accounts {
+		key := keyAt(account.seed, 0)
+		key.Wipe()
+		account.tag = key.AddrHash()
+		account.Tag = mcmaddr.ToHex(account.tag)
+		account.Address = mcmaddr.To58(account.tag)
+		if line, seen := first[account.tag]; seen {
+			account.skip("same account as line %d", line)
+			continue
+		}
+		first[account.tag] = account.Line
+		if account.tag == s.Destination {
+			account.skip("the account is the destination")
+			continue
+		}
+		tags = append(tags, account.tag)
+	}
+
+	resolutions, err := s.Client.BatchResolveTags(ctx, tags)
+	for _, account := range accounts {
+		if account.Status != "" {
+			continue
+		}
+		resolution := resolutions[account.tag]
+		switch {
+		case resolution.Err != nil:
+			account.fail("balance lookup failed: %v", resolution.Err)
+		case !resolution.Found:
+			account.skip("not on chain")
+		default:
+			account.Balance = resolution.Amount
+			s.plan(account, resolution, tip)
+		}
+	}
+	return err
+}
+
+// plan decides whether a funded account is swept, and with which key
+func (s *Sweeper) plan(account *Account, resolution meshclient.TagResolution, tip uint64) {
+	if account.Balance == 0 {
+		account.skip("empty")
+		return
+	}
+	if account.Balance < s.Dust {
+		account.skip("balance under the dust threshold of %s", amount.Format(s.Dust, amount.MCM))
+		return
+	}
+	if account.Balance <= s.Fee {
+		account.skip("balance does not cover the fee of %s", amount.Format(s.Fee, amount.MCM))
+		return
+	}
+	// The implicit address of a tag, the one a payment creates, is its first key's
+	hash := resolution.AddrHash()
+	generation, found := uint64(0), secure.Equal20(hash, account.tag)
+	if !found {
+		generation, found = findGeneration(account.seed, hash)
+	}
+	if !found {
+		account.fail("the tag belongs to %s, not to one of the first %d keys of the seed", resolution.AddressHex, MaxGenerations)
+		return
+	}
+	account.generation = generation
+	copy(account.source[:], resolution.Address)
+	account.Amount, account.Fee = account.Balance-s.Fee, s.Fee
+	account.ready = true
+
+	if s.Journal == nil {
+		return
+	}
+	entry, signed := s.Journal.Get(hex.EncodeToString(account.source[:]))
+	switch {
+	case !signed:
+	case entry.BlockToLive != 0 && tip > entry.BlockToLive:
+		account.fail("the key signed a sweep on %s that could not be mined after block %d: signing again could expose the key",
+			entry.SignedAt.Format(time.RFC3339), entry.BlockToLive)
+	case entry.Amount+entry.Fee != account.Balance:
+		account.fail("the key signed a sweep of %s on %s, the balance is now %s: signing again could expose the key",
+			amount.Format(entry.Amount+entry.Fee, amount.MCM), entry.SignedAt.Format(time.RFC3339), amount.Format(account.Balance, amount.MCM))
+	default:
+		account.signedTx = entry.SignedTx
+		account.Amount, account.Fee, account.BlockToLive, account.TxID = entry.Amount, entry.Fee, entry.BlockToLive, entry.TxID
+		account.Reason = "signed by an earlier run, broadcast again"
+	}
+}
+
+// sign builds and signs the sweep of an account, the change moving the tag to its next key, and saves it in the journal
+func (s *Sweeper) sign(account *Account, tip uint64) error {
+	change := keyAt(account.seed, account.generation+1)
+	change.Wipe()
+	payment, err := txbuild.NewDestination(s.Destination, "", account.Amount)
+	if err != nil {
+		return err
+	}
+	tx, err := txbuild.NewTransfer(account.source, txbuild.Address(account.tag, change.PublicKey), account.Balance, account.Fee, []txentry.Destination{payment})
+	if err != nil {
+		return err
+	}
+	if s.BlockToLive > 0 {
+		tx.BlockToLive = tip + s.BlockToLive
+	}
+	key := keyAt(account.seed, account.generation)
+	defer key.Wipe()
+	if err := txbuild.Sign(tx, &key); err != nil {
+		return err
+	}
+	account.BlockToLive = tx.BlockToLive
+	account.signedTx = hex.EncodeToString(tx.Bytes())
+
+	if s.Journal != nil {
+		if err := s.Journal.Put(s.journalEntry(account)); err != nil {
+			return fmt.Errorf("not broadcast, the journal could not be saved: %v", err)
+		}
+	}
+	return nil
+}
+
+// journalEntry returns the journal entry of an account's signed transaction
+func (s *Sweeper) journalEntry(account *Account) JournalEntry {
+	return JournalEntry{
+		Source:      hex.EncodeToString(account.source[:]),
+		SignedTx:    account.signedTx,
+		TxID:        account.TxID,
+		Amount:      account.Amount,
+		Fee:         account.Fee,
+		SignedAt:    time.Now().UTC(),
+		BlockToLive: account.BlockToLive,
+	}
+}
+
+// submit signs the sweep of an account, unless the journal had it, and submits it
+func (s *Sweeper) submit(ctx context.Context, account *Account, tip uint64) {
+	if s.DryRun {
+		account.Status = StatusWouldSweep
+		return
+	}
+	if account.signedTx == "" {
+		if err := s.sign(account, tip); err != nil {
+			account.fail("%v", err)
+			return
+		}
+	}
+	result, err := s.Client.SubmitTransaction(ctx, account.signedTx)
+	if err != nil {
+		account.fail("submit failed: %v", err)
+		return
+	}
+	account.Status, account.TxID = StatusSubmitted, result.TransactionIdentifier.Hash
+	if s.Journal != nil {
+		entry, ok := s.Journal.Get(hex.EncodeToString(account.source[:]))
+		if ok && entry.TxID != account.TxID {
+			entry.TxID = account.TxID
+			if err := s.Journal.Put(entry); err != nil {
+				s.logf("Warning: the transaction ID of %s could not be saved in the journal: %v", account.Address, err)
+			}
+		}
+	}
+	s.logf("%s: %s submitted, sending %s", account.Address, account.TxID, amount.Format(account.Amount, amount.MCM))
+}
+
+/*
+ * Submit signs and submits the sweep of every account Plan made ready, at
+ * most SubmitConcurrency at once
+ *
+ * Accounts not reached when ctx is canceled are skipped. tip is the
+ * height the block to live of new transactions counts from.
+ */
+func (s *Sweeper) Submit(ctx context.Context, accounts []*Account, tip uint64) {
+	queue := make(chan *Account)
+	var wg sync.WaitGroup
+	for i := 0; i < max(s.SubmitConcurrency, 1); i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for account := range queue {
+				s.submit(ctx, account, tip)
+			}
+		}()
+	}
+	for _, account := range accounts {
+		if !account.ready {
+			continue
+		}
+		if ctx.Err() != nil {
+			account.skip("not submitted, the sweep was interrupted")
+			continue
+		}
+		queue <- account
+	}
+	close(queue)
+	wg.Wait()
+}
+
+// confirmer follows the submitted sweeps block by block
+type confirmer struct {
+	s       *Sweeper
+	pending []*Account
+	hashes  monitor.BlockHashes
+	// next is the first height not read yet
+	next uint64
+}
+
+// scan reads the blocks up to height, noting the sweeps they hold; a block that cannot be read is read again on the next event
+func (c *confirmer) scan(ctx context.Context, height uint64) {
+	for ; c.next <= height; c.next++ {
+		block, err := c.s.Client.Block(ctx, c.next)
+		if err != nil {
+			if ctx.Err() == nil {
+				c.s.logf("Warning: block %d could not be read: %v", c.next, err)
+			}
+			return
+		}
+		c.hashes[c.next] = block.Block.BlockIdentifier.Hash
+		for _, account := range c.pending {
+			if account.Block == 0 && (block.Contains(account.TxID) || block.ListsOther(account.TxID)) {
+				account.Block = c.next
+				c.s.logf("%s: %s mined in block %d", account.Address, account.TxID, c.next)
+			}
+		}
+	}
+}
+
+// reorg forgets the sweeps seen in the blocks a reorg replaced, below the new block at height, so they are looked for again
+func (c *confirmer) reorg(ctx context.Context, height uint64) {
+	fork := height - min(height, 1)
+	if point, err := c.hashes.ForkPoint(ctx, c.s.Client, fork); err != nil {
+		c.s.logf("Warning: the fork of the reorg could not be found, only block %d is read again: %v", height, err)
+	} else {
+		fork = point
+	}
+	for _, account := range c.pending {
+		if account.Block > fork {
+			c.s.logf("%s: block %d holding %s was replaced", account.Address, account.Block, account.TxID)
+			account.Block = 0
+		}
+	}
+	c.hashes.Forget(fork)
+	c.next = min(c.next, fork+1)
+}
+
+// settle marks the sweeps confirmed or expired at height, and returns how many are still pending
+func (c *confirmer) settle(height uint64) int {
+	pending := c.pending[:0]
+	for _, account := range c.pending {
+		switch {
+		case account.Block > 0 && height+1-account.Block >= uint64(c.s.Confirmations):
+			account.Status = StatusSwept
+		case account.Block == 0 && account.BlockToLive != 0 && height > account.BlockToLive:
+			account.fail("not mined by its block to live, block %d", account.BlockToLive)
+		default:
+			pending = append(pending, account)
+		}
+	}
+	c.pending = pending
+	return len(pending)
+}
+
+// Confirm waits for the submitted sweeps to have Confirmations, following reorgs, until they all settle or the watch ends
+func (s *Sweeper) Confirm(ctx context.Context, accounts []*Account, watch <-chan meshclient.BlockEvent, tip uint64) {
+	c := &confirmer{s: s, hashes: monitor.BlockHashes{}, next: tip + 1}
+	for _, account := range accounts {
+		if account.Status == StatusSubmitted {
+			c.pending = append(c.pending, account)
+		}
+	}
+	if len(c.pending) == 0 {
+		return
+	}
+	for event := range watch {
+		if event.Err != nil {
+			s.logf("Warning: %v", event.Err)
+			continue
+		}
+		if event.Reorg {
+			c.reorg(ctx, event.Height)
+		}
+		c.scan(ctx, event.Height)
+		if c.settle(c.next-1) == 0 {
+			return
+		}
+	}
+	for _, account := range c.pending {
+		if account.Block > 0 {
+			account.Reason = fmt.Sprintf("mined in block %d, not yet confirmed", account.Block)
+		} else {
+			account.Reason = "not mined yet"
+		}
+	}
+}
+
+/*
+ * Run sweeps the accounts: Plan, then Submit and, unless DryRun or without
+ * Confirmations, Confirm until every sweep settles or ctx is done
+ *
+ * The outcome of each account is in its Status. Returns an error when the
+ * chain could not be read at all, or ctx's error if it ended the sweep.
+ */
+func (s *Sweeper) Run(ctx context.Context, accounts []*Account) error {
+	ctx, stop := context.WithCancel(ctx)
+	defer stop()
+
+	// The watch starts before the submits, so no block mined after them goes unseen
+	var watch <-chan meshclient.BlockEvent
+	if !s.DryRun && s.Confirmations > 0 {
+		var err error
+		if watch, err = s.Client.WatchBlocks(ctx, s.PollInterval); err != nil {
+			return fmt.Errorf("watching blocks: %v", err)
+		}
+	}
+	status, err := s.Client.NetworkStatus(ctx)
+	if err != nil {
+		return fmt.Errorf("reading the tip: %v", err)
+	}
+	tip := status.CurrentBlockIdentifier.Index
+
+	if err := s.Plan(ctx, accounts, tip); err != nil {
+		for _, account := range accounts {
+			if account.ready {
+				account.skip("not submitted, the sweep was interrupted")
+			}
+		}
+		return err
+	}
+	s.Submit(ctx, accounts, tip)
+	if watch != nil {
+		s.Confirm(ctx, accounts, watch, tip)
+	}
+	return ctx.Err()
+}
+
+/*
+ * Summary totals a sweep
+ *
+ * Amount adds up what the swept and submitted accounts send, or would
+ * send in a dry run, and Fees their fees.
+ */
+type Summary struct {
+	Accounts   int    `json:"accounts"`
+	Swept      int    `json:"swept"`
+	Submitted  int    `json:"submitted"`
+	WouldSweep int    `json:"wouldSweep"`
+	Skipped    int    `json:"skipped"`
+	Failed     int    `json:"failed"`
+	Amount     uint64 `json:"amount"`
+	Fees       uint64 `json:"fees"`
+}
+
+// Summarize counts the accounts by status and totals what they send
+func Summarize(accounts []*Account) Summary {
+	summary := Summary{Accounts: len(accounts)}
+	for _, account := range accounts {
+		switch account.Status {
+		case StatusSwept:
+			summary.Swept++
+		case StatusSubmitted:
+			summary.Submitted++
+		case StatusWouldSweep:
+			summary.WouldSweep++
+		case StatusSkipped:
+			summary.Skipped++
+		case StatusFailed:
+			summary.Failed++
+		}
+		switch account.Status {
+		case StatusSwept, StatusSubmitted, StatusWouldSweep:
+			summary.Amount += account.Amount
+			summary.Fees += account.Fee
+		}
+	}
+	return summary
+}
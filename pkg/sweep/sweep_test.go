@@ -0,0 +1,387 @@
+package sweep
+
+import (
+	"context"
+	"encoding/hex"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/NickP005/Vindax-MCM-tools/pkg/meshclient"
+	"github.com/NickP005/Vindax-MCM-tools/pkg/meshmock"
+	"github.com/NickP005/Vindax-MCM-tools/pkg/secure"
+	"github.com/NickP005/Vindax-MCM-tools/pkg/txbuild"
+	"github.com/NickP005/Vindax-MCM-tools/pkg/txentry"
+	mcm "github.com/NickP005/go_mcminterface"
+)
+
+const testFee = 500
+
+// tagOf returns the tag of a seed, the address hash of its first key
+func tagOf(seed [secure.KeyLength]byte) [txentry.TagLength]byte {
+	key := keyAt(seed, 0)
+	key.Wipe()
+	return key.AddrHash()
+}
+
+// fund sets the balance of the account of a seed at its implicit address, as a first payment to its tag does
+func fund(mock *meshmock.Server, seed [secure.KeyLength]byte, balance uint64) {
+	tag := tagOf(seed)
+	mock.SetAccount(tag[:], "0x"+hex.EncodeToString(tag[:])+hex.EncodeToString(tag[:]), balance)
+}
+
+// accountsOf returns the accounts of seeds, on lines 1, 2...
+func accountsOf(seeds ...[secure.KeyLength]byte) []*Account {
+	accounts := make([]*Account, len(seeds))
+	for i, seed := range seeds {
+		accounts[i] = &Account{Line: i + 1, seed: seed}
+	}
+	return accounts
+}
+
+// sweepRun is a Sweeper run against a mock, the lines it logs received on log
+type sweepRun struct {
+	t    *testing.T
+	mock *meshmock.Server
+	log  chan string
+	done chan error
+}
+
+/*
+ * startSweep runs s over accounts against mock, polling every 10ms; the
+ * test mines the blocks, and waits for the sweep with wait
+ */
+func startSweep(t *testing.T, mock *meshmock.Server, s *Sweeper, accounts []*Account) *sweepRun {
+	t.Helper()
+	run := &sweepRun{t: t, mock: mock, log: make(chan string, 1000), done: make(chan error, 1)}
+	s.Client = meshclient.NewMeshAPIClient(mock.URL(), nil)
+	s.PollInterval = 10 * time.Millisecond
+	s.Log = func(line string) { run.log <- line }
+	go func() { run.done <- s.Run(context.Background(), accounts) }()
+	return run
+}
+
+// waitLog waits for a line logged by the sweep holding text, the lines before it dropped
+func (run *sweepRun) waitLog(text string) string {
+	run.t.Helper()
+	timeout := time.After(10 * time.Second)
+	for {
+		select {
+		case line := <-run.log:
+			if strings.Contains(line, text) {
+				return line
+			}
+		case <-timeout:
+			run.t.Fatalf("never logged %q", text)
+		}
+	}
+}
+
+// mineUntilDone mines a block every 10ms until the sweep ends, and returns its error
+func (run *sweepRun) mineUntilDone() error {
+	run.t.Helper()
+	ticker := time.NewTicker(10 * time.Millisecond)
+	defer ticker.Stop()
+	timeout := time.After(20 * time.Second)
+	for {
+		select {
+		case err := <-run.done:
+			return err
+		case <-ticker.C:
+			run.mock.MineBlock()
+		case <-timeout:
+			run.t.Fatal("the sweep did not end")
+		}
+	}
+}
+
+// checkStatuses compares the status of every account, in order, with want
+func checkStatuses(t *testing.T, accounts []*Account, want ...string) {
+	t.Helper()
+	if len(accounts) != len(want) {
+		t.Fatalf("%d accounts, want %d", len(accounts), len(want))
+	}
+	for i, account := range accounts {
+		if account.Status != want[i] {
+			t.Errorf("line %d: %s (%s), want %s", account.Line, account.Status, account.Reason, want[i])
+		}
+	}
+}
+
+/*
+ * TestSweep sweeps two funded accounts next to every account Plan leaves
+ * alone, a dry run first, and checks the funds arrived, the tags moved to
+ * the next key of their seed and the journal holds every transaction
+ */
+func TestSweep(t *testing.T) {
+	mock := meshmock.New()
+	defer mock.Close()
+	mock.MineBlock()
+	destination := testSeed("destination")
+	funded, rich, dust, unknown, foreign := testSeed("funded"), testSeed("rich"), testSeed("dust"), testSeed("unknown"), testSeed("foreign")
+	fund(mock, funded, 100000)
+	fund(mock, rich, 200000)
+	fund(mock, dust, 800)
+	fund(mock, destination, 5000)
+	// A tag held by a key that is none of the seed's
+	foreignTag := tagOf(foreign)
+	mock.SetAccount(foreignTag[:], "0x"+hex.EncodeToString(foreignTag[:])+strings.Repeat("77", 20), 9000)
+	seeds := [][secure.KeyLength]byte{funded, rich, dust, unknown, funded, destination, foreign}
+	want := []string{StatusSwept, StatusSwept, StatusSkipped, StatusSkipped, StatusSkipped, StatusSkipped, StatusFailed}
+	reasons := []string{"", "", "under the dust threshold", "not on chain", "same account as line 1", "the account is the destination", "not to one of the first 16 keys"}
+
+	journal, err := OpenJournal(filepath.Join(t.TempDir(), "journal.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := &Sweeper{Destination: tagOf(destination), Fee: testFee, Dust: 1000, BlockToLive: 20, SubmitConcurrency: 2, Confirmations: 2, Journal: journal, DryRun: true}
+	accounts := accountsOf(seeds...)
+	defer Wipe(accounts)
+	if err := startSweep(t, mock, s, accounts).mineUntilDone(); err != nil {
+		t.Fatal(err)
+	}
+	checkStatuses(t, accounts, StatusWouldSweep, StatusWouldSweep, StatusSkipped, StatusSkipped, StatusSkipped, StatusSkipped, StatusFailed)
+	if summary := Summarize(accounts); summary.WouldSweep != 2 || summary.Amount != 300000-2*testFee || len(mock.Submitted()) != 0 {
+		t.Errorf("dry run %+v, %d submitted", summary, len(mock.Submitted()))
+	}
+	if _, signed := journal.Get(hex.EncodeToString(accounts[0].source[:])); signed {
+		t.Error("the dry run signed")
+	}
+
+	s.DryRun = false
+	accounts = accountsOf(seeds...)
+	defer Wipe(accounts)
+	start := mock.Height()
+	if err := startSweep(t, mock, s, accounts).mineUntilDone(); err != nil {
+		t.Fatal(err)
+	}
+	checkStatuses(t, accounts, want...)
+	for i, account := range accounts {
+		if !strings.Contains(account.Reason, reasons[i]) {
+			t.Errorf("line %d: reason %q, want %q", account.Line, account.Reason, reasons[i])
+		}
+	}
+	if summary := Summarize(accounts); summary.Swept != 2 || summary.Skipped != 4 || summary.Failed != 1 || summary.Amount != 300000-2*testFee || summary.Fees != 2*testFee {
+		t.Errorf("summary %+v", summary)
+	}
+	if balance, _ := mock.Balance(s.Destination[:]); balance != 5000+300000-2*testFee {
+		t.Errorf("destination balance %d", balance)
+	}
+	for _, account := range accounts[:2] {
+		entry, signed := journal.Get(hex.EncodeToString(account.source[:]))
+		if !signed || entry.TxID != account.TxID || entry.Amount != account.Amount {
+			t.Errorf("line %d: journal %+v, %v", account.Line, entry, signed)
+		}
+		// The tip the sweep started at is start, or a block mined since
+		if account.BlockToLive < start+20 || account.BlockToLive > start+21 || account.Block <= start || account.Block > account.BlockToLive || account.Amount != account.Balance-testFee {
+			t.Errorf("line %d: %+v, started at %d", account.Line, account, start)
+		}
+		// The tag moved to the second key of the seed, empty
+		next := keyAt(account.seed, 1)
+		next.Wipe()
+		resolution, err := s.Client.ResolveTag(context.Background(), account.tag[:])
+		if err != nil || resolution.Amount != 0 || resolution.AddrHash() != next.AddrHash() {
+			t.Errorf("line %d after the sweep: %+v, %v", account.Line, resolution, err)
+		}
+	}
+	if len(mock.Submitted()) != 2 || accounts[0].TxID == accounts[1].TxID {
+		t.Errorf("%d submitted, txids %s and %s", len(mock.Submitted()), accounts[0].TxID, accounts[1].TxID)
+	}
+
+	// Funded again, an account is swept with its second key
+	resolution, _ := s.Client.ResolveTag(context.Background(), accounts[0].tag[:])
+	mock.SetAccount(accounts[0].tag[:], resolution.AddressHex, 50000)
+	again := accountsOf(funded)
+	defer Wipe(again)
+	if err := startSweep(t, mock, s, again).mineUntilDone(); err != nil {
+		t.Fatal(err)
+	}
+	checkStatuses(t, again, StatusSwept)
+	if again[0].generation != 1 {
+		t.Errorf("swept with key %d", again[0].generation)
+	}
+	if balance, _ := mock.Balance(s.Destination[:]); balance != 5000+350000-3*testFee {
+		t.Errorf("destination balance %d", balance)
+	}
+}
+
+// TestSweepReorg replaces the block holding a sweep before it has its confirmations: the sweep is found again in the next block
+func TestSweepReorg(t *testing.T) {
+	mock := meshmock.New()
+	defer mock.Close()
+	mock.MineBlock()
+	seed := testSeed("funded")
+	fund(mock, seed, 100000)
+	accounts := accountsOf(seed)
+	defer Wipe(accounts)
+	run := startSweep(t, mock, &Sweeper{Destination: tagOf(testSeed("destination")), Fee: testFee, Confirmations: 2}, accounts)
+
+	run.waitLog("submitted")
+	height := mock.MineBlock()
+	run.waitLog("mined in block 2")
+	mock.Reorg(1, true)
+	run.waitLog("block 2 holding")
+	mock.MineBlock()
+	run.waitLog("mined in block 3")
+	mock.MineBlock()
+	if err := run.mineUntilDone(); err != nil {
+		t.Fatal(err)
+	}
+	checkStatuses(t, accounts, StatusSwept)
+	if accounts[0].Block != height+1 {
+		t.Errorf("swept in block %d", accounts[0].Block)
+	}
+}
+
+// TestSweepBlockToLive keeps a sweep out of the blocks past its block to live: the account fails, its funds left
+func TestSweepBlockToLive(t *testing.T) {
+	mock := meshmock.New()
+	defer mock.Close()
+	mock.MineBlock()
+	seed := testSeed("funded")
+	fund(mock, seed, 100000)
+	mock.HoldNext(1000, false)
+	accounts := accountsOf(seed)
+	defer Wipe(accounts)
+	run := startSweep(t, mock, &Sweeper{Destination: tagOf(testSeed("destination")), Fee: testFee, BlockToLive: 3, Confirmations: 1}, accounts)
+	if err := run.mineUntilDone(); err != nil {
+		t.Fatal(err)
+	}
+	checkStatuses(t, accounts, StatusFailed)
+	if accounts[0].Reason != "not mined by its block to live, block 4" {
+		t.Errorf("reason %q", accounts[0].Reason)
+	}
+	tag := tagOf(seed)
+	if balance, _ := mock.Balance(tag[:]); balance != 100000 {
+		t.Errorf("balance %d", balance)
+	}
+}
+
+/*
+ * TestSignMatchesMcminterface signs the sweeps of a seed's first and next
+ * keys, with a block to live, and compares them byte for byte with the
+ * transactions go_mcminterface builds for the same transfers
+ */
+func TestSignMatchesMcminterface(t *testing.T) {
+	seed, destination := testSeed("funded"), tagOf(testSeed("destination"))
+	tag := tagOf(seed)
+	for _, generation := range []uint64{0, 1} {
+		key, change := keyAt(seed, generation), keyAt(seed, generation+1)
+		account := &Account{seed: seed, tag: tag, generation: generation, source: txbuild.Address(tag, key.PublicKey),
+			Balance: 100000, Amount: 100000 - testFee, Fee: testFee}
+		s := &Sweeper{Destination: destination, Fee: testFee, BlockToLive: 3}
+		if err := s.sign(account, 40); err != nil {
+			t.Fatal(err)
+		}
+
+		want := mcm.NewTXENTRY()
+		source := mcm.WotsAddressFromBytes(key.PublicKey[:])
+		source.SetTAG(tag[:])
+		changeAddress := mcm.WotsAddressFromBytes(change.PublicKey[:])
+		changeAddress.SetTAG(tag[:])
+		want.SetSourceAddress(source)
+		want.SetChangeAddress(changeAddress)
+		want.SetSendTotal(100000 - testFee)
+		want.SetChangeTotal(0)
+		want.SetFee(testFee)
+		want.SetBlockToLive(43)
+		want.AddDestination(mcm.NewDSTFromString(hex.EncodeToString(destination[:]), "", 100000-testFee))
+		want.SetDestinationCount(1)
+		signature := key.Sign(want.GetMessageToSign())
+		want.SetWotsSignature(signature[:])
+		want.SetWotsSigAddresses(key.Address[:])
+		want.SetWotsSigPubSeed(key.Components.PublicSeed)
+		key.Wipe()
+		change.Wipe()
+
+		if account.signedTx != want.String() {
+			t.Errorf("generation %d: signed\n%s\nwant\n%s", generation, account.signedTx, want.String())
+		}
+		if account.BlockToLive != 43 {
+			t.Errorf("generation %d: block to live %d", generation, account.BlockToLive)
+		}
+	}
+}
+
+/*
+ * TestSweepJournal sweeps keys that already signed: a failed submit is
+ * broadcast again from the journal, and an entry that can no longer be
+ * broadcast fails the account rather than sign a second time
+ */
+func TestSweepJournal(t *testing.T) {
+	mock := meshmock.New()
+	defer mock.Close()
+	// At height 3, past the block to live of the expired entry
+	for i := 0; i < 3; i++ {
+		mock.MineBlock()
+	}
+	replayed, expired, changed := testSeed("replayed"), testSeed("expired"), testSeed("changed")
+	for _, seed := range [][secure.KeyLength]byte{replayed, expired, changed} {
+		fund(mock, seed, 100000)
+	}
+	path := filepath.Join(t.TempDir(), "journal.json")
+	journal, err := OpenJournal(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	source := func(seed [secure.KeyLength]byte) string {
+		tag := tagOf(seed)
+		return hex.EncodeToString(tag[:]) + hex.EncodeToString(tag[:])
+	}
+	journal.Put(JournalEntry{Source: source(expired), SignedTx: "00", Amount: 100000 - testFee, Fee: testFee, BlockToLive: 1})
+	journal.Put(JournalEntry{Source: source(changed), SignedTx: "00", Amount: 50000 - testFee, Fee: testFee})
+
+	mock.Fail("/construction/submit", meshmock.Fault{Status: 500, Body: "node restarting"})
+	s := &Sweeper{Destination: tagOf(testSeed("destination")), Fee: testFee, Confirmations: 1, Journal: journal}
+	accounts := accountsOf(replayed, expired, changed)
+	defer Wipe(accounts)
+	if err := startSweep(t, mock, s, accounts).mineUntilDone(); err != nil {
+		t.Fatal(err)
+	}
+	checkStatuses(t, accounts, StatusFailed, StatusFailed, StatusFailed)
+	for i, reason := range []string{"submit failed", "could not be mined after block 1", "the balance is now 0.0001"} {
+		if !strings.Contains(accounts[i].Reason, reason) {
+			t.Errorf("line %d: reason %q, want %q", i+1, accounts[i].Reason, reason)
+		}
+	}
+	first, signed := journal.Get(source(replayed))
+	if !signed || len(mock.Submitted()) != 0 {
+		t.Fatalf("journal %+v, %d submitted", first, len(mock.Submitted()))
+	}
+
+	// A new run reads the journal back and broadcasts the same transaction
+	if s.Journal, err = OpenJournal(path); err != nil {
+		t.Fatal(err)
+	}
+	accounts = accountsOf(replayed)
+	defer Wipe(accounts)
+	if err := startSweep(t, mock, s, accounts).mineUntilDone(); err != nil {
+		t.Fatal(err)
+	}
+	checkStatuses(t, accounts, StatusSwept)
+	if submitted := mock.Submitted(); len(submitted) != 1 || submitted[0] != first.SignedTx {
+		t.Errorf("submitted %d transactions, not the one of the journal", len(submitted))
+	}
+	if second, _ := s.Journal.Get(source(replayed)); second.TxID == "" || second.TxID != accounts[0].TxID {
+		t.Errorf("journal txid %q, swept %q", second.TxID, accounts[0].TxID)
+	}
+}
+
+// TestPlanLookupFailed fails the accounts whose balance could not be read, the others planned as usual
+func TestPlanLookupFailed(t *testing.T) {
+	mock := meshmock.New()
+	defer mock.Close()
+	fund(mock, testSeed("funded"), 100000)
+	mock.Outage(meshmock.Fault{Status: 503}, time.Minute)
+	s := &Sweeper{Client: meshclient.NewMeshAPIClient(mock.URL(), nil), Destination: tagOf(testSeed("destination")), Fee: testFee}
+	accounts := accountsOf(testSeed("funded"))
+	defer Wipe(accounts)
+	if err := s.Plan(context.Background(), accounts, 1); err != nil {
+		t.Fatal(err)
+	}
+	checkStatuses(t, accounts, StatusFailed)
+	if !strings.HasPrefix(accounts[0].Reason, "balance lookup failed") || accounts[0].ready {
+		t.Errorf("account %+v", accounts[0])
+	}
+}
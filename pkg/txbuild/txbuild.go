@@ -0,0 +1,133 @@
+/*
+ * Package txbuild builds and signs the transactions the tools submit, the
+ * one builder wallet-tool, tool-3 and mcm-sweep share.
+ *
+ * The bytes are the ones go_mcminterface writes for the same transfer:
+ * destinations sorted by tag then reference, the options counting them
+ * less one, and the zero nonce and ID trailer the node fills in. Unlike
+ * go_mcminterface, the totals are checked for overflow and every memo
+ * against the reference rules before anything is signed:
+ *
+ *	payment, _ := txbuild.NewDestination(tag, "INV-12", 2500)
+ *	tx, _ := txbuild.NewTransfer(source, change, balance, fee, []txentry.Destination{payment})
+ *	err := txbuild.Sign(tx, &keypair)
+ *	submit(hex.EncodeToString(tx.Bytes()))
+ */
+package txbuild
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"math/bits"
+	"sort"
+
+	"github.com/NickP005/Vindax-MCM-tools/pkg/secure"
+	"github.com/NickP005/Vindax-MCM-tools/pkg/txentry"
+	"github.com/NickP005/Vindax-MCM-tools/pkg/wotsp"
+)
+
+// ErrInsufficientBalance is returned by NewTransfer when the balance does not cover the payments and the fee
+var ErrInsufficientBalance = errors.New("insufficient balance")
+
+// Address returns the 40 bytes address of a tag and a WOTS+ public key: the tag, then the address hash of the key
+func Address(tag [txentry.TagLength]byte, pk [wotsp.SigSize]byte) [txentry.AddressLength]byte {
+	var address [txentry.AddressLength]byte
+	copy(address[:txentry.TagLength], tag[:])
+	hash := wotsp.AddrHash(pk)
+	copy(address[txentry.TagLength:], hash[:])
+	return address
+}
+
+// NewDestination returns a payment of amount to a tag, with a memo that must follow the reference rules
+func NewDestination(tag [txentry.TagLength]byte, memo string, amount uint64) (txentry.Destination, error) {
+	destination := txentry.Destination{Tag: tag, Amount: amount}
+	if len(memo) > txentry.ReferenceLength {
+		return destination, fmt.Errorf("memo %q longer than %d bytes", memo, txentry.ReferenceLength)
+	}
+	copy(destination.Reference[:], memo)
+	if !destination.ValidMemo() {
+		return destination, fmt.Errorf("invalid memo %q", memo)
+	}
+	return destination, nil
+}
+
+/*
+ * NewTransfer builds an unsigned transaction spending balance from source:
+ * the destinations are paid, the fee goes to the miner and what is left
+ * goes to change
+ *
+ * The source tag moves to the change address, so change normally carries
+ * the same tag with the address hash of the next key. The destinations are
+ * sorted by tag then reference, those equal keeping their order, as
+ * go_mcminterface inserts them. Returns ErrInsufficientBalance, wrapped
+ * with the amounts, when the balance does not cover the payments and the
+ * fee.
+ */
+func NewTransfer(source [txentry.AddressLength]byte, change [txentry.AddressLength]byte, balance uint64, fee uint64, destinations []txentry.Destination) (*txentry.Transaction, error) {
+	if len(destinations) == 0 || len(destinations) > txentry.MaxDestinations {
+		return nil, fmt.Errorf("%d destinations, expected 1 to %d", len(destinations), txentry.MaxDestinations)
+	}
+	tx := &txentry.Transaction{
+		Source:       source,
+		Change:       change,
+		Fee:          fee,
+		Destinations: append([]txentry.Destination(nil), destinations...),
+		HasTrailer:   true,
+	}
+	tx.Options[0] = txentry.DataMultiDestination
+	tx.Options[1] = txentry.SignatureWOTS
+	tx.Options[2] = byte(len(destinations) - 1)
+
+	for i, destination := range destinations {
+		if !destination.ValidMemo() {
+			return nil, fmt.Errorf("destination %d: invalid memo %q", i+1, destination.Memo())
+		}
+		var carry uint64
+		tx.SendTotal, carry = bits.Add64(tx.SendTotal, destination.Amount, 0)
+		if carry != 0 {
+			return nil, fmt.Errorf("destination %d: send total overflows", i+1)
+		}
+	}
+	spent, carry := bits.Add64(fee, tx.SendTotal, 0)
+	if carry != 0 || spent > balance {
+		return nil, fmt.Errorf("%w: %d nanoMCM, sending %d with a fee of %d", ErrInsufficientBalance, balance, tx.SendTotal, fee)
+	}
+	tx.ChangeTotal = balance - spent
+
+	sort.SliceStable(tx.Destinations, func(i, j int) bool {
+		a, b := tx.Destinations[i], tx.Destinations[j]
+		if order := bytes.Compare(a.Tag[:], b.Tag[:]); order != 0 {
+			return order < 0
+		}
+		return bytes.Compare(a.Reference[:], b.Reference[:]) < 0
+	})
+	return tx, nil
+}
+
+/*
+ * Sign signs tx with the keypair of its source address, and checks the
+ * signature before returning
+ *
+ * The address hash of the keypair must be the one of the source address:
+ * signing with another key gives a transaction every node rejects. The
+ * keypair must not have signed before, WOTS+ keys being one-time keys.
+ */
+func Sign(tx *txentry.Transaction, keypair *wotsp.Keypair) error {
+	hash := keypair.AddrHash()
+	if !secure.Equal(hash[:], tx.Source[txentry.TagLength:]) {
+		return fmt.Errorf("the keypair does not own the source address")
+	}
+	tx.Signature = keypair.Sign(tx.MessageHash())
+	tx.PubSeed = keypair.Components.PublicSeed
+	tx.AddrSeed = keypair.Address
+
+	valid, _, err := tx.VerifySignature()
+	if err != nil {
+		return err
+	}
+	if !valid {
+		return fmt.Errorf("the signature does not verify against the source address")
+	}
+	return nil
+}
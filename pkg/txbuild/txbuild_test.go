@@ -0,0 +1,162 @@
+package txbuild
+
+import (
+	"bytes"
+	"encoding/hex"
+	"errors"
+	"math"
+	"strings"
+	"testing"
+
+	"github.com/NickP005/Vindax-MCM-tools/pkg/txentry"
+	"github.com/NickP005/Vindax-MCM-tools/pkg/wotsp"
+	mcm "github.com/NickP005/go_mcminterface"
+)
+
+// mcmTransfer builds and signs a transfer with go_mcminterface, as wallet-tool and tool-3 did before txbuild
+func mcmTransfer(key, next *wotsp.Keypair, tag [txentry.TagLength]byte, balance uint64, fee uint64, destinations []txentry.Destination) mcm.TXENTRY {
+	tx := mcm.NewTXENTRY()
+	source := mcm.WotsAddressFromBytes(key.PublicKey[:wotsp.SigSize])
+	source.SetTAG(tag[:])
+	change := mcm.WotsAddressFromBytes(next.PublicKey[:wotsp.SigSize])
+	change.SetTAG(tag[:])
+	tx.SetSourceAddress(source)
+	tx.SetChangeAddress(change)
+	var total uint64
+	for _, destination := range destinations {
+		total += destination.Amount
+		tx.AddDestination(mcm.NewDSTFromString(hex.EncodeToString(destination.Tag[:]), destination.Memo(), destination.Amount))
+	}
+	tx.SetSendTotal(total)
+	tx.SetChangeTotal(balance - total - fee)
+	tx.SetFee(fee)
+	tx.SetDestinationCount(uint8(len(destinations)))
+	signature := key.Sign(tx.GetMessageToSign())
+	tx.SetWotsSignature(signature[:])
+	tx.SetWotsSigAddresses(key.Address[:])
+	tx.SetWotsSigPubSeed(key.Components.PublicSeed)
+	tx.SetSignatureScheme("wotsp")
+	tx.SetBlockToLive(0)
+	return tx
+}
+
+// destination is a payment to a tag starting with first, the rest of it filled with 0x5a
+func destination(t *testing.T, first byte, memo string, amount uint64) txentry.Destination {
+	t.Helper()
+	tag := [txentry.TagLength]byte{first}
+	for i := 1; i < len(tag); i++ {
+		tag[i] = 0x5a
+	}
+	d, err := NewDestination(tag, memo, amount)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return d
+}
+
+/*
+ * TestMatchesMcminterface builds the same transfers with txbuild and with
+ * go_mcminterface, and compares the bytes: destinations given out of
+ * order, sharing a tag, repeated, and the 256 an options byte can count
+ */
+func TestMatchesMcminterface(t *testing.T) {
+	key, next := wotsp.Keygen([32]byte{1}), wotsp.Keygen([32]byte{2})
+	tag := key.AddrHash()
+	var many []txentry.Destination
+	for i := 0; i < txentry.MaxDestinations; i++ {
+		many = append(many, destination(t, byte(255-i), "", uint64(i+1)))
+	}
+
+	for _, tc := range []struct {
+		name         string
+		destinations []txentry.Destination
+	}{
+		{"one", []txentry.Destination{destination(t, 0x42, "INV-12", 2500)}},
+		{"unsorted", []txentry.Destination{destination(t, 0x43, "", 1000), destination(t, 0x41, "A-1", 2000), destination(t, 0x42, "", 3000)}},
+		{"same tag", []txentry.Destination{destination(t, 0x42, "INV-2", 10), destination(t, 0x42, "", 20), destination(t, 0x42, "INV-10", 30)}},
+		{"repeated", []txentry.Destination{destination(t, 0x42, "X", 10), destination(t, 0x41, "", 20), destination(t, 0x42, "X", 30), destination(t, 0x42, "X", 40)}},
+		{"256", many},
+	} {
+		tx, err := NewTransfer(Address(tag, key.PublicKey), Address(tag, next.PublicKey), math.MaxUint64/2, 500, tc.destinations)
+		if err != nil {
+			t.Fatalf("%s: %v", tc.name, err)
+		}
+		if err := Sign(tx, &key); err != nil {
+			t.Fatalf("%s: %v", tc.name, err)
+		}
+		want := mcmTransfer(&key, &next, tag, math.MaxUint64/2, 500, tc.destinations)
+		if tx.MessageHash() != want.GetMessageToSign() {
+			t.Errorf("%s: message hash differs", tc.name)
+		}
+		if !bytes.Equal(tx.Bytes(), want.Bytes()) {
+			t.Errorf("%s: bytes differ\n%x\nwant\n%x", tc.name, tx.Bytes()[:txentry.HeaderLength+txentry.DestinationLength], want.Bytes()[:txentry.HeaderLength+txentry.DestinationLength])
+		}
+
+		decoded, err := txentry.Decode(tx.Bytes())
+		if err != nil {
+			t.Fatalf("%s: %v", tc.name, err)
+		}
+		if valid, _, err := decoded.VerifySignature(); !valid || err != nil || !decoded.HasTrailer || len(decoded.Destinations) != len(tc.destinations) {
+			t.Errorf("%s: decoded %d destinations, trailer %v, signature %v, %v", tc.name, len(decoded.Destinations), decoded.HasTrailer, valid, err)
+		}
+	}
+
+	// Destinations equal in tag and reference keep their order
+	tx, _ := NewTransfer(Address(tag, key.PublicKey), Address(tag, next.PublicKey), 1000, 0, []txentry.Destination{
+		destination(t, 0x42, "X", 10), destination(t, 0x41, "", 20), destination(t, 0x42, "X", 30)})
+	if tx.Destinations[0].Amount != 20 || tx.Destinations[1].Amount != 10 || tx.Destinations[2].Amount != 30 || tx.Options[2] != 2 {
+		t.Errorf("destinations %+v, options %v", tx.Destinations, tx.Options)
+	}
+}
+
+func TestNewTransfer(t *testing.T) {
+	key, next := wotsp.Keygen([32]byte{1}), wotsp.Keygen([32]byte{2})
+	tag := key.AddrHash()
+	source, change := Address(tag, key.PublicKey), Address(tag, next.PublicKey)
+	payment := destination(t, 0x42, "", 1000)
+
+	tx, err := NewTransfer(source, change, 1500, 500, []txentry.Destination{payment})
+	if err != nil || tx.SendTotal != 1000 || tx.ChangeTotal != 0 || tx.Fee != 500 {
+		t.Fatalf("whole balance: %+v, %v", tx, err)
+	}
+	if _, err := NewTransfer(source, change, 1499, 500, []txentry.Destination{payment}); !errors.Is(err, ErrInsufficientBalance) {
+		t.Errorf("short of 1: %v", err)
+	}
+	if _, err := NewTransfer(source, change, math.MaxUint64, math.MaxUint64, []txentry.Destination{payment}); !errors.Is(err, ErrInsufficientBalance) {
+		t.Errorf("fee overflowing: %v", err)
+	}
+	huge := destination(t, 0x43, "", math.MaxUint64)
+	if _, err := NewTransfer(source, change, math.MaxUint64, 0, []txentry.Destination{payment, huge}); err == nil || !strings.Contains(err.Error(), "destination 2: send total overflows") {
+		t.Errorf("send total overflowing: %v", err)
+	}
+	invalid := payment
+	copy(invalid.Reference[:], "AB-CD")
+	if _, err := NewTransfer(source, change, 1500, 500, []txentry.Destination{invalid}); err == nil || !strings.Contains(err.Error(), "invalid memo") {
+		t.Errorf("invalid memo: %v", err)
+	}
+	for _, n := range []int{0, txentry.MaxDestinations + 1} {
+		if _, err := NewTransfer(source, change, math.MaxUint64, 0, make([]txentry.Destination, n)); err == nil {
+			t.Errorf("%d destinations accepted", n)
+		}
+	}
+
+	// Only the key of the source address signs
+	if err := Sign(tx, &next); err == nil || !strings.Contains(err.Error(), "does not own the source address") {
+		t.Errorf("signed by the change key: %v", err)
+	}
+	if tx.Signature != ([wotsp.SigSize]byte{}) {
+		t.Error("signature set by a key that does not own the source")
+	}
+}
+
+func TestNewDestination(t *testing.T) {
+	if d, err := NewDestination([txentry.TagLength]byte{1}, "ORDER-1234567890", 5); err != nil || d.Memo() != "ORDER-1234567890" {
+		t.Errorf("16 bytes memo: %q, %v", d.Memo(), err)
+	}
+	if _, err := NewDestination([txentry.TagLength]byte{1}, "ORDER-12345678901", 5); err == nil || !strings.Contains(err.Error(), "longer than 16 bytes") {
+		t.Errorf("17 bytes memo: %v", err)
+	}
+	if _, err := NewDestination([txentry.TagLength]byte{1}, "AB-CD", 5); err == nil {
+		t.Error("AB-CD accepted")
+	}
+}
@@ -0,0 +1,36 @@
+package txentry
+
+import "encoding/binary"
+
+// MaxDestinations is the number of destinations the options byte can count
+const MaxDestinations = 256
+
+// encodeSigned serializes the header and destinations, the bytes the signature covers
+func (tx *Transaction) encodeSigned() []byte {
+	out := make([]byte, 0, HeaderLength+len(tx.Destinations)*DestinationLength)
+	out = append(out, tx.Options[:]...)
+	out = append(out, tx.Source[:]...)
+	out = append(out, tx.Change[:]...)
+	for _, total := range []uint64{tx.SendTotal, tx.ChangeTotal, tx.Fee, tx.BlockToLive} {
+		out = binary.LittleEndian.AppendUint64(out, total)
+	}
+	for _, destination := range tx.Destinations {
+		out = append(out, destination.Tag[:]...)
+		out = append(out, destination.Reference[:]...)
+		out = binary.LittleEndian.AppendUint64(out, destination.Amount)
+	}
+	return out
+}
+
+// Bytes serializes the transaction as Decode reads it, with the trailer when HasTrailer is set
+func (tx *Transaction) Bytes() []byte {
+	out := tx.encodeSigned()
+	out = append(out, tx.Signature[:]...)
+	out = append(out, tx.PubSeed[:]...)
+	out = append(out, tx.AddrSeed[:]...)
+	if tx.HasTrailer {
+		out = binary.LittleEndian.AppendUint64(out, tx.Nonce)
+		out = append(out, tx.ID[:]...)
+	}
+	return out
+}
@@ -0,0 +1,279 @@
+/*
+ * Package txentry decodes Mochimo 3.0 signed transactions (TXENTRY) with
+ * every length checked.
+ *
+ * The layout is the one of go_mcminterface: a 116 bytes header (options,
+ * source and change addresses, send, change and fee totals, block-to-live),
+ * one 44 bytes entry per destination (tag, reference, amount), the 2208
+ * bytes WOTS+ validation data (signature, public seed, address scheme) and
+ * an optional 40 bytes trailer (nonce, transaction ID). Integers are
+ * little-endian. Unlike mcm.TransactionFromBytes, malformed input is
+ * reported as a *DecodeError naming the offset and the field, never a panic.
+ * Transactions are built and signed with pkg/txbuild.
+ */
+package txentry
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"fmt"
+	"strings"
+
+	"github.com/NickP005/Vindax-MCM-tools/pkg/secure"
+	"github.com/NickP005/Vindax-MCM-tools/pkg/wotsp"
+)
+
+// Sizes of the parts of a transaction, in bytes
+const (
+	AddressLength     = 40
+	TagLength         = 20
+	ReferenceLength   = 16
+	HeaderLength      = 4 + 2*AddressLength + 4*8
+	DestinationLength = TagLength + ReferenceLength + 8
+	ValidationLength  = wotsp.SigSize + 32 + 32
+	TrailerLength     = 8 + 32
+)
+
+// Type codes of the options bytes
+const (
+	DataMultiDestination = 0x00
+	SignatureWOTS        = 0x00
+)
+
+// DecodeError is returned for input that is not a transaction; Offset is where the problem is, in bytes
+type DecodeError struct {
+	Offset int
+	Field  string
+	Reason string
+}
+
+func (e *DecodeError) Error() string {
+	return fmt.Sprintf("offset %d (%s): %s", e.Offset, e.Field, e.Reason)
+}
+
+// Destination is one payment of a transaction
+type Destination struct {
+	Tag       [TagLength]byte
+	Reference [ReferenceLength]byte
+	Amount    uint64
+}
+
+// Memo returns the reference up to its first NUL byte, empty when unset
+func (d Destination) Memo() string {
+	memo := string(d.Reference[:])
+	if i := strings.IndexByte(memo, 0); i >= 0 {
+		memo = memo[:i]
+	}
+	return memo
+}
+
+/*
+ * ValidMemo reports whether the reference follows the Mochimo rules, or is
+ * unset
+ *
+ * A reference is groups of uppercase letters or of digits, never both in a
+ * group, separated by single dashes, two neighbouring groups of different
+ * kinds: "AB-12-CD", "123-ABC" and "XYZ" are valid, "AB-CD", "12-34",
+ * "ABC-" and "-123" are not. It ends at the first NUL byte.
+ */
+func (d Destination) ValidMemo() bool {
+	const digits, letters = 1, 2
+	group, previous := 0, 0 // kind of the current and of the previous group, 0 before the first character of a group
+	for i, c := range d.Reference {
+		kind := 0
+		switch {
+		case c == 0:
+			return i == 0 || group != 0
+		case c == '-':
+			if group == 0 {
+				return false
+			}
+			group, previous = 0, group
+			continue
+		case c >= '0' && c <= '9':
+			kind = digits
+		case c >= 'A' && c <= 'Z':
+			kind = letters
+		default:
+			return false
+		}
+		switch {
+		case group == 0 && kind == previous:
+			return false
+		case group == 0:
+			group = kind
+		case kind != group:
+			return false
+		}
+	}
+	return group != 0
+}
+
+// Transaction is a decoded TXENTRY
+type Transaction struct {
+	Options      [4]byte
+	Source       [AddressLength]byte
+	Change       [AddressLength]byte
+	SendTotal    uint64
+	ChangeTotal  uint64
+	Fee          uint64
+	BlockToLive  uint64
+	Destinations []Destination
+	Signature    [wotsp.SigSize]byte
+	PubSeed      [32]byte
+	AddrSeed     [32]byte
+	// HasTrailer is set when the input ends with the nonce and ID, as in blocks
+	HasTrailer bool
+	Nonce      uint64
+	ID         [32]byte
+
+	// signed holds the header and destinations as decoded, the bytes the
+	// signature covers; nil for a transaction built, its fields encoded instead
+	signed []byte
+}
+
+// reader hands out the fields of the input in order, reporting where it ran short
+type reader struct {
+	data []byte
+	pos  int
+}
+
+func (r *reader) take(n int, field string) ([]byte, error) {
+	if left := len(r.data) - r.pos; left < n {
+		return nil, &DecodeError{Offset: r.pos, Field: field, Reason: fmt.Sprintf("truncated: %d bytes left, need %d", left, n)}
+	}
+	b := r.data[r.pos : r.pos+n]
+	r.pos += n
+	return b, nil
+}
+
+func (r *reader) uint64(field string) (uint64, error) {
+	b, err := r.take(8, field)
+	if err != nil {
+		return 0, err
+	}
+	return binary.LittleEndian.Uint64(b), nil
+}
+
+/*
+ * Decode parses a signed transaction, with or without its trailer
+ *
+ * Only the multi-destination data type and the WOTS+ signature scheme are
+ * known; any other type code is a *DecodeError, as is input running short
+ * of a field or bytes left over after the last one.
+ */
+func Decode(raw []byte) (*Transaction, error) {
+	r := &reader{data: raw}
+	tx := &Transaction{}
+
+	options, err := r.take(4, "options")
+	if err != nil {
+		return nil, err
+	}
+	copy(tx.Options[:], options)
+	if tx.Options[0] != DataMultiDestination {
+		return nil, &DecodeError{Offset: 0, Field: "options", Reason: fmt.Sprintf("unknown data type 0x%02x", tx.Options[0])}
+	}
+	if tx.Options[1] != SignatureWOTS {
+		return nil, &DecodeError{Offset: 1, Field: "options", Reason: fmt.Sprintf("unknown signature scheme 0x%02x", tx.Options[1])}
+	}
+
+	for _, field := range []struct {
+		name string
+		out  []byte
+	}{{"source address", tx.Source[:]}, {"change address", tx.Change[:]}} {
+		b, err := r.take(AddressLength, field.name)
+		if err != nil {
+			return nil, err
+		}
+		copy(field.out, b)
+	}
+	for _, field := range []struct {
+		name string
+		out  *uint64
+	}{{"send total", &tx.SendTotal}, {"change total", &tx.ChangeTotal}, {"fee", &tx.Fee}, {"block-to-live", &tx.BlockToLive}} {
+		if *field.out, err = r.uint64(field.name); err != nil {
+			return nil, err
+		}
+	}
+
+	count := int(tx.Options[2]) + 1
+	tx.Destinations = make([]Destination, 0, count)
+	for i := 0; i < count; i++ {
+		b, err := r.take(DestinationLength, fmt.Sprintf("destination %d of %d", i+1, count))
+		if err != nil {
+			return nil, err
+		}
+		var dst Destination
+		copy(dst.Tag[:], b[:TagLength])
+		copy(dst.Reference[:], b[TagLength:TagLength+ReferenceLength])
+		dst.Amount = binary.LittleEndian.Uint64(b[TagLength+ReferenceLength:])
+		tx.Destinations = append(tx.Destinations, dst)
+	}
+	tx.signed = raw[:r.pos]
+
+	for _, field := range []struct {
+		name string
+		out  []byte
+	}{{"signature", tx.Signature[:]}, {"public seed", tx.PubSeed[:]}, {"address seed", tx.AddrSeed[:]}} {
+		b, err := r.take(len(field.out), field.name)
+		if err != nil {
+			return nil, err
+		}
+		copy(field.out, b)
+	}
+
+	switch left := len(raw) - r.pos; left {
+	case 0:
+	case TrailerLength:
+		tx.HasTrailer = true
+		tx.Nonce, _ = r.uint64("nonce")
+		id, _ := r.take(32, "transaction ID")
+		copy(tx.ID[:], id)
+	default:
+		return nil, &DecodeError{Offset: r.pos, Field: "trailer", Reason: fmt.Sprintf("%d bytes left after the signature, expected none or a %d bytes trailer", left, TrailerLength)}
+	}
+	return tx, nil
+}
+
+// SignatureScheme names the signature scheme of the options, "wotsp" for every decoded transaction
+func (tx *Transaction) SignatureScheme() string {
+	if tx.Options[1] == SignatureWOTS {
+		return "wotsp"
+	}
+	return fmt.Sprintf("unknown (0x%02x)", tx.Options[1])
+}
+
+// MessageHash returns the SHA-256 of the header and destinations, the message the WOTS+ signature signs
+func (tx *Transaction) MessageHash() [32]byte {
+	return sha256.Sum256(tx.signedBytes())
+}
+
+// SignedLength is the length of the header and destinations, the part of the input the signature covers
+func (tx *Transaction) SignedLength() int {
+	return len(tx.signedBytes())
+}
+
+// signedBytes returns the bytes the signature covers, as decoded or else encoded from the fields
+func (tx *Transaction) signedBytes() []byte {
+	if tx.signed == nil {
+		return tx.encodeSigned()
+	}
+	return tx.signed
+}
+
+/*
+ * VerifySignature recomputes the WOTS+ public key from the signature and
+ * reports whether it derives the address hash of the source address
+ *
+ * Returns the address hash derived, to show what key actually signed.
+ */
+func (tx *Transaction) VerifySignature() (bool, [wotsp.AddrHashLength]byte, error) {
+	message := tx.MessageHash()
+	pk, err := wotsp.PkFromSigBytes(tx.Signature[:], message[:], tx.PubSeed[:], tx.AddrSeed[:])
+	if err != nil {
+		return false, [wotsp.AddrHashLength]byte{}, err
+	}
+	derived := wotsp.AddrHash(pk)
+	return secure.Equal(derived[:], tx.Source[TagLength:]), derived, nil
+}
@@ -0,0 +1,160 @@
+package txentry
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"github.com/NickP005/Vindax-MCM-tools/pkg/wotsp"
+)
+
+// signedTransfer is a transfer of 2500 to "INV-12" and 1000 without memo, signed by the key of seed 1
+func signedTransfer(t testing.TB) *Transaction {
+	t.Helper()
+	key, next := wotsp.Keygen([32]byte{1}), wotsp.Keygen([32]byte{2})
+	tag, hash, nextHash := key.AddrHash(), key.AddrHash(), next.AddrHash()
+	tx := &Transaction{SendTotal: 3500, ChangeTotal: 6000, Fee: 500}
+	tx.Options[0], tx.Options[1], tx.Options[2] = DataMultiDestination, SignatureWOTS, 1
+	copy(tx.Source[:], tag[:])
+	copy(tx.Source[TagLength:], hash[:])
+	copy(tx.Change[:], tag[:])
+	copy(tx.Change[TagLength:], nextHash[:])
+	first := Destination{Tag: [TagLength]byte{0x42}, Amount: 2500}
+	copy(first.Reference[:], "INV-12")
+	tx.Destinations = []Destination{first, {Tag: [TagLength]byte{0x43}, Amount: 1000}}
+	tx.Signature = key.Sign(tx.MessageHash())
+	tx.PubSeed, tx.AddrSeed = key.Components.PublicSeed, key.Address
+	return tx
+}
+
+func TestDecodeRoundTrip(t *testing.T) {
+	tx := signedTransfer(t)
+	raw := tx.Bytes()
+	if len(raw) != HeaderLength+2*DestinationLength+ValidationLength {
+		t.Fatalf("%d bytes", len(raw))
+	}
+	decoded, err := Decode(raw)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if decoded.SendTotal != 3500 || decoded.ChangeTotal != 6000 || decoded.Fee != 500 || decoded.HasTrailer {
+		t.Errorf("totals %d %d %d, trailer %v", decoded.SendTotal, decoded.ChangeTotal, decoded.Fee, decoded.HasTrailer)
+	}
+	if len(decoded.Destinations) != 2 || decoded.Destinations[0].Memo() != "INV-12" || decoded.Destinations[1].Amount != 1000 {
+		t.Errorf("destinations %+v", decoded.Destinations)
+	}
+	if decoded.MessageHash() != tx.MessageHash() || decoded.SignedLength() != HeaderLength+2*DestinationLength || decoded.SignatureScheme() != "wotsp" {
+		t.Error("signed part differs")
+	}
+	if valid, _, err := decoded.VerifySignature(); !valid || err != nil {
+		t.Errorf("signature: %v, %v", valid, err)
+	}
+	if !bytes.Equal(decoded.Bytes(), raw) {
+		t.Error("Bytes does not give back the input")
+	}
+
+	// The trailer of blocks is read when present
+	withTrailer := append(append([]byte{}, raw...), make([]byte, TrailerLength)...)
+	withTrailer[len(raw)] = 7
+	withTrailer[len(withTrailer)-1] = 0xff
+	decoded, err = Decode(withTrailer)
+	if err != nil || !decoded.HasTrailer || decoded.Nonce != 7 || decoded.ID[31] != 0xff {
+		t.Fatalf("trailer: %+v, %v", decoded, err)
+	}
+
+	// A changed amount is still decoded, but no longer verifies
+	raw[HeaderLength+TagLength+ReferenceLength]++
+	decoded, err = Decode(raw)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if valid, _, err := decoded.VerifySignature(); valid || err != nil {
+		t.Errorf("tampered amount: %v, %v", valid, err)
+	}
+}
+
+func TestDecodeErrors(t *testing.T) {
+	raw := signedTransfer(t).Bytes()
+	with := func(offset int, b byte) []byte {
+		changed := append([]byte{}, raw...)
+		changed[offset] = b
+		return changed
+	}
+	for _, tc := range []struct {
+		name   string
+		input  []byte
+		offset int
+		field  string
+	}{
+		{"empty", nil, 0, "options"},
+		{"data type", with(0, 1), 0, "options"},
+		{"signature scheme", with(1, 2), 1, "options"},
+		{"short address", raw[:50], 4 + AddressLength, "change address"},
+		{"short header", raw[:HeaderLength-3], HeaderLength - 8, "block-to-live"},
+		{"short destination", raw[:HeaderLength+DestinationLength+10], HeaderLength + DestinationLength, "destination 2 of 2"},
+		{"destination count", with(2, 0xff), HeaderLength + 52*DestinationLength, "destination 53 of 256"},
+		{"short signature", raw[:len(raw)-100], HeaderLength + 2*DestinationLength, "signature"},
+		{"extra bytes", append(append([]byte{}, raw...), 1, 2, 3), len(raw), "trailer"},
+	} {
+		_, err := Decode(tc.input)
+		var decodeErr *DecodeError
+		if !errors.As(err, &decodeErr) || decodeErr.Offset != tc.offset || decodeErr.Field != tc.field {
+			t.Errorf("%s: %v", tc.name, err)
+		}
+	}
+}
+
+func TestValidMemo(t *testing.T) {
+	for memo, valid := range map[string]bool{
+		"":                 true,
+		"XYZ":              true,
+		"INV-12":           true,
+		"AB-12-CD":         true,
+		"123-ABC":          true,
+		"1-A-2-B-3-C-4-D":  true,
+		"AB-CD":            false,
+		"12-34":            false,
+		"ABC-":             false,
+		"-123":             false,
+		"A--1":             false,
+		"A1":               false,
+		"inv-12":           false,
+		"INV 12":           false,
+		"INV-1234567890AB": false,
+	} {
+		var d Destination
+		copy(d.Reference[:], memo)
+		if d.ValidMemo() != valid {
+			t.Errorf("%q: valid %v", memo, d.ValidMemo())
+		}
+	}
+	// Bytes after the first NUL are not read
+	d := Destination{Reference: [ReferenceLength]byte{'A', 'B', 0, 'x'}}
+	if !d.ValidMemo() || d.Memo() != "AB" {
+		t.Errorf("NUL terminated: %q, %v", d.Memo(), d.ValidMemo())
+	}
+}
+
+// FuzzDecode checks that no input panics, errors point inside the input and what decodes encodes back to the input
+func FuzzDecode(f *testing.F) {
+	raw := signedTransfer(f).Bytes()
+	f.Add(raw)
+	f.Add(append(append([]byte{}, raw...), make([]byte, TrailerLength)...))
+	f.Add(raw[:HeaderLength+5])
+	f.Add([]byte{})
+	f.Add([]byte{0, 0, 0xff, 0})
+	f.Fuzz(func(t *testing.T, data []byte) {
+		tx, err := Decode(data)
+		if err != nil {
+			var decodeErr *DecodeError
+			if !errors.As(err, &decodeErr) || decodeErr.Offset < 0 || decodeErr.Offset > len(data) || decodeErr.Field == "" {
+				t.Fatalf("unpositioned error: %v", err)
+			}
+			return
+		}
+		if !bytes.Equal(tx.Bytes(), data) {
+			t.Fatal("decoded transaction encodes to other bytes")
+		}
+		tx.VerifySignature()
+	})
+}
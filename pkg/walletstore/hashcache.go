@@ -0,0 +1,145 @@
+package walletstore
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/NickP005/Vindax-MCM-tools/pkg/fileutil"
+	"github.com/NickP005/Vindax-MCM-tools/pkg/wotsp"
+)
+
+// HashCacheMax bounds the indices kept in the derivation cache; the search never goes past MaxIndexSearch
+const HashCacheMax = MaxIndexSearch
+
+/*
+ * DerivationCache remembers the address hash of every wallet index derived,
+ * in a sidecar file of the wallet cache, so the index search of a wallet
+ * with a high index does not derive thousands of WOTS+ keys on every run
+ *
+ * The file is tied to its wallet by a fingerprint of the secret key: a cache
+ * written for another secret is discarded, never consulted. Address hashes
+ * are public, so the file holds nothing secret.
+ */
+type DerivationCache struct {
+	path        string
+	fingerprint string
+	hashes      map[uint64][wotsp.AddrHashLength]byte
+	dirty       bool
+}
+
+// derivationCacheFile is the JSON layout of the sidecar file
+type derivationCacheFile struct {
+	Wallet string            `json:"wallet"`
+	Hashes map[string]string `json:"hashes"`
+}
+
+// DerivationCachePath returns the sidecar file of a wallet cache, e.g. wallet-cache.hashes.json
+func DerivationCachePath(walletCacheFile string) string {
+	return strings.TrimSuffix(walletCacheFile, filepath.Ext(walletCacheFile)) + ".hashes.json"
+}
+
+/*
+ * LoadDerivationCache reads the derivation cache of the wallet with the
+ * given fingerprint, see Keychain.Fingerprint
+ *
+ * A missing file gives an empty cache. A file of another wallet, or one that
+ * cannot be read or decoded, gives an empty cache too, replacing the file on
+ * Save, along with an error telling why it was ignored: it only ever costs
+ * derivations, never a wrong index, so the cache is always usable.
+ */
+func LoadDerivationCache(path string, fingerprint string) (*DerivationCache, error) {
+	cache := &DerivationCache{
+		path:        path,
+		fingerprint: fingerprint,
+		hashes:      make(map[uint64][wotsp.AddrHashLength]byte),
+	}
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return cache, nil
+	}
+	if err != nil {
+		return cache, fmt.Errorf("ignoring derivation cache %s: %v", path, err)
+	}
+
+	var file derivationCacheFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		cache.dirty = true
+		return cache, fmt.Errorf("ignoring derivation cache %s: %v", path, err)
+	}
+	if file.Wallet != fingerprint {
+		cache.dirty = true
+		return cache, fmt.Errorf("derivation cache %s belongs to another wallet secret, starting a new one", path)
+	}
+	for key, value := range file.Hashes {
+		index, err := strconv.ParseUint(key, 10, 64)
+		if err != nil {
+			cache.dirty = true
+			continue
+		}
+		var hash [wotsp.AddrHashLength]byte
+		decoded, err := hex.DecodeString(value)
+		if err != nil || len(decoded) != len(hash) {
+			cache.dirty = true
+			continue
+		}
+		copy(hash[:], decoded)
+		cache.hashes[index] = hash
+	}
+	return cache, nil
+}
+
+// AddrHash returns the cached address hash of an index
+func (c *DerivationCache) AddrHash(index uint64) ([wotsp.AddrHashLength]byte, bool) {
+	hash, ok := c.hashes[index]
+	return hash, ok
+}
+
+// Put records the address hash of an index
+func (c *DerivationCache) Put(index uint64, hash [wotsp.AddrHashLength]byte) {
+	if existing, ok := c.hashes[index]; ok && existing == hash {
+		return
+	}
+	c.hashes[index] = hash
+	c.dirty = true
+}
+
+/*
+ * Save writes the cache if it changed, keeping the HashCacheMax highest
+ * indices
+ *
+ * The wallet only moves up its keychain, so the lowest indices are the ones
+ * least likely to be searched again.
+ */
+func (c *DerivationCache) Save() error {
+	if !c.dirty {
+		return nil
+	}
+	indices := make([]uint64, 0, len(c.hashes))
+	for index := range c.hashes {
+		indices = append(indices, index)
+	}
+	sort.Slice(indices, func(i, j int) bool { return indices[i] > indices[j] })
+	for _, index := range indices[min(len(indices), HashCacheMax):] {
+		delete(c.hashes, index)
+	}
+
+	file := derivationCacheFile{Wallet: c.fingerprint, Hashes: make(map[string]string, len(c.hashes))}
+	for index, hash := range c.hashes {
+		file.Hashes[strconv.FormatUint(index, 10)] = hex.EncodeToString(hash[:])
+	}
+	data, err := json.Marshal(file)
+	if err != nil {
+		return err
+	}
+	if err := fileutil.WriteAtomic(c.path, data, false); err != nil {
+		return fmt.Errorf("failed to write derivation cache: %v", err)
+	}
+	c.dirty = false
+	return nil
+}
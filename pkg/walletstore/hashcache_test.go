@@ -0,0 +1,153 @@
+package walletstore
+
+import (
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/NickP005/Vindax-MCM-tools/pkg/wotsp"
+)
+
+// TestDerivationCache derives indices once, then finds them in the cache of a later run without deriving
+func TestDerivationCache(t *testing.T) {
+	path := DerivationCachePath(filepath.Join(t.TempDir(), "wallet-cache.json"))
+	if filepath.Base(path) != "wallet-cache.hashes.json" {
+		t.Errorf("sidecar %s", path)
+	}
+	keychain, err := NewKeychain(testSecret("a"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer keychain.Wipe()
+	cache, err := LoadDerivationCache(path, keychain.Fingerprint())
+	if err != nil {
+		t.Fatal(err)
+	}
+	keychain.UseDerivationCache(cache)
+
+	// Misses derive the key and extend the cache
+	var want [4][wotsp.AddrHashLength]byte
+	for index := range want {
+		want[index] = keychain.AddrHash(uint64(index))
+	}
+	if len(keychain.keypairs) != 4 || len(cache.hashes) != 4 {
+		t.Fatalf("%d keys derived, %d cached", len(keychain.keypairs), len(cache.hashes))
+	}
+	if err := cache.Save(); err != nil {
+		t.Fatal(err)
+	}
+	// Nothing written when nothing changed
+	os.Remove(path)
+	keychain.AddrHash(2)
+	if err := cache.Save(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Errorf("unchanged cache written: %v", err)
+	}
+	cache.dirty = true
+	cache.Save()
+
+	// A later run hits the cache: nothing is derived, the hashes are the same
+	again, _ := NewKeychain(testSecret("a"))
+	defer again.Wipe()
+	loaded, err := LoadDerivationCache(path, again.Fingerprint())
+	if err != nil {
+		t.Fatal(err)
+	}
+	again.UseDerivationCache(loaded)
+	for index := range want {
+		if again.AddrHash(uint64(index)) != want[index] {
+			t.Errorf("index %d: cached hash differs", index)
+		}
+	}
+	if len(again.keypairs) != 0 {
+		t.Errorf("%d keys derived on cache hits", len(again.keypairs))
+	}
+	if again.AddrHash(4); len(again.keypairs) != 1 {
+		t.Error("index 4 not derived on a miss")
+	}
+}
+
+// TestDerivationCacheWrongSecret loads the cache of one wallet for another: it is never consulted
+func TestDerivationCacheWrongSecret(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "hashes.json")
+	a, _ := NewKeychain(testSecret("a"))
+	defer a.Wipe()
+	cache, _ := LoadDerivationCache(path, a.Fingerprint())
+	a.UseDerivationCache(cache)
+	a.AddrHash(0)
+	if err := cache.Save(); err != nil {
+		t.Fatal(err)
+	}
+
+	b, _ := NewKeychain(testSecret("b"))
+	defer b.Wipe()
+	if a.Fingerprint() == b.Fingerprint() || strings.Contains(a.Fingerprint(), testSecret("a")) {
+		t.Fatal("fingerprints do not tell the secrets apart, or reveal them")
+	}
+	other, err := LoadDerivationCache(path, b.Fingerprint())
+	if err == nil || !strings.Contains(err.Error(), "another wallet secret") || len(other.hashes) != 0 {
+		t.Fatalf("cache of another secret: %d hashes, %v", len(other.hashes), err)
+	}
+	b.UseDerivationCache(other)
+	if b.AddrHash(0) == a.AddrHash(0) {
+		t.Error("wallet b got the hash of wallet a")
+	}
+	// Saving replaces the file with the cache of the new secret
+	if err := other.Save(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadDerivationCache(path, b.Fingerprint()); err != nil {
+		t.Errorf("replaced cache: %v", err)
+	}
+}
+
+func TestDerivationCacheDamaged(t *testing.T) {
+	dir := t.TempDir()
+	a, _ := NewKeychain(testSecret("a"))
+	defer a.Wipe()
+	hash := a.AddrHash(1)
+
+	// An unreadable file is ignored
+	corrupt := filepath.Join(dir, "corrupt.json")
+	os.WriteFile(corrupt, []byte("{not json"), 0600)
+	if cache, err := LoadDerivationCache(corrupt, a.Fingerprint()); err == nil || len(cache.hashes) != 0 || !cache.dirty {
+		t.Errorf("corrupt file: %v", err)
+	}
+
+	// Bad entries are dropped, the others kept
+	partial := filepath.Join(dir, "partial.json")
+	os.WriteFile(partial, []byte(`{"wallet":"`+a.Fingerprint()+`","hashes":{"1":"`+hex.EncodeToString(hash[:])+`","x":"00","2":"abcd","3":"zz"}}`), 0600)
+	cache, err := LoadDerivationCache(partial, a.Fingerprint())
+	if got, ok := cache.AddrHash(1); err != nil || !ok || got != hash || len(cache.hashes) != 1 || !cache.dirty {
+		t.Errorf("partial file: %d hashes, %v", len(cache.hashes), err)
+	}
+}
+
+// TestDerivationCacheCap keeps the HashCacheMax highest indices on Save
+func TestDerivationCacheCap(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "hashes.json")
+	cache, _ := LoadDerivationCache(path, "wallet")
+	for index := uint64(0); index < HashCacheMax+2; index++ {
+		cache.Put(index, [wotsp.AddrHashLength]byte{byte(index)})
+	}
+	if err := cache.Save(); err != nil {
+		t.Fatal(err)
+	}
+	loaded, err := LoadDerivationCache(path, "wallet")
+	if err != nil || len(loaded.hashes) != HashCacheMax {
+		t.Fatalf("%d hashes kept, %v", len(loaded.hashes), err)
+	}
+	for _, index := range []uint64{0, 1} {
+		if _, ok := loaded.AddrHash(index); ok {
+			t.Errorf("lowest index %d kept", index)
+		}
+	}
+	top := uint64(HashCacheMax + 1)
+	if hash, ok := loaded.AddrHash(top); !ok || hash[0] != byte(top) {
+		t.Error("highest index lost")
+	}
+}
@@ -0,0 +1,98 @@
+package walletstore
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+
+	"github.com/NickP005/Vindax-MCM-tools/pkg/mcmaddr"
+	"github.com/NickP005/Vindax-MCM-tools/pkg/secure"
+	"github.com/NickP005/Vindax-MCM-tools/pkg/wotsp"
+)
+
+/*
+ * Keychain derives the keys of one wallet seed, each index at most once
+ *
+ * The index search and the transaction signing both need keypairs of the
+ * same indices, and each derivation expands the seed into 67 chains from
+ * scratch. Keys are those of WOTS-Go's keychain, see wotsp.DeriveSeed.
+ * Cached keypairs hold secret material: call Wipe once done.
+ *
+ * Address hashes can also come from a DerivationCache kept across runs, so
+ * the search only derives the indices never seen before.
+ */
+type Keychain struct {
+	master      [secure.KeyLength]byte
+	keypairs    map[uint64]*wotsp.Keypair
+	fingerprint string
+	hashes      *DerivationCache
+}
+
+// NewKeychain creates the keychain of a 32 bytes hex secret key
+func NewKeychain(secretKey string) (*Keychain, error) {
+	seed, err := secure.DecodeKey([]byte(secretKey))
+	if err != nil {
+		return nil, err
+	}
+	defer secure.Wipe(seed[:])
+
+	fingerprint := sha256.New()
+	fingerprint.Write([]byte("wallet-tool derivation cache\x00"))
+	fingerprint.Write(seed[:])
+	return &Keychain{
+		master:      seed,
+		keypairs:    make(map[uint64]*wotsp.Keypair),
+		fingerprint: hex.EncodeToString(fingerprint.Sum(nil)),
+	}, nil
+}
+
+// Fingerprint identifies the secret key without revealing it, to tie a DerivationCache to its wallet
+func (k *Keychain) Fingerprint() string {
+	return k.fingerprint
+}
+
+// UseDerivationCache makes AddrHash consult and extend cache
+func (k *Keychain) UseDerivationCache(cache *DerivationCache) {
+	k.hashes = cache
+}
+
+// AddrHash returns the address hash of the key at index, deriving the key only if no cache knows it
+func (k *Keychain) AddrHash(index uint64) [wotsp.AddrHashLength]byte {
+	if k.hashes != nil {
+		if hash, ok := k.hashes.AddrHash(index); ok {
+			return hash
+		}
+	}
+	hash := k.Keypair(index).AddrHash()
+	if k.hashes != nil {
+		k.hashes.Put(index, hash)
+	}
+	return hash
+}
+
+// Tag returns the wallet tag: the address hash of index 0, which every later key of the wallet spends from
+func (k *Keychain) Tag() [mcmaddr.TagLength]byte {
+	return k.AddrHash(0)
+}
+
+// RefillAddress returns the base58 address of the wallet tag, the one to send funds to
+func (k *Keychain) RefillAddress() string {
+	return mcmaddr.To58(k.Tag())
+}
+
+// Keypair returns the keypair at index, deriving it only on first use
+func (k *Keychain) Keypair(index uint64) *wotsp.Keypair {
+	if keypair, ok := k.keypairs[index]; ok {
+		return keypair
+	}
+	keypair := wotsp.KeychainKeygen(k.master, index)
+	k.keypairs[index] = &keypair
+	return &keypair
+}
+
+// Wipe clears the secret part of every cached keypair and empties the cache; the keychain can still derive
+func (k *Keychain) Wipe() {
+	for index, keypair := range k.keypairs {
+		keypair.Wipe()
+		delete(k.keypairs, index)
+	}
+}
@@ -0,0 +1,156 @@
+package walletstore
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/NickP005/Vindax-MCM-tools/pkg/mcmaddr"
+	"github.com/NickP005/Vindax-MCM-tools/pkg/wotsp"
+)
+
+// testSecret is the hex secret key of the test wallets of a label
+func testSecret(label string) string {
+	seed := sha256.Sum256([]byte("walletstore test " + label))
+	return hex.EncodeToString(seed[:])
+}
+
+// testMaster is the decoded secret of testSecret
+func testMaster(label string) [32]byte {
+	return sha256.Sum256([]byte("walletstore test " + label))
+}
+
+func TestKeychainDerivesOnce(t *testing.T) {
+	keychain, err := NewKeychain(testSecret("a"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer keychain.Wipe()
+
+	first := keychain.Keypair(7)
+	if keychain.Keypair(7) != first {
+		t.Error("index 7 derived twice")
+	}
+	want := wotsp.KeychainKeygen(testMaster("a"), 7)
+	if first.PublicKey != want.PublicKey || first.Components != want.Components {
+		t.Error("cached keypair differs from wotsp.KeychainKeygen")
+	}
+	if keychain.AddrHash(7) != want.AddrHash() {
+		t.Error("AddrHash differs from the keypair's")
+	}
+	if len(keychain.keypairs) != 1 {
+		t.Errorf("%d keypairs cached, want 1", len(keychain.keypairs))
+	}
+}
+
+func TestKeychainWipe(t *testing.T) {
+	keychain, err := NewKeychain(testSecret("a"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	cached := []*wotsp.Keypair{keychain.Keypair(0), keychain.Keypair(1)}
+	keychain.Wipe()
+	for i, keypair := range cached {
+		if keypair.Components.PrivateSeed != [32]byte{} {
+			t.Errorf("keypair %d: private seed not wiped", i)
+		}
+	}
+	if len(keychain.keypairs) != 0 {
+		t.Errorf("%d keypairs left after Wipe", len(keychain.keypairs))
+	}
+	// The keychain still derives after a wipe
+	again := keychain.Keypair(1)
+	if again == cached[1] || again.Components.PrivateSeed == [32]byte{} {
+		t.Error("keypair not derived again after Wipe")
+	}
+	keychain.Wipe()
+}
+
+func TestKeychainTag(t *testing.T) {
+	keychain, err := NewKeychain("0x" + strings.ToUpper(testSecret("a")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer keychain.Wipe()
+	first := wotsp.KeychainKeygen(testMaster("a"), 0)
+	if keychain.Tag() != first.AddrHash() {
+		t.Error("tag is not the address hash of index 0")
+	}
+	if keychain.RefillAddress() != mcmaddr.To58(first.AddrHash()) {
+		t.Errorf("refill address %s", keychain.RefillAddress())
+	}
+
+	other, err := NewKeychain(testSecret("b"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if other.Fingerprint() == keychain.Fingerprint() || other.Tag() == keychain.Tag() {
+		t.Error("two secrets share a fingerprint or a tag")
+	}
+	// The fingerprint reveals nothing of the secret
+	if strings.Contains(keychain.Fingerprint(), testSecret("a")) {
+		t.Error("fingerprint holds the secret")
+	}
+}
+
+func TestNewKeychainErrors(t *testing.T) {
+	for _, secret := range []string{"", "abcd", testSecret("a") + "00", "zz" + testSecret("a")[2:]} {
+		if _, err := NewKeychain(secret); err == nil {
+			t.Errorf("%q: no error", secret)
+		}
+	}
+}
+
+// benchIndex is the wallet index of the search and sign benchmark
+const benchIndex = 5000
+
+/*
+ * BenchmarkSearchAndSign finds a wallet at benchIndex then signs with its
+ * key, the path of a wallet-tool transaction
+ *
+ * Without the cache the signing derives the found index a second time,
+ * one derivation in benchIndex; with a DerivationCache of an earlier run
+ * the search derives nothing and only the signing key is derived.
+ */
+func BenchmarkSearchAndSign(b *testing.B) {
+	master := testMaster("bench")
+	msg := sha256.Sum256([]byte("message"))
+	search := func(keychain *Keychain) {
+		for index := uint64(0); index <= benchIndex; index++ {
+			keychain.AddrHash(index)
+		}
+		keychain.Keypair(benchIndex).Sign(msg)
+		keychain.Wipe()
+	}
+	b.Run("derivation cache", func(b *testing.B) {
+		keychain, _ := NewKeychain(hex.EncodeToString(master[:]))
+		cache, _ := LoadDerivationCache(filepath.Join(b.TempDir(), "hashes.json"), keychain.Fingerprint())
+		keychain.UseDerivationCache(cache)
+		search(keychain)
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			keychain, _ := NewKeychain(hex.EncodeToString(master[:]))
+			keychain.UseDerivationCache(cache)
+			search(keychain)
+		}
+	})
+	b.Run("cached", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			keychain, _ := NewKeychain(hex.EncodeToString(master[:]))
+			search(keychain)
+		}
+	})
+	b.Run("uncached", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			for index := uint64(0); index <= benchIndex; index++ {
+				keypair := wotsp.KeychainKeygen(master, index)
+				keypair.AddrHash()
+			}
+			keypair := wotsp.KeychainKeygen(master, benchIndex)
+			keypair.Sign(msg)
+			keypair.Wipe()
+		}
+	})
+}
@@ -0,0 +1,51 @@
+package walletstore
+
+import (
+	"fmt"
+	"time"
+)
+
+// PendingTx is a signed transaction saved in the wallet cache before it is broadcast
+type PendingTx struct {
+	// Index is the wallet index of the key that signed it
+	Index    uint64    `json:"index"`
+	SignedTx string    `json:"signedTx"`
+	SignedAt time.Time `json:"signedAt"`
+	Fee      uint64    `json:"fee"`
+	// FeeRejected is set once the node refused the transaction for its fee: only these bytes may be rebroadcast, once the node accepts the fee
+	FeeRejected bool `json:"feeRejected,omitempty"`
+}
+
+// PendingTxError is returned when the funds are still held by a key whose signature may have been broadcast
+type PendingTxError struct {
+	Pending PendingTx
+}
+
+func (e *PendingTxError) Error() string {
+	if e.Pending.FeeRejected {
+		return fmt.Sprintf("index %d signed a transaction on %s that was rejected for its fee of %d nMCM: signing again with it would expose the key",
+			e.Pending.Index, e.Pending.SignedAt.Format(time.RFC3339), e.Pending.Fee)
+	}
+	return fmt.Sprintf("index %d already signed a transaction on %s that may have been broadcast: signing again with it could expose the key",
+		e.Pending.Index, e.Pending.SignedAt.Format(time.RFC3339))
+}
+
+/*
+ * CheckPending fails with a *PendingTxError if the key at index already
+ * signed a persisted transaction
+ *
+ * A WOTS key signs one message: once its signature is persisted it is only
+ * ever used to rebroadcast those bytes, even when the node rejected them
+ * for their fee. A pending transaction of another index is forgotten: the
+ * funds moved to another key, so it confirmed or can no longer.
+ */
+func CheckPending(cache *WalletCache, index uint64) error {
+	if cache.Pending == nil {
+		return nil
+	}
+	if cache.Pending.Index == index {
+		return &PendingTxError{Pending: *cache.Pending}
+	}
+	cache.Pending = nil
+	return nil
+}
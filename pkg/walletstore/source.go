@@ -0,0 +1,103 @@
+package walletstore
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/NickP005/Vindax-MCM-tools/pkg/meshclient"
+	"github.com/NickP005/Vindax-MCM-tools/pkg/secure"
+	"github.com/NickP005/Vindax-MCM-tools/pkg/wotsp"
+)
+
+// ErrSourceMoved is wrapped by every *SourceMovedError, for errors.Is
+var ErrSourceMoved = errors.New("wallet source changed on chain")
+
+// SourceState is what the chain holds for the wallet tag: the WOTS+ address controlling it and its balance
+type SourceState struct {
+	// Found is false when the chain knows no account with the tag
+	Found      bool
+	AddrHash   [wotsp.AddrHashLength]byte
+	Balance    uint64
+	AddressHex string
+}
+
+// ResolveSource resolves the wallet tag; a tag unknown to the chain is not an error, Found is then false
+func ResolveSource(ctx context.Context, client *meshclient.MeshAPIClient, tag []byte) (SourceState, error) {
+	resolution, err := client.ResolveTag(ctx, tag)
+	if errors.Is(err, meshclient.ErrTagNotFound) {
+		return SourceState{}, nil
+	}
+	if err != nil {
+		return SourceState{}, err
+	}
+	// The resolved address is validated by the client: the tag followed by the 20 bytes address hash
+	return SourceState{
+		Found:      true,
+		AddrHash:   resolution.AddrHash(),
+		Balance:    resolution.Amount,
+		AddressHex: resolution.AddressHex,
+	}, nil
+}
+
+// Controls reports whether publicKey is the WOTS+ key the tag currently belongs to; a key too short never is
+func (s SourceState) Controls(publicKey []byte) bool {
+	// Address hash computed locally, no go_mcminterface address object per index
+	hash, err := wotsp.AddrHashFromPK(publicKey)
+	return err == nil && s.ControlledBy(hash)
+}
+
+// ControlledBy reports whether the tag currently belongs to the key with the given address hash
+func (s SourceState) ControlledBy(hash [wotsp.AddrHashLength]byte) bool {
+	return s.Found && secure.Equal(s.AddrHash[:], hash[:])
+}
+
+// FindIndex returns the first index in [from, to) whose key controls the tag, see Keychain.AddrHash
+func (s SourceState) FindIndex(keychain *Keychain, from, to uint64) (uint64, bool) {
+	for i := from; i < to; i++ {
+		if s.ControlledBy(keychain.AddrHash(i)) {
+			return i, true
+		}
+	}
+	return 0, false
+}
+
+// SourceMovedError is returned when the wallet tag no longer holds what a signed transaction spends
+type SourceMovedError struct {
+	Expected SourceState
+	Actual   SourceState
+}
+
+func (e *SourceMovedError) Error() string {
+	switch {
+	case !e.Actual.Found:
+		return "the wallet tag is no longer on chain"
+	case e.Actual.AddrHash != e.Expected.AddrHash:
+		return fmt.Sprintf("the wallet tag moved to another WOTS+ address (%s): the signing key was spent by another transaction", e.Actual.AddressHex)
+	}
+	return fmt.Sprintf("the wallet balance changed from %d to %d nMCM, while the transaction spends exactly %d nMCM",
+		e.Expected.Balance, e.Actual.Balance, e.Expected.Balance)
+}
+
+func (e *SourceMovedError) Unwrap() error { return ErrSourceMoved }
+
+/*
+ * CheckSourceUnchanged checks that the wallet tag is still controlled by the
+ * key that signed a transaction, with the balance it spends
+ *
+ * A Mochimo transaction spends the whole balance of its WOTS+ address, and
+ * the key can sign once: if any other transaction from the wallet confirmed,
+ * or a refill changed the balance, the signed bytes can no longer be valid.
+ *
+ * Returns a *SourceMovedError in that case, or the error of the lookup.
+ */
+func CheckSourceUnchanged(ctx context.Context, client *meshclient.MeshAPIClient, tag []byte, expected SourceState) error {
+	actual, err := ResolveSource(ctx, client, tag)
+	if err != nil {
+		return err
+	}
+	if !actual.Found || actual.AddrHash != expected.AddrHash || actual.Balance != expected.Balance {
+		return &SourceMovedError{Expected: expected, Actual: actual}
+	}
+	return nil
+}
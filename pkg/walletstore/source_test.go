@@ -0,0 +1,102 @@
+package walletstore
+
+import (
+	"context"
+	"encoding/hex"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/NickP005/Vindax-MCM-tools/pkg/meshclient"
+	"github.com/NickP005/Vindax-MCM-tools/pkg/meshmock"
+)
+
+// sourceMock is a mock where the tag of keychain is controlled by the key at index, holding balance
+func sourceMock(t *testing.T, keychain *Keychain, index uint64, balance uint64) (*meshmock.Server, *meshclient.MeshAPIClient) {
+	t.Helper()
+	mock := meshmock.New()
+	t.Cleanup(mock.Close)
+	tag, hash := keychain.Tag(), keychain.AddrHash(index)
+	mock.SetAccount(tag[:], "0x"+hex.EncodeToString(tag[:])+hex.EncodeToString(hash[:]), balance)
+	return mock, meshclient.NewMeshAPIClient(mock.URL(), nil)
+}
+
+func TestResolveSource(t *testing.T) {
+	keychain, err := NewKeychain(testSecret("source"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer keychain.Wipe()
+	_, client := sourceMock(t, keychain, 3, 1000)
+	tag := keychain.Tag()
+
+	source, err := ResolveSource(context.Background(), client, tag[:])
+	if err != nil || !source.Found || source.Balance != 1000 || source.AddrHash != keychain.AddrHash(3) {
+		t.Fatalf("source %+v, %v", source, err)
+	}
+	if !source.Controls(keychain.Keypair(3).PublicKey[:]) || source.Controls(keychain.Keypair(2).PublicKey[:]) || source.Controls(nil) {
+		t.Error("Controls accepts the wrong keys")
+	}
+	if index, found := source.FindIndex(keychain, 0, 10); !found || index != 3 {
+		t.Errorf("FindIndex: %d, %v", index, found)
+	}
+	if _, found := source.FindIndex(keychain, 4, 10); found {
+		t.Error("FindIndex found the key outside of its range")
+	}
+
+	// A tag unknown to the chain is not an error
+	other, _ := NewKeychain(testSecret("other"))
+	defer other.Wipe()
+	otherTag := other.Tag()
+	if source, err := ResolveSource(context.Background(), client, otherTag[:]); err != nil || source.Found || source.ControlledBy(source.AddrHash) {
+		t.Errorf("unknown tag: %+v, %v", source, err)
+	}
+}
+
+// TestCheckSourceUnchanged spends the key that signed a transaction, as another payout confirming meanwhile does
+func TestCheckSourceUnchanged(t *testing.T) {
+	keychain, err := NewKeychain(testSecret("source"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer keychain.Wipe()
+	mock, client := sourceMock(t, keychain, 3, 1000)
+	tag := keychain.Tag()
+	ctx := context.Background()
+	signed, err := ResolveSource(ctx, client, tag[:])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := CheckSourceUnchanged(ctx, client, tag[:], signed); err != nil {
+		t.Fatalf("unchanged source: %v", err)
+	}
+
+	change := keychain.AddrHash(5)
+	for _, tc := range []struct {
+		name    string
+		address string
+		balance uint64
+		message string
+	}{
+		{"key consumed", "0x" + hex.EncodeToString(tag[:]) + hex.EncodeToString(change[:]), 400, "signing key was spent by another transaction"},
+		{"refilled", signed.AddressHex, 1500, "balance changed from 1000 to 1500 nMCM"},
+		{"tag gone", "", 0, "no longer on chain"},
+	} {
+		mock.SetAccount(tag[:], tc.address, tc.balance)
+		err := CheckSourceUnchanged(ctx, client, tag[:], signed)
+		var moved *SourceMovedError
+		if !errors.Is(err, ErrSourceMoved) || !errors.As(err, &moved) || moved.Expected != signed {
+			t.Errorf("%s: %v", tc.name, err)
+			continue
+		}
+		if !strings.Contains(err.Error(), tc.message) {
+			t.Errorf("%s: %q does not say %q", tc.name, err, tc.message)
+		}
+	}
+
+	// Not knowing is not a move
+	mock.Fail("/call", meshmock.Fault{Status: 500, Body: `{"code":2,"message":"internal error","retriable":false}`})
+	if err := CheckSourceUnchanged(ctx, client, tag[:], signed); err == nil || errors.Is(err, ErrSourceMoved) {
+		t.Errorf("failed lookup: %v", err)
+	}
+}
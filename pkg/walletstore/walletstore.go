@@ -0,0 +1,148 @@
+/*
+ * Package walletstore holds the state of a wallet-tool wallet: the wallet
+ * cache file, the keychain of its secret key, the derivation cache kept
+ * next to it, and what the chain holds for the wallet tag.
+ *
+ * A wallet is one WOTS+ keychain sharing the tag of its key at index 0. A
+ * Mochimo transaction spends the whole balance of its source key and sends
+ * the change to the next one, so the wallet moves up its keychain one index
+ * per payout; the cache remembers that index, and the signed transaction
+ * still pending, so that no key ever signs two different transactions.
+ *
+ *	cache, err := walletstore.Read("wallet-cache.json")
+ *	keychain, err := cache.Keychain()
+ *	defer keychain.Wipe()
+ *	source, err := walletstore.ResolveSource(ctx, client, keychain.Tag())
+ *	index, found := source.FindIndex(keychain, cache.Index, walletstore.MaxIndexSearch)
+ */
+package walletstore
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+
+	"github.com/NickP005/Vindax-MCM-tools/pkg/fileutil"
+	"github.com/NickP005/Vindax-MCM-tools/pkg/mcmaddr"
+	"github.com/NickP005/Vindax-MCM-tools/pkg/secure"
+)
+
+// MaxIndexSearch bounds the wallet indices searched for the key the tag belongs to
+const MaxIndexSearch = 10000
+
+// WalletCache is the wallet cache file of wallet-tool
+type WalletCache struct {
+	// SecretKey is the 32 bytes seed of the keychain in hex
+	SecretKey string `json:"secretKey"`
+	// Index is the next unused key: the change key of the last transaction signed
+	Index         uint64 `json:"index"`
+	RefillAddress string `json:"refillAddress,omitempty"`
+	// Pending is the last transaction signed, kept until the funds move to another key
+	Pending *PendingTx `json:"pendingTx,omitempty"`
+}
+
+// New creates the cache of a wallet with a random secret key, its refill address set
+func New() (*WalletCache, error) {
+	var seed [secure.KeyLength]byte
+	if _, err := rand.Read(seed[:]); err != nil {
+		return nil, fmt.Errorf("failed to generate random seed: %v", err)
+	}
+	defer secure.Wipe(seed[:])
+
+	cache := &WalletCache{SecretKey: hex.EncodeToString(seed[:])}
+	if err := cache.SetRefillAddress(); err != nil {
+		return nil, err
+	}
+	return cache, nil
+}
+
+/*
+ * Read reads a wallet cache file
+ *
+ * An empty file is reported as a missing one, wrapping fs.ErrNotExist: a
+ * crash before the first save leaves it so. The refill address is left as
+ * the file has it, see SetRefillAddress.
+ */
+func Read(path string) (*WalletCache, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	if len(data) == 0 {
+		return nil, fmt.Errorf("wallet cache %s is empty: %w", path, fs.ErrNotExist)
+	}
+	var cache WalletCache
+	if err := json.Unmarshal(data, &cache); err != nil {
+		return nil, err
+	}
+	return &cache, nil
+}
+
+// Keychain creates the keychain of the cache's secret key; the caller wipes it
+func (c *WalletCache) Keychain() (*Keychain, error) {
+	return NewKeychain(c.SecretKey)
+}
+
+// SetRefillAddress sets the refill address to the base58 address of the wallet tag
+func (c *WalletCache) SetRefillAddress() error {
+	keychain, err := c.Keychain()
+	if err != nil {
+		return err
+	}
+	defer keychain.Wipe()
+	c.RefillAddress = keychain.RefillAddress()
+	return nil
+}
+
+// ErrRefillMismatch is wrapped by every *RefillMismatchError, for errors.Is
+var ErrRefillMismatch = errors.New("refill address is not the wallet tag")
+
+// RefillMismatchError is a wallet cache whose refill address is not the tag of its secret key
+type RefillMismatchError struct {
+	RefillAddress string
+	// Tag is the wallet tag of the secret key
+	Tag [mcmaddr.TagLength]byte
+}
+
+func (e *RefillMismatchError) Error() string {
+	return fmt.Sprintf("refill address %s is not the wallet tag %s of the secret key: the cache was edited or mixes two wallets",
+		e.RefillAddress, mcmaddr.To58(e.Tag))
+}
+
+func (e *RefillMismatchError) Unwrap() error { return ErrRefillMismatch }
+
+/*
+ * CheckRefillAddress checks that the refill address is the tag of the
+ * secret key, in any representation mcmaddr.Normalize reads
+ *
+ * Funds sent to a refill address of another wallet cannot be spent by this
+ * one, so a cache failing the check must not be used. Returns a
+ * *RefillMismatchError, or the error of an invalid secret key.
+ */
+func (c *WalletCache) CheckRefillAddress() error {
+	keychain, err := c.Keychain()
+	if err != nil {
+		return err
+	}
+	defer keychain.Wipe()
+	tag := keychain.Tag()
+	refill, err := mcmaddr.Normalize(c.RefillAddress)
+	if err != nil || !secure.Equal20(refill, tag) {
+		return &RefillMismatchError{RefillAddress: c.RefillAddress, Tag: tag}
+	}
+	return nil
+}
+
+// Save writes the wallet cache to path durably, see fileutil.WriteAtomic: a crash leaves
+// either the old or the new cache, never a partial one
+func Save(path string, cache *WalletCache) error {
+	data, err := json.MarshalIndent(cache, "", "  ")
+	if err != nil {
+		return err
+	}
+	return fileutil.WriteAtomic(path, data, true)
+}
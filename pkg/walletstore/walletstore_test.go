@@ -0,0 +1,70 @@
+package walletstore
+
+import (
+	"encoding/hex"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/NickP005/Vindax-MCM-tools/pkg/mcmaddr"
+)
+
+func TestNewSaveRead(t *testing.T) {
+	cache, err := New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(cache.SecretKey) != 64 || cache.CheckRefillAddress() != nil {
+		t.Fatalf("new cache %+v", cache)
+	}
+	path := filepath.Join(t.TempDir(), "wallet-cache.json")
+	cache.Index = 4
+	if err := Save(path, cache); err != nil {
+		t.Fatal(err)
+	}
+	read, err := Read(path)
+	if err != nil || *read != *cache {
+		t.Errorf("read %+v, %v", read, err)
+	}
+
+	// An empty file is a missing one
+	os.WriteFile(path, nil, 0600)
+	if _, err := Read(path); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("empty file: %v", err)
+	}
+}
+
+func TestCheckRefillAddress(t *testing.T) {
+	cache := &WalletCache{SecretKey: testSecret("a")}
+	if err := cache.SetRefillAddress(); err != nil {
+		t.Fatal(err)
+	}
+	if err := cache.CheckRefillAddress(); err != nil {
+		t.Errorf("own refill address: %v", err)
+	}
+	// Any representation of the tag is the same address
+	tag, _ := mcmaddr.Normalize(cache.RefillAddress)
+	cache.RefillAddress = "0x" + strings.ToUpper(hex.EncodeToString(tag[:]))
+	if err := cache.CheckRefillAddress(); err != nil {
+		t.Errorf("hex refill address: %v", err)
+	}
+
+	other := &WalletCache{SecretKey: testSecret("b")}
+	other.SetRefillAddress()
+	for _, refill := range []string{other.RefillAddress, "not an address", ""} {
+		cache.RefillAddress = refill
+		var mismatch *RefillMismatchError
+		err := cache.CheckRefillAddress()
+		if !errors.As(err, &mismatch) || !errors.Is(err, ErrRefillMismatch) || mismatch.Tag != tag {
+			t.Errorf("refill address %q: %v", refill, err)
+		}
+	}
+
+	cache.SecretKey = "1234"
+	if err := cache.CheckRefillAddress(); err == nil || errors.Is(err, ErrRefillMismatch) {
+		t.Errorf("invalid secret key: %v", err)
+	}
+}
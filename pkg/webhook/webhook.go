@@ -0,0 +1,82 @@
+/*
+ * Package webhook posts the events of the tools to the webhook of the
+ * shared configuration (see config.Webhook).
+ *
+ * Every event is a JSON object with its kind, the time and the tool's data:
+ *
+ *	{"event": "deposit", "time": "2025-01-02T03:04:05Z", "tool": "wallet-tool", "data": {...}}
+ *
+ * With a secret, the request carries X-Signature-256: sha256=<hex>, the
+ * HMAC-SHA256 of the body keyed with the secret, for the receiver to check
+ * that the event comes from the tools.
+ */
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/NickP005/Vindax-MCM-tools/pkg/config"
+)
+
+// SignatureHeader is the header holding the HMAC-SHA256 of the body, when the webhook has a secret
+const SignatureHeader = "X-Signature-256"
+
+// Event is the body posted to the webhook
+type Event struct {
+	Event string    `json:"event"`
+	Time  time.Time `json:"time"`
+	Tool  string    `json:"tool"`
+	Data  any       `json:"data"`
+}
+
+// Sign returns the value of SignatureHeader for a body
+func Sign(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+/*
+ * Post sends an event to the webhook, once
+ *
+ * A webhook without URL posts nothing. Any answer other than 2xx is an
+ * error; the caller decides whether a lost event matters.
+ */
+func Post(ctx context.Context, hook config.Webhook, tool string, event string, data any) error {
+	if hook.URL == "" {
+		return nil
+	}
+	body, err := json.Marshal(Event{Event: event, Time: time.Now().UTC(), Tool: tool, Data: data})
+	if err != nil {
+		return err
+	}
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, hook.URL, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("webhook: %v", err)
+	}
+	request.Header.Set("Content-Type", "application/json")
+	if hook.Secret != "" {
+		request.Header.Set(SignatureHeader, Sign(hook.Secret, body))
+	}
+
+	client := &http.Client{Timeout: hook.Timeout}
+	response, err := client.Do(request)
+	if err != nil {
+		return fmt.Errorf("webhook: %v", err)
+	}
+	defer response.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(response.Body, 64*1024))
+	if response.StatusCode < 200 || response.StatusCode > 299 {
+		return fmt.Errorf("webhook: %s answered %s", hook.URL, response.Status)
+	}
+	return nil
+}
@@ -0,0 +1,106 @@
+package webhook
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/NickP005/Vindax-MCM-tools/pkg/config"
+)
+
+// received is a request the test server got
+type received struct {
+	header http.Header
+	body   []byte
+}
+
+// newReceiver serves the webhook, answering status and sending every request it gets on the channel
+func newReceiver(t *testing.T, status int) (*httptest.Server, chan received) {
+	t.Helper()
+	requests := make(chan received, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		if r.Method != http.MethodPost {
+			t.Errorf("method %s", r.Method)
+		}
+		requests <- received{header: r.Header, body: body}
+		w.WriteHeader(status)
+	}))
+	t.Cleanup(server.Close)
+	return server, requests
+}
+
+// TestPostSigned posts an event with a secret: the body is the event, signed with the HMAC-SHA256 of the secret
+func TestPostSigned(t *testing.T) {
+	server, requests := newReceiver(t, http.StatusNoContent)
+	hook := config.Webhook{URL: server.URL, Secret: "s3cret", Timeout: 5 * time.Second}
+	if err := Post(context.Background(), hook, "wallet-tool", "deposit", map[string]uint64{"amount": 1000}); err != nil {
+		t.Fatal(err)
+	}
+	r := <-requests
+
+	mac := hmac.New(sha256.New, []byte("s3cret"))
+	mac.Write(r.body)
+	if want := "sha256=" + hex.EncodeToString(mac.Sum(nil)); r.header.Get(SignatureHeader) != want {
+		t.Errorf("%s %q, want %q", SignatureHeader, r.header.Get(SignatureHeader), want)
+	}
+	if r.header.Get("Content-Type") != "application/json" {
+		t.Errorf("content type %q", r.header.Get("Content-Type"))
+	}
+	var event struct {
+		Event string
+		Time  time.Time
+		Tool  string
+		Data  map[string]uint64
+	}
+	if err := json.Unmarshal(r.body, &event); err != nil {
+		t.Fatalf("%v: %s", err, r.body)
+	}
+	if event.Event != "deposit" || event.Tool != "wallet-tool" || event.Data["amount"] != 1000 || time.Since(event.Time) > time.Minute {
+		t.Errorf("event %s", r.body)
+	}
+
+	// Without a secret nothing is signed
+	if err := Post(context.Background(), config.Webhook{URL: server.URL}, "wallet-tool", "deposit", nil); err != nil {
+		t.Fatal(err)
+	}
+	if r := <-requests; r.header.Get(SignatureHeader) != "" {
+		t.Errorf("unsigned webhook sent %s %q", SignatureHeader, r.header.Get(SignatureHeader))
+	}
+}
+
+// TestPostNoURL posts nothing for a webhook without URL, not even encoding the event
+func TestPostNoURL(t *testing.T) {
+	if err := Post(context.Background(), config.Webhook{Secret: "s3cret"}, "wallet-tool", "deposit", make(chan int)); err != nil {
+		t.Errorf("no URL: %v", err)
+	}
+}
+
+// TestPostStatus fails on any answer other than 2xx, naming the status
+func TestPostStatus(t *testing.T) {
+	for _, status := range []int{http.StatusOK, http.StatusAccepted, http.StatusNotFound, http.StatusInternalServerError} {
+		server, requests := newReceiver(t, status)
+		err := Post(context.Background(), config.Webhook{URL: server.URL}, "mempool-watch", "departed", nil)
+		<-requests
+		if status < 300 && err != nil {
+			t.Errorf("%d: %v", status, err)
+		}
+		if status >= 300 && (err == nil || !strings.Contains(err.Error(), http.StatusText(status))) {
+			t.Errorf("%d: %v", status, err)
+		}
+	}
+
+	server, _ := newReceiver(t, http.StatusOK)
+	server.Close()
+	if err := Post(context.Background(), config.Webhook{URL: server.URL}, "mempool-watch", "departed", nil); err == nil {
+		t.Error("closed server: no error")
+	}
+}
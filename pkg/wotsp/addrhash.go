@@ -0,0 +1,44 @@
+package wotsp
+
+import (
+	"fmt"
+
+	"golang.org/x/crypto/ripemd160"
+	"golang.org/x/crypto/sha3"
+)
+
+// AddrHashLength is the length in bytes of an MCM 3.0 address hash
+const AddrHashLength = 20
+
+/*
+ * AddrHash computes the 20 bytes MCM 3.0 address hash of a WOTS public key,
+ * the same rule go_mcminterface applies in WotsAddressFromBytes:
+ *
+ *   hash = ripemd160(sha3-512(pk))
+ *
+ * Unlike going through mcm.WotsAddressFromBytes it builds no address object,
+ * which matters in loops deriving thousands of keys.
+ */
+func AddrHash(pk [SigSize]byte) [AddrHashLength]byte {
+	digest := sha3.Sum512(pk[:])
+	h := ripemd160.New()
+	h.Write(digest[:])
+
+	var out [AddrHashLength]byte
+	copy(out[:], h.Sum(nil))
+	return out
+}
+
+/*
+ * AddrHashFromPK is AddrHash for a public key held in a slice
+ *
+ * Only the first 2144 bytes are hashed, so a full 2208 bytes WOTS address
+ * (with its public and address seeds) gives the same result. Returns an
+ * error if pk is shorter than SigSize bytes.
+ */
+func AddrHashFromPK(pk []byte) ([AddrHashLength]byte, error) {
+	if len(pk) < SigSize {
+		return [AddrHashLength]byte{}, fmt.Errorf("public key must be at least %d bytes, got %d", SigSize, len(pk))
+	}
+	return AddrHash([SigSize]byte(pk[:SigSize])), nil
+}
@@ -0,0 +1,42 @@
+package wotsp
+
+import (
+	"testing"
+)
+
+func TestAddrHashFromPK(t *testing.T) {
+	seed, _ := vectorSeed(t, 0)
+	keypair := Keygen(seed)
+	want := AddrHash(keypair.PublicKey)
+	if hash := keypair.AddrHash(); hash != want {
+		t.Errorf("Keypair.AddrHash %x, want %x", hash, want)
+	}
+
+	// A full 2208 bytes address hashes as its public key
+	full := append(keypair.PublicKey[:], make([]byte, 64)...)
+	for _, pk := range [][]byte{keypair.PublicKey[:], full} {
+		if hash, err := AddrHashFromPK(pk); err != nil || hash != want {
+			t.Errorf("%d bytes: %x, %v", len(pk), hash, err)
+		}
+	}
+	for _, length := range []int{0, 20, SigSize - 1} {
+		if _, err := AddrHashFromPK(make([]byte, length)); err == nil {
+			t.Errorf("%d bytes: no error", length)
+		}
+	}
+}
+
+func FuzzAddrHashFromPK(f *testing.F) {
+	f.Add([]byte{})
+	f.Add(make([]byte, SigSize))
+	f.Add(make([]byte, SigSize+64))
+	f.Fuzz(func(t *testing.T, pk []byte) {
+		hash, err := AddrHashFromPK(pk)
+		if (err == nil) != (len(pk) >= SigSize) {
+			t.Fatalf("%d bytes: %v", len(pk), err)
+		}
+		if err == nil && hash != AddrHash([SigSize]byte(pk[:SigSize])) {
+			t.Fatal("slice and array hashes differ")
+		}
+	})
+}
@@ -0,0 +1,91 @@
+package wotsp
+
+import (
+	"fmt"
+
+	"github.com/NickP005/Vindax-MCM-tools/pkg/secure"
+)
+
+// Variants of the primitives for inputs held in slices (hex decoded, read
+// from transactions), validating every length instead of panicking.
+
+// array32 copies a 32 bytes input, named in the error if its length is wrong
+func array32(name string, b []byte) ([32]byte, error) {
+	var out [32]byte
+	if len(b) != 32 {
+		return out, fmt.Errorf("%s must be 32 bytes, got %d", name, len(b))
+	}
+	copy(out[:], b)
+	return out, nil
+}
+
+// arraySig copies a 2144 bytes input, named in the error if its length is wrong
+func arraySig(name string, b []byte) ([SigSize]byte, error) {
+	var out [SigSize]byte
+	if len(b) != SigSize {
+		return out, fmt.Errorf("%s must be %d bytes, got %d", name, SigSize, len(b))
+	}
+	copy(out[:], b)
+	return out, nil
+}
+
+// PkGenBytes is PkGen for slice inputs; it returns an error on any wrong length
+func PkGenBytes(seed []byte, pubSeed []byte, addrSeed []byte) ([SigSize]byte, error) {
+	s, err := array32("seed", seed)
+	defer secure.Wipe(s[:])
+	if err != nil {
+		return [SigSize]byte{}, err
+	}
+	p, err := array32("public seed", pubSeed)
+	if err != nil {
+		return [SigSize]byte{}, err
+	}
+	a, err := array32("address seed", addrSeed)
+	if err != nil {
+		return [SigSize]byte{}, err
+	}
+	return PkGen(s, p, a), nil
+}
+
+// SignBytes is Sign for slice inputs; it returns an error on any wrong length
+func SignBytes(msg []byte, seed []byte, pubSeed []byte, addrSeed []byte) ([SigSize]byte, error) {
+	m, err := array32("message", msg)
+	if err != nil {
+		return [SigSize]byte{}, err
+	}
+	s, err := array32("seed", seed)
+	defer secure.Wipe(s[:])
+	if err != nil {
+		return [SigSize]byte{}, err
+	}
+	p, err := array32("public seed", pubSeed)
+	if err != nil {
+		return [SigSize]byte{}, err
+	}
+	a, err := array32("address seed", addrSeed)
+	if err != nil {
+		return [SigSize]byte{}, err
+	}
+	return Sign(m, s, p, a), nil
+}
+
+// PkFromSigBytes is PkFromSig for slice inputs; it returns an error on any wrong length
+func PkFromSigBytes(sig []byte, msg []byte, pubSeed []byte, addrSeed []byte) ([SigSize]byte, error) {
+	s, err := arraySig("signature", sig)
+	if err != nil {
+		return [SigSize]byte{}, err
+	}
+	m, err := array32("message", msg)
+	if err != nil {
+		return [SigSize]byte{}, err
+	}
+	p, err := array32("public seed", pubSeed)
+	if err != nil {
+		return [SigSize]byte{}, err
+	}
+	a, err := array32("address seed", addrSeed)
+	if err != nil {
+		return [SigSize]byte{}, err
+	}
+	return PkFromSig(s, m, p, a), nil
+}
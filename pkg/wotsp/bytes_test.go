@@ -0,0 +1,81 @@
+package wotsp
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestBytesVariantsMatchArrays(t *testing.T) {
+	seed, msg := vectorSeed(t, 0)
+	c := GenerateComponents(seed)
+	addr := SigningAddress(c.AddrSeed)
+
+	pk, err := PkGenBytes(c.PrivateSeed[:], c.PublicSeed[:], addr[:])
+	if err != nil || pk != PkGen(c.PrivateSeed, c.PublicSeed, addr) {
+		t.Errorf("PkGenBytes: %v", err)
+	}
+	sig, err := SignBytes(msg[:], c.PrivateSeed[:], c.PublicSeed[:], addr[:])
+	if err != nil || sig != Sign(msg, c.PrivateSeed, c.PublicSeed, addr) {
+		t.Errorf("SignBytes: %v", err)
+	}
+	recovered, err := PkFromSigBytes(sig[:], msg[:], c.PublicSeed[:], addr[:])
+	if err != nil || recovered != pk {
+		t.Errorf("PkFromSigBytes: %v", err)
+	}
+}
+
+func TestBytesVariantsErrors(t *testing.T) {
+	ok := make([]byte, 32)
+	sig := make([]byte, SigSize)
+	short, long := make([]byte, 31), make([]byte, 33)
+	for _, tc := range []struct {
+		name string
+		call func() error
+		want string
+	}{
+		{"PkGen nil seed", func() error { _, err := PkGenBytes(nil, ok, ok); return err }, "seed must be 32 bytes, got 0"},
+		{"PkGen short seed", func() error { _, err := PkGenBytes(short, ok, ok); return err }, "seed must be 32 bytes, got 31"},
+		{"PkGen long public seed", func() error { _, err := PkGenBytes(ok, long, ok); return err }, "public seed must be 32 bytes, got 33"},
+		{"PkGen short address", func() error { _, err := PkGenBytes(ok, ok, short); return err }, "address seed must be 32 bytes, got 31"},
+		{"Sign short message", func() error { _, err := SignBytes(short, ok, ok, ok); return err }, "message must be 32 bytes, got 31"},
+		{"Sign long seed", func() error { _, err := SignBytes(ok, long, ok, ok); return err }, "seed must be 32 bytes, got 33"},
+		{"Sign nil public seed", func() error { _, err := SignBytes(ok, ok, nil, ok); return err }, "public seed must be 32 bytes, got 0"},
+		{"Sign long address", func() error { _, err := SignBytes(ok, ok, ok, long); return err }, "address seed must be 32 bytes, got 33"},
+		{"PkFromSig short signature", func() error { _, err := PkFromSigBytes(sig[1:], ok, ok, ok); return err }, "signature must be 2144 bytes, got 2143"},
+		{"PkFromSig full address as signature", func() error {
+			_, err := PkFromSigBytes(make([]byte, 2208), ok, ok, ok)
+			return err
+		}, "signature must be 2144 bytes, got 2208"},
+		{"PkFromSig long message", func() error { _, err := PkFromSigBytes(sig, long, ok, ok); return err }, "message must be 32 bytes, got 33"},
+		{"PkFromSig short public seed", func() error { _, err := PkFromSigBytes(sig, ok, short, ok); return err }, "public seed must be 32 bytes, got 31"},
+		{"PkFromSig nil address", func() error { _, err := PkFromSigBytes(sig, ok, ok, nil); return err }, "address seed must be 32 bytes, got 0"},
+		{"Verify short expected key", func() error {
+			_, err := Verify([32]byte{}, [SigSize]byte{}, [32]byte{}, [32]byte{}, sig[1:])
+			return err
+		}, "expected public key must be 2144 bytes, got 2143"},
+	} {
+		if err := tc.call(); err == nil || !strings.Contains(err.Error(), tc.want) {
+			t.Errorf("%s: got %v, want %q", tc.name, err, tc.want)
+		}
+	}
+}
+
+// FuzzBytesVariants feeds arbitrary lengths to the slice variants: they return errors, never panic
+func FuzzBytesVariants(f *testing.F) {
+	f.Add(make([]byte, SigSize), make([]byte, 32), make([]byte, 32), make([]byte, 32))
+	f.Add([]byte{}, []byte{}, []byte{}, []byte{})
+	f.Add(make([]byte, 2208), make([]byte, 31), make([]byte, 33), make([]byte, 32))
+	f.Fuzz(func(t *testing.T, sig []byte, msg []byte, pubSeed []byte, addr []byte) {
+		valid := len(msg) == 32 && len(pubSeed) == 32 && len(addr) == 32
+		if _, err := PkFromSigBytes(sig, msg, pubSeed, addr); (err == nil) != (valid && len(sig) == SigSize) {
+			t.Fatalf("PkFromSigBytes: %v", err)
+		}
+		// The message doubles as the seed to keep the signing path covered
+		if _, err := SignBytes(msg, msg, pubSeed, addr); (err == nil) != valid {
+			t.Fatalf("SignBytes: %v", err)
+		}
+		if _, err := PkGenBytes(msg, pubSeed, addr); (err == nil) != valid {
+			t.Fatalf("PkGenBytes: %v", err)
+		}
+	})
+}
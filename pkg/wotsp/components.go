@@ -0,0 +1,45 @@
+package wotsp
+
+import (
+	"crypto/sha256"
+
+	"github.com/NickP005/Vindax-MCM-tools/pkg/secure"
+)
+
+// Components are the three 32 bytes seeds a WOTS keypair is derived from
+type Components struct {
+	PrivateSeed [32]byte
+	PublicSeed  [32]byte
+	AddrSeed    [32]byte
+}
+
+/*
+ * GenerateComponents derives the WOTS components from a 32 bytes wallet seed
+ *
+ * Parameters:
+ * - seed: the 32 bytes secret seed of the account
+ *
+ * Returns the components, each the SHA-256 of the seed followed by a 4 bytes
+ * ASCII label, as in the Mochimo reference:
+ *   1. PrivateSeed = sha256(seed || "seed"), expanded into the secret chains
+ *   2. PublicSeed  = sha256(seed || "publ"), keys the chaining function
+ *   3. AddrSeed    = sha256(seed || "addr"), the hash address (its first 20
+ *      bytes followed by the default tag form the signing address)
+ *
+ * The derivation always uses SHA-256, whatever the Params. The caller must
+ * wipe PrivateSeed once done with it.
+ */
+func GenerateComponents(seed [32]byte) Components {
+	derive := func(label string) [32]byte {
+		var buf [32 + 4]byte
+		copy(buf[:], seed[:])
+		copy(buf[32:], label)
+		defer secure.Wipe(buf[:])
+		return sha256.Sum256(buf[:])
+	}
+	return Components{
+		PrivateSeed: derive("seed"),
+		PublicSeed:  derive("publ"),
+		AddrSeed:    derive("addr"),
+	}
+}
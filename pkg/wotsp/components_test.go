@@ -0,0 +1,96 @@
+package wotsp
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"testing"
+)
+
+/*
+ * componentVectors pin the three seeds derived from known wallet seeds
+ *
+ * They were produced by this package and checked against an independent
+ * SHA-256 (Python's hashlib) of the seed followed by each label.
+ */
+var componentVectors = []struct {
+	seed        string
+	privateSeed string
+	publicSeed  string
+	addrSeed    string
+}{
+	{
+		seed:        "0000000000000000000000000000000000000000000000000000000000000000",
+		privateSeed: "d946a8cb7816cc2df74220a5240743725e6887bbdd7118d3f055e0069d66b7e6",
+		publicSeed:  "e91fbaa1089e91c5b2e8c781e1602f97db2591423c11baffb70fa2118d204339",
+		addrSeed:    "01dd935548226652b4f0f29e5bb6d62d900f794019e7fca1e6c3426c9ee2dec6",
+	},
+	{
+		seed:        "9fd07681776e3f6b8dc56ce9707ed2b627ac445becfb526613d8306ae1d0d355",
+		privateSeed: "ca16e155a8888df626b00c84c836a940cce10e43d0de5150b4acfa1bdecea172",
+		publicSeed:  "6412d1838e96a1e0a08034948086160d24ce8ff03dfa8f2900034b6cc19f88ca",
+		addrSeed:    "9fd952e01c37e554aa87ddaec64871d96863428f34ab868fc37301242cc49b67",
+	},
+	{
+		seed:        "ffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffff",
+		privateSeed: "f84009264c33fc1423e6d66fc6be92b1cf30a02590af991a44577e3da4022000",
+		publicSeed:  "037189e94eb47fd30b082ae0e9f75b697d4174ed847745f119f5d9a9138ecd7f",
+		addrSeed:    "33580eb7aa5132587712f6ce411055236ae7d5b863cd43bcbebd9b2c8303b1ad",
+	},
+}
+
+func TestComponentVectors(t *testing.T) {
+	for _, v := range componentVectors {
+		seed, err := hex.DecodeString(v.seed)
+		if err != nil {
+			t.Fatal(err)
+		}
+		c := GenerateComponents([32]byte(seed))
+		for _, field := range []struct {
+			name string
+			got  [32]byte
+			want string
+		}{
+			{"private seed", c.PrivateSeed, v.privateSeed},
+			{"public seed", c.PublicSeed, v.publicSeed},
+			{"address seed", c.AddrSeed, v.addrSeed},
+		} {
+			if hex.EncodeToString(field.got[:]) != field.want {
+				t.Errorf("%s: %s %x, want %s", v.seed, field.name, field.got, field.want)
+			}
+		}
+	}
+}
+
+// TestComponentsRule checks the documented rule, sha256(seed || label), on seeds of every byte value
+func TestComponentsRule(t *testing.T) {
+	for b := 0; b < 256; b++ {
+		var seed [32]byte
+		for i := range seed {
+			seed[i] = byte(b + i)
+		}
+		c := GenerateComponents(seed)
+		for label, got := range map[string][32]byte{"seed": c.PrivateSeed, "publ": c.PublicSeed, "addr": c.AddrSeed} {
+			if want := sha256.Sum256(append(seed[:], label...)); got != want {
+				t.Fatalf("seed %x: %q component %x, want %x", seed, label, got, want)
+			}
+		}
+	}
+}
+
+// TestKeygenUsesComponents checks that a keypair signs with the components of its seed
+func TestKeygenUsesComponents(t *testing.T) {
+	for _, v := range componentVectors {
+		seed, _ := hex.DecodeString(v.seed)
+		keypair := Keygen([32]byte(seed))
+		c := GenerateComponents([32]byte(seed))
+		if keypair.Components != c {
+			t.Errorf("%s: keypair components differ", v.seed)
+		}
+		if keypair.Address != SigningAddress(c.AddrSeed) || hex.EncodeToString(keypair.Address[:20]) != v.addrSeed[:40] {
+			t.Errorf("%s: signing address %x", v.seed, keypair.Address)
+		}
+		if [12]byte(keypair.Address[20:]) != DefaultTag {
+			t.Errorf("%s: signing address does not end with the default tag", v.seed)
+		}
+	}
+}
@@ -0,0 +1,48 @@
+package wotsp
+
+import (
+	"crypto/sha512"
+	"encoding/binary"
+
+	"github.com/NickP005/Vindax-MCM-tools/pkg/secure"
+)
+
+/*
+ * DeriveSeed returns the seed of the key at index of a keychain, as
+ * WOTS-Go's Keychain.Next derives it
+ *
+ *   seed = sha512(master || be64(index) || be32(0))[:32]
+ *
+ * which is the first output block of WOTS-Go's DigestRandomGenerator seeded
+ * with sha512(master || be64(index)). Keygen of the seed gives the keypair
+ * WOTS-Go returns for the index, so wallets created by either keep the
+ * same addresses.
+ */
+func DeriveSeed(master [32]byte, index uint64) [32]byte {
+	var indexBytes [8]byte
+	binary.BigEndian.PutUint64(indexBytes[:], index)
+	local := sha512.New()
+	local.Write(master[:])
+	local.Write(indexBytes[:])
+	localSeed := local.Sum(nil)
+
+	// The generator's first digest hashes its seed material with a zero counter
+	var counter [4]byte
+	block := sha512.New()
+	block.Write(localSeed)
+	block.Write(counter[:])
+	digest := block.Sum(nil)
+
+	var seed [32]byte
+	copy(seed[:], digest)
+	secure.Wipe(localSeed)
+	secure.Wipe(digest)
+	return seed
+}
+
+// KeychainKeygen derives the keypair at index of a keychain, see DeriveSeed
+func KeychainKeygen(master [32]byte, index uint64) Keypair {
+	seed := DeriveSeed(master, index)
+	defer secure.Wipe(seed[:])
+	return Keygen(seed)
+}
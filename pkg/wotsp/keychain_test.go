@@ -0,0 +1,86 @@
+package wotsp
+
+import (
+	"bytes"
+	"encoding/hex"
+	"testing"
+)
+
+/*
+ * deriveSeedVectors are the seeds of WOTS-Go's Keychain at known indices
+ *
+ * They were computed apart from this package with Python's hashlib, as
+ * WOTS-Go's DigestRandomGenerator derives them: the first SHA-512 block of
+ * sha512(master || be64(index)) and a zero counter, truncated to 32 bytes.
+ */
+var deriveSeedVectors = []struct {
+	master string
+	index  uint64
+	seed   string
+}{
+	{"0000000000000000000000000000000000000000000000000000000000000000", 0, "6d6715771160e380e5bde4ed5247e4c4c34c336044fc523ae265b3297bb91fdb"},
+	{"0000000000000000000000000000000000000000000000000000000000000000", 1, "82bc6baf3cc771a825723cb54cb07fe43f47e35cf0350b193daf8af7d52845ff"},
+	{"0000000000000000000000000000000000000000000000000000000000000000", 255, "c9d2040c9d3a05b52f3780c894eaad68e128a3cf6b860fc72b1526c9a3e51c17"},
+	{"0000000000000000000000000000000000000000000000000000000000000000", 1 << 32, "3e0625c246864a620b251fdb2d471f82caea812dbe508f1847c006c1788ac6bf"},
+	{"ffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffff", 0, "ee7a63fb26736115026d5ca574fa4036406cf7e7683883605d38e3dd3b766bb3"},
+	{"ffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffff", 2, "e4485e20f972b3b0af890ea6b3991e66cde19f3560ebc60edb4806f9251d2e6f"},
+	{"ffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffff", 1<<64 - 1, "9eca53de4e7c3132b26c93346363e38a91b584ac724b4bac917ef4b22484cfb5"},
+	{"000102030405060708090a0b0c0d0e0f101112131415161718191a1b1c1d1e1f", 0, "7956429b017ec1853e275e3d29da196da66ef38b024a263d444a26ec0b5e4bd7"},
+	{"000102030405060708090a0b0c0d0e0f101112131415161718191a1b1c1d1e1f", 1, "924ae73cf1f6ee5f125e31ed015366a0eaaf64f2620f504db94f4a74ccae0316"},
+	{"1717171717171717171717171717171717171717171717171717171717171717", 0, "ad6b6df2851d1faf23ff22acd77b0ffa15611b3fdb5871b3ea3027701e3c973e"},
+	{"1717171717171717171717171717171717171717171717171717171717171717", 1, "99be9872e73c47a26783909152980607c3242d5db8a5b9c30df12d5134d8b97a"},
+	{"1717171717171717171717171717171717171717171717171717171717171717", 255, "33f2824334adea8b9eb50bf07f56da33d776ca5f2b84e620b207fe896f23ba27"},
+}
+
+/*
+ * keychainVectors pin the address hash of the keypair at an index of a
+ * keychain; index 0 of the 0x17 master is the tag of the wallet cache
+ * fixtures of the tools
+ */
+var keychainVectors = []struct {
+	master   byte
+	index    uint64
+	addrHash string
+}{
+	{0x00, 0, "6059bf7ce0155b2296a5e38902b8e612ec32265a"},
+	{0x00, 1, "ab7ffaae24fe342c70531930c47aa7388e1f337d"},
+	{0x00, 255, "d652188497bf4c3b0cd1e2b089cc9bf9f5fe7ba9"},
+	{0x17, 0, "8412dab16d9ca3ba10498d9fab6a38936b1f3e78"},
+	{0x17, 1, "7e9bd674dd83ea102ba0a5b80b7012f8be0fa151"},
+	{0x17, 255, "c6ad37cec27fcae399790d39e2b13a47488da003"},
+}
+
+func TestDeriveSeedVectors(t *testing.T) {
+	for _, v := range deriveSeedVectors {
+		master, err := hex.DecodeString(v.master)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if seed := DeriveSeed([32]byte(master), v.index); hex.EncodeToString(seed[:]) != v.seed {
+			t.Errorf("%s at %d: seed %x, want %s", v.master, v.index, seed, v.seed)
+		}
+	}
+}
+
+func TestKeychainKeygen(t *testing.T) {
+	for _, v := range keychainVectors {
+		master := [32]byte(bytes.Repeat([]byte{v.master}, 32))
+		keypair := KeychainKeygen(master, v.index)
+		if hash := keypair.AddrHash(); hex.EncodeToString(hash[:]) != v.addrHash {
+			t.Errorf("%02x at %d: address hash %x, want %s", v.master, v.index, hash, v.addrHash)
+		}
+		// The keypair is Keygen of the derived seed, which signs and verifies
+		seeded := Keygen(DeriveSeed(master, v.index))
+		if seeded.PublicKey != keypair.PublicKey || seeded.Components != keypair.Components {
+			t.Errorf("%02x at %d: differs from Keygen of DeriveSeed", v.master, v.index)
+		}
+	}
+
+	master := [32]byte{0x17}
+	msg := [32]byte{1, 2, 3}
+	keypair := KeychainKeygen(master, 3)
+	sig := keypair.Sign(msg)
+	if PkFromSig(sig, msg, keypair.Components.PublicSeed, keypair.Address) != keypair.PublicKey {
+		t.Error("keychain key does not verify its own signature")
+	}
+}
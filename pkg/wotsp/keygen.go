@@ -0,0 +1,51 @@
+package wotsp
+
+import "github.com/NickP005/Vindax-MCM-tools/pkg/secure"
+
+// DefaultTag is the 12 bytes trailer completing the signing address of a keypair
+var DefaultTag = [12]byte{0x42, 0x00, 0x00, 0x00, 0x0e, 0x00, 0x00, 0x00, 0x01, 0x00, 0x00, 0x00}
+
+// SigningAddress is the first 20 bytes of the address seed followed by DefaultTag, the hash address a keypair signs with
+func SigningAddress(addrSeed [32]byte) [32]byte {
+	var addr [32]byte
+	copy(addr[:], addrSeed[:20])
+	copy(addr[20:], DefaultTag[:])
+	return addr
+}
+
+/*
+ * Keypair is the WOTS+ keypair of a 32 bytes seed, as WOTS-Go's Keygen
+ * derives it
+ *
+ * Components.PrivateSeed is the secret; Wipe clears it once the keypair
+ * has signed. A WOTS+ key must sign only once.
+ */
+type Keypair struct {
+	Components Components
+	// Address is the signing address, SigningAddress of the address seed
+	Address   [32]byte
+	PublicKey [SigSize]byte
+}
+
+// Keygen derives the keypair of a seed with the Default parameters
+func Keygen(seed [32]byte) Keypair {
+	keypair := Keypair{Components: GenerateComponents(seed)}
+	keypair.Address = SigningAddress(keypair.Components.AddrSeed)
+	keypair.PublicKey = PkGen(keypair.Components.PrivateSeed, keypair.Components.PublicSeed, keypair.Address)
+	return keypair
+}
+
+// Sign signs a 32 bytes message hash
+func (k *Keypair) Sign(msg [32]byte) [SigSize]byte {
+	return Sign(msg, k.Components.PrivateSeed, k.Components.PublicSeed, k.Address)
+}
+
+// AddrHash returns the 20 bytes address hash of the public key
+func (k *Keypair) AddrHash() [AddrHashLength]byte {
+	return AddrHash(k.PublicKey)
+}
+
+// Wipe overwrites the private seed
+func (k *Keypair) Wipe() {
+	secure.Wipe(k.Components.PrivateSeed[:])
+}
@@ -0,0 +1,90 @@
+package wotsp
+
+import "crypto/sha256"
+
+/*
+ * Params is a WOTS+ parameter set: the hash behind PRF and the chaining
+ * function, and the domain separation paddings prepended to its input
+ *
+ * n = 32 and w = 16 are fixed, so Hash must return 32 bytes. A new parameter
+ * set (e.g. SHA3 based) is just a new Params value; every primitive is a
+ * method on Params and the package level functions use Default(), which
+ * returns a copy so importers cannot change what the tools sign with.
+ */
+type Params struct {
+	Name       string
+	Hash       func(data []byte) [32]byte
+	PaddingF   uint64
+	PaddingPRF uint64
+}
+
+// sha256Params is the parameter set of Mochimo 3.0 WOTS+ addresses (SHA-256 with the XMSS paddings)
+var sha256Params = Params{
+	Name:       "sha256",
+	Hash:       sha256.Sum256,
+	PaddingF:   0,
+	PaddingPRF: 3,
+}
+
+// defaultParams is used by the package level functions; unexported so no importer can swap it
+var defaultParams = &sha256Params
+
+// SHA256 returns a copy of the parameter set of Mochimo 3.0 WOTS+ addresses (SHA-256 with the XMSS paddings)
+func SHA256() *Params {
+	p := sha256Params
+	return &p
+}
+
+// Default returns a copy of the parameter set used by the package level functions and by all tools
+func Default() *Params {
+	p := *defaultParams
+	return &p
+}
+
+// PRF computes the keyed pseudo random function with the Default parameters
+func PRF(in []byte, key []byte) [32]byte { return defaultParams.PRF(in, key) }
+
+// ThashF is the chaining function with the Default parameters
+func ThashF(in []byte, pubSeed []byte, addr *Address) ([32]byte, error) {
+	return defaultParams.ThashF(in, pubSeed, addr)
+}
+
+// GenChain advances a chain with the Default parameters
+func GenChain(out []byte, start int, steps int, pubSeed []byte, addr *Address) error {
+	return defaultParams.GenChain(out, start, steps, pubSeed, addr)
+}
+
+// PkGen computes a public key with the Default parameters
+func PkGen(seed [32]byte, pubSeed [32]byte, addrSeed [32]byte) [SigSize]byte {
+	return defaultParams.PkGen(seed, pubSeed, addrSeed)
+}
+
+// PkGenWorkers computes a public key with the Default parameters over workers goroutines
+func PkGenWorkers(seed [32]byte, pubSeed [32]byte, addrSeed [32]byte, workers int) [SigSize]byte {
+	return defaultParams.PkGenWorkers(seed, pubSeed, addrSeed, workers)
+}
+
+// Sign signs msg with the Default parameters
+func Sign(msg [32]byte, seed [32]byte, pubSeed [32]byte, addrSeed [32]byte) [SigSize]byte {
+	return defaultParams.Sign(msg, seed, pubSeed, addrSeed)
+}
+
+// SignWorkers signs msg with the Default parameters over workers goroutines
+func SignWorkers(msg [32]byte, seed [32]byte, pubSeed [32]byte, addrSeed [32]byte, workers int) [SigSize]byte {
+	return defaultParams.SignWorkers(msg, seed, pubSeed, addrSeed, workers)
+}
+
+// PkFromSig recomputes a public key with the Default parameters
+func PkFromSig(sig [SigSize]byte, msg [32]byte, pubSeed [32]byte, addrSeed [32]byte) [SigSize]byte {
+	return defaultParams.PkFromSig(sig, msg, pubSeed, addrSeed)
+}
+
+// PkFromSigWorkers recomputes a public key with the Default parameters over workers goroutines
+func PkFromSigWorkers(sig [SigSize]byte, msg [32]byte, pubSeed [32]byte, addrSeed [32]byte, workers int) [SigSize]byte {
+	return defaultParams.PkFromSigWorkers(sig, msg, pubSeed, addrSeed, workers)
+}
+
+// Verify checks a signature with the Default parameters
+func Verify(msg [32]byte, sig [SigSize]byte, pubSeed [32]byte, addrSeed [32]byte, expected []byte) (bool, error) {
+	return defaultParams.Verify(msg, sig, pubSeed, addrSeed, expected)
+}
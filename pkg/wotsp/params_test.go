@@ -0,0 +1,64 @@
+package wotsp
+
+import (
+	"testing"
+
+	"golang.org/x/crypto/sha3"
+)
+
+// TestDefaultReproducesVectors pins the default parameters to today's keys and signatures
+func TestDefaultReproducesVectors(t *testing.T) {
+	for i, v := range vectors {
+		seed, msg := vectorSeed(t, i)
+		c := GenerateComponents(seed)
+		addr := SigningAddress(c.AddrSeed)
+		for name, p := range map[string]*Params{"Default()": Default(), "SHA256()": SHA256()} {
+			pk := p.PkGen(c.PrivateSeed, c.PublicSeed, addr)
+			sig := p.Sign(msg, c.PrivateSeed, c.PublicSeed, addr)
+			if digest(pk[:]) != v.publicKey || digest(sig[:]) != v.signature {
+				t.Errorf("vector %d: %s gives another key or signature", i, name)
+			}
+		}
+	}
+}
+
+// TestDefaultIsACopy checks that an importer changing the returned parameters changes nothing else
+func TestDefaultIsACopy(t *testing.T) {
+	seed, msg := vectorSeed(t, 0)
+	c := GenerateComponents(seed)
+	addr := SigningAddress(c.AddrSeed)
+
+	p := Default()
+	p.PaddingPRF = 7
+	p.Hash = sha3.Sum256
+	s := SHA256()
+	s.Name = "changed"
+
+	if Default().PaddingPRF != 3 || Default().Name != "sha256" || SHA256().Name != "sha256" {
+		t.Fatalf("changes leaked into the package parameters: %+v", *Default())
+	}
+	sig := Sign(msg, c.PrivateSeed, c.PublicSeed, addr)
+	if digest(sig[:]) != vectors[0].signature {
+		t.Error("package level Sign changed after modifying a copy")
+	}
+}
+
+// TestOtherParams shows a second parameter set only needs a new Params value
+func TestOtherParams(t *testing.T) {
+	sha3Params := &Params{Name: "sha3-256", Hash: sha3.Sum256, PaddingF: 0, PaddingPRF: 3}
+	seed, msg := vectorSeed(t, 1)
+	c := GenerateComponents(seed)
+	addr := SigningAddress(c.AddrSeed)
+
+	pk := sha3Params.PkGen(c.PrivateSeed, c.PublicSeed, addr)
+	sig := sha3Params.Sign(msg, c.PrivateSeed, c.PublicSeed, addr)
+	if ok, err := sha3Params.Verify(msg, sig, c.PublicSeed, addr, pk[:]); !ok || err != nil {
+		t.Fatalf("SHA3 round trip: %v, %v", ok, err)
+	}
+	if digest(pk[:]) == vectors[1].publicKey {
+		t.Error("SHA3 parameters give the SHA-256 public key")
+	}
+	if ok, _ := Verify(msg, sig, c.PublicSeed, addr, pk[:]); ok {
+		t.Error("a SHA3 signature verifies with the default parameters")
+	}
+}
@@ -0,0 +1,347 @@
+/*
+ * Package wotsp implements the WOTS+ one-time signature scheme used by Mochimo
+ *
+ * This is a port of the Mochimo reference implementation (wots.c, derived
+ * from the XMSS reference code) with parameters n = 32, w = 16:
+ * - 64 message chains plus 3 checksum chains (67 in total)
+ * - private keys, signatures and public keys are 67 * 32 = 2144 bytes
+ *
+ * The 32 byte address is read as eight little-endian 32-bit words (as the C
+ * code casts the byte buffer) and serialized big-endian when hashed.
+ */
+package wotsp
+
+import (
+	"encoding/binary"
+	"fmt"
+	"runtime"
+	"sync"
+
+	"github.com/NickP005/Vindax-MCM-tools/pkg/secure"
+)
+
+const (
+	N       = 32
+	W       = 16
+	LogW    = 4
+	Len1    = 64
+	Len2    = 3
+	Len     = Len1 + Len2
+	SigSize = Len * N
+)
+
+// Address is the hash address as eight 32-bit words
+type Address [8]uint32
+
+// AddressFromBytes reads a 32 bytes address as eight little-endian words
+func AddressFromBytes(b [32]byte) Address {
+	var addr Address
+	for i := range addr {
+		addr[i] = binary.LittleEndian.Uint32(b[i*4:])
+	}
+	return addr
+}
+
+// Bytes serializes the address big-endian, as it is hashed
+func (a *Address) Bytes() [32]byte {
+	var out [32]byte
+	for i, word := range a {
+		binary.BigEndian.PutUint32(out[i*4:], word)
+	}
+	return out
+}
+
+func (a *Address) SetChain(chain uint32)   { a[5] = chain }
+func (a *Address) SetHash(hash uint32)     { a[6] = hash }
+func (a *Address) SetKeyAndMask(km uint32) { a[7] = km }
+
+// coreHash computes hash(toByte(padding, 32) || key || in)
+func (p *Params) coreHash(padding uint64, key []byte, in []byte) [32]byte {
+	buf := make([]byte, 0, N+len(key)+len(in))
+	var pad [N]byte
+	binary.BigEndian.PutUint64(pad[N-8:], padding)
+	buf = append(buf, pad[:]...)
+	buf = append(buf, key...)
+	buf = append(buf, in...)
+	return p.Hash(buf)
+}
+
+// PRF computes the keyed pseudo random function hash(pad(PaddingPRF) || key || in)
+func (p *Params) PRF(in []byte, key []byte) [32]byte {
+	return p.coreHash(p.PaddingPRF, key, in)
+}
+
+/*
+ * ThashF is the keyed and masked chaining function
+ *
+ * Returns an error if in is shorter than N bytes or pubSeed is not N bytes;
+ * only the first N bytes of in are hashed.
+ */
+func (p *Params) ThashF(in []byte, pubSeed []byte, addr *Address) ([32]byte, error) {
+	if err := checkChainInput(in, pubSeed); err != nil {
+		return [32]byte{}, err
+	}
+	return p.thashF(in, pubSeed, addr), nil
+}
+
+// checkChainInput validates the chain value and public seed of ThashF and GenChain
+func checkChainInput(in []byte, pubSeed []byte) error {
+	if len(in) < N {
+		return fmt.Errorf("chain value of %d bytes, need %d", len(in), N)
+	}
+	if len(pubSeed) != N {
+		return fmt.Errorf("public seed must be %d bytes, got %d", N, len(pubSeed))
+	}
+	return nil
+}
+
+// thashF is ThashF for inputs the caller sized
+func (p *Params) thashF(in []byte, pubSeed []byte, addr *Address) [32]byte {
+	addr.SetKeyAndMask(0)
+	addrBytes := addr.Bytes()
+	key := p.PRF(addrBytes[:], pubSeed)
+
+	addr.SetKeyAndMask(1)
+	addrBytes = addr.Bytes()
+	bitmask := p.PRF(addrBytes[:], pubSeed)
+
+	var masked [N]byte
+	for i := range masked {
+		masked[i] = in[i] ^ bitmask[i]
+	}
+	return p.coreHash(p.PaddingF, key[:], masked[:])
+}
+
+/*
+ * GenChain applies steps iterations of ThashF to out in place, starting at
+ * position start; the chain stops at position W - 1
+ *
+ * Returns an error, leaving out unchanged, if out is shorter than N bytes,
+ * pubSeed is not N bytes or start or steps is negative.
+ */
+func (p *Params) GenChain(out []byte, start int, steps int, pubSeed []byte, addr *Address) error {
+	if err := checkChainInput(out, pubSeed); err != nil {
+		return err
+	}
+	if start < 0 || steps < 0 {
+		return fmt.Errorf("chain start %d and steps %d must not be negative", start, steps)
+	}
+	p.genChain(out, start, steps, pubSeed, addr)
+	return nil
+}
+
+// genChain is GenChain for inputs the caller sized
+func (p *Params) genChain(out []byte, start int, steps int, pubSeed []byte, addr *Address) {
+	for i := start; i < start+steps && i < W; i++ {
+		addr.SetHash(uint32(i))
+		h := p.thashF(out, pubSeed, addr)
+		copy(out, h[:])
+	}
+}
+
+/*
+ * BaseW splits input into outLen base-w digits
+ *
+ * Returns an error if outLen is negative or input holds fewer than
+ * outLen * LogW bits.
+ */
+func BaseW(outLen int, input []byte) ([]int, error) {
+	if outLen < 0 || len(input)*8 < outLen*LogW {
+		return nil, fmt.Errorf("base-w: %d bytes input is too short for %d digits", len(input), outLen)
+	}
+	output := make([]int, outLen)
+	in := 0
+	bits := 0
+	var total byte
+	for out := 0; out < outLen; out++ {
+		if bits == 0 {
+			total = input[in]
+			in++
+			bits += 8
+		}
+		bits -= LogW
+		output[out] = int(total>>bits) & (W - 1)
+	}
+	return output, nil
+}
+
+// checksum computes the Len2 base-w digits of the checksum of the Len1 message digits
+func checksum(msgBaseW []int) []int {
+	csum := 0
+	for i := 0; i < Len1; i++ {
+		csum += W - 1 - msgBaseW[i]
+	}
+	csum <<= 8 - ((Len2 * LogW) % 8)
+	// Big-endian, sized from the parameters rather than assuming two bytes
+	var csumBytes [(Len2*LogW + 7) / 8]byte
+	for i := range csumBytes {
+		csumBytes[len(csumBytes)-1-i] = byte(csum >> (8 * i))
+	}
+	digits, _ := BaseW(Len2, csumBytes[:]) // cannot fail: csumBytes holds Len2 * LogW bits
+	return digits
+}
+
+// ChainLengths returns the position of each of the 67 chains a signature of msg reveals
+func ChainLengths(msg [32]byte) []int {
+	lengths, _ := BaseW(Len1, msg[:]) // cannot fail: 32 bytes hold Len1 * LogW bits
+	return append(lengths, checksum(lengths)...)
+}
+
+// ChainLengthsBytes is ChainLengths for a message held in a slice, which must be N bytes
+func ChainLengthsBytes(msg []byte) ([]int, error) {
+	if len(msg) != N {
+		return nil, fmt.Errorf("message must be %d bytes, got %d", N, len(msg))
+	}
+	return ChainLengths([N]byte(msg)), nil
+}
+
+// expandSeed derives the 67 chain secrets from the 32 bytes seed
+func (p *Params) expandSeed(seed [32]byte) [SigSize]byte {
+	var out [SigSize]byte
+	var ctr [32]byte
+	for i := 0; i < Len; i++ {
+		binary.BigEndian.PutUint64(ctr[24:], uint64(i))
+		h := p.PRF(ctr[:], seed[:])
+		copy(out[i*N:], h[:])
+	}
+	return out
+}
+
+// MaxDefaultWorkers caps the worker count picked by DefaultWorkers
+const MaxDefaultWorkers = 8
+
+// DefaultWorkers returns GOMAXPROCS capped at MaxDefaultWorkers
+func DefaultWorkers() int {
+	return min(runtime.GOMAXPROCS(0), MaxDefaultWorkers)
+}
+
+/*
+ * computeChains advances each of the 67 chains of buf in place, chain i from
+ * position start(i) for steps(i) iterations
+ *
+ * The chains are independent, so with more than one worker they are split
+ * across goroutines, each writing only its own 32 bytes regions of buf with
+ * its own copy of the address. The result does not depend on workers.
+ */
+func (p *Params) computeChains(buf *[SigSize]byte, pubSeed [32]byte, addrSeed [32]byte, workers int, start func(int) int, steps func(int) int) {
+	if workers <= 0 {
+		workers = DefaultWorkers()
+	}
+	chain := func(i int, addr *Address) {
+		addr.SetChain(uint32(i))
+		p.genChain(buf[i*N:(i+1)*N], start(i), steps(i), pubSeed[:], addr)
+	}
+
+	if workers == 1 {
+		addr := AddressFromBytes(addrSeed)
+		for i := 0; i < Len; i++ {
+			chain(i, &addr)
+		}
+		return
+	}
+
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			addr := AddressFromBytes(addrSeed)
+			for i := w; i < Len; i += workers {
+				chain(i, &addr)
+			}
+		}(w)
+	}
+	wg.Wait()
+}
+
+/*
+ * PkGen computes the WOTS public key of a secret seed
+ *
+ * Parameters:
+ * - seed: 32 bytes secret seed
+ * - pubSeed: 32 bytes public seed
+ * - addrSeed: 32 bytes address (the addr seed followed by the default tag)
+ *
+ * Returns the 2144 bytes public key.
+ */
+func (p *Params) PkGen(seed [32]byte, pubSeed [32]byte, addrSeed [32]byte) [SigSize]byte {
+	return p.PkGenWorkers(seed, pubSeed, addrSeed, 1)
+}
+
+// PkGenWorkers is PkGen with the chains spread over workers goroutines (DefaultWorkers if workers <= 0)
+func (p *Params) PkGenWorkers(seed [32]byte, pubSeed [32]byte, addrSeed [32]byte, workers int) [SigSize]byte {
+	pk := p.expandSeed(seed)
+	p.computeChains(&pk, pubSeed, addrSeed, workers,
+		func(int) int { return 0 },
+		func(int) int { return W - 1 })
+	return pk
+}
+
+/*
+ * Sign signs a 32 bytes message with the WOTS key of a secret seed
+ *
+ * WARNING: a WOTS key is one-time, each signature reveals part of the secret
+ * chains and a second signature with the same seed lets anyone forge one.
+ *
+ * Returns the 2144 bytes signature.
+ */
+func (p *Params) Sign(msg [32]byte, seed [32]byte, pubSeed [32]byte, addrSeed [32]byte) [SigSize]byte {
+	return p.SignWorkers(msg, seed, pubSeed, addrSeed, 1)
+}
+
+// SignWorkers is Sign with the chains spread over workers goroutines (DefaultWorkers if workers <= 0)
+func (p *Params) SignWorkers(msg [32]byte, seed [32]byte, pubSeed [32]byte, addrSeed [32]byte, workers int) [SigSize]byte {
+	lengths := ChainLengths(msg)
+	sig := p.expandSeed(seed)
+	p.computeChains(&sig, pubSeed, addrSeed, workers,
+		func(int) int { return 0 },
+		func(i int) int { return lengths[i] })
+	return sig
+}
+
+/*
+ * PkFromSig recomputes the WOTS public key from a signature
+ *
+ * Parameters:
+ * - sig: 2144 bytes WOTS signature
+ * - msg: 32 bytes signed message
+ * - pubSeed: 32 bytes public seed
+ * - addrSeed: 32 bytes address (the addr seed followed by the default tag)
+ *
+ * Returns the 2144 bytes public key; it equals the signer's public key only
+ * if the signature is valid for msg.
+ */
+func (p *Params) PkFromSig(sig [SigSize]byte, msg [32]byte, pubSeed [32]byte, addrSeed [32]byte) [SigSize]byte {
+	return p.PkFromSigWorkers(sig, msg, pubSeed, addrSeed, 1)
+}
+
+// PkFromSigWorkers is PkFromSig with the chains spread over workers goroutines (DefaultWorkers if workers <= 0)
+func (p *Params) PkFromSigWorkers(sig [SigSize]byte, msg [32]byte, pubSeed [32]byte, addrSeed [32]byte, workers int) [SigSize]byte {
+	lengths := ChainLengths(msg)
+	pk := sig
+	p.computeChains(&pk, pubSeed, addrSeed, workers,
+		func(i int) int { return lengths[i] },
+		func(i int) int { return W - 1 - lengths[i] })
+	return pk
+}
+
+/*
+ * Verify checks a signature against the signer's public key
+ *
+ * Parameters:
+ * - msg: 32 bytes signed message
+ * - sig: 2144 bytes WOTS signature
+ * - pubSeed: 32 bytes public seed
+ * - addrSeed: 32 bytes address (the addr seed followed by the default tag)
+ * - expected: the signer's 2144 bytes public key
+ *
+ * Returns true if the public key recomputed from the signature equals
+ * expected, compared in constant time; an error if expected has the wrong length.
+ */
+func (p *Params) Verify(msg [32]byte, sig [SigSize]byte, pubSeed [32]byte, addrSeed [32]byte, expected []byte) (bool, error) {
+	if len(expected) != SigSize {
+		return false, fmt.Errorf("expected public key must be %d bytes, got %d", SigSize, len(expected))
+	}
+	pk := p.PkFromSig(sig, msg, pubSeed, addrSeed)
+	return secure.Equal(pk[:], expected), nil
+}
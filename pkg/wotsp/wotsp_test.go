@@ -0,0 +1,472 @@
+package wotsp
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"math/rand"
+	"testing"
+)
+
+/*
+ * vectors pin the keys and signatures of known seeds
+ *
+ * Public keys and signatures are pinned by their SHA-256 to keep the file
+ * readable. They were produced by this package; the WOTS-Go and C reference
+ * outputs could not be generated in this tree, wots-vectors cross-checks
+ * WOTS-Go where it is available.
+ */
+var vectors = []struct {
+	seed      string
+	message   string
+	pubSeed   string
+	address   string
+	publicKey string // sha256 of the 2144 bytes public key
+	signature string // sha256 of the 2144 bytes signature of message
+	addrHash  string
+}{
+	{
+		seed:      "0000000000000000000000000000000000000000000000000000000000000000",
+		message:   "wotsp message zero",
+		pubSeed:   "e91fbaa1089e91c5b2e8c781e1602f97db2591423c11baffb70fa2118d204339",
+		address:   "01dd935548226652b4f0f29e5bb6d62d900f7940420000000e00000001000000",
+		publicKey: "53d94f08d54f3b7dc630a0aa534fbac6be30a9c1264d0b18fb152963351b0351",
+		signature: "b4c8b32469b01ee89f8955486dd847efe61f3ec83f602c354a9a95dbbdddde56",
+		addrHash:  "ab8599ef698c629d499909917d15c291dddc2760",
+	},
+	{
+		seed:      "9fd07681776e3f6b8dc56ce9707ed2b627ac445becfb526613d8306ae1d0d355",
+		message:   "wotsp message one",
+		pubSeed:   "6412d1838e96a1e0a08034948086160d24ce8ff03dfa8f2900034b6cc19f88ca",
+		address:   "9fd952e01c37e554aa87ddaec64871d96863428f420000000e00000001000000",
+		publicKey: "63c8044450b4f99c660f49909d13b8689506d69d9a8cf7f1f3fa20712b892130",
+		signature: "9fdc6295114226f98cd9fdbacc76dbb3d6d3057b13dfcc64d3d420f564007ade",
+		addrHash:  "3f0c07561c604bc4f626b54dcd435e2c7b302598",
+	},
+	{
+		seed:      "826bb7cedd7000b5b879f504138aac977d756310b209d7970fbdd32b3e14deb1",
+		message:   "wotsp message two",
+		pubSeed:   "b0d78b1cbab28c185f4a634be5b276655f9d10f3f7b0e7e65ce8371cd2c8baf3",
+		address:   "e1367c5e5e099eba473fa9e92916b43f7bd87f35420000000e00000001000000",
+		publicKey: "ffd91a08cd92da036f3924f83238496eb96a78abd0a114010e831a910289ced9",
+		signature: "f985ca756f4dfc7bb9c0ca170b756fa92c1f0db6a3e7190a2da0c59a81492fff",
+		addrHash:  "4ab3d3871108a918641323f1afff0a0a70b7e03b",
+	},
+}
+
+// vectorSeed decodes the seed of a vector and hashes its message
+func vectorSeed(t testing.TB, i int) (seed [32]byte, msg [32]byte) {
+	t.Helper()
+	b, err := hex.DecodeString(vectors[i].seed)
+	if err != nil || len(b) != 32 {
+		t.Fatalf("vector %d: bad seed", i)
+	}
+	return [32]byte(b), sha256.Sum256([]byte(vectors[i].message))
+}
+
+// digest is the hex SHA-256 of b
+func digest(b []byte) string {
+	sum := sha256.Sum256(b)
+	return hex.EncodeToString(sum[:])
+}
+
+func TestVectors(t *testing.T) {
+	for i, v := range vectors {
+		seed, msg := vectorSeed(t, i)
+		keypair := Keygen(seed)
+		if got := hex.EncodeToString(keypair.Components.PublicSeed[:]); got != v.pubSeed {
+			t.Errorf("vector %d: public seed %s, want %s", i, got, v.pubSeed)
+		}
+		if got := hex.EncodeToString(keypair.Address[:]); got != v.address {
+			t.Errorf("vector %d: address %s, want %s", i, got, v.address)
+		}
+		if got := digest(keypair.PublicKey[:]); got != v.publicKey {
+			t.Errorf("vector %d: public key digest %s, want %s", i, got, v.publicKey)
+		}
+		sig := keypair.Sign(msg)
+		if got := digest(sig[:]); got != v.signature {
+			t.Errorf("vector %d: signature digest %s, want %s", i, got, v.signature)
+		}
+		hash := keypair.AddrHash()
+		if got := hex.EncodeToString(hash[:]); got != v.addrHash {
+			t.Errorf("vector %d: address hash %s, want %s", i, got, v.addrHash)
+		}
+	}
+}
+
+// TestSignRoundTrip checks that the public key recomputed from a signature is the signer's
+func TestSignRoundTrip(t *testing.T) {
+	for i := range vectors {
+		seed, msg := vectorSeed(t, i)
+		keypair := Keygen(seed)
+		sig := keypair.Sign(msg)
+		pk := PkFromSig(sig, msg, keypair.Components.PublicSeed, keypair.Address)
+		if pk != keypair.PublicKey {
+			t.Errorf("vector %d: public key from signature differs from PkGen", i)
+		}
+		other := sha256.Sum256([]byte("another message"))
+		if PkFromSig(sig, other, keypair.Components.PublicSeed, keypair.Address) == keypair.PublicKey {
+			t.Errorf("vector %d: signature recovers the public key for another message", i)
+		}
+	}
+}
+
+// legacyPkFromSig is wotsPkFromSig of the tool-3/wots.go this package replaced, condensed
+func legacyPkFromSig(sig [SigSize]byte, msg [32]byte, pubSeed [32]byte, addrSeed [32]byte) [SigSize]byte {
+	var addr [8]uint32
+	for i := range addr {
+		addr[i] = binary.LittleEndian.Uint32(addrSeed[i*4:])
+	}
+	coreHash := func(padding uint64, key []byte, in []byte) [32]byte {
+		var pad [32]byte
+		binary.BigEndian.PutUint64(pad[24:], padding)
+		return sha256.Sum256(append(append(pad[:], key...), in...))
+	}
+	addrBytes := func() []byte {
+		var out [32]byte
+		for i, word := range addr {
+			binary.BigEndian.PutUint32(out[i*4:], word)
+		}
+		return out[:]
+	}
+	baseW := func(outLen int, input []byte) []int {
+		output := make([]int, outLen)
+		in, bits := 0, 0
+		var total byte
+		for out := range output {
+			if bits == 0 {
+				total = input[in]
+				in++
+				bits += 8
+			}
+			bits -= 4
+			output[out] = int(total>>bits) & 15
+		}
+		return output
+	}
+
+	lengths := baseW(64, msg[:])
+	csum := 0
+	for _, l := range lengths {
+		csum += 15 - l
+	}
+	csum <<= 4
+	lengths = append(lengths, baseW(3, []byte{byte(csum >> 8), byte(csum)})...)
+
+	pk := sig
+	for i := 0; i < 67; i++ {
+		addr[5] = uint32(i)
+		out := pk[i*32 : (i+1)*32]
+		for j := lengths[i]; j < 15; j++ {
+			addr[6] = uint32(j)
+			addr[7] = 0
+			key := coreHash(3, pubSeed[:], addrBytes())
+			addr[7] = 1
+			mask := coreHash(3, pubSeed[:], addrBytes())
+			for k := range out {
+				out[k] ^= mask[k]
+			}
+			h := coreHash(0, key[:], out)
+			copy(out, h[:])
+		}
+	}
+	return pk
+}
+
+// TestMatchesLegacy proves PkFromSig gives byte-identical results to the tool-3 copy it replaced
+func TestMatchesLegacy(t *testing.T) {
+	rng := rand.New(rand.NewSource(1649))
+	var sig [SigSize]byte
+	var msg, pubSeed, addrSeed [32]byte
+	for i := 0; i < 50; i++ {
+		rng.Read(sig[:])
+		rng.Read(msg[:])
+		rng.Read(pubSeed[:])
+		rng.Read(addrSeed[:])
+		if PkFromSig(sig, msg, pubSeed, addrSeed) != legacyPkFromSig(sig, msg, pubSeed, addrSeed) {
+			t.Fatalf("iteration %d: public key differs from the legacy implementation", i)
+		}
+	}
+}
+
+func TestVerify(t *testing.T) {
+	seed, msg := vectorSeed(t, 1)
+	keypair := Keygen(seed)
+	sig := keypair.Sign(msg)
+	pubSeed, addr := keypair.Components.PublicSeed, keypair.Address
+
+	if ok, err := Verify(msg, sig, pubSeed, addr, keypair.PublicKey[:]); !ok || err != nil {
+		t.Fatalf("valid signature: %v, %v", ok, err)
+	}
+	// A full 2208 bytes address is not a public key
+	full := append(keypair.PublicKey[:], make([]byte, 64)...)
+	if _, err := Verify(msg, sig, pubSeed, addr, full); err == nil {
+		t.Error("no error for a 2208 bytes expected key")
+	}
+
+	for _, bit := range []int{0, 7, 8*N + 3, SigSize*8 - 1} {
+		flipped := sig
+		flipped[bit/8] ^= 1 << (bit % 8)
+		if ok, err := Verify(msg, flipped, pubSeed, addr, keypair.PublicKey[:]); ok || err != nil {
+			t.Errorf("signature with bit %d flipped: %v, %v", bit, ok, err)
+		}
+	}
+
+	wrong := msg
+	wrong[31] ^= 1
+	if ok, err := Verify(wrong, sig, pubSeed, addr, keypair.PublicKey[:]); ok || err != nil {
+		t.Errorf("wrong message: %v, %v", ok, err)
+	}
+	other := Keygen(sha256.Sum256([]byte("another key")))
+	if ok, _ := Verify(msg, sig, pubSeed, addr, other.PublicKey[:]); ok {
+		t.Error("signature verifies against another key")
+	}
+	if ok, _ := Verify(msg, sig, other.Components.PublicSeed, addr, keypair.PublicKey[:]); ok {
+		t.Error("signature verifies with another public seed")
+	}
+}
+
+func TestKeypairWipe(t *testing.T) {
+	seed, msg := vectorSeed(t, 2)
+	keypair := Keygen(seed)
+	public := keypair.Components.PublicSeed
+	keypair.Wipe()
+	if keypair.Components.PrivateSeed != [32]byte{} {
+		t.Error("private seed not wiped")
+	}
+	// Only the secret is cleared: the public parts still verify a signature made before
+	if keypair.Components.PublicSeed != public || keypair.PublicKey == [SigSize]byte{} {
+		t.Error("public parts wiped")
+	}
+	signer := Keygen(seed)
+	sig := signer.Sign(msg)
+	if ok, err := Verify(msg, sig, public, keypair.Address, keypair.PublicKey[:]); !ok || err != nil {
+		t.Errorf("verify after wipe: %v, %v", ok, err)
+	}
+}
+
+// TestWorkersMatchSerial proves the parallel chains give bit-identical results for any worker count
+func TestWorkersMatchSerial(t *testing.T) {
+	rng := rand.New(rand.NewSource(1653))
+	var seed, pubSeed, addr, msg [32]byte
+	for i := 0; i < 5; i++ {
+		rng.Read(seed[:])
+		rng.Read(pubSeed[:])
+		rng.Read(addr[:])
+		rng.Read(msg[:])
+		pk := PkGen(seed, pubSeed, addr)
+		sig := Sign(msg, seed, pubSeed, addr)
+		for _, workers := range []int{-1, 0, 2, 3, 8, Len, Len + 5} {
+			if PkGenWorkers(seed, pubSeed, addr, workers) != pk {
+				t.Fatalf("PkGen with %d workers differs from serial", workers)
+			}
+			if SignWorkers(msg, seed, pubSeed, addr, workers) != sig {
+				t.Fatalf("Sign with %d workers differs from serial", workers)
+			}
+			if PkFromSigWorkers(sig, msg, pubSeed, addr, workers) != pk {
+				t.Fatalf("PkFromSig with %d workers differs from serial", workers)
+			}
+		}
+	}
+}
+
+func TestDefaultWorkers(t *testing.T) {
+	if w := DefaultWorkers(); w < 1 || w > MaxDefaultWorkers {
+		t.Errorf("DefaultWorkers() = %d", w)
+	}
+}
+
+func BenchmarkSign(b *testing.B) {
+	seed, msg := vectorSeed(b, 0)
+	keypair := Keygen(seed)
+	for _, workers := range []int{1, 2, 4, 8} {
+		b.Run(fmt.Sprintf("workers=%d", workers), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				SignWorkers(msg, keypair.Components.PrivateSeed, keypair.Components.PublicSeed, keypair.Address, workers)
+			}
+		})
+	}
+}
+
+func BenchmarkPkGen(b *testing.B) {
+	seed, _ := vectorSeed(b, 0)
+	keypair := Keygen(seed)
+	for _, workers := range []int{1, 2, 4, 8} {
+		b.Run(fmt.Sprintf("workers=%d", workers), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				PkGenWorkers(keypair.Components.PrivateSeed, keypair.Components.PublicSeed, keypair.Address, workers)
+			}
+		})
+	}
+}
+
+func TestChainInputErrors(t *testing.T) {
+	pubSeed := make([]byte, N)
+	var addr Address
+	for _, tc := range []struct {
+		name    string
+		in      []byte
+		pubSeed []byte
+	}{
+		{"nil value", nil, pubSeed},
+		{"short value", make([]byte, N-1), pubSeed},
+		{"short public seed", make([]byte, N), pubSeed[:N-1]},
+		{"long public seed", make([]byte, N), make([]byte, N+1)},
+	} {
+		if _, err := ThashF(tc.in, tc.pubSeed, &addr); err == nil {
+			t.Errorf("ThashF %s: no error", tc.name)
+		}
+		in := append([]byte(nil), tc.in...)
+		if err := GenChain(in, 0, W-1, tc.pubSeed, &addr); err == nil || !bytes.Equal(in, tc.in) {
+			t.Errorf("GenChain %s: %v, value changed %v", tc.name, err, !bytes.Equal(in, tc.in))
+		}
+	}
+	for _, tc := range [][2]int{{-1, 1}, {0, -1}} {
+		if err := GenChain(make([]byte, N), tc[0], tc[1], pubSeed, &addr); err == nil {
+			t.Errorf("GenChain start %d steps %d: no error", tc[0], tc[1])
+		}
+	}
+	// A value longer than N is accepted, only its first N bytes chain
+	long := make([]byte, N+8)
+	if err := GenChain(long, 0, 1, pubSeed, &addr); err != nil || !bytes.Equal(long[N:], make([]byte, 8)) {
+		t.Errorf("long value: %v, tail %x", err, long[N:])
+	}
+}
+
+// TestGenChainComposes checks that advancing a chain in two steps equals advancing it at once
+func TestGenChainComposes(t *testing.T) {
+	seed, _ := vectorSeed(t, 0)
+	c := GenerateComponents(seed)
+	addr := AddressFromBytes(SigningAddress(c.AddrSeed))
+	whole := append([]byte(nil), c.PrivateSeed[:]...)
+	parts := append([]byte(nil), c.PrivateSeed[:]...)
+	if err := GenChain(whole, 0, W-1, c.PublicSeed[:], &addr); err != nil {
+		t.Fatal(err)
+	}
+	if err := GenChain(parts, 0, 6, c.PublicSeed[:], &addr); err != nil {
+		t.Fatal(err)
+	}
+	if err := GenChain(parts, 6, W-1-6, c.PublicSeed[:], &addr); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(whole, parts) {
+		t.Error("chain advanced in two steps differs")
+	}
+}
+
+func TestBaseWErrors(t *testing.T) {
+	for _, tc := range []struct {
+		outLen int
+		input  []byte
+	}{
+		{-1, nil},
+		{1, nil},
+		{3, make([]byte, 1)},
+		{Len1, make([]byte, N-1)},
+	} {
+		if _, err := BaseW(tc.outLen, tc.input); err == nil {
+			t.Errorf("%d digits of %d bytes: no error", tc.outLen, len(tc.input))
+		}
+	}
+	if digits, err := BaseW(4, []byte{0x12, 0xef}); err != nil || fmt.Sprint(digits) != "[1 2 14 15]" {
+		t.Errorf("got %v, %v", digits, err)
+	}
+	for _, length := range []int{0, N - 1, N + 1} {
+		if _, err := ChainLengthsBytes(make([]byte, length)); err == nil {
+			t.Errorf("%d bytes message: no error", length)
+		}
+	}
+}
+
+func FuzzBaseW(f *testing.F) {
+	f.Add([]byte{0x12, 0xef}, 4)
+	f.Add([]byte{}, 0)
+	f.Add([]byte{0xff}, 3)
+	f.Add([]byte{0x00}, -1)
+	f.Fuzz(func(t *testing.T, input []byte, outLen int) {
+		digits, err := BaseW(outLen, input)
+		if err != nil {
+			return
+		}
+		if len(digits) != outLen {
+			t.Fatalf("%d digits, want %d", len(digits), outLen)
+		}
+		for _, d := range digits {
+			if d < 0 || d >= W {
+				t.Fatalf("digit %d out of range", d)
+			}
+		}
+	})
+}
+
+func FuzzChainLengths(f *testing.F) {
+	f.Add(make([]byte, N))
+	f.Add(bytes.Repeat([]byte{0xff}, N))
+	f.Add([]byte("short"))
+	f.Fuzz(func(t *testing.T, msg []byte) {
+		lengths, err := ChainLengthsBytes(msg)
+		if (err == nil) != (len(msg) == N) {
+			t.Fatalf("%d bytes message: %v", len(msg), err)
+		}
+		if err != nil {
+			return
+		}
+		if len(lengths) != Len {
+			t.Fatalf("%d lengths", len(lengths))
+		}
+		// The checksum digits hold the sum of what the message digits leave to W - 1
+		csum := 0
+		for i, l := range lengths {
+			if l < 0 || l >= W {
+				t.Fatalf("length %d of chain %d out of range", l, i)
+			}
+			if i < Len1 {
+				csum += W - 1 - l
+			}
+		}
+		if got := lengths[Len1]<<8 | lengths[Len1+1]<<4 | lengths[Len1+2]; got != csum {
+			t.Fatalf("checksum digits give %d, want %d", got, csum)
+		}
+	})
+}
+
+func FuzzGenChain(f *testing.F) {
+	f.Add(make([]byte, N), make([]byte, N), 0, W-1)
+	f.Add([]byte{1}, make([]byte, N), 0, 1)
+	f.Add(make([]byte, N), make([]byte, N), W, 3)
+	f.Add(make([]byte, N), make([]byte, N), -1, 100)
+	f.Fuzz(func(t *testing.T, value []byte, pubSeed []byte, start int, steps int) {
+		var addr Address
+		before := append([]byte(nil), value...)
+		err := GenChain(value, start, steps, pubSeed, &addr)
+		if err != nil && !bytes.Equal(value, before) {
+			t.Fatal("value changed by a rejected call")
+		}
+	})
+}
+
+// FuzzSignRoundTrip checks that PkFromSig of any signature reproduces PkGen, and that wrong lengths are errors
+func FuzzSignRoundTrip(f *testing.F) {
+	seed, msg := vectorSeed(f, 0)
+	f.Add(seed[:], msg[:])
+	f.Add(make([]byte, N), make([]byte, N))
+	f.Add(make([]byte, N-1), make([]byte, N))
+	f.Add(make([]byte, N), []byte{})
+	f.Fuzz(func(t *testing.T, seed []byte, msg []byte) {
+		c := GenerateComponents(sha256.Sum256(seed))
+		addr := SigningAddress(c.AddrSeed)
+		sig, err := SignBytes(msg, seed, c.PublicSeed[:], addr[:])
+		if (err == nil) != (len(seed) == N && len(msg) == N) {
+			t.Fatalf("%d bytes seed and %d bytes message: %v", len(seed), len(msg), err)
+		}
+		if err != nil {
+			return
+		}
+		pk := PkGen([N]byte(seed), c.PublicSeed, addr)
+		if PkFromSig(sig, [N]byte(msg), c.PublicSeed, addr) != pk {
+			t.Fatal("public key from signature differs from PkGen")
+		}
+	})
+}
@@ -1,5 +0,0 @@
-module github.com/NickP005/Vindax-MCM-tools/tool-2
-
-go 1.23.5
-
-require github.com/NickP005/WOTS-Go v0.0.4
@@ -1,14 +0,0 @@
-module github.com/NickP005/Vindax-MCM-tools/tool-3
-
-go 1.23.5
-
-require (
-	github.com/NickP005/WOTS-Go v0.0.4
-	github.com/NickP005/go_mcminterface v1.0.18
-)
-
-require (
-	github.com/sigurn/crc16 v0.0.0-20240131213347-83fcde1e29d1 // indirect
-	golang.org/x/crypto v0.33.0 // indirect
-	golang.org/x/sys v0.30.0 // indirect
-)
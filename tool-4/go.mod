@@ -1,8 +0,0 @@
-module tool-4
-
-go 1.22.5
-
-require (
-	github.com/btcsuite/btcutil v1.0.2
-	github.com/sigurn/crc16 v0.0.0-20240131213347-83fcde1e29d1
-)
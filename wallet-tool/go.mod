@@ -1,15 +0,0 @@
-module wallet-tool
-
-go 1.24.0
-
-require (
-	github.com/NickP005/WOTS-Go v0.0.4
-	github.com/NickP005/go_mcminterface v1.1.1
-	github.com/btcsuite/btcutil v1.0.2
-	github.com/sigurn/crc16 v0.0.0-20240131213347-83fcde1e29d1
-)
-
-require (
-	golang.org/x/crypto v0.37.0 // indirect
-	golang.org/x/sys v0.32.0 // indirect
-)